@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// allTestType is reported as the test type of -all documents.
+const allTestType = "IID and Non-IID"
+
+// AllOutput is the JSON document written by -all -output. IID and NonIID
+// are laid out like the documents of single-mode runs; HAssessed is the
+// smaller of their h_assessed values and is only set when both succeeded.
+// With -fail-below, Threshold and Passed apply to HAssessed. TimingsMS
+// merges the phase timings of both runs.
+type AllOutput struct {
+	Version      string             `json:"version"`
+	Filename     string             `json:"filename"`
+	TestType     string             `json:"test_type"`
+	DataSize     int                `json:"data_size"`
+	HAssessed    *float64           `json:"h_assessed,omitempty"`
+	Threshold    *float64           `json:"threshold,omitempty"`
+	Passed       *bool              `json:"passed,omitempty"`
+	IID          *JSONOutput        `json:"iid,omitempty"`
+	NonIID       *JSONOutput        `json:"non_iid,omitempty"`
+	TimingsMS    map[string]float64 `json:"timings_ms,omitempty"`
+	ErrorCode    int                `json:"error_code"`
+	ErrorMessage string             `json:"error_message,omitempty"`
+}
+
+// assessAll decodes one input once and runs both the IID and the Non-IID
+// assessment on it. Both result blocks are printed, followed by the overall
+// H_assessed. The exit code is 1 when either assessment failed, otherwise 3
+// when the overall H_assessed, the lower of the two, is below -fail-below,
+// and 0 when both succeeded.
+func (o *cliOptions) assessAll(filename string, raw []byte, stdout, stderr io.Writer) (AllOutput, int) {
+	out := AllOutput{
+		Version:  version,
+		Filename: filename,
+		TestType: allTestType,
+	}
+
+	prepared, data, ok := o.prepare(filename, raw, stderr)
+	if !ok {
+		out.ErrorCode = prepared.ErrorCode
+		out.ErrorMessage = prepared.ErrorMessage
+		return out, 1
+	}
+	out.DataSize = len(data)
+
+	code := 0
+	for _, testType := range []entropy.TestType{entropy.IID, entropy.NonIID} {
+		run := *o
+		run.testType = testType
+		doc := prepared
+		doc.TestType = testType.String()
+		doc, runCode := run.assessData(data, doc, stdout, stderr)
+		if testType == entropy.IID {
+			out.IID = &doc
+		} else {
+			out.NonIID = &doc
+		}
+		code = combineExitCodes(code, runCode)
+		for phase, ms := range doc.TimingsMS {
+			if out.TimingsMS == nil {
+				out.TimingsMS = make(map[string]float64)
+			}
+			out.TimingsMS[phase] = ms
+		}
+	}
+
+	if out.IID.ErrorCode != 0 || out.NonIID.ErrorCode != 0 {
+		out.ErrorCode = combineExitCodes(out.IID.ErrorCode, out.NonIID.ErrorCode)
+		out.ErrorMessage = "the IID or Non-IID assessment failed"
+		return out, code
+	}
+	h := math.Min(out.IID.HAssessed, out.NonIID.HAssessed)
+	out.HAssessed = &h
+	if o.thresholdSet {
+		threshold := o.failBelow
+		passed := h >= threshold
+		out.Threshold = &threshold
+		out.Passed = &passed
+	}
+	if !o.toFile && o.verbose >= 1 {
+		fmt.Fprintf(stdout, "\nOverall H_assessed: %.6f (minimum of IID and Non-IID)\n", h)
+	}
+	return out, code
+}
+
+// combineExitCodes returns the exit code of two runs: an assessment error (1)
+// takes precedence over a failed -remote call (exitRemote), then a threshold
+// failure (3), then success.
+func combineExitCodes(a, b int) int {
+	if a == 1 || b == 1 {
+		return 1
+	}
+	return max(a, b)
+}
@@ -0,0 +1,58 @@
+package main
+
+import "github.com/AmmannChristian/nist-800-90b/internal/entropy"
+
+// archiveSeparator joins the name of an archive and of one of its members in
+// the name a member is reported under.
+const archiveSeparator = "!"
+
+// expandArchives replaces every input with the regular files of the tar or
+// zip archive it names, in archive order, for -archive. An archive that
+// cannot be listed, or that is not an archive, is kept as a single input
+// that fails with the reason, so that it does not stop the other inputs.
+func expandArchives(inputs []batchInput) []batchInput {
+	var members []batchInput
+	for _, input := range inputs {
+		names, err := entropy.ArchiveMembers(input.path)
+		if err != nil {
+			input.err = err
+			members = append(members, input)
+			continue
+		}
+		for _, name := range names {
+			members = append(members, batchInput{path: input.path, rel: input.rel, member: name})
+		}
+	}
+	return members
+}
+
+// name returns the name the input is reported under: its path, followed by
+// archiveSeparator and the member name for an archive member.
+func (in batchInput) name() string {
+	if in.member == "" {
+		return in.path
+	}
+	return in.path + archiveSeparator + in.member
+}
+
+// relName returns rel like name returns path; it is empty when rel is.
+func (in batchInput) relName() string {
+	if in.rel == "" || in.member == "" {
+		return in.rel
+	}
+	return in.rel + archiveSeparator + in.member
+}
+
+// read reads the input: the member of an archive, or a file that is
+// decompressed as entropy.ReadFile does. A compressed file or a member is
+// read up to limit bytes when limit is above 0.
+func (in batchInput) read(limit int64) ([]byte, error) {
+	switch {
+	case in.err != nil:
+		return nil, in.err
+	case in.member != "":
+		return entropy.ReadArchiveMember(in.path, in.member, limit)
+	default:
+		return entropy.ReadFileLimit(in.path, limit, nil)
+	}
+}
@@ -0,0 +1,634 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/schema"
+)
+
+// quickTestType is reported as the test type of -quick results.
+const quickTestType = "MCV quick estimate"
+
+// lrsTestType is reported as the test type of -lrs results.
+const lrsTestType = "LRS estimate"
+
+// cliOptions holds the validated command-line settings applied to every input.
+type cliOptions struct {
+	testType      entropy.TestType
+	bits          int
+	verbose       int
+	encoding      string
+	column        int
+	delimiter     string
+	hSubmitter    float64
+	hSubmitterSet bool
+	estimators    []string
+	failBelow     float64
+	thresholdSet  bool
+	offset        int64
+	length        int64 // 0 assesses from offset to the end of the input
+	maxBytes      int64 // caps the window; 0 for no limit
+	sampleBytes   int64 // caps the window for a non-conforming dry run; 0 for no limit
+	stride        int   // assess every stride-th sample of the window; 0 or 1 for all
+	bitOrder      entropy.BitOrder
+	histogram     bool
+	raw           bool // include the NIST tool JSON document of the run
+	permRounds    int
+	permSeed      uint64
+	permSeedSet   bool
+	threads       int // permutation test threads per assessment; 0 for one per CPU
+	timeout       time.Duration
+	iidCheck      bool
+	quick         bool                        // compute only the pure-Go Most Common Value estimate
+	lrs           bool                        // compute only the longest repeated substring and its estimate
+	health        bool                        // run the continuous health tests instead of an assessment
+	conditioning  *entropy.ConditioningParams // conditioning applied to h_final; nil for none
+	hIn           float64                     // h_in of the conditioned subcommand; 0 derives it from h_final
+	nonVetted     bool                        // the conditioned component is not vetted (SP 800-90B 3.1.5.2)
+	restart       *entropy.RestartMatrix      // run the restart tests on this matrix; nil for none
+	hInitial      float64                     // initial entropy estimate H_I of the restart tests
+	layoutSet     bool                        // -restart-layout was given; otherwise a split-restart manifest may set it
+	remote        *remoteService              // run the assessments on this -remote service; nil for the local library
+	toFile        bool                        // results go to the -output file or a CSV, NDJSON or YAML stream instead of text
+	format        string
+	commandline   string    // the invocation, reported by -format nist-json
+	warnings      io.Writer // receives the assessment warnings; nil for stderr
+
+	ctx context.Context // cancels running assessments; nil for none
+}
+
+// assess decodes one input and runs the configured assessment on it. Text
+// results and errors are printed to stdout and stderr unless results go to a
+// file. It returns the JSON document for the input and its exit code.
+func (o *cliOptions) assess(filename string, raw []byte, stdout, stderr io.Writer) (JSONOutput, int) {
+	jsonOut, data, ok := o.prepare(filename, raw, stderr)
+	if !ok {
+		return jsonOut, 1
+	}
+	return o.assessData(data, jsonOut, stdout, stderr)
+}
+
+// prepare decodes raw and selects the -offset/-length window. It returns the
+// JSON document describing the input, the samples to assess and whether
+// they could be prepared; on failure the document carries the error.
+func (o *cliOptions) prepare(filename string, raw []byte, stderr io.Writer) (JSONOutput, []byte, bool) {
+	jsonOut := JSONOutput{
+		SchemaVersion: schema.Version,
+		Version:       version,
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		Filename:      filename,
+		TestType:      o.testType.String(),
+		BitsPerSymbol: o.bits,
+		DataSHA256:    sha256Hex(raw),
+		ErrorCode:     0,
+	}
+
+	data, err := o.decode(raw)
+	if err != nil {
+		jsonOut.ErrorCode = 1
+		jsonOut.ErrorMessage = err.Error()
+		fmt.Fprintf(stderr, "Error %s %s: %v\n", o.decodeVerb(), filename, err)
+		return jsonOut, nil, false
+	}
+	if o.windowed() {
+		inputSize := int64(len(data))
+		data, err = o.window(data)
+		if err != nil {
+			jsonOut.ErrorCode = 1
+			jsonOut.ErrorMessage = err.Error()
+			fmt.Fprintf(stderr, "Error selecting window of %s: %v\n", filename, err)
+			return jsonOut, nil, false
+		}
+		jsonOut.Section = &SectionOutput{Offset: o.offset, Length: int64(len(data)), InputSize: inputSize}
+		if o.stride > 1 {
+			data = o.strided(data)
+			jsonOut.Section.Stride = o.stride
+		}
+	}
+	jsonOut.DataSize = len(data)
+	return jsonOut, data, true
+}
+
+// windowed reports whether -offset, -length, -max-bytes, -sample-bytes or
+// -stride selects a window of the decoded samples.
+func (o *cliOptions) windowed() bool {
+	return o.offset > 0 || o.length > 0 || o.maxBytes > 0 || o.sampleBytes > 0 || o.stride > 1
+}
+
+// window returns the -offset/-length window of the decoded samples, cut
+// to at most -max-bytes and -sample-bytes samples.
+func (o *cliOptions) window(data []byte) ([]byte, error) {
+	if o.offset > 0 || o.length > 0 {
+		var err error
+		if data, err = entropy.SliceSection(data, o.offset, o.length); err != nil {
+			return nil, err
+		}
+	}
+	if o.maxBytes > 0 && int64(len(data)) > o.maxBytes {
+		data = data[:o.maxBytes]
+	}
+	if o.sampleBytes > 0 && int64(len(data)) > o.sampleBytes {
+		data = data[:o.sampleBytes]
+	}
+	return data, nil
+}
+
+// readLimit returns how many decompressed bytes of a compressed input or an
+// archive member can reach the window: -offset plus -length, which must all
+// be present, or else plus the smaller of -max-bytes and -sample-bytes. It is
+// 0, read everything, when the window runs to the end of the input, when
+// -input-encoding or -column decode the samples from text, and with -format
+// nist-json, whose digest covers the whole input.
+func (o *cliOptions) readLimit() int64 {
+	if o.encoding != encodingBinary || o.column > 0 || o.format == formatNISTJSON {
+		return 0
+	}
+	end := o.length
+	if end == 0 {
+		for _, n := range []int64{o.maxBytes, o.sampleBytes} {
+			if n > 0 && (end == 0 || n < end) {
+				end = n
+			}
+		}
+	}
+	if end == 0 {
+		return 0
+	}
+	return o.offset + end
+}
+
+// strided returns every -stride-th sample of the window, starting with the
+// first.
+func (o *cliOptions) strided(window []byte) []byte {
+	if o.stride <= 1 {
+		return window
+	}
+	data, _ := entropy.StrideSamples(window, o.stride)
+	return data
+}
+
+// assessData runs the configured assessment on prepared samples and fills
+// in jsonOut, including the time the assessment took. The text results end
+// with the elapsed time.
+func (o *cliOptions) assessData(data []byte, jsonOut JSONOutput, stdout, stderr io.Writer) (JSONOutput, int) {
+	started := time.Now()
+	jsonOut, code := o.assessSamples(data, jsonOut, stdout, stderr)
+	elapsed := time.Since(started)
+	jsonOut.DurationMS = elapsed.Milliseconds()
+	if !o.toFile && o.verbose >= 1 && jsonOut.ErrorCode == 0 {
+		fmt.Fprintf(stdout, "\nElapsed: %s\n", formatElapsed(elapsed))
+	}
+	return jsonOut, code
+}
+
+// assessSamples implements assessData.
+func (o *cliOptions) assessSamples(data []byte, jsonOut JSONOutput, stdout, stderr io.Writer) (JSONOutput, int) {
+	if o.quick {
+		return o.quickEstimate(data, jsonOut, stdout, stderr)
+	}
+	if o.lrs {
+		return o.lrsEstimate(data, jsonOut, stdout, stderr)
+	}
+	if o.restart != nil {
+		return o.restartTest(data, jsonOut, stdout, stderr)
+	}
+
+	assessment := o.newAssessment()
+	var progress *progressLine
+	if o.remote == nil && o.showProgress(stderr) {
+		progress = newProgressLine(stderr)
+		assessment = assessment.WithProgress(progress.report)
+	}
+	if o.iidCheck {
+		return o.checkIID(assessment, progress, data, jsonOut, stdout, stderr)
+	}
+
+	result, err := o.run(assessment, data)
+	progress.end()
+	if err != nil {
+		jsonOut.ErrorCode = errorCode(err)
+		jsonOut.ErrorMessage = err.Error()
+		if !o.toFile {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return jsonOut, jsonOut.ErrorCode
+	}
+
+	jsonOut.result = result
+	jsonOut.MinEntropy = result.MinEntropy
+	jsonOut.HOriginal = result.HOriginal
+	jsonOut.HBitstring = result.HBitstring
+	jsonOut.HAssessed = result.HAssessed
+	jsonOut.ShannonEntropy = &result.ShannonEntropy
+	jsonOut.AlphabetSize = result.AlphabetSize
+	jsonOut.HFinal = result.HFinal
+	jsonOut.SubmitterBinding = result.SubmitterBinding
+	if result.HasHSubmitter {
+		jsonOut.HSubmitter = &result.HSubmitter
+	}
+	jsonOut.Estimators = result.EstimatorSelection
+	jsonOut.EstimatorResults = estimatorResults(result.Estimators)
+	jsonOut.Partial = result.Partial
+	jsonOut.Backend = result.Backend
+	if o.bitOrder != entropy.MSBFirst {
+		jsonOut.BitOrder = o.bitOrder.String()
+	}
+	jsonOut.PermutationRounds = o.nonConformingRounds()
+	jsonOut.Histogram = result.Histogram
+	jsonOut.Raw = result.RawJSON
+	jsonOut.TimingsMS = timingsMS(result.Timings)
+	if o.conditioning != nil {
+		conditioned, err := o.condition(result)
+		if err != nil {
+			jsonOut.ErrorCode = 1
+			jsonOut.ErrorMessage = err.Error()
+			if !o.toFile {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+			}
+			return jsonOut, 1
+		}
+		jsonOut.Conditioning = conditioned
+	}
+	if o.raw && result.RawJSON == nil {
+		fmt.Fprintf(stderr, "Warning: the %s backend produces no NIST tool JSON; -raw has no effect\n", result.Backend)
+	}
+	if o.histogram {
+		// The assessment masks symbols to the word size; flag input that
+		// does not fit instead of silently folding it into other values.
+		if _, err := entropy.SymbolHistogram(data, result.DataWordSize); err != nil {
+			fmt.Fprintf(stderr, "Warning: %v; the histogram counts such symbols masked to %d bits\n", err, result.DataWordSize)
+		}
+	}
+
+	passed := true
+	if o.thresholdSet {
+		passed = result.MinEntropy >= o.failBelow
+		threshold := o.failBelow
+		jsonOut.Threshold = &threshold
+		jsonOut.Passed = &passed
+	}
+
+	if !o.toFile && o.verbose >= 1 {
+		fmt.Fprintf(stdout, "\nEntropy Assessment Results:\n")
+		fmt.Fprintf(stdout, "  Test Type:       %s\n", o.testType)
+		fmt.Fprintf(stdout, "  Bits/Symbol:     %d\n", result.DataWordSize)
+		fmt.Fprintf(stdout, "  H_original:      %.6f\n", result.HOriginal)
+		if result.HBitstring > 0 {
+			fmt.Fprintf(stdout, "  H_bitstring:     %.6f\n", result.HBitstring)
+		}
+		if o.bitOrder != entropy.MSBFirst {
+			fmt.Fprintf(stdout, "  Bit Order:       %s first\n", strings.ToUpper(o.bitOrder.String()))
+		}
+		fmt.Fprintf(stdout, "  H_assessed:      %.6f\n", result.HAssessed)
+		fmt.Fprintf(stdout, "  Min Entropy:     %.6f\n", result.MinEntropy)
+		fmt.Fprintf(stdout, "  Shannon Entropy: %.6f (baseline, not an SP 800-90B estimate)\n", result.ShannonEntropy)
+		fmt.Fprintf(stdout, "  Alphabet Size:   %d distinct symbols\n", result.AlphabetSize)
+		if len(result.EstimatorSelection) > 0 {
+			fmt.Fprintf(stdout, "  Estimators:      %s (non-conforming subset)\n", strings.Join(result.EstimatorSelection, ", "))
+		}
+		if result.Partial {
+			fmt.Fprintf(stdout, "  Partial:         only pure-Go estimators ran (non-conforming)\n")
+		}
+		if rounds := o.nonConformingRounds(); rounds > 0 {
+			fmt.Fprintf(stdout, "  Permutation:     %d rounds (non-conforming)\n", rounds)
+		}
+		if o.verbose >= 2 {
+			printEstimators(stdout, jsonOut.EstimatorResults)
+		}
+		if result.HasHSubmitter {
+			printFinalComparison(stdout, result)
+		}
+		if jsonOut.Conditioning != nil {
+			printConditioning(stdout, jsonOut.Conditioning)
+		}
+		if o.histogram {
+			printHistogram(stdout, result.Histogram, len(data))
+		}
+		if len(result.RawJSON) > 0 {
+			fmt.Fprintf(stdout, "\nNIST Tool JSON:\n%s", result.RawJSON)
+		}
+	}
+
+	if !passed {
+		fmt.Fprintf(stderr, "FAIL: min-entropy %.6f is below the required %.6f bits per symbol\n", result.MinEntropy, o.failBelow)
+		return jsonOut, 3
+	}
+
+	return jsonOut, 0
+}
+
+// newAssessment returns an assessment configured with the command-line
+// settings. -iid-check excludes -h-submitter, so the claim is always applied.
+func (o *cliOptions) newAssessment() *entropy.Assessment {
+	assessment := entropy.NewAssessment()
+	assessment.SetVerbose(o.verbose)
+	assessment.SetTimeout(o.timeout)
+	assessment.SetEstimators(o.estimators)
+	assessment.SetHistogram(o.histogram)
+	assessment.SetRawJSON(o.raw)
+	assessment.SetBitOrder(o.bitOrder)
+	assessment.SetPermutationRounds(o.permRounds)
+	assessment.SetThreads(o.threads)
+	if o.warnings != nil {
+		assessment.SetWarnWriter(o.warnings)
+	}
+	if o.permSeedSet {
+		assessment.SetPermutationSeed(o.permSeed)
+	}
+	if o.hSubmitterSet {
+		assessment.SetHSubmitter(o.hSubmitter)
+	}
+	return assessment
+}
+
+// run assesses data with the configured test type, on the -remote service
+// when one is set.
+func (o *cliOptions) run(assessment *entropy.Assessment, data []byte) (*entropy.Result, error) {
+	if o.remote != nil {
+		return o.remote.assess(o.context(), o, data)
+	}
+	if o.testType == entropy.IID {
+		return assessment.AssessIIDContext(o.context(), data, o.bits)
+	}
+	return assessment.AssessNonIIDContext(o.context(), data, o.bits)
+}
+
+// context returns the context that cancels the assessments.
+func (o *cliOptions) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+// decode converts raw input into samples, either by column extraction or by
+// the configured input encoding.
+func (o *cliOptions) decode(raw []byte) ([]byte, error) {
+	if o.column > 0 {
+		return extractColumn(raw, o.column, o.delimiter, o.bits)
+	}
+	return decodeInput(raw, o.encoding)
+}
+
+// decodeVerb names the decoding step in error messages.
+func (o *cliOptions) decodeVerb() string {
+	if o.column > 0 {
+		return "parsing"
+	}
+	return "decoding"
+}
+
+// checkIID runs only the IID statistical tests and reports the outcome. The
+// exit code is 0 when every test passed and 3 when any test failed.
+func (o *cliOptions) checkIID(assessment *entropy.Assessment, progress *progressLine, data []byte, jsonOut JSONOutput, stdout, stderr io.Writer) (JSONOutput, int) {
+	var passed bool
+	var tests []entropy.EstimatorResult
+	var err error
+	if o.remote != nil {
+		passed, tests, err = o.remote.checkIID(o.context(), o, data)
+	} else {
+		passed, tests, err = assessment.CheckIIDContext(o.context(), data, o.bits)
+	}
+	progress.end()
+	if err != nil {
+		jsonOut.ErrorCode = errorCode(err)
+		jsonOut.ErrorMessage = err.Error()
+		if !o.toFile {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return jsonOut, jsonOut.ErrorCode
+	}
+
+	jsonOut.IIDCheckPassed = &passed
+	jsonOut.PermutationRounds = o.nonConformingRounds()
+	for _, test := range tests {
+		jsonOut.Tests = append(jsonOut.Tests, TestOutput{ID: test.ID.String(), Name: test.Name, Passed: test.Passed})
+	}
+
+	if !o.toFile && o.verbose >= 1 {
+		fmt.Fprintf(stdout, "\nIID Check Results:\n")
+		for _, test := range tests {
+			fmt.Fprintf(stdout, "  %-42s %s\n", test.Name+":", passFail(test.Passed))
+		}
+		fmt.Fprintf(stdout, "  %-42s %s\n", "IID assumption:", passFail(passed))
+		if rounds := o.nonConformingRounds(); rounds > 0 {
+			fmt.Fprintf(stdout, "  %-42s %d (non-conforming)\n", "Permutation rounds:", rounds)
+		}
+	}
+
+	if !passed {
+		fmt.Fprintf(stderr, "FAIL: data does not satisfy the IID assumption\n")
+		return jsonOut, 3
+	}
+	return jsonOut, 0
+}
+
+// quickEstimate computes the Most Common Value estimate in pure Go. It is an
+// upper bound on the min-entropy of a full assessment, so -fail-below fails
+// only inputs that a full assessment would fail as well. The exit code is 3
+// when the estimate is below the threshold.
+func (o *cliOptions) quickEstimate(data []byte, jsonOut JSONOutput, stdout, stderr io.Writer) (JSONOutput, int) {
+	jsonOut.TestType = quickTestType
+	estimate, err := entropy.MostCommonValueEstimate(data, o.bits)
+	if err != nil {
+		jsonOut.ErrorCode = 1
+		jsonOut.ErrorMessage = err.Error()
+		if !o.toFile {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return jsonOut, 1
+	}
+	jsonOut.MinEntropy = estimate
+	jsonOut.Backend = entropy.BackendGo
+
+	passed := true
+	if o.thresholdSet {
+		passed = estimate >= o.failBelow
+		threshold := o.failBelow
+		jsonOut.Threshold = &threshold
+		jsonOut.Passed = &passed
+	}
+
+	if !o.toFile && o.verbose >= 1 {
+		fmt.Fprintf(stdout, "\nQuick Estimate:\n")
+		fmt.Fprintf(stdout, "  MCV Estimate:    %.6f\n", estimate)
+		fmt.Fprintf(stdout, "  Note:            upper bound from the Most Common Value estimate only, not an SP 800-90B assessment\n")
+	}
+
+	if !passed {
+		fmt.Fprintf(stderr, "FAIL: MCV estimate %.6f is below the required %.6f bits per symbol\n", estimate, o.failBelow)
+		return jsonOut, 3
+	}
+	return jsonOut, 0
+}
+
+// lrsEstimate computes the longest repeated substring of the samples and the
+// LRS estimate on them. The estimate is -1 when it cannot be computed; such a
+// result is not held against -fail-below, as a Non-IID assessment ignores it
+// too. The exit code is 3 when a valid estimate is below the threshold.
+func (o *cliOptions) lrsEstimate(data []byte, jsonOut JSONOutput, stdout, stderr io.Writer) (JSONOutput, int) {
+	jsonOut.TestType = lrsTestType
+	result, err := entropy.LongestRepeatedSubstring(data, o.bits)
+	if err != nil {
+		jsonOut.ErrorCode = 1
+		jsonOut.ErrorMessage = err.Error()
+		if !o.toFile {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return jsonOut, 1
+	}
+	jsonOut.MinEntropy = result.Estimate
+	jsonOut.LRSLength = &result.Length
+	jsonOut.Backend = entropy.LibraryInfo().Backend
+
+	passed := true
+	if o.thresholdSet {
+		passed = result.Estimate < 0 || result.Estimate >= o.failBelow
+		threshold := o.failBelow
+		jsonOut.Threshold = &threshold
+		jsonOut.Passed = &passed
+	}
+
+	if !o.toFile && o.verbose >= 1 {
+		fmt.Fprintf(stdout, "\nLongest Repeated Substring:\n")
+		fmt.Fprintf(stdout, "  Length:          %d symbols\n", result.Length)
+		if result.Estimate >= 0 {
+			fmt.Fprintf(stdout, "  LRS Estimate:    %.6f\n", result.Estimate)
+		} else {
+			fmt.Fprintf(stdout, "  LRS Estimate:    not applicable\n")
+		}
+		fmt.Fprintf(stdout, "  Note:            SP 800-90B Section 6.3.6 estimate on the literal symbols only, not an SP 800-90B assessment\n")
+	}
+
+	if !passed {
+		fmt.Fprintf(stderr, "FAIL: LRS estimate %.6f is below the required %.6f bits per symbol\n", result.Estimate, o.failBelow)
+		return jsonOut, 3
+	}
+	return jsonOut, 0
+}
+
+// nonConformingRounds returns the permutation rounds when a run shuffles fewer or
+// more times than SP 800-90B prescribes, and 0 otherwise. Only the pure-Go
+// permutation tests honour the setting; the NIST library always runs
+// entropy.PermutationRounds.
+func (o *cliOptions) nonConformingRounds() int {
+	if o.testType != entropy.IID || o.permRounds == entropy.PermutationRounds || entropy.LibraryInfo().CGO {
+		return 0
+	}
+	return o.permRounds
+}
+
+// passFail renders a test outcome for text output.
+func passFail(passed bool) string {
+	if passed {
+		return "PASS"
+	}
+	return "FAIL"
+}
+
+// timingsMS converts the phase timings of an assessment to milliseconds; it
+// returns nil when there are none.
+func timingsMS(timings map[string]time.Duration) map[string]float64 {
+	if len(timings) == 0 {
+		return nil
+	}
+	out := make(map[string]float64, len(timings))
+	for phase, d := range timings {
+		out[phase] = float64(d) / float64(time.Millisecond)
+	}
+	return out
+}
+
+// estimatorResults converts the estimator results of an assessment; it
+// returns nil when the backend reported none.
+func estimatorResults(estimators []entropy.EstimatorResult) []EstimatorResultOutput {
+	if len(estimators) == 0 {
+		return nil
+	}
+	out := make([]EstimatorResultOutput, len(estimators))
+	for i, est := range estimators {
+		out[i] = EstimatorResultOutput{
+			ID:              est.ID.String(),
+			Name:            est.Name,
+			EntropyEstimate: est.EntropyEstimate,
+			Passed:          est.Passed,
+			IsEntropyValid:  est.IsEntropyValid,
+		}
+	}
+	return out
+}
+
+// lowestEstimator returns the index of the first estimator with the lowest
+// valid entropy estimate, the one that determined the min-entropy, or -1
+// when no estimate is valid.
+func lowestEstimator(estimators []EstimatorResultOutput) int {
+	lowest := -1
+	for i, est := range estimators {
+		if est.IsEntropyValid && (lowest < 0 || est.EntropyEstimate < estimators[lowest].EntropyEstimate) {
+			lowest = i
+		}
+	}
+	return lowest
+}
+
+// printEstimators prints the estimate of every estimator, or the outcome of
+// a pass/fail test, and marks the estimator that determined the min-entropy.
+// It prints nothing when the backend reported no estimators.
+func printEstimators(w io.Writer, estimators []EstimatorResultOutput) {
+	if len(estimators) == 0 {
+		return
+	}
+	lowest := lowestEstimator(estimators)
+	fmt.Fprintf(w, "\nEstimators:\n")
+	fmt.Fprintf(w, "  %-38s %12s\n", "Estimator", "Result")
+	for i, est := range estimators {
+		result := passFail(est.Passed)
+		if est.IsEntropyValid {
+			result = strconv.FormatFloat(est.EntropyEstimate, 'f', 6, 64)
+		}
+		if i == lowest {
+			fmt.Fprintf(w, "  %-38s %12s  <- min-entropy\n", est.Name, result)
+			continue
+		}
+		fmt.Fprintf(w, "  %-38s %12s\n", est.Name, result)
+	}
+}
+
+// printHistogram prints the count and share of every symbol value that
+// occurs in the data.
+func printHistogram(w io.Writer, counts []uint64, samples int) {
+	fmt.Fprintf(w, "\nSymbol Histogram:\n")
+	for value, count := range counts {
+		if count > 0 {
+			fmt.Fprintf(w, "  0x%02x: %10d  %7.3f%%\n", value, count, 100*float64(count)/float64(samples))
+		}
+	}
+}
+
+// printFinalComparison prints the terms of the final entropy computation in
+// the style of the NIST reference tools and names the binding constraint.
+func printFinalComparison(w io.Writer, result *entropy.Result) {
+	binding := "H_original / H_bitstring"
+	if result.SubmitterBinding {
+		binding = "H_submitter"
+	}
+
+	fmt.Fprintf(w, "\nFinal Entropy (SP 800-90B Section 3.1.3):\n")
+	fmt.Fprintf(w, "  H_original:      %.6f\n", result.HOriginal)
+	if result.HBitstring > 0 {
+		fmt.Fprintf(w, "  %d X H_bitstring: %.6f\n", result.DataWordSize, float64(result.DataWordSize)*result.HBitstring)
+	}
+	fmt.Fprintf(w, "  H_submitter:     %.6f\n", result.HSubmitter)
+	if result.HBitstring > 0 {
+		fmt.Fprintf(w, "  min(H_original, %d X H_bitstring, H_submitter): %.6f\n", result.DataWordSize, result.HFinal)
+	} else {
+		fmt.Fprintf(w, "  min(H_original, H_submitter): %.6f\n", result.HFinal)
+	}
+	fmt.Fprintf(w, "  Binding:         %s\n", binding)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/schema"
+)
+
+// fileOutcome collects the output of one input file in a batch so that it
+// can be printed in argument order once all files are done.
+type fileOutcome struct {
+	json   JSONOutput
+	code   int
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+}
+
+// runBatch assesses several files using up to jobs concurrent workers.
+// Unless -threads is given, the CPUs are shared among the workers so that
+// their permutation tests do not oversubscribe the machine. Results are
+// printed, and written to outputFile, or stdout for "-", as a JSON array or
+// one YAML document per file, in the order of files whatever the order in
+// which they complete; with -format ndjson each result is instead streamed
+// as soon as its file is done.
+// Once ctx is done, running assessments are abandoned and files not yet
+// started are reported as not assessed. The exit code is that of the first
+// file that did not succeed, or 0.
+func runBatch(ctx context.Context, opts *cliOptions, files []batchInput, jobs int, outputFile string, stdout, stderr io.Writer) int {
+	doc, stdout := outputWriters(outputFile, stdout, stderr)
+	jobs = min(jobs, len(files))
+	run := *opts
+	run.ctx = ctx
+	if run.threads == 0 && jobs > 1 {
+		run.threads = max(1, runtime.NumCPU()/jobs)
+	}
+	outcomes := make([]fileOutcome, len(files))
+
+	var stream *ndjsonWriter
+	if opts.format == formatNDJSON {
+		var err error
+		if stream, err = newNDJSONWriter(outputFile, doc); err != nil {
+			fmt.Fprintf(stderr, "Error creating output file: %v\n", err)
+			return 1
+		}
+	}
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				started := time.Now()
+				assessFile(&run, files[i], &outcomes[i])
+				if stream != nil {
+					stream.write(outcomes[i].json, started, time.Now())
+				}
+			}
+		}()
+	}
+	started := 0
+dispatch:
+	for started < len(files) && ctx.Err() == nil {
+		select {
+		case work <- started:
+			started++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(work)
+	wg.Wait()
+	for i := started; i < len(files); i++ {
+		out := &outcomes[i]
+		out.json = run.failedOutput(files[i], errInterrupted)
+		out.code = 1
+		fmt.Fprintf(&out.stderr, "Skipped %s: %v\n", files[i].name(), errInterrupted)
+		if stream != nil {
+			now := time.Now()
+			stream.write(out.json, now, now)
+		}
+	}
+
+	code := 0
+	results := make([]any, len(files))
+	rows := make([]JSONOutput, len(files))
+	for i := range outcomes {
+		out := &outcomes[i]
+		if !opts.toFile && opts.verbose >= 1 {
+			fmt.Fprintf(stdout, "\n==> %s <==\n", files[i].name())
+		}
+		stdout.Write(out.stdout.Bytes())
+		stderr.Write(out.stderr.Bytes())
+		results[i] = opts.document(out.json)
+		rows[i] = out.json
+		if code == 0 {
+			code = out.code
+		}
+	}
+	if skipped := len(files) - started; skipped > 0 {
+		fmt.Fprintf(stderr, "Interrupted: %d of %d files were not assessed\n", skipped, len(files))
+	}
+
+	if stream != nil {
+		if err := stream.close(); err != nil {
+			fmt.Fprintf(stderr, "Error writing NDJSON: %v\n", err)
+			return 1
+		}
+		if namedOutput(outputFile) && opts.verbose > 0 {
+			fmt.Fprintf(stdout, "Results for %d files written to %s\n", len(files), outputFile)
+		}
+	} else if opts.format == formatCSV {
+		writeCSV(outputFile, doc, rows)
+		if namedOutput(outputFile) && opts.verbose > 0 {
+			fmt.Fprintf(stdout, "Results for %d files written to %s\n", len(files), outputFile)
+		}
+	} else if opts.format == formatYAML {
+		writeYAML(outputFile, doc, results)
+		if namedOutput(outputFile) && opts.verbose > 0 {
+			fmt.Fprintf(stdout, "Results for %d files written to %s\n", len(files), outputFile)
+		}
+	} else if outputFile != "" {
+		writeJSON(outputFile, doc, results)
+		if namedOutput(outputFile) && opts.verbose > 0 {
+			fmt.Fprintf(stdout, "Results for %d files written to %s\n", len(files), outputFile)
+		}
+	}
+	return code
+}
+
+// errInterrupted is reported for the files a cancelled batch did not start.
+var errInterrupted = errors.New("interrupted before the assessment started")
+
+// assessFile reads and assesses a single batch input into out.
+func assessFile(opts *cliOptions, input batchInput, out *fileOutcome) {
+	filename := input.name()
+	raw, err := input.read(opts.readLimit())
+	if err != nil {
+		out.json = opts.failedOutput(input, err)
+		out.code = 1
+		fmt.Fprintf(&out.stderr, "Error reading file %s: %v\n", filename, err)
+		return
+	}
+	out.json, out.code = opts.assess(filename, raw, &out.stdout, &out.stderr)
+	out.json.Path = input.relName()
+}
+
+// failedOutput returns the JSON document of a batch input that could not be
+// assessed because of err.
+func (o *cliOptions) failedOutput(input batchInput, err error) JSONOutput {
+	return JSONOutput{
+		SchemaVersion: schema.Version,
+		Version:       version,
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		Filename:      input.name(),
+		Path:          input.relName(),
+		TestType:      o.testType.String(),
+		BitsPerSymbol: o.bits,
+		ErrorCode:     1,
+		ErrorMessage:  err.Error(),
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// defaultReadTimeout is the -read-timeout default of "ea_tool capture": how
+// long the device may deliver no data before the capture fails.
+const defaultReadTimeout = 10 * time.Second
+
+// captureChunk is the most a capture asks the device for in one read.
+const captureChunk = 64 << 10
+
+// runCapture implements the "capture" subcommand. It reads exactly -samples
+// bytes from a device, file or pipe, optionally saves them with -save, and
+// assesses them as the main command assesses a file. It returns 0 on
+// success, 1 when the capture, the save or the assessment fails, 2 on
+// argument validation failure, or 3 when the min-entropy is below
+// -fail-below.
+func runCapture(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ea_tool capture", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	device := fs.String("device", "", "Device, file or pipe to capture from, - for stdin (required)")
+	samples := fs.Int64("samples", entropy.MinRecommendedSamples, "Number of samples (bytes) to capture")
+	readTimeout := fs.Duration("read-timeout", defaultReadTimeout, "Fail when the device delivers no data for this long, 0 to wait forever")
+	save := fs.String("save", "", "Save the raw capture to this file before assessing it")
+	iid := fs.Bool("iid", false, "Run the IID test")
+	nonIID := fs.Bool("non-iid", false, "Run the Non-IID test")
+	bits := fs.Int("bits", 0, "Bits per symbol (1-8), 0 for auto-detect")
+	hSubmitter := fs.Float64("h-submitter", 0, "Entropy claimed by the submitter in bits per sample (0 to bits)")
+	estimators := fs.String("estimators", "", "Comma-separated estimator subset (e.g. mcv,markov); non-conforming, default all")
+	failBelow := fs.Float64("fail-below", 0, "Exit with code 3 if the min-entropy is below this value (0 disables the check)")
+	outputFile := fs.String("output", "", "Output file for JSON results, - for stdout with the text on stderr")
+	verbose := fs.Int("verbose", 1, "Verbosity level (0=quiet, 1=normal, 2=verbose, 3=very verbose)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s -device path -iid|-non-iid [options]\n\n", fs.Name())
+		fmt.Fprintf(stderr, "Capture a fixed number of samples from a live source and assess them.\n")
+		fmt.Fprintf(stderr, "The capture fails, stating how many bytes were read, when the source\n")
+		fmt.Fprintf(stderr, "ends or stalls before -samples bytes arrive.\n\n")
+		fmt.Fprintf(stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExamples:\n")
+		fmt.Fprintf(stderr, "  %s -device /dev/hwrng -samples 1000000 -non-iid -bits 8\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -device /dev/ttyUSB0 -samples 1000000 -save capture.bin -iid -bits 8 -output result.json\n", fs.Name())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	doc, stdout := outputWriters(*outputFile, stdout, stderr)
+
+	if *device == "" {
+		fmt.Fprintf(stderr, "Error: capture requires -device\n")
+		return 2
+	}
+	if *samples < 1 {
+		fmt.Fprintf(stderr, "Error: samples must be at least 1, got %d\n", *samples)
+		return 2
+	}
+	if *readTimeout < 0 {
+		fmt.Fprintf(stderr, "Error: read-timeout must not be negative, got %s\n", *readTimeout)
+		return 2
+	}
+	if *iid == *nonIID {
+		fmt.Fprintf(stderr, "Error: Must specify exactly one of -iid or -non-iid\n")
+		return 2
+	}
+	testType := entropy.NonIID
+	if *iid {
+		testType = entropy.IID
+	}
+	if *bits < 0 || *bits > 8 {
+		fmt.Fprintf(stderr, "Error: bits per symbol must be 0-8, got %d\n", *bits)
+		return 2
+	}
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	hSubmitterSet := setFlags["h-submitter"]
+	if hSubmitterSet && (*hSubmitter < 0 || (*bits > 0 && *hSubmitter > float64(*bits))) {
+		fmt.Fprintf(stderr, "Error: h-submitter must be between 0 and bits per symbol, got %g\n", *hSubmitter)
+		return 2
+	}
+	var selection []string
+	if *estimators != "" {
+		selection = strings.Split(*estimators, ",")
+		if err := entropy.ValidateEstimators(testType, selection); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+	thresholdSet := setFlags["fail-below"] && *failBelow != 0
+	if thresholdSet && (math.IsNaN(*failBelow) || *failBelow < 0) {
+		fmt.Fprintf(stderr, "Error: fail-below must be a non-negative number, got %g\n", *failBelow)
+		return 2
+	}
+	if maxBits := cmp.Or(*bits, 8); thresholdSet && *failBelow > float64(maxBits) {
+		fmt.Fprintf(stderr, "Error: fail-below must not exceed %d bits per symbol, got %g\n", maxBits, *failBelow)
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "Error: capture takes no file arguments, got %s\n", strings.Join(fs.Args(), " "))
+		return 2
+	}
+
+	opts := &cliOptions{
+		testType:      testType,
+		bits:          *bits,
+		verbose:       *verbose,
+		encoding:      encodingBinary,
+		hSubmitter:    *hSubmitter,
+		hSubmitterSet: hSubmitterSet,
+		estimators:    selection,
+		failBelow:     *failBelow,
+		thresholdSet:  thresholdSet,
+		permRounds:    entropy.PermutationRounds,
+		toFile:        *outputFile != "",
+		format:        formatJSON,
+		commandline:   strings.Join(append([]string{fs.Name()}, args...), " "),
+	}
+
+	r := stdin
+	if *device != "-" {
+		file, err := os.Open(*device)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error opening %s: %v\n", *device, err)
+			return 1
+		}
+		// Closing the device also ends a read still blocked after a timeout.
+		defer file.Close()
+		r = file
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var report func(read, size int64)
+	if opts.showProgress(stderr) {
+		progress := &readProgress{w: stderr}
+		defer progress.end()
+		report = progress.report
+	}
+	started := time.Now()
+	data, err := captureSamples(ctx, r, *samples, *readTimeout, report)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error capturing from %s: %v\n", *device, err)
+		return 1
+	}
+	if *verbose > 0 {
+		fmt.Fprintf(stdout, "Captured %d samples from %s in %s\n", len(data), *device, formatElapsed(time.Since(started)))
+	}
+
+	if *save != "" {
+		if err := os.WriteFile(*save, data, 0o644); err != nil {
+			fmt.Fprintf(stderr, "Error saving capture: %v\n", err)
+			return 1
+		}
+		if *verbose > 0 {
+			fmt.Fprintf(stdout, "Capture saved to %s\n", *save)
+		}
+	}
+
+	jsonOut, code := opts.assess(*device, data, stdout, stderr)
+	if *outputFile != "" {
+		writeJSON(*outputFile, doc, opts.document(jsonOut))
+		if namedOutput(*outputFile) && *verbose > 0 && jsonOut.ErrorCode == 0 {
+			fmt.Fprintf(stdout, "Results written to %s\n", *outputFile)
+		}
+	}
+	return code
+}
+
+// readResult is the outcome of one read of a capture.
+type readResult struct {
+	n   int
+	err error
+}
+
+// captureSamples reads exactly n bytes from r. Short reads, which devices
+// return routinely, are continued. The capture fails with an error stating
+// how many bytes were obtained when r ends or fails first, when a read
+// delivers nothing for timeout (zero waits forever), or when ctx is done.
+// progress, if not nil, is called after every read.
+//
+// The reads run on a separate goroutine so that a stalled device cannot
+// block the caller; a read still pending when the capture fails ends once
+// the caller closes r.
+func captureSamples(ctx context.Context, r io.Reader, n int64, timeout time.Duration, progress func(read, size int64)) ([]byte, error) {
+	requests := make(chan []byte)
+	results := make(chan readResult, 1)
+	defer close(requests)
+	go func() {
+		for p := range requests {
+			read, err := r.Read(p)
+			results <- readResult{n: read, err: err}
+		}
+	}()
+
+	buf := make([]byte, n)
+	var got int64
+	for got < n {
+		requests <- buf[got:min(got+captureChunk, n)]
+		res, err := awaitRead(ctx, results, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("%w after %d of %d bytes", err, got, n)
+		}
+		got += int64(res.n)
+		if progress != nil {
+			progress(got, n)
+		}
+		if got == n {
+			break
+		}
+		if errors.Is(res.err, io.EOF) {
+			return nil, fmt.Errorf("input ended after %d of %d bytes", got, n)
+		}
+		if res.err != nil {
+			return nil, fmt.Errorf("read failed after %d of %d bytes: %w", got, n, res.err)
+		}
+	}
+	return buf, nil
+}
+
+// awaitRead waits for the result of the pending read of a capture, for at
+// most timeout when it is above zero.
+func awaitRead(ctx context.Context, results <-chan readResult, timeout time.Duration) (readResult, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case res := <-results:
+		return res, nil
+	case <-expired:
+		return readResult{}, fmt.Errorf("no data for %s", timeout)
+	case <-ctx.Done():
+		return readResult{}, errors.New("interrupted")
+	}
+}
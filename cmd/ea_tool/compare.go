@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// defaultCompareTolerance is the -compare-tolerance default: the largest
+// difference in bits per sample between two captures that -compare still
+// considers similar.
+const defaultCompareTolerance = 0.1
+
+// CompareOutput is the JSON document written by -compare -output.
+type CompareOutput struct {
+	Version       string         `json:"version"`
+	TestType      string         `json:"test_type"`
+	BitsPerSymbol int            `json:"bits_per_symbol"`
+	Tolerance     float64        `json:"tolerance"`
+	Primary       *CompareResult `json:"primary,omitempty"`
+	Compare       *CompareResult `json:"compare,omitempty"`
+	Deltas        []CompareDelta `json:"deltas,omitempty"`
+	Similar       bool           `json:"similar"`
+	ErrorCode     int            `json:"error_code"`
+	ErrorMessage  string         `json:"error_message,omitempty"`
+}
+
+// CompareResult is the assessment of one of the two compared inputs.
+type CompareResult struct {
+	Filename   string            `json:"filename"`
+	DataSize   int               `json:"data_size"`
+	MinEntropy float64           `json:"min_entropy"`
+	HOriginal  float64           `json:"h_original"`
+	HBitstring float64           `json:"h_bitstring,omitempty"`
+	HAssessed  float64           `json:"h_assessed"`
+	HFinal     float64           `json:"h_final"`
+	Estimators []EstimatorOutput `json:"estimators"`
+}
+
+// EstimatorOutput is the result of a single estimator or test. Estimate is
+// omitted for pass/fail tests.
+type EstimatorOutput struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Estimate *float64 `json:"estimate,omitempty"`
+	Passed   bool     `json:"passed"`
+}
+
+// CompareDelta compares one estimator, or the min-entropy, between the two
+// inputs. For pass/fail tests the values are 1 for pass and 0 for fail.
+type CompareDelta struct {
+	ID      string  `json:"id,omitempty"`
+	Name    string  `json:"name"`
+	Primary float64 `json:"primary"`
+	Compare float64 `json:"compare"`
+	Delta   float64 `json:"delta"` // Compare - Primary
+	Test    bool    `json:"test"`
+	Missing bool    `json:"missing,omitempty"` // The second input has no result for the estimator
+	Within  bool    `json:"within"`            // |Delta| is at most the tolerance
+}
+
+// compare assesses both inputs with the same settings and reports the
+// per-estimator and min-entropy differences. The exit code is 0 when every
+// difference is within the tolerance, 1 when an input cannot be read or
+// assessed, 3 when the inputs differ by more than the tolerance, and
+// exitRemote when the -remote service cannot be used.
+func (o *cliOptions) compare(nameA string, rawA []byte, nameB string, rawB []byte, tolerance float64, stdout, stderr io.Writer) (CompareOutput, int) {
+	out := CompareOutput{
+		Version:       version,
+		TestType:      o.testType.String(),
+		BitsPerSymbol: o.bits,
+		Tolerance:     tolerance,
+	}
+
+	a, resA, err := o.assessForCompare(nameA, rawA)
+	if err == nil {
+		out.Primary = a
+		out.Compare, _, err = o.assessForCompare(nameB, rawB)
+	}
+	if err != nil {
+		out.ErrorCode = errorCode(err)
+		out.ErrorMessage = err.Error()
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return out, out.ErrorCode
+	}
+	out.BitsPerSymbol = resA.DataWordSize
+
+	out.Similar = true
+	add := func(d CompareDelta) {
+		d.Delta = d.Compare - d.Primary
+		d.Within = !d.Missing && math.Abs(d.Delta) <= tolerance
+		out.Similar = out.Similar && d.Within
+		out.Deltas = append(out.Deltas, d)
+	}
+	for _, estA := range out.Primary.Estimators {
+		d := CompareDelta{ID: estA.ID, Name: estA.Name, Test: estA.Estimate == nil}
+		estB, ok := findEstimatorOutput(out.Compare.Estimators, estA.Name)
+		d.Primary = estimateOrOutcome(estA)
+		if ok {
+			d.Compare = estimateOrOutcome(estB)
+		} else {
+			// A missing result is a difference regardless of the tolerance.
+			d.Missing = true
+		}
+		add(d)
+	}
+	add(CompareDelta{Name: "Min Entropy", Primary: out.Primary.MinEntropy, Compare: out.Compare.MinEntropy})
+
+	if !o.toFile && o.verbose >= 1 {
+		printComparison(stdout, &out)
+	}
+	if !out.Similar {
+		fmt.Fprintf(stderr, "FAIL: %s and %s differ by more than %g bits per sample\n", nameA, nameB, tolerance)
+		return out, 3
+	}
+	return out, 0
+}
+
+// assessForCompare decodes one input, selects the -offset/-length window and
+// assesses it.
+func (o *cliOptions) assessForCompare(filename string, raw []byte) (*CompareResult, *entropy.Result, error) {
+	data, err := o.decode(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s: %w", o.decodeVerb(), filename, err)
+	}
+	if o.windowed() {
+		if data, err = o.window(data); err != nil {
+			return nil, nil, fmt.Errorf("selecting window of %s: %w", filename, err)
+		}
+		data = o.strided(data)
+	}
+
+	result, err := o.run(o.newAssessment(), data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("assessing %s: %w", filename, err)
+	}
+
+	cr := &CompareResult{
+		Filename:   filename,
+		DataSize:   len(data),
+		MinEntropy: result.MinEntropy,
+		HOriginal:  result.HOriginal,
+		HBitstring: result.HBitstring,
+		HAssessed:  result.HAssessed,
+		HFinal:     result.HFinal,
+		Estimators: make([]EstimatorOutput, len(result.Estimators)),
+	}
+	for i, est := range result.Estimators {
+		cr.Estimators[i] = EstimatorOutput{ID: est.ID.String(), Name: est.Name, Passed: est.Passed}
+		if est.IsEntropyValid {
+			estimate := est.EntropyEstimate
+			cr.Estimators[i].Estimate = &estimate
+		}
+	}
+	return cr, result, nil
+}
+
+// findEstimatorOutput returns the result reported under name.
+func findEstimatorOutput(estimators []EstimatorOutput, name string) (EstimatorOutput, bool) {
+	for _, est := range estimators {
+		if est.Name == name {
+			return est, true
+		}
+	}
+	return EstimatorOutput{}, false
+}
+
+// estimateOrOutcome returns the entropy estimate of est, or its outcome
+// encoded by entropy.PassValue for a pass/fail test.
+func estimateOrOutcome(est EstimatorOutput) float64 {
+	if est.Estimate == nil {
+		return entropy.PassValue(est.Passed)
+	}
+	return *est.Estimate
+}
+
+// printComparison prints the compared inputs and a table of the deltas.
+// Pass/fail tests show their outcomes instead of values.
+func printComparison(w io.Writer, out *CompareOutput) {
+	fmt.Fprintf(w, "\nComparison (%s, tolerance %g):\n", out.TestType, out.Tolerance)
+	fmt.Fprintf(w, "  Primary: %s (%d samples)\n", out.Primary.Filename, out.Primary.DataSize)
+	fmt.Fprintf(w, "  Compare: %s (%d samples)\n", out.Compare.Filename, out.Compare.DataSize)
+	fmt.Fprintf(w, "  %-38s %12s %12s %12s  %s\n", "Estimator", "Primary", "Compare", "Delta", "Result")
+	for _, d := range out.Deltas {
+		result := "ok"
+		if !d.Within {
+			result = "DIFFERS"
+		}
+		switch {
+		case d.Missing:
+			fmt.Fprintf(w, "  %-38s %12s %12s %12s  %s\n", d.Name, "-", "missing", "-", result)
+		case d.Test:
+			fmt.Fprintf(w, "  %-38s %12s %12s %12s  %s\n", d.Name, passFail(d.Primary == 1), passFail(d.Compare == 1), "-", result)
+		default:
+			fmt.Fprintf(w, "  %-38s %12.6f %12.6f %+12.6f  %s\n", d.Name, d.Primary, d.Compare, d.Delta, result)
+		}
+	}
+}
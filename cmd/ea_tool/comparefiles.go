@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// Kinds of ResultDelta.
+const (
+	deltaField     = "field"
+	deltaEstimator = "estimator"
+	deltaTest      = "test"
+)
+
+// ResultsDiffOutput is the JSON document written by "ea_tool compare
+// -output".
+type ResultsDiffOutput struct {
+	Version       string         `json:"version"`
+	Baseline      string         `json:"baseline"`
+	Current       string         `json:"current"`
+	Tolerance     float64        `json:"tolerance"`
+	Documents     []DocumentDiff `json:"documents"`
+	Within        bool           `json:"within"`
+	ErrorCode     int            `json:"error_code"`
+	ErrorMessage  string         `json:"error_message,omitempty"`
+	SchemaWarning string         `json:"schema_warning,omitempty"`
+}
+
+// DocumentDiff compares the results of one input and test type in the two
+// files.
+type DocumentDiff struct {
+	Filename string        `json:"filename"`
+	TestType string        `json:"test_type"`
+	Missing  string        `json:"missing,omitempty"` // "baseline" or "current", the file without the result
+	Deltas   []ResultDelta `json:"deltas,omitempty"`
+	Within   bool          `json:"within"`
+}
+
+// ResultDelta compares one field, estimator or test of a result. For
+// pass/fail tests the values are 1 for pass and 0 for fail. A value is nil
+// on the side that lacks it.
+type ResultDelta struct {
+	Kind     string   `json:"kind"` // field, estimator or test
+	ID       string   `json:"id,omitempty"`
+	Name     string   `json:"name"`
+	Baseline *float64 `json:"baseline"`
+	Current  *float64 `json:"current"`
+	Delta    float64  `json:"delta"`             // Current - Baseline
+	Exact    bool     `json:"exact,omitempty"`   // Any difference counts, regardless of the tolerance
+	Missing  string   `json:"missing,omitempty"` // "baseline" or "current", the file without the value
+	Within   bool     `json:"within"`
+}
+
+// runCompareFiles implements the "compare" subcommand. It diffs two files of
+// results written by -output, single documents, the arrays of several inputs,
+// -all documents or NDJSON, and reports the per-field and per-estimator
+// differences. It returns 0 when every difference is within -tolerance, 1 when
+// a file cannot be read or holds no results, 2 on argument validation failure,
+// or 3 when a difference exceeds the tolerance or a result is missing from one
+// of the files.
+func runCompareFiles(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ea_tool compare", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	tolerance := fs.Float64("tolerance", defaultCompareTolerance, "Largest accepted difference in bits per sample")
+	verbose := fs.Int("verbose", 1, "Verbosity level (0=quiet, 1=normal, 2=also list the unchanged values)")
+	outputFile := fs.String("output", "", "Also write the differences as JSON to this file, - for stdout with the text on stderr")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s [options] baseline.json current.json\n\n", fs.Name())
+		fmt.Fprintf(stderr, "Compare two files of results written by -output.\n\n")
+		fmt.Fprintf(stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExamples:\n")
+		fmt.Fprintf(stderr, "  %s baseline.json current.json -tolerance 0.001\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -output diff.json baseline.json current.json\n", fs.Name())
+	}
+
+	// The options may follow the file names.
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(files) != 2 {
+		fmt.Fprintf(stderr, "Error: compare requires a baseline and a current file, got %d files\n", len(files))
+		return 2
+	}
+	if *tolerance < 0 || math.IsNaN(*tolerance) {
+		fmt.Fprintf(stderr, "Error: -tolerance must not be negative, got %g\n", *tolerance)
+		return 2
+	}
+
+	out := ResultsDiffOutput{
+		Version:   version,
+		Baseline:  files[0],
+		Current:   files[1],
+		Tolerance: *tolerance,
+	}
+	doc, stdout := outputWriters(*outputFile, stdout, stderr)
+	code := out.compareFiles(stdout, stderr, *verbose)
+	if *outputFile != "" {
+		writeJSON(*outputFile, doc, out)
+	}
+	return code
+}
+
+// compareFiles reads and diffs the two files of out and returns the exit code
+// of runCompareFiles.
+func (out *ResultsDiffOutput) compareFiles(stdout, stderr io.Writer, verbose int) int {
+	baseline, err := readResultDocuments(out.Baseline)
+	var current []JSONOutput
+	if err == nil {
+		current, err = readResultDocuments(out.Current)
+	}
+	if err != nil {
+		out.ErrorCode = 1
+		out.ErrorMessage = err.Error()
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if a, b := schemaVersions(baseline), schemaVersions(current); a != b {
+		out.SchemaWarning = fmt.Sprintf("schema version %s of %s differs from %s of %s; only the fields of both are compared", a, out.Baseline, b, out.Current)
+		fmt.Fprintf(stderr, "Warning: %s\n", out.SchemaWarning)
+	}
+
+	out.Within = true
+	matched := make([]bool, len(current))
+	for i := range baseline {
+		d := DocumentDiff{Filename: resultKey(&baseline[i]), TestType: baseline[i].TestType}
+		j := findResultDocument(current, matched, &baseline[i])
+		if j < 0 {
+			d.Missing = "current"
+		} else {
+			matched[j] = true
+			d.Deltas = diffResults(&baseline[i], &current[j], out.Tolerance)
+			d.Within = true
+			for _, delta := range d.Deltas {
+				d.Within = d.Within && delta.Within
+			}
+		}
+		out.Within = out.Within && d.Within
+		out.Documents = append(out.Documents, d)
+	}
+	for j := range current {
+		if !matched[j] {
+			out.Documents = append(out.Documents, DocumentDiff{Filename: resultKey(&current[j]), TestType: current[j].TestType, Missing: "baseline"})
+			out.Within = false
+		}
+	}
+
+	if verbose >= 1 {
+		printResultsDiff(stdout, out, verbose >= 2)
+	}
+	if !out.Within {
+		fmt.Fprintf(stderr, "FAIL: %s and %s differ by more than %g bits per sample or in their results\n", out.Baseline, out.Current, out.Tolerance)
+		return 3
+	}
+	return 0
+}
+
+// readResultDocuments reads the results of filename: one JSON document, a
+// JSON array of documents as written for several inputs, or one document per
+// line. The IID and Non-IID results of a -all document are returned as two
+// documents.
+func readResultDocuments(filename string) ([]JSONOutput, error) {
+	encoded, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var raws []json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		if raw[0] == '[' {
+			var items []json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, fmt.Errorf("%s: %w", filename, err)
+			}
+			raws = append(raws, items...)
+			continue
+		}
+		raws = append(raws, raw)
+	}
+
+	var docs []JSONOutput
+	for _, raw := range raws {
+		var doc JSONOutput
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		switch doc.TestType {
+		case "":
+			return nil, fmt.Errorf("%s: not an ea_tool result document", filename)
+		case allTestType:
+			var all AllOutput
+			if err := json.Unmarshal(raw, &all); err != nil {
+				return nil, fmt.Errorf("%s: %w", filename, err)
+			}
+			for _, part := range []*JSONOutput{all.IID, all.NonIID} {
+				if part != nil {
+					docs = append(docs, *part)
+				}
+			}
+		default:
+			docs = append(docs, doc)
+		}
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%s holds no results", filename)
+	}
+	return docs, nil
+}
+
+// schemaVersions returns the schema version of docs, or their versions
+// separated by commas when they differ.
+func schemaVersions(docs []JSONOutput) string {
+	versions := ""
+	seen := make(map[string]bool)
+	for _, doc := range docs {
+		if !seen[doc.SchemaVersion] {
+			seen[doc.SchemaVersion] = true
+			if versions != "" {
+				versions += ","
+			}
+			versions += doc.SchemaVersion
+		}
+	}
+	return versions
+}
+
+// resultKey returns the name under which doc is paired with the result of
+// the other file: its path below a -recursive argument, else its file name.
+func resultKey(doc *JSONOutput) string {
+	if doc.Path != "" {
+		return doc.Path
+	}
+	return doc.Filename
+}
+
+// findResultDocument returns the index of the first document of docs not yet
+// matched with the input and test type of doc, or -1.
+func findResultDocument(docs []JSONOutput, matched []bool, doc *JSONOutput) int {
+	for i := range docs {
+		if !matched[i] && resultKey(&docs[i]) == resultKey(doc) && docs[i].TestType == doc.TestType {
+			return i
+		}
+	}
+	return -1
+}
+
+// diffResults compares the fields, estimators and tests of two results of
+// the same input. Entropies are compared within tolerance; the sample size,
+// the word size and the error code must be equal. A field omitted as zero by
+// the JSON encoding counts as zero, but shannon_entropy is only compared when
+// both results have it.
+func diffResults(a, b *JSONOutput, tolerance float64) []ResultDelta {
+	var deltas []ResultDelta
+	add := func(d ResultDelta) {
+		switch {
+		case d.Baseline == nil:
+			d.Missing = "baseline"
+		case d.Current == nil:
+			d.Missing = "current"
+		default:
+			d.Delta = *d.Current - *d.Baseline
+			d.Within = math.Abs(d.Delta) <= tolerance
+			if d.Exact || d.Kind == deltaTest {
+				d.Within = d.Delta == 0
+			}
+		}
+		deltas = append(deltas, d)
+	}
+	field := func(name string, x, y float64, exact bool) {
+		add(ResultDelta{Kind: deltaField, Name: name, Baseline: &x, Current: &y, Exact: exact})
+	}
+
+	field("bits_per_symbol", float64(a.BitsPerSymbol), float64(b.BitsPerSymbol), true)
+	field("data_size", float64(a.DataSize), float64(b.DataSize), true)
+	field("error_code", float64(a.ErrorCode), float64(b.ErrorCode), true)
+	field("min_entropy", a.MinEntropy, b.MinEntropy, false)
+	field("h_original", a.HOriginal, b.HOriginal, false)
+	field("h_bitstring", a.HBitstring, b.HBitstring, false)
+	field("h_assessed", a.HAssessed, b.HAssessed, false)
+	if a.ShannonEntropy != nil && b.ShannonEntropy != nil {
+		field("shannon_entropy", *a.ShannonEntropy, *b.ShannonEntropy, false)
+	}
+	field("h_final", a.HFinal, b.HFinal, false)
+
+	for _, d := range pairResults(estimatorDeltas(a.EstimatorResults), estimatorDeltas(b.EstimatorResults)) {
+		add(d)
+	}
+	for _, d := range pairResults(testDeltas(a.Tests), testDeltas(b.Tests)) {
+		add(d)
+	}
+	return deltas
+}
+
+// estimatorDeltas returns the estimators as deltas holding one value: the
+// entropy estimate, or the outcome encoded by entropy.PassValue for a test.
+func estimatorDeltas(estimators []EstimatorResultOutput) []ResultDelta {
+	deltas := make([]ResultDelta, len(estimators))
+	for i, est := range estimators {
+		value := est.EntropyEstimate
+		kind := deltaEstimator
+		if !est.IsEntropyValid {
+			value, kind = entropy.PassValue(est.Passed), deltaTest
+		}
+		deltas[i] = ResultDelta{Kind: kind, ID: est.ID, Name: est.Name, Baseline: &value}
+	}
+	return deltas
+}
+
+// testDeltas returns the tests of an -iid-check as deltas holding one value,
+// the outcome encoded by entropy.PassValue.
+func testDeltas(tests []TestOutput) []ResultDelta {
+	deltas := make([]ResultDelta, len(tests))
+	for i, test := range tests {
+		value := entropy.PassValue(test.Passed)
+		deltas[i] = ResultDelta{Kind: deltaTest, ID: test.ID, Name: test.Name, Baseline: &value}
+	}
+	return deltas
+}
+
+// pairResults pairs the estimators of the two results, which hold their
+// value in Baseline, by ID, or by name when either has no known ID. The
+// pairs follow the order of a, then the estimators only b has.
+func pairResults(a, b []ResultDelta) []ResultDelta {
+	same := func(x, y ResultDelta) bool {
+		if knownEstimatorID(x.ID) && knownEstimatorID(y.ID) {
+			return x.ID == y.ID
+		}
+		return x.Name == y.Name
+	}
+
+	var pairs []ResultDelta
+	matched := make([]bool, len(b))
+	for _, x := range a {
+		d := x
+		for j, y := range b {
+			if !matched[j] && same(x, y) {
+				matched[j] = true
+				d.Current = y.Baseline
+				break
+			}
+		}
+		pairs = append(pairs, d)
+	}
+	for j, y := range b {
+		if !matched[j] {
+			y.Current, y.Baseline = y.Baseline, nil
+			pairs = append(pairs, y)
+		}
+	}
+	return pairs
+}
+
+// knownEstimatorID reports whether id names an estimator rather than
+// standing in for one the tool does not know.
+func knownEstimatorID(id string) bool {
+	return id != "" && id != "unknown"
+}
+
+// printResultsDiff prints a table of the deltas of every document. Only the
+// values that differ are listed unless all is set; pass/fail tests show
+// their outcomes instead of values.
+func printResultsDiff(w io.Writer, out *ResultsDiffOutput, all bool) {
+	fmt.Fprintf(w, "Comparing %s (baseline) with %s (current), tolerance %g:\n", out.Baseline, out.Current, out.Tolerance)
+	differing := 0
+	for _, doc := range out.Documents {
+		fmt.Fprintf(w, "\n==> %s (%s) <==\n", doc.Filename, doc.TestType)
+		if doc.Missing != "" {
+			fmt.Fprintf(w, "  missing from the %s file\n", doc.Missing)
+			differing++
+			continue
+		}
+		if doc.Within && !all {
+			fmt.Fprintf(w, "  all %d values within the tolerance\n", len(doc.Deltas))
+			continue
+		}
+		if !doc.Within {
+			differing++
+		}
+		fmt.Fprintf(w, "  %-38s %12s %12s %12s  %s\n", "Value", "Baseline", "Current", "Delta", "Result")
+		for _, d := range doc.Deltas {
+			if d.Within && !all {
+				continue
+			}
+			result := "ok"
+			if !d.Within {
+				result = "DIFFERS"
+			}
+			switch {
+			case d.Missing != "":
+				baseline, current := "missing", "missing"
+				if d.Baseline != nil {
+					baseline = formatDeltaValue(d, *d.Baseline)
+				}
+				if d.Current != nil {
+					current = formatDeltaValue(d, *d.Current)
+				}
+				fmt.Fprintf(w, "  %-38s %12s %12s %12s  %s\n", d.Name, baseline, current, "-", "MISSING")
+			case d.Kind == deltaTest:
+				fmt.Fprintf(w, "  %-38s %12s %12s %12s  %s\n", d.Name, passFail(*d.Baseline == 1), passFail(*d.Current == 1), "-", result)
+			case d.Exact:
+				fmt.Fprintf(w, "  %-38s %12.0f %12.0f %+12.0f  %s\n", d.Name, *d.Baseline, *d.Current, d.Delta, result)
+			default:
+				fmt.Fprintf(w, "  %-38s %12.6f %12.6f %+12.6f  %s\n", d.Name, *d.Baseline, *d.Current, d.Delta, result)
+			}
+		}
+	}
+	fmt.Fprintf(w, "\n%d of %d results differ\n", differing, len(out.Documents))
+}
+
+// formatDeltaValue formats the value of d for the table.
+func formatDeltaValue(d ResultDelta, value float64) string {
+	if d.Kind == deltaTest {
+		return passFail(value == 1)
+	}
+	return fmt.Sprintf("%.6f", value)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// ConditioningOutput is the output entropy of a conditioning component:
+// with -conditioning, of a vetted component fed with the assessed samples;
+// with "ea_tool conditioned", of the component whose output was assessed.
+// HPrime, the entropy per bit of that output, is present for a component
+// that is not vetted.
+type ConditioningOutput struct {
+	NIn    int      `json:"n_in"`
+	NOut   int      `json:"n_out"`
+	NW     int      `json:"nw"`
+	Vetted bool     `json:"vetted"`
+	HIn    float64  `json:"h_in"`
+	HPrime *float64 `json:"h_prime,omitempty"`
+	HOut   float64  `json:"h_out"`
+}
+
+// parseConditioning parses the -conditioning value "n_in,n_out,nw".
+func parseConditioning(value string) (entropy.ConditioningParams, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) != 3 {
+		return entropy.ConditioningParams{}, fmt.Errorf("conditioning must be n_in,n_out,nw, got %q", value)
+	}
+	var widths [3]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return entropy.ConditioningParams{}, fmt.Errorf("conditioning must be n_in,n_out,nw, got %q", value)
+		}
+		widths[i] = n
+	}
+	p := entropy.ConditioningParams{NIn: widths[0], NOut: widths[1], NW: widths[2]}
+	if err := p.Validate(); err != nil {
+		return entropy.ConditioningParams{}, err
+	}
+	return p, nil
+}
+
+// condition applies the -conditioning component to the final entropy of
+// result. One input of n_in bits holds n_in / word size samples, so its
+// entropy h_in is h_final * n_in / word size. In the conditioned subcommand
+// h_in is given and result is the assessment of the conditioned output.
+func (o *cliOptions) condition(result *entropy.Result) (*ConditioningOutput, error) {
+	p := *o.conditioning
+	out := &ConditioningOutput{NIn: p.NIn, NOut: p.NOut, NW: p.NW, Vetted: !o.nonVetted}
+	if o.hIn > 0 {
+		return o.conditionOutput(result, out)
+	}
+	if result.DataWordSize < 1 || result.HFinal <= 0 {
+		return out, nil
+	}
+	out.HIn = min(result.HFinal*float64(p.NIn)/float64(result.DataWordSize), float64(p.NIn))
+	hOut, err := entropy.ApplyConditioning(out.HIn, p)
+	if err != nil {
+		return nil, err
+	}
+	out.HOut = hOut
+	return out, nil
+}
+
+// conditionOutput computes h_out of the conditioned subcommand from the
+// given h_in. A component that is not vetted is also limited by h', the
+// assessed entropy per bit of its output, H_assessed / word size.
+func (o *cliOptions) conditionOutput(result *entropy.Result, out *ConditioningOutput) (*ConditioningOutput, error) {
+	out.HIn = o.hIn
+	var err error
+	if !o.nonVetted {
+		out.HOut, err = entropy.ApplyConditioning(o.hIn, *o.conditioning)
+		return out, err
+	}
+	hPrime := 0.0
+	if result.DataWordSize > 0 {
+		hPrime = min(result.HAssessed/float64(result.DataWordSize), 1)
+	}
+	out.HPrime = &hPrime
+	out.HOut, err = entropy.ApplyNonVettedConditioning(o.hIn, hPrime, *o.conditioning)
+	return out, err
+}
+
+// printConditioning prints the conditioning calculation.
+func printConditioning(w io.Writer, c *ConditioningOutput) {
+	if c.Vetted {
+		fmt.Fprintf(w, "\nVetted Conditioning (SP 800-90B Section 3.1.5.1.2):\n")
+	} else {
+		fmt.Fprintf(w, "\nNon-Vetted Conditioning (SP 800-90B Section 3.1.5.2):\n")
+	}
+	fmt.Fprintf(w, "  n_in, n_out, nw: %d, %d, %d\n", c.NIn, c.NOut, c.NW)
+	fmt.Fprintf(w, "  h_in:            %.6f\n", c.HIn)
+	if c.HPrime != nil {
+		fmt.Fprintf(w, "  h':              %.6f (per bit of output)\n", *c.HPrime)
+	}
+	fmt.Fprintf(w, "  h_out:           %.6f\n", c.HOut)
+}
+
+// runConditioned implements the "conditioned" subcommand. It assesses the
+// output of a conditioning component with the Non-IID track and computes the
+// entropy h_out of one output from the entropy -h-in of one input, with the
+// formula of SP 800-90B Section 3.1.5.1.2 for a -vetted component and that
+// of Section 3.1.5.2 otherwise. It returns 0 on success, 1 on a read or
+// assessment error, or 2 on argument validation failure.
+func runConditioned(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ea_tool conditioned", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	vetted := fs.Bool("vetted", false, "The component is vetted (SP 800-90B 3.1.5.1.1); otherwise h_out is also limited by the assessed output")
+	nIn := fs.Int("n-in", 0, "Input width n_in of the component in bits")
+	nOut := fs.Int("n-out", 0, "Output width n_out of the component in bits")
+	nw := fs.Int("nw", 0, "Narrowest internal width nw of the component in bits")
+	hIn := fs.Float64("h-in", 0, "Entropy of one n_in-bit input in bits")
+	bits := fs.Int("bits", 8, "Bits per symbol of the conditioned output (1-8), 0 for auto-detect")
+	verbose := fs.Int("verbose", 1, "Verbosity level (0=quiet, 1=normal, 2=verbose, 3=very verbose)")
+	outputFile := fs.String("output", "", "Output file for JSON results, - for stdout with the text on stderr")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s -n-in n -n-out n -nw n -h-in h [options] [file|-]\n\n", fs.Name())
+		fmt.Fprintf(stderr, "Assess the output of a conditioning component and compute its entropy h_out.\n\n")
+		fmt.Fprintf(stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExamples:\n")
+		fmt.Fprintf(stderr, "  %s -vetted -n-in 512 -n-out 256 -nw 256 -h-in 5.2 data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -n-in 512 -n-out 256 -nw 256 -h-in 300 -output result.json data.bin\n", fs.Name())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	doc, stdout := outputWriters(*outputFile, stdout, stderr)
+
+	p := entropy.ConditioningParams{NIn: *nIn, NOut: *nOut, NW: *nw}
+	if err := p.Validate(); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if math.IsNaN(*hIn) || *hIn <= 0 || *hIn > float64(p.NIn) {
+		fmt.Fprintf(stderr, "Error: h-in must be above 0 and at most n_in = %d bits, got %g\n", p.NIn, *hIn)
+		return 2
+	}
+	if *bits < 0 || *bits > 8 {
+		fmt.Fprintf(stderr, "Error: bits per symbol must be 0-8, got %d\n", *bits)
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintf(stderr, "Error: conditioned accepts a single input\n")
+		return 2
+	}
+
+	filename, data := "stdin", []byte(nil)
+	var err error
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		filename = fs.Arg(0)
+		data, err = entropy.ReadFile(filename)
+	} else {
+		data, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading %s: %v\n", filename, err)
+		return 1
+	}
+
+	opts := &cliOptions{
+		testType:     entropy.NonIID,
+		bits:         *bits,
+		verbose:      *verbose,
+		encoding:     encodingBinary,
+		permRounds:   entropy.PermutationRounds,
+		conditioning: &p,
+		hIn:          *hIn,
+		nonVetted:    !*vetted,
+		toFile:       *outputFile != "",
+		format:       formatJSON,
+	}
+	jsonOut, code := opts.assess(filename, data, stdout, stderr)
+	if *outputFile != "" {
+		writeJSON(*outputFile, doc, jsonOut)
+		if namedOutput(*outputFile) && *verbose > 0 && jsonOut.ErrorCode == 0 {
+			fmt.Fprintf(stdout, "Results written to %s\n", *outputFile)
+		}
+	}
+	return code
+}
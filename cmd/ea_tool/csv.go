@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// csvHeader names the columns of -format csv, one row per assessment.
+var csvHeader = []string{
+	"filename", "test_type", "bits_per_symbol", "data_size",
+	"h_original", "h_bitstring", "h_assessed", "min_entropy", "error",
+}
+
+// csvRow returns the columns of one assessment. The entropy columns are
+// empty when the assessment failed; error holds its message.
+func csvRow(out JSONOutput) []string {
+	row := []string{
+		out.Filename,
+		out.TestType,
+		strconv.Itoa(out.BitsPerSymbol),
+		strconv.Itoa(out.DataSize),
+		"", "", "", "",
+		out.ErrorMessage,
+	}
+	if out.ErrorCode == 0 {
+		for i, v := range []float64{out.HOriginal, out.HBitstring, out.HAssessed, out.MinEntropy} {
+			row[4+i] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return row
+}
+
+// allRows returns the -all assessments of out as CSV rows: one per test
+// type, or a single row carrying the error when the input could not be
+// prepared.
+func allRows(out AllOutput) []JSONOutput {
+	if out.IID == nil || out.NonIID == nil {
+		return []JSONOutput{{
+			Filename:     out.Filename,
+			TestType:     out.TestType,
+			DataSize:     out.DataSize,
+			ErrorCode:    out.ErrorCode,
+			ErrorMessage: out.ErrorMessage,
+		}}
+	}
+	return []JSONOutput{*out.IID, *out.NonIID}
+}
+
+// encodeCSV writes the header and one row per assessment to w. Fields with
+// commas, quotes or line breaks are quoted.
+func encodeCSV(w io.Writer, outs []JSONOutput) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, out := range outs {
+		if err := cw.Write(csvRow(out)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeCSV writes the assessments to filename, or to stdout when filename is
+// empty or "-".
+func writeCSV(filename string, stdout io.Writer, outs []JSONOutput) {
+	if filename == "" || filename == stdoutName {
+		if err := encodeCSV(stdout, outs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	if err := encodeCSV(file, outs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/health"
+)
+
+// checkHealth runs the continuous health tests over one input, using the
+// claimed -h-submitter entropy. The exit code is 0 when the input passes, 3
+// on the first failing sample and 1 when the input cannot be read.
+func (o *cliOptions) checkHealth(filename string, raw []byte, stdout, stderr io.Writer) int {
+	data, err := o.decode(raw)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error %s %s: %v\n", o.decodeVerb(), filename, err)
+		return 1
+	}
+	if o.windowed() {
+		data, err = o.window(data)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error selecting window of %s: %v\n", filename, err)
+			return 1
+		}
+		data = o.strided(data)
+	}
+
+	bits := o.bits
+	if bits == 0 {
+		bits = 8
+	}
+	monitor, err := health.NewMonitor(health.Config{H: o.hSubmitter, Bits: bits})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	err = monitor.Run(bytes.NewReader(data))
+	var failure *health.FailureError
+	if err != nil && !errors.As(err, &failure) {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if o.verbose >= 1 {
+		rct, apt := monitor.RepetitionCount(), monitor.AdaptiveProportion()
+		fmt.Fprintf(stdout, "\nHealth Test Results:\n")
+		fmt.Fprintf(stdout, "  Samples:         %d\n", monitor.Samples())
+		fmt.Fprintf(stdout, "  H:               %g\n", o.hSubmitter)
+		fmt.Fprintf(stdout, "  RCT cutoff:      %d\n", rct.Cutoff())
+		fmt.Fprintf(stdout, "  APT cutoff:      %d (window %d)\n", apt.Cutoff(), apt.Window())
+		fmt.Fprintf(stdout, "  Result:          %s\n", passFail(failure == nil))
+	}
+
+	if failure != nil {
+		fmt.Fprintf(stderr, "FAIL: %v\n", failure)
+		return 3
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Supported values for the -input-encoding flag.
+const (
+	encodingBinary = "binary"
+	encodingHex    = "hex"
+	encodingBase64 = "base64"
+)
+
+// parseInputEncoding normalizes the -input-encoding flag value; "raw" is an
+// alias of binary.
+func parseInputEncoding(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", encodingBinary, "raw":
+		return encodingBinary, nil
+	case encodingHex:
+		return encodingHex, nil
+	case encodingBase64:
+		return encodingBase64, nil
+	default:
+		return "", fmt.Errorf("invalid input encoding: %s (use binary, raw, hex, or base64)", value)
+	}
+}
+
+// decodeInput converts raw input bytes into samples according to encoding.
+// ASCII whitespace, including line breaks, is ignored for the text
+// encodings, hex digits may be of either case, and base64 padding is
+// optional. Malformed input is reported with the byte offset of the first
+// invalid character in raw.
+func decodeInput(raw []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case encodingHex:
+		data, err := decodeHex(raw)
+		if err != nil {
+			return nil, fmt.Errorf("malformed hex input: %w", err)
+		}
+		return data, nil
+	case encodingBase64:
+		data, err := decodeBase64(raw)
+		if err != nil {
+			return nil, fmt.Errorf("malformed base64 input: %w", err)
+		}
+		return data, nil
+	default:
+		return raw, nil
+	}
+}
+
+// decodeHex decodes hex digits separated by any ASCII whitespace.
+func decodeHex(raw []byte) ([]byte, error) {
+	text, offsets := stripWhitespace(raw)
+	for i, c := range text {
+		switch {
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+		default:
+			return nil, fmt.Errorf("invalid character %q at byte offset %d", c, offsets[i])
+		}
+	}
+	if len(text)%2 != 0 {
+		return nil, fmt.Errorf("odd number of hex digits (%d)", len(text))
+	}
+	data := make([]byte, len(text)/2)
+	_, err := hex.Decode(data, text)
+	return data, err
+}
+
+// decodeBase64 decodes standard base64 separated by any ASCII whitespace,
+// with or without trailing padding.
+func decodeBase64(raw []byte) ([]byte, error) {
+	text, offsets := stripWhitespace(raw)
+	text = bytes.TrimRight(text, "=")
+	data := make([]byte, base64.RawStdEncoding.DecodedLen(len(text)))
+	n, err := base64.RawStdEncoding.Decode(data, text)
+	// A single character left over carries only 6 bits, less than a byte;
+	// the decoder reports it as corrupt.
+	truncated := len(text)%4 == 1
+	var corrupt base64.CorruptInputError
+	if errors.As(err, &corrupt) && int(corrupt) < len(text) && !(truncated && int(corrupt) == len(text)-1) {
+		return nil, fmt.Errorf("invalid character %q at byte offset %d", text[corrupt], offsets[corrupt])
+	}
+	if truncated {
+		return nil, fmt.Errorf("truncated input of %d base64 characters", len(text))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+// stripWhitespace returns raw with all ASCII whitespace removed, and the
+// offset in raw of each byte it kept.
+func stripWhitespace(raw []byte) (text []byte, offsets []int) {
+	text = make([]byte, 0, len(raw))
+	offsets = make([]int, 0, len(raw))
+	for i, c := range raw {
+		switch c {
+		case ' ', '\t', '\n', '\v', '\f', '\r':
+			continue
+		}
+		text = append(text, c)
+		offsets = append(offsets, i)
+	}
+	return text, offsets
+}
+
+// extractColumn parses a delimited text file and returns the integer samples
+// of the 1-based column as one symbol per byte. An empty delimiter splits on
+// runs of whitespace. Blank lines and lines starting with '#' are skipped.
+// Every value must fit in bits per symbol, or in a byte when bits is 0.
+func extractColumn(raw []byte, column int, delimiter string, bits int) ([]byte, error) {
+	if bits == 0 {
+		bits = 8
+	}
+	maxValue := uint64(1)<<uint(bits) - 1
+
+	var samples []byte
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var fields []string
+		if delimiter == "" {
+			fields = strings.Fields(line)
+		} else {
+			fields = strings.Split(line, delimiter)
+		}
+		if column > len(fields) {
+			return nil, fmt.Errorf("line %d: has %d columns, cannot select column %d", lineNo, len(fields), column)
+		}
+
+		field := strings.TrimSpace(fields[column-1])
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d, column %d: %q is not a non-negative integer", lineNo, column, field)
+		}
+		if value > maxValue {
+			return nil, fmt.Errorf("line %d, column %d: value %d does not fit in %d bits per symbol", lineNo, column, value, bits)
+		}
+		samples = append(samples, byte(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", lineNo+1, err)
+	}
+	return samples, nil
+}
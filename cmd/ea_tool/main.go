@@ -6,7 +6,10 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
 )
 
 const (
@@ -14,28 +17,122 @@ const (
 )
 
 // JSONOutput represents the structured JSON output of an entropy assessment,
-// including entropy estimates, metadata, and any error information.
+// including entropy estimates, metadata, and any error information. Its
+// layout is described by the JSON Schema in internal/schema; SchemaVersion
+// changes whenever a field does.
 type JSONOutput struct {
-	Version       string  `json:"version"`
-	Filename      string  `json:"filename"`
-	TestType      string  `json:"test_type"`
-	BitsPerSymbol int     `json:"bits_per_symbol"`
-	DataSize      int     `json:"data_size"`
-	MinEntropy    float64 `json:"min_entropy"`
-	HOriginal     float64 `json:"h_original,omitempty"`
-	HBitstring    float64 `json:"h_bitstring,omitempty"`
-	HAssessed     float64 `json:"h_assessed"`
-	ErrorCode     int     `json:"error_code"`
-	ErrorMessage  string  `json:"error_message,omitempty"`
+	SchemaVersion     string                  `json:"schema_version"`
+	Version           string                  `json:"version"`
+	GeneratedAt       string                  `json:"generated_at,omitempty"`
+	Filename          string                  `json:"filename"`
+	Path              string                  `json:"path,omitempty"`
+	TestType          string                  `json:"test_type"`
+	BitsPerSymbol     int                     `json:"bits_per_symbol"`
+	DataSize          int                     `json:"data_size"`
+	DataSHA256        string                  `json:"data_sha256,omitempty"`
+	Section           *SectionOutput          `json:"section,omitempty"`
+	MinEntropy        float64                 `json:"min_entropy"`
+	HOriginal         float64                 `json:"h_original,omitempty"`
+	HBitstring        float64                 `json:"h_bitstring,omitempty"`
+	HAssessed         float64                 `json:"h_assessed"`
+	ShannonEntropy    *float64                `json:"shannon_entropy,omitempty"`
+	AlphabetSize      int                     `json:"alphabet_size,omitempty"`
+	LRSLength         *int                    `json:"lrs_length,omitempty"`
+	HSubmitter        *float64                `json:"h_submitter,omitempty"`
+	HFinal            float64                 `json:"h_final"`
+	Conditioning      *ConditioningOutput     `json:"conditioning,omitempty"`
+	Restart           *RestartOutput          `json:"restart,omitempty"`
+	SubmitterBinding  bool                    `json:"submitter_binding"`
+	Estimators        []string                `json:"estimators,omitempty"`
+	EstimatorResults  []EstimatorResultOutput `json:"estimator_results,omitempty"`
+	Partial           bool                    `json:"partial,omitempty"`
+	Backend           string                  `json:"backend,omitempty"`
+	BitOrder          string                  `json:"bit_order,omitempty"`
+	PermutationRounds int                     `json:"permutation_rounds,omitempty"`
+	Threshold         *float64                `json:"threshold,omitempty"`
+	Passed            *bool                   `json:"passed,omitempty"`
+	IIDCheckPassed    *bool                   `json:"iid_check_passed,omitempty"`
+	Tests             []TestOutput            `json:"tests,omitempty"`
+	Histogram         []uint64                `json:"histogram,omitempty"`
+	Raw               json.RawMessage         `json:"raw,omitempty"`
+	StartedAt         string                  `json:"started_at,omitempty"`
+	FinishedAt        string                  `json:"finished_at,omitempty"`
+	DurationMS        int64                   `json:"duration_ms"`
+	TimingsMS         map[string]float64      `json:"timings_ms,omitempty"`
+	ErrorCode         int                     `json:"error_code"`
+	ErrorMessage      string                  `json:"error_message,omitempty"`
+
+	result *entropy.Result // the assessment, for -format nist-json
+}
+
+// SectionOutput is the window of the decoded samples that was assessed when
+// -offset, -length, -max-bytes, -sample-bytes or -stride is given. InputSize
+// is the number of decoded samples the window was taken from, and Stride,
+// when above 1, the step between the samples of the window that were
+// assessed.
+type SectionOutput struct {
+	Offset    int64 `json:"offset"`
+	Length    int64 `json:"length"`
+	InputSize int64 `json:"input_size"`
+	Stride    int   `json:"stride,omitempty"`
+}
+
+// TestOutput is the outcome of a single IID statistical test in -iid-check
+// mode. ID is the stable estimator identifier, such as "chi-square", or
+// "unknown" for a test this version does not know.
+type TestOutput struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+}
+
+// EstimatorResultOutput is the result of a single estimator or test of an
+// assessment. EntropyEstimate is -1 when IsEntropyValid is false, as for the
+// IID pass/fail tests.
+type EstimatorResultOutput struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	EntropyEstimate float64 `json:"entropy_estimate"`
+	Passed          bool    `json:"passed"`
+	IsEntropyValid  bool    `json:"is_entropy_valid"`
 }
 
 func main() {
 	os.Exit(runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
 
-// writeJSON serializes data as indented JSON and writes it to the specified file.
-// On failure, an error message is printed to stderr and the process exits.
-func writeJSON(filename string, data interface{}) {
+// stdoutName is the -output value that writes the results to stdout. The
+// text that would otherwise go to stdout is then written to stderr.
+const stdoutName = "-"
+
+// namedOutput reports whether the -output value outputFile names a file, as
+// opposed to stdout.
+func namedOutput(outputFile string) bool {
+	return outputFile != "" && outputFile != stdoutName
+}
+
+// outputWriters returns the writers of the results document and of the text
+// of a run with the -output value outputFile. With "-" the document takes
+// stdout and the text goes to stderr, so that stdout can be piped.
+func outputWriters(outputFile string, stdout, stderr io.Writer) (doc, text io.Writer) {
+	if outputFile == stdoutName {
+		return stdout, stderr
+	}
+	return stdout, stdout
+}
+
+// writeJSON serializes data as indented JSON and writes it to the specified
+// file, or to stdout when filename is "-". On failure, an error message is
+// printed to stderr and the process exits.
+func writeJSON(filename string, stdout io.Writer, data any) {
+	if filename == stdoutName {
+		if err := encodeJSON(stdout, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	file, err := os.Create(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
@@ -43,10 +140,20 @@ func writeJSON(filename string, data interface{}) {
 	}
 	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(data); err != nil {
+	if err := encodeJSON(file, data); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// encodeJSON writes data to w as indented JSON followed by a newline. The
+// output is byte-for-byte reproducible for equal data: struct fields are
+// written in declaration order, which for JSONOutput is the order of the
+// schema, map keys are sorted, and characters such as < and & are written
+// as they are rather than escaped for HTML.
+func encodeJSON(w io.Writer, data any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(data)
+}
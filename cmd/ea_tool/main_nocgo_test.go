@@ -0,0 +1,57 @@
+//go:build (nocgo || !cgo) && !teststub
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCLI_VersionReportsPureGo(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-version"}, bytes.NewReader(nil), &out, &out)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "Backend: go\n")
+	assert.Contains(t, out.String(), "IID estimators: mcv, chi-square, permutation\n")
+	assert.NotContains(t, out.String(), "markov")
+	assert.Contains(t, out.String(), "WARNING: pure-Go build")
+
+	out.Reset()
+	code = runCLI([]string{"-version", "-json"}, bytes.NewReader(nil), &out, &out)
+	require.Equal(t, 0, code)
+
+	var got versionOutput
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	assert.False(t, got.Library.CGO)
+	assert.Equal(t, "go", got.Library.Backend)
+	assert.NotContains(t, got.Library.NonIIDEstimators, "compression")
+}
+
+func TestRunCLI_PureGoResultIsMarked(t *testing.T) {
+	var out bytes.Buffer
+	data := bytes.Repeat([]byte{0, 1, 2, 3, 1, 2}, 200)
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+
+	code := runCLI([]string{"-non-iid", "-bits", "2", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code)
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, "go", got.Backend)
+	assert.True(t, got.Partial)
+}
+
+func TestRunCLI_ValidateNeedsNISTLibrary(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"validate", "-bits", "4"}, bytes.NewReader([]byte{0, 1, 2, 3}), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "cross-validation needs the NIST library")
+}
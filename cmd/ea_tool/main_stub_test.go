@@ -3,14 +3,40 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/klauspost/compress/zstd"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/schema"
+	"github.com/AmmannChristian/nist-800-90b/internal/service"
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
 )
 
 func TestRunCLI_StdinSuccessWithStub(t *testing.T) {
@@ -19,6 +45,7 @@ func TestRunCLI_StdinSuccessWithStub(t *testing.T) {
 	code := runCLI([]string{"-non-iid", "-bits", "8"}, bytes.NewReader(data), &out, &out)
 	assert.Equal(t, 0, code)
 	assert.Contains(t, out.String(), "Entropy Assessment Results")
+	assert.Contains(t, out.String(), "Shannon Entropy: 2.000000 (baseline, not an SP 800-90B estimate)\n")
 }
 
 func TestRunCLI_OutputFileSuccess(t *testing.T) {
@@ -38,6 +65,354 @@ func TestRunCLI_OutputFileSuccess(t *testing.T) {
 	assert.Equal(t, "Non-IID", got.TestType)
 	assert.Equal(t, 0, got.ErrorCode)
 	assert.Equal(t, len(data), got.DataSize)
+	assert.Equal(t, "stub", got.Backend)
+	require.NotNil(t, got.ShannonEntropy)
+	assert.InDelta(t, 2.0, *got.ShannonEntropy, 1e-12, "four distinct values")
+	assert.Equal(t, 4, got.AlphabetSize)
+}
+
+func TestRunCLI_OutputStdout(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.bin")
+	second := filepath.Join(dir, "b.bin")
+	data := []byte{1, 2, 3, 4}
+	require.NoError(t, os.WriteFile(first, data, 0o600))
+	require.NoError(t, os.WriteFile(second, []byte{5, 6, 7, 8}, 0o600))
+
+	// stdout holds the document alone; the text goes to stderr.
+	var stdout, stderr bytes.Buffer
+	before := time.Now().UTC().Truncate(time.Second)
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-output", "-", first}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.NotContains(t, stderr.String(), "Results written")
+
+	var got JSONOutput
+	dec := json.NewDecoder(&stdout)
+	dec.DisallowUnknownFields()
+	require.NoError(t, dec.Decode(&got))
+	assert.False(t, dec.More(), "a single document on stdout")
+	assert.Equal(t, schema.Version, got.SchemaVersion)
+	assert.Equal(t, sha256Hex(data), got.DataSHA256)
+	generated, err := time.Parse(time.RFC3339, got.GeneratedAt)
+	require.NoError(t, err)
+	assert.False(t, generated.Before(before))
+	assert.True(t, strings.HasSuffix(got.GeneratedAt, "Z"), got.GeneratedAt)
+
+	// Several inputs give an array.
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-output", "-", first, second}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	var docs []JSONOutput
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &docs))
+	require.Len(t, docs, 2)
+	assert.Equal(t, sha256Hex([]byte{5, 6, 7, 8}), docs[1].DataSHA256)
+
+	// The subcommands move their text to stderr as well.
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"capture", "-device", first, "-samples", "4", "-non-iid", "-bits", "8", "-output", "-"}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stderr.String(), "Captured 4 samples from "+first)
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &got))
+	assert.Equal(t, first, got.Filename)
+
+	// The other formats take "-" as well.
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-format", "yaml", "-output", "-", first}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "data_sha256: "+sha256Hex(data))
+}
+
+func TestRunCLI_HistogramInJSON(t *testing.T) {
+	var out bytes.Buffer
+	data := []byte{5, 5, 6, 0x15}
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+
+	code := runCLI([]string{"-non-iid", "-bits", "4", "-histogram", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code)
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.Len(t, got.Histogram, 256)
+	assert.Equal(t, uint64(3), got.Histogram[5])
+	assert.Equal(t, uint64(1), got.Histogram[6])
+
+	code = runCLI([]string{"-non-iid", "-bits", "4", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code)
+	raw, err = os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "histogram")
+}
+
+func TestRunCLI_RawJSON(t *testing.T) {
+	var out bytes.Buffer
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-raw", "-output", tmpFile}, bytes.NewReader([]byte{1, 2, 3, 4}), &out, &out)
+	require.Equal(t, 0, code)
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.NotEmpty(t, got.Raw)
+	assert.True(t, json.Valid(got.Raw))
+	var doc struct{ IID bool }
+	require.NoError(t, json.Unmarshal(got.Raw, &doc))
+	assert.False(t, doc.IID)
+
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-output", tmpFile}, bytes.NewReader([]byte{1, 2, 3, 4}), &out, &out)
+	require.Equal(t, 0, code)
+	raw, err = os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), `"raw"`)
+
+	var stdout, stderr bytes.Buffer
+	code = runCLI([]string{"-iid", "-bits", "8", "-raw"}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "NIST Tool JSON:\n{\n   \"IID\" : true,")
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunCLI_Duration(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-bits", "8"}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.Regexp(t, `\nElapsed: \S+\n$`, stdout.String())
+
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+	stdout.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-output", tmpFile}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.NotContains(t, stdout.String(), "Elapsed")
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"duration_ms": `)
+}
+
+func TestRunCLI_HistogramText(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	data := []byte{1, 1, 1, 2}
+	code := runCLI([]string{"-non-iid", "-bits", "2", "-histogram"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Symbol Histogram:\n  0x01:          3   75.000%\n  0x02:          1   25.000%\n")
+	assert.NotContains(t, stdout.String(), "0x00:")
+	assert.Empty(t, stderr.String())
+
+	// A symbol wider than -bits is reported and counted masked.
+	stdout.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "2", "-histogram"}, bytes.NewReader([]byte{1, 1, 1, 6}), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.Contains(t, stderr.String(), "Warning: SymbolHistogram: symbol 0x06 at index 3 does not fit in 2 bits per symbol")
+	assert.Contains(t, stdout.String(), "  0x02:          1   25.000%\n")
+}
+
+func TestRunCLI_WindowInJSON(t *testing.T) {
+	var out bytes.Buffer
+	data := []byte{0xFF, 7, 7, 8, 0xFF}
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-offset", "1", "-length", "3", "-histogram", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code)
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.NotNil(t, got.Section)
+	assert.Equal(t, SectionOutput{Offset: 1, Length: 3, InputSize: 5}, *got.Section)
+	assert.Equal(t, 3, got.DataSize)
+	assert.Equal(t, uint64(2), got.Histogram[7])
+	assert.Zero(t, got.Histogram[0xFF])
+
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-offset", "3", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code)
+	raw, err = os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, SectionOutput{Offset: 3, Length: 2, InputSize: 5}, *got.Section)
+}
+
+func TestRunCLI_SkipAndMaxBytes(t *testing.T) {
+	var out bytes.Buffer
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+	readSection := func(t *testing.T) (int, SectionOutput) {
+		t.Helper()
+		raw, err := os.ReadFile(tmpFile)
+		require.NoError(t, err)
+		var got JSONOutput
+		require.NoError(t, json.Unmarshal(raw, &got))
+		require.NotNil(t, got.Section)
+		return got.DataSize, *got.Section
+	}
+
+	// A 4-byte header and a 2-byte trailer around 6 samples.
+	data := []byte{0xFF, 0xFF, 0xFF, 0xFF, 1, 2, 3, 4, 5, 6, 0xAA, 0xBB}
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-skip-bytes", "4", "-max-bytes", "6", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code, out.String())
+	size, section := readSection(t)
+	assert.Equal(t, 6, size)
+	assert.Equal(t, SectionOutput{Offset: 4, Length: 6, InputSize: 12}, section)
+
+	// -max-bytes caps the window but does not require that much input.
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-skip-bytes", "4", "-max-bytes", "100", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code, out.String())
+	_, section = readSection(t)
+	assert.Equal(t, SectionOutput{Offset: 4, Length: 8, InputSize: 12}, section)
+
+	// The skip applies to the decoded samples.
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-input-encoding", "hex", "-skip-bytes", "4", "-max-bytes", "6", "-output", tmpFile},
+		strings.NewReader(hex.EncodeToString(data)), &out, &out)
+	require.Equal(t, 0, code, out.String())
+	size, section = readSection(t)
+	assert.Equal(t, 6, size)
+	assert.Equal(t, SectionOutput{Offset: 4, Length: 6, InputSize: 12}, section)
+
+	// Skipping past the end of the input fails.
+	out.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-skip-bytes", "12"}, bytes.NewReader(data), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "Error selecting window of stdin")
+}
+
+func TestRunCLI_SampleBytes(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+	readResult := func(t *testing.T) JSONOutput {
+		t.Helper()
+		raw, err := os.ReadFile(tmpFile)
+		require.NoError(t, err)
+		var got JSONOutput
+		require.NoError(t, json.Unmarshal(raw, &got))
+		return got
+	}
+
+	// Only the first four samples are assessed, and the word size is
+	// detected on them.
+	data := []byte{1, 0, 1, 1, 0xAA, 0xBB, 0xCC, 0xDD}
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-sample-bytes", "4", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	got := readResult(t)
+	assert.Equal(t, 4, got.DataSize)
+	assert.Equal(t, 6.5, got.MinEntropy)
+	require.NotNil(t, got.Section)
+	assert.Equal(t, SectionOutput{Offset: 0, Length: 4, InputSize: 8}, *got.Section)
+	assert.Contains(t, stderr.String(), "Warning: -sample-bytes assesses only the first 4 samples of each input; this is not a conforming SP 800-90B assessment\n")
+
+	code = runCLI([]string{"-non-iid", "-sample-bytes", "4"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "  Bits/Symbol:     1\n")
+
+	// The limit applies after -offset, and to both tracks of -all.
+	stderr.Reset()
+	code = runCLI([]string{"-all", "-bits", "8", "-offset", "2", "-sample-bytes", "3", "-verbose", "0", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var all AllOutput
+	require.NoError(t, json.Unmarshal(raw, &all))
+	assert.Equal(t, 3, all.DataSize)
+	require.NotNil(t, all.IID)
+	assert.Equal(t, 7.5, all.IID.MinEntropy)
+	assert.Equal(t, SectionOutput{Offset: 2, Length: 3, InputSize: 8}, *all.NonIID.Section)
+	assert.Empty(t, stderr.String(), "-verbose 0 drops the warning")
+
+	// Shorter inputs are assessed whole.
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-sample-bytes", "100", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, 8, readResult(t).DataSize)
+}
+
+func TestRunCLI_Stride(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+	readResult := func(t *testing.T) JSONOutput {
+		t.Helper()
+		raw, err := os.ReadFile(tmpFile)
+		require.NoError(t, err)
+		var got JSONOutput
+		require.NoError(t, json.Unmarshal(raw, &got))
+		return got
+	}
+
+	// Stride 2 keeps the samples at even indexes, 0 and 1, so the word
+	// size detected on them is 1 and 0xAA at the odd ones is skipped.
+	data := []byte{1, 0xAA, 0, 0xAA, 1, 0xAA, 1, 0xAA, 0}
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-stride", "2", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	got := readResult(t)
+	assert.Equal(t, 5, got.DataSize)
+	require.NotNil(t, got.Section)
+	assert.Equal(t, SectionOutput{Offset: 0, Length: 9, InputSize: 9, Stride: 2}, *got.Section)
+	assert.Contains(t, stderr.String(), "Warning: -stride assesses only one in every 2 samples of each input; this is not a conforming SP 800-90B assessment\n")
+
+	code = runCLI([]string{"-non-iid", "-stride", "2"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "  Bits/Symbol:     1\n")
+
+	// The stride applies to the window, after -offset and -sample-bytes.
+	code = runCLI([]string{"-iid", "-bits", "8", "-offset", "1", "-sample-bytes", "6", "-stride", "2", "-verbose", "0", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	got = readResult(t)
+	assert.Equal(t, 3, got.DataSize)
+	assert.Equal(t, SectionOutput{Offset: 1, Length: 6, InputSize: 9, Stride: 2}, *got.Section)
+
+	// A stride of 1 assesses every sample and records no section.
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-stride", "1", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	got = readResult(t)
+	assert.Equal(t, 9, got.DataSize)
+	assert.Nil(t, got.Section)
+}
+
+func TestRunCLI_BitOrder(t *testing.T) {
+	var out bytes.Buffer
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-bit-order", "lsb", "-output", tmpFile}, bytes.NewReader([]byte{1, 2, 3, 4}), &out, &out)
+	require.Equal(t, 0, code)
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, "lsb", got.BitOrder)
+	assert.Less(t, got.HBitstring, 6.1)
+
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-output", tmpFile}, bytes.NewReader([]byte{1, 2, 3, 4}), &out, &out)
+	require.Equal(t, 0, code)
+	raw, err = os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "bit_order")
+}
+
+func TestRunCLI_PermutationRounds(t *testing.T) {
+	var out bytes.Buffer
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+
+	code := runCLI([]string{"-iid-check", "-bits", "8", "-permutation-rounds", "500", "-permutation-seed", "1"}, bytes.NewReader([]byte{1, 2, 3, 4}), &out, &out)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "500 (non-conforming)")
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-permutation-rounds", "500", "-output", tmpFile}, bytes.NewReader([]byte{1, 2, 3, 4}), &out, &out)
+	require.Equal(t, 0, code)
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, 500, got.PermutationRounds)
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-permutation-seed", "1"}, bytes.NewReader([]byte{1, 2, 3, 4}), &out, &out)
+	require.Equal(t, 0, code)
+	assert.NotContains(t, out.String(), "non-conforming")
 }
 
 func TestRunCLI_IIDModeSuccess(t *testing.T) {
@@ -49,3 +424,1733 @@ func TestRunCLI_IIDModeSuccess(t *testing.T) {
 	assert.Contains(t, out.String(), "Test Type:       IID")
 	assert.Contains(t, out.String(), "H_bitstring")
 }
+
+func TestRunCLI_TimeoutExceeded(t *testing.T) {
+	var out bytes.Buffer
+	data := []byte{0xCC, 1, 2, 3}
+
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-timeout", "50ms"}, bytes.NewReader(data), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "assessment exceeded its time limit")
+}
+
+func TestRunCLI_HSubmitterComparison(t *testing.T) {
+	var out bytes.Buffer
+	data := []byte{1, 2, 3, 4}
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-h-submitter", "3"}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "H_submitter:     3.000000")
+	assert.Contains(t, out.String(), "min(H_original, 8 X H_bitstring, H_submitter): 3.000000")
+	assert.Contains(t, out.String(), "Binding:         H_submitter")
+
+	out.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-h-submitter", "3", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code)
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.NotNil(t, got.HSubmitter)
+	assert.Equal(t, 3.0, *got.HSubmitter)
+	assert.Equal(t, 3.0, got.HFinal)
+	assert.True(t, got.SubmitterBinding)
+}
+
+func TestRunCLI_EncodedInputMatchesBinary(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 250, 251, 252, 253}
+	inputs := map[string][]byte{
+		"binary": data,
+		"hex":    []byte(hex.EncodeToString(data[:4]) + "\n" + strings.ToUpper(hex.EncodeToString(data[4:])) + "\n"),
+		"base64": []byte(base64.StdEncoding.EncodeToString(data) + "\n"),
+	}
+
+	results := make(map[string]JSONOutput)
+	for encoding, input := range inputs {
+		var out bytes.Buffer
+		tmpFile := filepath.Join(t.TempDir(), "result.json")
+
+		code := runCLI([]string{"-non-iid", "-bits", "8", "-input-encoding", encoding, "-output", tmpFile}, bytes.NewReader(input), &out, &out)
+		require.Equal(t, 0, code, encoding)
+
+		raw, err := os.ReadFile(tmpFile)
+		require.NoError(t, err)
+
+		var got JSONOutput
+		require.NoError(t, json.Unmarshal(raw, &got))
+		// The digest is of the input as read, before decoding.
+		assert.Equal(t, sha256Hex(input), got.DataSHA256, encoding)
+		got.DurationMS, got.TimingsMS, got.GeneratedAt, got.DataSHA256 = 0, nil, "", ""
+		results[encoding] = got
+	}
+
+	assert.Equal(t, len(data), results["hex"].DataSize)
+	assert.Equal(t, results["hex"], results["base64"])
+	assert.Equal(t, results["binary"], results["hex"])
+}
+
+func TestRunCLI_GzipInputMatchesPlain(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte{1, 2, 3, 4, 1, 2, 3, 1, 2, 1}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	plain := filepath.Join(dir, "data.bin")
+	compressed := filepath.Join(dir, "data.bin.gz")
+	require.NoError(t, os.WriteFile(plain, data, 0o644))
+	require.NoError(t, os.WriteFile(compressed, buf.Bytes(), 0o644))
+
+	results := make([]JSONOutput, 2)
+	for i, path := range []string{plain, compressed} {
+		var out bytes.Buffer
+		tmpFile := filepath.Join(dir, "result.json")
+		code := runCLI([]string{"-non-iid", "-bits", "8", "-histogram", "-output", tmpFile, path}, bytes.NewReader(nil), &out, &out)
+		require.Equal(t, 0, code, out.String())
+
+		raw, err := os.ReadFile(tmpFile)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(raw, &results[i]))
+		results[i].Filename = ""
+		results[i].DurationMS, results[i].TimingsMS, results[i].GeneratedAt = 0, nil, ""
+	}
+	assert.Equal(t, len(data), results[1].DataSize)
+	assert.Equal(t, results[0], results[1])
+}
+
+func TestRunCLI_ZstdInputMatchesPlain(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte{1, 2, 3, 4, 1, 2, 3, 1, 2, 1}
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	plain := filepath.Join(dir, "data.bin")
+	compressed := filepath.Join(dir, "data.bin.zst")
+	require.NoError(t, os.WriteFile(plain, data, 0o644))
+	require.NoError(t, os.WriteFile(compressed, buf.Bytes(), 0o644))
+
+	results := make([]JSONOutput, 2)
+	for i, path := range []string{plain, compressed} {
+		var out bytes.Buffer
+		tmpFile := filepath.Join(dir, "result.json")
+		code := runCLI([]string{"-non-iid", "-bits", "8", "-histogram", "-output", tmpFile, path}, bytes.NewReader(nil), &out, &out)
+		require.Equal(t, 0, code, out.String())
+
+		raw, err := os.ReadFile(tmpFile)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(raw, &results[i]))
+		results[i].Filename = ""
+		results[i].DurationMS, results[i].TimingsMS, results[i].GeneratedAt = 0, nil, ""
+	}
+	assert.Equal(t, results[0], results[1])
+}
+
+func TestRunCLI_CompressedInputStopsAtWindow(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte{1, 2, 3, 4}, 100)
+	compressed := filepath.Join(dir, "data.bin.gz")
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(compressed, buf.Bytes(), 0o644))
+
+	run := func(args ...string) JSONOutput {
+		t.Helper()
+		tmpFile := filepath.Join(dir, "result.json")
+		var out bytes.Buffer
+		code := runCLI(append(append([]string{"-non-iid", "-bits", "8", "-output", tmpFile}, args...), compressed), bytes.NewReader(nil), &out, &out)
+		require.Equal(t, 0, code, out.String())
+		var got JSONOutput
+		require.NoError(t, json.Unmarshal(mustReadFile(t, tmpFile), &got))
+		require.NotNil(t, got.Section)
+		return got
+	}
+
+	// Only the samples up to the end of the window are decompressed.
+	got := run("-offset", "8", "-max-bytes", "20")
+	assert.Equal(t, SectionOutput{Offset: 8, Length: 20, InputSize: 28}, *got.Section)
+	got = run("-max-bytes", "50", "-sample-bytes", "10")
+	assert.Equal(t, SectionOutput{Offset: 0, Length: 10, InputSize: 10}, *got.Section)
+	got = run("-length", "50", "-sample-bytes", "10")
+	assert.Equal(t, SectionOutput{Offset: 0, Length: 10, InputSize: 50}, *got.Section)
+
+	// Without an end all of it is.
+	got = run("-offset", "8")
+	assert.Equal(t, SectionOutput{Offset: 8, Length: 392, InputSize: 400}, *got.Section)
+}
+
+func TestRunCLI_Archive(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{"a.bin", []byte{1, 2, 3, 4}},
+		{"empty.bin", nil},
+		{"sub/b.bin", []byte{4, 3, 2, 1}},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(f.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+	archive := filepath.Join(dir, "captures.tar.gz")
+	require.NoError(t, os.WriteFile(archive, buf.Bytes(), 0o644))
+
+	var zipBuf bytes.Buffer
+	zipWriter := zip.NewWriter(&zipBuf)
+	w, err := zipWriter.Create("c.bin")
+	require.NoError(t, err)
+	_, err = w.Write([]byte{5, 6, 7, 8})
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+	zipArchive := filepath.Join(dir, "more.zip")
+	require.NoError(t, os.WriteFile(zipArchive, zipBuf.Bytes(), 0o644))
+
+	corrupted := filepath.Join(dir, "corrupted.tar.gz")
+	require.NoError(t, os.WriteFile(corrupted, buf.Bytes()[:len(buf.Bytes())/2], 0o644))
+
+	output := filepath.Join(dir, "results.json")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-archive", "-output", output, archive, corrupted, zipArchive}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+
+	var got []JSONOutput
+	require.NoError(t, json.Unmarshal(mustReadFile(t, output), &got))
+	var names []string
+	for _, r := range got {
+		names = append(names, r.Filename)
+	}
+	assert.Equal(t, []string{archive + "!a.bin", archive + "!empty.bin", archive + "!sub/b.bin", corrupted, zipArchive + "!c.bin"}, names)
+
+	// The empty member and the corrupted archive fail on their own.
+	assert.Zero(t, got[0].ErrorCode)
+	assert.Equal(t, 6.5, got[0].MinEntropy)
+	assert.Equal(t, 1, got[1].ErrorCode)
+	assert.Zero(t, got[2].ErrorCode)
+	assert.Equal(t, 1, got[3].ErrorCode)
+	assert.Contains(t, got[3].ErrorMessage, "not a valid tar or zip archive")
+	assert.Zero(t, got[4].ErrorCode)
+	assert.Contains(t, stderr.String(), "Error reading file "+corrupted)
+
+	// A plain file is not an archive.
+	plain := filepath.Join(dir, "data.bin")
+	require.NoError(t, os.WriteFile(plain, []byte{1, 2, 3, 4}, 0o644))
+	stderr.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-archive", plain}, nil, io.Discard, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "not a valid tar or zip archive")
+}
+
+func TestRunCLI_EstimatorSubset(t *testing.T) {
+	var out bytes.Buffer
+	data := []byte{1, 2, 3, 4}
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-estimators", "markov,mcv"}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "Min Entropy:     6.700000")
+	assert.Contains(t, out.String(), "Estimators:      mcv, markov (non-conforming subset)")
+
+	out.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-estimators", "markov,mcv", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+	require.Equal(t, 0, code)
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, []string{"mcv", "markov"}, got.Estimators)
+	assert.Equal(t, 6.7, got.MinEntropy)
+}
+
+func TestRunCLI_ColumnInput(t *testing.T) {
+	var out bytes.Buffer
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+	csv := []byte("1000,3\n1001,15\n1002,0\n1003,7\n")
+
+	code := runCLI([]string{"-non-iid", "-bits", "4", "-column", "2", "-output", tmpFile}, bytes.NewReader(csv), &out, &out)
+	require.Equal(t, 0, code)
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, 4, got.DataSize)
+	assert.Equal(t, 4, got.BitsPerSymbol)
+}
+
+func TestRunCLI_FailBelow(t *testing.T) {
+	data := []byte{1, 2, 3, 4}
+
+	readOutput := func(t *testing.T, path string) JSONOutput {
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var got JSONOutput
+		require.NoError(t, json.Unmarshal(raw, &got))
+		return got
+	}
+
+	t.Run("default", func(t *testing.T) {
+		var out bytes.Buffer
+		tmpFile := filepath.Join(t.TempDir(), "result.json")
+		code := runCLI([]string{"-non-iid", "-bits", "8", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+		require.Equal(t, 0, code)
+
+		got := readOutput(t, tmpFile)
+		assert.Nil(t, got.Threshold)
+		assert.Nil(t, got.Passed)
+	})
+
+	t.Run("zero disables the check", func(t *testing.T) {
+		var out bytes.Buffer
+		tmpFile := filepath.Join(t.TempDir(), "result.json")
+		code := runCLI([]string{"-non-iid", "-bits", "8", "-fail-below", "0", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+		require.Equal(t, 0, code)
+
+		got := readOutput(t, tmpFile)
+		assert.Nil(t, got.Threshold)
+		assert.Nil(t, got.Passed)
+	})
+
+	t.Run("pass", func(t *testing.T) {
+		var out bytes.Buffer
+		tmpFile := filepath.Join(t.TempDir(), "result.json")
+		code := runCLI([]string{"-non-iid", "-bits", "8", "-fail-below", "6.5", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+		require.Equal(t, 0, code)
+
+		got := readOutput(t, tmpFile)
+		require.NotNil(t, got.Threshold)
+		assert.Equal(t, 6.5, *got.Threshold)
+		require.NotNil(t, got.Passed)
+		assert.True(t, *got.Passed)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var out bytes.Buffer
+		tmpFile := filepath.Join(t.TempDir(), "result.json")
+		code := runCLI([]string{"-non-iid", "-bits", "8", "-fail-below", "7", "-output", tmpFile}, bytes.NewReader(data), &out, &out)
+		assert.Equal(t, 3, code)
+		assert.Contains(t, out.String(), "FAIL: min-entropy 6.500000 is below the required 7.000000 bits per symbol")
+
+		got := readOutput(t, tmpFile)
+		assert.Equal(t, 6.5, got.MinEntropy)
+		require.NotNil(t, got.Passed)
+		assert.False(t, *got.Passed)
+	})
+
+	t.Run("fail with text output", func(t *testing.T) {
+		var out bytes.Buffer
+		code := runCLI([]string{"-non-iid", "-bits", "8", "-fail-below", "7"}, bytes.NewReader(data), &out, &out)
+		assert.Equal(t, 3, code)
+		assert.Contains(t, out.String(), "Entropy Assessment Results")
+		assert.Contains(t, out.String(), "FAIL: min-entropy")
+	})
+}
+
+func TestRunCLI_IIDCheck(t *testing.T) {
+	t.Run("pass", func(t *testing.T) {
+		var out bytes.Buffer
+		code := runCLI([]string{"-iid-check", "-bits", "8"}, bytes.NewReader([]byte{1, 2, 3, 4}), &out, &out)
+		require.Equal(t, 0, code)
+		assert.Contains(t, out.String(), "IID Check Results")
+		assert.Contains(t, out.String(), "Permutation Tests:")
+		assert.NotContains(t, out.String(), "Most Common Value")
+		assert.Regexp(t, `IID assumption:\s+PASS`, out.String())
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var out bytes.Buffer
+		tmpFile := filepath.Join(t.TempDir(), "result.json")
+		code := runCLI([]string{"-iid-check", "-bits", "8", "-output", tmpFile}, bytes.NewReader([]byte{0xBB, 2, 3, 4}), &out, &out)
+		assert.Equal(t, 3, code)
+		assert.Contains(t, out.String(), "FAIL: data does not satisfy the IID assumption")
+
+		raw, err := os.ReadFile(tmpFile)
+		require.NoError(t, err)
+
+		var got JSONOutput
+		require.NoError(t, json.Unmarshal(raw, &got))
+		require.NotNil(t, got.IIDCheckPassed)
+		assert.False(t, *got.IIDCheckPassed)
+		assert.Equal(t, []TestOutput{
+			{ID: "chi-square", Name: "Chi-Square Tests", Passed: false},
+			{ID: "lrs", Name: "Length of Longest Repeated Substring Test", Passed: true},
+			{ID: "permutation", Name: "Permutation Tests", Passed: false},
+		}, got.Tests)
+	})
+
+	t.Run("assessment error", func(t *testing.T) {
+		var out bytes.Buffer
+		code := runCLI([]string{"-iid-check", "-bits", "8"}, bytes.NewReader([]byte{0xFF, 2}), &out, &out)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, out.String(), "stub failure")
+	})
+}
+
+func TestRunCLI_VersionReportsStub(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-version"}, bytes.NewReader(nil), &out, &out)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "NIST SP 800-90B tool stub")
+	assert.Contains(t, out.String(), "Backend: stub\n")
+	assert.Contains(t, out.String(), "IID estimators: mcv, chi-square, lrs, permutation\n")
+	assert.Contains(t, out.String(), "WARNING: stub build")
+
+	out.Reset()
+	code = runCLI([]string{"-version", "-json"}, bytes.NewReader(nil), &out, &out)
+	require.Equal(t, 0, code)
+
+	var got versionOutput
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	assert.Equal(t, version, got.Version)
+	assert.False(t, got.Library.CGO)
+	assert.Equal(t, "stub", got.Library.ToolVersion)
+	assert.Equal(t, "stub", got.Library.Backend)
+	assert.NotEmpty(t, got.Library.Estimators)
+	assert.Len(t, got.Library.NonIIDEstimators, 10)
+}
+
+func TestRunCLI_SelfTestFailsAgainstStub(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"selftest"}, bytes.NewReader(nil), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "Self-test FAILED")
+	assert.Contains(t, stderr.String(), "Most Common Value")
+}
+
+func TestRunCLI_JobsPreservesFileOrder(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 6; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("sample%d.bin", i))
+		require.NoError(t, os.WriteFile(name, bytes.Repeat([]byte{1, 2, 3, 4}, i+1), 0o644))
+		files = append(files, name)
+	}
+
+	var out bytes.Buffer
+	args := append([]string{"-non-iid", "-bits", "8", "-jobs", "4"}, files...)
+	code := runCLI(args, bytes.NewReader(nil), &out, &out)
+	require.Equal(t, 0, code)
+
+	text := out.String()
+	last := -1
+	for _, name := range files {
+		idx := strings.Index(text, "==> "+name+" <==")
+		require.GreaterOrEqual(t, idx, 0, "missing result for %s", name)
+		assert.Greater(t, idx, last, "result for %s out of order", name)
+		last = idx
+	}
+	assert.Equal(t, len(files), strings.Count(text, "Entropy Assessment Results"))
+
+	tmpFile := filepath.Join(dir, "results.json")
+	out.Reset()
+	args = append([]string{"-non-iid", "-bits", "8", "-jobs", "4", "-output", tmpFile}, files...)
+	code = runCLI(args, bytes.NewReader(nil), &out, &out)
+	require.Equal(t, 0, code)
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+
+	var got []JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.Len(t, got, len(files))
+	for i, res := range got {
+		assert.Equal(t, files[i], res.Filename)
+		assert.Equal(t, 4*(i+1), res.DataSize)
+		assert.Equal(t, 6.5, res.MinEntropy)
+	}
+}
+
+// hangingFiles writes n inputs on which the stub blocks until the
+// assessment's time limit or context ends it.
+func hangingFiles(t *testing.T, n int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	files := make([]string, n)
+	for i := range files {
+		files[i] = filepath.Join(dir, fmt.Sprintf("hang%d.bin", i))
+		require.NoError(t, os.WriteFile(files[i], []byte{0xCC, 1, 2, 3}, 0o644))
+	}
+	return files
+}
+
+func TestRunCLI_JobsRunConcurrently(t *testing.T) {
+	// Every file takes the 200ms time limit, so four of them take at least
+	// 800ms one after another and about 200ms on four workers.
+	files := hangingFiles(t, 4)
+	elapsed := func(jobs string) time.Duration {
+		var out bytes.Buffer
+		start := time.Now()
+		args := append([]string{"-non-iid", "-bits", "8", "-timeout", "200ms", "-jobs", jobs}, files...)
+		code := runCLI(args, bytes.NewReader(nil), &out, &out)
+		require.Equal(t, 1, code)
+		require.Equal(t, len(files), strings.Count(out.String(), "assessment exceeded its time limit"))
+		return time.Since(start)
+	}
+
+	sequential := elapsed("1")
+	parallel := elapsed("4")
+	assert.GreaterOrEqual(t, sequential, 800*time.Millisecond)
+	assert.Less(t, parallel, sequential/2, "sequential %s, parallel %s", sequential, parallel)
+}
+
+func TestRunBatch_Interrupted(t *testing.T) {
+	files := hangingFiles(t, 3)
+	inputs := make([]batchInput, len(files))
+	for i, name := range files {
+		inputs[i] = batchInput{path: name}
+	}
+	opts := &cliOptions{testType: entropy.NonIID, bits: 8, verbose: 1}
+	output := filepath.Join(t.TempDir(), "results.json")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var out bytes.Buffer
+	start := time.Now()
+	code := runBatch(ctx, opts, inputs, 1, output, &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Less(t, time.Since(start), 10*time.Second, "the running assessment is abandoned")
+	assert.Contains(t, out.String(), "Skipped "+files[1]+": interrupted before the assessment started")
+	assert.Contains(t, out.String(), "Skipped "+files[2])
+	assert.Contains(t, out.String(), "Interrupted: 2 of 3 files were not assessed")
+
+	raw, err := os.ReadFile(output)
+	require.NoError(t, err)
+	var got []JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.Len(t, got, len(files))
+	for i, res := range got {
+		assert.Equal(t, files[i], res.Filename)
+		assert.Equal(t, 1, res.ErrorCode)
+	}
+	assert.Contains(t, got[0].ErrorMessage, "deadline exceeded")
+	assert.Equal(t, "interrupted before the assessment started", got[2].ErrorMessage)
+}
+
+func TestRunCLI_BatchReportsFirstFailure(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.bin")
+	bad := filepath.Join(dir, "bad.bin")
+	require.NoError(t, os.WriteFile(good, []byte{1, 2, 3, 4}, 0o644))
+	require.NoError(t, os.WriteFile(bad, []byte{0xFF, 2, 3, 4}, 0o644))
+	missing := filepath.Join(dir, "missing.bin")
+
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-bits", "8", "-jobs", "2", good, bad, missing}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "stub failure")
+	assert.Contains(t, out.String(), "Error reading file "+missing)
+	assert.Equal(t, 1, strings.Count(out.String(), "Entropy Assessment Results"))
+}
+
+func TestRunCLI_Recursive(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "captures")
+	other := filepath.Join(root, "other")
+	for _, d := range []string{filepath.Join(dir, "sub"), filepath.Join(dir, ".cache"), other} {
+		require.NoError(t, os.MkdirAll(d, 0o755))
+	}
+	for name, data := range map[string][]byte{
+		"captures/a.bin":        {1, 2, 3, 4},
+		"captures/empty.bin":    {},
+		"captures/.d.bin":       {1, 2, 3},
+		"captures/sub/b.bin":    {4, 3, 2, 1},
+		"captures/sub/note.txt": {1},
+		"captures/.cache/c.bin": {1, 2},
+		"other/e.bin":           {5, 6, 7},
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), data, 0o600))
+	}
+	require.NoError(t, os.Symlink(other, filepath.Join(dir, "linked")))
+	require.NoError(t, os.Symlink(dir, filepath.Join(dir, "sub", "loop")))
+
+	run := func(t *testing.T, extra ...string) ([]JSONOutput, int) {
+		t.Helper()
+		output := filepath.Join(root, "results.json")
+		var out bytes.Buffer
+		code := runCLI(append(append([]string{"-non-iid", "-bits", "8", "-recursive", "-pattern", "*.bin", "-output", output}, extra...), dir), nil, &out, &out)
+		raw, err := os.ReadFile(output)
+		require.NoError(t, err, out.String())
+		var got []JSONOutput
+		require.NoError(t, json.Unmarshal(raw, &got))
+		return got, code
+	}
+	paths := func(results []JSONOutput) []string {
+		var p []string
+		for _, r := range results {
+			p = append(p, r.Path)
+		}
+		return p
+	}
+
+	// The empty file fails on its own without stopping the walk.
+	got, code := run(t)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, []string{"a.bin", "empty.bin", "sub/b.bin"}, paths(got))
+	assert.Equal(t, filepath.Join(dir, "sub", "b.bin"), got[2].Filename)
+	assert.Zero(t, got[0].ErrorCode)
+	assert.Equal(t, 6.5, got[0].MinEntropy)
+	assert.Equal(t, 1, got[1].ErrorCode)
+	assert.NotEmpty(t, got[1].ErrorMessage)
+	assert.Zero(t, got[2].ErrorCode)
+
+	got, _ = run(t, "-hidden")
+	assert.Equal(t, []string{".cache/c.bin", ".d.bin", "a.bin", "empty.bin", "sub/b.bin"}, paths(got))
+
+	// The loop back to the root is walked once.
+	got, _ = run(t, "-follow-symlinks")
+	assert.Equal(t, []string{"a.bin", "empty.bin", "linked/e.bin", "sub/b.bin"}, paths(got))
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema.AssessOutput()))
+	require.NoError(t, err)
+	c := jsonschema.NewCompiler()
+	require.NoError(t, c.AddResource("assess_output.schema.json", doc))
+	sch, err := c.Compile("assess_output.schema.json")
+	require.NoError(t, err)
+	raw, err := json.Marshal(got[0])
+	require.NoError(t, err)
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	require.NoError(t, err)
+	assert.NoError(t, sch.Validate(v))
+
+	var out bytes.Buffer
+	code = runCLI([]string{"-non-iid", "-recursive", "-pattern", "*.dat", dir}, nil, &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "Error: no input files found in "+dir)
+}
+
+func TestRunCLI_Monitor(t *testing.T) {
+	data := make([]byte, 2500)
+	for i := range data {
+		data[i] = byte(i * 7 % 251)
+	}
+	path := filepath.Join(t.TempDir(), "stream.bin")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	// Two complete windows: one is assessed while the other waits, the
+	// trailing 500 samples are not assessed.
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"monitor", "-h-submitter", "4", "-window", "1000", path}, bytes.NewReader(nil), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "window 0: min-entropy 6.500000\nwindow 1: min-entropy 6.500000\n")
+	assert.Contains(t, stdout.String(), "Monitored 2 windows of "+path+", 0 dropped, 0 health test failures")
+
+	// A stuck source fails the health tests, read from stdin.
+	for i := 1200; i < 1300; i++ {
+		data[i] = 0
+	}
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"monitor", "-h-submitter", "4", "-window", "1000", "-"}, bytes.NewReader(data), &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stderr.String(), "window 1: FAIL: repetition count test: sample 0x00 at index 1205")
+	assert.Contains(t, stdout.String(), "of stdin, 0 dropped, 1 health test failures")
+}
+
+func TestRunCLI_All(t *testing.T) {
+	dir := t.TempDir()
+	tmpFile := filepath.Join(dir, "all.json")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-all", "-bits", "8", "-output", tmpFile}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got AllOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, "IID and Non-IID", got.TestType)
+	assert.Equal(t, 4, got.DataSize)
+	assert.Zero(t, got.ErrorCode)
+	require.NotNil(t, got.IID)
+	require.NotNil(t, got.NonIID)
+	assert.Equal(t, "IID", got.IID.TestType)
+	assert.Equal(t, 7.5, got.IID.MinEntropy)
+	assert.Equal(t, "Non-IID", got.NonIID.TestType)
+	assert.Equal(t, 6.5, got.NonIID.MinEntropy)
+	require.NotNil(t, got.HAssessed)
+	assert.Equal(t, min(got.IID.HAssessed, got.NonIID.HAssessed), *got.HAssessed)
+	assert.Contains(t, got.IID.TimingsMS, "IID")
+	assert.Contains(t, got.NonIID.TimingsMS, "Non-IID")
+	require.Len(t, got.TimingsMS, 2)
+	assert.Equal(t, got.IID.TimingsMS["IID"], got.TimingsMS["IID"])
+	assert.Equal(t, got.NonIID.TimingsMS["Non-IID"], got.TimingsMS["Non-IID"])
+
+	// Without -output both result blocks and the overall value are printed;
+	// -iid -non-iid is the same as -all.
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"-iid", "-non-iid", "-bits", "8"}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Test Type:       IID\n")
+	assert.Contains(t, stdout.String(), "Test Type:       Non-IID\n")
+	assert.Contains(t, stdout.String(), "Overall H_assessed: ")
+
+	// -fail-below applies to the overall H_assessed, the Non-IID 6.5.
+	stdout.Reset()
+	code = runCLI([]string{"-all", "-bits", "8", "-fail-below", "7", "-output", tmpFile}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	raw, err = os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	got = AllOutput{}
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.NotNil(t, got.Threshold)
+	assert.Equal(t, 7.0, *got.Threshold)
+	require.NotNil(t, got.Passed)
+	assert.False(t, *got.Passed)
+
+	code = runCLI([]string{"-all", "-bits", "8", "-fail-below", "6.5", "-output", tmpFile}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	raw, err = os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	got = AllOutput{}
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.NotNil(t, got.Passed)
+	assert.True(t, *got.Passed)
+
+	// An assessment error takes precedence and is recorded in the document.
+	code = runCLI([]string{"-all", "-bits", "8", "-output", tmpFile}, bytes.NewReader([]byte{0xFF, 1, 2}), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	raw, err = os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	got = AllOutput{}
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, 1, got.ErrorCode)
+	assert.Nil(t, got.HAssessed)
+}
+
+func TestRunCLI_Compare(t *testing.T) {
+	dir := t.TempDir()
+	pathA := filepath.Join(dir, "run1.bin")
+	pathB := filepath.Join(dir, "run2.bin")
+	require.NoError(t, os.WriteFile(pathA, []byte{1, 2, 3, 4}, 0o600))
+	require.NoError(t, os.WriteFile(pathB, []byte{4, 3, 2, 1, 0}, 0o600))
+
+	// Both inputs yield the stub values, so every delta is zero.
+	var stdout, stderr bytes.Buffer
+	tmpFile := filepath.Join(dir, "compare.json")
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-compare", pathB, "-output", tmpFile, pathA}, bytes.NewReader(nil), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got CompareOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, "Non-IID", got.TestType)
+	assert.Equal(t, 0.1, got.Tolerance)
+	assert.True(t, got.Similar)
+	require.NotNil(t, got.Primary)
+	require.NotNil(t, got.Compare)
+	assert.Equal(t, pathA, got.Primary.Filename)
+	assert.Equal(t, 5, got.Compare.DataSize)
+	assert.Equal(t, 6.5, got.Compare.MinEntropy)
+	require.Len(t, got.Primary.Estimators, 10)
+	assert.Equal(t, "mcv", got.Primary.Estimators[0].ID)
+	require.Len(t, got.Deltas, 11, "ten estimators and the min-entropy")
+	for _, d := range got.Deltas {
+		assert.Zero(t, d.Delta, d.Name)
+		assert.True(t, d.Within, d.Name)
+	}
+	assert.Equal(t, CompareDelta{Name: "Min Entropy", Primary: 6.5, Compare: 6.5, Within: true}, got.Deltas[10])
+
+	// 0xBB makes the stub fail the Chi-Square and permutation tests.
+	require.NoError(t, os.WriteFile(pathB, []byte{0xBB, 3, 2, 1}, 0o600))
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-compare", pathB}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stdout.String(), "Comparison (IID, tolerance 0.1):\n")
+	assert.Contains(t, stdout.String(), "  Primary: stdin (4 samples)\n")
+	assert.Regexp(t, `  Most Common Value +7\.600000 +7\.600000 +\+0\.000000  ok\n`, stdout.String())
+	assert.Regexp(t, `  Chi-Square Tests +PASS +FAIL +-  DIFFERS\n`, stdout.String())
+	assert.Contains(t, stderr.String(), "FAIL: stdin and "+pathB+" differ by more than 0.1 bits per sample")
+}
+
+func TestRunCompareFiles_Outputs(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "data.bin")
+	require.NoError(t, os.WriteFile(input, []byte{1, 2, 3, 4}, 0o600))
+	baseline := filepath.Join(dir, "baseline.json")
+	current := filepath.Join(dir, "current.ndjson")
+
+	// The JSON and NDJSON results of the same -all assessment agree.
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-all", "-bits", "8", "-output", baseline, input}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	code = runCLI([]string{"-all", "-bits", "8", "-format", "ndjson", "-output", current, input}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	stdout.Reset()
+	code = runCLI([]string{"compare", "-tolerance", "0", baseline, current}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "==> "+input+" (IID) <==\n")
+	assert.Contains(t, stdout.String(), "==> "+input+" (Non-IID) <==\n")
+	assert.Contains(t, stdout.String(), "0 of 2 results differ\n")
+
+	// A Non-IID result misses the IID one.
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-output", current, input}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	stdout.Reset()
+	code = runCLI([]string{"compare", baseline, current}, nil, &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stdout.String(), "==> "+input+" (IID) <==\n  missing from the current file\n")
+}
+
+func TestRunCLI_EstimatorBreakdown(t *testing.T) {
+	data := make([]byte, 1000)
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-verbose", "2"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "\nEstimators:\n  Estimator                                    Result\n")
+	assert.Contains(t, stdout.String(), "  Most Common Value                          6.800000\n")
+	// Compression and LZ78Y tie at 6.5; the first one is marked.
+	assert.Contains(t, stdout.String(), "  Compression Test                           6.500000  <- min-entropy\n")
+	assert.Contains(t, stdout.String(), "  LZ78Y Test                                 6.500000\n")
+
+	stdout.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-verbose", "2"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "  Chi-Square Tests                               PASS\n")
+
+	// The table needs -verbose 2.
+	stdout.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.NotContains(t, stdout.String(), "Estimators:")
+
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+	code = runCLI([]string{"-iid", "-bits", "8", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.NotEmpty(t, got.EstimatorResults)
+	assert.Equal(t, EstimatorResultOutput{ID: "mcv", Name: "Most Common Value", EntropyEstimate: 7.6, Passed: true, IsEntropyValid: true}, got.EstimatorResults[0])
+	for _, est := range got.EstimatorResults {
+		if !est.IsEntropyValid {
+			assert.Equal(t, -1.0, est.EntropyEstimate, est.Name)
+		}
+	}
+}
+
+func TestRunCLI_Conditioning(t *testing.T) {
+	data := make([]byte, 1000)
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-conditioning", "512,256,256"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Vetted Conditioning (SP 800-90B Section 3.1.5.1.2):\n")
+	assert.Contains(t, stdout.String(), "  h_in:            416.000000\n")
+
+	// 64 samples of 6.5 bits each feed one 512-bit input.
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-conditioning", "512,256,256", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.NotNil(t, got.Conditioning)
+	assert.Equal(t, ConditioningOutput{NIn: 512, NOut: 256, NW: 256, Vetted: true, HIn: 416, HOut: got.Conditioning.HOut}, *got.Conditioning)
+	want, err := entropy.ApplyConditioning(416, entropy.ConditioningParams{NIn: 512, NOut: 256, NW: 256})
+	require.NoError(t, err)
+	assert.InDelta(t, want, got.Conditioning.HOut, 1e-12)
+	assert.Greater(t, got.Conditioning.HOut, 255.0)
+}
+
+func TestRunCLI_Restart(t *testing.T) {
+	// 20 restarts of 20 samples; no value repeats within a row or a column.
+	data := make([]byte, 20*20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-restart", "20x20", "-h-initial", "7"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Restart Test Results (SP 800-90B Section 3.1.4):\n")
+	assert.Contains(t, stdout.String(), "  Matrix:          20 restarts x 20 samples (row layout)\n")
+	assert.Contains(t, stdout.String(), "  Sanity Check:    PASS\n")
+	assert.Contains(t, stdout.String(), "  Validation:      PASS\n")
+	assert.Contains(t, stdout.String(), "  Min Entropy:     6.600000 (min(H_r, H_c, H_I))\n")
+
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+	code = runCLI([]string{"-iid", "-bits", "8", "-restart", "20,20", "-restart-layout", "column", "-h-initial", "7", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.NotNil(t, got.Restart)
+	assert.Equal(t, "IID", got.TestType)
+	assert.Equal(t, 7.0, got.MinEntropy)
+	assert.Equal(t, "column", got.Restart.Layout)
+	assert.True(t, got.Restart.SanityPassed)
+	require.NotNil(t, got.Restart.HRow)
+	assert.Equal(t, 7.6, *got.Restart.HRow)
+	assert.True(t, got.Restart.Passed)
+}
+
+func TestRunCLI_RestartFailures(t *testing.T) {
+	// Every restart repeats a single value.
+	data := make([]byte, 20*20)
+	for i := range data {
+		data[i] = byte(i / 20)
+	}
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-restart", "20x20", "-h-initial", "7"}, bytes.NewReader(data), &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stdout.String(), "  Sanity Check:    FAIL\n")
+	assert.NotContains(t, stdout.String(), "H_r:")
+	assert.Contains(t, stderr.String(), "FAIL: restart sanity check, a symbol occurs 20 times in one row or column\n")
+
+	// The size mismatch names both sizes.
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-restart", "20x21", "-h-initial", "7"}, bytes.NewReader(data), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Error: -restart 20x21 needs 420 bytes (rows x cols samples, one per byte), but stdin holds 400; "+
+		"select the matrix with -offset and -length or correct the dimensions\n")
+
+	// -offset and -length select the matrix from a larger capture.
+	stderr.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-restart", "10x20", "-h-initial", "7", "-offset", "200", "-length", "200"}, bytes.NewReader(data), &stdout, &stderr)
+	assert.Equal(t, 3, code, stderr.String())
+	assert.Contains(t, stderr.String(), "FAIL: restart sanity check")
+}
+
+func TestRunConditioned(t *testing.T) {
+	data := make([]byte, 1000)
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"conditioned", "-vetted", "-n-in", "512", "-n-out", "256", "-nw", "256", "-h-in", "5.2"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "  Min Entropy:     6.500000\n")
+	assert.Contains(t, stdout.String(), "Vetted Conditioning (SP 800-90B Section 3.1.5.1.2):\n")
+	assert.Contains(t, stdout.String(), "  h_in:            5.200000\n")
+	assert.Contains(t, stdout.String(), "  h_out:           5.200000\n")
+	assert.NotContains(t, stdout.String(), "h':")
+
+	// Not vetted, h_out is limited by h' * n_out.
+	tmpFile := filepath.Join(t.TempDir(), "result.json")
+	stdout.Reset()
+	code = runCLI([]string{"conditioned", "-n-in", "512", "-n-out", "256", "-nw", "256", "-h-in", "512", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "Results written to "+tmpFile+"\n", stdout.String())
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, "Non-IID", got.TestType)
+	assert.Equal(t, 6.5, got.MinEntropy)
+	require.NotNil(t, got.Conditioning)
+	require.NotNil(t, got.Conditioning.HPrime)
+	hPrime := got.HAssessed / 8
+	assert.InDelta(t, hPrime, *got.Conditioning.HPrime, 1e-12)
+	assert.Equal(t, ConditioningOutput{NIn: 512, NOut: 256, NW: 256, HIn: 512, HPrime: got.Conditioning.HPrime, HOut: got.Conditioning.HOut}, *got.Conditioning)
+	want, err := entropy.ApplyNonVettedConditioning(512, hPrime, entropy.ConditioningParams{NIn: 512, NOut: 256, NW: 256})
+	require.NoError(t, err)
+	assert.InDelta(t, want, got.Conditioning.HOut, 1e-12)
+	assert.InDelta(t, hPrime*256, got.Conditioning.HOut, 1e-9)
+}
+
+func TestRunCLI_RestartManifest(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "capture.bin")
+	capture := make([]byte, 20*20)
+	for i := range capture {
+		capture[i] = byte(i)
+	}
+	require.NoError(t, os.WriteFile(input, capture, 0o600))
+	out := filepath.Join(dir, "matrix.bin")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"split-restart", "-rows", "20", "-cols", "20", "-layout", "column", "-out", out, input}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	// The manifest selects the column layout.
+	stdout.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-restart", "20x20", "-h-initial", "7", out}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "  Matrix:          20 restarts x 20 samples (column layout)\n")
+
+	// -restart-layout takes precedence over the manifest.
+	stdout.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-restart", "20x20", "-restart-layout", "row", "-h-initial", "7", out}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "(row layout)\n")
+
+	stderr.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-restart", "10x40", "-h-initial", "7", out}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "Error: manifest "+out+".manifest.json describes a 20x20 matrix, not the -restart 10x40\n", stderr.String())
+}
+
+func TestRunCLI_Validate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, 5000)
+	for i := range data {
+		data[i] = byte(rng.Intn(16))
+	}
+	path := filepath.Join(t.TempDir(), "data.bin")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	// The stub's fixed values diverge from the Go estimators.
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"validate", "-against", "cgo", "-bits", "4", path}, bytes.NewReader(nil), &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stdout.String(), "Cross-validation of the Non-IID estimators: stub vs go (tolerance 1e-06)\n")
+	assert.Regexp(t, `  Most Common Value +6\.800000 +[0-9.]+ +[0-9.e-]+ +[0-9.e-]+  DIVERGES\n`, stdout.String())
+	assert.Contains(t, stderr.String(), "FAIL: the Go estimators diverge from stub by more than 1e-06 bits per sample")
+
+	// A loose tolerance on the IID track, with JSON output.
+	stdout.Reset()
+	stderr.Reset()
+	tmpFile := filepath.Join(t.TempDir(), "diff.json")
+	code = runCLI([]string{"validate", "-iid", "-bits", "4", "-estimators", "chi-square", "-tolerance", "0", "-output", tmpFile, "-"},
+		bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Regexp(t, `  Chi-Square Tests +PASS +PASS +- +-  ok\n`, stdout.String())
+
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got ValidateOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, "IID", got.TestType)
+	assert.Equal(t, "stub", got.Reference)
+	assert.True(t, got.Passed)
+	require.Len(t, got.Deltas, 1)
+	assert.True(t, got.Deltas[0].Test)
+}
+
+func TestRunCLI_OutputMatchesSchema(t *testing.T) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema.AssessOutput()))
+	require.NoError(t, err)
+	c := jsonschema.NewCompiler()
+	require.NoError(t, c.AddResource("assess_output.schema.json", doc))
+	sch, err := c.Compile("assess_output.schema.json")
+	require.NoError(t, err)
+
+	rng := rand.New(rand.NewSource(2))
+	data := make([]byte, 2000)
+	for i := range data {
+		data[i] = byte(rng.Intn(16))
+	}
+	failing := append([]byte{0xFF}, data[1:]...)
+	for name, tc := range map[string]struct {
+		args []string
+		data []byte
+	}{
+		"non-iid":      {[]string{"-non-iid", "-bits", "4", "-histogram", "-h-submitter", "3", "-fail-below", "1"}, data},
+		"iid":          {[]string{"-iid", "-bits", "4", "-estimators", "mcv,chi-square", "-bit-order", "lsb", "-offset", "10", "-length", "1000"}, data},
+		"iid-check":    {[]string{"-iid-check", "-bits", "4"}, data},
+		"quick":        {[]string{"-quick", "-bits", "4"}, data},
+		"lrs":          {[]string{"-lrs", "-bits", "4"}, data},
+		"conditioning": {[]string{"-non-iid", "-bits", "4", "-conditioning", "64,32,32"}, data},
+		"restart":      {[]string{"-non-iid", "-bits", "4", "-restart", "40x50", "-h-initial", "3"}, data},
+		"conditioned":  {[]string{"conditioned", "-bits", "4", "-n-in", "64", "-n-out", "32", "-nw", "32", "-h-in", "40"}, data},
+		"error":        {[]string{"-non-iid", "-bits", "4"}, failing},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "data.bin")
+			output := filepath.Join(dir, "result.json")
+			require.NoError(t, os.WriteFile(input, tc.data, 0o600))
+
+			var out bytes.Buffer
+			runCLI(append(tc.args, "-output", output, input), nil, &out, &out)
+			f, err := os.Open(output)
+			require.NoError(t, err)
+			defer f.Close()
+			v, err := jsonschema.UnmarshalJSON(f)
+			require.NoError(t, err)
+			assert.NoError(t, sch.Validate(v))
+			assert.Equal(t, schema.Version, v.(map[string]any)["schema_version"])
+		})
+	}
+}
+
+func TestRunCLI_CSVFormat(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "run 1, device A.bin")
+	bad := filepath.Join(dir, "bad.bin")
+	require.NoError(t, os.WriteFile(good, []byte{1, 2, 3, 4}, 0o644))
+	require.NoError(t, os.WriteFile(bad, []byte{0xFF, 2, 3, 4}, 0o644))
+
+	// Without -output the CSV replaces the text results on stdout.
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-format", "csv", good}, bytes.NewReader(nil), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "filename,test_type,bits_per_symbol,data_size,h_original,h_bitstring,h_assessed,min_entropy,error\n"+
+		`"`+good+`",Non-IID,8,4,6.6,6.1,6.5,6.5,`+"\n", stdout.String())
+
+	output := filepath.Join(dir, "results.csv")
+	stdout.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-format", "csv", "-output", output, "-jobs", "2", good, bad}, bytes.NewReader(nil), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.NotContains(t, stdout.String(), "Entropy Assessment Results")
+	assert.Contains(t, stdout.String(), "Results for 2 files written to "+output)
+
+	f, err := os.Open(output)
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, csvHeader, records[0])
+	assert.Equal(t, []string{good, "IID", "8", "4", "7.6", "7.1", "7.5", "7.5", ""}, records[1])
+	assert.Equal(t, []string{bad, "IID", "8", "4", "", "", "", ""}, records[2][:8])
+	assert.Contains(t, records[2][8], "stub failure")
+
+	stdout.Reset()
+	code = runCLI([]string{"-all", "-bits", "8", "-format", "csv", good}, bytes.NewReader(nil), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	records, err = csv.NewReader(&stdout).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "IID", records[1][1])
+	assert.Equal(t, "Non-IID", records[2][1])
+}
+
+func TestRunCLI_JSONLFormat(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 5; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("data%d.bin", i))
+		data := []byte{1, 2, 3, 4}
+		if i == 2 {
+			data[0] = 0xFF
+		}
+		require.NoError(t, os.WriteFile(name, data, 0o644))
+		files = append(files, name)
+	}
+
+	// -format jsonl is -format ndjson: one document per file, failures
+	// included.
+	var stdout, stderr bytes.Buffer
+	code := runCLI(append([]string{"-non-iid", "-bits", "8", "-jobs", "2", "-format", "jsonl"}, files...), nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	require.Len(t, lines, len(files))
+	seen := make(map[string]int)
+	for _, line := range lines {
+		var got JSONOutput
+		require.NoError(t, json.Unmarshal([]byte(line), &got), line)
+		seen[got.Filename] = got.ErrorCode
+	}
+	for i, name := range files {
+		want := 0
+		if i == 2 {
+			want = 1
+		}
+		assert.Equal(t, want, seen[name], name)
+	}
+}
+
+func TestRunCLI_NDJSONFormat(t *testing.T) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema.AssessOutput()))
+	require.NoError(t, err)
+	c := jsonschema.NewCompiler()
+	require.NoError(t, c.AddResource("assess_output.schema.json", doc))
+	sch, err := c.Compile("assess_output.schema.json")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 12; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("data%02d.bin", i))
+		data := []byte{1, 2, 3, 4}
+		if i == 5 {
+			data[0] = 0xFF
+		}
+		require.NoError(t, os.WriteFile(name, data, 0o644))
+		files = append(files, name)
+	}
+
+	// readLines parses every line as a complete, schema-valid document.
+	readLines := func(t *testing.T, raw string) map[string]map[string]any {
+		t.Helper()
+		require.True(t, strings.HasSuffix(raw, "\n"))
+		docs := make(map[string]map[string]any)
+		for _, line := range strings.Split(strings.TrimSuffix(raw, "\n"), "\n") {
+			v, err := jsonschema.UnmarshalJSON(strings.NewReader(line))
+			require.NoError(t, err, line)
+			assert.NoError(t, sch.Validate(v))
+			doc := v.(map[string]any)
+			for _, field := range []string{"started_at", "finished_at", "duration_ms", "error_code"} {
+				assert.Contains(t, doc, field)
+			}
+			docs[doc["filename"].(string)] = doc
+		}
+		return docs
+	}
+
+	// Concurrent workers write to stdout without interleaving lines.
+	var stdout, stderr bytes.Buffer
+	code := runCLI(append([]string{"-non-iid", "-bits", "8", "-format", "ndjson", "-jobs", "4"}, files...), bytes.NewReader(nil), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.NotContains(t, stdout.String(), "Entropy Assessment Results")
+	docs := readLines(t, stdout.String())
+	require.Len(t, docs, len(files))
+	assert.Equal(t, json.Number("6.5"), docs[files[0]]["min_entropy"])
+	assert.Contains(t, docs[files[5]]["error_message"], "stub failure")
+
+	output := filepath.Join(dir, "results.ndjson")
+	stdout.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-format", "ndjson", "-output", output, files[0]}, bytes.NewReader(nil), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "Results written to "+output+"\n", stdout.String())
+	raw, err := os.ReadFile(output)
+	require.NoError(t, err)
+	docs = readLines(t, string(raw))
+	require.Len(t, docs, 1)
+	assert.Equal(t, "IID", docs[files[0]]["test_type"])
+
+	stdout.Reset()
+	code = runCLI([]string{"-all", "-bits", "8", "-format", "ndjson", files[0]}, bytes.NewReader(nil), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.Equal(t, 2, strings.Count(stdout.String(), "\n"))
+}
+
+// dropDurations removes the duration_ms and timings_ms fields, which differ
+// between runs, from a decoded document.
+func dropDurations(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		delete(v, "duration_ms")
+		delete(v, "timings_ms")
+		for _, field := range v {
+			dropDurations(field)
+		}
+	case []any:
+		for _, item := range v {
+			dropDurations(item)
+		}
+	}
+	return v
+}
+
+func TestRunCLI_YAMLFormat(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.bin")
+	bad := filepath.Join(dir, "bad.bin")
+	require.NoError(t, os.WriteFile(good, []byte{1, 2, 3, 4, 1, 2}, 0o644))
+	require.NoError(t, os.WriteFile(bad, []byte{0xFF, 2, 3, 4}, 0o644))
+
+	// asJSON re-encodes a decoded YAML document so that it compares equal to
+	// the JSON output whatever numeric types the YAML decoder chose.
+	asJSON := func(t *testing.T, doc any) any {
+		t.Helper()
+		raw, err := json.Marshal(doc)
+		require.NoError(t, err)
+		var v any
+		require.NoError(t, json.Unmarshal(raw, &v))
+		return dropDurations(v)
+	}
+	readJSON := func(t *testing.T, path string) any {
+		t.Helper()
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var v any
+		require.NoError(t, json.Unmarshal(raw, &v))
+		return dropDurations(v)
+	}
+
+	for name, args := range map[string][]string{
+		"non-iid":   {"-non-iid", "-bits", "8", "-histogram", "-h-submitter", "3", "-offset", "1"},
+		"iid-check": {"-iid-check", "-bits", "8"},
+		"all":       {"-all", "-bits", "8"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			jsonFile := filepath.Join(t.TempDir(), "result.json")
+			var out bytes.Buffer
+			runCLI(append(args, "-output", jsonFile, good), nil, &out, &out)
+
+			// Without -output the YAML replaces the text results on stdout.
+			var stdout, stderr bytes.Buffer
+			code := runCLI(append(args, "-format", "yaml", good), nil, &stdout, &stderr)
+			require.Equal(t, 0, code, stderr.String())
+			assert.NotContains(t, stdout.String(), "Results:")
+			var doc any
+			require.NoError(t, yaml.Unmarshal(stdout.Bytes(), &doc))
+			assert.Equal(t, readJSON(t, jsonFile), asJSON(t, doc))
+		})
+	}
+
+	// Several files give one document each, in argument order.
+	jsonFile := filepath.Join(dir, "results.json")
+	yamlFile := filepath.Join(dir, "results.yaml")
+	var out bytes.Buffer
+	runCLI([]string{"-non-iid", "-bits", "8", "-output", jsonFile, good, bad}, nil, &out, &out)
+	out.Reset()
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-format", "yaml", "-output", yamlFile, "-jobs", "2", good, bad}, nil, &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "Results for 2 files written to "+yamlFile)
+
+	raw, err := os.ReadFile(yamlFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(raw), "\n---\n"))
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	var docs []any
+	for {
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		docs = append(docs, asJSON(t, doc))
+	}
+	assert.Equal(t, readJSON(t, jsonFile), any(docs))
+	assert.Contains(t, string(raw), "schema_version: \""+schema.Version+"\"")
+}
+
+func TestRunCLI_NISTJSONFormat(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	data := make([]byte, 2000)
+	for i := range data {
+		data[i] = byte(rng.Intn(256))
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "data.bin")
+	require.NoError(t, os.WriteFile(input, data, 0o600))
+
+	readRun := func(t *testing.T, args ...string) map[string]any {
+		t.Helper()
+		output := filepath.Join(dir, "nist.json")
+		var stdout, stderr bytes.Buffer
+		code := runCLI(append(args, "-format", "nist-json", "-output", output, input), nil, &stdout, &stderr)
+		require.Equal(t, 0, code, stderr.String())
+		raw, err := os.ReadFile(output)
+		require.NoError(t, err)
+		var run map[string]any
+		require.NoError(t, json.Unmarshal(raw, &run))
+		return run
+	}
+
+	t.Run("non-iid", func(t *testing.T) {
+		run := readRun(t, "-non-iid", "-bits", "8")
+		for _, key := range []string{"IID", "commandline", "dateTimeStamp", "errorLevel", "filename", "sha256", "testCases", "toolVersion", "type"} {
+			assert.Contains(t, run, key)
+		}
+		assert.NotContains(t, run, "errorMessage")
+		assert.Equal(t, false, run["IID"])
+		assert.Equal(t, 0.0, run["errorLevel"])
+		assert.Equal(t, input, run["filename"])
+		assert.Equal(t, "stub", run["toolVersion"])
+		assert.Regexp(t, `^\d{14}$`, run["dateTimeStamp"])
+		assert.Equal(t, "ea_tool -non-iid -bits 8 -format nist-json -output "+filepath.Join(dir, "nist.json")+" "+input, run["commandline"])
+		sum := sha256.Sum256(data)
+		assert.Equal(t, hex.EncodeToString(sum[:]), run["sha256"])
+
+		cases := run["testCases"].([]any)
+		require.Len(t, cases, 11)
+		mcv := cases[0].(map[string]any)
+		assert.Equal(t, "Most Common Value", mcv["testCaseDesc"])
+		assert.Equal(t, 6.8, mcv["hOriginal"])
+		assert.Contains(t, mcv, "hBitstring")
+		assert.Nil(t, mcv["hBitstring"])
+		assert.Contains(t, mcv, "mcvEstimatePHat")
+
+		collision := cases[1].(map[string]any)
+		assert.Equal(t, "Collision Test (for bit strings only)", collision["testCaseDesc"])
+		assert.Equal(t, 6.9, collision["hBitstring"])
+		assert.NotContains(t, collision, "hOriginal")
+
+		tuple := cases[4].(map[string]any)
+		assert.Equal(t, "T-Tuple Test", tuple["testCaseDesc"])
+		assert.Equal(t, 6.6, tuple["tTupleRes"])
+		assert.Contains(t, tuple, "binTTupleRes")
+
+		assert.Equal(t, map[string]any{
+			"testCaseDesc": "Overall",
+			"dataWordSize": 8.0,
+			"hOriginal":    6.6,
+			"hBitstring":   6.1,
+			"hAssessed":    6.5,
+		}, cases[10])
+	})
+
+	t.Run("iid", func(t *testing.T) {
+		run := readRun(t, "-iid", "-bits", "8")
+		assert.Equal(t, true, run["IID"])
+		cases := run["testCases"].([]any)
+		require.Len(t, cases, 1)
+		tc := cases[0].(map[string]any)
+		assert.Equal(t, 7.6, tc["hOriginal"])
+		assert.Equal(t, 7.1, tc["hBitstring"])
+		assert.Equal(t, 7.5, tc["hAssessed"])
+		assert.Equal(t, true, tc["passedChiSquareTests"])
+		assert.Equal(t, true, tc["passedLongestRepeatedSubstringTest"])
+		assert.Equal(t, true, tc["passedIidPermutationTests"])
+		for _, key := range []string{"mean", "median", "permutationTestResults"} {
+			assert.Contains(t, tc, key)
+			assert.Nil(t, tc[key])
+		}
+		assert.NotContains(t, tc, "binary")
+	})
+
+	t.Run("iid-check", func(t *testing.T) {
+		run := readRun(t, "-iid-check", "-bits", "8")
+		tc := run["testCases"].([]any)[0].(map[string]any)
+		assert.Nil(t, tc["hAssessed"])
+		assert.Equal(t, true, tc["passedChiSquareTests"])
+	})
+
+	t.Run("error", func(t *testing.T) {
+		failing := filepath.Join(dir, "failing.bin")
+		require.NoError(t, os.WriteFile(failing, []byte{0xFF, 1, 2, 3}, 0o600))
+		output := filepath.Join(dir, "error.json")
+		var stdout, stderr bytes.Buffer
+		code := runCLI([]string{"-non-iid", "-bits", "8", "-format", "nist-json", "-output", output, failing}, nil, &stdout, &stderr)
+		assert.Equal(t, 1, code)
+		raw, err := os.ReadFile(output)
+		require.NoError(t, err)
+		var run map[string]any
+		require.NoError(t, json.Unmarshal(raw, &run))
+		assert.Equal(t, -1.0, run["errorLevel"])
+		assert.Contains(t, run["errorMessage"], "stub failure")
+		assert.Nil(t, run["testCases"])
+	})
+}
+
+// startRemoteService serves the assessment service on a free local port and
+// returns its address. The server is stopped on cleanup.
+func startRemoteService(t *testing.T, opts ...grpc.ServerOption) string {
+	t.Helper()
+
+	svc := service.NewService()
+	svc.SetMinSamples(0)
+	server := grpc.NewServer(opts...)
+	pb.RegisterSp80090BAssessmentServiceServer(server, service.NewGRPCServer(svc))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(server.Stop)
+	return ln.Addr().String()
+}
+
+func TestRunCLI_RemoteMatchesLocal(t *testing.T) {
+	addr := startRemoteService(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "data.bin")
+	require.NoError(t, os.WriteFile(input, []byte{1, 2, 3, 4, 5, 6, 7, 8}, 0o644))
+
+	// withoutElapsed drops the elapsed time, which differs between runs, from
+	// the text output.
+	withoutElapsed := func(s string) string {
+		var lines []string
+		for _, line := range strings.Split(s, "\n") {
+			if !strings.HasPrefix(line, "Elapsed: ") {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for _, args := range [][]string{
+		{"-non-iid", "-bits", "8", "-verbose", "2"},
+		{"-iid", "-bits", "8", "-h-submitter", "7", "-estimators", "chi-square,mcv", "-verbose", "2"},
+		{"-non-iid", "-bits", "0", "-histogram", "-raw", "-bit-order", "lsb"},
+		{"-iid-check", "-bits", "8"},
+		{"-all", "-bits", "8"},
+		{"-non-iid", "-bits", "8", "-conditioning", "512,256,256"},
+	} {
+		name := strings.Join(args, " ")
+		var localOut, localErr bytes.Buffer
+		localCode := runCLI(append(args, input), bytes.NewReader(nil), &localOut, &localErr)
+		var remoteOut, remoteErr bytes.Buffer
+		remoteCode := runCLI(append(args, "-remote", addr, input), bytes.NewReader(nil), &remoteOut, &remoteErr)
+		require.Equal(t, localCode, remoteCode, "%s: %s", name, remoteErr.String())
+		assert.Equal(t, withoutElapsed(localOut.String()), withoutElapsed(remoteOut.String()), name)
+		assert.Equal(t, localErr.String(), remoteErr.String(), name)
+
+		localFile := filepath.Join(dir, "local.json")
+		remoteFile := filepath.Join(dir, "remote.json")
+		require.Equal(t, localCode, runCLI(append(args, "-output", localFile, input), bytes.NewReader(nil), io.Discard, io.Discard), name)
+		require.Equal(t, localCode, runCLI(append(args, "-remote", addr, "-output", remoteFile, input), bytes.NewReader(nil), io.Discard, io.Discard), name)
+		var local, remote any
+		require.NoError(t, json.Unmarshal(mustReadFile(t, localFile), &local), name)
+		require.NoError(t, json.Unmarshal(mustReadFile(t, remoteFile), &remote), name)
+		assert.Equal(t, dropDurations(local), dropDurations(remote), name)
+	}
+}
+
+func TestRunCLI_RemoteErrors(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out.json")
+	readDoc := func(t *testing.T) JSONOutput {
+		var doc JSONOutput
+		require.NoError(t, json.Unmarshal(mustReadFile(t, output), &doc))
+		return doc
+	}
+
+	// An assessment that fails on the service is an assessment error.
+	addr := startRemoteService(t)
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-bits", "8", "-remote", addr, "-output", output}, bytes.NewReader([]byte{0xFF, 2, 3, 4}), &stdout, &stderr)
+	assert.Equal(t, 1, code, stderr.String())
+	assert.Equal(t, 1, readDoc(t).ErrorCode)
+
+	// A service that rejects the credentials is not.
+	deny := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return nil, grpcstatus.Error(codes.Unauthenticated, "invalid token")
+	}
+	addr = startRemoteService(t, grpc.UnaryInterceptor(deny))
+	stderr.Reset()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-remote", addr, "-token", "wrong"}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	assert.Equal(t, exitRemote, code)
+	assert.Contains(t, stderr.String(), "Error: remote service "+addr+": invalid token")
+
+	code = runCLI([]string{"-all", "-bits", "8", "-remote", addr, "-output", output}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	assert.Equal(t, exitRemote, code)
+	var all AllOutput
+	require.NoError(t, json.Unmarshal(mustReadFile(t, output), &all))
+	assert.Equal(t, exitRemote, all.ErrorCode)
+	assert.Equal(t, exitRemote, all.IID.ErrorCode)
+
+	// Nor is a service that cannot be reached.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := ln.Addr().String()
+	ln.Close()
+	code = runCLI([]string{"-non-iid", "-bits", "8", "-remote", closed, "-output", output}, bytes.NewReader([]byte{1, 2, 3, 4}), &stdout, &stderr)
+	assert.Equal(t, exitRemote, code)
+	doc := readDoc(t)
+	assert.Equal(t, exitRemote, doc.ErrorCode)
+	assert.Contains(t, doc.ErrorMessage, "remote service "+closed)
+}
+
+func mustReadFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	return data
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	incoming := filepath.Join(dir, "incoming")
+	results := filepath.Join(dir, "results")
+	require.NoError(t, os.Mkdir(incoming, 0o755))
+
+	// A file dropped before the watch starts is picked up by the scan.
+	require.NoError(t, os.WriteFile(filepath.Join(incoming, "early.bin"), []byte{1, 2, 3, 4}, 0o600))
+
+	cfg := watchConfig{
+		dir:       incoming,
+		outputDir: results,
+		settle:    500 * time.Millisecond,
+		opts: &cliOptions{
+			testType:   entropy.NonIID,
+			bits:       8,
+			verbose:    1,
+			encoding:   encodingBinary,
+			permRounds: entropy.PermutationRounds,
+			toFile:     true,
+			format:     formatJSON,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	exited := make(chan int, 1)
+	go func() { exited <- cfg.watch(ctx, &stdout, &stderr) }()
+
+	// A file that keeps growing is not assessed before it stops, however
+	// many events its writes raise.
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(incoming, "done"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	slow, err := os.Create(filepath.Join(incoming, "slow.bin"))
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err := slow.Write([]byte{1, 2, 3, 4})
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		assert.NoFileExists(t, filepath.Join(incoming, "done", "slow.bin"))
+	}
+	require.NoError(t, slow.Close())
+
+	// The stub fails on a first byte of 0xFF; hidden files are left alone.
+	require.NoError(t, os.WriteFile(filepath.Join(incoming, "bad.bin"), []byte{0xFF, 1, 2}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(incoming, ".upload.tmp"), []byte{1, 2}, 0o600))
+
+	moved := []string{
+		filepath.Join(incoming, "done", "early.bin"),
+		filepath.Join(incoming, "done", "slow.bin"),
+		filepath.Join(incoming, "failed", "bad.bin"),
+	}
+	require.Eventually(t, func() bool {
+		for _, path := range moved {
+			if _, err := os.Stat(path); err != nil {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second, 20*time.Millisecond)
+	cancel()
+	require.Equal(t, 0, <-exited)
+
+	readResult := func(name string) JSONOutput {
+		raw, err := os.ReadFile(filepath.Join(results, name))
+		require.NoError(t, err)
+		var got JSONOutput
+		require.NoError(t, json.Unmarshal(raw, &got))
+		return got
+	}
+	got := readResult("slow.bin.json")
+	assert.Equal(t, "slow.bin", got.Filename)
+	assert.Equal(t, 40, got.DataSize)
+	assert.Equal(t, 6.5, got.MinEntropy)
+	assert.Equal(t, 4, readResult("early.bin.json").DataSize)
+	assert.Equal(t, 1, readResult("bad.bin.json").ErrorCode)
+
+	entries, err := os.ReadDir(results)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "every input is assessed once")
+	assert.FileExists(t, filepath.Join(incoming, ".upload.tmp"))
+	assert.Contains(t, stdout.String(), "slow.bin: min-entropy 6.500000, h_final 6.500000; result "+filepath.Join(results, "slow.bin.json")+"\n")
+	assert.Contains(t, stdout.String(), "Stopped watching "+incoming+": 2 files assessed, 1 failed, 0 waiting\n")
+	assert.Contains(t, stderr.String(), "bad.bin: FAIL: ")
+}
+
+var updateGolden = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+func TestRunReport_Golden(t *testing.T) {
+	reportNow = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	defer func() { reportNow = time.Now }()
+
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, tc := range []struct {
+		golden string
+		args   []string
+		code   int
+	}{
+		{"report.md.golden", []string{"report", "-iid", "-non-iid", "-bits", "8", "-h-submitter", "7", "-fail-below", "7"}, 3},
+		{"report.html.golden", []string{"report", "-non-iid", "-bits", "8", "-estimators", "mcv,lz78y", "-fail-below", "6", "-format", "html"}, 0},
+	} {
+		t.Run(tc.golden, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runCLI(tc.args, bytes.NewReader(data), &stdout, &stderr)
+			require.Equal(t, tc.code, code, stderr.String())
+
+			path := filepath.Join("testdata", tc.golden)
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(path, stdout.Bytes(), 0o644))
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), stdout.String())
+		})
+	}
+}
+
+func TestRunReport_Output(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "<b>.bin")
+	data := []byte{1, 2, 3, 4}
+	require.NoError(t, os.WriteFile(input, data, 0o600))
+
+	// A user template sees the report data; html/template escapes it.
+	tmpl := filepath.Join(dir, "lab.html.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte(`{{.SHA256}} {{(index .Assessments 0).TestType}} [{{index .Warnings 0}}] <i>{{.Filename}}</i>`), 0o600))
+	out := filepath.Join(dir, "report.html")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"report", "-iid", "-bits", "8", "-template", tmpl, "-format", "html", "-o", out, input}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "Report written to "+out+"\n", stdout.String())
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:])+" IID [stub build; entropy results are fixed test values] <i>"+dir+"/&lt;b&gt;.bin</i>", string(got))
+
+	// The warnings are printed as well as reported.
+	assert.Contains(t, stderr.String(), "Warning: data contains less than 1000000 samples\n")
+
+	// A template that fails to execute writes no report.
+	require.NoError(t, os.WriteFile(tmpl, []byte(`{{.Missing}}`), 0o600))
+	require.NoError(t, os.Remove(out))
+	stderr.Reset()
+	code = runCLI([]string{"report", "-iid", "-bits", "8", "-template", tmpl, "-o", out, input}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "Error rendering report:")
+	assert.NoFileExists(t, out)
+
+	// An assessment error is reported instead of a report.
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"report", "-non-iid", "-bits", "8"}, bytes.NewReader(nil), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "Error: ")
+}
+
+func TestRunCapture(t *testing.T) {
+	dir := t.TempDir()
+	device := filepath.Join(dir, "hwrng")
+	source := make([]byte, 3000)
+	for i := range source {
+		source[i] = byte(i)
+	}
+	require.NoError(t, os.WriteFile(device, source, 0o600))
+	saved := filepath.Join(dir, "capture.bin")
+	output := filepath.Join(dir, "result.json")
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"capture", "-device", device, "-samples", "1000", "-save", saved, "-non-iid", "-bits", "8", "-output", output}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Captured 1000 samples from "+device)
+	assert.Contains(t, stdout.String(), "Capture saved to "+saved+"\n")
+	assert.Contains(t, stdout.String(), "Results written to "+output+"\n")
+
+	got, err := os.ReadFile(saved)
+	require.NoError(t, err)
+	assert.Equal(t, source[:1000], got)
+
+	var out JSONOutput
+	raw, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(raw, &out))
+	assert.Equal(t, device, out.Filename)
+	assert.Equal(t, 1000, out.DataSize)
+	assert.Equal(t, "Non-IID", out.TestType)
+
+	// Standard input serves as the device with "-".
+	stdout.Reset()
+	code = runCLI([]string{"capture", "-device", "-", "-samples", "4", "-iid", "-bits", "8"}, bytes.NewReader(source), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Captured 4 samples from -")
+	assert.Contains(t, stdout.String(), "Min Entropy:")
+}
+
+func TestRunCapture_ShortSource(t *testing.T) {
+	dir := t.TempDir()
+	device := filepath.Join(dir, "hwrng")
+	require.NoError(t, os.WriteFile(device, make([]byte, 600), 0o600))
+	saved := filepath.Join(dir, "capture.bin")
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"capture", "-device", device, "-samples", "1000", "-save", saved, "-non-iid", "-bits", "8"}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "Error capturing from "+device+": input ended after 600 of 1000 bytes\n", stderr.String())
+	assert.Empty(t, stdout.String())
+	assert.NoFileExists(t, saved)
+}
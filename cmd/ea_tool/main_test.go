@@ -2,10 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -24,7 +35,7 @@ func TestWriteJSONCreatesFile(t *testing.T) {
 		ErrorCode:     0,
 	}
 
-	writeJSON(tmp, payload)
+	writeJSON(tmp, io.Discard, payload)
 
 	raw, err := os.ReadFile(tmp)
 	require.NoError(t, err)
@@ -35,6 +46,136 @@ func TestWriteJSONCreatesFile(t *testing.T) {
 	assert.Equal(t, payload.MinEntropy, got.MinEntropy)
 }
 
+func TestWriteJSON_Stdout(t *testing.T) {
+	var out bytes.Buffer
+	writeJSON(stdoutName, &out, JSONOutput{Version: "test", Filename: "a<b>&c.bin"})
+
+	assert.Contains(t, out.String(), `"filename": "a<b>&c.bin"`, "no HTML escaping")
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	assert.Equal(t, "a<b>&c.bin", got.Filename)
+}
+
+func TestEncodeJSON_FieldOrder(t *testing.T) {
+	shannon, lrs := 2.0, 3
+	doc := JSONOutput{
+		SchemaVersion: schema.Version, Version: version, GeneratedAt: "2026-10-16T09:30:00Z",
+		Filename: "a.bin", Path: "a.bin", TestType: "Non-IID", BitsPerSymbol: 8, DataSize: 4,
+		DataSHA256: sha256Hex([]byte{1, 2, 3, 4}), Section: &SectionOutput{Length: 4, InputSize: 4},
+		MinEntropy: 1, HOriginal: 1, HBitstring: 1, HAssessed: 1, ShannonEntropy: &shannon,
+		AlphabetSize: 4, LRSLength: &lrs, HSubmitter: &shannon, HFinal: 1,
+		Conditioning: &ConditioningOutput{}, Restart: &RestartOutput{}, Estimators: []string{"mcv"},
+		EstimatorResults: []EstimatorResultOutput{{ID: "mcv"}}, Partial: true, Backend: "stub",
+		BitOrder: "lsb", PermutationRounds: 10, Threshold: &shannon, Passed: new(bool),
+		IIDCheckPassed: new(bool), Tests: []TestOutput{{ID: "chi-square"}}, Histogram: []uint64{1},
+		Raw: json.RawMessage(`{}`), StartedAt: "s", FinishedAt: "f", DurationMS: 1,
+		TimingsMS: map[string]float64{"Non-IID": 1, "IID": 2}, ErrorCode: 1, ErrorMessage: "e",
+	}
+
+	var first, second bytes.Buffer
+	require.NoError(t, encodeJSON(&first, doc))
+	require.NoError(t, encodeJSON(&second, doc))
+	assert.Equal(t, first.String(), second.String())
+
+	// The keys follow the declaration order of the fields, which is the
+	// order of the schema, and map keys are sorted.
+	var want []string
+	typ := reflect.TypeOf(doc)
+	for i := 0; i < typ.NumField(); i++ {
+		if tag := typ.Field(i).Tag.Get("json"); tag != "" {
+			want = append(want, strings.Split(tag, ",")[0])
+		}
+	}
+	dec := json.NewDecoder(&first)
+	_, err := dec.Token()
+	require.NoError(t, err)
+	var keys []string
+	for dec.More() {
+		key, err := dec.Token()
+		require.NoError(t, err)
+		keys = append(keys, key.(string))
+		var value json.RawMessage
+		require.NoError(t, dec.Decode(&value))
+		if key == "timings_ms" {
+			assert.Equal(t, `{"IID":2,"Non-IID":1}`, string(bytes.Join(bytes.Fields(value), nil)))
+		}
+	}
+	assert.Equal(t, want, keys)
+}
+
+// TestJSONOutput_ParsesOlderSchemas checks that documents written by earlier
+// versions of the tool decode into the current JSONOutput without unknown
+// fields, so that consumers built on this version read archived results.
+func TestJSONOutput_ParsesOlderSchemas(t *testing.T) {
+	for name, tc := range map[string]struct {
+		doc     string
+		version string
+		check   func(t *testing.T, got JSONOutput)
+	}{
+		"v1": {
+			doc: `{"schema_version": "1", "version": "1.0.0", "filename": "data.bin", "test_type": "Non-IID",
+				"bits_per_symbol": 8, "data_size": 1000, "section": {"offset": 10, "length": 1000, "input_size": 2000},
+				"min_entropy": 6.5, "h_original": 6.6, "h_bitstring": 6.1, "h_assessed": 6.5, "shannon_entropy": 7.9,
+				"h_final": 6.5, "submitter_binding": false, "estimators": ["mcv"], "partial": true, "backend": "go",
+				"bit_order": "lsb", "permutation_rounds": 100, "threshold": 6, "passed": true, "error_code": 0}`,
+			version: "1",
+			check: func(t *testing.T, got JSONOutput) {
+				assert.Equal(t, &SectionOutput{Offset: 10, Length: 1000, InputSize: 2000}, got.Section)
+				assert.Equal(t, 6.1, got.HBitstring)
+				require.NotNil(t, got.Passed)
+				assert.True(t, *got.Passed)
+				assert.Empty(t, got.GeneratedAt)
+				assert.Empty(t, got.DataSHA256)
+			},
+		},
+		"v1 iid-check error": {
+			doc: `{"schema_version": "1", "version": "1.0.0", "filename": "stdin", "test_type": "IID",
+				"bits_per_symbol": 1, "data_size": 0, "min_entropy": 0, "h_assessed": 0, "h_final": 0,
+				"submitter_binding": false, "iid_check_passed": false,
+				"tests": [{"id": "chi-square", "name": "Chi-square", "passed": false}],
+				"error_code": 1, "error_message": "failed"}`,
+			version: "1",
+			check: func(t *testing.T, got JSONOutput) {
+				require.Len(t, got.Tests, 1)
+				assert.Equal(t, "chi-square", got.Tests[0].ID)
+				assert.Equal(t, "failed", got.ErrorMessage)
+			},
+		},
+		"v15": {
+			doc: `{"schema_version": "15", "version": "1.0.0", "filename": "data.bin", "path": "a/data.bin",
+				"test_type": "Non-IID", "bits_per_symbol": 8, "data_size": 1000, "min_entropy": 6.5,
+				"h_assessed": 6.5, "alphabet_size": 200, "h_final": 6.5, "submitter_binding": false,
+				"estimator_results": [{"id": "mcv", "name": "Most Common Value", "entropy_estimate": 6.5, "passed": true, "is_entropy_valid": true}],
+				"duration_ms": 812, "timings_ms": {"Non-IID": 800.5}, "error_code": 0}`,
+			version: "15",
+			check: func(t *testing.T, got JSONOutput) {
+				assert.Equal(t, "a/data.bin", got.Path)
+				assert.Equal(t, int64(812), got.DurationMS)
+				assert.Equal(t, map[string]float64{"Non-IID": 800.5}, got.TimingsMS)
+				require.Len(t, got.EstimatorResults, 1)
+				assert.Equal(t, 6.5, got.EstimatorResults[0].EntropyEstimate)
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dec := json.NewDecoder(strings.NewReader(tc.doc))
+			dec.DisallowUnknownFields()
+			var got JSONOutput
+			require.NoError(t, dec.Decode(&got))
+			assert.Equal(t, tc.version, got.SchemaVersion)
+			assert.Equal(t, "1.0.0", got.Version)
+			tc.check(t, got)
+
+			// Re-encoding keeps the fields of the older document.
+			var out bytes.Buffer
+			require.NoError(t, encodeJSON(&out, got))
+			var again JSONOutput
+			require.NoError(t, json.Unmarshal(out.Bytes(), &again))
+			assert.Equal(t, got, again)
+		})
+	}
+}
+
 func TestRunCLI_Version(t *testing.T) {
 	var out bytes.Buffer
 	code := runCLI([]string{"-version"}, bytes.NewReader(nil), &out, &out)
@@ -56,6 +197,109 @@ func TestRunCLI_InvalidBits(t *testing.T) {
 	assert.Contains(t, out.String(), "bits per symbol must be 0-8")
 }
 
+func TestRunCLI_NegativeTimeout(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-timeout", "-1s"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "timeout must not be negative")
+}
+
+func TestRunCLI_InvalidHSubmitter(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-bits", "1", "-h-submitter", "1.5"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "h-submitter must be between 0 and bits per symbol")
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-h-submitter", "-1"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+}
+
+func TestRunCLI_InvalidEstimators(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-estimators", "mcv,markov"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), `unknown IID estimator "markov"`)
+	assert.Contains(t, out.String(), "valid: mcv, chi-square, lrs, permutation")
+}
+
+func TestRunCLI_InvalidFailBelow(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-fail-below", "-1"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "fail-below must be a non-negative number")
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-bits", "4", "-fail-below", "4.5"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "fail-below must not exceed 4 bits per symbol, got 4.5")
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-fail-below", "9"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "fail-below must not exceed 8 bits per symbol, got 9")
+}
+
+func TestRunCLI_InvalidMaxBytes(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-max-bytes", "-1"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "max-bytes must not be negative, got -1")
+
+	out.Reset()
+	code = runCLI([]string{"-non-iid", "-skip-bytes", "-1"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "offset and length must not be negative")
+}
+
+func TestRunCLI_InvalidSampleBytes(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-sample-bytes", "-1"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "sample-bytes must not be negative, got -1")
+}
+
+func TestRunCLI_InvalidStride(t *testing.T) {
+	for _, stride := range []string{"0", "-3"} {
+		var out bytes.Buffer
+		code := runCLI([]string{"-non-iid", "-stride", stride}, bytes.NewReader(nil), &out, &out)
+		assert.Equal(t, 2, code)
+		assert.Contains(t, out.String(), "stride must be at least 1, got "+stride)
+	}
+}
+
+func TestRunCLI_HelpListsExitCodes(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-help"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "\nExit codes:\n  0  success\n")
+	assert.Contains(t, out.String(), "  3  min-entropy below -fail-below")
+}
+
+func TestRunCLI_IIDCheckConflicts(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid-check", "-non-iid"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "-iid-check cannot be combined with -non-iid")
+
+	out.Reset()
+	code = runCLI([]string{"-iid-check", "-fail-below", "5"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "-iid-check cannot be combined with -h-submitter, -fail-below, or -estimators")
+}
+
+func TestRunCLI_InvalidJobs(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-jobs", "0", "a.bin", "b.bin"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "jobs must be at least 1")
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-threads", "-1", "a.bin", "b.bin"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "threads must not be negative, got -1")
+}
+
 func TestRunCLI_FileNotFound(t *testing.T) {
 	var out bytes.Buffer
 	code := runCLI([]string{"-iid", "-bits", "8", "nope.bin"}, bytes.NewReader(nil), &out, &out)
@@ -78,3 +322,1019 @@ func TestRunCLI_OutputFileErrorPath(t *testing.T) {
 	assert.Equal(t, 1, got.ErrorCode)
 	assert.Contains(t, got.ErrorMessage, "data is empty")
 }
+
+func TestRunCLI_InvalidInputEncoding(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-input-encoding", "ascii85"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "invalid input encoding")
+}
+
+func TestRunCLI_MalformedEncodedInput(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-bits", "8", "-input-encoding", "hex"}, bytes.NewReader([]byte("0g")), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "malformed hex input")
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-input-encoding", "base64"}, bytes.NewReader([]byte("AQ*D")), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "malformed base64 input")
+}
+
+func TestDecodeInput(t *testing.T) {
+	want := []byte{0x01, 0x02, 0xfe, 0xff}
+
+	got, err := decodeInput([]byte("0102\nFE ff\r\n"), encodingHex)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = decodeInput([]byte("AQL+\n/w==\n"), encodingBase64)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	raw := []byte("01 02")
+	got, err = decodeInput(raw, encodingBinary)
+	require.NoError(t, err)
+	assert.Equal(t, raw, got)
+
+	// Mixed whitespace, mixed case and missing padding.
+	got, err = decodeInput([]byte(" 0102\tfE\r\n\vFf\f"), encodingHex)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = decodeInput([]byte("AQL+/w"), encodingBase64)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = decodeInput([]byte("AQL+\r\n\t/w\n"), encodingBase64)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = decodeInput([]byte("012"), encodingHex)
+	assert.EqualError(t, err, "malformed hex input: odd number of hex digits (3)")
+
+	// Offsets count the whitespace that precedes the invalid character.
+	_, err = decodeInput([]byte("01 02\nfx"), encodingHex)
+	assert.EqualError(t, err, `malformed hex input: invalid character 'x' at byte offset 7`)
+
+	_, err = decodeInput([]byte("AQL+\n/*=="), encodingBase64)
+	assert.EqualError(t, err, `malformed base64 input: invalid character '*' at byte offset 6`)
+
+	_, err = decodeInput([]byte("AQL+/"), encodingBase64)
+	assert.EqualError(t, err, "malformed base64 input: truncated input of 5 base64 characters")
+
+	_, err = decodeInput([]byte("A*L+/"), encodingBase64)
+	assert.EqualError(t, err, `malformed base64 input: invalid character '*' at byte offset 1`)
+
+	enc, err := parseInputEncoding("RAW")
+	require.NoError(t, err)
+	assert.Equal(t, encodingBinary, enc)
+}
+
+func TestExtractColumn(t *testing.T) {
+	csv := []byte("# timestamp,raw_sample\n1000,3\n1001,15\r\n\n1002, 0\n")
+	got, err := extractColumn(csv, 2, ",", 4)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{3, 15, 0}, got)
+
+	got, err = extractColumn([]byte("1 200\n2\t255\n"), 2, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{200, 255}, got)
+
+	_, err = extractColumn([]byte("1000,3\n1001,16\n"), 2, ",", 4)
+	require.Error(t, err)
+	assert.Equal(t, "line 2, column 2: value 16 does not fit in 4 bits per symbol", err.Error())
+
+	_, err = extractColumn([]byte("1000,3\n1001,abc\n"), 2, ",", 8)
+	require.Error(t, err)
+	assert.Equal(t, `line 2, column 2: "abc" is not a non-negative integer`, err.Error())
+
+	_, err = extractColumn([]byte("1000,3\n1001\n"), 2, ",", 8)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2: has 1 columns")
+}
+
+func TestRunCLI_InvalidWindow(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-offset", "-1"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "offset and length must not be negative")
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-bits", "8", "-offset", "2", "-length", "2"}, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "Error selecting window of stdin")
+}
+
+func TestRunCLI_InvalidBitOrder(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-bit-order", "middle"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "invalid bit order")
+}
+
+func TestRunCLI_PermutationValidation(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-permutation-rounds", "0"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "permutation-rounds must be at least 1")
+
+	out.Reset()
+	code = runCLI([]string{"-non-iid", "-permutation-seed", "1"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "require -iid or -iid-check")
+}
+
+func TestRunCLI_ColumnValidation(t *testing.T) {
+	var out bytes.Buffer
+	code := runCLI([]string{"-iid", "-column", "-1"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "column must be 1 or greater")
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-column", "2", "-input-encoding", "hex"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "-column cannot be combined")
+
+	out.Reset()
+	code = runCLI([]string{"-iid", "-bits", "1", "-column", "2"}, bytes.NewReader([]byte("0,1\n1,2\n")), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "Error parsing stdin: line 2, column 2: value 2 does not fit in 1 bits per symbol")
+}
+
+func TestRunCLI_QuickValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-quick", "-non-iid"}, "-quick cannot be combined with -iid, -non-iid, -iid-check, or -health"},
+		{[]string{"-quick", "-health", "-h-submitter", "4"}, "-quick cannot be combined with -iid, -non-iid, -iid-check, or -health"},
+		{[]string{"-quick", "-h-submitter", "4"}, "-quick cannot be combined with -h-submitter"},
+		{[]string{"-quick", "-bit-order", "lsb"}, "-quick cannot be combined with -h-submitter, -estimators, -histogram, -raw, or -bit-order"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestRunCLI_LRSValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-lrs", "-non-iid"}, "-lrs cannot be combined with -quick, -iid, -non-iid, -all, -iid-check, or -health"},
+		{[]string{"-lrs", "-quick"}, "-lrs cannot be combined with -quick"},
+		{[]string{"-lrs", "-estimators", "mcv"}, "-lrs cannot be combined with -h-submitter, -estimators, -histogram, -raw, or -bit-order"},
+		{[]string{"-lrs", "-compare", "b.bin"}, "-compare cannot be combined with"},
+		{[]string{"-lrs", "-format", "nist-json", "-output", "r.json"}, "-format nist-json cannot be combined with"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestRunCLI_CompareValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-non-iid", "-compare", "b.bin", "-fail-below", "1"}, "-compare cannot be combined with -quick, -lrs, -iid-check, -health, -fail-below, -histogram, or -raw"},
+		{[]string{"-iid-check", "-compare", "b.bin"}, "-compare cannot be combined with"},
+		{[]string{"-non-iid", "-compare", "b.bin", "a.bin", "c.bin"}, "-compare accepts a single primary input"},
+		{[]string{"-non-iid", "-compare-tolerance", "0.5"}, "-compare-tolerance requires -compare"},
+		{[]string{"-non-iid", "-compare", "b.bin", "-compare-tolerance", "-1"}, "compare-tolerance must be a non-negative number, got -1"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+
+	var out bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-compare", filepath.Join(t.TempDir(), "missing.bin")}, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "Error reading file")
+}
+
+func TestRunCLI_AllValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-all", "-quick"}, "-all cannot be combined with -quick, -health, or -iid-check"},
+		{[]string{"-all", "-iid-check"}, "-all cannot be combined with"},
+		{[]string{"-all", "-compare", "b.bin"}, "-all cannot be combined with -compare or -format nist-json"},
+		{[]string{"-iid", "-non-iid", "-format", "nist-json", "-output", "r.json"}, "-all cannot be combined with -compare or -format nist-json"},
+		{[]string{"-all", "a.bin", "b.bin"}, "-all accepts a single input file"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestRunCLI_RecursiveValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-non-iid", "-pattern", "*.bin", "a.bin"}, "-pattern, -follow-symlinks, and -hidden require -recursive"},
+		{[]string{"-non-iid", "-hidden", "a.bin"}, "require -recursive"},
+		{[]string{"-non-iid", "-recursive"}, "-recursive requires at least one directory"},
+		{[]string{"-all", "-recursive", "dir"}, "-recursive cannot be combined with -health, -compare, or -all"},
+		{[]string{"-non-iid", "-recursive", "-compare", "b.bin", "dir"}, "-recursive cannot be combined with"},
+		{[]string{"-non-iid", "-recursive", "-pattern", "[", "dir"}, `invalid pattern "["`},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestRunCLI_ArchiveValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-non-iid", "-archive"}, "-archive requires at least one archive"},
+		{[]string{"-all", "-archive", "a.tar"}, "-archive cannot be combined with -health, -compare, or -all"},
+		{[]string{"-non-iid", "-archive", "-compare", "b.bin", "a.tar"}, "-archive cannot be combined with"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestCombineExitCodes(t *testing.T) {
+	assert.Equal(t, 0, combineExitCodes(0, 0))
+	assert.Equal(t, 3, combineExitCodes(0, 3))
+	assert.Equal(t, 3, combineExitCodes(3, 0))
+	assert.Equal(t, 1, combineExitCodes(3, 1))
+	assert.Equal(t, 1, combineExitCodes(1, 3))
+}
+
+func TestRunCLI_FormatValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-non-iid", "-format", "xml", "-output", "r.json"}, `format must be json, nist-json, csv, ndjson, or yaml, got "xml"`},
+		{[]string{"-format", "yml", "-iid", "-non-iid", "-compare", "b.bin"}, `format must be json, nist-json, csv, ndjson, or yaml, got "yml"`},
+		{[]string{"-non-iid", "-format", "csv", "-compare", "b.bin"}, "-format csv cannot be combined with -compare or -health"},
+		{[]string{"-health", "-h-submitter", "4", "-format", "csv"}, "-format csv cannot be combined with"},
+		{[]string{"-non-iid", "-format", "ndjson", "-compare", "b.bin"}, "-format ndjson cannot be combined with -compare or -health"},
+		{[]string{"-health", "-h-submitter", "4", "-format", "yaml"}, "-format yaml cannot be combined with -compare or -health"},
+		{[]string{"-non-iid", "-format", "nist-json"}, "-format nist-json requires -output"},
+		{[]string{"-quick", "-format", "nist-json", "-output", "r.json"}, "-format nist-json cannot be combined with -quick, -lrs, or -compare"},
+		{[]string{"-non-iid", "-format", "nist-json", "-output", "r.json", "-compare", "b.bin"}, "-format nist-json cannot be combined with"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestRunCLI_ConditioningValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-non-iid", "-conditioning", "512,256"}, `conditioning must be n_in,n_out,nw, got "512,256"`},
+		{[]string{"-non-iid", "-conditioning", "512,x,256"}, `conditioning must be n_in,n_out,nw, got "512,x,256"`},
+		{[]string{"-non-iid", "-conditioning", "256,512,512"}, "need n_out <= nw <= n_in"},
+		{[]string{"-non-iid", "-conditioning", "0,0,0"}, "widths must be positive"},
+		{[]string{"-quick", "-conditioning", "512,256,256"}, "-conditioning cannot be combined with -quick, -lrs, -iid-check, -health, -compare, or -all"},
+		{[]string{"-iid", "-non-iid", "-conditioning", "512,256,256"}, "-conditioning cannot be combined with"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestRunCLI_RemoteValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-non-iid", "-tls"}, "Error: -tls, -ca, and -token require -remote\n"},
+		{[]string{"-non-iid", "-token", "secret"}, "Error: -tls, -ca, and -token require -remote\n"},
+		{[]string{"-quick", "-remote", "localhost:9090"}, "Error: -remote cannot be combined with -quick, -lrs, -health, or -restart\n"},
+		{[]string{"-non-iid", "-restart", "2x2", "-h-initial", "4", "-remote", "localhost:9090"}, "-remote cannot be combined with -quick"},
+		{[]string{"-iid", "-threads", "2", "-remote", "localhost:9090"}, "Error: -remote cannot be combined with -permutation-rounds, -permutation-seed, -threads, or -format nist-json\n"},
+		{[]string{"-non-iid", "-format", "nist-json", "-output", "r.json", "-remote", "localhost:9090"}, "-remote cannot be combined with -permutation-rounds"},
+		{[]string{"-non-iid", "-remote", "localhost:9090", "-ca", "missing.pem"}, "Error: reading -ca file: open missing.pem"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o644))
+	var out bytes.Buffer
+	code := runCLI([]string{"-non-iid", "-remote", "localhost:9090", "-ca", notPEM}, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "holds no PEM certificate")
+}
+
+func TestRunConditioned_Validation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-h-in", "5"}, "widths must be positive"},
+		{[]string{"-n-in", "256", "-n-out", "512", "-nw", "512", "-h-in", "5"}, "need n_out <= nw <= n_in"},
+		{[]string{"-n-in", "512", "-n-out", "256", "-nw", "256"}, "Error: h-in must be above 0 and at most n_in = 512 bits, got 0\n"},
+		{[]string{"-n-in", "512", "-n-out", "256", "-nw", "256", "-h-in", "513"}, "Error: h-in must be above 0 and at most n_in = 512 bits, got 513\n"},
+		{[]string{"-n-in", "512", "-n-out", "256", "-nw", "256", "-h-in", "5", "-bits", "9"}, "Error: bits per symbol must be 0-8, got 9\n"},
+		{[]string{"-n-in", "512", "-n-out", "256", "-nw", "256", "-h-in", "5", "a.bin", "b.bin"}, "Error: conditioned accepts a single input\n"},
+		{[]string{"-n-in", "x"}, `invalid value "x" for flag -n-in`},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(append([]string{"conditioned"}, tc.args...), bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestRunCLI_RestartValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-non-iid", "-restart", "1000"}, `restart must be rows x cols such as 1000x1000, got "1000"`},
+		{[]string{"-non-iid", "-restart", "1000x1"}, `restart needs at least 2 rows and 2 columns, got "1000x1"`},
+		{[]string{"-non-iid", "-restart", "10x10"}, "-restart requires -h-initial, the initial entropy estimate H_I"},
+		{[]string{"-non-iid", "-bits", "4", "-restart", "10x10", "-h-initial", "5"}, "h-initial must be above 0 and at most 4 bits per symbol, got 5"},
+		{[]string{"-non-iid", "-restart", "10x10", "-h-initial", "5", "-restart-layout", "diagonal"}, "invalid restart layout: diagonal (use row or column)"},
+		{[]string{"-quick", "-restart", "10x10", "-h-initial", "5"}, "-restart cannot be combined with -quick, -lrs, -iid-check, -health, -compare, -all, -conditioning, -sample-bytes, or -stride"},
+		{[]string{"-all", "-restart", "10x10", "-h-initial", "5"}, "-restart cannot be combined with"},
+		{[]string{"-non-iid", "-restart", "10x10", "-h-initial", "5", "-fail-below", "1"}, "-restart cannot be combined with -h-submitter, -fail-below, -histogram, -raw, or -format nist-json"},
+		{[]string{"-non-iid", "-h-initial", "5"}, "-restart-layout and -h-initial require -restart"},
+		{[]string{"-non-iid", "-restart-layout", "column"}, "-restart-layout and -h-initial require -restart"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestParseRestart(t *testing.T) {
+	for _, value := range []string{"1000x1000", "1000X1000", "1000,1000", " 1000 x 1000 "} {
+		rows, cols, err := parseRestart(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, 1000, rows, value)
+		assert.Equal(t, 1000, cols, value)
+	}
+	rows, cols, err := parseRestart("20x50")
+	require.NoError(t, err)
+	assert.Equal(t, []int{20, 50}, []int{rows, cols})
+}
+
+func TestSplitRestart(t *testing.T) {
+	// A capture of 3 blocks of 5 samples, 10*block + index.
+	capture := []byte{0, 1, 2, 3, 4, 10, 11, 12, 13, 14, 20, 21, 22, 23, 24}
+
+	matrix, blockSize, err := splitRestart(capture, entropy.RestartMatrix{Rows: 3, Cols: 2}, schemeSequential)
+	require.NoError(t, err)
+	assert.Equal(t, 5, blockSize)
+	assert.Equal(t, []byte{0, 1, 10, 11, 20, 21}, matrix)
+
+	matrix, _, err = splitRestart(capture, entropy.RestartMatrix{Rows: 3, Cols: 2, Layout: entropy.ColumnMajor}, schemeSequential)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 10, 20, 1, 11, 21}, matrix)
+
+	// Interleaved, restart i holds samples i, i+3, i+6, ...
+	matrix, blockSize, err = splitRestart(capture, entropy.RestartMatrix{Rows: 3, Cols: 3}, schemeInterleaved)
+	require.NoError(t, err)
+	assert.Zero(t, blockSize)
+	assert.Equal(t, []byte{0, 3, 11, 1, 4, 12, 2, 10, 13}, matrix)
+
+	_, _, err = splitRestart(capture, entropy.RestartMatrix{Rows: 4, Cols: 4}, schemeSequential)
+	assert.EqualError(t, err, "a 4x4 restart matrix needs at least 16 samples, the capture holds 15")
+}
+
+func TestRunSplitRestart(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "capture.bin")
+	capture := make([]byte, 50)
+	for i := range capture {
+		capture[i] = byte(i)
+	}
+	require.NoError(t, os.WriteFile(input, capture, 0o600))
+	out := filepath.Join(dir, "matrix.bin")
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"split-restart", "-rows", "5", "-cols", "4", "-layout", "column", "-out", out, input}, nil, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "Wrote 5 restarts x 4 samples (sequential scheme, column layout) to "+out+"\n"+
+		"Manifest: "+out+".manifest.json\n", stdout.String())
+
+	matrix, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 10, 20, 30, 40, 1, 11, 21, 31, 41, 2, 12, 22, 32, 42, 3, 13, 23, 33, 43}, matrix)
+
+	manifest, err := readRestartManifest(out)
+	require.NoError(t, err)
+	assert.Equal(t, &RestartManifest{
+		Version:    version,
+		Source:     input,
+		SourceSize: 50,
+		Scheme:     "sequential",
+		BlockSize:  10,
+		Rows:       5,
+		Cols:       4,
+		Layout:     "column",
+		Matrix:     "matrix.bin",
+		SHA256:     sha256Hex(matrix),
+	}, manifest)
+
+	manifest, err = readRestartManifest(input)
+	require.NoError(t, err)
+	assert.Nil(t, manifest)
+
+	stderr.Reset()
+	code = runCLI([]string{"split-restart", "-rows", "10", "-cols", "10", "-out", out, input}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "Error: "+input+": a 10x10 restart matrix needs at least 100 samples, the capture holds 50\n", stderr.String())
+}
+
+func TestRunSplitRestart_Validation(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-rows", "1", "-out", "m.bin"}, "Error: -rows and -cols must be at least 2, got 1 and 1000\n"},
+		{[]string{"-scheme", "random", "-out", "m.bin"}, "Error: -scheme must be sequential or interleaved, got \"random\"\n"},
+		{[]string{"-layout", "diagonal", "-out", "m.bin"}, "Error: invalid restart layout: diagonal (use row or column)\n"},
+		{[]string{}, "Error: split-restart requires -out\n"},
+		{[]string{"-out", "m.bin", "a.bin", "b.bin"}, "Error: split-restart accepts a single input\n"},
+	}
+	for _, tc := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runCLI(append([]string{"split-restart"}, tc.args...), nil, &stdout, &stderr)
+		assert.Equal(t, 2, code, tc.args)
+		assert.Equal(t, tc.want, stderr.String(), tc.args)
+	}
+}
+
+func TestRunCompareFiles_Validation(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"a.json"}, "Error: compare requires a baseline and a current file, got 1 files\n"},
+		{[]string{"a.json", "b.json", "c.json"}, "Error: compare requires a baseline and a current file, got 3 files\n"},
+		{[]string{"a.json", "b.json", "-tolerance", "-1"}, "Error: -tolerance must not be negative, got -1\n"},
+	}
+	for _, tc := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runCLI(append([]string{"compare"}, tc.args...), nil, &stdout, &stderr)
+		assert.Equal(t, 2, code, tc.args)
+		assert.Equal(t, tc.want, stderr.String(), tc.args)
+	}
+}
+
+// resultDocument returns a Non-IID result of filename with two estimators.
+func resultDocument(filename string, minEntropy float64) JSONOutput {
+	return JSONOutput{
+		SchemaVersion: schema.Version,
+		Version:       version,
+		Filename:      filename,
+		TestType:      "Non-IID",
+		BitsPerSymbol: 8,
+		DataSize:      1000000,
+		MinEntropy:    minEntropy,
+		HOriginal:     minEntropy,
+		HBitstring:    7.2,
+		HAssessed:     minEntropy,
+		HFinal:        minEntropy,
+		EstimatorResults: []EstimatorResultOutput{
+			{ID: "mcv", Name: "Most Common Value", EntropyEstimate: minEntropy, Passed: true, IsEntropyValid: true},
+			{ID: "markov", Name: "Markov", EntropyEstimate: 7.4, Passed: true, IsEntropyValid: true},
+		},
+	}
+}
+
+func TestRunCompareFiles(t *testing.T) {
+	dir := t.TempDir()
+	baseline := filepath.Join(dir, "baseline.json")
+	current := filepath.Join(dir, "current.json")
+	writeJSON(baseline, io.Discard, resultDocument("data.bin", 6.5))
+
+	// Within the tolerance, with the options after the file names.
+	writeJSON(current, io.Discard, resultDocument("data.bin", 6.5004))
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"compare", baseline, current, "-tolerance", "0.001"}, nil, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "==> data.bin (Non-IID) <==\n  all 10 values within the tolerance\n")
+	assert.Contains(t, stdout.String(), "0 of 1 results differ\n")
+
+	// Beyond the tolerance, with the JSON diff.
+	diff := filepath.Join(dir, "diff.json")
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"compare", "-tolerance", "0.0001", "-output", diff, baseline, current}, nil, &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stdout.String(), "  min_entropy                                6.500000     6.500400    +0.000400  DIFFERS\n")
+	assert.Contains(t, stdout.String(), "  Most Common Value                          6.500000     6.500400    +0.000400  DIFFERS\n")
+	assert.NotContains(t, stdout.String(), "Markov")
+	assert.Contains(t, stderr.String(), "FAIL: "+baseline+" and "+current+" differ by more than 0.0001 bits per sample or in their results\n")
+
+	var out ResultsDiffOutput
+	encoded, err := os.ReadFile(diff)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(encoded, &out))
+	assert.False(t, out.Within)
+	require.Len(t, out.Documents, 1)
+	assert.False(t, out.Documents[0].Within)
+	var differing []string
+	for _, d := range out.Documents[0].Deltas {
+		if !d.Within {
+			differing = append(differing, d.Name)
+		}
+	}
+	assert.Equal(t, []string{"min_entropy", "h_original", "h_assessed", "h_final", "Most Common Value"}, differing)
+
+	// A missing estimator and a changed sample size differ regardless of
+	// the tolerance.
+	doc := resultDocument("data.bin", 6.5)
+	doc.DataSize = 500000
+	doc.EstimatorResults = doc.EstimatorResults[:1]
+	writeJSON(current, io.Discard, doc)
+	stdout.Reset()
+	code = runCLI([]string{"compare", "-tolerance", "1", baseline, current}, nil, &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stdout.String(), "  data_size                                   1000000       500000      -500000  DIFFERS\n")
+	assert.Contains(t, stdout.String(), "  Markov                                     7.400000      missing            -  MISSING\n")
+}
+
+func TestRunCompareFiles_MultipleResults(t *testing.T) {
+	dir := t.TempDir()
+	baseline := filepath.Join(dir, "baseline.json")
+	current := filepath.Join(dir, "current.ndjson")
+
+	// A batch array against NDJSON: b.bin is missing from the current file
+	// and c.bin from the baseline.
+	writeJSON(baseline, io.Discard, []any{resultDocument("a.bin", 6.5), resultDocument("b.bin", 6.5)})
+	var ndjson bytes.Buffer
+	for _, doc := range []JSONOutput{resultDocument("c.bin", 6.5), resultDocument("a.bin", 6.5)} {
+		encoded, err := json.Marshal(doc)
+		require.NoError(t, err)
+		ndjson.Write(append(encoded, '\n'))
+	}
+	require.NoError(t, os.WriteFile(current, ndjson.Bytes(), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"compare", baseline, current}, nil, &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stdout.String(), "==> a.bin (Non-IID) <==\n  all 10 values within the tolerance\n")
+	assert.Contains(t, stdout.String(), "==> b.bin (Non-IID) <==\n  missing from the current file\n")
+	assert.Contains(t, stdout.String(), "==> c.bin (Non-IID) <==\n  missing from the baseline file\n")
+	assert.Contains(t, stdout.String(), "2 of 3 results differ\n")
+
+	// The two tracks of a -all document are compared on their own.
+	iid := resultDocument("a.bin", 7.5)
+	iid.TestType = "IID"
+	iid.EstimatorResults = append(iid.EstimatorResults, EstimatorResultOutput{ID: "chi_square", Name: "Chi-Square Tests", EntropyEstimate: -1, Passed: true})
+	nonIID := resultDocument("a.bin", 6.5)
+	writeJSON(baseline, io.Discard, AllOutput{Version: version, Filename: "a.bin", TestType: allTestType, IID: &iid, NonIID: &nonIID})
+	iid.EstimatorResults[2].Passed = false
+	writeJSON(current, io.Discard, AllOutput{Version: version, Filename: "a.bin", TestType: allTestType, IID: &iid, NonIID: &nonIID})
+	stdout.Reset()
+	code = runCLI([]string{"compare", baseline, current}, nil, &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stdout.String(), "  Chi-Square Tests                               PASS         FAIL            -  DIFFERS\n")
+	assert.Contains(t, stdout.String(), "==> a.bin (Non-IID) <==\n  all 10 values within the tolerance\n")
+	assert.Contains(t, stdout.String(), "1 of 2 results differ\n")
+}
+
+func TestRunCompareFiles_Errors(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	writeJSON(valid, io.Discard, resultDocument("a.bin", 6.5))
+	empty := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(empty, nil, 0o600))
+	other := filepath.Join(dir, "manifest.json")
+	require.NoError(t, os.WriteFile(other, []byte(`{"rows": 10, "cols": 10}`), 0o600))
+
+	for _, tc := range []struct {
+		file string
+		want string
+	}{
+		{filepath.Join(dir, "absent.json"), "no such file or directory"},
+		{empty, empty + " holds no results"},
+		{other, other + ": not an ea_tool result document"},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := runCLI([]string{"compare", valid, tc.file}, nil, &stdout, &stderr)
+		assert.Equal(t, 1, code, tc.file)
+		assert.Contains(t, stderr.String(), tc.want, tc.file)
+	}
+}
+
+func TestRunWatch_Validation(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-non-iid"}, "Error: watch requires -dir and -output-dir\n"},
+		{[]string{"-dir", "in", "-output-dir", "out"}, "Error: Must specify exactly one of -iid or -non-iid\n"},
+		{[]string{"-dir", "in", "-output-dir", "out", "-iid", "-non-iid"}, "Error: Must specify exactly one of -iid or -non-iid\n"},
+		{[]string{"-dir", "in", "-output-dir", "out", "-non-iid", "-bits", "9"}, "Error: bits per symbol must be 0-8, got 9\n"},
+		{[]string{"-dir", "in", "-output-dir", "out", "-non-iid", "-bits", "4", "-h-submitter", "5"}, "Error: h-submitter must be between 0 and bits per symbol, got 5\n"},
+		{[]string{"-dir", "in", "-output-dir", "out", "-non-iid", "-settle", "0s"}, "Error: -settle must be positive, got 0s\n"},
+		{[]string{"-dir", "in", "-output-dir", "out", "-non-iid", "data.bin"}, "Error: watch takes no file arguments, got data.bin\n"},
+	}
+	for _, tc := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runCLI(append([]string{"watch"}, tc.args...), nil, &stdout, &stderr)
+		assert.Equal(t, 2, code, tc.args)
+		assert.Equal(t, tc.want, stderr.String(), tc.args)
+	}
+}
+
+func TestMoveUnique(t *testing.T) {
+	dir := t.TempDir()
+	done := filepath.Join(dir, "done")
+	require.NoError(t, os.Mkdir(done, 0o755))
+	for i, want := range []string{"capture.bin", "capture.1.bin", "capture.2.bin"} {
+		src := filepath.Join(dir, "capture.bin")
+		require.NoError(t, os.WriteFile(src, []byte{byte(i)}, 0o600))
+		target, err := moveUnique(src, done, "capture.bin")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(done, want), target)
+		moved, err := os.ReadFile(target)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{byte(i)}, moved)
+	}
+	assert.NoFileExists(t, filepath.Join(dir, "capture.bin"))
+}
+
+func TestPrintEstimators(t *testing.T) {
+	var out bytes.Buffer
+	printEstimators(&out, nil)
+	assert.Empty(t, out.String())
+
+	printEstimators(&out, []EstimatorResultOutput{
+		{Name: "Chi-Square Tests", EntropyEstimate: -1, Passed: false},
+		{Name: "Most Common Value", EntropyEstimate: 7.2, Passed: true, IsEntropyValid: true},
+	})
+	assert.Equal(t, "\nEstimators:\n"+
+		"  Estimator                                    Result\n"+
+		"  Chi-Square Tests                               FAIL\n"+
+		"  Most Common Value                          7.200000  <- min-entropy\n", out.String())
+}
+
+func TestRunCLI_Quick(t *testing.T) {
+	// p = 0.5 over 100 samples gives -log2(0.5 + z*sqrt(0.25/99)) = 0.667859.
+	data := bytes.Repeat([]byte{0, 1}, 50)
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-quick", "-bits", "1"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "MCV Estimate:    0.667859\n")
+	assert.Contains(t, stdout.String(), "not an SP 800-90B assessment")
+
+	stdout.Reset()
+	code = runCLI([]string{"-quick", "-bits", "1", "-fail-below", "0.7"}, bytes.NewReader(data), &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stderr.String(), "FAIL: MCV estimate 0.667859 is below the required 0.700000")
+
+	tmpFile := filepath.Join(t.TempDir(), "quick.json")
+	code = runCLI([]string{"-quick", "-bits", "1", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, quickTestType, got.TestType)
+	assert.Equal(t, "go", got.Backend)
+	assert.InDelta(t, 0.6678585610843556, got.MinEntropy, 1e-12)
+
+	stderr.Reset()
+	code = runCLI([]string{"-quick"}, bytes.NewReader([]byte{1}), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "need at least 2 samples")
+}
+
+func TestRunCLI_LRS(t *testing.T) {
+	// "the cat sat" occurs twice; the estimate is the SP 800-90B 6.3.6 value.
+	data := []byte("the cat sat on the mat, the cat sat")
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-lrs", "-bits", "8"}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Length:          11 symbols\n")
+	assert.Contains(t, stdout.String(), "LRS Estimate:    0.290934\n")
+	assert.Contains(t, stdout.String(), "not an SP 800-90B assessment")
+
+	code = runCLI([]string{"-lrs", "-bits", "8", "-fail-below", "0.5"}, bytes.NewReader(data), &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stderr.String(), "FAIL: LRS estimate 0.290934 is below the required 0.500000")
+
+	tmpFile := filepath.Join(t.TempDir(), "lrs.json")
+	code = runCLI([]string{"-lrs", "-bits", "8", "-output", tmpFile}, bytes.NewReader(data), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	raw, err := os.ReadFile(tmpFile)
+	require.NoError(t, err)
+	var got JSONOutput
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, lrsTestType, got.TestType)
+	require.NotNil(t, got.LRSLength)
+	assert.Equal(t, 11, *got.LRSLength)
+	assert.InDelta(t, 0.2909337557679664, got.MinEntropy, 1e-12)
+
+	// Without a repeat there is no estimate, which -fail-below does not fail.
+	stdout.Reset()
+	code = runCLI([]string{"-lrs", "-bits", "8", "-fail-below", "0.5"}, bytes.NewReader([]byte("abcdefgh")), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "Length:          0 symbols\n")
+	assert.Contains(t, stdout.String(), "LRS Estimate:    not applicable\n")
+}
+
+func TestRunCLI_ValidateValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"validate", "-against", "go"}, `-against must be cgo, got "go"`},
+		{[]string{"validate", "-bits", "9"}, "bits per symbol must be 0-8"},
+		{[]string{"validate", "-tolerance", "-1"}, "tolerance must be a non-negative number"},
+		{[]string{"validate", "-estimators", "bogus"}, "unknown estimator name"},
+		{[]string{"validate", "-bit-order", "middle"}, "bit order"},
+		{[]string{"validate", "a.bin", "b.bin"}, "validate accepts a single input"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestRunCLI_HealthValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-health", "-iid"}, "-health cannot be combined with -iid, -non-iid, or -iid-check"},
+		{[]string{"-health", "-bits", "8"}, "-health requires -h-submitter"},
+		{[]string{"-health", "-h-submitter", "4", "-fail-below", "3"}, "-health cannot be combined with -fail-below"},
+		{[]string{"-health", "-h-submitter", "4", "-raw"}, "-health cannot be combined with -fail-below, -estimators, -histogram, -raw, or -output"},
+		{[]string{"-health", "-h-submitter", "4", "a.bin", "b.bin"}, "-health accepts a single input file"},
+		{[]string{"-health", "-h-submitter", "0"}, "min-entropy per sample must be in (0, 8]"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader([]byte{1, 2, 3}), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestRunCLI_Health(t *testing.T) {
+	healthy := make([]byte, 20000)
+	for i := range healthy {
+		healthy[i] = byte(i * 7 % 251)
+	}
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-health", "-bits", "8", "-h-submitter", "7"}, bytes.NewReader(healthy), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Samples:         20000")
+	assert.Contains(t, stdout.String(), "RCT cutoff:      4")
+	assert.Contains(t, stdout.String(), "APT cutoff:      26 (window 1024)")
+	assert.Contains(t, stdout.String(), "Result:          PASS")
+
+	// A stuck-at fault fails with the index of the sample that reached the
+	// RCT cutoff.
+	stuck := append(append([]byte{}, healthy[:1000]...), make([]byte, 10)...)
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"-health", "-bits", "8", "-h-submitter", "7"}, bytes.NewReader(stuck), &stdout, &stderr)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, stdout.String(), "Result:          FAIL")
+	assert.Contains(t, stderr.String(), "FAIL: repetition count test: sample 0x00 at index 1003 seen 4 times, cutoff 4")
+}
+
+func TestRunCLI_MonitorValidation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"monitor", "-bits", "9", "-h-submitter", "4"}, "bits per sample must be 1-8"},
+		{[]string{"monitor", "-bits", "8"}, "-h-submitter must be greater than 0"},
+		{[]string{"monitor", "-bits", "1", "-h-submitter", "2"}, "-h-submitter must be greater than 0 and at most bits per sample"},
+		{[]string{"monitor", "-h-submitter", "4", "-every", "0"}, "window and every must be at least 1"},
+		{[]string{"monitor", "-h-submitter", "4", "a", "b"}, "monitor accepts a single input"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader(nil), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+
+	var out bytes.Buffer
+	code := runCLI([]string{"monitor", "-h-submitter", "4", "missing.bin"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "Error opening missing.bin")
+}
+
+func TestProgressLine(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressLine(&buf)
+	p.report("Most Common Value", 0)
+	p.report(entropy.ProgressDone, 100)
+	p.end()
+	assert.Regexp(t, fmt.Sprintf(`^\rProgress:   0\.0%% %-42s +\S+\rProgress: 100\.0%% %-42s +\S+\n$`, "Most Common Value", "Done"), buf.String())
+
+	// A line left open by a failed assessment is terminated once.
+	buf.Reset()
+	p = newProgressLine(&buf)
+	p.report("Markov Test", 25)
+	p.end()
+	p.end()
+	assert.Regexp(t, `Markov Test +\S+\n$`, buf.String())
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+
+	var nilLine *progressLine
+	assert.NotPanics(t, nilLine.end)
+}
+
+func TestProgressLine_RedrawsElapsedTime(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressLine(&buf)
+	p.report("Lag Prediction Test", 60)
+	time.Sleep(progressInterval + 200*time.Millisecond)
+	p.end()
+	assert.Equal(t, 2, strings.Count(buf.String(), "Lag Prediction Test"), buf.String())
+}
+
+func TestReadProgress(t *testing.T) {
+	var buf bytes.Buffer
+	r := &readProgress{w: &buf}
+	r.report(1, 4<<20)
+	r.report(2, 4<<20) // unchanged at a tenth of a percent
+	r.report(2<<20, 4<<20)
+	r.end()
+	assert.Equal(t, "\rReading:    0.0% of 4.0 MiB\rReading:   50.0% of 4.0 MiB\n", buf.String())
+
+	buf.Reset()
+	r = &readProgress{w: &buf}
+	r.report(3<<20, 0)
+	r.end()
+	r.end()
+	assert.Equal(t, "\rReading:  3.0 MiB\n", buf.String())
+}
+
+func TestFormatElapsedAndBytes(t *testing.T) {
+	assert.Equal(t, "1.2s", formatElapsed(1234*time.Millisecond))
+	assert.Equal(t, "2m3s", formatElapsed(123456*time.Millisecond))
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "500.0 MiB", formatBytes(500<<20))
+	assert.Equal(t, "2.0 GiB", formatBytes(2<<30))
+}
+
+func TestIsTerminal(t *testing.T) {
+	assert.False(t, isTerminal(&bytes.Buffer{}))
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.False(t, isTerminal(f))
+}
+
+func TestRunReport_Validation(t *testing.T) {
+	badTemplate := filepath.Join(t.TempDir(), "bad.tmpl")
+	require.NoError(t, os.WriteFile(badTemplate, []byte("{{.Filename"), 0o600))
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"report"}, "report requires -iid, -non-iid, or both"},
+		{[]string{"report", "-non-iid", "-bits", "9"}, "bits per symbol must be 0-8, got 9"},
+		{[]string{"report", "-non-iid", "-bits", "4", "-h-submitter", "5"}, "h-submitter must be between 0 and bits per symbol, got 5"},
+		{[]string{"report", "-iid", "-non-iid", "-estimators", "markov"}, "markov"},
+		{[]string{"report", "-non-iid", "-bits", "4", "-fail-below", "5"}, "fail-below must not exceed 4 bits per symbol, got 5"},
+		{[]string{"report", "-non-iid", "-format", "pdf"}, `report format must be markdown or html, got "pdf"`},
+		{[]string{"report", "-non-iid", "-template", filepath.Join(t.TempDir(), "missing.tmpl")}, "invalid -template:"},
+		{[]string{"report", "-non-iid", "-template", badTemplate}, "invalid -template:"},
+		{[]string{"report", "-non-iid", "a.bin", "b.bin"}, "report accepts a single input"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader(nil), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+}
+
+func TestLoadReportTemplate_Defaults(t *testing.T) {
+	for _, format := range []string{reportMarkdown, reportHTML} {
+		_, err := loadReportTemplate(format, defaultTemplate)
+		require.NoError(t, err, format)
+	}
+	format, err := parseReportFormat("md")
+	require.NoError(t, err)
+	assert.Equal(t, reportMarkdown, format)
+}
+
+func TestRunCapture_Validation(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"capture", "-non-iid"}, "capture requires -device"},
+		{[]string{"capture", "-device", "-", "-samples", "0", "-non-iid"}, "samples must be at least 1, got 0"},
+		{[]string{"capture", "-device", "-", "-read-timeout", "-1s", "-non-iid"}, "read-timeout must not be negative"},
+		{[]string{"capture", "-device", "-"}, "Must specify exactly one of -iid or -non-iid"},
+		{[]string{"capture", "-device", "-", "-iid", "-non-iid"}, "Must specify exactly one of -iid or -non-iid"},
+		{[]string{"capture", "-device", "-", "-non-iid", "-bits", "9"}, "bits per symbol must be 0-8, got 9"},
+		{[]string{"capture", "-device", "-", "-non-iid", "-bits", "4", "-h-submitter", "5"}, "h-submitter must be between 0 and bits per symbol"},
+		{[]string{"capture", "-device", "-", "-iid", "-estimators", "markov"}, "markov"},
+		{[]string{"capture", "-device", "-", "-non-iid", "-bits", "4", "-fail-below", "5"}, "fail-below must not exceed 4 bits per symbol"},
+		{[]string{"capture", "-device", "-", "-non-iid", "extra.bin"}, "capture takes no file arguments, got extra.bin"},
+	}
+	for _, tc := range cases {
+		var out bytes.Buffer
+		code := runCLI(tc.args, bytes.NewReader(nil), &out, &out)
+		assert.Equal(t, 2, code, "%v", tc.args)
+		assert.Contains(t, out.String(), tc.want, "%v", tc.args)
+	}
+
+	var out bytes.Buffer
+	code := runCLI([]string{"capture", "-device", filepath.Join(t.TempDir(), "missing"), "-non-iid"}, bytes.NewReader(nil), &out, &out)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "Error opening ")
+}
+
+func TestCaptureSamples(t *testing.T) {
+	data := []byte("0123456789")
+
+	// Short reads are continued until n bytes have arrived.
+	var reported []int64
+	got, err := captureSamples(context.Background(), iotest.OneByteReader(bytes.NewReader(data)), 6, time.Second, func(read, size int64) {
+		assert.Equal(t, int64(6), size)
+		reported = append(reported, read)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, data[:6], got)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, reported)
+
+	// Data delivered together with EOF still counts.
+	got, err = captureSamples(context.Background(), iotest.DataErrReader(bytes.NewReader(data)), 10, time.Second, nil)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	_, err = captureSamples(context.Background(), bytes.NewReader(data), 12, time.Second, nil)
+	require.Error(t, err)
+	assert.Equal(t, "input ended after 10 of 12 bytes", err.Error())
+
+	_, err = captureSamples(context.Background(), io.MultiReader(bytes.NewReader(data[:4]), iotest.ErrReader(errors.New("device gone"))), 8, time.Second, nil)
+	require.Error(t, err)
+	assert.Equal(t, "read failed after 4 of 8 bytes: device gone", err.Error())
+}
+
+func TestCaptureSamples_Stalled(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		pw.Write([]byte{1, 2, 3})
+	}()
+
+	_, err := captureSamples(context.Background(), pr, 5, 50*time.Millisecond, nil)
+	require.Error(t, err)
+	assert.Equal(t, "no data for 50ms after 3 of 5 bytes", err.Error())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = captureSamples(ctx, pr, 5, 0, nil)
+	require.Error(t, err)
+	assert.Equal(t, "interrupted after 0 of 5 bytes", err.Error())
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/monitor"
+)
+
+// runMonitor implements the "monitor" subcommand. It streams samples from a
+// file, device or pipe (stdin when no file or "-" is given) through the
+// continuous health tests and assesses windows of the stream until the input
+// ends or the process is interrupted. It returns 0 when no health test
+// failed, 1 on a read error, 2 on argument validation failure, or 3 when a
+// health test failed.
+func runMonitor(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ea_tool monitor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	iid := fs.Bool("iid", false, "Assess windows with the IID track instead of Non-IID")
+	bits := fs.Int("bits", 8, "Bits per sample (1-8)")
+	hSubmitter := fs.Float64("h-submitter", 0, "Claimed min-entropy per sample for the health tests (required)")
+	window := fs.Int("window", monitor.DefaultWindow, "Samples per assessment window")
+	every := fs.Int("every", 1, "Assess every Nth window")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s [options] [file|-]\n\n", fs.Name())
+		fmt.Fprintf(stderr, "Run the continuous health tests over a live source and assess windows of it.\n")
+		fmt.Fprintf(stderr, "Windows arriving while two assessments are pending are dropped whole.\n\n")
+		fmt.Fprintf(stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExamples:\n")
+		fmt.Fprintf(stderr, "  %s -bits 8 -h-submitter 6.5 /dev/hwrng\n", fs.Name())
+		fmt.Fprintf(stderr, "  capture | %s -bits 4 -h-submitter 3 -window 100000 -every 10\n", fs.Name())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *bits < 1 || *bits > 8 {
+		fmt.Fprintf(stderr, "Error: bits per sample must be 1-8, got %d\n", *bits)
+		return 2
+	}
+	if !(*hSubmitter > 0 && *hSubmitter <= float64(*bits)) {
+		fmt.Fprintf(stderr, "Error: -h-submitter must be greater than 0 and at most bits per sample, got %g\n", *hSubmitter)
+		return 2
+	}
+	if *window < 1 || *every < 1 {
+		fmt.Fprintf(stderr, "Error: window and every must be at least 1, got %d and %d\n", *window, *every)
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintf(stderr, "Error: monitor accepts a single input\n")
+		return 2
+	}
+
+	filename, r := "stdin", stdin
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		filename = fs.Arg(0)
+		file, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error opening %s: %v\n", filename, err)
+			return 1
+		}
+		defer file.Close()
+		r = file
+	}
+
+	testType := entropy.NonIID
+	if *iid {
+		testType = entropy.IID
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m := monitor.New()
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Run(ctx, r, monitor.Config{
+			TestType: testType,
+			Bits:     *bits,
+			H:        *hSubmitter,
+			Window:   *window,
+			Every:    *every,
+		})
+	}()
+
+	failures := 0
+	for ev := range m.Events() {
+		switch {
+		case ev.Type == monitor.HealthFailure:
+			failures++
+			fmt.Fprintf(stderr, "window %d: FAIL: %v\n", ev.Window, ev.Failure)
+		case ev.Err != nil:
+			fmt.Fprintf(stderr, "window %d: assessment failed: %v\n", ev.Window, ev.Err)
+		default:
+			fmt.Fprintf(stdout, "window %d: min-entropy %.6f\n", ev.Window, ev.Result.MinEntropy)
+		}
+	}
+
+	err := <-done
+	fmt.Fprintf(stdout, "Monitored %d windows of %s, %d dropped, %d health test failures\n", m.Windows(), filename, m.Dropped(), failures)
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(stderr, "Error reading %s: %v\n", filename, err)
+		return 1
+	}
+	if failures > 0 {
+		return 3
+	}
+	return 0
+}
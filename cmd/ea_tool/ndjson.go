@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ndjsonWriter streams result documents as newline-delimited JSON, one line
+// per assessment as soon as it finishes. It is safe for concurrent use: each
+// line is written with a single call under a lock, so batch workers never
+// interleave partial lines.
+type ndjsonWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	file *os.File // the -output file, nil when writing to stdout
+	err  error    // first write error; later lines are dropped
+}
+
+// newNDJSONWriter creates filename for the stream, or writes to stdout when
+// filename is empty or "-".
+func newNDJSONWriter(filename string, stdout io.Writer) (*ndjsonWriter, error) {
+	if filename == "" || filename == stdoutName {
+		return &ndjsonWriter{w: stdout}, nil
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &ndjsonWriter{w: file, file: file}, nil
+}
+
+// write stamps out with the times it started and finished and appends it as one line. A
+// writer with a Flush method, such as a bufio.Writer, is flushed after every
+// line so that consumers see each result immediately.
+func (n *ndjsonWriter) write(out JSONOutput, started, finished time.Time) {
+	out.StartedAt = started.UTC().Format(time.RFC3339Nano)
+	out.FinishedAt = finished.UTC().Format(time.RFC3339Nano)
+	line, err := json.Marshal(out)
+	line = append(line, '\n')
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err != nil {
+		return
+	}
+	if err != nil {
+		n.err = err
+		return
+	}
+	if _, n.err = n.w.Write(line); n.err != nil {
+		return
+	}
+	if f, ok := n.w.(interface{ Flush() error }); ok {
+		n.err = f.Flush()
+	}
+}
+
+// close closes the -output file and returns the first error of the stream.
+func (n *ndjsonWriter) close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.file != nil {
+		if err := n.file.Close(); n.err == nil {
+			n.err = err
+		}
+	}
+	return n.err
+}
+
+// writeNDJSON writes the assessments of one input, which all started at
+// started, to filename, or to stdout when filename is empty or "-". On
+// failure, an error message is printed to stderr and the process exits.
+func writeNDJSON(filename string, stdout io.Writer, started time.Time, outs []JSONOutput) {
+	stream, err := newNDJSONWriter(filename, stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+	finished := time.Now()
+	for _, out := range outs {
+		stream.write(out, started, finished)
+	}
+	if err := stream.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing NDJSON: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// Output formats of the -output file.
+const (
+	formatJSON     = "json"
+	formatNISTJSON = "nist-json"
+	formatCSV      = "csv"
+	formatNDJSON   = "ndjson"
+	formatYAML     = "yaml"
+)
+
+// nistTimestamp is the layout of the dateTimeStamp field of the NIST tools.
+const nistTimestamp = "20060102150405"
+
+// formatJSONL is accepted as another name of formatNDJSON.
+const formatJSONL = "jsonl"
+
+// parseFormat validates the -format value.
+func parseFormat(format string) (string, error) {
+	switch format {
+	case formatJSON, formatNISTJSON, formatCSV, formatNDJSON, formatYAML:
+		return format, nil
+	case formatJSONL:
+		return formatNDJSON, nil
+	default:
+		return "", fmt.Errorf("format must be %s, %s, %s, %s, or %s, got %q", formatJSON, formatNISTJSON, formatCSV, formatNDJSON, formatYAML, format)
+	}
+}
+
+// toStdout reports whether format replaces the text results on stdout when
+// no -output file is given.
+func toStdout(format string) bool {
+	return format == formatCSV || format == formatNDJSON || format == formatYAML
+}
+
+// NISTTestRun mirrors the JSON document that the reference ea_iid and
+// ea_non_iid tools write with -o. The fields are declared in the byte order
+// of their keys, the order in which jsoncpp writes them.
+type NISTTestRun struct {
+	IID           bool   `json:"IID"`
+	Commandline   string `json:"commandline"`
+	DateTimeStamp string `json:"dateTimeStamp"`
+	ErrorLevel    int    `json:"errorLevel"`
+	ErrorMessage  string `json:"errorMessage,omitempty"`
+	Filename      string `json:"filename,omitempty"`
+	SHA256        string `json:"sha256,omitempty"`
+	// TestCases holds one object per test case. Its keys follow the NIST
+	// tools; a value this tool cannot compute is null. encoding/json writes
+	// map keys sorted, as jsoncpp does.
+	TestCases   []map[string]any `json:"testCases"`
+	ToolVersion string           `json:"toolVersion"`
+	Type        string           `json:"type"`
+}
+
+// nistTestCase describes how an estimator is reported by ea_non_iid: the
+// test case description and the keys of its literal and bitstring results.
+type nistTestCase struct {
+	desc          string
+	literal       string
+	bitstring     string
+	bitstringOnly bool // the literal result exists only for binary data
+}
+
+// nistNonIIDTestCases maps the Non-IID estimators to their ea_non_iid test
+// cases.
+var nistNonIIDTestCases = map[entropy.EstimatorID]nistTestCase{
+	entropy.EstimatorMCV:         {"Most Common Value", "hOriginal", "hBitstring", false},
+	entropy.EstimatorCollision:   {"Collision Test (for bit strings only)", "hOriginal", "hBitstring", true},
+	entropy.EstimatorMarkov:      {"Markov Test (for bit strings only)", "hOriginal", "hBitstring", true},
+	entropy.EstimatorCompression: {"Compression Test (for bit strings only)", "hOriginal", "hBitstring", true},
+	entropy.EstimatorTTuple:      {"T-Tuple Test", "tTupleRes", "binTTupleRes", false},
+	entropy.EstimatorLRS:         {"LRS Test", "lrsRes", "binLrsRes", false},
+	entropy.EstimatorMultiMCW:    {"Multi Most Common in Window Test", "hOriginal", "hBitstring", false},
+	entropy.EstimatorLag:         {"Lag Prediction Test", "hOriginal", "hBitstring", false},
+	entropy.EstimatorMultiMMC:    {"Multi Markov Model with Counting Test (MultiMMC)", "hOriginal", "hBitstring", false},
+	entropy.EstimatorLZ78Y:       {"LZ78Y Test", "hOriginal", "hBitstring", false},
+}
+
+// document returns the value written to the -output file for out.
+func (o *cliOptions) document(out JSONOutput) any {
+	if o.format == formatNISTJSON {
+		return o.nistTestRun(out)
+	}
+	return out
+}
+
+// nistTestRun converts an assessment into the layout of the NIST tools.
+func (o *cliOptions) nistTestRun(out JSONOutput) NISTTestRun {
+	run := NISTTestRun{
+		IID:           o.testType == entropy.IID,
+		Commandline:   o.commandline,
+		DateTimeStamp: time.Now().Format(nistTimestamp),
+		Filename:      out.Filename,
+		SHA256:        out.DataSHA256,
+		ToolVersion:   entropy.LibraryInfo().ToolVersion,
+	}
+	if out.ErrorCode != 0 {
+		run.ErrorLevel = -1
+		run.ErrorMessage = out.ErrorMessage
+		return run
+	}
+	if run.IID {
+		run.TestCases = []map[string]any{nistIIDCase(out)}
+	} else {
+		run.TestCases = nistNonIIDCases(out.result)
+	}
+	return run
+}
+
+// nistIIDCase returns the single test case of an ea_iid run. -iid-check
+// leaves the entropy fields null; the mean, median and permutation test
+// statistics are always null.
+func nistIIDCase(out JSONOutput) map[string]any {
+	tc := map[string]any{
+		"testCaseDesc":                       "",
+		"mean":                               nil,
+		"median":                             nil,
+		"hOriginal":                          nil,
+		"hBitstring":                         nil,
+		"hAssessed":                          nil,
+		"passedChiSquareTests":               nil,
+		"passedLongestRepeatedSubstringTest": nil,
+		"passedIidPermutationTests":          nil,
+		"permutationTestResults":             nil,
+	}
+	tests := out.Tests
+	if result := out.result; result != nil {
+		tc["hOriginal"] = result.HOriginal
+		tc["hBitstring"] = result.HBitstring
+		tc["hAssessed"] = result.HAssessed
+		if result.DataWordSize == 1 {
+			tc["binary"] = true
+		}
+		for _, est := range result.Estimators {
+			tests = append(tests, TestOutput{ID: est.ID.String(), Name: est.Name, Passed: est.Passed})
+		}
+	}
+	for _, test := range tests {
+		switch entropy.EstimatorIDFromName(test.Name) {
+		case entropy.EstimatorChiSquare:
+			tc["passedChiSquareTests"] = test.Passed
+		case entropy.EstimatorLRS:
+			tc["passedLongestRepeatedSubstringTest"] = test.Passed
+		case entropy.EstimatorPermutation:
+			tc["passedIidPermutationTests"] = test.Passed
+		}
+	}
+	return tc
+}
+
+// nistNonIIDCases returns the test cases of an ea_non_iid run, one per
+// estimator that ran followed by "Overall". The wrapper reports one estimate
+// per estimator: the literal result where ea_non_iid computes one and the
+// bitstring result otherwise, so the other one is null. The MCV estimate
+// details are null too.
+func nistNonIIDCases(result *entropy.Result) []map[string]any {
+	multiBit := result.DataWordSize > 1
+	cases := make([]map[string]any, 0, len(result.Estimators)+1)
+	for _, est := range result.Estimators {
+		ref, ok := nistNonIIDTestCases[est.ID]
+		if !ok {
+			ref = nistTestCase{est.Name, "hOriginal", "hBitstring", false}
+		}
+		var estimate any
+		if est.IsEntropyValid {
+			estimate = est.EntropyEstimate
+		}
+
+		tc := map[string]any{"testCaseDesc": ref.desc}
+		switch {
+		case !multiBit:
+			tc[ref.literal] = estimate
+		case ref.bitstringOnly:
+			tc[ref.bitstring] = estimate
+		default:
+			tc[ref.literal] = estimate
+			tc[ref.bitstring] = nil
+		}
+		if est.ID == entropy.EstimatorMCV {
+			tc["mcvEstimateMode"] = nil
+			tc["mcvEstimatePHat"] = nil
+			tc["mcvEstimatePU"] = nil
+		}
+		cases = append(cases, tc)
+	}
+
+	overall := map[string]any{
+		"testCaseDesc": "Overall",
+		"dataWordSize": result.DataWordSize,
+		"hOriginal":    result.HOriginal,
+		"hAssessed":    result.HAssessed,
+	}
+	if multiBit {
+		overall["hBitstring"] = result.HBitstring
+	}
+	return append(cases, overall)
+}
+
+// sha256Hex returns the SHA-256 digest of data in hex, as the NIST tools
+// report it for the input file.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
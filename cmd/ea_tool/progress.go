@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// progressInterval is how often the progress line redraws its elapsed time
+// while a phase runs.
+const progressInterval = time.Second
+
+// progressLine redraws the progress of a running assessment in place on one
+// line of w, with the time elapsed since it was created. The line is redrawn
+// every progressInterval, so long phases show that the tool is still
+// working. A nil progressLine prints nothing.
+type progressLine struct {
+	w       io.Writer
+	started time.Time
+	stop    chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	phase   string
+	percent float64
+	open    bool
+	ended   bool
+}
+
+// newProgressLine starts a progress line on w. It must be ended with end.
+func newProgressLine(w io.Writer) *progressLine {
+	p := &progressLine{w: w, started: time.Now(), stop: make(chan struct{}), phase: "Starting"}
+	go p.tick()
+	return p
+}
+
+// tick redraws the line every progressInterval until the line ends.
+func (p *progressLine) tick() {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			if !p.ended {
+				p.draw()
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// report draws the current phase; it is used as the entropy.ProgressFunc.
+func (p *progressLine) report(phase string, percent float64) {
+	p.mu.Lock()
+	p.phase, p.percent = phase, percent
+	p.draw()
+	p.mu.Unlock()
+	if phase == entropy.ProgressDone {
+		p.end()
+	}
+}
+
+// draw writes the line; p.mu must be held.
+func (p *progressLine) draw() {
+	fmt.Fprintf(p.w, "\rProgress: %5.1f%% %-42s %8s", p.percent, p.phase, formatElapsed(time.Since(p.started)))
+	p.open = true
+}
+
+// end stops the redraws and terminates the line, which stays open when an
+// assessment fails.
+func (p *progressLine) end() {
+	if p == nil {
+		return
+	}
+	p.once.Do(func() { close(p.stop) })
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ended = true
+	if p.open {
+		fmt.Fprintln(p.w)
+		p.open = false
+	}
+}
+
+// readProgress draws the share of a file read so far on one line of w. It
+// is redrawn only when the shown value changes.
+type readProgress struct {
+	w     io.Writer
+	shown int64 // last drawn value in tenths of a percent, or in MiB when the size is unknown
+	open  bool
+}
+
+// report draws the progress; it is used as the entropy.ReadFileProgress
+// callback.
+func (r *readProgress) report(read, size int64) {
+	value := read >> 20
+	if size > 0 {
+		value = read * 1000 / size
+	}
+	if r.open && value == r.shown {
+		return
+	}
+	r.shown, r.open = value, true
+	if size > 0 {
+		fmt.Fprintf(r.w, "\rReading:  %5.1f%% of %s", float64(value)/10, formatBytes(size))
+	} else {
+		fmt.Fprintf(r.w, "\rReading:  %s", formatBytes(read))
+	}
+}
+
+// end terminates the line.
+func (r *readProgress) end() {
+	if r.open {
+		fmt.Fprintln(r.w)
+		r.open = false
+	}
+}
+
+// showProgress reports whether progress lines are drawn on stderr: only for
+// text results on a terminal.
+func (o *cliOptions) showProgress(stderr io.Writer) bool {
+	return !o.toFile && o.verbose >= 1 && isTerminal(stderr)
+}
+
+// readFile reads an input file, drawing the share read so far on stderr
+// when progress is shown. A compressed file is decompressed up to readLimit.
+func (o *cliOptions) readFile(filename string, stderr io.Writer) ([]byte, error) {
+	if !o.showProgress(stderr) {
+		return entropy.ReadFileLimit(filename, o.readLimit(), nil)
+	}
+	progress := &readProgress{w: stderr}
+	defer progress.end()
+	return entropy.ReadFileLimit(filename, o.readLimit(), progress.report)
+}
+
+// isTerminal reports whether w is a terminal. The progress line is only
+// drawn there, so redirected output and batch buffers stay free of it.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatElapsed formats d to a tenth of a second below a minute and to the
+// second above.
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// formatBytes formats n in bytes, KiB, MiB or GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KiB"
+	for _, s := range []string{"MiB", "GiB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, s
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
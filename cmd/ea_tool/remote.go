@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/pkg/client"
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+// exitRemote is the exit code, and the error_code of the JSON output, of an
+// input whose -remote call failed before an assessment ran: the service
+// could not be reached or rejected the credentials.
+const exitRemote = 4
+
+// remoteAttempts caps the attempts of a -remote call that finds the service
+// unavailable or at its concurrency limit.
+const remoteAttempts = 3
+
+// remoteError is a -remote call that failed for want of a service to run
+// it, rather than in the assessment.
+type remoteError struct {
+	addr string
+	err  error
+}
+
+func (e *remoteError) Error() string {
+	return fmt.Sprintf("remote service %s: %s", e.addr, status.Convert(e.err).Message())
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.err
+}
+
+// errorCode returns the exit code and JSON error_code of a failed
+// assessment: exitRemote for a remoteError and 1 otherwise.
+func errorCode(err error) int {
+	var remote *remoteError
+	if errors.As(err, &remote) {
+		return exitRemote
+	}
+	return 1
+}
+
+// remoteService sends the assessments of -remote to the gRPC service
+// instead of the local library.
+type remoteService struct {
+	addr   string
+	client *client.Client
+}
+
+// newRemoteService connects to the service at addr, with TLS when useTLS
+// or caFile is set and with token as bearer token when it is not empty.
+// caFile replaces the system roots for the server certificate. Calls are
+// bounded by timeout unless it is 0.
+func newRemoteService(addr string, useTLS bool, caFile, token string, timeout time.Duration) (*remoteService, error) {
+	opts := []client.Option{
+		client.WithTimeout(timeout),
+		client.WithRetry(client.RetryPolicy{MaxAttempts: remoteAttempts}),
+	}
+	if useTLS || caFile != "" {
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading -ca file: %w", err)
+			}
+			cfg.RootCAs = x509.NewCertPool()
+			if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("-ca file %s holds no PEM certificate", caFile)
+			}
+		}
+		opts = append(opts, client.WithTLS(cfg))
+	}
+	if token != "" {
+		opts = append(opts, client.WithToken(token))
+	}
+
+	c, err := client.New(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteService{addr: addr, client: c}, nil
+}
+
+// Close closes the connection to the service.
+func (r *remoteService) Close() error {
+	return r.client.Close()
+}
+
+// assess runs the assessment of o on data on the service and converts the
+// response into the result the local library would have returned.
+func (r *remoteService) assess(ctx context.Context, o *cliOptions, data []byte) (*entropy.Result, error) {
+	resp, err := r.call(ctx, o, data, false)
+	if err != nil {
+		return nil, err
+	}
+	selection, err := entropy.CanonicalEstimators(o.testType, o.estimators)
+	if err != nil {
+		return nil, err
+	}
+
+	estimators := resp.GetNonIidResults()
+	rawJSON := resp.GetNonIidRawJson()
+	if o.testType == entropy.IID {
+		estimators = resp.GetIidResults()
+		rawJSON = resp.GetIidRawJson()
+	}
+	result := &entropy.Result{
+		MinEntropy:         resp.GetMinEntropy(),
+		HOriginal:          resp.GetHOriginal(),
+		HBitstring:         resp.GetHBitstring(),
+		HAssessed:          resp.GetMinEntropy(),
+		DataWordSize:       int(resp.GetBitsPerSymbol()),
+		TestType:           o.testType,
+		HSubmitter:         o.hSubmitter,
+		HasHSubmitter:      o.hSubmitterSet,
+		HFinal:             resp.GetHFinal(),
+		SubmitterBinding:   resp.GetSubmitterBinding(),
+		ShannonEntropy:     resp.GetShannonEntropy(),
+		AlphabetSize:       int(resp.GetAlphabetSize()),
+		EstimatorSelection: selection,
+		Partial:            resp.GetPartial(),
+		Backend:            resp.GetBackend(),
+		Histogram:          resp.GetHistogram(),
+		Estimators:         remoteEstimators(estimators),
+		Timings:            remoteTimings(resp.GetTimingsMs()),
+	}
+	if len(result.Histogram) == 0 {
+		result.Histogram = nil
+	}
+	if rawJSON != "" {
+		result.RawJSON = json.RawMessage(rawJSON)
+	}
+	return result, nil
+}
+
+// checkIID runs only the IID statistical tests of o on data on the service.
+func (r *remoteService) checkIID(ctx context.Context, o *cliOptions, data []byte) (bool, []entropy.EstimatorResult, error) {
+	resp, err := r.call(ctx, o, data, true)
+	if err != nil {
+		return false, nil, err
+	}
+	return resp.GetPassed(), remoteEstimators(resp.GetIidResults()), nil
+}
+
+// call sends one AssessEntropy request for o and data. Failures that leave
+// the assessment unrun are returned as a remoteError, the others with the
+// message of the service.
+func (r *remoteService) call(ctx context.Context, o *cliOptions, data []byte, iidCheck bool) (*pb.Sp80090BAssessmentResponse, error) {
+	req := &pb.Sp80090BAssessmentRequest{
+		Data:             data,
+		BitsPerSymbol:    uint32(o.bits),
+		IidMode:          o.testType == entropy.IID,
+		NonIidMode:       o.testType == entropy.NonIID,
+		Estimators:       o.estimators,
+		IidCheckOnly:     iidCheck,
+		IncludeHistogram: o.histogram,
+		IncludeRawJson:   o.raw,
+	}
+	if o.bitOrder == entropy.LSBFirst {
+		req.BitOrder = pb.BitOrder_BIT_ORDER_LSB_FIRST
+	}
+	if o.hSubmitterSet {
+		h := o.hSubmitter
+		req.HSubmitter = &h
+	}
+
+	resp, err := r.client.Assess(ctx, req)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unavailable, codes.Unauthenticated, codes.PermissionDenied:
+			return nil, &remoteError{addr: r.addr, err: err}
+		}
+		return nil, errors.New(status.Convert(err).Message())
+	}
+	return resp, nil
+}
+
+// remoteEstimators converts the estimator results of a response. The
+// estimate of an entropy estimator is also in its details, under
+// "entropy_estimate"; statistical tests have none.
+func remoteEstimators(estimators []*pb.Sp80090BEstimatorResult) []entropy.EstimatorResult {
+	if len(estimators) == 0 {
+		return nil
+	}
+	out := make([]entropy.EstimatorResult, len(estimators))
+	for i, est := range estimators {
+		var details map[string]float64
+		_, valid := est.GetDetails()["entropy_estimate"]
+		for key, value := range est.GetDetails() {
+			if key == "entropy_estimate" {
+				continue
+			}
+			if details == nil {
+				details = make(map[string]float64)
+			}
+			details[key] = value
+		}
+		out[i] = entropy.EstimatorResult{
+			Name:            est.GetName(),
+			ID:              entropy.EstimatorIDFromName(est.GetName()),
+			EntropyEstimate: est.GetEntropyEstimate(),
+			Passed:          est.GetPassed(),
+			IsEntropyValid:  valid,
+			Details:         details,
+		}
+	}
+	return out
+}
+
+// remoteTimings converts the phase timings of a response from milliseconds.
+func remoteTimings(timingsMS map[string]float64) map[string]time.Duration {
+	if len(timingsMS) == 0 {
+		return nil
+	}
+	timings := make(map[string]time.Duration, len(timingsMS))
+	for phase, ms := range timingsMS {
+		timings[phase] = time.Duration(ms * float64(time.Millisecond))
+	}
+	return timings
+}
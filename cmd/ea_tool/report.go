@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	_ "embed"
+	"flag"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// Formats of "ea_tool report".
+const (
+	reportMarkdown = "markdown"
+	reportHTML     = "html"
+)
+
+// defaultTemplate is the -template value that selects the built-in template
+// of the report format.
+const defaultTemplate = "default"
+
+//go:embed templates/report.md.tmpl
+var defaultMarkdownTemplate string
+
+//go:embed templates/report.html.tmpl
+var defaultHTMLTemplate string
+
+// reportNow returns the time a report is dated with.
+var reportNow = time.Now
+
+// ReportData is the value a report template is executed with. MinEntropy is
+// the value the verdict applies to: the min-entropy of a single test, or the
+// lower H_assessed of the IID and the Non-IID test. Verdict is PASS or FAIL
+// against Threshold, and empty when no -fail-below was given.
+type ReportData struct {
+	Filename    string
+	SHA256      string // digest of the input file
+	InputSize   int    // size of the input file in bytes
+	DataSize    int    // number of samples assessed
+	Date        string // UTC, RFC 3339
+	Version     string // ea_tool version
+	Library     entropy.Capabilities
+	Assessments []ReportAssessment
+	Warnings    []string
+	MinEntropy  float64
+	Threshold   float64
+	Verdict     string
+}
+
+// ReportAssessment is the result of the IID or the Non-IID test in a report.
+// Subset lists the estimators of a non-conforming -estimators run.
+type ReportAssessment struct {
+	TestType      string
+	BitsPerSymbol int
+	HOriginal     float64
+	HBitstring    float64 // 0 when the data is binary
+	HAssessed     float64
+	MinEntropy    float64
+	HSubmitter    float64
+	HasHSubmitter bool
+	HFinal        float64
+	Estimators    []ReportEstimator
+	Subset        []string
+	Partial       bool
+}
+
+// ReportEstimator is one row of the per-estimator table. Result is the
+// estimate to six decimals, or PASS or FAIL for an IID test; Lowest marks
+// the estimator that determined the min-entropy.
+type ReportEstimator struct {
+	Name   string
+	Result string
+	Lowest bool
+}
+
+// reportTemplate is a parsed text/template or html/template template.
+type reportTemplate interface {
+	Execute(w io.Writer, data any) error
+}
+
+// reportFuncs are the functions available to report templates.
+var reportFuncs = map[string]any{
+	"f6":   func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) },
+	"join": strings.Join,
+}
+
+// parseReportFormat validates the -format value of "ea_tool report".
+func parseReportFormat(format string) (string, error) {
+	switch format {
+	case reportMarkdown, "md":
+		return reportMarkdown, nil
+	case reportHTML:
+		return reportHTML, nil
+	default:
+		return "", fmt.Errorf("report format must be %s or %s, got %q", reportMarkdown, reportHTML, format)
+	}
+}
+
+// loadReportTemplate parses the built-in template of format, or the template
+// file at path. HTML templates are parsed with html/template, which escapes
+// the values they insert.
+func loadReportTemplate(format, path string) (reportTemplate, error) {
+	name, text := "report", defaultMarkdownTemplate
+	if format == reportHTML {
+		text = defaultHTMLTemplate
+	}
+	if path != defaultTemplate {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name, text = filepath.Base(path), string(raw)
+	}
+	if format == reportHTML {
+		return htmltemplate.New(name).Funcs(htmltemplate.FuncMap(reportFuncs)).Parse(text)
+	}
+	return texttemplate.New(name).Funcs(texttemplate.FuncMap(reportFuncs)).Parse(text)
+}
+
+// runReport implements the "report" subcommand. It runs the IID or Non-IID
+// assessment, or both, on one input and renders the results with a
+// Markdown or HTML template for a certification submission. The warnings of
+// the assessments are collected into the report as well as printed. It
+// returns 0 on success, 1 on a read, assessment or rendering error, 2 on
+// argument validation failure, or 3 when the min-entropy is below
+// -fail-below; the report is written in that case too.
+func runReport(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ea_tool report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	iid := fs.Bool("iid", false, "Run the IID test")
+	nonIID := fs.Bool("non-iid", false, "Run the Non-IID test; with -iid, run both")
+	bits := fs.Int("bits", 0, "Bits per symbol (1-8), 0 for auto-detect")
+	hSubmitter := fs.Float64("h-submitter", 0, "Entropy claimed by the submitter in bits per sample (0 to bits)")
+	estimators := fs.String("estimators", "", "Comma-separated estimator subset (e.g. mcv,markov); non-conforming, default all")
+	failBelow := fs.Float64("fail-below", 0, "Report FAIL and exit with code 3 if the min-entropy is below this value (0 disables the check)")
+	tmpl := fs.String("template", defaultTemplate, "Template file of the report, or default for the built-in one")
+	format := fs.String("format", reportMarkdown, "Report format: markdown (or md) or html")
+	outputFile := fs.String("o", "", "Write the report to this file instead of stdout")
+	fs.StringVar(outputFile, "output", "", "Same as -o")
+	verbose := fs.Int("verbose", 1, "Verbosity level (0=quiet, 1=normal, 2=verbose, 3=very verbose)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s -iid|-non-iid [options] [file|-]\n\n", fs.Name())
+		fmt.Fprintf(stderr, "Assess one input and render a Markdown or HTML report for a certification\n")
+		fmt.Fprintf(stderr, "submission. A -template file is executed with Go text/template, or\n")
+		fmt.Fprintf(stderr, "html/template for -format html.\n\n")
+		fmt.Fprintf(stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExamples:\n")
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -template default -o report.md data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -iid -non-iid -bits 8 -fail-below 6 -format html -o report.html data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -template lab.md.tmpl -o report.md data.bin\n", fs.Name())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if !*iid && !*nonIID {
+		fmt.Fprintf(stderr, "Error: report requires -iid, -non-iid, or both\n")
+		return 2
+	}
+	var testTypes []entropy.TestType
+	if *iid {
+		testTypes = append(testTypes, entropy.IID)
+	}
+	if *nonIID {
+		testTypes = append(testTypes, entropy.NonIID)
+	}
+	if *bits < 0 || *bits > 8 {
+		fmt.Fprintf(stderr, "Error: bits per symbol must be 0-8, got %d\n", *bits)
+		return 2
+	}
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	hSubmitterSet := setFlags["h-submitter"]
+	if hSubmitterSet && (*hSubmitter < 0 || (*bits > 0 && *hSubmitter > float64(*bits))) {
+		fmt.Fprintf(stderr, "Error: h-submitter must be between 0 and bits per symbol, got %g\n", *hSubmitter)
+		return 2
+	}
+	var selection []string
+	if *estimators != "" {
+		selection = strings.Split(*estimators, ",")
+		for _, t := range testTypes {
+			if err := entropy.ValidateEstimators(t, selection); err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return 2
+			}
+		}
+	}
+	thresholdSet := setFlags["fail-below"] && *failBelow != 0
+	if thresholdSet && (math.IsNaN(*failBelow) || *failBelow < 0) {
+		fmt.Fprintf(stderr, "Error: fail-below must be a non-negative number, got %g\n", *failBelow)
+		return 2
+	}
+	if maxBits := cmp.Or(*bits, 8); thresholdSet && *failBelow > float64(maxBits) {
+		fmt.Fprintf(stderr, "Error: fail-below must not exceed %d bits per symbol, got %g\n", maxBits, *failBelow)
+		return 2
+	}
+	reportFormat, err := parseReportFormat(*format)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	tpl, err := loadReportTemplate(reportFormat, *tmpl)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: invalid -template: %v\n", err)
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintf(stderr, "Error: report accepts a single input\n")
+		return 2
+	}
+
+	filename, raw := "stdin", []byte(nil)
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		filename = fs.Arg(0)
+		raw, err = entropy.ReadFile(filename)
+	} else {
+		raw, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading %s: %v\n", filename, err)
+		return 1
+	}
+
+	var warnings bytes.Buffer
+	opts := &cliOptions{
+		testType:      testTypes[0],
+		bits:          *bits,
+		verbose:       *verbose,
+		encoding:      encodingBinary,
+		hSubmitter:    *hSubmitter,
+		hSubmitterSet: hSubmitterSet,
+		estimators:    selection,
+		failBelow:     *failBelow,
+		thresholdSet:  thresholdSet,
+		permRounds:    entropy.PermutationRounds,
+		toFile:        true,
+		format:        formatJSON,
+		warnings:      io.MultiWriter(stderr, &warnings),
+	}
+	data := ReportData{
+		Filename:  filename,
+		SHA256:    sha256Hex(raw),
+		InputSize: len(raw),
+		Date:      reportNow().UTC().Format(time.RFC3339),
+		Version:   version,
+		Library:   entropy.LibraryInfo(),
+	}
+
+	var docs []JSONOutput
+	if len(testTypes) == 2 {
+		out, _ := opts.assessAll(filename, raw, io.Discard, stderr)
+		if out.ErrorCode != 0 {
+			fmt.Fprintf(stderr, "Error: %s\n", reportError(out.ErrorMessage, out.IID, out.NonIID))
+			return 1
+		}
+		docs = []JSONOutput{*out.IID, *out.NonIID}
+		data.DataSize = out.DataSize
+		data.MinEntropy = *out.HAssessed
+	} else {
+		out, _ := opts.assess(filename, raw, io.Discard, stderr)
+		if out.ErrorCode != 0 {
+			fmt.Fprintf(stderr, "Error: %s\n", out.ErrorMessage)
+			return 1
+		}
+		docs = []JSONOutput{out}
+		data.DataSize = out.DataSize
+		data.MinEntropy = out.MinEntropy
+	}
+	for _, doc := range docs {
+		data.Assessments = append(data.Assessments, reportAssessment(doc))
+	}
+	data.Warnings = reportWarnings(warnings.String(), data.Library.Backend)
+
+	code := 0
+	if thresholdSet {
+		data.Threshold = *failBelow
+		data.Verdict = passFail(data.MinEntropy >= *failBelow)
+		if data.MinEntropy < *failBelow {
+			code = 3
+		}
+	}
+
+	var rendered bytes.Buffer
+	if err := tpl.Execute(&rendered, data); err != nil {
+		fmt.Fprintf(stderr, "Error rendering report: %v\n", err)
+		return 1
+	}
+	if *outputFile == "" {
+		stdout.Write(rendered.Bytes())
+		return code
+	}
+	if err := os.WriteFile(*outputFile, rendered.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(stderr, "Error writing report: %v\n", err)
+		return 1
+	}
+	if *verbose > 0 {
+		fmt.Fprintf(stdout, "Report written to %s\n", *outputFile)
+	}
+	return code
+}
+
+// reportAssessment converts the JSON document of a successful assessment
+// into its report section.
+func reportAssessment(doc JSONOutput) ReportAssessment {
+	a := ReportAssessment{
+		TestType:   doc.TestType,
+		HOriginal:  doc.HOriginal,
+		HBitstring: doc.HBitstring,
+		HAssessed:  doc.HAssessed,
+		MinEntropy: doc.MinEntropy,
+		HFinal:     doc.HFinal,
+		Subset:     doc.Estimators,
+		Partial:    doc.Partial,
+	}
+	if doc.result != nil {
+		a.BitsPerSymbol = doc.result.DataWordSize
+	}
+	if doc.HSubmitter != nil {
+		a.HSubmitter, a.HasHSubmitter = *doc.HSubmitter, true
+	}
+	lowest := lowestEstimator(doc.EstimatorResults)
+	for i, est := range doc.EstimatorResults {
+		result := passFail(est.Passed)
+		if est.IsEntropyValid {
+			result = strconv.FormatFloat(est.EntropyEstimate, 'f', 6, 64)
+		}
+		a.Estimators = append(a.Estimators, ReportEstimator{Name: est.Name, Result: result, Lowest: i == lowest})
+	}
+	return a
+}
+
+// reportWarnings returns the distinct warnings the assessments wrote, in
+// order and without their "Warning: " prefix, preceded by a warning when
+// the backend is not the NIST library.
+func reportWarnings(written, backend string) []string {
+	var warnings []string
+	switch backend {
+	case entropy.BackendGo:
+		warnings = append(warnings, "pure-Go build; only the implemented estimators run and results are not conforming")
+	case entropy.BackendStub:
+		warnings = append(warnings, "stub build; entropy results are fixed test values")
+	}
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(written))
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "Warning: ")
+		if line != "" && !seen[line] {
+			seen[line] = true
+			warnings = append(warnings, line)
+		}
+	}
+	return warnings
+}
+
+// reportError names the assessment of an -iid -non-iid report that failed.
+func reportError(message string, results ...*JSONOutput) string {
+	for _, doc := range results {
+		if doc != nil && doc.ErrorCode != 0 {
+			return fmt.Sprintf("%s assessment: %s", doc.TestType, doc.ErrorMessage)
+		}
+	}
+	return message
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// RestartOutput is the outcome of the restart tests (SP 800-90B 3.1.4), with
+// -restart. HRow and HColumn are absent when the sanity check failed, as
+// the datasets are then not assessed.
+type RestartOutput struct {
+	Rows             int      `json:"rows"`
+	Cols             int      `json:"cols"`
+	Layout           string   `json:"layout"`
+	HI               float64  `json:"h_i"`
+	Alpha            float64  `json:"alpha"`
+	MaxRowCount      int      `json:"max_row_count"`
+	MaxColumnCount   int      `json:"max_column_count"`
+	RowCutoff        int      `json:"row_cutoff"`
+	ColumnCutoff     int      `json:"column_cutoff"`
+	SanityPassed     bool     `json:"sanity_passed"`
+	HRow             *float64 `json:"h_r,omitempty"`
+	HColumn          *float64 `json:"h_c,omitempty"`
+	ValidationPassed bool     `json:"validation_passed"`
+	Passed           bool     `json:"passed"`
+}
+
+// parseRestart parses the -restart value "rows x cols", written as 1000x1000
+// or 1000,1000.
+func parseRestart(value string) (rows, cols int, err error) {
+	fields := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool { return r == 'x' || r == ',' })
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("restart must be rows x cols such as 1000x1000, got %q", value)
+	}
+	var dims [2]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 2 {
+			return 0, 0, fmt.Errorf("restart needs at least 2 rows and 2 columns, got %q", value)
+		}
+		dims[i] = n
+	}
+	return dims[0], dims[1], nil
+}
+
+// restartTest runs the restart tests on the samples, which must form the
+// -restart matrix. Without -restart-layout the layout is taken from the
+// split-restart manifest of the input, when there is one. min_entropy is min(H_r, H_c, H_I) when the tests pass
+// and 0 otherwise. The exit code is 3 when the sanity check or the
+// validation test fails.
+func (o *cliOptions) restartTest(data []byte, jsonOut JSONOutput, stdout, stderr io.Writer) (JSONOutput, int) {
+	m := *o.restart
+	if !o.layoutSet && jsonOut.Filename != "stdin" && jsonOut.Section == nil {
+		layout, err := restartManifestLayout(jsonOut.Filename, m)
+		if err != nil {
+			jsonOut.ErrorCode = 1
+			jsonOut.ErrorMessage = err.Error()
+			if !o.toFile {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+			}
+			return jsonOut, 1
+		}
+		m.Layout = layout
+	}
+	if int64(len(data)) != m.Size() {
+		err := fmt.Errorf("-restart %dx%d needs %d bytes (rows x cols samples, one per byte), but %s holds %d; select the matrix with -offset and -length or correct the dimensions",
+			m.Rows, m.Cols, m.Size(), jsonOut.Filename, len(data))
+		jsonOut.ErrorCode = 1
+		jsonOut.ErrorMessage = err.Error()
+		if !o.toFile {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return jsonOut, 1
+	}
+
+	assessment := o.newAssessment()
+	var progress *progressLine
+	if o.showProgress(stderr) {
+		progress = newProgressLine(stderr)
+		assessment = assessment.WithProgress(progress.report)
+	}
+	result, err := assessment.AssessRestartContext(o.context(), data, m, o.bits, o.hInitial, o.testType)
+	progress.end()
+	if err != nil {
+		jsonOut.ErrorCode = 1
+		jsonOut.ErrorMessage = err.Error()
+		if !o.toFile {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return jsonOut, 1
+	}
+
+	out := &RestartOutput{
+		Rows:             result.Rows,
+		Cols:             result.Cols,
+		Layout:           m.Layout.String(),
+		HI:               result.HI,
+		Alpha:            result.Alpha,
+		MaxRowCount:      result.MaxRowCount,
+		MaxColumnCount:   result.MaxColumnCount,
+		RowCutoff:        result.RowCutoff,
+		ColumnCutoff:     result.ColumnCutoff,
+		SanityPassed:     result.SanityPassed,
+		ValidationPassed: result.ValidationPassed,
+		Passed:           result.Passed,
+	}
+	if result.SanityPassed {
+		out.HRow = &result.HRow
+		out.HColumn = &result.HColumn
+	}
+	jsonOut.Restart = out
+	jsonOut.MinEntropy = result.MinEntropy
+	jsonOut.Backend = entropy.LibraryInfo().Backend
+
+	if !o.toFile && o.verbose >= 1 {
+		printRestart(stdout, o.testType, result, m.Layout)
+	}
+
+	switch {
+	case !result.SanityPassed:
+		fmt.Fprintf(stderr, "FAIL: restart sanity check, a symbol occurs %d times in one row or column\n", max(result.MaxRowCount, result.MaxColumnCount))
+		return jsonOut, 3
+	case !result.ValidationPassed:
+		fmt.Fprintf(stderr, "FAIL: restart validation, min(H_r, H_c) = %.6f is below H_I/2 = %.6f\n", min(result.HRow, result.HColumn), result.HI/2)
+		return jsonOut, 3
+	}
+	return jsonOut, 0
+}
+
+// restartManifestLayout returns the layout that the split-restart manifest
+// of filename records, or m.Layout when there is no manifest. A manifest of
+// other dimensions than m is an error.
+func restartManifestLayout(filename string, m entropy.RestartMatrix) (entropy.RestartLayout, error) {
+	manifest, err := readRestartManifest(filename)
+	if err != nil || manifest == nil {
+		return m.Layout, err
+	}
+	if manifest.Rows != m.Rows || manifest.Cols != m.Cols {
+		return m.Layout, fmt.Errorf("manifest %s describes a %dx%d matrix, not the -restart %dx%d",
+			filename+restartManifestSuffix, manifest.Rows, manifest.Cols, m.Rows, m.Cols)
+	}
+	return entropy.ParseRestartLayout(manifest.Layout)
+}
+
+// printRestart prints the restart test results.
+func printRestart(w io.Writer, testType entropy.TestType, r *entropy.RestartResult, layout entropy.RestartLayout) {
+	fmt.Fprintf(w, "\nRestart Test Results (SP 800-90B Section 3.1.4):\n")
+	fmt.Fprintf(w, "  Test Type:       %s\n", testType)
+	fmt.Fprintf(w, "  Matrix:          %d restarts x %d samples (%s layout)\n", r.Rows, r.Cols, layout)
+	fmt.Fprintf(w, "  Bits/Symbol:     %d\n", r.BitsPerSymbol)
+	fmt.Fprintf(w, "  H_I:             %.6f\n", r.HI)
+	fmt.Fprintf(w, "  Alpha:           %.6g\n", r.Alpha)
+	fmt.Fprintf(w, "  X_r, cutoff:     %d, %d\n", r.MaxRowCount, r.RowCutoff)
+	fmt.Fprintf(w, "  X_c, cutoff:     %d, %d\n", r.MaxColumnCount, r.ColumnCutoff)
+	fmt.Fprintf(w, "  Sanity Check:    %s\n", passFail(r.SanityPassed))
+	if !r.SanityPassed {
+		return
+	}
+	fmt.Fprintf(w, "  H_r:             %.6f\n", r.HRow)
+	fmt.Fprintf(w, "  H_c:             %.6f\n", r.HColumn)
+	fmt.Fprintf(w, "  Validation:      %s\n", passFail(r.ValidationPassed))
+	if r.Passed {
+		fmt.Fprintf(w, "  Min Entropy:     %.6f (min(H_r, H_c, H_I))\n", r.MinEntropy)
+	}
+}
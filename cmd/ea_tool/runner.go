@@ -1,37 +1,166 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
 )
 
 // runCLI parses command-line arguments, reads input data from a file or stdin,
 // and performs an IID or Non-IID entropy assessment. It returns an exit code:
-// 0 on success, 1 on assessment error, or 2 on argument validation failure.
+// 0 on success, 1 on assessment error, 2 on argument validation failure, 3
+// when the min-entropy is below the -fail-below threshold or the data fails
+// the -iid-check or -health tests, or when the -compare inputs differ by more
+// than the tolerance, or 4 when the -remote service cannot be reached or
+// rejects the credentials. The "selftest" subcommand runs the
+// known-answer self-test instead and returns 0 or 1; "monitor" watches a live
+// source, see runMonitor, "validate" compares the pure-Go estimators with
+// the NIST library, see runValidate, "conditioned" assesses the output of a
+// conditioning component, see runConditioned, "split-restart" builds a
+// restart matrix for -restart, see runSplitRestart, "compare" diffs two
+// files of results, see runCompareFiles, "watch" assesses the files
+// dropped into a directory, see runWatch, "report" renders a
+// certification report, see runReport, and "capture" assesses a fixed
+// number of samples read from a device, see runCapture.
 func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "selftest" {
+		return runSelfTest(stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "monitor" {
+		return runMonitor(args[1:], stdin, stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "validate" {
+		return runValidate(args[1:], stdin, stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "conditioned" {
+		return runConditioned(args[1:], stdin, stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "split-restart" {
+		return runSplitRestart(args[1:], stdin, stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "compare" {
+		return runCompareFiles(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "watch" {
+		return runWatch(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "report" {
+		return runReport(args[1:], stdin, stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "capture" {
+		return runCapture(args[1:], stdin, stdout, stderr)
+	}
+
 	fs := flag.NewFlagSet("ea_tool", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 
 	iid := fs.Bool("iid", false, "Run IID (Independent and Identically Distributed) test")
 	nonIID := fs.Bool("non-iid", false, "Run Non-IID test")
+	all := fs.Bool("all", false, "Run both the IID and the Non-IID test on the data, read once; same as -iid -non-iid")
+	iidCheck := fs.Bool("iid-check", false, "Only run the IID statistical tests (pass/fail), skipping entropy estimation")
+	quick := fs.Bool("quick", false, "Only compute the pure-Go Most Common Value estimate, an upper bound on the min-entropy")
+	lrs := fs.Bool("lrs", false, "Only compute the longest repeated substring and its LRS estimate (SP 800-90B 6.3.6)")
+	conditioning := fs.String("conditioning", "", "Apply a vetted conditioning component n_in,n_out,nw (SP 800-90B 3.1.5.1.2) to the assessed entropy")
+	restart := fs.String("restart", "", "Run the restart tests (SP 800-90B 3.1.4) on a rows x cols restart matrix, e.g. 1000x1000, against -h-initial")
+	restartLayout := fs.String("restart-layout", "row", "Sample order of the -restart matrix: row (restart after restart) or column; default the layout of a split-restart manifest, else row")
+	hInitial := fs.Float64("h-initial", 0, "Initial entropy estimate H_I in bits per sample for -restart")
+	healthMode := fs.Bool("health", false, "Run the continuous health tests (RCT and APT) for the -h-submitter entropy instead of an assessment")
+	compareFile := fs.String("compare", "", "Also assess this file with the same parameters and report the differences")
+	compareTolerance := fs.Float64("compare-tolerance", defaultCompareTolerance, "Largest accepted -compare difference in bits per sample")
 	bits := fs.Int("bits", 0, "Bits per symbol (1-8), 0 for auto-detect")
 	verbose := fs.Int("verbose", 1, "Verbosity level (0=quiet, 1=normal, 2=verbose, 3=very verbose)")
-	outputFile := fs.String("output", "", "Output file for JSON results")
+	outputFile := fs.String("output", "", "Output file for JSON results, - for stdout with the text on stderr")
+	format := fs.String("format", formatJSON, "Layout of the -output file: json, nist-json for the layout of the NIST ea_iid and ea_non_iid tools, csv, ndjson, one line per result as it finishes, or yaml; csv, ndjson and yaml go to stdout without -output")
+	inputEncoding := fs.String("input-encoding", encodingBinary, "Input encoding: binary (or raw), hex, or base64")
+	column := fs.Int("column", 0, "Read integer samples from this 1-based column of a delimited text file")
+	delimiter := fs.String("delimiter", ",", "Column delimiter for -column; empty splits on whitespace")
+	hSubmitter := fs.Float64("h-submitter", 0, "Entropy claimed by the submitter in bits per sample (0 to bits)")
+	estimators := fs.String("estimators", "", "Comma-separated estimator subset (e.g. mcv,markov); non-conforming, default all")
+	failBelow := fs.Float64("fail-below", 0, "Exit with code 3 if the min-entropy is below this value (0 disables the check)")
+	offset := fs.Int64("offset", 0, "Skip this many samples before assessing")
+	fs.Int64Var(offset, "skip-bytes", 0, "Same as -offset")
+	length := fs.Int64("length", 0, "Assess only this many samples from -offset, 0 for the rest of the input")
+	maxBytes := fs.Int64("max-bytes", 0, "Assess at most this many samples from -offset, 0 for no limit")
+	sampleBytes := fs.Int64("sample-bytes", 0, "Assess only the first N samples from -offset, a quick non-conforming dry run; 0 for all")
+	stride := fs.Int("stride", 1, "Assess only every k-th sample of the window, starting with the first; non-conforming, 1 for all")
+	bitOrder := fs.String("bit-order", "msb", "Bit order of the bitstring expansion: msb or lsb")
+	histogram := fs.Bool("histogram", false, "Include the 256-entry symbol histogram in the JSON output")
+	raw := fs.Bool("raw", false, "Include the NIST tool JSON document of the run in the output")
+	permRounds := fs.Int("permutation-rounds", entropy.PermutationRounds, "IID permutation test rounds in pure-Go builds; fewer is non-conforming, for smoke tests only")
+	permSeed := fs.Uint64("permutation-seed", 0, "Seed the IID permutation test shuffles for reproducible results in pure-Go builds")
+	jobs := fs.Int("jobs", 1, "Number of input files to assess concurrently")
+	threads := fs.Int("threads", 0, "Threads of the IID permutation tests per assessment, 0 to share the CPUs among -jobs")
+	recursive := fs.Bool("recursive", false, "Assess every file below the directory arguments, as with several input files")
+	pattern := fs.String("pattern", "", "With -recursive, only assess files whose name matches this glob (e.g. '*.bin')")
+	followSymlinks := fs.Bool("follow-symlinks", false, "With -recursive, descend into symlinked directories")
+	hidden := fs.Bool("hidden", false, "With -recursive, include files and directories whose name starts with a dot")
+	archive := fs.Bool("archive", false, "Assess every file in the tar, tar.gz, tar.zst or zip archives given as input as a separate file")
+	timeout := fs.Duration("timeout", 0, "Abort the assessment after this duration (e.g. 10m), 0 for no limit")
+	remote := fs.String("remote", "", "Assess on the gRPC assessment service at host:port instead of the local library")
+	remoteTLS := fs.Bool("tls", false, "With -remote, connect with TLS, verifying the server certificate against the system roots or -ca")
+	caFile := fs.String("ca", "", "With -remote, PEM file of the CA of the server certificate instead of the system roots; implies -tls")
+	token := fs.String("token", "", "With -remote, bearer token for a service with AUTH_ENABLED")
 	showVersion := fs.Bool("version", false, "Show version information")
+	versionJSON := fs.Bool("json", false, "With -version, print version and library information as JSON")
 
 	fs.Usage = func() {
-		fmt.Fprintf(stderr, "Usage: %s [options] <file>\n\n", fs.Name())
+		fmt.Fprintf(stderr, "Usage: %s [options] [file ...]\n", fs.Name())
+		fmt.Fprintf(stderr, "       %s selftest\n", fs.Name())
+		fmt.Fprintf(stderr, "       %s monitor [options] [file|-]\n", fs.Name())
+		fmt.Fprintf(stderr, "       %s validate [options] [file|-]\n", fs.Name())
+		fmt.Fprintf(stderr, "       %s conditioned -n-in n -n-out n -nw n -h-in h [options] [file|-]\n", fs.Name())
+		fmt.Fprintf(stderr, "       %s split-restart -out file [options] [file|-]\n", fs.Name())
+		fmt.Fprintf(stderr, "       %s compare [options] baseline.json current.json\n", fs.Name())
+		fmt.Fprintf(stderr, "       %s watch -dir dir -output-dir dir -iid|-non-iid [options]\n", fs.Name())
+		fmt.Fprintf(stderr, "       %s report -iid|-non-iid [-format markdown|html] [-o file] [options] [file|-]\n", fs.Name())
+		fmt.Fprintf(stderr, "       %s capture -device path -iid|-non-iid [options]\n\n", fs.Name())
 		fmt.Fprintf(stderr, "Entropy Assessment Tool for NIST SP800-90B\n\n")
 		fmt.Fprintf(stderr, "Options:\n")
 		fs.PrintDefaults()
 		fmt.Fprintf(stderr, "\nExamples:\n")
 		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 data.bin\n", fs.Name())
 		fmt.Fprintf(stderr, "  %s -iid -bits 1 data.bin -output result.json\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -all -bits 8 data.bin -output result.json\n", fs.Name())
 		fmt.Fprintf(stderr, "  cat data.bin | %s -non-iid -bits 8\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -input-encoding hex capture.hex\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 4 -column 2 samples.csv\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -estimators mcv,markov data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -offset 1024 -length 1000000 capture.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -skip-bytes 512 -max-bytes 1000000 capture.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -sample-bytes 100000 capture.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -stride 4 capture.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -iid-check -bits 8 data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -iid-check -bits 8 -permutation-rounds 500 -permutation-seed 1 data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -jobs 4 a.bin b.bin c.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -recursive -pattern '*.bin' -output results.json captures/\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 capture.bin.zst\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -archive -output results.json captures.tar.gz\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -conditioning 512,256,256 data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -health -bits 8 -h-submitter 6.5 capture.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -restart 1000x1000 -h-initial 6.5 restarts.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -quick -bits 8 -fail-below 6 data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -lrs -bits 8 data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -compare run2.bin -compare-tolerance 0.05 run1.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -format nist-json -output result.json data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -non-iid -bits 8 -remote entropy.example.com:9090 -tls -token \"$TOKEN\" data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "\nExit codes:\n")
+		fmt.Fprintf(stderr, "  0  success\n")
+		fmt.Fprintf(stderr, "  1  assessment error\n")
+		fmt.Fprintf(stderr, "  2  invalid arguments\n")
+		fmt.Fprintf(stderr, "  3  min-entropy below -fail-below (with -all, the lower of IID and Non-IID),\n")
+		fmt.Fprintf(stderr, "     failed -iid-check, -health or -restart tests, or -compare inputs not similar\n")
+		fmt.Fprintf(stderr, "  4  -remote service unreachable or credentials rejected\n")
 	}
 
 	if err := fs.Parse(args); err != nil {
@@ -39,18 +168,54 @@ func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 	}
 
 	if *showVersion {
-		fmt.Fprintf(stdout, "ea_tool version %s\n", version)
-		return 0
+		return printVersion(stdout, *versionJSON)
+	}
+
+	outputFormat, err := parseFormat(*format)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if *all && (*quick || *healthMode || *iidCheck) {
+		fmt.Fprintf(stderr, "Error: -all cannot be combined with -quick, -health, or -iid-check\n")
+		return 2
+	}
+	bothModes := *all || (*iid && *nonIID)
+
+	if *lrs {
+		if *quick || *iid || *nonIID || *all || *iidCheck || *healthMode {
+			fmt.Fprintf(stderr, "Error: -lrs cannot be combined with -quick, -iid, -non-iid, -all, -iid-check, or -health\n")
+			return 2
+		}
+	} else if *quick {
+		if *iid || *nonIID || *iidCheck || *healthMode {
+			fmt.Fprintf(stderr, "Error: -quick cannot be combined with -iid, -non-iid, -iid-check, or -health\n")
+			return 2
+		}
+	} else if *healthMode {
+		if *iid || *nonIID || *iidCheck {
+			fmt.Fprintf(stderr, "Error: -health cannot be combined with -iid, -non-iid, or -iid-check\n")
+			return 2
+		}
+	} else if *iidCheck {
+		if *nonIID {
+			fmt.Fprintf(stderr, "Error: -iid-check cannot be combined with -non-iid\n")
+			return 2
+		}
+		*iid = true
 	}
 
-	if *iid == *nonIID {
-		fmt.Fprintf(stderr, "Error: Must specify exactly one of -iid or -non-iid\n\n")
+	if !*healthMode && !*quick && !*lrs && !bothModes && *iid == *nonIID {
+		fmt.Fprintf(stderr, "Error: Must specify exactly one of -iid or -non-iid, or -all for both\n\n")
 		fs.Usage()
 		return 2
 	}
 
+	// -all runs the IID assessment first; its settings are validated for
+	// both test types below.
 	var testType entropy.TestType
-	if *iid {
+	if *iid || bothModes {
 		testType = entropy.IID
 	} else {
 		testType = entropy.NonIID
@@ -61,9 +226,364 @@ func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 		return 2
 	}
 
+	encoding, err := parseInputEncoding(*inputEncoding)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if *column < 0 {
+		fmt.Fprintf(stderr, "Error: column must be 1 or greater, got %d\n", *column)
+		return 2
+	}
+	if *column > 0 && encoding != encodingBinary {
+		fmt.Fprintf(stderr, "Error: -column cannot be combined with -input-encoding %s\n", encoding)
+		return 2
+	}
+
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	hSubmitterSet := setFlags["h-submitter"]
+	if hSubmitterSet && (*hSubmitter < 0 || (*bits > 0 && *hSubmitter > float64(*bits))) {
+		fmt.Fprintf(stderr, "Error: h-submitter must be between 0 and bits per symbol, got %g\n", *hSubmitter)
+		return 2
+	}
+
+	var selection []string
+	if *estimators != "" {
+		selection = strings.Split(*estimators, ",")
+		if err := entropy.ValidateEstimators(testType, selection); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+		if bothModes {
+			if err := entropy.ValidateEstimators(entropy.NonIID, selection); err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return 2
+			}
+		}
+	}
+
+	// A threshold of 0 can never fail, so it disables the gate.
+	thresholdSet := setFlags["fail-below"] && *failBelow != 0
+	if thresholdSet && (math.IsNaN(*failBelow) || *failBelow < 0) {
+		fmt.Fprintf(stderr, "Error: fail-below must be a non-negative number, got %g\n", *failBelow)
+		return 2
+	}
+	if maxBits := cmp.Or(*bits, 8); thresholdSet && *failBelow > float64(maxBits) {
+		fmt.Fprintf(stderr, "Error: fail-below must not exceed %d bits per symbol, got %g\n", maxBits, *failBelow)
+		return 2
+	}
+
+	if *iidCheck && (hSubmitterSet || thresholdSet || len(selection) > 0) {
+		fmt.Fprintf(stderr, "Error: -iid-check cannot be combined with -h-submitter, -fail-below, or -estimators\n")
+		return 2
+	}
+
+	order, err := entropy.ParseBitOrder(*bitOrder)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if *offset < 0 || *length < 0 {
+		fmt.Fprintf(stderr, "Error: offset and length must not be negative, got %d and %d\n", *offset, *length)
+		return 2
+	}
+	if *maxBytes < 0 {
+		fmt.Fprintf(stderr, "Error: max-bytes must not be negative, got %d\n", *maxBytes)
+		return 2
+	}
+	if *sampleBytes < 0 {
+		fmt.Fprintf(stderr, "Error: sample-bytes must not be negative, got %d\n", *sampleBytes)
+		return 2
+	}
+	if *stride < 1 {
+		fmt.Fprintf(stderr, "Error: stride must be at least 1, got %d\n", *stride)
+		return 2
+	}
+
+	if *quick && (hSubmitterSet || len(selection) > 0 || *histogram || *raw || setFlags["bit-order"]) {
+		fmt.Fprintf(stderr, "Error: -quick cannot be combined with -h-submitter, -estimators, -histogram, -raw, or -bit-order\n")
+		return 2
+	}
+
+	if *lrs && (hSubmitterSet || len(selection) > 0 || *histogram || *raw || setFlags["bit-order"]) {
+		fmt.Fprintf(stderr, "Error: -lrs cannot be combined with -h-submitter, -estimators, -histogram, -raw, or -bit-order\n")
+		return 2
+	}
+
+	if *healthMode {
+		if !hSubmitterSet {
+			fmt.Fprintf(stderr, "Error: -health requires -h-submitter, the claimed min-entropy per sample\n")
+			return 2
+		}
+		if thresholdSet || len(selection) > 0 || *histogram || *raw || *outputFile != "" {
+			fmt.Fprintf(stderr, "Error: -health cannot be combined with -fail-below, -estimators, -histogram, -raw, or -output\n")
+			return 2
+		}
+		if fs.NArg() > 1 {
+			fmt.Fprintf(stderr, "Error: -health accepts a single input file\n")
+			return 2
+		}
+	}
+
+	var conditioningParams *entropy.ConditioningParams
+	if *conditioning != "" {
+		if *quick || *lrs || *iidCheck || *healthMode || *compareFile != "" || bothModes {
+			fmt.Fprintf(stderr, "Error: -conditioning cannot be combined with -quick, -lrs, -iid-check, -health, -compare, or -all\n")
+			return 2
+		}
+		p, err := parseConditioning(*conditioning)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+		conditioningParams = &p
+	}
+
+	var restartMatrix *entropy.RestartMatrix
+	if *restart != "" {
+		if *quick || *lrs || *iidCheck || *healthMode || *compareFile != "" || bothModes || *conditioning != "" || *sampleBytes > 0 || *stride > 1 {
+			fmt.Fprintf(stderr, "Error: -restart cannot be combined with -quick, -lrs, -iid-check, -health, -compare, -all, -conditioning, -sample-bytes, or -stride\n")
+			return 2
+		}
+		if hSubmitterSet || thresholdSet || *histogram || *raw || outputFormat == formatNISTJSON {
+			fmt.Fprintf(stderr, "Error: -restart cannot be combined with -h-submitter, -fail-below, -histogram, -raw, or -format nist-json\n")
+			return 2
+		}
+		rows, cols, err := parseRestart(*restart)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+		layout, err := entropy.ParseRestartLayout(*restartLayout)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+		if !setFlags["h-initial"] {
+			fmt.Fprintf(stderr, "Error: -restart requires -h-initial, the initial entropy estimate H_I\n")
+			return 2
+		}
+		if math.IsNaN(*hInitial) || *hInitial <= 0 || *hInitial > float64(cmp.Or(*bits, 8)) {
+			fmt.Fprintf(stderr, "Error: h-initial must be above 0 and at most %d bits per symbol, got %g\n", cmp.Or(*bits, 8), *hInitial)
+			return 2
+		}
+		restartMatrix = &entropy.RestartMatrix{Rows: rows, Cols: cols, Layout: layout}
+	} else if setFlags["restart-layout"] || setFlags["h-initial"] {
+		fmt.Fprintf(stderr, "Error: -restart-layout and -h-initial require -restart\n")
+		return 2
+	}
+
+	if *permRounds < 1 {
+		fmt.Fprintf(stderr, "Error: permutation-rounds must be at least 1, got %d\n", *permRounds)
+		return 2
+	}
+	permSeedSet := setFlags["permutation-seed"]
+	if testType != entropy.IID && (setFlags["permutation-rounds"] || permSeedSet) {
+		fmt.Fprintf(stderr, "Error: -permutation-rounds and -permutation-seed require -iid or -iid-check\n")
+		return 2
+	}
+
+	if *timeout < 0 {
+		fmt.Fprintf(stderr, "Error: timeout must not be negative, got %s\n", *timeout)
+		return 2
+	}
+
+	if *remote != "" {
+		if *quick || *lrs || *healthMode || restartMatrix != nil {
+			fmt.Fprintf(stderr, "Error: -remote cannot be combined with -quick, -lrs, -health, or -restart\n")
+			return 2
+		}
+		if setFlags["permutation-rounds"] || permSeedSet || setFlags["threads"] || outputFormat == formatNISTJSON {
+			fmt.Fprintf(stderr, "Error: -remote cannot be combined with -permutation-rounds, -permutation-seed, -threads, or -format nist-json\n")
+			return 2
+		}
+	} else if setFlags["tls"] || setFlags["ca"] || setFlags["token"] {
+		fmt.Fprintf(stderr, "Error: -tls, -ca, and -token require -remote\n")
+		return 2
+	}
+
+	if *jobs < 1 {
+		fmt.Fprintf(stderr, "Error: jobs must be at least 1, got %d\n", *jobs)
+		return 2
+	}
+	if *threads < 0 {
+		fmt.Fprintf(stderr, "Error: threads must not be negative, got %d\n", *threads)
+		return 2
+	}
+
+	if *compareFile != "" {
+		if *quick || *lrs || *iidCheck || *healthMode || thresholdSet || *histogram || *raw {
+			fmt.Fprintf(stderr, "Error: -compare cannot be combined with -quick, -lrs, -iid-check, -health, -fail-below, -histogram, or -raw\n")
+			return 2
+		}
+		if fs.NArg() > 1 {
+			fmt.Fprintf(stderr, "Error: -compare accepts a single primary input\n")
+			return 2
+		}
+	} else if setFlags["compare-tolerance"] {
+		fmt.Fprintf(stderr, "Error: -compare-tolerance requires -compare\n")
+		return 2
+	}
+	if math.IsNaN(*compareTolerance) || math.IsInf(*compareTolerance, 0) || *compareTolerance < 0 {
+		fmt.Fprintf(stderr, "Error: compare-tolerance must be a non-negative number, got %g\n", *compareTolerance)
+		return 2
+	}
+
+	if bothModes {
+		if *compareFile != "" || *format == formatNISTJSON {
+			fmt.Fprintf(stderr, "Error: -all cannot be combined with -compare or -format nist-json\n")
+			return 2
+		}
+		if fs.NArg() > 1 {
+			fmt.Fprintf(stderr, "Error: -all accepts a single input file\n")
+			return 2
+		}
+	}
+
+	if *recursive {
+		if fs.NArg() == 0 {
+			fmt.Fprintf(stderr, "Error: -recursive requires at least one directory\n")
+			return 2
+		}
+		if *healthMode || *compareFile != "" || bothModes {
+			fmt.Fprintf(stderr, "Error: -recursive cannot be combined with -health, -compare, or -all\n")
+			return 2
+		}
+		if err := validatePattern(*pattern); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+	} else if setFlags["pattern"] || setFlags["follow-symlinks"] || setFlags["hidden"] {
+		fmt.Fprintf(stderr, "Error: -pattern, -follow-symlinks, and -hidden require -recursive\n")
+		return 2
+	}
+
+	if *archive {
+		if fs.NArg() == 0 {
+			fmt.Fprintf(stderr, "Error: -archive requires at least one archive\n")
+			return 2
+		}
+		if *healthMode || *compareFile != "" || bothModes {
+			fmt.Fprintf(stderr, "Error: -archive cannot be combined with -health, -compare, or -all\n")
+			return 2
+		}
+	}
+
+	if outputFormat == formatNISTJSON {
+		if *outputFile == "" {
+			fmt.Fprintf(stderr, "Error: -format nist-json requires -output\n")
+			return 2
+		}
+		if *quick || *lrs || *compareFile != "" {
+			fmt.Fprintf(stderr, "Error: -format nist-json cannot be combined with -quick, -lrs, or -compare\n")
+			return 2
+		}
+	}
+	if toStdout(outputFormat) && (*compareFile != "" || *healthMode) {
+		fmt.Fprintf(stderr, "Error: -format %s cannot be combined with -compare or -health\n", outputFormat)
+		return 2
+	}
+
+	opts := &cliOptions{
+		testType:      testType,
+		bits:          *bits,
+		verbose:       *verbose,
+		encoding:      encoding,
+		column:        *column,
+		delimiter:     *delimiter,
+		hSubmitter:    *hSubmitter,
+		hSubmitterSet: hSubmitterSet,
+		estimators:    selection,
+		failBelow:     *failBelow,
+		thresholdSet:  thresholdSet,
+		offset:        *offset,
+		length:        *length,
+		maxBytes:      *maxBytes,
+		sampleBytes:   *sampleBytes,
+		stride:        *stride,
+		bitOrder:      order,
+		histogram:     *histogram,
+		raw:           *raw,
+		permRounds:    *permRounds,
+		permSeed:      *permSeed,
+		permSeedSet:   permSeedSet,
+		threads:       *threads,
+		timeout:       *timeout,
+		iidCheck:      *iidCheck,
+		quick:         *quick,
+		lrs:           *lrs,
+		health:        *healthMode,
+		conditioning:  conditioningParams,
+		restart:       restartMatrix,
+		hInitial:      *hInitial,
+		layoutSet:     setFlags["restart-layout"],
+		toFile:        *outputFile != "" || toStdout(outputFormat),
+		format:        outputFormat,
+		commandline:   strings.Join(append([]string{fs.Name()}, args...), " "),
+	}
+
+	if *sampleBytes > 0 && *verbose > 0 {
+		fmt.Fprintf(stderr, "Warning: -sample-bytes assesses only the first %d samples of each input; this is not a conforming SP 800-90B assessment\n", *sampleBytes)
+	}
+	if *stride > 1 && *verbose > 0 {
+		fmt.Fprintf(stderr, "Warning: -stride assesses only one in every %d samples of each input; this is not a conforming SP 800-90B assessment\n", *stride)
+	}
+
+	if *remote != "" {
+		service, err := newRemoteService(*remote, *remoteTLS, *caFile, *token, *timeout)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+		defer service.Close()
+		opts.remote = service
+	}
+
+	if *recursive || *archive || fs.NArg() > 1 {
+		var inputs []batchInput
+		if *recursive {
+			walk := walkOptions{pattern: *pattern, followSymlinks: *followSymlinks, hidden: *hidden}
+			inputs, err = walk.collectInputs(fs.Args())
+			if err != nil {
+				fmt.Fprintf(stderr, "Error reading directory: %v\n", err)
+				return 1
+			}
+			if len(inputs) == 0 {
+				fmt.Fprintf(stderr, "Error: no input files found in %s\n", strings.Join(fs.Args(), ", "))
+				return 1
+			}
+		} else {
+			for _, name := range fs.Args() {
+				inputs = append(inputs, batchInput{path: name})
+			}
+		}
+		if *archive {
+			inputs = expandArchives(inputs)
+			if len(inputs) == 0 {
+				fmt.Fprintf(stderr, "Error: no files found in %s\n", strings.Join(fs.Args(), ", "))
+				return 1
+			}
+		}
+
+		// Ctrl-C stops the batch: running assessments are abandoned and the
+		// remaining files are reported as not assessed.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runBatch(ctx, opts, inputs, *jobs, *outputFile, stdout, stderr)
+	}
+
+	// With -output -, stdout carries the results alone and the text that
+	// would go there is written to stderr.
+	doc, stdout := outputWriters(*outputFile, stdout, stderr)
+
 	var data []byte
 	var filename string
-	var err error
 
 	if fs.NArg() == 0 {
 		filename = "stdin"
@@ -74,64 +594,108 @@ func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 		}
 	} else {
 		filename = fs.Arg(0)
-		data, err = os.ReadFile(filename)
+		data, err = opts.readFile(filename, stderr)
 		if err != nil {
 			fmt.Fprintf(stderr, "Error reading file %s: %v\n", filename, err)
 			return 1
 		}
 	}
 
-	assessment := entropy.NewAssessment()
-	assessment.SetVerbose(*verbose)
-
-	var result *entropy.Result
-	if testType == entropy.IID {
-		result, err = assessment.AssessIID(data, *bits)
-	} else {
-		result, err = assessment.AssessNonIID(data, *bits)
-	}
-
-	jsonOut := JSONOutput{
-		Version:       version,
-		Filename:      filename,
-		TestType:      testType.String(),
-		BitsPerSymbol: *bits,
-		DataSize:      len(data),
-		ErrorCode:     0,
+	if opts.health {
+		return opts.checkHealth(filename, data, stdout, stderr)
 	}
 
-	if err != nil {
-		jsonOut.ErrorCode = 1
-		jsonOut.ErrorMessage = err.Error()
+	if *compareFile != "" {
+		other, err := opts.readFile(*compareFile, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading file %s: %v\n", *compareFile, err)
+			return 1
+		}
+		out, code := opts.compare(filename, data, *compareFile, other, *compareTolerance, stdout, stderr)
 		if *outputFile != "" {
-			writeJSON(*outputFile, jsonOut)
-		} else {
-			fmt.Fprintf(stderr, "Error: %v\n", err)
+			writeJSON(*outputFile, doc, out)
+			if namedOutput(*outputFile) && *verbose > 0 && out.ErrorCode == 0 {
+				fmt.Fprintf(stdout, "Results written to %s\n", *outputFile)
+			}
 		}
-		return 1
+		return code
 	}
 
-	jsonOut.MinEntropy = result.MinEntropy
-	jsonOut.HOriginal = result.HOriginal
-	jsonOut.HBitstring = result.HBitstring
-	jsonOut.HAssessed = result.HAssessed
-
-	if *outputFile != "" {
-		writeJSON(*outputFile, jsonOut)
-		if *verbose > 0 {
+	started := time.Now()
+	if bothModes {
+		out, code := opts.assessAll(filename, data, stdout, stderr)
+		if outputFormat == formatCSV {
+			writeCSV(*outputFile, doc, allRows(out))
+		} else if outputFormat == formatNDJSON {
+			writeNDJSON(*outputFile, doc, started, allRows(out))
+		} else if outputFormat == formatYAML {
+			writeYAML(*outputFile, doc, []any{out})
+		} else if *outputFile != "" {
+			writeJSON(*outputFile, doc, out)
+		}
+		if namedOutput(*outputFile) && *verbose > 0 && out.ErrorCode == 0 {
 			fmt.Fprintf(stdout, "Results written to %s\n", *outputFile)
 		}
-	} else if *verbose >= 1 {
-		fmt.Fprintf(stdout, "\nEntropy Assessment Results:\n")
-		fmt.Fprintf(stdout, "  Test Type:       %s\n", testType)
-		fmt.Fprintf(stdout, "  Bits/Symbol:     %d\n", result.DataWordSize)
-		fmt.Fprintf(stdout, "  H_original:      %.6f\n", result.HOriginal)
-		if result.HBitstring > 0 {
-			fmt.Fprintf(stdout, "  H_bitstring:     %.6f\n", result.HBitstring)
+		return code
+	}
+
+	jsonOut, code := opts.assess(filename, data, stdout, stderr)
+	if outputFormat == formatCSV {
+		writeCSV(*outputFile, doc, []JSONOutput{jsonOut})
+	} else if outputFormat == formatNDJSON {
+		writeNDJSON(*outputFile, doc, started, []JSONOutput{jsonOut})
+	} else if outputFormat == formatYAML {
+		writeYAML(*outputFile, doc, []any{opts.document(jsonOut)})
+	} else if *outputFile != "" {
+		writeJSON(*outputFile, doc, opts.document(jsonOut))
+	}
+	if namedOutput(*outputFile) && *verbose > 0 && jsonOut.ErrorCode == 0 {
+		fmt.Fprintf(stdout, "Results written to %s\n", *outputFile)
+	}
+	return code
+}
+
+// versionOutput is the -version -json document.
+type versionOutput struct {
+	Version string               `json:"version"`
+	Library entropy.Capabilities `json:"library"`
+}
+
+// printVersion writes the tool version, the linked library information and
+// the active estimators, warning when the build runs the pure-Go subset or
+// the test stub instead of the NIST code.
+func printVersion(w io.Writer, asJSON bool) int {
+	info := entropy.LibraryInfo()
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(versionOutput{Version: version, Library: info}); err != nil {
+			return 1
 		}
-		fmt.Fprintf(stdout, "  H_assessed:      %.6f\n", result.HAssessed)
-		fmt.Fprintf(stdout, "  Min Entropy:     %.6f\n", result.MinEntropy)
+		return 0
 	}
 
+	fmt.Fprintf(w, "ea_tool version %s\n", version)
+	fmt.Fprintf(w, "NIST SP 800-90B tool %s (wrapper %s)\n", info.ToolVersion, info.WrapperVersion)
+	fmt.Fprintf(w, "Backend: %s\n", info.Backend)
+	fmt.Fprintf(w, "IID estimators: %s\n", strings.Join(info.IIDEstimators, ", "))
+	fmt.Fprintf(w, "Non-IID estimators: %s\n", strings.Join(info.NonIIDEstimators, ", "))
+	switch info.Backend {
+	case entropy.BackendGo:
+		fmt.Fprintf(w, "WARNING: pure-Go build, only the estimators above run and results are not conforming\n")
+	case entropy.BackendStub:
+		fmt.Fprintf(w, "WARNING: stub build, entropy results are fixed test values\n")
+	}
+	return 0
+}
+
+// runSelfTest checks the linked library against the embedded known-answer
+// vectors.
+func runSelfTest(stdout, stderr io.Writer) int {
+	if err := entropy.SelfTest(); err != nil {
+		fmt.Fprintf(stderr, "Self-test FAILED: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Self-test passed\n")
 	return 0
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// Schemes of "ea_tool split-restart", the ways a capture holds its restarts.
+const (
+	// schemeSequential splits the capture into rows blocks of equal size,
+	// one restart after the other; each row is the start of one block.
+	schemeSequential = "sequential"
+	// schemeInterleaved reads the capture as the restarts sampled round
+	// robin: sample j of restart i is sample j*rows+i of the capture.
+	schemeInterleaved = "interleaved"
+)
+
+// restartManifestSuffix is appended to the matrix file name to name its
+// manifest. -restart looks for the manifest under the same name.
+const restartManifestSuffix = ".manifest.json"
+
+// RestartManifest describes how "ea_tool split-restart" built a restart
+// matrix. It is written next to the matrix, see restartManifestSuffix.
+type RestartManifest struct {
+	Version    string `json:"version"`
+	Source     string `json:"source"`
+	SourceSize int    `json:"source_size"`
+	Scheme     string `json:"scheme"`
+	BlockSize  int    `json:"block_size,omitempty"`
+	Rows       int    `json:"rows"`
+	Cols       int    `json:"cols"`
+	Layout     string `json:"layout"`
+	Matrix     string `json:"matrix"`
+	SHA256     string `json:"sha256"`
+}
+
+// runSplitRestart implements the "split-restart" subcommand. It carves a
+// rows x cols restart matrix out of one capture, writes it to -out and
+// writes the manifest next to it. It returns 0 on success, 1 on a read or
+// write error or when the capture is too short, or 2 on argument validation
+// failure.
+func runSplitRestart(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ea_tool split-restart", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	rows := fs.Int("rows", 1000, "Number of restarts, the rows of the matrix")
+	cols := fs.Int("cols", 1000, "Samples per restart, the columns of the matrix")
+	scheme := fs.String("scheme", schemeSequential, "How the capture holds the restarts: sequential, each row the start of one of rows equal blocks, or interleaved, sample j of restart i at j*rows+i")
+	layout := fs.String("layout", "row", "Sample order of the written matrix: row (restart after restart, as the NIST ea_restart tool reads it) or column")
+	out := fs.String("out", "", "Output file of the matrix; the manifest is written to this name plus "+restartManifestSuffix)
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s -out file [options] [file|-]\n\n", fs.Name())
+		fmt.Fprintf(stderr, "Split one capture into a restart matrix for -restart.\n\n")
+		fmt.Fprintf(stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExamples:\n")
+		fmt.Fprintf(stderr, "  %s -rows 1000 -cols 1000 -out matrix.bin capture.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -scheme interleaved -out matrix.bin capture.bin\n", fs.Name())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *rows < 2 || *cols < 2 {
+		fmt.Fprintf(stderr, "Error: -rows and -cols must be at least 2, got %d and %d\n", *rows, *cols)
+		return 2
+	}
+	*scheme = strings.ToLower(*scheme)
+	if *scheme != schemeSequential && *scheme != schemeInterleaved {
+		fmt.Fprintf(stderr, "Error: -scheme must be sequential or interleaved, got %q\n", *scheme)
+		return 2
+	}
+	matrixLayout, err := entropy.ParseRestartLayout(*layout)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *out == "" {
+		fmt.Fprintf(stderr, "Error: split-restart requires -out\n")
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintf(stderr, "Error: split-restart accepts a single input\n")
+		return 2
+	}
+
+	filename, data := "stdin", []byte(nil)
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		filename = fs.Arg(0)
+		data, err = entropy.ReadFile(filename)
+	} else {
+		data, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading %s: %v\n", filename, err)
+		return 1
+	}
+
+	m := entropy.RestartMatrix{Rows: *rows, Cols: *cols, Layout: matrixLayout}
+	matrix, blockSize, err := splitRestart(data, m, *scheme)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %s: %v\n", filename, err)
+		return 1
+	}
+
+	manifest := RestartManifest{
+		Version:    version,
+		Source:     filename,
+		SourceSize: len(data),
+		Scheme:     *scheme,
+		BlockSize:  blockSize,
+		Rows:       m.Rows,
+		Cols:       m.Cols,
+		Layout:     m.Layout.String(),
+		Matrix:     filepath.Base(*out),
+		SHA256:     sha256Hex(matrix),
+	}
+	if err := os.WriteFile(*out, matrix, 0o644); err != nil {
+		fmt.Fprintf(stderr, "Error writing matrix: %v\n", err)
+		return 1
+	}
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		fmt.Fprintf(stderr, "Error encoding manifest: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(*out+restartManifestSuffix, append(encoded, '\n'), 0o644); err != nil {
+		fmt.Fprintf(stderr, "Error writing manifest: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Wrote %d restarts x %d samples (%s scheme, %s layout) to %s\n", m.Rows, m.Cols, manifest.Scheme, manifest.Layout, *out)
+	fmt.Fprintf(stdout, "Manifest: %s\n", *out+restartManifestSuffix)
+	return 0
+}
+
+// splitRestart returns the restart matrix m taken from data with scheme,
+// and the block size of the sequential scheme. Samples beyond the matrix
+// are dropped.
+func splitRestart(data []byte, m entropy.RestartMatrix, scheme string) ([]byte, int, error) {
+	if int64(len(data)) < m.Size() {
+		return nil, 0, fmt.Errorf("a %dx%d restart matrix needs at least %d samples, the capture holds %d", m.Rows, m.Cols, m.Size(), len(data))
+	}
+
+	// at returns sample j of restart i.
+	at := func(i, j int) byte { return data[j*m.Rows+i] }
+	blockSize := 0
+	if scheme == schemeSequential {
+		blockSize = len(data) / m.Rows
+		at = func(i, j int) byte { return data[i*blockSize+j] }
+	}
+
+	matrix := make([]byte, m.Size())
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if m.Layout == entropy.ColumnMajor {
+				matrix[j*m.Rows+i] = at(i, j)
+			} else {
+				matrix[i*m.Cols+j] = at(i, j)
+			}
+		}
+	}
+	return matrix, blockSize, nil
+}
+
+// readRestartManifest reads the manifest that split-restart wrote for the
+// matrix file filename. It returns nil without an error when there is none.
+func readRestartManifest(filename string) (*RestartManifest, error) {
+	encoded, err := os.ReadFile(filename + restartManifestSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest RestartManifest
+	if err := json.Unmarshal(encoded, &manifest); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", filename+restartManifestSuffix, err)
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// ValidateOutput is the JSON document written by "ea_tool validate -output".
+type ValidateOutput struct {
+	Version       string                   `json:"version"`
+	Filename      string                   `json:"filename"`
+	TestType      string                   `json:"test_type"`
+	BitsPerSymbol int                      `json:"bits_per_symbol"`
+	Reference     string                   `json:"reference,omitempty"`
+	Tolerance     float64                  `json:"tolerance"`
+	Passed        bool                     `json:"passed"`
+	Deltas        []entropy.EstimatorDelta `json:"deltas,omitempty"`
+	ErrorCode     int                      `json:"error_code"`
+	ErrorMessage  string                   `json:"error_message,omitempty"`
+}
+
+// runValidate implements the "validate" subcommand. It runs the estimators
+// with a pure-Go implementation on one input with both the linked NIST
+// library and the Go code and prints a per-estimator diff. It returns 0 when
+// every estimate agrees within the tolerance, 1 on a read or assessment
+// error, 2 on argument validation failure, or 3 when an estimator diverges.
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ea_tool validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	against := fs.String("against", "cgo", "Backend to compare the pure-Go estimators against: cgo")
+	iid := fs.Bool("iid", false, "Compare the IID track instead of Non-IID")
+	bits := fs.Int("bits", 0, "Bits per symbol (1-8), 0 for auto-detect")
+	tolerance := fs.Float64("tolerance", entropy.DefaultCrossValidationTolerance, "Largest accepted absolute difference in bits per sample")
+	estimators := fs.String("estimators", "", "Comma-separated estimator subset to compare, default all with a Go implementation")
+	bitOrder := fs.String("bit-order", "msb", "Bit order of the bitstring expansion: msb or lsb")
+	outputFile := fs.String("output", "", "Output file for JSON results, - for stdout with the text on stderr")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s [options] [file|-]\n\n", fs.Name())
+		fmt.Fprintf(stderr, "Compare the pure-Go estimators with the NIST library on the same data.\n")
+		fmt.Fprintf(stderr, "The IID permutation tests are not compared.\n\n")
+		fmt.Fprintf(stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExamples:\n")
+		fmt.Fprintf(stderr, "  %s -against cgo -bits 8 data.bin\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -iid -bits 4 -tolerance 1e-9 -output diff.json data.bin\n", fs.Name())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	doc, stdout := outputWriters(*outputFile, stdout, stderr)
+
+	if *against != "cgo" {
+		fmt.Fprintf(stderr, "Error: -against must be cgo, got %q\n", *against)
+		return 2
+	}
+	if *bits < 0 || *bits > 8 {
+		fmt.Fprintf(stderr, "Error: bits per symbol must be 0-8, got %d\n", *bits)
+		return 2
+	}
+	if !(*tolerance >= 0) {
+		fmt.Fprintf(stderr, "Error: tolerance must be a non-negative number, got %g\n", *tolerance)
+		return 2
+	}
+	testType := entropy.NonIID
+	if *iid {
+		testType = entropy.IID
+	}
+	var selection []string
+	if *estimators != "" {
+		selection = strings.Split(*estimators, ",")
+		if err := entropy.ValidateEstimators(testType, selection); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+	order, err := entropy.ParseBitOrder(*bitOrder)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintf(stderr, "Error: validate accepts a single input\n")
+		return 2
+	}
+
+	filename, data := "stdin", []byte(nil)
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		filename = fs.Arg(0)
+		data, err = entropy.ReadFile(filename)
+	} else {
+		data, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading %s: %v\n", filename, err)
+		return 1
+	}
+
+	out := ValidateOutput{
+		Version:       version,
+		Filename:      filename,
+		TestType:      testType.String(),
+		BitsPerSymbol: *bits,
+		Tolerance:     *tolerance,
+	}
+
+	assessment := entropy.NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetEstimators(selection)
+	assessment.SetBitOrder(order)
+	cv, err := assessment.CrossValidate(data, *bits, testType, *tolerance)
+	if err != nil {
+		out.ErrorCode = 1
+		out.ErrorMessage = err.Error()
+		if *outputFile != "" {
+			writeJSON(*outputFile, doc, out)
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	out.BitsPerSymbol = cv.DataWordSize
+	out.Reference = cv.Reference
+	out.Passed = cv.Passed
+	out.Deltas = cv.Deltas
+	if *outputFile != "" {
+		writeJSON(*outputFile, doc, out)
+	}
+
+	printDeltas(stdout, cv)
+	if !cv.Passed {
+		fmt.Fprintf(stderr, "FAIL: the Go estimators diverge from %s by more than %g bits per sample\n", cv.Reference, cv.Tolerance)
+		return 3
+	}
+	return 0
+}
+
+// printDeltas prints the cross-validation report as a table. Pass/fail
+// tests show their outcomes instead of values.
+func printDeltas(w io.Writer, cv *entropy.CrossValidation) {
+	fmt.Fprintf(w, "Cross-validation of the %s estimators: %s vs go (tolerance %g)\n", cv.TestType, cv.Reference, cv.Tolerance)
+	fmt.Fprintf(w, "  %-38s %12s %12s %12s %12s  %s\n", "Estimator", cv.Reference, "go", "Abs Delta", "Rel Delta", "Result")
+	for _, d := range cv.Deltas {
+		result := "ok"
+		if !d.Within {
+			result = "DIVERGES"
+		}
+		if d.Test {
+			fmt.Fprintf(w, "  %-38s %12s %12s %12s %12s  %s\n", d.Name, passFail(d.Reference == 1), passFail(d.Native == 1), "-", "-", result)
+			continue
+		}
+		fmt.Fprintf(w, "  %-38s %12.6f %12.6f %12.3g %12.3g  %s\n", d.Name, d.Reference, d.Native, d.AbsDelta, d.RelDelta, result)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// batchInput is one file of a multi-file run. rel is its path relative to
+// the -recursive directory it was found in, empty for files named directly.
+// With -archive, member names the member of the archive at path, and err
+// is set in place of the members of an archive that could not be listed.
+type batchInput struct {
+	path   string
+	rel    string
+	member string
+	err    error
+}
+
+// walkOptions selects the files -recursive collects from a directory tree.
+type walkOptions struct {
+	pattern        string // filepath.Match pattern for base names; empty matches every file
+	followSymlinks bool   // descend into symlinked directories
+	hidden         bool   // include files and directories whose name starts with a dot
+}
+
+// collectInputs expands every directory in args into the files below it, in
+// lexical order; other arguments are kept as they are. Symlinks to files are
+// collected like files, so that broken ones are reported with the other
+// per-file errors, while symlinks to directories are only followed with
+// followSymlinks. Named pipes, sockets and devices in a tree are skipped.
+func (w walkOptions) collectInputs(args []string) ([]batchInput, error) {
+	var inputs []batchInput
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil || !info.IsDir() {
+			inputs = append(inputs, batchInput{path: arg})
+			continue
+		}
+		if err := w.walk(arg, arg, make(map[string]bool), &inputs); err != nil {
+			return nil, err
+		}
+	}
+	return inputs, nil
+}
+
+// walk appends the matching files below dir to inputs. visited holds the
+// resolved directories already walked below root, which stops symlink loops.
+func (w walkOptions) walk(root, dir string, visited map[string]bool, inputs *[]batchInput) error {
+	if w.followSymlinks {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return err
+		}
+		if visited[resolved] {
+			return nil
+		}
+		visited[resolved] = true
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !w.hidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		mode := entry.Type()
+		if mode&fs.ModeSymlink != 0 {
+			if target, err := os.Stat(path); err == nil {
+				mode = target.Mode().Type()
+			} else {
+				mode = 0 // broken: collected as a file so that reading it fails
+			}
+		}
+
+		switch {
+		case mode.IsDir():
+			if entry.Type()&fs.ModeSymlink != 0 && !w.followSymlinks {
+				continue
+			}
+			if err := w.walk(root, path, visited, inputs); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			if w.pattern != "" {
+				if ok, _ := filepath.Match(w.pattern, name); !ok {
+					continue
+				}
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			*inputs = append(*inputs, batchInput{path: path, rel: filepath.ToSlash(rel)})
+		}
+	}
+	return nil
+}
+
+// validatePattern reports a malformed -pattern before any directory is read.
+func validatePattern(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+)
+
+// Subdirectories of the -dir of "ea_tool watch" that receive the inputs
+// once they have been assessed.
+const (
+	watchDone   = "done"
+	watchFailed = "failed"
+)
+
+// defaultSettle is the -settle default: how long a file must keep its size
+// and modification time before it is considered completely written.
+const defaultSettle = 2 * time.Second
+
+// watchConfig holds the validated settings of "ea_tool watch".
+type watchConfig struct {
+	dir       string
+	outputDir string
+	settle    time.Duration
+	opts      *cliOptions
+}
+
+// pendingFile is a file of the watched directory that is waiting to stop
+// growing.
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // when size and modTime were last seen to change
+}
+
+// runWatch implements the "watch" subcommand. It watches a drop directory
+// for new files, assesses each once it has stopped growing, writes its JSON
+// result to -output-dir and moves it into the done or failed subdirectory.
+// SIGINT and SIGTERM stop the watch once the running assessment is finished.
+// It returns 0 after such a shutdown, 1 when the directories cannot be
+// prepared or watched, or 2 on argument validation failure.
+func runWatch(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ea_tool watch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	dir := fs.String("dir", "", "Drop directory to watch for new files (required)")
+	outputDir := fs.String("output-dir", "", "Directory of the result JSON files, one per input (required)")
+	iid := fs.Bool("iid", false, "Run the IID test")
+	nonIID := fs.Bool("non-iid", false, "Run the Non-IID test")
+	bits := fs.Int("bits", 0, "Bits per symbol (1-8), 0 for auto-detect")
+	hSubmitter := fs.Float64("h-submitter", 0, "Entropy claimed by the submitter in bits per sample (0 to bits)")
+	estimators := fs.String("estimators", "", "Comma-separated estimator subset (e.g. mcv,markov); non-conforming, default all")
+	settle := fs.Duration("settle", defaultSettle, "How long a file must stop growing before it is assessed")
+	verbose := fs.Int("verbose", 1, "Verbosity level (0=quiet, 1=normal, 2=verbose, 3=very verbose)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s -dir dir -output-dir dir -iid|-non-iid [options]\n\n", fs.Name())
+		fmt.Fprintf(stderr, "Assess the files dropped into a directory as they arrive. Each input is moved\n")
+		fmt.Fprintf(stderr, "into the %s or %s subdirectory of -dir once its result is written.\n", watchDone, watchFailed)
+		fmt.Fprintf(stderr, "Files whose name starts with a dot are ignored until renamed.\n\n")
+		fmt.Fprintf(stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExamples:\n")
+		fmt.Fprintf(stderr, "  %s -dir ./incoming -non-iid -bits 8 -output-dir ./results\n", fs.Name())
+		fmt.Fprintf(stderr, "  %s -dir ./incoming -iid -bits 1 -settle 10s -output-dir ./results\n", fs.Name())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *dir == "" || *outputDir == "" {
+		fmt.Fprintf(stderr, "Error: watch requires -dir and -output-dir\n")
+		return 2
+	}
+	if *iid == *nonIID {
+		fmt.Fprintf(stderr, "Error: Must specify exactly one of -iid or -non-iid\n")
+		return 2
+	}
+	testType := entropy.NonIID
+	if *iid {
+		testType = entropy.IID
+	}
+	if *bits < 0 || *bits > 8 {
+		fmt.Fprintf(stderr, "Error: bits per symbol must be 0-8, got %d\n", *bits)
+		return 2
+	}
+	hSubmitterSet := false
+	fs.Visit(func(f *flag.Flag) {
+		hSubmitterSet = hSubmitterSet || f.Name == "h-submitter"
+	})
+	if hSubmitterSet && (*hSubmitter < 0 || (*bits > 0 && *hSubmitter > float64(*bits))) {
+		fmt.Fprintf(stderr, "Error: h-submitter must be between 0 and bits per symbol, got %g\n", *hSubmitter)
+		return 2
+	}
+	var selection []string
+	if *estimators != "" {
+		selection = strings.Split(*estimators, ",")
+		if err := entropy.ValidateEstimators(testType, selection); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+	if *settle <= 0 {
+		fmt.Fprintf(stderr, "Error: -settle must be positive, got %s\n", *settle)
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "Error: watch takes no file arguments, got %s\n", strings.Join(fs.Args(), " "))
+		return 2
+	}
+
+	cfg := watchConfig{
+		dir:       *dir,
+		outputDir: *outputDir,
+		settle:    *settle,
+		opts: &cliOptions{
+			testType:      testType,
+			bits:          *bits,
+			verbose:       *verbose,
+			encoding:      encodingBinary,
+			hSubmitter:    *hSubmitter,
+			hSubmitterSet: hSubmitterSet,
+			estimators:    selection,
+			permRounds:    entropy.PermutationRounds,
+			toFile:        true,
+			format:        formatJSON,
+		},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// A second signal terminates at once.
+		<-ctx.Done()
+		stop()
+	}()
+	return cfg.watch(ctx, stdout, stderr)
+}
+
+// watch processes the files of the drop directory until ctx is done, then
+// waits for the running assessment and returns the exit code of runWatch.
+// The watch starts before the directory is scanned, so that files dropped
+// during the startup are seen by one or the other; the pending set absorbs
+// the duplicates.
+func (c *watchConfig) watch(ctx context.Context, stdout, stderr io.Writer) int {
+	for _, d := range []string{filepath.Join(c.dir, watchDone), filepath.Join(c.dir, watchFailed), c.outputDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer watcher.Close()
+	if err := watcher.Add(c.dir); err != nil {
+		fmt.Fprintf(stderr, "Error watching %s: %v\n", c.dir, err)
+		return 1
+	}
+
+	pending := make(map[string]*pendingFile)
+	queued := make(map[string]bool) // waiting for the worker or being assessed
+	var ready []string
+	consider := func(name string) {
+		base := filepath.Base(name)
+		if strings.HasPrefix(base, ".") || queued[base] {
+			return
+		}
+		if p, ok := pending[base]; ok {
+			p.since = time.Now()
+			return
+		}
+		pending[base] = &pendingFile{since: time.Now()}
+	}
+	scan := func() {
+		entries, err := os.ReadDir(c.dir)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading %s: %v\n", c.dir, err)
+			return
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				consider(entry.Name())
+			}
+		}
+	}
+	scan()
+	if c.opts.verbose > 0 {
+		fmt.Fprintf(stdout, "Watching %s for new files; results go to %s\n", c.dir, c.outputDir)
+	}
+
+	// The worker buffers its output, which the loop prints, so that only
+	// the loop writes to stdout and stderr.
+	work := make(chan string)
+	finished := make(chan *fileOutcome)
+	go func() {
+		for name := range work {
+			out := &fileOutcome{}
+			out.code = c.process(name, &out.stdout, &out.stderr)
+			out.json.Filename = name
+			finished <- out
+		}
+		close(finished)
+	}()
+	assessed, failed := 0, 0
+	report := func(out *fileOutcome) {
+		stdout.Write(out.stdout.Bytes())
+		stderr.Write(out.stderr.Bytes())
+		if out.code == 0 {
+			assessed++
+		} else {
+			failed++
+		}
+	}
+
+	tick := time.NewTicker(min(max(c.settle/4, 10*time.Millisecond), time.Second))
+	defer tick.Stop()
+	busy := ""
+loop:
+	for {
+		var next chan<- string
+		if len(ready) > 0 && busy == "" {
+			next = work
+		}
+		select {
+		case <-ctx.Done():
+			break loop
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				break loop
+			}
+			if ev.Has(fsnotify.Create) || ev.Has(fsnotify.Write) {
+				consider(ev.Name)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				break loop
+			}
+			fmt.Fprintf(stderr, "Warning: watching %s: %v; rescanning\n", c.dir, err)
+			scan()
+		case now := <-tick.C:
+			for base, p := range pending {
+				info, err := os.Stat(filepath.Join(c.dir, base))
+				if err != nil || !info.Mode().IsRegular() {
+					delete(pending, base)
+					continue
+				}
+				if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+					p.size, p.modTime, p.since = info.Size(), info.ModTime(), now
+					continue
+				}
+				if now.Sub(p.since) >= c.settle {
+					delete(pending, base)
+					queued[base] = true
+					ready = append(ready, base)
+				}
+			}
+		case next <- readyHead(ready):
+			busy, ready = ready[0], ready[1:]
+		case out := <-finished:
+			report(out)
+			delete(queued, out.json.Filename)
+			busy = ""
+		}
+	}
+
+	close(work)
+	if busy != "" && c.opts.verbose > 0 {
+		fmt.Fprintf(stderr, "Interrupted: finishing the assessment of %s\n", busy)
+	}
+	for out := range finished {
+		report(out)
+	}
+	if c.opts.verbose > 0 {
+		fmt.Fprintf(stdout, "Stopped watching %s: %d files assessed, %d failed, %d waiting\n", c.dir, assessed, failed, len(pending)+len(ready))
+	}
+	return 0
+}
+
+// readyHead returns the first file of ready, or "" when there is none.
+func readyHead(ready []string) string {
+	if len(ready) == 0 {
+		return ""
+	}
+	return ready[0]
+}
+
+// process assesses the file base of the drop directory, writes its result
+// to the output directory and moves it into the done or failed subdirectory.
+// It returns 0 when the assessment succeeded and 1 otherwise.
+func (c *watchConfig) process(base string, stdout, stderr io.Writer) int {
+	path := filepath.Join(c.dir, base)
+	raw, err := entropy.ReadFile(path)
+	var out JSONOutput
+	code := 1
+	if err != nil {
+		out = c.opts.failedOutput(batchInput{path: base}, err)
+	} else {
+		out, code = c.opts.assess(base, raw, io.Discard, stderr)
+	}
+
+	subdir := watchDone
+	if code != 0 {
+		subdir = watchFailed
+	}
+	target, err := moveUnique(path, filepath.Join(c.dir, subdir), base)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error moving %s: %v\n", path, err)
+		return 1
+	}
+	result := filepath.Join(c.outputDir, filepath.Base(target)+".json")
+	encoded, err := json.MarshalIndent(out, "", "  ")
+	if err == nil {
+		err = os.WriteFile(result, append(encoded, '\n'), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error writing the result of %s: %v\n", base, err)
+		return 1
+	}
+
+	if code != 0 {
+		fmt.Fprintf(stderr, "%s: FAIL: %s; moved to %s\n", base, out.ErrorMessage, target)
+		return 1
+	}
+	if c.opts.verbose > 0 {
+		fmt.Fprintf(stdout, "%s: min-entropy %.6f, h_final %.6f; result %s\n", base, out.MinEntropy, out.HFinal, result)
+	}
+	return 0
+}
+
+// moveUnique moves path into dir under name, or under name with a numeric
+// suffix when dir already holds a file of that name, and returns the new
+// path.
+func moveUnique(path, dir, name string) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 0; ; i++ {
+		target := filepath.Join(dir, name)
+		if i > 0 {
+			target = filepath.Join(dir, stem+"."+strconv.Itoa(i)+ext)
+		}
+		if _, err := os.Lstat(target); err == nil {
+			continue
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		return target, os.Rename(path, target)
+	}
+}
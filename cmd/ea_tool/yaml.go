@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// yamlDocument converts v into a YAML node with the keys, key order and
+// values of its JSON encoding, so that -format yaml follows the JSON schema
+// without a second set of struct tags. JSON is valid YAML; only the flow
+// style and the quoting of the parsed nodes are reset to the block layout.
+func yamlDocument(v any) (*yaml.Node, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	blockStyle(&doc)
+	return &doc, nil
+}
+
+// blockStyle clears the style of n and its children, leaving the encoder to
+// quote only the strings that need it.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
+
+// encodeYAML writes one YAML document per element of docs to w, separated
+// by "---" lines.
+func encodeYAML(w io.Writer, docs []any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		node, err := yamlDocument(doc)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(node); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// writeYAML writes the documents to filename, or to stdout when filename is
+// empty or "-". On failure, an error message is printed to stderr and the process
+// exits.
+func writeYAML(filename string, stdout io.Writer, docs []any) {
+	if filename == "" || filename == stdoutName {
+		if err := encodeYAML(stdout, docs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing YAML: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	if err := encodeYAML(file, docs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing YAML: %v\n", err)
+		os.Exit(1)
+	}
+}
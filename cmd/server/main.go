@@ -12,8 +12,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"runtime"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -21,29 +24,46 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip" // registers the gzip compressor for clients that request it
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/AmmannChristian/nist-800-90b/internal/buildinfo"
 	"github.com/AmmannChristian/nist-800-90b/internal/config"
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/metrics"
 	"github.com/AmmannChristian/nist-800-90b/internal/middleware"
+	"github.com/AmmannChristian/nist-800-90b/internal/schema"
 	"github.com/AmmannChristian/nist-800-90b/internal/service"
 	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
 )
 
-const (
-	version = "1.0.0"
-)
-
 // server holds references to the loaded configuration and the HTTP multiplexer
-// used for health and metrics endpoints.
+// used for health and metrics endpoints. ready is set by run once every
+// listener is bound and cleared again when shutdown begins. assessor serves
+// the assessments of /v1/assess/stream, which are registered with svc like
+// the gRPC calls.
 type server struct {
-	config *config.Config
-	mux    *http.ServeMux
+	config   *config.Config
+	mux      *http.ServeMux
+	ready    atomic.Bool
+	assessor *service.GRPCServer
+	svc      *service.EntropyService
 }
 
 func main() {
+	// Subprocess isolation re-executes this binary as a single-shot worker.
+	if entropy.IsChildInvocation(os.Args[1:]) {
+		os.Exit(entropy.RunChild())
+	}
+
 	if err := run(); err != nil {
 		log.Fatal().Err(err).Msg("server error")
 	}
@@ -51,7 +71,8 @@ func main() {
 
 // run initializes the server, starts gRPC and HTTP listeners, and blocks until
 // a termination signal is received or a fatal error occurs. It performs a
-// graceful shutdown with a 30-second deadline.
+// graceful shutdown with a 30-second deadline, waiting for in-flight
+// assessments within it.
 func run() error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -60,20 +81,68 @@ func run() error {
 
 	setupLogging(cfg.LogLevel)
 
+	// The cap must be set before the first assessment, the self-test
+	// included. It bounds the CPU usage of the service at the number of
+	// concurrent assessments times this value.
+	assessmentThreads := runtime.NumCPU()
+	if cfg.AssessmentThreads > 0 {
+		if err := entropy.SetMaxThreads(cfg.AssessmentThreads); err != nil {
+			return fmt.Errorf("failed to configure assessment threads: %w", err)
+		}
+		assessmentThreads = cfg.AssessmentThreads
+	}
+
+	info := buildinfo.Get()
+	metrics.RegisterBuildInfo(info.Version, info.Commit, info.BuildDate, info.CGO)
+
 	log.Info().
-		Str("version", version).
+		Str("version", info.Version).
+		Str("commit", info.Commit).
+		Str("build_date", info.BuildDate).
 		Int("metrics_port", cfg.ServerPort).
 		Int("grpc_port", cfg.GRPCPort).
 		Int("grpc_max_recv_message_size", cfg.GRPCMaxRecvMessageSize).
 		Int("grpc_max_send_message_size", cfg.GRPCMaxSendMessageSize).
 		Bool("grpc_enabled", cfg.GRPCEnabled).
 		Bool("auth_enabled", cfg.AuthEnabled).
+		Bool("tracing_enabled", cfg.TracingEnabled).
 		Int64("max_upload_bytes", cfg.MaxUploadSize).
+		Int64("max_assess_memory", cfg.MaxAssessMemory).
+		Int("assessment_threads", assessmentThreads).
+		Str("assess_isolation", cfg.AssessIsolation.String()).
+		Dur("assess_timeout", cfg.Timeout).
 		Msg("starting SP800-90B entropy assessment server")
 
+	if cfg.SelfTestOnStart {
+		if err := entropy.SelfTest(); err != nil {
+			return fmt.Errorf("startup self-test failed: %w", err)
+		}
+		log.Info().Msg("known-answer self-test passed")
+	}
+
+	shutdownTracing, err := setupTracing(context.Background(), cfg)
+	if err != nil {
+		return fmt.Errorf("failed to configure tracing: %w", err)
+	}
+
+	// The gRPC API and /v1/assess/stream share the service and its limits.
+	svc := service.NewService()
+	svc.SetIsolation(cfg.AssessIsolation)
+	svc.SetTimeout(cfg.Timeout)
+	svc.SetMaxMemory(uint64(cfg.MaxAssessMemory))
+	if cfg.AllowSmallSamples {
+		svc.SetMinSamples(0)
+		log.Warn().Msg("minimum sample count check disabled; small datasets reach the NIST library")
+	}
+	assessmentServer := service.NewGRPCServer(svc)
+	assessmentServer.SetFileBaseDir(cfg.AssessFileBaseDir)
+	assessmentServer.SetMaxUploadSize(cfg.MaxUploadSize)
+
 	srv := &server{
-		config: cfg,
-		mux:    http.NewServeMux(),
+		config:   cfg,
+		mux:      http.NewServeMux(),
+		assessor: assessmentServer,
+		svc:      svc,
 	}
 
 	serverErrors := make(chan error, 2)
@@ -90,6 +159,9 @@ func run() error {
 		if err != nil {
 			return fmt.Errorf("failed to configure gRPC server: %w", err)
 		}
+		// Innermost, so that only requests that passed authentication are
+		// waited for at shutdown.
+		unaryInterceptors = append(unaryInterceptors, service.UnaryInFlightInterceptor(svc))
 
 		serverOpts, err := buildGRPCServerOptions(cfg, unaryInterceptors)
 		if err != nil {
@@ -97,8 +169,7 @@ func run() error {
 		}
 
 		grpcServer = grpc.NewServer(serverOpts...)
-
-		pb.RegisterSp80090BAssessmentServiceServer(grpcServer, service.NewGRPCServer(service.NewService()))
+		pb.RegisterSp80090BAssessmentServiceServer(grpcServer, assessmentServer)
 		healthServer := health.NewServer()
 		healthpb.RegisterHealthServer(grpcServer, healthServer)
 		healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
@@ -117,32 +188,68 @@ func run() error {
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
 	var httpServer *http.Server
+	var httpListener net.Listener
+	if cfg.PprofEnabled && !cfg.MetricsEnabled {
+		log.Warn().Msg("PPROF_ENABLED has no effect while METRICS_ENABLED is false")
+	}
 	if cfg.MetricsEnabled {
 		srv.registerRoutes()
+		if cfg.AuthEnabled {
+			log.Info().Msg("/v1/assess/stream disabled: the HTTP endpoints are not authenticated while AUTH_ENABLED is set")
+		}
+		if cfg.PprofEnabled {
+			log.Warn().Int("metrics_port", cfg.ServerPort).Msg("pprof endpoints enabled under /debug/pprof/")
+		}
 		httpServer = &http.Server{
 			Addr:         fmt.Sprintf("%s:%d", cfg.ServerHost, cfg.ServerPort),
 			Handler:      srv.mux,
 			ReadTimeout:  cfg.Timeout,
 			WriteTimeout: cfg.Timeout,
 		}
+		httpListener, err = net.Listen("tcp", httpServer.Addr)
+		if err != nil {
+			if grpcServer != nil {
+				grpcServer.Stop()
+			}
+			return fmt.Errorf("failed to create HTTP listener: %w", err)
+		}
 
 		go func() {
-			log.Info().Str("addr", httpServer.Addr).Msg("HTTP metrics server listening")
-			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Info().Str("addr", httpListener.Addr().String()).Msg("HTTP metrics server listening")
+			if err := httpServer.Serve(httpListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 				serverErrors <- err
 			}
 		}()
 	}
 
+	srv.ready.Store(true)
+
 	select {
 	case err := <-serverErrors:
+		srv.ready.Store(false)
 		return fmt.Errorf("server error: %w", err)
 	case sig := <-shutdown:
+		srv.ready.Store(false)
 		log.Info().Str("signal", sig.String()).Msg("shutdown requested")
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		// gRPC drains first so that /readyz keeps reporting 503 while
+		// in-flight assessments finish, those of /v1/assess/stream included.
+		if grpcServer != nil {
+			drainGRPC(ctx, grpcServer, svc)
+			if grpcListener != nil {
+				_ = grpcListener.Close()
+			}
+		} else {
+			drainAssessments(ctx, svc)
+		}
+
+		if err := shutdownTracing(ctx); err != nil {
+			log.Warn().Err(err).Msg("failed to flush pending spans")
+		}
+
 		if httpServer != nil {
 			if err := httpServer.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
 				httpServer.Close()
@@ -150,28 +257,92 @@ func run() error {
 			}
 		}
 
-		if grpcServer != nil {
-			grpcServer.GracefulStop()
-			if grpcListener != nil {
-				_ = grpcListener.Close()
-			}
-		}
-
 		log.Info().Msg("server stopped gracefully")
 	}
 
 	return nil
 }
 
+// drainGRPC stops grpcServer gracefully and waits for the assessments
+// registered with svc, both bounded by ctx. When ctx expires first, the
+// remaining calls are cancelled and the assessments still running are
+// logged as abandoned, as are in-process computations that outlived their
+// request.
+func drainGRPC(ctx context.Context, grpcServer *grpc.Server, svc *service.EntropyService) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Warn().Msg("graceful gRPC stop timed out; cancelling remaining calls")
+		grpcServer.Stop()
+	}
+	drainAssessments(ctx, svc)
+}
+
+// drainAssessments waits for the assessments registered with svc until ctx
+// is done, and logs those still running as abandoned, as well as in-process
+// computations that outlived their request.
+func drainAssessments(ctx context.Context, svc *service.EntropyService) {
+	if n := svc.Drain(ctx); n > 0 {
+		log.Warn().Int("assessments", n).Msg("shutdown deadline elapsed; abandoning in-flight assessments")
+	}
+	if n := entropy.AbandonedAssessments(); n > 0 {
+		log.Warn().Int64("computations", n).Msg("abandoned in-process computations still running at shutdown")
+	}
+}
+
+// setupTracing installs an OpenTelemetry tracer provider that exports spans
+// in batches to the configured OTLP/gRPC collector, and returns the function
+// that flushes and stops it. When tracing is disabled the global provider
+// stays the default no-op one and the returned function does nothing.
+func setupTracing(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	if !cfg.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.TracingOTLPEndpoint)}
+	if cfg.TracingOTLPInsecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	// The exporter connects lazily, so an unreachable collector does not
+	// prevent the server from starting.
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "nist-sp800-90b"),
+			attribute.String("service.version", buildinfo.Get().Version),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+	log.Info().Str("endpoint", cfg.TracingOTLPEndpoint).Msg("exporting OpenTelemetry spans")
+	return tp.Shutdown, nil
+}
+
 // buildUnaryInterceptors assembles the chain of gRPC unary interceptors. It
-// always includes request ID injection and structured logging. When authentication
-// is enabled, an OIDC token validator is appended with health-check exemptions.
-// Validation supports JWT (JWKS) and opaque tokens (introspection).
+// always includes request ID injection, request metrics, and structured
+// logging; with tracing enabled, a span per call is started right after the
+// request ID is assigned. When authentication is enabled, an OIDC token validator is appended
+// with health-check exemptions. Validation supports JWT (JWKS) and opaque
+// tokens (introspection).
 func buildUnaryInterceptors(cfg *config.Config) ([]grpc.UnaryServerInterceptor, error) {
-	interceptors := []grpc.UnaryServerInterceptor{
-		middleware.UnaryRequestIDInterceptor(),
-		loggingInterceptor,
+	interceptors := []grpc.UnaryServerInterceptor{middleware.UnaryRequestIDInterceptor()}
+	if cfg.TracingEnabled {
+		interceptors = append(interceptors, middleware.UnaryTracingInterceptor())
 	}
+	interceptors = append(interceptors,
+		middleware.UnaryMetricsInterceptor(),
+		loggingInterceptor,
+	)
 
 	if !cfg.AuthEnabled {
 		return interceptors, nil
@@ -268,19 +439,10 @@ func authorizationEnabled(cfg *config.Config) bool {
 // configuration. When TLS is enabled, it loads certificates and configures
 // client authentication and minimum protocol version.
 func buildGRPCServerOptions(cfg *config.Config, unaryInterceptors []grpc.UnaryServerInterceptor) ([]grpc.ServerOption, error) {
-	maxRecvMessageSize := cfg.GRPCMaxRecvMessageSize
-	if maxRecvMessageSize <= 0 {
-		maxRecvMessageSize = 10 * 1024 * 1024
-	}
-	maxSendMessageSize := cfg.GRPCMaxSendMessageSize
-	if maxSendMessageSize <= 0 {
-		maxSendMessageSize = 10 * 1024 * 1024
-	}
-
 	opts := []grpc.ServerOption{
 		grpc.ChainUnaryInterceptor(unaryInterceptors...),
-		grpc.MaxRecvMsgSize(maxRecvMessageSize),
-		grpc.MaxSendMsgSize(maxSendMessageSize),
+		grpc.MaxRecvMsgSize(cfg.GRPCMaxRecvMessageSizeValue()),
+		grpc.MaxSendMsgSize(cfg.GRPCMaxSendMessageSizeValue()),
 	}
 
 	if !cfg.TLSEnabled {
@@ -334,22 +496,70 @@ func tlsVersionString(version uint16) string {
 	}
 }
 
-// registerRoutes configures HTTP handlers for the /health and /metrics endpoints.
+// registerRoutes configures HTTP handlers for the /livez, /readyz and /metrics
+// endpoints, /health as an alias of /livez, the result schema under
+// /v1/assess/schema, the streaming assessment under /v1/assess/stream unless
+// AUTH_ENABLED is set, as the HTTP endpoints are not authenticated, and the
+// pprof endpoints under /debug/pprof/ when PPROF_ENABLED is set.
 func (s *server) registerRoutes() {
-	s.mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-		health := map[string]interface{}{
-			"status":  "healthy",
-			"version": version,
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(health)
-	})
+	s.mux.HandleFunc("/livez", s.handleLive)
+	s.mux.HandleFunc("/health", s.handleLive)
+	s.mux.HandleFunc("/readyz", s.handleReady)
+	s.mux.HandleFunc("/v1/assess/schema", s.handleSchema)
+	if s.assessor != nil && !s.config.AuthEnabled {
+		s.mux.HandleFunc("/v1/assess/stream", s.handleAssessStream)
+	}
 
 	s.mux.Handle("/metrics", promhttp.Handler())
+
+	if s.config.PprofEnabled {
+		s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+		s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+}
+
+// handleLive reports that the process is up.
+func (s *server) handleLive(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, http.StatusOK, "healthy")
+}
+
+// handleReady reports whether the server accepts assessments: 200 once every
+// listener is bound and 503 before that and during shutdown.
+func (s *server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.ready.Load() {
+		writeHealth(w, r, http.StatusOK, "ready")
+	} else {
+		writeHealth(w, r, http.StatusServiceUnavailable, "not ready")
+	}
+}
+
+// handleSchema serves the JSON Schema of the assessment result documents.
+func (s *server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", schema.ContentType)
+	w.Write(schema.AssessOutput())
+}
+
+// writeHealth writes the JSON body shared by the health endpoints.
+func writeHealth(w http.ResponseWriter, r *http.Request, code int, status string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	health := map[string]interface{}{
+		"status":  status,
+		"version": buildinfo.Version,
+		"library": entropy.LibraryInfo(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(health)
 }
 
 // setupLogging configures zerolog for structured output.
@@ -0,0 +1,337 @@
+//go:build teststub
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/config"
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/metrics"
+	"github.com/AmmannChristian/nist-800-90b/internal/service"
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+)
+
+// The stub library cannot reproduce the known answers, so startup must abort.
+func TestRunFailsSelfTestOnStart(t *testing.T) {
+	os.Setenv("SELF_TEST_ON_START", "true")
+	t.Cleanup(func() {
+		os.Unsetenv("SELF_TEST_ON_START")
+	})
+
+	err := run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "startup self-test failed")
+	assert.True(t, errors.Is(err, entropy.ErrSelfTestFailed))
+}
+
+// startGRPCServer runs the server with gRPC enabled on a free port and
+// returns a client connection to it. The server is stopped on cleanup.
+func startGRPCServer(t *testing.T, dialOpts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+
+	grpcLn := mustListen(t)
+	grpcPort := grpcLn.Addr().(*net.TCPAddr).Port
+	grpcLn.Close()
+
+	os.Setenv("GRPC_PORT", fmt.Sprintf("%d", grpcPort))
+	os.Setenv("GRPC_ENABLED", "true")
+	os.Setenv("METRICS_ENABLED", "false")
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- run()
+	}()
+
+	t.Cleanup(func() {
+		p, err := os.FindProcess(os.Getpid())
+		require.NoError(t, err)
+		require.NoError(t, p.Signal(syscall.SIGTERM))
+
+		select {
+		case err := <-errCh:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("run did not return in time")
+		}
+
+		os.Unsetenv("GRPC_PORT")
+		os.Unsetenv("GRPC_ENABLED")
+		os.Unsetenv("METRICS_ENABLED")
+	})
+
+	waitForPort(t, grpcPort)
+
+	dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(fmt.Sprintf("127.0.0.1:%d", grpcPort), dialOpts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRunServesGzipCompressedResponses(t *testing.T) {
+	conn := startGRPCServer(t, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := pb.NewSp80090BAssessmentServiceClient(conn).AssessEntropy(ctx, &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4, 5},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), resp.SampleCount)
+	assert.InDelta(t, 6.5, resp.MinEntropy, 1e-9)
+	assert.Len(t, resp.NonIidResults, 10)
+}
+
+// A request just over gRPC's stock 4 MB receive limit must be accepted, since
+// the default limit follows MAX_UPLOAD_SIZE.
+func TestRunAcceptsRequestsAboveGRPCDefaultLimit(t *testing.T) {
+	conn := startGRPCServer(t)
+
+	data := bytes.Repeat([]byte{1, 2, 3, 4}, (4*1024*1024+4096)/4)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := pb.NewSp80090BAssessmentServiceClient(conn).AssessEntropy(ctx, &pb.Sp80090BAssessmentRequest{
+		Data:          data,
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(data)), resp.SampleCount)
+}
+
+// startSlowAssessmentServer serves the assessment service with every call
+// delayed by delay after it has been registered as in flight, and returns
+// the server, its EntropyService and a client. A call cancelled during the
+// delay, as by stopping the server, fails without reaching the handler, and
+// the test waits for every call to return, so that no handler outlives it.
+func startSlowAssessmentServer(t *testing.T, delay time.Duration) (*grpc.Server, *service.EntropyService, pb.Sp80090BAssessmentServiceClient) {
+	t.Helper()
+
+	svc := service.NewService()
+	var calls sync.WaitGroup
+	t.Cleanup(calls.Wait)
+	slow := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		calls.Add(1)
+		defer calls.Done()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return handler(ctx, req)
+	}
+	grpcServer, dialer := service.NewInProcessServer(svc, grpc.ChainUnaryInterceptor(slow))
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return grpcServer, svc, pb.NewSp80090BAssessmentServiceClient(conn)
+}
+
+// assessAsync starts a stub Non-IID assessment and waits until the server
+// has registered it.
+func assessAsync(t *testing.T, svc *service.EntropyService, client pb.Sp80090BAssessmentServiceClient) chan error {
+	t.Helper()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := client.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+			Data:          []byte{1, 2, 3, 4},
+			BitsPerSymbol: 8,
+			NonIidMode:    true,
+		})
+		errCh <- err
+	}()
+	require.Eventually(t, func() bool { return svc.InFlight() == 1 }, 5*time.Second, 5*time.Millisecond)
+	return errCh
+}
+
+func TestDrainGRPC_WaitsForSlowAssessment(t *testing.T) {
+	grpcServer, svc, client := startSlowAssessmentServer(t, 300*time.Millisecond)
+	errCh := assessAsync(t, svc, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	drainGRPC(ctx, grpcServer, svc)
+
+	assert.Equal(t, 0, svc.InFlight(), "shutdown returned before the assessment ended")
+	require.NoError(t, <-errCh)
+}
+
+func TestDrainGRPC_DeadlineAbandonsAssessment(t *testing.T) {
+	grpcServer, svc, client := startSlowAssessmentServer(t, 2*time.Second)
+	errCh := assessAsync(t, svc, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	drainGRPC(ctx, grpcServer, svc)
+
+	assert.Less(t, time.Since(start), time.Second, "shutdown is bounded by its deadline")
+	assert.Error(t, <-errCh, "the stopped server cancels the call")
+}
+
+func TestAssessStream_ReportsProgressAndResult(t *testing.T) {
+	svc := service.NewService()
+	svc.SetMinSamples(0)
+	srv := newStreamServer(&config.Config{MaxUploadSize: 1 << 20}, svc)
+	srv.registerRoutes()
+	ts := httptest.NewServer(srv.mux)
+	t.Cleanup(ts.Close)
+
+	resp, err := http.Post(ts.URL+"/v1/assess/stream?bits_per_symbol=8", "application/octet-stream", bytes.NewReader([]byte{1, 2, 3, 4}))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	type event struct{ name, data string }
+	var events []event
+	scanner := bufio.NewScanner(resp.Body)
+	var current event
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, current)
+			current = event{}
+		}
+	}
+	require.NoError(t, scanner.Err())
+	require.NotEmpty(t, events)
+
+	for _, e := range events[:len(events)-1] {
+		assert.Equal(t, "progress", e.name)
+		var p streamProgress
+		require.NoError(t, json.Unmarshal([]byte(e.data), &p))
+		assert.NotEmpty(t, p.Phase)
+	}
+	assert.Greater(t, len(events), 1, "the stub library reports progress")
+
+	last := events[len(events)-1]
+	require.Equal(t, "result", last.name, last.data)
+	var result struct {
+		MinEntropy  float64 `json:"min_entropy"`
+		SampleCount string  `json:"sample_count"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(last.data), &result))
+	assert.Equal(t, 6.5, result.MinEntropy)
+	assert.Equal(t, "4", result.SampleCount)
+}
+
+func TestAssessStream_ClientDisconnectCancelsAssessment(t *testing.T) {
+	svc := service.NewService()
+	svc.SetMinSamples(0)
+	srv := newStreamServer(&config.Config{MaxUploadSize: 1 << 20}, svc)
+
+	// A client that is already gone cancels the assessment before it starts.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/v1/assess/stream?bits_per_symbol=8", bytes.NewReader([]byte{1, 2, 3, 4})).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handled := make(chan struct{})
+	go func() {
+		srv.handleAssessStream(w, req)
+		close(handled)
+	}()
+
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler did not return after the client disconnected")
+	}
+	assert.NotContains(t, w.Body.String(), "event: result")
+	assert.Equal(t, 0, svc.InFlight())
+}
+
+// A stream runs as an AssessEntropy call: it is registered as in flight,
+// logged with the request ID of its X-Request-ID header and counted in the
+// request metrics.
+func TestAssessStream_RunsAsAssessEntropyCall(t *testing.T) {
+	svc := service.NewService()
+	svc.SetMinSamples(0)
+	srv := newStreamServer(&config.Config{MaxUploadSize: 1 << 20}, svc)
+	srv.registerRoutes()
+	ts := httptest.NewServer(srv.mux)
+	t.Cleanup(ts.Close)
+
+	var mu sync.Mutex
+	var logs bytes.Buffer
+	inFlight := -1
+	origLogger := log.Logger
+	log.Logger = zerolog.New(lockedWriter{&mu, &logs}).Hook(zerolog.HookFunc(func(_ *zerolog.Event, _ zerolog.Level, msg string) {
+		if msg == "AssessEntropy request received" {
+			mu.Lock()
+			inFlight = svc.InFlight()
+			mu.Unlock()
+		}
+	}))
+	t.Cleanup(func() { log.Logger = origLogger })
+
+	requests := metrics.GRPCRequestsTotal.WithLabelValues(pb.Sp80090BAssessmentService_AssessEntropy_FullMethodName, "OK")
+	before := testutil.ToFloat64(requests)
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/assess/stream?bits_per_symbol=8", bytes.NewReader([]byte{1, 2, 3, 4}))
+	require.NoError(t, err)
+	req.Header.Set("X-Request-ID", "stream-42")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(body), "event: result")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, inFlight, "the assessment is registered while it runs")
+	assert.Contains(t, logs.String(), `"request_id":"stream-42"`)
+	assert.Equal(t, before+1, testutil.ToFloat64(requests))
+	assert.Equal(t, 0, svc.InFlight())
+}
+
+// lockedWriter serializes writes to w with mu.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
@@ -19,9 +20,14 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"google.golang.org/grpc"
 
 	"github.com/AmmannChristian/nist-800-90b/internal/config"
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/schema"
+	"github.com/AmmannChristian/nist-800-90b/internal/service"
 )
 
 func TestSetupLogging(t *testing.T) {
@@ -64,17 +70,173 @@ func TestRegisterRoutesHealthAndMetrics(t *testing.T) {
 	srv.mux.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
 
+	var health struct {
+		Status  string               `json:"status"`
+		Library entropy.Capabilities `json:"library"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&health))
+	assert.Equal(t, "healthy", health.Status)
+	assert.Equal(t, entropy.LibraryInfo(), health.Library)
+
 	// Health wrong method
 	req = httptest.NewRequest(http.MethodPost, "/health", nil)
 	w = httptest.NewRecorder()
 	srv.mux.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
 
+	// Liveness is reported under /livez too
+	req = httptest.NewRequest(http.MethodGet, "/livez", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
 	// Metrics endpoint should exist
 	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
 	w = httptest.NewRecorder()
 	srv.mux.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Profiling is off by default
+	req = httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRegisterRoutesReadiness(t *testing.T) {
+	srv := &server{
+		config: &config.Config{MetricsEnabled: true},
+		mux:    http.NewServeMux(),
+	}
+	srv.registerRoutes()
+
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body struct {
+			Status string `json:"status"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+		return w.Code, body.Status
+	}
+
+	// Not ready before run has bound the listeners, but alive.
+	code, status := get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not ready", status)
+	code, _ = get("/livez")
+	assert.Equal(t, http.StatusOK, code)
+
+	srv.ready.Store(true)
+	code, status = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", status)
+
+	// Shutting down
+	srv.ready.Store(false)
+	code, _ = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	code, status = get("/health")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "healthy", status)
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/readyz", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestRegisterRoutesSchema(t *testing.T) {
+	srv := &server{config: &config.Config{}, mux: http.NewServeMux()}
+	srv.registerRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/assess/schema", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/schema+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, schema.AssessOutput(), w.Body.Bytes())
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/assess/schema", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+// newStreamServer returns a server that serves /v1/assess/stream with svc
+// and is ready, as run leaves it once the listeners are bound.
+func newStreamServer(cfg *config.Config, svc *service.EntropyService) *server {
+	srv := &server{
+		config:   cfg,
+		mux:      http.NewServeMux(),
+		assessor: service.NewGRPCServer(svc),
+		svc:      svc,
+	}
+	srv.ready.Store(true)
+	return srv
+}
+
+func TestRegisterRoutesAssessStream(t *testing.T) {
+	srv := newStreamServer(&config.Config{MaxUploadSize: 16}, service.NewService())
+	srv.registerRoutes()
+
+	post := func(target string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body)))
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/assess/stream", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	for _, target := range []string{
+		"/v1/assess/stream?bits_per_symbol=eight",
+		"/v1/assess/stream?iid_mode=maybe",
+		"/v1/assess/stream?h_submitter=high",
+	} {
+		assert.Equal(t, http.StatusBadRequest, post(target, []byte{1, 2, 3}).Code, target)
+	}
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/v1/assess/stream", make([]byte, 17)).Code)
+
+	// Request validation fails after the stream has started.
+	w = post("/v1/assess/stream?bits_per_symbol=8", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "event: error\ndata: {\"code\":\"InvalidArgument\"")
+
+	// Once shutdown has begun, no new stream starts.
+	srv.ready.Store(false)
+	assert.Equal(t, http.StatusServiceUnavailable, post("/v1/assess/stream?bits_per_symbol=8", []byte{1, 2, 3}).Code)
+
+	// The HTTP port is not authenticated, so the endpoint is not served
+	// while gRPC authentication is on.
+	srv = newStreamServer(&config.Config{AuthEnabled: true}, service.NewService())
+	srv.registerRoutes()
+	w = post("/v1/assess/stream", []byte{1, 2, 3})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRegisterRoutesPprof(t *testing.T) {
+	srv := &server{
+		config: &config.Config{
+			MetricsEnabled: true,
+			PprofEnabled:   true,
+		},
+		mux: http.NewServeMux(),
+	}
+
+	srv.registerRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine")
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/pprof/heap?debug=1", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
 }
 
 func TestLoggingInterceptor(t *testing.T) {
@@ -103,7 +265,33 @@ func TestBuildUnaryInterceptors_WithoutAuth(t *testing.T) {
 
 	interceptors, err := buildUnaryInterceptors(cfg)
 	require.NoError(t, err)
-	assert.Len(t, interceptors, 2)
+	assert.Len(t, interceptors, 3)
+}
+
+func TestBuildUnaryInterceptors_WithTracing(t *testing.T) {
+	cfg := &config.Config{TracingEnabled: true, TracingOTLPEndpoint: "127.0.0.1:4317"}
+
+	interceptors, err := buildUnaryInterceptors(cfg)
+	require.NoError(t, err)
+	assert.Len(t, interceptors, 4)
+}
+
+func TestSetupTracing(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown, err := setupTracing(context.Background(), &config.Config{})
+	require.NoError(t, err)
+	assert.Same(t, previous, otel.GetTracerProvider(), "disabled tracing keeps the no-op provider")
+	require.NoError(t, shutdown(context.Background()))
+
+	cfg := &config.Config{TracingEnabled: true, TracingOTLPEndpoint: "127.0.0.1:4317", TracingOTLPInsecure: true}
+	shutdown, err = setupTracing(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &sdktrace.TracerProvider{}, otel.GetTracerProvider())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, shutdown(ctx))
 }
 
 func TestBuildUnaryInterceptors_WithOpaqueAuth(t *testing.T) {
@@ -119,7 +307,7 @@ func TestBuildUnaryInterceptors_WithOpaqueAuth(t *testing.T) {
 
 	interceptors, err := buildUnaryInterceptors(cfg)
 	require.NoError(t, err)
-	assert.Len(t, interceptors, 3)
+	assert.Len(t, interceptors, 4)
 }
 
 func TestBuildUnaryInterceptors_WithOpaqueAuthPrivateKeyJWTPEM(t *testing.T) {
@@ -138,7 +326,7 @@ func TestBuildUnaryInterceptors_WithOpaqueAuthPrivateKeyJWTPEM(t *testing.T) {
 
 	interceptors, err := buildUnaryInterceptors(cfg)
 	require.NoError(t, err)
-	assert.Len(t, interceptors, 3)
+	assert.Len(t, interceptors, 4)
 }
 
 func TestBuildUnaryInterceptors_WithOpaqueAuthPrivateKeyJWTZitadelJSON(t *testing.T) {
@@ -163,7 +351,7 @@ func TestBuildUnaryInterceptors_WithOpaqueAuthPrivateKeyJWTZitadelJSON(t *testin
 
 	interceptors, err := buildUnaryInterceptors(cfg)
 	require.NoError(t, err)
-	assert.Len(t, interceptors, 3)
+	assert.Len(t, interceptors, 4)
 }
 
 func TestBuildAuthorizationPolicy(t *testing.T) {
@@ -217,8 +405,12 @@ func TestRunStartsAndStopsWithSignal(t *testing.T) {
 		errCh <- run()
 	}()
 
-	// allow startup
-	time.Sleep(200 * time.Millisecond)
+	waitForPort(t, port)
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/readyz", port))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
 	// Send SIGTERM to self
 	p, err := os.FindProcess(os.Getpid())
@@ -258,7 +450,7 @@ func TestRunStartsGRPCAndStops(t *testing.T) {
 		errCh <- run()
 	}()
 
-	time.Sleep(200 * time.Millisecond)
+	waitForPort(t, grpcPort)
 
 	p, err := os.FindProcess(os.Getpid())
 	require.NoError(t, err)
@@ -298,3 +490,18 @@ func mustGenerateRSAPrivateKeyPEM(t *testing.T) string {
 
 	return string(privateKeyPEM)
 }
+
+// waitForPort waits until the server started by run accepts connections on
+// port.
+func waitForPort(t *testing.T, port int) {
+	t.Helper()
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	require.Eventually(t, func() bool {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond, "server did not listen on %s", addr)
+}
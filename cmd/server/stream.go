@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/middleware"
+	"github.com/AmmannChristian/nist-800-90b/internal/service"
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+// streamProgressBuffer is the number of progress events that may wait for
+// the client. Reports that find the buffer full are dropped rather than
+// stalling the calculation.
+const streamProgressBuffer = 64
+
+// streamProgress is the data of a progress event.
+type streamProgress struct {
+	Phase   string  `json:"phase"`
+	Percent float64 `json:"percent"`
+}
+
+// streamError is the data of an error event.
+type streamError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// handleAssessStream assesses the request body, the raw samples, and
+// reports the progress as Server-Sent Events: "progress" events with the
+// phase and percentage of the calculation, then either a "result" event
+// with the assessment response as JSON, with the field names of the proto
+// definition, or an "error" event with the gRPC status code and message.
+// The query parameters bits_per_symbol, iid_mode, non_iid_mode, h_submitter
+// and estimators (comma-separated) set the fields of the same name of
+// AssessEntropy; without iid_mode and non_iid_mode the Non-IID track runs.
+// A client that disconnects cancels the assessment, and the handler returns
+// once the assessment has stopped.
+//
+// The assessment passes through the request ID, metrics and in-flight
+// interceptors of the gRPC server as an AssessEntropy call, so it is logged
+// with a request ID, taken from a well-formed X-Request-ID header if there is
+// one, counted in grpc_requests_total and waited for at shutdown. New streams
+// are refused once shutdown has begun.
+func (s *server) handleAssessStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.ready.Load() {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	req, err := streamRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Data, err = io.ReadAll(http.MaxBytesReader(w, r.Body, s.config.MaxUploadSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("upload exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read the samples", http.StatusBadRequest)
+		return
+	}
+
+	// The assessment is bounded by the service timeout, so the write
+	// deadline of the HTTP server must not end the stream first.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-request-id", id))
+	}
+	progress := make(chan streamProgress, streamProgressBuffer)
+	ctx = service.WithProgress(ctx, func(phase string, percent float64) {
+		select {
+		case progress <- streamProgress{Phase: phase, Percent: percent}:
+		default:
+		}
+	})
+
+	type outcome struct {
+		resp *pb.Sp80090BAssessmentResponse
+		err  error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		resp, err := s.assess(ctx, req)
+		done <- outcome{resp, err}
+	}()
+
+	for {
+		select {
+		case p := <-progress:
+			if writeEvent(w, rc, "progress", p) != nil {
+				return
+			}
+		case out := <-done:
+			// Reports sent before the assessment returned come first.
+			for len(progress) > 0 {
+				if writeEvent(w, rc, "progress", <-progress) != nil {
+					return
+				}
+			}
+			if out.err != nil {
+				st := status.Convert(out.err)
+				_ = writeEvent(w, rc, "error", streamError{Code: st.Code().String(), Message: st.Message()})
+				return
+			}
+			data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(out.resp)
+			if err != nil {
+				_ = writeEvent(w, rc, "error", streamError{Code: "Internal", Message: err.Error()})
+				return
+			}
+			_ = writeEvent(w, rc, "result", json.RawMessage(data))
+			return
+		case <-r.Context().Done():
+			log.Info().Msg("assessment stream closed by the client; cancelling the assessment")
+			cancel()
+			<-done
+			return
+		}
+	}
+}
+
+// assess runs AssessEntropy through the interceptors of the gRPC server that
+// apply to every assessment call: request ID, metrics and in-flight
+// registration with svc.
+func (s *server) assess(ctx context.Context, req *pb.Sp80090BAssessmentRequest) (*pb.Sp80090BAssessmentResponse, error) {
+	interceptors := []grpc.UnaryServerInterceptor{
+		middleware.UnaryRequestIDInterceptor(),
+		middleware.UnaryMetricsInterceptor(),
+		service.UnaryInFlightInterceptor(s.svc),
+	}
+	info := &grpc.UnaryServerInfo{Server: s.assessor, FullMethod: pb.Sp80090BAssessmentService_AssessEntropy_FullMethodName}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return s.assessor.AssessEntropy(ctx, req.(*pb.Sp80090BAssessmentRequest))
+	}
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req any) (any, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.Sp80090BAssessmentResponse), nil
+}
+
+// streamRequest builds the AssessEntropy request from the query parameters
+// of r; the samples are added by the caller.
+func streamRequest(r *http.Request) (*pb.Sp80090BAssessmentRequest, error) {
+	query := r.URL.Query()
+	req := &pb.Sp80090BAssessmentRequest{}
+	if v := query.Get("bits_per_symbol"); v != "" {
+		bits, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bits_per_symbol must be an integer between 0 and 8, got %q", v)
+		}
+		req.BitsPerSymbol = uint32(bits)
+	}
+	modes := []struct {
+		name  string
+		field *bool
+	}{{"iid_mode", &req.IidMode}, {"non_iid_mode", &req.NonIidMode}}
+	for _, mode := range modes {
+		if v := query.Get(mode.name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be true or false, got %q", mode.name, v)
+			}
+			*mode.field = b
+		}
+	}
+	if !query.Has("iid_mode") && !query.Has("non_iid_mode") {
+		req.NonIidMode = true
+	}
+	if v := query.Get("h_submitter"); v != "" {
+		h, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("h_submitter must be a number, got %q", v)
+		}
+		req.HSubmitter = &h
+	}
+	if v := query.Get("estimators"); v != "" {
+		req.Estimators = strings.Split(v, ",")
+	}
+	return req, nil
+}
+
+// writeEvent writes one Server-Sent Event with data encoded as JSON and
+// flushes it to the client.
+func writeEvent(w io.Writer, rc *http.ResponseController, event string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, encoded); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,34 @@
+// Package buildinfo describes the running binary: its release version, the
+// commit and date it was built from, and whether the NIST C++ library is
+// linked. Commit and BuildDate are set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/AmmannChristian/nist-800-90b/internal/buildinfo.Commit=$(git rev-parse --short HEAD)"
+package buildinfo
+
+import "github.com/AmmannChristian/nist-800-90b/internal/entropy"
+
+// Version, Commit and BuildDate identify the build. The linker overrides
+// Commit and BuildDate; both stay "unknown" for plain go build and go test.
+var (
+	Version   = "1.0.0"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info is a snapshot of the build information.
+type Info struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	CGO       bool // the NIST C++ library is linked instead of a stub or pure Go
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		CGO:       entropy.LibraryInfo().CGO,
+	}
+}
@@ -5,15 +5,25 @@ package config
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io/fs"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
 )
 
 const defaultGRPCMaxMessageSize = 10 * 1024 * 1024
 
+// grpcRequestOverhead is added to MaxUploadSize for the default gRPC receive
+// limit so that a request carrying a maximum-size upload, plus its other
+// fields and protobuf framing, is not rejected with ResourceExhausted.
+const grpcRequestOverhead = 64 * 1024
+
 // Config holds all runtime parameters for the server, including network
 // addresses, TLS settings, authentication, logging, and resource limits.
 type Config struct {
@@ -39,11 +49,25 @@ type Config struct {
 	// File upload limits
 	MaxUploadSize int64 // in bytes
 
-	// Request timeouts
+	// Request timeouts (HTTP read/write and per-assessment limit)
 	Timeout time.Duration
 
 	// Metrics
 	MetricsEnabled bool
+	PprofEnabled   bool // Serve net/http/pprof under /debug/pprof/ on the metrics listener
+
+	// Tracing
+	TracingEnabled      bool   // Export an OpenTelemetry span per gRPC call
+	TracingOTLPEndpoint string // host:port of the OTLP/gRPC collector
+	TracingOTLPInsecure bool   // Connect to the collector without TLS
+
+	// Assessment execution
+	AssessIsolation   entropy.IsolationMode // In-process or subprocess execution
+	SelfTestOnStart   bool                  // Run the known-answer self-test before serving
+	AssessFileBaseDir string                // Directory AssessEntropyFile may read from; empty disables it
+	AllowSmallSamples bool                  // Pass datasets below MinRecommendedSamples to a patched library
+	MaxAssessMemory   int64                 // Estimated peak memory budget per assessment in bytes; 0 disables it
+	AssessmentThreads int                   // Threads one assessment may use; 0 means one per CPU
 
 	// Authentication
 	AuthEnabled                             bool
@@ -71,13 +95,22 @@ type Config struct {
 // values for any unset variables, and validates the resulting configuration.
 // It returns an error if validation fails.
 func LoadConfig() (*Config, error) {
+	isolation, err := entropy.ParseIsolationMode(getEnv("ASSESS_ISOLATION", "inprocess"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ASSESS_ISOLATION: %w", err)
+	}
+	assessmentThreads, err := getEnvAsPositiveInt("ASSESSMENT_THREADS")
+	if err != nil {
+		return nil, err
+	}
+
 	config := &Config{
 		// Defaults
 		ServerPort:                              getEnvAsInt("METRICS_PORT", getEnvAsInt("SERVER_PORT", 9091)),
 		ServerHost:                              getEnv("SERVER_HOST", "0.0.0.0"),
 		GRPCEnabled:                             getEnvAsBool("GRPC_ENABLED", false),
 		GRPCPort:                                getEnvAsInt("GRPC_PORT", 9090),
-		GRPCMaxRecvMessageSize:                  getEnvAsInt("GRPC_MAX_RECV_MESSAGE_SIZE", defaultGRPCMaxMessageSize),
+		GRPCMaxRecvMessageSize:                  getEnvAsInt("GRPC_MAX_RECV_MESSAGE_SIZE", 0), // 0 derives it from MaxUploadSize
 		GRPCMaxSendMessageSize:                  getEnvAsInt("GRPC_MAX_SEND_MESSAGE_SIZE", defaultGRPCMaxMessageSize),
 		TLSEnabled:                              getEnvAsBool("TLS_ENABLED", false),
 		TLSCertFile:                             getEnv("TLS_CERT_FILE", ""),
@@ -89,6 +122,16 @@ func LoadConfig() (*Config, error) {
 		MaxUploadSize:                           getEnvAsInt64("MAX_UPLOAD_SIZE", 100*1024*1024), // 100MB default
 		Timeout:                                 getEnvAsDuration("TIMEOUT", 5*time.Minute),
 		MetricsEnabled:                          getEnvAsBool("METRICS_ENABLED", true),
+		PprofEnabled:                            getEnvAsBool("PPROF_ENABLED", false),
+		TracingEnabled:                          getEnvAsBool("TRACING_ENABLED", false),
+		TracingOTLPEndpoint:                     getEnv("TRACING_OTLP_ENDPOINT", ""),
+		TracingOTLPInsecure:                     getEnvAsBool("TRACING_OTLP_INSECURE", false),
+		AssessIsolation:                         isolation,
+		SelfTestOnStart:                         getEnvAsBool("SELF_TEST_ON_START", false),
+		AssessFileBaseDir:                       getEnv("ASSESS_FILE_BASE_DIR", ""),
+		AllowSmallSamples:                       getEnvAsBool("ALLOW_SMALL_SAMPLES", false),
+		MaxAssessMemory:                         getEnvAsInt64("MAX_ASSESS_MEMORY", 0),
+		AssessmentThreads:                       assessmentThreads,
 		AuthEnabled:                             getEnvAsBool("AUTH_ENABLED", false),
 		AuthIssuer:                              getEnv("AUTH_ISSUER", ""),
 		AuthAudience:                            getEnv("AUTH_AUDIENCE", ""),
@@ -119,7 +162,8 @@ func LoadConfig() (*Config, error) {
 
 // Validate checks all configuration invariants, including port ranges, upload
 // size limits, log level validity, and cross-field constraints such as TLS and
-// authentication requiring gRPC to be enabled.
+// authentication requiring gRPC to be enabled. When TLS is enabled, the
+// configured certificate, key, and CA files must exist and be readable.
 func (c *Config) Validate() error {
 	if c.ServerPort < 1 || c.ServerPort > 65535 {
 		return fmt.Errorf("invalid server port: %d (must be 1-65535)", c.ServerPort)
@@ -134,16 +178,22 @@ func (c *Config) Validate() error {
 	if c.GRPCMaxSendMessageSize < 0 {
 		return fmt.Errorf("invalid GRPC_MAX_SEND_MESSAGE_SIZE: %d (must be >= 0)", c.GRPCMaxSendMessageSize)
 	}
-	if c.GRPCMaxRecvMessageSize == 0 {
-		c.GRPCMaxRecvMessageSize = defaultGRPCMaxMessageSize
-	}
-	if c.GRPCMaxSendMessageSize == 0 {
-		c.GRPCMaxSendMessageSize = defaultGRPCMaxMessageSize
-	}
+	c.GRPCMaxSendMessageSize = c.GRPCMaxSendMessageSizeValue()
 
 	if c.MaxUploadSize < 1024 {
 		return fmt.Errorf("max upload size too small: %d (must be at least 1024 bytes)", c.MaxUploadSize)
 	}
+	c.GRPCMaxRecvMessageSize = c.GRPCMaxRecvMessageSizeValue()
+	if c.MaxAssessMemory < 0 {
+		return fmt.Errorf("invalid MAX_ASSESS_MEMORY: %d (must be >= 0)", c.MaxAssessMemory)
+	}
+	if c.AssessmentThreads < 0 {
+		return fmt.Errorf("invalid ASSESSMENT_THREADS: %d (must be a positive integer)", c.AssessmentThreads)
+	}
+
+	if c.TracingEnabled && c.TracingOTLPEndpoint == "" {
+		return errors.New("TRACING_OTLP_ENDPOINT is required when TRACING_ENABLED is true")
+	}
 
 	validLogLevels := map[string]bool{
 		"debug": true,
@@ -155,6 +205,12 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.LogLevel)
 	}
 
+	if c.AssessFileBaseDir != "" {
+		if err := checkDirectory(c.AssessFileBaseDir, "ASSESS_FILE_BASE_DIR"); err != nil {
+			return err
+		}
+	}
+
 	roleMatchMode, err := parseAuthzMatchMode(c.AuthzRoleMatchMode, "AUTHZ_ROLE_MATCH_MODE")
 	if err != nil {
 		return err
@@ -250,8 +306,59 @@ func (c *Config) Validate() error {
 		if _, err := parseTLSMinVersion(c.TLSMinVersion); err != nil {
 			return err
 		}
+		if err := checkReadableFile(c.TLSCertFile, "TLS_CERT_FILE"); err != nil {
+			return err
+		}
+		if err := checkReadableFile(c.TLSKeyFile, "TLS_KEY_FILE"); err != nil {
+			return err
+		}
+		// The CA bundle is loaded whenever it is set, and is what client
+		// certificates are verified against.
+		if c.TLSCAFile != "" {
+			if err := checkReadableFile(c.TLSCAFile, "TLS_CA_FILE"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkReadableFile verifies that path names an existing regular file that
+// the process can open, so that misconfigured TLS material is reported at
+// startup rather than when the listener is built.
+func checkReadableFile(path, envName string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("invalid %s: file %s does not exist", envName, path)
+		}
+		return fmt.Errorf("invalid %s: cannot read %s: %w", envName, path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("invalid %s: cannot stat %s: %w", envName, path, err)
 	}
+	if info.IsDir() {
+		return fmt.Errorf("invalid %s: %s is a directory", envName, path)
+	}
+	return nil
+}
 
+// checkDirectory verifies that path names an existing directory.
+func checkDirectory(path, envName string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("invalid %s: directory %s does not exist", envName, path)
+		}
+		return fmt.Errorf("invalid %s: cannot stat %s: %w", envName, path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid %s: %s is not a directory", envName, path)
+	}
 	return nil
 }
 
@@ -265,6 +372,24 @@ func (c *Config) TLSMinVersionValue() (uint16, error) {
 	return parseTLSMinVersion(c.TLSMinVersion)
 }
 
+// GRPCMaxRecvMessageSizeValue returns the gRPC receive limit in bytes. When
+// GRPCMaxRecvMessageSize is not set, it is derived from MaxUploadSize plus
+// room for the other request fields, as Validate does.
+func (c *Config) GRPCMaxRecvMessageSizeValue() int {
+	if c.GRPCMaxRecvMessageSize > 0 {
+		return c.GRPCMaxRecvMessageSize
+	}
+	return int(min(max(c.MaxUploadSize, 0), math.MaxInt32-grpcRequestOverhead) + grpcRequestOverhead)
+}
+
+// GRPCMaxSendMessageSizeValue returns the gRPC send limit in bytes (defaults to 10 MiB).
+func (c *Config) GRPCMaxSendMessageSizeValue() int {
+	if c.GRPCMaxSendMessageSize > 0 {
+		return c.GRPCMaxSendMessageSize
+	}
+	return defaultGRPCMaxMessageSize
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -396,6 +521,21 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	return value
 }
 
+// getEnvAsPositiveInt reads an optional positive integer. Unlike the other
+// getters it rejects a malformed value instead of falling back to a default,
+// and it returns 0 when the variable is unset.
+func getEnvAsPositiveInt(key string) (int, error) {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(valueStr))
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("invalid %s: %q (must be a positive integer)", key, valueStr)
+	}
+	return value, nil
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
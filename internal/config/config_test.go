@@ -1,10 +1,13 @@
 package config
 
 import (
+	"math"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,7 +23,7 @@ func TestLoadConfig_Defaults(t *testing.T) {
 	assert.Equal(t, "0.0.0.0", cfg.ServerHost)
 	assert.False(t, cfg.GRPCEnabled)
 	assert.Equal(t, 9090, cfg.GRPCPort)
-	assert.Equal(t, 10*1024*1024, cfg.GRPCMaxRecvMessageSize)
+	assert.Equal(t, 100*1024*1024+64*1024, cfg.GRPCMaxRecvMessageSize)
 	assert.Equal(t, 10*1024*1024, cfg.GRPCMaxSendMessageSize)
 	assert.False(t, cfg.TLSEnabled)
 	assert.Empty(t, cfg.TLSCertFile)
@@ -31,7 +34,17 @@ func TestLoadConfig_Defaults(t *testing.T) {
 	assert.Equal(t, "info", cfg.LogLevel)
 	assert.Equal(t, int64(100*1024*1024), cfg.MaxUploadSize)
 	assert.Equal(t, 5*time.Minute, cfg.Timeout)
+	assert.Equal(t, entropy.IsolationInProcess, cfg.AssessIsolation)
+	assert.False(t, cfg.SelfTestOnStart)
+	assert.False(t, cfg.AllowSmallSamples)
+	assert.Equal(t, int64(0), cfg.MaxAssessMemory)
+	assert.Equal(t, 0, cfg.AssessmentThreads)
+	assert.Empty(t, cfg.AssessFileBaseDir)
 	assert.True(t, cfg.MetricsEnabled)
+	assert.False(t, cfg.PprofEnabled)
+	assert.False(t, cfg.TracingEnabled)
+	assert.Empty(t, cfg.TracingOTLPEndpoint)
+	assert.False(t, cfg.TracingOTLPInsecure)
 	assert.False(t, cfg.AuthEnabled)
 	assert.Empty(t, cfg.AuthIssuer)
 	assert.Empty(t, cfg.AuthAudience)
@@ -56,6 +69,10 @@ func TestLoadConfig_Defaults(t *testing.T) {
 func TestLoadConfig_EnvironmentVariables(t *testing.T) {
 	clearEnv(t)
 
+	certFile := writeTempFile(t, "server.crt")
+	keyFile := writeTempFile(t, "server.key")
+	caFile := writeTempFile(t, "ca.crt")
+
 	os.Setenv("SERVER_PORT", "9000")
 	os.Setenv("METRICS_PORT", "9100")
 	os.Setenv("SERVER_HOST", "127.0.0.1")
@@ -64,15 +81,24 @@ func TestLoadConfig_EnvironmentVariables(t *testing.T) {
 	os.Setenv("GRPC_MAX_RECV_MESSAGE_SIZE", "12582912")
 	os.Setenv("GRPC_MAX_SEND_MESSAGE_SIZE", "12582912")
 	os.Setenv("TLS_ENABLED", "true")
-	os.Setenv("TLS_CERT_FILE", "/tmp/server.crt")
-	os.Setenv("TLS_KEY_FILE", "/tmp/server.key")
-	os.Setenv("TLS_CA_FILE", "/tmp/ca.crt")
+	os.Setenv("TLS_CERT_FILE", certFile)
+	os.Setenv("TLS_KEY_FILE", keyFile)
+	os.Setenv("TLS_CA_FILE", caFile)
 	os.Setenv("TLS_CLIENT_AUTH", "requireandverify")
 	os.Setenv("TLS_MIN_VERSION", "1.3")
 	os.Setenv("LOG_LEVEL", "debug")
 	os.Setenv("MAX_UPLOAD_SIZE", "52428800")
 	os.Setenv("TIMEOUT", "10m")
+	os.Setenv("ASSESS_ISOLATION", "Subprocess")
+	os.Setenv("SELF_TEST_ON_START", "true")
+	os.Setenv("ALLOW_SMALL_SAMPLES", "true")
+	os.Setenv("MAX_ASSESS_MEMORY", "8589934592")
+	os.Setenv("ASSESSMENT_THREADS", "4")
 	os.Setenv("METRICS_ENABLED", "false")
+	os.Setenv("PPROF_ENABLED", "true")
+	os.Setenv("TRACING_ENABLED", "true")
+	os.Setenv("TRACING_OTLP_ENDPOINT", "otel-collector:4317")
+	os.Setenv("TRACING_OTLP_INSECURE", "true")
 	os.Setenv("AUTH_ENABLED", "true")
 	os.Setenv("AUTH_ISSUER", "https://issuer.example.com")
 	os.Setenv("AUTH_AUDIENCE", "nist-entropy")
@@ -95,15 +121,24 @@ func TestLoadConfig_EnvironmentVariables(t *testing.T) {
 	assert.Equal(t, 12582912, cfg.GRPCMaxRecvMessageSize)
 	assert.Equal(t, 12582912, cfg.GRPCMaxSendMessageSize)
 	assert.True(t, cfg.TLSEnabled)
-	assert.Equal(t, "/tmp/server.crt", cfg.TLSCertFile)
-	assert.Equal(t, "/tmp/server.key", cfg.TLSKeyFile)
-	assert.Equal(t, "/tmp/ca.crt", cfg.TLSCAFile)
+	assert.Equal(t, certFile, cfg.TLSCertFile)
+	assert.Equal(t, keyFile, cfg.TLSKeyFile)
+	assert.Equal(t, caFile, cfg.TLSCAFile)
 	assert.Equal(t, "requireandverify", cfg.TLSClientAuth)
 	assert.Equal(t, "1.3", cfg.TLSMinVersion)
 	assert.Equal(t, "debug", cfg.LogLevel)
 	assert.Equal(t, int64(52428800), cfg.MaxUploadSize)
 	assert.Equal(t, 10*time.Minute, cfg.Timeout)
+	assert.Equal(t, entropy.IsolationSubprocess, cfg.AssessIsolation)
+	assert.True(t, cfg.SelfTestOnStart)
+	assert.True(t, cfg.AllowSmallSamples)
+	assert.Equal(t, int64(8<<30), cfg.MaxAssessMemory)
+	assert.Equal(t, 4, cfg.AssessmentThreads)
 	assert.False(t, cfg.MetricsEnabled)
+	assert.True(t, cfg.PprofEnabled)
+	assert.True(t, cfg.TracingEnabled)
+	assert.Equal(t, "otel-collector:4317", cfg.TracingOTLPEndpoint)
+	assert.True(t, cfg.TracingOTLPInsecure)
 	assert.True(t, cfg.AuthEnabled)
 	assert.Equal(t, "https://issuer.example.com", cfg.AuthIssuer)
 	assert.Equal(t, "nist-entropy", cfg.AuthAudience)
@@ -265,6 +300,39 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "GRPC_MAX_SEND_MESSAGE_SIZE",
 		},
+		{
+			name: "negative assessment memory budget",
+			cfg: &Config{
+				ServerPort:      8080,
+				MaxUploadSize:   1024,
+				MaxAssessMemory: -1,
+				LogLevel:        "info",
+			},
+			wantErr: true,
+			errMsg:  "MAX_ASSESS_MEMORY",
+		},
+		{
+			name: "negative assessment threads",
+			cfg: &Config{
+				ServerPort:        8080,
+				MaxUploadSize:     1024,
+				AssessmentThreads: -1,
+				LogLevel:          "info",
+			},
+			wantErr: true,
+			errMsg:  "ASSESSMENT_THREADS",
+		},
+		{
+			name: "tracing without an OTLP endpoint",
+			cfg: &Config{
+				ServerPort:     8080,
+				MaxUploadSize:  1024,
+				LogLevel:       "info",
+				TracingEnabled: true,
+			},
+			wantErr: true,
+			errMsg:  "TRACING_OTLP_ENDPOINT is required",
+		},
 		{
 			name: "invalid max upload size",
 			cfg: &Config{
@@ -633,6 +701,133 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestConfig_ValidateAssessFileBaseDir(t *testing.T) {
+	dir := t.TempDir()
+	file := writeTempFile(t, "samples.bin")
+
+	newConfig := func(baseDir string) *Config {
+		return &Config{
+			ServerPort:        8080,
+			LogLevel:          "info",
+			MaxUploadSize:     1024,
+			AssessFileBaseDir: baseDir,
+		}
+	}
+
+	assert.NoError(t, newConfig("").Validate())
+	assert.NoError(t, newConfig(dir).Validate())
+
+	err := newConfig(filepath.Join(dir, "missing")).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ASSESS_FILE_BASE_DIR")
+	assert.Contains(t, err.Error(), "does not exist")
+
+	err = newConfig(file).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not a directory")
+}
+
+func TestConfig_ValidateDerivesGRPCMaxRecvMessageSize(t *testing.T) {
+	cfg := &Config{ServerPort: 8080, LogLevel: "info", MaxUploadSize: 20 * 1024 * 1024}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, 20*1024*1024+grpcRequestOverhead, cfg.GRPCMaxRecvMessageSize)
+
+	cfg = &Config{ServerPort: 8080, LogLevel: "info", MaxUploadSize: 20 * 1024 * 1024, GRPCMaxRecvMessageSize: 5 * 1024 * 1024}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, 5*1024*1024, cfg.GRPCMaxRecvMessageSize)
+}
+
+// A Config that was never validated gets the same limits as a validated one.
+func TestConfig_GRPCMessageSizeValues(t *testing.T) {
+	cfg := &Config{MaxUploadSize: 20 * 1024 * 1024}
+	assert.Equal(t, 20*1024*1024+grpcRequestOverhead, cfg.GRPCMaxRecvMessageSizeValue())
+	assert.Equal(t, 10*1024*1024, cfg.GRPCMaxSendMessageSizeValue())
+
+	cfg = &Config{MaxUploadSize: 20 * 1024 * 1024, GRPCMaxRecvMessageSize: 5 * 1024 * 1024, GRPCMaxSendMessageSize: 6 * 1024 * 1024}
+	assert.Equal(t, 5*1024*1024, cfg.GRPCMaxRecvMessageSizeValue())
+	assert.Equal(t, 6*1024*1024, cfg.GRPCMaxSendMessageSizeValue())
+
+	cfg = &Config{MaxUploadSize: math.MaxInt64}
+	assert.Equal(t, math.MaxInt32, cfg.GRPCMaxRecvMessageSizeValue())
+}
+
+func TestConfig_ValidateTLSFiles(t *testing.T) {
+	certFile := writeTempFile(t, "server.crt")
+	keyFile := writeTempFile(t, "server.key")
+	caFile := writeTempFile(t, "ca.crt")
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	newTLSConfig := func() *Config {
+		return &Config{
+			ServerPort:    8080,
+			GRPCEnabled:   true,
+			GRPCPort:      9090,
+			LogLevel:      "info",
+			MaxUploadSize: 1024,
+			TLSEnabled:    true,
+			TLSCertFile:   certFile,
+			TLSKeyFile:    keyFile,
+			TLSCAFile:     caFile,
+			TLSClientAuth: "requireandverify",
+		}
+	}
+
+	t.Run("all files present", func(t *testing.T) {
+		assert.NoError(t, newTLSConfig().Validate())
+	})
+
+	t.Run("missing cert", func(t *testing.T) {
+		cfg := newTLSConfig()
+		cfg.TLSCertFile = missing
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "TLS_CERT_FILE")
+		assert.Contains(t, err.Error(), missing)
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		cfg := newTLSConfig()
+		cfg.TLSKeyFile = missing
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "TLS_KEY_FILE")
+		assert.Contains(t, err.Error(), missing)
+	})
+
+	t.Run("missing ca", func(t *testing.T) {
+		cfg := newTLSConfig()
+		cfg.TLSCAFile = missing
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "TLS_CA_FILE")
+		assert.Contains(t, err.Error(), missing)
+	})
+
+	t.Run("directory instead of file", func(t *testing.T) {
+		cfg := newTLSConfig()
+		cfg.TLSCertFile = t.TempDir()
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is a directory")
+	})
+
+	t.Run("ca optional", func(t *testing.T) {
+		cfg := newTLSConfig()
+		cfg.TLSCAFile = ""
+		cfg.TLSClientAuth = "none"
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("tls disabled ignores paths", func(t *testing.T) {
+		cfg := newTLSConfig()
+		cfg.TLSEnabled = false
+		cfg.TLSCertFile = missing
+		cfg.TLSKeyFile = missing
+		cfg.TLSCAFile = missing
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
 func TestLoadConfig_InvalidEnvironmentVariables(t *testing.T) {
 	clearEnv(t)
 
@@ -676,6 +871,41 @@ func TestLoadConfig_InvalidEnvironmentVariables(t *testing.T) {
 	assert.Equal(t, 5*time.Minute, cfg.Timeout) // Should fall back to default
 }
 
+func TestLoadConfig_AssessIsolation(t *testing.T) {
+	clearEnv(t)
+
+	// Every spelling entropy.ParseIsolationMode accepts is accepted.
+	os.Setenv("ASSESS_ISOLATION", "in-process")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, entropy.IsolationInProcess, cfg.AssessIsolation)
+
+	os.Setenv("ASSESS_ISOLATION", "fork")
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid ASSESS_ISOLATION")
+}
+
+func TestLoadConfig_AssessmentThreads(t *testing.T) {
+	clearEnv(t)
+	defer clearEnv(t)
+
+	os.Setenv("ASSESSMENT_THREADS", " 2 ")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.AssessmentThreads)
+
+	// Unlike the other integers, a malformed value is rejected rather than
+	// replaced by the default.
+	for _, value := range []string{"0", "-3", "four", "1.5"} {
+		os.Setenv("ASSESSMENT_THREADS", value)
+		_, err := LoadConfig()
+		require.Error(t, err, value)
+		assert.Contains(t, err.Error(), "invalid ASSESSMENT_THREADS")
+		assert.Contains(t, err.Error(), "must be a positive integer")
+	}
+}
+
 func TestLoadConfig_ValidationFailure(t *testing.T) {
 	clearEnv(t)
 
@@ -691,7 +921,8 @@ func clearEnv(t *testing.T) {
 	envVars := []string{
 		"SERVER_PORT", "SERVER_HOST", "GRPC_ENABLED", "GRPC_PORT", "GRPC_MAX_RECV_MESSAGE_SIZE", "GRPC_MAX_SEND_MESSAGE_SIZE", "METRICS_PORT",
 		"TLS_ENABLED", "TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_CA_FILE", "TLS_CLIENT_AUTH", "TLS_MIN_VERSION",
-		"LOG_LEVEL", "MAX_UPLOAD_SIZE", "TIMEOUT", "METRICS_ENABLED",
+		"LOG_LEVEL", "MAX_UPLOAD_SIZE", "TIMEOUT", "ASSESS_ISOLATION", "SELF_TEST_ON_START", "ASSESS_FILE_BASE_DIR", "ALLOW_SMALL_SAMPLES", "MAX_ASSESS_MEMORY", "ASSESSMENT_THREADS", "METRICS_ENABLED", "PPROF_ENABLED",
+		"TRACING_ENABLED", "TRACING_OTLP_ENDPOINT", "TRACING_OTLP_INSECURE",
 		"AUTH_ENABLED", "AUTH_ISSUER", "AUTH_AUDIENCE", "AUTH_JWKS_URL",
 		"AUTH_TOKEN_TYPE", "AUTH_INTROSPECTION_URL",
 		"AUTH_INTROSPECTION_CLIENT_ID", "AUTH_INTROSPECTION_CLIENT_SECRET",
@@ -705,3 +936,11 @@ func clearEnv(t *testing.T) {
 		os.Unsetenv(v)
 	}
 }
+
+// writeTempFile creates a small placeholder file and returns its path.
+func writeTempFile(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte("placeholder"), 0o600))
+	return path
+}
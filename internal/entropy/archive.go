@@ -0,0 +1,162 @@
+package entropy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// zipHeaders are the signatures a zip archive starts with: a local file
+// header, or the end of central directory record of an empty archive.
+var zipHeaders = [][]byte{[]byte("PK\x03\x04"), []byte("PK\x05\x06")}
+
+// isZip reports whether filename is a zip archive, by its .zip extension or
+// its signature.
+func isZip(filename string) bool {
+	if strings.EqualFold(filepath.Ext(filename), ".zip") {
+		return true
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+	for _, signature := range zipHeaders {
+		if bytes.Equal(header, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// ArchiveMembers returns the names of the regular files in a tar or zip
+// archive, in archive order; directories, links and other entries are left
+// out, and a name that occurs twice is listed once. Tar archives may be
+// compressed as OpenFile decompresses them, as in .tar.gz or .tar.zst. An
+// archive that cannot be read to the end fails with ErrInvalidData.
+func ArchiveMembers(filename string) ([]string, error) {
+	const op = "ArchiveMembers"
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if isZip(filename) {
+		zr, err := zip.OpenReader(filename)
+		if err != nil {
+			return nil, archiveError(op, filename, err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.Mode().IsRegular() {
+				add(f.Name)
+			}
+		}
+		return names, nil
+	}
+
+	r, err := openFile(op, filename, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, archiveError(op, filename, err)
+		}
+		if hdr.FileInfo().Mode().IsRegular() {
+			add(hdr.Name)
+		}
+	}
+}
+
+// ReadArchiveMember reads the member name of a tar or zip archive, as listed
+// by ArchiveMembers. The member may decompress to at most
+// MaxDecompressedSize bytes; when limit is above 0 reading stops after limit
+// bytes, as for ReadFileLimit. Tar archives are read from the start up to
+// the member.
+func ReadArchiveMember(filename, name string, limit int64) ([]byte, error) {
+	const op = "ReadArchiveMember"
+	member := fmt.Sprintf("%s member %s", filename, name)
+	read := func(r io.Reader) ([]byte, error) {
+		src := guardSize(op, member, r)
+		if limit > 0 {
+			src = io.LimitReader(src, limit)
+		}
+		data, err := io.ReadAll(src)
+		if err != nil {
+			if errors.Is(err, ErrResourceLimit) {
+				return nil, err
+			}
+			return nil, newError(op, ErrInvalidData, fmt.Sprintf("failed to read %s: %v", member, err))
+		}
+		return data, nil
+	}
+
+	if isZip(filename) {
+		zr, err := zip.OpenReader(filename)
+		if err != nil {
+			return nil, archiveError(op, filename, err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.Name != name || !f.Mode().IsRegular() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, newError(op, ErrInvalidData, fmt.Sprintf("failed to open %s: %v", member, err))
+			}
+			defer rc.Close()
+			return read(rc)
+		}
+		return nil, newError(op, os.ErrNotExist, fmt.Sprintf("%s not found", member))
+	}
+
+	r, err := openFile(op, filename, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, newError(op, os.ErrNotExist, fmt.Sprintf("%s not found", member))
+		}
+		if err != nil {
+			return nil, archiveError(op, filename, err)
+		}
+		if hdr.Name == name && hdr.FileInfo().Mode().IsRegular() {
+			return read(tr)
+		}
+	}
+}
+
+// archiveError reports an archive that could not be opened or read. A file
+// that does not exist keeps its error; anything else is invalid data.
+func archiveError(op, filename string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return newError(op, err, fmt.Sprintf("failed to open file: %s", filename))
+	}
+	return newError(op, ErrInvalidData, fmt.Sprintf("%s is not a valid tar or zip archive: %v", filename, err))
+}
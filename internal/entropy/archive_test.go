@@ -0,0 +1,151 @@
+package entropy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archiveFile is a member of a test archive; a name ending in "/" is a
+// directory.
+type archiveFile struct {
+	name string
+	data []byte
+}
+
+// writeTar writes files as a tar archive to path, gzip-compressed when
+// compress is set.
+func writeTar(t *testing.T, path string, compress bool, files []archiveFile) {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}
+		if f.name[len(f.name)-1] == '/' {
+			hdr = &tar.Header{Name: f.name, Mode: 0o755, Typeflag: tar.TypeDir}
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write(f.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	if !compress {
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+		return
+	}
+	writeGzip(t, path, buf.Bytes())
+}
+
+// writeZip writes files as a zip archive to path.
+func writeZip(t *testing.T, path string, files []archiveFile) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		require.NoError(t, err)
+		_, err = w.Write(f.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
+
+func TestArchiveMembers(t *testing.T) {
+	dir := t.TempDir()
+	files := []archiveFile{
+		{name: "a.bin", data: randomSamples(1000, 8, 1)},
+		{name: "sub/"},
+		{name: "sub/b.bin", data: randomSamples(500, 8, 2)},
+	}
+	for _, name := range []string{"data.tar", "data.tar.gz", "data.zip", "zip-without-extension"} {
+		path := filepath.Join(dir, name)
+		switch name {
+		case "data.tar":
+			writeTar(t, path, false, files)
+		case "data.tar.gz":
+			writeTar(t, path, true, files)
+		default:
+			writeZip(t, path, files)
+		}
+
+		members, err := ArchiveMembers(path)
+		require.NoError(t, err, name)
+		assert.Equal(t, []string{"a.bin", "sub/b.bin"}, members, name)
+
+		for _, f := range []archiveFile{files[0], files[2]} {
+			got, err := ReadArchiveMember(path, f.name, 0)
+			require.NoError(t, err, name)
+			assert.Equal(t, f.data, got, name)
+		}
+		got, err := ReadArchiveMember(path, "sub/b.bin", 10)
+		require.NoError(t, err, name)
+		assert.Equal(t, files[2].data[:10], got, name)
+
+		_, err = ReadArchiveMember(path, "missing.bin", 0)
+		assert.True(t, errors.Is(err, os.ErrNotExist), name)
+	}
+}
+
+func TestArchiveMembers_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := ArchiveMembers(filepath.Join(dir, "missing.tar"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	plain := filepath.Join(dir, "data.bin")
+	require.NoError(t, os.WriteFile(plain, randomSamples(1000, 8, 3), 0o644))
+	_, err = ArchiveMembers(plain)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+
+	// A truncated tar.gz fails as a whole.
+	path := filepath.Join(dir, "data.tar.gz")
+	writeTar(t, path, true, []archiveFile{{name: "a.bin", data: randomSamples(4096, 8, 4)}})
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, raw[:len(raw)/2], 0o644))
+	_, err = ArchiveMembers(path)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+
+	// A corrupted zip member fails its checksum.
+	zipPath := filepath.Join(dir, "data.zip")
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "a.bin", Method: zip.Store})
+	require.NoError(t, err)
+	_, err = w.Write(bytes.Repeat([]byte{7}, 100))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	corrupted := bytes.Replace(buf.Bytes(), bytes.Repeat([]byte{7}, 100), bytes.Repeat([]byte{8}, 100), 1)
+	require.NoError(t, os.WriteFile(zipPath, corrupted, 0o644))
+	members, err := ArchiveMembers(zipPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.bin"}, members)
+	_, err = ReadArchiveMember(zipPath, "a.bin", 0)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+}
+
+func TestReadArchiveMember_DecompressedSizeLimit(t *testing.T) {
+	defer func(limit int64) { maxDecompressedSize = limit }(maxDecompressedSize)
+	maxDecompressedSize = 1000
+
+	// The limit applies to each member, not to the archive as a whole.
+	path := filepath.Join(t.TempDir(), "data.tar.gz")
+	writeTar(t, path, true, []archiveFile{
+		{name: "small.bin", data: make([]byte, 800)},
+		{name: "large.bin", data: make([]byte, 1001)},
+		{name: "last.bin", data: make([]byte, 800)},
+	})
+	got, err := ReadArchiveMember(path, "last.bin", 0)
+	require.NoError(t, err)
+	assert.Len(t, got, 800)
+	_, err = ReadArchiveMember(path, "large.bin", 0)
+	assert.True(t, errors.Is(err, ErrResourceLimit), "%v", err)
+}
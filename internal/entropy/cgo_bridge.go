@@ -1,45 +1,146 @@
-//go:build !teststub
+//go:build cgo && !nocgo && !teststub
 
 // This file provides the CGO bridge to the NIST SP 800-90B C++ reference
 // implementation. It is excluded when the "teststub" build tag is active,
-// allowing unit tests to run without the C++ toolchain.
+// allowing unit tests to run without the C++ toolchain, and in nocgo builds,
+// which use the pure-Go estimators instead.
+//
+// Ownership across the boundary: the caller's sample slice stays Go memory
+// and is never copied on the Go side. pinInput pins its backing array with a
+// runtime.Pinner for the duration of the C call, so the garbage collector
+// can neither move nor free it while the wrapper holds the pointer. The
+// wrapper reads exactly len(data) bytes, once, into its own buffers before
+// any estimator runs, and does not retain the pointer after returning. The
+// EntropyResult is C memory owned by the bridge, which copies every field
+// into Go values before releasing it with free_entropy_result. Its fixed
+// char buffers are read with a length bound and never assumed to be
+// NUL-terminated.
 
 package entropy
 
 /*
 #cgo CXXFLAGS: -std=c++11 -fopenmp -I${SRCDIR}/../../internal/nist/cpp -I${SRCDIR}/../../internal/nist/wrapper
 #cgo LDFLAGS: -L${SRCDIR}/../../internal/nist/lib -lentropy90b -lbz2 -ldivsufsort -ldivsufsort64 -ljsoncpp -lmpfr -lgmp -lgomp -lstdc++ -lm -lcrypto
+#cgo CFLAGS: -fopenmp
 #include "../../internal/nist/wrapper/wrapper.h"
+#include <omp.h>
 #include <stdlib.h>
+
+// calculate_iid_entropy_threads runs calculate_iid_entropy with at most
+// threads OpenMP threads, or the default team size when threads is 0. The
+// setting belongs to the calling OS thread, which runs the whole call, and
+// is restored before returning.
+static EntropyResult* calculate_iid_entropy_threads(int threads, const uint8_t* data, size_t length, int bits_per_symbol, bool is_binary, int verbose, uint32_t estimator_mask, int bit_order, ProgressCallback progress, uintptr_t progress_data) {
+	int previous = omp_get_max_threads();
+	if (threads > 0) {
+		omp_set_num_threads(threads);
+	}
+	EntropyResult* result = calculate_iid_entropy(data, length, bits_per_symbol, is_binary, verbose, estimator_mask, bit_order, progress, progress_data);
+	if (threads > 0) {
+		omp_set_num_threads(previous);
+	}
+	return result;
+}
+
+// release_openmp releases the idle thread pool of the OpenMP runtime, which
+// creates it again on the next parallel region.
+static int release_openmp(void) {
+	return omp_pause_resource_all(omp_pause_soft);
+}
+
+extern void goProgress(char* phase, double percent, uintptr_t handle);
 */
 import "C"
 
 import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/cgo"
 	"unsafe"
 )
 
+// cgoEnabled reports that the real NIST library is linked.
+const cgoEnabled = true
+
+// backend reports that the NIST C++ reference implementation runs.
+const backend = BackendNIST
+
+// libraryVersions returns the NIST tool and wrapper versions compiled into
+// the linked library.
+func libraryVersions() (tool, wrapper string) {
+	return C.GoString(C.nist_tool_version()), C.GoString(C.wrapper_version())
+}
+
+// releaseRuntime releases the OpenMP thread pool. No computation may be
+// running.
+func releaseRuntime() error {
+	if rc := C.release_openmp(); rc != 0 {
+		return fmt.Errorf("omp_pause_resource_all returned %d", int(rc))
+	}
+	return nil
+}
+
+// pinInput pins the backing array of data, which must not be empty, and
+// returns a pointer to its first byte for the wrapper. The caller must call
+// the returned unpin once the C call has returned.
+func pinInput(data []byte) (*C.uint8_t, func()) {
+	var pinner runtime.Pinner
+	pinner.Pin(&data[0])
+	return (*C.uint8_t)(unsafe.Pointer(&data[0])), pinner.Unpin
+}
+
+// goProgress is the ProgressCallback passed to the wrapper. handle refers to
+// the ProgressFunc of the running calculation.
+//
+//export goProgress
+func goProgress(phase *C.char, percent C.double, handle C.uintptr_t) {
+	cgo.Handle(handle).Value().(ProgressFunc)(C.GoString(phase), float64(percent))
+}
+
+// progressCallback returns the wrapper arguments that report to progress,
+// and a release function the caller must call once the C call has returned.
+// A nil progress disables the callback.
+func progressCallback(progress ProgressFunc) (C.ProgressCallback, C.uintptr_t, func()) {
+	if progress == nil {
+		return nil, 0, func() {}
+	}
+	h := cgo.NewHandle(progress)
+	return C.ProgressCallback(C.goProgress), C.uintptr_t(h), h.Delete
+}
+
 // calculateIIDEntropy invokes the C wrapper to run IID tests including
-// Most Common Value, Chi-Square, LRS, and Permutation tests.
-func calculateIIDEntropy(data []byte, bitsPerSymbol int, verbose int) (*Result, error) {
+// Most Common Value, Chi-Square, LRS, and Permutation tests. A non-zero mask
+// restricts the run to the selected tests; order selects the bit expansion
+// used for H_bitstring. The library always runs PermutationRounds rounds
+// with its own random seed, so of perm only the thread cap applies. progress,
+// if not nil, receives the wrapper's progress reports.
+func calculateIIDEntropy(data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, perm permutationOptions, progress ProgressFunc) (*Result, error) {
 	if len(data) == 0 {
 		return nil, newError("calculateIIDEntropy", ErrInvalidData, "data is empty")
 	}
 
-	cData := (*C.uint8_t)(unsafe.Pointer(&data[0]))
+	cData, unpin := pinInput(data)
+	defer unpin()
 	cLength := C.size_t(len(data))
 	cBitsPerSymbol := C.int(bitsPerSymbol)
 	// Always use initial_entropy=true for IID tests (not conditioned mode)
 	cInitialEntropy := C.bool(true)
 	cVerbose := C.int(verbose)
+	cMask := C.uint32_t(mask)
+	cBitOrder := C.int(order)
+	cThreads := C.int(perm.threads)
+	cProgress, cProgressData, release := progressCallback(progress)
+	defer release()
 
-	cResult := C.calculate_iid_entropy(cData, cLength, cBitsPerSymbol, cInitialEntropy, cVerbose)
+	cResult := C.calculate_iid_entropy_threads(cThreads, cData, cLength, cBitsPerSymbol, cInitialEntropy, cVerbose, cMask, cBitOrder, cProgress, cProgressData)
 	if cResult == nil {
 		return nil, newError("calculateIIDEntropy", ErrMemoryAllocation, "failed to allocate result structure")
 	}
 	defer C.free_entropy_result(cResult)
 
 	if cResult.error_code != 0 {
-		errMsg := C.GoString(&cResult.error_message[0])
+		errMsg := cErrorMessage(C.GoStringN(&cResult.error_message[0], C.int(len(cResult.error_message))))
 		return nil, wrapCError("calculateIIDEntropy", int(cResult.error_code), errMsg)
 	}
 
@@ -51,6 +152,8 @@ func calculateIIDEntropy(data []byte, bitsPerSymbol int, verbose int) (*Result,
 		DataWordSize: int(cResult.data_word_size),
 		TestType:     IID,
 		Estimators:   convertEstimators(cResult),
+		Histogram:    convertHistogram(cResult),
+		RawJSON:      convertRawJSON(cResult),
 	}
 
 	return result, nil
@@ -67,8 +170,10 @@ func convertEstimators(cResult *C.EntropyResult) []EstimatorResult {
 	estimators := make([]EstimatorResult, count)
 	for i := 0; i < count; i++ {
 		cEst := cResult.estimators[i]
+		name := cBufferString(C.GoStringN(&cEst.name[0], C.int(len(cEst.name))))
 		estimators[i] = EstimatorResult{
-			Name:            C.GoString(&cEst.name[0]),
+			Name:            name,
+			ID:              EstimatorIDFromName(name),
 			EntropyEstimate: float64(cEst.entropy_estimate),
 			Passed:          bool(cEst.passed),
 			IsEntropyValid:  bool(cEst.is_entropy_valid),
@@ -77,29 +182,55 @@ func convertEstimators(cResult *C.EntropyResult) []EstimatorResult {
 	return estimators
 }
 
-// calculateNonIIDEntropy invokes the C wrapper to run all ten Non-IID
-// estimators defined in NIST SP 800-90B Section 6.3.
-func calculateNonIIDEntropy(data []byte, bitsPerSymbol int, verbose int) (*Result, error) {
+// convertHistogram copies the symbol counts gathered by the wrapper while it
+// prepared the data.
+func convertHistogram(cResult *C.EntropyResult) []uint64 {
+	histogram := make([]uint64, HistogramSize)
+	for i := range histogram {
+		histogram[i] = uint64(cResult.histogram[i])
+	}
+	return histogram
+}
+
+// convertRawJSON copies the NIST tool JSON document built by the wrapper,
+// or returns nil when it could not build one.
+func convertRawJSON(cResult *C.EntropyResult) json.RawMessage {
+	if cResult.raw_json == nil {
+		return nil
+	}
+	return json.RawMessage(C.GoString(cResult.raw_json))
+}
+
+// calculateNonIIDEntropy invokes the C wrapper to run the ten Non-IID
+// estimators defined in NIST SP 800-90B Section 6.3, or the subset selected
+// by a non-zero mask. order selects the bit expansion of the bitstring
+// estimates. progress, if not nil, receives the wrapper's progress reports.
+func calculateNonIIDEntropy(data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, progress ProgressFunc) (*Result, error) {
 	if len(data) == 0 {
 		return nil, newError("calculateNonIIDEntropy", ErrInvalidData, "data is empty")
 	}
 
-	cData := (*C.uint8_t)(unsafe.Pointer(&data[0]))
+	cData, unpin := pinInput(data)
+	defer unpin()
 	cLength := C.size_t(len(data))
 	cBitsPerSymbol := C.int(bitsPerSymbol)
 	// Always use initial_entropy=true for Non-IID tests (not conditioned mode)
 	// This matches the NIST CLI default behavior with -i flag
 	cInitialEntropy := C.bool(true)
 	cVerbose := C.int(verbose)
+	cMask := C.uint32_t(mask)
+	cBitOrder := C.int(order)
+	cProgress, cProgressData, release := progressCallback(progress)
+	defer release()
 
-	cResult := C.calculate_non_iid_entropy(cData, cLength, cBitsPerSymbol, cInitialEntropy, cVerbose)
+	cResult := C.calculate_non_iid_entropy(cData, cLength, cBitsPerSymbol, cInitialEntropy, cVerbose, cMask, cBitOrder, cProgress, cProgressData)
 	if cResult == nil {
 		return nil, newError("calculateNonIIDEntropy", ErrMemoryAllocation, "failed to allocate result structure")
 	}
 	defer C.free_entropy_result(cResult)
 
 	if cResult.error_code != 0 {
-		errMsg := C.GoString(&cResult.error_message[0])
+		errMsg := cErrorMessage(C.GoStringN(&cResult.error_message[0], C.int(len(cResult.error_message))))
 		return nil, wrapCError("calculateNonIIDEntropy", int(cResult.error_code), errMsg)
 	}
 
@@ -111,7 +242,37 @@ func calculateNonIIDEntropy(data []byte, bitsPerSymbol int, verbose int) (*Resul
 		DataWordSize: int(cResult.data_word_size),
 		TestType:     NonIID,
 		Estimators:   convertEstimators(cResult),
+		Histogram:    convertHistogram(cResult),
+		RawJSON:      convertRawJSON(cResult),
 	}
 
 	return result, nil
 }
+
+// calculateLRS invokes the C wrapper to compute the length of the longest
+// repeated substring of the symbols and their LRS estimate.
+func calculateLRS(data []byte, bitsPerSymbol int) (LRSResult, error) {
+	if len(data) == 0 {
+		return LRSResult{}, newError("calculateLRS", ErrInvalidData, "data is empty")
+	}
+
+	cData, unpin := pinInput(data)
+	defer unpin()
+
+	cResult := C.calculate_lrs(cData, C.size_t(len(data)), C.int(bitsPerSymbol), C.int(0))
+	if cResult == nil {
+		return LRSResult{}, newError("calculateLRS", ErrMemoryAllocation, "failed to allocate result structure")
+	}
+	defer C.free_lrs_result(cResult)
+
+	if cResult.error_code != 0 {
+		errMsg := cErrorMessage(C.GoStringN(&cResult.error_message[0], C.int(len(cResult.error_message))))
+		return LRSResult{}, wrapCError("calculateLRS", int(cResult.error_code), errMsg)
+	}
+
+	return LRSResult{
+		Length:        int(cResult.length),
+		Estimate:      float64(cResult.entropy_estimate),
+		BitsPerSymbol: int(cResult.data_word_size),
+	}, nil
+}
@@ -0,0 +1,142 @@
+//go:build cgo && !nocgo && !teststub
+
+package entropy
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bitOrderPattern returns 2-bit symbols whose high bit repeats the low bit of
+// the previous symbol. Expanded MSB first, every other bit equals its
+// predecessor; expanded LSB first, neighbouring bits are independent.
+func bitOrderPattern(n int) []byte {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, n)
+	prev := byte(0)
+	for i := range data {
+		cur := byte(rng.Intn(2))
+		data[i] = prev<<1 | cur
+		prev = cur
+	}
+	return data
+}
+
+func TestBitOrder_CraftedPattern(t *testing.T) {
+	data := bitOrderPattern(200000)
+
+	assess := func(order *BitOrder) *Result {
+		t.Helper()
+		assessment := NewAssessment()
+		assessment.SetVerbose(0)
+		assessment.SetEstimators([]string{"markov"})
+		if order != nil {
+			assessment.SetBitOrder(*order)
+		}
+		res, err := assessment.AssessNonIID(data, 2)
+		require.NoError(t, err)
+		return res
+	}
+
+	msb, lsb := MSBFirst, LSBFirst
+	def := assess(nil)
+	msbRes := assess(&msb)
+	lsbRes := assess(&lsb)
+
+	// The default must stay MSB first, where the Markov estimate sees the
+	// repeated bits: P(same as previous) = 0.75, so about 0.42 bits.
+	assert.Equal(t, msbRes.HBitstring, def.HBitstring)
+	assert.Less(t, def.HBitstring, 0.6)
+	assert.Greater(t, lsbRes.HBitstring, 0.9)
+	assert.Equal(t, msbRes.HOriginal, lsbRes.HOriginal)
+}
+
+func TestLibraryInfo_NIST(t *testing.T) {
+	info := LibraryInfo()
+	assert.True(t, info.CGO)
+	assert.Equal(t, BackendNIST, info.Backend)
+	assert.Equal(t, ValidEstimatorNames(IID), info.IIDEstimators)
+	assert.Equal(t, ValidEstimatorNames(NonIID), info.NonIIDEstimators)
+}
+
+func TestCrossValidate_GoMatchesNIST(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	for _, testType := range []TestType{IID, NonIID} {
+		cv, err := assessment.CrossValidate(randomSamples(100000, 4, 7), 4, testType, DefaultCrossValidationTolerance)
+		require.NoError(t, err)
+		assert.Equal(t, BackendNIST, cv.Reference)
+		for _, d := range cv.Deltas {
+			assert.True(t, d.Within, "%s %s: NIST %.17g, Go %.17g", testType, d.Name, d.Reference, d.Native)
+		}
+		assert.True(t, cv.Passed)
+	}
+}
+
+// TestCGOBridge_SoakUnderGCPressure runs many concurrent assessments while
+// other goroutines churn the heap and force collections, checking that every
+// run sees its input intact. Run it with GODEBUG=gccheckmark=1 (make
+// test-soak) to have the collector verify its marking as well.
+func TestCGOBridge_SoakUnderGCPressure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("soak test")
+	}
+	const workers, rounds = 8, 25
+
+	data := randomSamples(20000, 8, 11)
+	reference := NewAssessment()
+	reference.SetVerbose(0)
+	reference.SetEstimators([]string{"mcv", "collision"})
+	want, err := reference.AssessNonIID(data, 8)
+	require.NoError(t, err)
+
+	stop := make(chan struct{})
+	var churn sync.WaitGroup
+	churn.Add(1)
+	go func() {
+		defer churn.Done()
+		var sink [][]byte
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			sink = append(sink, make([]byte, 1<<16))
+			if len(sink) == 64 {
+				sink = nil
+				runtime.GC()
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assessment := NewAssessment()
+			assessment.SetVerbose(0)
+			assessment.SetEstimators([]string{"mcv", "collision"})
+			for r := 0; r < rounds; r++ {
+				// A fresh copy per run turns every input into short-lived
+				// garbage once its assessment returns.
+				got, err := assessment.AssessNonIID(append([]byte(nil), data...), 8)
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.Equal(t, want.MinEntropy, got.MinEntropy)
+				assert.Equal(t, want.Histogram, got.Histogram)
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	churn.Wait()
+}
@@ -0,0 +1,66 @@
+//go:build cgo && !nocgo && !teststub && faultinject
+
+// These tests need a wrapper built with FAULT_INJECTION=1; run them with
+// make test-faultinject.
+
+package entropy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCGOBridge_UnterminatedErrorMessage(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	data := randomSamples(1000, 8, 1)
+	data[0] = 0xFA
+	_, iidErr := assessment.AssessIID(data, 8)
+	_, nonIIDErr := assessment.AssessNonIID(data, 8)
+	_, lrsErr := LongestRepeatedSubstring(data, 8)
+	for _, err := range []error{iidErr, nonIIDErr, lrsErr} {
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrCFunction))
+
+		var entropyErr *EntropyError
+		require.True(t, errors.As(err, &entropyErr))
+		assert.True(t, strings.HasPrefix(entropyErr.Msg, "code=-1, message=injected fault red  injected fault red "), entropyErr.Msg)
+		assert.True(t, strings.HasSuffix(entropyErr.Msg, "..."), "the 512-byte buffer is truncated")
+		assert.NotContains(t, err.Error(), "\n")
+		assert.NotContains(t, err.Error(), "\x1b")
+		assert.LessOrEqual(t, len(entropyErr.Msg), len("code=-1, message=")+maxCErrorMessage)
+	}
+}
+
+func TestCGOBridge_CExceptions(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	tests := []struct {
+		sentinel byte
+		want     error
+		msg      string
+	}{
+		{0xFB, ErrMemoryAllocation, "code=-3, message=Out of memory: std::bad_alloc"},
+		{0xFC, ErrCException, "code=-4, message=Logic error: injected logic_error"},
+		{0xFD, ErrCException, "code=-5, message=Runtime error: injected runtime_error"},
+		{0xFE, ErrCException, "code=-6, message=Unknown exception occurred"},
+	}
+	for _, tt := range tests {
+		data := randomSamples(1000, 8, 1)
+		data[0] = tt.sentinel
+		_, iidErr := assessment.AssessIID(data, 8)
+		_, nonIIDErr := assessment.AssessNonIID(data, 8)
+		_, lrsErr := LongestRepeatedSubstring(data, 8)
+		for _, err := range []error{iidErr, nonIIDErr, lrsErr} {
+			require.Error(t, err, "the process survives the exception")
+			assert.True(t, errors.Is(err, tt.want))
+			assert.Contains(t, err.Error(), tt.msg)
+		}
+	}
+}
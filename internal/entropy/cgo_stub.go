@@ -3,43 +3,225 @@
 // This file provides deterministic stub implementations of the CGO-backed
 // entropy calculation functions. It is compiled only when the "teststub" build
 // tag is active, enabling unit tests to run without the C++ library and
-// toolchain. Specific first-byte sentinel values (0xFF, 0xEE) trigger error
-// and edge-case paths for testing purposes.
+// toolchain. Specific first-byte sentinel values (0xFF, 0xEE, 0xDD, 0xCC)
+// trigger error, edge-case, crash, and hang paths for testing purposes; 0xBB
+// makes the IID Chi-Square and Permutation tests fail, 0xFA reports an
+// error through an unterminated C message buffer, and 0xFB to 0xFE report
+// the C++ exceptions the fault-injection build of the wrapper throws.
 
 package entropy
 
-import "math"
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// cgoEnabled reports that the NIST library is replaced by this stub.
+const cgoEnabled = false
+
+// backend reports that the stub returns fixed results.
+const backend = BackendStub
+
+// stubVersion is reported for both library versions in stub builds.
+const stubVersion = "stub"
+
+func libraryVersions() (tool, wrapper string) {
+	return stubVersion, stubVersion
+}
+
+// stubCrashExitCode mirrors the exit status of a process killed by SIGABRT.
+const stubCrashExitCode = 134
+
+// simulateCrash mimics the NIST code aborting on a pathological input. Inside
+// an isolated child the process terminates without a response; in-process the
+// crash is reported as an error so the test binary itself survives.
+func simulateCrash(op string) error {
+	if inIsolatedChild {
+		os.Exit(stubCrashExitCode)
+	}
+	return newError(op, ErrAssessmentCrashed, "stub simulated abort")
+}
+
+// stubHangDuration is how long the 0xCC sentinel blocks, standing in for a
+// pathological input that keeps the NIST code busy far beyond any timeout.
+const stubHangDuration = time.Minute
+
+// stubErrorMessageSize mirrors the error_message buffer of the C
+// EntropyResult in wrapper.h.
+const stubErrorMessageSize = 512
+
+// stubMalformedError fills a copy of the wrapper's error message buffer with
+// line breaks and ANSI escapes, leaves it without a terminating NUL like the
+// fault-injection build of the wrapper, and converts it as the bridge does.
+func stubMalformedError(op string) error {
+	const pattern = "injected fault\n\x1b[31mred\x1b[0m\r\t"
+	var buf [stubErrorMessageSize]byte
+	for i := range buf {
+		buf[i] = pattern[i%len(pattern)]
+	}
+	return wrapCError(op, -1, cErrorMessage(string(buf[:])))
+}
+
+// stubExceptions mirrors the errors that the fault-injection build of the
+// wrapper reports for the exceptions it throws, keyed by sentinel byte.
+var stubExceptions = map[byte]struct {
+	code    int
+	message string
+}{
+	0xFB: {cErrorOutOfMemory, "Out of memory: std::bad_alloc"},
+	0xFC: {cErrorLogic, "Logic error: injected logic_error"},
+	0xFD: {cErrorRuntime, "Runtime error: injected runtime_error"},
+	0xFE: {cErrorUnknown, "Unknown exception occurred"},
+}
+
+// stubFault returns the error injected by the sentinel byte of data, or nil.
+func stubFault(op string, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if data[0] == 0xFA {
+		return stubMalformedError(op)
+	}
+	if exc, ok := stubExceptions[data[0]]; ok {
+		return wrapCError(op, exc.code, exc.message)
+	}
+	return nil
+}
+
+// stubEstimator pairs a mock estimator result with its selection bit.
+type stubEstimator struct {
+	bit    uint32
+	result EstimatorResult
+}
 
 // stubIIDEstimators returns mock IID estimator results.
-func stubIIDEstimators() []EstimatorResult {
-	return []EstimatorResult{
-		{Name: "Most Common Value", EntropyEstimate: 7.6, Passed: true, IsEntropyValid: true},
-		{Name: "Chi-Square Tests", EntropyEstimate: -1.0, Passed: true, IsEntropyValid: false},
-		{Name: "Length of Longest Repeated Substring Test", EntropyEstimate: -1.0, Passed: true, IsEntropyValid: false},
-		{Name: "Permutation Tests", EntropyEstimate: -1.0, Passed: true, IsEntropyValid: false},
+func stubIIDEstimators() []stubEstimator {
+	return []stubEstimator{
+		{estimatorMCV, EstimatorResult{Name: "Most Common Value", ID: EstimatorMCV, EntropyEstimate: 7.6, Passed: true, IsEntropyValid: true}},
+		{estimatorChiSquare, EstimatorResult{Name: "Chi-Square Tests", ID: EstimatorChiSquare, EntropyEstimate: -1.0, Passed: true, IsEntropyValid: false}},
+		{estimatorLRS, EstimatorResult{Name: "Length of Longest Repeated Substring Test", ID: EstimatorLRS, EntropyEstimate: -1.0, Passed: true, IsEntropyValid: false}},
+		{estimatorPermutation, EstimatorResult{Name: "Permutation Tests", ID: EstimatorPermutation, EntropyEstimate: -1.0, Passed: true, IsEntropyValid: false}},
 	}
 }
 
 // stubNonIIDEstimators returns mock Non-IID estimator results.
-func stubNonIIDEstimators() []EstimatorResult {
-	return []EstimatorResult{
-		{Name: "Most Common Value", EntropyEstimate: 6.8, Passed: true, IsEntropyValid: true},
-		{Name: "Collision Test", EntropyEstimate: 6.9, Passed: true, IsEntropyValid: true},
-		{Name: "Markov Test", EntropyEstimate: 6.7, Passed: true, IsEntropyValid: true},
-		{Name: "Compression Test", EntropyEstimate: 6.5, Passed: true, IsEntropyValid: true},
-		{Name: "t-Tuple Test", EntropyEstimate: 6.6, Passed: true, IsEntropyValid: true},
-		{Name: "LRS Test", EntropyEstimate: 6.8, Passed: true, IsEntropyValid: true},
-		{Name: "Multi Most Common in Window Test", EntropyEstimate: 6.7, Passed: true, IsEntropyValid: true},
-		{Name: "Lag Prediction Test", EntropyEstimate: 6.9, Passed: true, IsEntropyValid: true},
-		{Name: "Multi Markov Model with Counting Test", EntropyEstimate: 6.6, Passed: true, IsEntropyValid: true},
-		{Name: "LZ78Y Test", EntropyEstimate: 6.5, Passed: true, IsEntropyValid: true},
-	}
-}
-
-func calculateIIDEntropy(data []byte, bitsPerSymbol int, verbose int) (*Result, error) {
+func stubNonIIDEstimators() []stubEstimator {
+	return []stubEstimator{
+		{estimatorMCV, EstimatorResult{Name: "Most Common Value", ID: EstimatorMCV, EntropyEstimate: 6.8, Passed: true, IsEntropyValid: true}},
+		{estimatorCollision, EstimatorResult{Name: "Collision Test", ID: EstimatorCollision, EntropyEstimate: 6.9, Passed: true, IsEntropyValid: true}},
+		{estimatorMarkov, EstimatorResult{Name: "Markov Test", ID: EstimatorMarkov, EntropyEstimate: 6.7, Passed: true, IsEntropyValid: true}},
+		{estimatorCompression, EstimatorResult{Name: "Compression Test", ID: EstimatorCompression, EntropyEstimate: 6.5, Passed: true, IsEntropyValid: true}},
+		{estimatorTTuple, EstimatorResult{Name: "t-Tuple Test", ID: EstimatorTTuple, EntropyEstimate: 6.6, Passed: true, IsEntropyValid: true}},
+		{estimatorLRS, EstimatorResult{Name: "LRS Test", ID: EstimatorLRS, EntropyEstimate: 6.8, Passed: true, IsEntropyValid: true}},
+		{estimatorMultiMCW, EstimatorResult{Name: "Multi Most Common in Window Test", ID: EstimatorMultiMCW, EntropyEstimate: 6.7, Passed: true, IsEntropyValid: true}},
+		{estimatorLag, EstimatorResult{Name: "Lag Prediction Test", ID: EstimatorLag, EntropyEstimate: 6.9, Passed: true, IsEntropyValid: true}},
+		{estimatorMultiMMC, EstimatorResult{Name: "Multi Markov Model with Counting Test", ID: EstimatorMultiMMC, EntropyEstimate: 6.6, Passed: true, IsEntropyValid: true}},
+		{estimatorLZ78Y, EstimatorResult{Name: "LZ78Y Test", ID: EstimatorLZ78Y, EntropyEstimate: 6.5, Passed: true, IsEntropyValid: true}},
+	}
+}
+
+// stubWordSize resolves a bitsPerSymbol of 0 to the detected word size, as
+// the wrapper does while preparing the data.
+func stubWordSize(data []byte, bitsPerSymbol int) int {
+	if bitsPerSymbol == 0 {
+		return detectWordSize(data)
+	}
+	return bitsPerSymbol
+}
+
+// stubHistogram counts the symbol values masked to the word size, as the
+// wrapper does while preparing the data.
+func stubHistogram(data []byte, bitsPerSymbol int) []uint64 {
+	symbolMask := byte(1<<uint(stubWordSize(data, bitsPerSymbol)) - 1)
+	histogram := make([]uint64, HistogramSize)
+	for _, b := range data {
+		histogram[b&symbolMask]++
+	}
+	return histogram
+}
+
+// stubLSBFirstShift is subtracted from HBitstring for LSBFirst so that tests
+// can observe the bit order reaching the library; the stub builds no bitstring.
+const stubLSBFirstShift = 0.25
+
+// applyStubBitOrder adjusts the bitstring entropy for the requested order.
+func applyStubBitOrder(result *Result, order BitOrder) *Result {
+	if order == LSBFirst {
+		result.HBitstring -= stubLSBFirstShift
+	}
+	return result
+}
+
+// applyStubPermutation reports the rounds and seed requested for the
+// permutation tests in their details so that tests can observe the options
+// reaching the library; the stub shuffles nothing.
+func applyStubPermutation(result *Result, perm permutationOptions) *Result {
+	rounds := perm.rounds
+	if rounds <= 0 {
+		rounds = PermutationRounds
+	}
+	for i := range result.Estimators {
+		if result.Estimators[i].Name == "Permutation Tests" {
+			result.Estimators[i].Details = map[string]float64{"rounds": float64(rounds)}
+			if perm.hasSeed {
+				result.Estimators[i].Details["seed"] = float64(perm.seed)
+			}
+		}
+	}
+	return result
+}
+
+// applyStubMask keeps the estimators selected by mask. For a subset, the
+// entropy fields become the minimum over the selected valid estimates, as the
+// wrapper computes them from the estimators it actually ran.
+func applyStubMask(result *Result, all []stubEstimator, mask uint32) *Result {
+	minEstimate := math.Inf(1)
+	for _, e := range all {
+		if mask != 0 && mask&e.bit == 0 {
+			continue
+		}
+		result.Estimators = append(result.Estimators, e.result)
+		if e.result.IsEntropyValid {
+			minEstimate = math.Min(minEstimate, e.result.EntropyEstimate)
+		}
+	}
+	if mask != 0 && !math.IsInf(minEstimate, 1) {
+		result.MinEntropy = minEstimate
+		result.HOriginal = minEstimate
+		result.HAssessed = minEstimate
+	}
+	return result
+}
+
+// stubProgress reports the phases the wrapper would run for mask, followed
+// by ProgressDone.
+func stubProgress(progress ProgressFunc, testType TestType, mask uint32) {
+	tracker := newProgressTracker(progress, testType, mask)
+	if tracker == nil {
+		return
+	}
+	for _, phase := range progressPhases(testType, mask) {
+		tracker.start(phase)
+	}
+	tracker.done()
+}
+
+func calculateIIDEntropy(data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, perm permutationOptions, progress ProgressFunc) (*Result, error) {
+	if len(data) > 0 && data[0] == 0xDD {
+		return nil, simulateCrash("calculateIIDEntropy")
+	}
+	if len(data) > 0 && data[0] == 0xCC {
+		time.Sleep(stubHangDuration)
+	}
 	if len(data) > 0 && data[0] == 0xFF {
 		return nil, newError("calculateIIDEntropy", ErrInvalidData, "stub failure")
 	}
+	if err := stubFault("calculateIIDEntropy", data); err != nil {
+		return nil, err
+	}
 	if len(data) > 0 && data[0] == 0xEE {
 		return &Result{
 			MinEntropy:   math.Inf(1),
@@ -51,21 +233,40 @@ func calculateIIDEntropy(data []byte, bitsPerSymbol int, verbose int) (*Result,
 			Estimators:   nil,
 		}, nil
 	}
-	return &Result{
+	stubProgress(progress, IID, mask)
+	estimators := stubIIDEstimators()
+	if len(data) > 0 && data[0] == 0xBB {
+		for i := range estimators {
+			if estimators[i].bit == estimatorChiSquare || estimators[i].bit == estimatorPermutation {
+				estimators[i].result.Passed = false
+			}
+		}
+	}
+	return applyStubPermutation(applyStubBitOrder(applyStubMask(&Result{
 		MinEntropy:   7.5,
 		HOriginal:    7.6,
 		HBitstring:   7.1,
 		HAssessed:    7.5,
-		DataWordSize: bitsPerSymbol,
+		DataWordSize: stubWordSize(data, bitsPerSymbol),
 		TestType:     IID,
-		Estimators:   stubIIDEstimators(),
-	}, nil
+		Histogram:    stubHistogram(data, bitsPerSymbol),
+		RawJSON:      stubRawJSON(IID, stubWordSize(data, bitsPerSymbol)),
+	}, estimators, mask), order), perm), nil
 }
 
-func calculateNonIIDEntropy(data []byte, bitsPerSymbol int, verbose int) (*Result, error) {
+func calculateNonIIDEntropy(data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, progress ProgressFunc) (*Result, error) {
+	if len(data) > 0 && data[0] == 0xDD {
+		return nil, simulateCrash("calculateNonIIDEntropy")
+	}
+	if len(data) > 0 && data[0] == 0xCC {
+		time.Sleep(stubHangDuration)
+	}
 	if len(data) > 0 && data[0] == 0xFF {
 		return nil, newError("calculateNonIIDEntropy", ErrInvalidData, "stub failure")
 	}
+	if err := stubFault("calculateNonIIDEntropy", data); err != nil {
+		return nil, err
+	}
 	if len(data) > 0 && data[0] == 0xEE {
 		return &Result{
 			MinEntropy:   math.Inf(1),
@@ -77,13 +278,95 @@ func calculateNonIIDEntropy(data []byte, bitsPerSymbol int, verbose int) (*Resul
 			Estimators:   nil,
 		}, nil
 	}
-	return &Result{
+	stubProgress(progress, NonIID, mask)
+	return applyStubBitOrder(applyStubMask(&Result{
 		MinEntropy:   6.5,
 		HOriginal:    6.6,
 		HBitstring:   6.1,
 		HAssessed:    6.5,
-		DataWordSize: bitsPerSymbol,
+		DataWordSize: stubWordSize(data, bitsPerSymbol),
 		TestType:     NonIID,
-		Estimators:   stubNonIIDEstimators(),
-	}, nil
+		Histogram:    stubHistogram(data, bitsPerSymbol),
+		RawJSON:      stubRawJSON(NonIID, stubWordSize(data, bitsPerSymbol)),
+	}, stubNonIIDEstimators(), mask), order), nil
+}
+
+// stubRawIIDJSON and stubRawNonIIDJSON are abridged NIST tool documents
+// with the stub's aggregate values, in the layout the wrapper produces. The
+// verb is the data word size.
+const (
+	stubRawIIDJSON = `{
+   "IID" : true,
+   "commandline" : "",
+   "dateTimeStamp" : "",
+   "errorLevel" : 0,
+   "testCases" : [
+      {
+         "dataWordSize" : %d,
+         "hAssessed" : 7.5,
+         "hBitstring" : 7.1,
+         "hOriginal" : 7.6,
+         "mean" : 127.5,
+         "median" : 127.5,
+         "passedChiSquareTests" : true,
+         "passedIidPermutationTests" : true,
+         "passedLongestRepeatedSubstringTest" : true,
+         "permutationTestResults" : null,
+         "testCaseDesc" : ""
+      }
+   ],
+   "toolVersion" : "stub",
+   "type" : ""
+}
+`
+	stubRawNonIIDJSON = `{
+   "IID" : false,
+   "commandline" : "",
+   "dateTimeStamp" : "",
+   "errorLevel" : 0,
+   "testCases" : [
+      {
+         "hBitstring" : 6.1,
+         "hOriginal" : 6.8,
+         "testCaseDesc" : "Most Common Value"
+      },
+      {
+         "dataWordSize" : %d,
+         "hAssessed" : 6.5,
+         "hBitstring" : 6.1,
+         "hOriginal" : 6.6,
+         "testCaseDesc" : "Overall"
+      }
+   ],
+   "toolVersion" : "stub",
+   "type" : ""
+}
+`
+)
+
+// stubRawJSON returns the fixture document of testType for wordSize.
+func stubRawJSON(testType TestType, wordSize int) json.RawMessage {
+	doc := stubRawNonIIDJSON
+	if testType == IID {
+		doc = stubRawIIDJSON
+	}
+	return json.RawMessage(fmt.Sprintf(doc, wordSize))
+}
+
+// releaseRuntime has nothing to release in stub builds.
+func releaseRuntime() error {
+	return nil
+}
+
+// calculateLRS runs the pure-Go suffix-array implementation, so that stub
+// builds report real lengths and estimates. The 0xFF and fault-injection
+// sentinels fail as in the assessments.
+func calculateLRS(data []byte, bitsPerSymbol int) (LRSResult, error) {
+	if len(data) > 0 && data[0] == 0xFF {
+		return LRSResult{}, newError("calculateLRS", ErrInvalidData, "stub failure")
+	}
+	if err := stubFault("calculateLRS", data); err != nil {
+		return LRSResult{}, err
+	}
+	return nativeLRS("calculateLRS", data, bitsPerSymbol)
 }
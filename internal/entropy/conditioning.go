@@ -0,0 +1,101 @@
+package entropy
+
+import (
+	"fmt"
+	"math"
+)
+
+// ConditioningParams describes a vetted conditioning component in the terms
+// of SP 800-90B Section 3.1.5: it reads NIn bits, writes NOut bits, and its
+// narrowest internal width is NW bits.
+type ConditioningParams struct {
+	NIn  int `json:"n_in"`
+	NOut int `json:"n_out"`
+	NW   int `json:"nw"`
+}
+
+// Validate reports an ErrInvalidConditioning error unless the widths are
+// positive and satisfy NOut <= NW <= NIn.
+func (p ConditioningParams) Validate() error {
+	return p.validate("ConditioningParams.Validate")
+}
+
+// validate is Validate with the operation reported in the error.
+func (p ConditioningParams) validate(op string) error {
+	if p.NIn < 1 || p.NOut < 1 || p.NW < 1 {
+		return newError(op, ErrInvalidConditioning, fmt.Sprintf("widths must be positive, got n_in=%d n_out=%d nw=%d", p.NIn, p.NOut, p.NW))
+	}
+	if p.NOut > p.NW || p.NW > p.NIn {
+		return newError(op, ErrInvalidConditioning, fmt.Sprintf("need n_out <= nw <= n_in, got n_in=%d n_out=%d nw=%d", p.NIn, p.NOut, p.NW))
+	}
+	return nil
+}
+
+// ApplyConditioning returns the entropy of one output of a vetted
+// conditioning component, h_out = Output_Entropy(n_in, n_out, nw, h_in) of
+// SP 800-90B Section 3.1.5.1.2, given the entropy hIn in bits of one input.
+// hIn must be positive and at most NIn; the result is at most
+// min(hIn, NOut, NW).
+//
+// The formula is evaluated in the log domain, so the widths may exceed the
+// exponent range of a float64. The result differs from the arbitrary
+// precision NIST ea_conditioning tool only in the last bits of a float64.
+func ApplyConditioning(hIn float64, p ConditioningParams) (float64, error) {
+	const op = "ApplyConditioning"
+	if err := p.validate(op); err != nil {
+		return 0, err
+	}
+	if math.IsNaN(hIn) || hIn <= 0 || hIn > float64(p.NIn) {
+		return 0, newError(op, ErrInvalidConditioning, fmt.Sprintf("h_in must be in (0, %d], got %g", p.NIn, hIn))
+	}
+
+	n := min(p.NOut, p.NW)
+	shift := float64(p.NIn - n) // log2 of 2^(n_in - n)
+
+	// Step 1: P_high = 2^-h_in and P_low = (1 - P_high) / (2^n_in - 1),
+	// with 1 - 2^-h_in computed by Expm1 to keep its precision for small h_in.
+	log2PHigh := -hIn
+	log2PLow := math.Log2(-math.Expm1(-hIn*math.Ln2)) - float64(p.NIn) - math.Log1p(-math.Exp2(-float64(p.NIn)))/math.Ln2
+
+	// Step 3: psi = 2^(n_in - n) * P_low + P_high.
+	log2Psi := logAddExp2(shift+log2PLow, log2PHigh)
+
+	// Steps 4 and 5: U = 2^(n_in - n) + sqrt(2 n 2^(n_in - n) ln 2) and
+	// omega = U * P_low.
+	log2U := shift + math.Log1p(math.Sqrt(2*float64(n)*math.Ln2*math.Exp2(-shift)))/math.Ln2
+	log2Omega := log2U + log2PLow
+
+	// Step 6: Output_Entropy = -log2(max(psi, omega)); psi and omega are
+	// probabilities, so the result is not negative.
+	hOut := max(0, -max(log2Psi, log2Omega))
+	return min(hOut, hIn, float64(p.NOut), float64(p.NW)), nil
+}
+
+// ApplyNonVettedConditioning returns the entropy of one output of a
+// conditioning component that is not vetted, SP 800-90B Section 3.1.5.2:
+//
+//	h_out = min(Output_Entropy(n_in, n_out, nw, h_in), 0.999 * n_out, h' * n_out)
+//
+// where hPrime, h', is the entropy per bit of the conditioned output,
+// estimated by assessing that output with the Non-IID track. hIn is
+// validated as by ApplyConditioning; hPrime must be in [0, 1].
+func ApplyNonVettedConditioning(hIn, hPrime float64, p ConditioningParams) (float64, error) {
+	const op = "ApplyNonVettedConditioning"
+	if math.IsNaN(hPrime) || hPrime < 0 || hPrime > 1 {
+		return 0, newError(op, ErrInvalidConditioning, fmt.Sprintf("h' must be in [0, 1], got %g", hPrime))
+	}
+	hOut, err := ApplyConditioning(hIn, p)
+	if err != nil {
+		return 0, err
+	}
+	nOut := float64(p.NOut)
+	return min(hOut, 0.999*nOut, hPrime*nOut), nil
+}
+
+// logAddExp2 returns log2(2^a + 2^b) without overflowing.
+func logAddExp2(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log1p(math.Exp2(b-a))/math.Ln2
+}
@@ -0,0 +1,97 @@
+package entropy
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The expected values were computed from the formula of SP 800-90B Section
+// 3.1.5.1.2 with 400 significant digits.
+func TestApplyConditioning_OutputEntropy(t *testing.T) {
+	tests := []struct {
+		name string
+		p    ConditioningParams
+		hIn  float64
+		want float64
+	}{
+		{"full entropy input, n_in = n_out", ConditioningParams{NIn: 256, NOut: 256, NW: 256}, 256, 251.689764568727},
+		{"twice the output width", ConditioningParams{NIn: 512, NOut: 256, NW: 256}, 512, 256},
+		{"64 bits of headroom", ConditioningParams{NIn: 320, NOut: 256, NW: 256}, 320, 255.999999993672},
+		{"wide internal state", ConditioningParams{NIn: 384, NOut: 256, NW: 384}, 300, 256},
+		{"low input entropy", ConditioningParams{NIn: 256, NOut: 256, NW: 256}, 8, 8},
+		{"small widths", ConditioningParams{NIn: 64, NOut: 32, NW: 64}, 40, 31.9943754508074},
+		{"fractional input entropy", ConditioningParams{NIn: 8, NOut: 8, NW: 8}, 0.5, 0.497658435178292},
+		{"byte input", ConditioningParams{NIn: 16, NOut: 8, NW: 8}, 16, 7.72721396403504},
+		{"beyond the float64 exponent range", ConditioningParams{NIn: 4096, NOut: 256, NW: 512}, 4000, 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyConditioning(tt.hIn, tt.p)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+			assert.LessOrEqual(t, got, math.Min(tt.hIn, float64(tt.p.NOut)))
+		})
+	}
+}
+
+func TestApplyConditioning_RejectsInvalidParameters(t *testing.T) {
+	tests := []struct {
+		name string
+		p    ConditioningParams
+		hIn  float64
+	}{
+		{"zero width", ConditioningParams{NIn: 0, NOut: 256, NW: 256}, 1},
+		{"n_out above nw", ConditioningParams{NIn: 512, NOut: 256, NW: 128}, 1},
+		{"nw above n_in", ConditioningParams{NIn: 256, NOut: 256, NW: 512}, 1},
+		{"zero input entropy", ConditioningParams{NIn: 512, NOut: 256, NW: 256}, 0},
+		{"input entropy above n_in", ConditioningParams{NIn: 512, NOut: 256, NW: 256}, 513},
+		{"NaN input entropy", ConditioningParams{NIn: 512, NOut: 256, NW: 256}, math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ApplyConditioning(tt.hIn, tt.p)
+			assert.True(t, errors.Is(err, ErrInvalidConditioning), "%v", err)
+		})
+	}
+
+	assert.NoError(t, ConditioningParams{NIn: 512, NOut: 256, NW: 256}.Validate())
+	assert.True(t, errors.Is(ConditioningParams{NIn: 128, NOut: 256, NW: 256}.Validate(), ErrInvalidConditioning))
+}
+
+// Worked from the formulas of SP 800-90B Sections 3.1.5.1.2 and 3.1.5.2:
+// h_out is the smallest of the vetted output entropy, 0.999 * n_out and
+// h' * n_out.
+func TestApplyNonVettedConditioning(t *testing.T) {
+	tests := []struct {
+		name   string
+		p      ConditioningParams
+		hIn    float64
+		hPrime float64
+		want   float64
+	}{
+		{"full entropy output is capped at 0.999 n_out", ConditioningParams{NIn: 512, NOut: 256, NW: 256}, 512, 1, 255.744},
+		{"h' limits the output", ConditioningParams{NIn: 512, NOut: 256, NW: 256}, 512, 0.9, 230.4},
+		{"the input limits the output", ConditioningParams{NIn: 256, NOut: 256, NW: 256}, 8, 0.95, 8},
+		{"vetted formula below both caps", ConditioningParams{NIn: 256, NOut: 256, NW: 256}, 256, 1, 251.689764568727},
+		{"byte output", ConditioningParams{NIn: 16, NOut: 8, NW: 8}, 16, 0.97, 7.72721396403504},
+		{"zero h'", ConditioningParams{NIn: 512, NOut: 256, NW: 256}, 512, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyNonVettedConditioning(tt.hIn, tt.hPrime, tt.p)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+
+	for _, hPrime := range []float64{-0.1, 1.5, math.NaN()} {
+		_, err := ApplyNonVettedConditioning(512, hPrime, ConditioningParams{NIn: 512, NOut: 256, NW: 256})
+		assert.True(t, errors.Is(err, ErrInvalidConditioning), "%v", err)
+	}
+	_, err := ApplyNonVettedConditioning(0, 1, ConditioningParams{NIn: 512, NOut: 256, NW: 256})
+	assert.True(t, errors.Is(err, ErrInvalidConditioning), "%v", err)
+}
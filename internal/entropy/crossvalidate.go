@@ -0,0 +1,147 @@
+package entropy
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// DefaultCrossValidationTolerance is the absolute difference in bits per
+// sample up to which CrossValidate considers two estimates to agree.
+const DefaultCrossValidationTolerance = 1e-6
+
+// crossValidationMask selects the estimators CrossValidate compares: every
+// estimator with a pure-Go implementation except the IID permutation tests,
+// whose outcome depends on random shuffles.
+func crossValidationMask(testType TestType) uint32 {
+	return nativeEstimatorsFor(testType) &^ estimatorPermutation
+}
+
+// EstimatorDelta compares the output of one estimator, or one of the
+// aggregate entropies H_original, H_bitstring and H_assessed, between the
+// linked backend and the pure-Go estimators.
+type EstimatorDelta struct {
+	Name      string  `json:"name"`
+	Reference float64 `json:"reference"` // Value from the linked backend
+	Native    float64 `json:"native"`    // Value from the pure-Go estimators
+	AbsDelta  float64 `json:"abs_delta"` // |Native - Reference|
+	RelDelta  float64 `json:"rel_delta"` // AbsDelta relative to the larger magnitude, 0 when both are 0
+	Test      bool    `json:"test"`      // Pass/fail test: the values are 1 for pass and 0 for fail
+	Within    bool    `json:"within"`    // AbsDelta is at most the tolerance
+}
+
+// CrossValidation is the per-estimator comparison produced by CrossValidate.
+type CrossValidation struct {
+	TestType     TestType
+	Reference    string // Backend compared against the pure-Go estimators
+	DataWordSize int    // Bits per symbol used by the pure-Go estimators
+	Tolerance    float64
+	Deltas       []EstimatorDelta // Estimators in execution order, then the aggregate entropies
+	Passed       bool             // Every delta is within the tolerance
+}
+
+// CrossValidate runs the estimators of testType that have a pure-Go
+// implementation on data with both the linked backend and the pure-Go code,
+// and compares the results estimator by estimator. An estimate diverges when
+// the two values differ by more than tolerance bits per sample; a pass/fail
+// test diverges when the outcomes differ. The IID permutation tests are not
+// compared because their outcome depends on random shuffles. The estimator
+// selection, bit order, verbosity, timeout and isolation of a apply; the
+// submitter claim is ignored.
+//
+// Cross-validation needs both backends and fails with ErrEstimatorUnavailable
+// in pure-Go builds. In stub builds the reference values are the fixed stub
+// results.
+func (a *Assessment) CrossValidate(data []byte, bitsPerSymbol int, testType TestType, tolerance float64) (*CrossValidation, error) {
+	return a.CrossValidateContext(context.Background(), data, bitsPerSymbol, testType, tolerance)
+}
+
+// CrossValidateContext is like CrossValidate but honours cancellation of ctx
+// while the linked backend runs.
+func (a *Assessment) CrossValidateContext(ctx context.Context, data []byte, bitsPerSymbol int, testType TestType, tolerance float64) (*CrossValidation, error) {
+	const op = "CrossValidate"
+	if backend == BackendGo {
+		return nil, newError(op, ErrEstimatorUnavailable, "cross-validation needs the NIST library, this is a pure-Go build")
+	}
+	if testType != IID && testType != NonIID {
+		return nil, newError(op, ErrInvalidData, "invalid test type")
+	}
+	if bitsPerSymbol < 0 || bitsPerSymbol > 8 {
+		return nil, newError(op, ErrInvalidBitsPerSymbol, fmt.Sprintf("got %d", bitsPerSymbol))
+	}
+	if len(data) == 0 {
+		return nil, newError(op, ErrInvalidData, "data is empty")
+	}
+	if math.IsNaN(tolerance) || math.IsInf(tolerance, 0) || tolerance < 0 {
+		return nil, newError(op, ErrInvalidTolerance, fmt.Sprintf("got %g", tolerance))
+	}
+	if err := a.validateBitOrder(op); err != nil {
+		return nil, err
+	}
+
+	mask, _, err := estimatorMask(op, testType, a.estimators)
+	if err != nil {
+		return nil, err
+	}
+	if mask == 0 {
+		mask = crossValidationMask(testType)
+	} else {
+		mask &= crossValidationMask(testType)
+	}
+	if mask == 0 {
+		return nil, newError(op, ErrEstimatorUnavailable, "none of the selected estimators can be cross-validated")
+	}
+
+	reference, err := a.execute(ctx, testType, data, bitsPerSymbol, mask)
+	if err != nil {
+		return nil, err
+	}
+	native, err := calculateNative(op, testType, data, bitsPerSymbol, a.verbose, mask, a.bitOrder, permutationOptions{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cv := &CrossValidation{
+		TestType:     testType,
+		Reference:    backend,
+		DataWordSize: native.DataWordSize,
+		Tolerance:    tolerance,
+		Passed:       true,
+	}
+	add := func(d EstimatorDelta) {
+		d.AbsDelta = math.Abs(d.Native - d.Reference)
+		if scale := math.Max(math.Abs(d.Reference), math.Abs(d.Native)); scale > 0 {
+			d.RelDelta = d.AbsDelta / scale
+		}
+		d.Within = d.AbsDelta <= tolerance
+		cv.Passed = cv.Passed && d.Within
+		cv.Deltas = append(cv.Deltas, d)
+	}
+
+	for _, n := range native.Estimators {
+		r, ok := findEstimator(reference.Estimators, n.Name)
+		if !ok {
+			return nil, newError(op, ErrEstimatorUnavailable, fmt.Sprintf("the %s backend did not report %q", backend, n.Name))
+		}
+		if n.IsEntropyValid {
+			add(EstimatorDelta{Name: n.Name, Reference: r.EntropyEstimate, Native: n.EntropyEstimate})
+		} else {
+			add(EstimatorDelta{Name: n.Name, Reference: PassValue(r.Passed), Native: PassValue(n.Passed), Test: true})
+		}
+	}
+	if mask&^iidTestMask != 0 {
+		add(EstimatorDelta{Name: "H_original", Reference: reference.HOriginal, Native: native.HOriginal})
+		add(EstimatorDelta{Name: "H_bitstring", Reference: reference.HBitstring, Native: native.HBitstring})
+		add(EstimatorDelta{Name: "H_assessed", Reference: reference.HAssessed, Native: native.HAssessed})
+	}
+	return cv, nil
+}
+
+// PassValue encodes a test outcome as 1 for pass and 0 for fail, the values
+// an EstimatorDelta of a pass/fail test compares.
+func PassValue(passed bool) float64 {
+	if passed {
+		return 1
+	}
+	return 0
+}
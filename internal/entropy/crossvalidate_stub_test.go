@@ -0,0 +1,121 @@
+//go:build teststub
+
+package entropy
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deltaNames returns the names of the compared estimators in order.
+func deltaNames(deltas []EstimatorDelta) []string {
+	names := make([]string, len(deltas))
+	for i, d := range deltas {
+		names[i] = d.Name
+	}
+	return names
+}
+
+func TestCrossValidate_StubDivergesFromGo(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	cv, err := assessment.CrossValidate(randomSamples(5000, 4, 1), 4, NonIID, DefaultCrossValidationTolerance)
+	require.NoError(t, err)
+	assert.Equal(t, BackendStub, cv.Reference)
+	assert.Equal(t, NonIID, cv.TestType)
+	assert.Equal(t, 4, cv.DataWordSize)
+	assert.False(t, cv.Passed)
+	assert.Equal(t, []string{
+		"Most Common Value", "Collision Test", "t-Tuple Test", "LRS Test",
+		"Multi Most Common in Window Test", "Lag Prediction Test",
+		"Multi Markov Model with Counting Test", "LZ78Y Test",
+		"H_original", "H_bitstring", "H_assessed",
+	}, deltaNames(cv.Deltas))
+
+	mcv := cv.Deltas[0]
+	assert.Equal(t, 6.8, mcv.Reference)
+	assert.InDelta(t, math.Abs(mcv.Native-6.8), mcv.AbsDelta, 1e-12)
+	assert.InDelta(t, mcv.AbsDelta/6.8, mcv.RelDelta, 1e-12, "the stub value is the larger one")
+	assert.False(t, mcv.Within)
+	assert.False(t, mcv.Test)
+}
+
+func TestCrossValidate_Tolerance(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetEstimators([]string{"mcv"})
+	data := randomSamples(10000, 8, 2)
+
+	cv, err := assessment.CrossValidate(data, 8, IID, 8)
+	require.NoError(t, err)
+	assert.True(t, cv.Passed)
+	assert.Equal(t, []string{"Most Common Value", "H_original", "H_bitstring", "H_assessed"}, deltaNames(cv.Deltas))
+	for _, d := range cv.Deltas {
+		assert.True(t, d.Within, d.Name)
+	}
+
+	// The stub reports 7.6 bits for the Most Common Value estimate; random
+	// bytes give a little less.
+	mcv := cv.Deltas[0]
+	cv, err = assessment.CrossValidate(data, 8, IID, mcv.AbsDelta)
+	require.NoError(t, err)
+	assert.True(t, cv.Deltas[0].Within)
+	assert.False(t, cv.Passed, "H_bitstring of the stub is not a per-bit value")
+}
+
+func TestCrossValidate_ComparesTestOutcomes(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetEstimators([]string{"chi-square", "permutation"})
+	data := randomSamples(5000, 4, 3)
+
+	cv, err := assessment.CrossValidate(data, 4, IID, 0)
+	require.NoError(t, err)
+	require.Len(t, cv.Deltas, 1, "the permutation tests are not compared")
+	assert.Equal(t, EstimatorDelta{Name: "Chi-Square Tests", Reference: 1, Native: 1, Test: true, Within: true}, cv.Deltas[0])
+	assert.True(t, cv.Passed)
+
+	// 0xBB makes the stub fail the Chi-Square tests.
+	data[0] = 0xBB
+	cv, err = assessment.CrossValidate(data, 4, IID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, cv.Deltas[0].Reference)
+	assert.False(t, cv.Passed)
+}
+
+func TestCrossValidate_Errors(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	data := randomSamples(100, 2, 4)
+
+	for _, tol := range []float64{-1, math.NaN(), math.Inf(1)} {
+		_, err := assessment.CrossValidate(data, 2, NonIID, tol)
+		assert.True(t, errors.Is(err, ErrInvalidTolerance), "tolerance %g", tol)
+	}
+
+	_, err := assessment.CrossValidate(data, 9, NonIID, 0)
+	assert.True(t, errors.Is(err, ErrInvalidBitsPerSymbol))
+
+	_, err = assessment.CrossValidate(nil, 2, NonIID, 0)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+
+	_, err = assessment.CrossValidate(data, 2, TestType(7), 0)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+
+	assessment.SetEstimators([]string{"markov"})
+	_, err = assessment.CrossValidate(data, 2, NonIID, 0)
+	assert.True(t, errors.Is(err, ErrEstimatorUnavailable))
+
+	assessment.SetEstimators([]string{"permutation"})
+	_, err = assessment.CrossValidate(data, 2, IID, 0)
+	assert.True(t, errors.Is(err, ErrEstimatorUnavailable))
+
+	assessment.SetEstimators([]string{"bogus"})
+	_, err = assessment.CrossValidate(data, 2, IID, 0)
+	assert.True(t, errors.Is(err, ErrInvalidEstimator))
+}
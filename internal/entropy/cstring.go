@@ -0,0 +1,72 @@
+package entropy
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxCErrorMessage is the longest message, in bytes, that the bridge passes
+// on from the C wrapper. Longer messages are truncated and end in "...".
+const maxCErrorMessage = 256
+
+// cBufferString returns the contents of a fixed-size C char buffer up to its
+// first NUL, or the whole buffer when the wrapper left it unterminated.
+func cBufferString(buf string) string {
+	if i := strings.IndexByte(buf, 0); i >= 0 {
+		return buf[:i]
+	}
+	return buf
+}
+
+// cErrorMessage converts the error_message buffer of a C EntropyResult into a
+// message that is safe for logs and gRPC status strings: the buffer is read
+// up to its first NUL or its end, ANSI escape sequences, invalid UTF-8 and
+// other non-printable characters are removed, line breaks and tabs become
+// spaces, and the result is truncated to maxCErrorMessage bytes.
+func cErrorMessage(buf string) string {
+	msg := cBufferString(buf)
+	var b strings.Builder
+	for i := 0; i < len(msg); {
+		r, size := utf8.DecodeRuneInString(msg[i:])
+		switch {
+		case r == '\x1b':
+			size = ansiSequenceLen(msg[i:])
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r == utf8.RuneError && size == 1, !unicode.IsPrint(r):
+		default:
+			b.WriteRune(r)
+		}
+		i += size
+	}
+	return truncateMessage(strings.TrimSpace(b.String()), maxCErrorMessage)
+}
+
+// ansiSequenceLen returns the length of the escape sequence at the start of
+// s, which begins with ESC: a CSI sequence such as "\x1b[31m" up to and
+// including its final byte, or the ESC alone otherwise.
+func ansiSequenceLen(s string) int {
+	if len(s) < 2 || s[1] != '[' {
+		return 1
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// truncateMessage shortens msg to at most limit bytes, cutting at a rune
+// boundary and marking the cut with "...".
+func truncateMessage(msg string, limit int) string {
+	if len(msg) <= limit {
+		return msg
+	}
+	cut := limit - len("...")
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut] + "..."
+}
@@ -0,0 +1,50 @@
+package entropy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCBufferString(t *testing.T) {
+	assert.Equal(t, "Most Common Value", cBufferString("Most Common Value\x00\x00garbage"))
+	assert.Equal(t, "", cBufferString("\x00abc"))
+	assert.Equal(t, "unterminated", cBufferString("unterminated"))
+}
+
+func TestCErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  string
+		want string
+	}{
+		{"plain", "Invalid bits_per_symbol: must be 0-8\x00junk", "Invalid bits_per_symbol: must be 0-8"},
+		{"line breaks", "first line\nsecond\r\nthird\tend", "first line second  third end"},
+		{"ansi", "\x1b[1;31mred\x1b[0m text\x1b", "red text"},
+		{"unterminated csi", "bad\x1b[31", "bad"},
+		{"controls", "a\x07b\x7fc\x1bd", "abcd"},
+		{"invalid utf-8", "bad \xff\xfe byte", "bad  byte"},
+		{"unicode", "Größe überschritten", "Größe überschritten"},
+		{"surrounding space", "\n  padded \t", "padded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cErrorMessage(tt.buf))
+		})
+	}
+}
+
+func TestCErrorMessage_Truncates(t *testing.T) {
+	got := cErrorMessage(strings.Repeat("x", 600))
+	assert.Len(t, got, maxCErrorMessage)
+	assert.True(t, strings.HasSuffix(got, "..."))
+
+	// The cut never splits a multi-byte rune.
+	got = cErrorMessage(strings.Repeat("ä", 300))
+	assert.LessOrEqual(t, len(got), maxCErrorMessage)
+	assert.Equal(t, strings.Repeat("ä", 126)+"...", got)
+
+	exact := strings.Repeat("y", maxCErrorMessage)
+	assert.Equal(t, exact, cErrorMessage(exact))
+}
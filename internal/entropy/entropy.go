@@ -1,11 +1,19 @@
 package entropy
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"os"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// abandonedAssessments counts in-process calculations that outlived their
+// caller. See AbandonedAssessments.
+var abandonedAssessments atomic.Int64
+
 const (
 	// MinRecommendedSamples is the minimum number of samples recommended by
 	// NIST SP 800-90B for reliable entropy estimation (1,000,000).
@@ -14,10 +22,11 @@ const (
 
 // AssessFile reads a binary file from disk and delegates to AssessReader for
 // entropy assessment using the specified test type and bits-per-symbol value.
+// Gzip files are decompressed transparently, see OpenFile.
 func (a *Assessment) AssessFile(filename string, bitsPerSymbol int, testType TestType) (*Result, error) {
-	file, err := os.Open(filename)
+	file, err := OpenFile(filename)
 	if err != nil {
-		return nil, newError("AssessFile", err, fmt.Sprintf("failed to open file: %s", filename))
+		return nil, err
 	}
 	defer file.Close()
 
@@ -27,6 +36,7 @@ func (a *Assessment) AssessFile(filename string, bitsPerSymbol int, testType Tes
 // AssessReader reads all data from the provided io.Reader and dispatches to
 // AssessIID or AssessNonIID based on the given test type.
 func (a *Assessment) AssessReader(r io.Reader, bitsPerSymbol int, testType TestType) (*Result, error) {
+	a.reportStage(ProgressReading, 0)
 	data, err := io.ReadAll(r)
 	if err != nil {
 		return nil, newError("AssessReader", err, "failed to read data")
@@ -46,6 +56,12 @@ func (a *Assessment) AssessReader(r io.Reader, bitsPerSymbol int, testType TestT
 // assessment. A bitsPerSymbol value of 0 triggers auto-detection; valid explicit
 // values are 1 through 8. The data slice must be non-empty.
 func (a *Assessment) AssessIID(data []byte, bitsPerSymbol int) (*Result, error) {
+	return a.AssessIIDContext(context.Background(), data, bitsPerSymbol)
+}
+
+// AssessIIDContext is like AssessIID but honours cancellation of ctx. In
+// subprocess isolation mode the child process is killed when ctx is done.
+func (a *Assessment) AssessIIDContext(ctx context.Context, data []byte, bitsPerSymbol int) (*Result, error) {
 	if bitsPerSymbol < 0 || bitsPerSymbol > 8 {
 		return nil, newError("AssessIID", ErrInvalidBitsPerSymbol, fmt.Sprintf("got %d", bitsPerSymbol))
 	}
@@ -54,17 +70,51 @@ func (a *Assessment) AssessIID(data []byte, bitsPerSymbol int) (*Result, error)
 		return nil, newError("AssessIID", ErrInvalidData, "data is empty")
 	}
 
-	if len(data) < MinRecommendedSamples && a.verbose > 0 {
-		fmt.Fprintf(os.Stderr, "Warning: data contains less than %d samples\n", MinRecommendedSamples)
+	if err := a.validateHSubmitter("AssessIID", bitsPerSymbol); err != nil {
+		return nil, err
+	}
+
+	if err := a.validateBitOrder("AssessIID"); err != nil {
+		return nil, err
+	}
+
+	data = a.truncateSamples(data)
+	data, err := a.strideSamples("AssessIID", data)
+	if err != nil {
+		return nil, err
+	}
+	data, err = a.normalizeSamples("AssessIID", data, bitsPerSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkMemory("AssessIID", IID, data, bitsPerSymbol); err != nil {
+		return nil, err
+	}
+
+	mask, selection, err := a.selectEstimators("AssessIID", IID)
+	if err != nil {
+		return nil, err
+	}
+	a.warnPermutation(mask)
+
+	if len(data) < MinRecommendedSamples {
+		a.warnf("data contains less than %d samples", MinRecommendedSamples)
 	}
 
-	return calculateIIDEntropy(data, bitsPerSymbol, a.verbose)
+	return a.calculate(ctx, IID, data, bitsPerSymbol, mask, selection)
 }
 
 // AssessNonIID performs a Non-IID entropy assessment using the ten estimators
 // defined in NIST SP 800-90B Section 6.3. A bitsPerSymbol value of 0 triggers
 // auto-detection; valid explicit values are 1 through 8.
 func (a *Assessment) AssessNonIID(data []byte, bitsPerSymbol int) (*Result, error) {
+	return a.AssessNonIIDContext(context.Background(), data, bitsPerSymbol)
+}
+
+// AssessNonIIDContext is like AssessNonIID but honours cancellation of ctx. In
+// subprocess isolation mode the child process is killed when ctx is done.
+func (a *Assessment) AssessNonIIDContext(ctx context.Context, data []byte, bitsPerSymbol int) (*Result, error) {
 	if bitsPerSymbol < 0 || bitsPerSymbol > 8 {
 		return nil, newError("AssessNonIID", ErrInvalidBitsPerSymbol, fmt.Sprintf("got %d", bitsPerSymbol))
 	}
@@ -73,9 +123,333 @@ func (a *Assessment) AssessNonIID(data []byte, bitsPerSymbol int) (*Result, erro
 		return nil, newError("AssessNonIID", ErrInvalidData, "data is empty")
 	}
 
-	if len(data) < MinRecommendedSamples && a.verbose > 0 {
-		fmt.Fprintf(os.Stderr, "Warning: data contains less than %d samples\n", MinRecommendedSamples)
+	if err := a.validateHSubmitter("AssessNonIID", bitsPerSymbol); err != nil {
+		return nil, err
+	}
+
+	if err := a.validateBitOrder("AssessNonIID"); err != nil {
+		return nil, err
+	}
+
+	data = a.truncateSamples(data)
+	data, err := a.strideSamples("AssessNonIID", data)
+	if err != nil {
+		return nil, err
+	}
+	data, err = a.normalizeSamples("AssessNonIID", data, bitsPerSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkMemory("AssessNonIID", NonIID, data, bitsPerSymbol); err != nil {
+		return nil, err
+	}
+
+	mask, selection, err := a.selectEstimators("AssessNonIID", NonIID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < MinRecommendedSamples {
+		a.warnf("data contains less than %d samples", MinRecommendedSamples)
+	}
+
+	return a.calculate(ctx, NonIID, data, bitsPerSymbol, mask, selection)
+}
+
+// iidTestMask selects the IID statistical tests without any estimator.
+const iidTestMask = estimatorChiSquare | estimatorLRS | estimatorPermutation
+
+// CheckIID runs only the IID statistical tests of SP 800-90B Section 5 (the
+// Chi-Square, LRS, and Permutation tests) and skips entropy estimation. It
+// reports whether the data passed every test, together with the per-test
+// results.
+func (a *Assessment) CheckIID(data []byte, bitsPerSymbol int) (bool, []EstimatorResult, error) {
+	return a.CheckIIDContext(context.Background(), data, bitsPerSymbol)
+}
+
+// CheckIIDContext is like CheckIID but honours cancellation of ctx and the
+// configured isolation mode and timeout.
+func (a *Assessment) CheckIIDContext(ctx context.Context, data []byte, bitsPerSymbol int) (bool, []EstimatorResult, error) {
+	if bitsPerSymbol < 0 || bitsPerSymbol > 8 {
+		return false, nil, newError("CheckIID", ErrInvalidBitsPerSymbol, fmt.Sprintf("got %d", bitsPerSymbol))
+	}
+
+	if len(data) == 0 {
+		return false, nil, newError("CheckIID", ErrInvalidData, "data is empty")
+	}
+
+	if err := a.validateBitOrder("CheckIID"); err != nil {
+		return false, nil, err
+	}
+
+	data = a.truncateSamples(data)
+	data, err := a.strideSamples("CheckIID", data)
+	if err != nil {
+		return false, nil, err
+	}
+	data, err = a.normalizeSamples("CheckIID", data, bitsPerSymbol)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if err := a.checkMemory("CheckIID", IID, data, bitsPerSymbol); err != nil {
+		return false, nil, err
+	}
+	a.warnPermutation(iidTestMask)
+
+	if len(data) < MinRecommendedSamples {
+		a.warnf("data contains less than %d samples", MinRecommendedSamples)
+	}
+
+	result, err := a.execute(ctx, IID, data, bitsPerSymbol, iidTestMask)
+	if err != nil {
+		return false, nil, err
+	}
+	if result.Partial {
+		a.warnf("only the IID tests with a pure-Go implementation ran; this is not a conforming SP 800-90B assessment")
+	}
+
+	passed := true
+	for _, test := range result.Estimators {
+		if !test.Passed {
+			passed = false
+		}
+	}
+	return passed, result.Estimators, nil
+}
+
+// calculate runs a validated assessment and derives the final entropy from
+// the estimator output and the submitter's claim.
+func (a *Assessment) calculate(ctx context.Context, testType TestType, data []byte, bitsPerSymbol int, mask uint32, selection []string) (*Result, error) {
+	result, err := a.execute(ctx, testType, data, bitsPerSymbol, mask)
+	if err != nil {
+		return nil, err
+	}
+	result.EstimatorSelection = selection
+	result.Backend = backend
+	result.ShannonEntropy = shannonEntropy(data, result.DataWordSize)
+	if result.DataWordSize >= 1 && result.DataWordSize <= 8 {
+		result.AlphabetSize = distinctSymbols(countSymbols(data, result.DataWordSize))
+		if need := alphabetBits(result.AlphabetSize); need < result.DataWordSize {
+			a.warnf("data has only %d distinct symbols, which fit in %d bits per symbol instead of %d", result.AlphabetSize, need, result.DataWordSize)
+		}
+	}
+	if !a.histogram {
+		result.Histogram = nil
+	}
+	if !a.rawJSON {
+		result.RawJSON = nil
+	}
+
+	if err := a.applyHSubmitter("calculate", result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// validateHSubmitter rejects a submitter claim that is negative, not finite,
+// or larger than an explicitly requested bits per symbol.
+func (a *Assessment) validateHSubmitter(op string, bitsPerSymbol int) error {
+	if !a.hasHSubmitter {
+		return nil
+	}
+	h := a.hSubmitter
+	if math.IsNaN(h) || math.IsInf(h, 0) || h < 0 {
+		return newError(op, ErrInvalidHSubmitter, fmt.Sprintf("got %g", h))
+	}
+	if bitsPerSymbol > 0 && h > float64(bitsPerSymbol) {
+		return newError(op, ErrInvalidHSubmitter, fmt.Sprintf("got %g for %d-bit symbols", h, bitsPerSymbol))
+	}
+	return nil
+}
+
+// validateBitOrder rejects a bit order other than MSBFirst or LSBFirst.
+func (a *Assessment) validateBitOrder(op string) error {
+	if a.bitOrder != MSBFirst && a.bitOrder != LSBFirst {
+		return newError(op, ErrInvalidBitOrder, fmt.Sprintf("got %d", a.bitOrder))
+	}
+	return nil
+}
+
+// selectEstimators resolves the configured estimator selection into a wrapper
+// mask. A non-empty selection triggers a warning because the resulting
+// min-entropy does not come from the full set of estimators.
+func (a *Assessment) selectEstimators(op string, testType TestType) (uint32, []string, error) {
+	mask, selection, err := estimatorMask(op, testType, a.estimators)
+	if err != nil {
+		return 0, nil, err
+	}
+	if mask != 0 {
+		a.warnf("running estimator subset (%s); this is not a conforming SP 800-90B assessment", strings.Join(selection, ", "))
+	}
+	return mask, selection, nil
+}
+
+// truncateSamples cuts data to the sample limit set with SetMaxSamples,
+// warning that the assessment of a prefix is not conforming.
+func (a *Assessment) truncateSamples(data []byte) []byte {
+	if a.maxSamples == 0 || len(data) <= a.maxSamples {
+		return data
+	}
+	a.warnf("assessing only the first %d of %d samples; this is not a conforming SP 800-90B assessment", a.maxSamples, len(data))
+	return data[:a.maxSamples]
+}
+
+// strideSamples keeps every k-th sample of data for the stride set with
+// SetStride, warning that the assessment of a subsample is not conforming.
+func (a *Assessment) strideSamples(op string, data []byte) ([]byte, error) {
+	if a.stride == 0 || a.stride == 1 {
+		return data, nil
+	}
+	strided, err := StrideSamples(data, a.stride)
+	if err != nil {
+		return nil, newError(op, ErrInvalidStride, fmt.Sprintf("got %d", a.stride))
+	}
+	a.warnf("assessing one in every %d samples, %d of %d; this is not a conforming SP 800-90B assessment", a.stride, len(strided), len(data))
+	return strided, nil
+}
+
+// warnPermutation warns about permutation settings that depart from SP 800-90B
+// when the permutation tests are part of the IID run selected by mask. The
+// NIST library has no such settings and ignores them.
+func (a *Assessment) warnPermutation(mask uint32) {
+	if mask != 0 && mask&estimatorPermutation == 0 {
+		return
+	}
+	rounds := a.GetPermutationRounds()
+	if cgoEnabled {
+		if rounds != PermutationRounds || a.hasPermSeed {
+			a.warnf("permutation rounds and seed only apply to pure-Go builds; the NIST library runs %d rounds", PermutationRounds)
+		}
+		return
+	}
+	if rounds != PermutationRounds {
+		a.warnf("running %d permutation rounds instead of %d; this is not a conforming SP 800-90B assessment", rounds, PermutationRounds)
+	}
+}
+
+// applyHSubmitter fills in HFinal as min(HAssessed, H_submitter). The claim
+// is checked again against the detected word size, which is only known once
+// auto-detection has run.
+func (a *Assessment) applyHSubmitter(op string, r *Result) error {
+	r.HFinal = r.HAssessed
+	r.HSubmitter = 0
+	r.HasHSubmitter = false
+	r.SubmitterBinding = false
+
+	if !a.hasHSubmitter {
+		return nil
+	}
+	if r.DataWordSize > 0 && a.hSubmitter > float64(r.DataWordSize) {
+		return newError(op, ErrInvalidHSubmitter, fmt.Sprintf("got %g for detected %d-bit symbols", a.hSubmitter, r.DataWordSize))
+	}
+
+	r.HSubmitter = a.hSubmitter
+	r.HasHSubmitter = true
+	if a.hSubmitter <= r.HAssessed {
+		r.HFinal = a.hSubmitter
+		r.SubmitterBinding = true
+	}
+	return nil
+}
+
+// execute dispatches a validated assessment either to the in-process CGO
+// bridge or to an isolated child process, depending on the isolation mode.
+func (a *Assessment) execute(ctx context.Context, testType TestType, data []byte, bitsPerSymbol int, mask uint32) (*Result, error) {
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, a.timeout, ErrAssessmentTimeout)
+		defer cancel()
+	}
+
+	if a.isolation == IsolationSubprocess {
+		a.reportStage(progressStage(testType), 0)
+		result, err := runIsolated(ctx, testType, data, bitsPerSymbol, a.verbose, mask, a.bitOrder, a.permutation())
+		if err == nil {
+			a.reportStage(ProgressDone, 100)
+		}
+		return result, err
+	}
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, newError("calculate", err, "assessment cancelled before start")
+	}
+	a.reportStage(progressStage(testType), 0)
+
+	if ctx.Done() == nil {
+		return calculateInProcess(testType, data, bitsPerSymbol, a.verbose, mask, a.bitOrder, a.permutation(), a.progress)
+	}
+
+	return calculateAbandonable(ctx, testType, data, bitsPerSymbol, a.verbose, mask, a.bitOrder, a.permutation(), a.progress)
+}
+
+// calculateAbandonable runs the in-process calculation on a separate goroutine
+// so that the caller can return when ctx is done. The C++ code cannot be
+// interrupted: an abandoned goroutine keeps its OpenMP thread team busy until
+// the computation completes on its own, but no longer reports progress.
+func calculateAbandonable(ctx context.Context, testType TestType, data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, perm permutationOptions, progress ProgressFunc) (*Result, error) {
+	type outcome struct {
+		result *Result
+		err    error
 	}
 
-	return calculateNonIIDEntropy(data, bitsPerSymbol, a.verbose)
+	var abandoned atomic.Bool
+	if report := progress; report != nil {
+		progress = func(phase string, percent float64) {
+			if !abandoned.Load() {
+				report(phase, percent)
+			}
+		}
+	}
+
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := calculateInProcess(testType, data, bitsPerSymbol, verbose, mask, order, perm, progress)
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-ctx.Done():
+	}
+
+	abandoned.Store(true)
+	abandonedAssessments.Add(1)
+	go func() {
+		<-done
+		abandonedAssessments.Add(-1)
+	}()
+
+	return nil, newError("calculate", context.Cause(ctx),
+		"in-process computation abandoned and still consuming CPU until it completes; use subprocess isolation to reclaim resources")
+}
+
+// AbandonedAssessments reports how many in-process computations are still
+// running after their caller gave up on them due to a timeout or cancellation.
+func AbandonedAssessments() int64 {
+	return abandonedAssessments.Load()
+}
+
+// calculateInProcess invokes the CGO bridge (or its test stub) for testType
+// and records the time the call took in Result.Timings. A zero mask runs all
+// estimators; perm only applies to IID assessments.
+func calculateInProcess(testType TestType, data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, perm permutationOptions, progress ProgressFunc) (*Result, error) {
+	defer enterRuntime()()
+	started := time.Now()
+	var result *Result
+	var err error
+	switch testType {
+	case IID:
+		result, err = calculateIIDEntropy(data, bitsPerSymbol, verbose, mask, order, perm, progress)
+	case NonIID:
+		result, err = calculateNonIIDEntropy(data, bitsPerSymbol, verbose, mask, order, progress)
+	default:
+		return nil, newError("calculate", ErrInvalidData, "invalid test type")
+	}
+	if err == nil {
+		result.Timings = map[string]time.Duration{testType.String(): time.Since(started)}
+	}
+	return result, err
 }
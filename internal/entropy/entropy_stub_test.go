@@ -3,8 +3,13 @@
 package entropy
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -45,3 +50,619 @@ func TestAssessFile_SuccessStub(t *testing.T) {
 	assert.Equal(t, 7.5, res.MinEntropy)
 	assert.Equal(t, IID, res.TestType)
 }
+
+func TestAssessFile_GzipMatchesPlain(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte{1, 2, 3, 4, 1, 2, 3, 1, 2, 1}
+	plain := filepath.Join(dir, "data.bin")
+	require.NoError(t, os.WriteFile(plain, data, 0o644))
+	compressed := filepath.Join(dir, "data.bin.gz")
+	writeGzip(t, compressed, data)
+
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetHistogram(true)
+
+	want, err := assessment.AssessFile(plain, 8, NonIID)
+	require.NoError(t, err)
+	got, err := assessment.AssessFile(compressed, 8, NonIID)
+	require.NoError(t, err)
+	want.Timings, got.Timings = nil, nil
+	assert.Equal(t, want, got)
+	assert.Equal(t, uint64(4), got.Histogram[1], "the samples are decompressed, not the gzip bytes")
+}
+
+func TestAssess_HSubmitterStub(t *testing.T) {
+	t.Run("no claim", func(t *testing.T) {
+		assessment := NewAssessment()
+		assessment.SetVerbose(0)
+
+		res, err := assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err)
+		assert.False(t, res.HasHSubmitter)
+		assert.False(t, res.SubmitterBinding)
+		assert.Equal(t, res.HAssessed, res.HFinal)
+	})
+
+	t.Run("claim is binding", func(t *testing.T) {
+		assessment := NewAssessment()
+		assessment.SetVerbose(0)
+		assessment.SetHSubmitter(4.0)
+
+		res, err := assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err)
+		assert.True(t, res.HasHSubmitter)
+		assert.Equal(t, 4.0, res.HSubmitter)
+		assert.Equal(t, 4.0, res.HFinal)
+		assert.True(t, res.SubmitterBinding)
+	})
+
+	t.Run("estimators are binding", func(t *testing.T) {
+		assessment := NewAssessment()
+		assessment.SetVerbose(0)
+		assessment.SetHSubmitter(8.0)
+
+		res, err := assessment.AssessIID([]byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err)
+		assert.True(t, res.HasHSubmitter)
+		assert.Equal(t, 7.5, res.HFinal)
+		assert.False(t, res.SubmitterBinding)
+	})
+
+	t.Run("isolated", func(t *testing.T) {
+		assessment := newIsolatedAssessment()
+		assessment.SetHSubmitter(2.0)
+
+		res, err := assessment.AssessIID([]byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err)
+		assert.Equal(t, 2.0, res.HFinal)
+		assert.True(t, res.SubmitterBinding)
+	})
+}
+
+func TestAssess_EstimatorSubsetStub(t *testing.T) {
+	t.Run("all estimators", func(t *testing.T) {
+		assessment := NewAssessment()
+		assessment.SetVerbose(0)
+
+		res, err := assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err)
+		assert.Nil(t, res.EstimatorSelection)
+		assert.Len(t, res.Estimators, 10)
+	})
+
+	t.Run("non-iid subset", func(t *testing.T) {
+		assessment := NewAssessment()
+		assessment.SetVerbose(0)
+		assessment.SetEstimators([]string{"markov", "mcv"})
+
+		res, err := assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"mcv", "markov"}, res.EstimatorSelection)
+		require.Len(t, res.Estimators, 2)
+		assert.Equal(t, "Most Common Value", res.Estimators[0].Name)
+		assert.Equal(t, "Markov Test", res.Estimators[1].Name)
+		assert.Equal(t, EstimatorMarkov, res.Estimators[1].ID)
+		assert.Equal(t, 6.7, res.MinEntropy)
+		assert.Equal(t, 6.7, res.HAssessed)
+	})
+
+	t.Run("config subset", func(t *testing.T) {
+		cfg := NewAssessment().Config()
+		cfg.Estimators = []string{"markov", "compression"}
+		assessment := NewAssessment().WithConfig(cfg)
+		assessment.SetVerbose(0)
+
+		res, err := assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"markov", "compression"}, res.EstimatorSelection)
+		require.Len(t, res.Estimators, 2)
+		assert.Equal(t, EstimatorMarkov, res.Estimators[0].ID)
+		assert.Equal(t, EstimatorCompression, res.Estimators[1].ID)
+	})
+
+	t.Run("iid subset", func(t *testing.T) {
+		assessment := NewAssessment()
+		assessment.SetVerbose(0)
+		assessment.SetEstimators([]string{"chi-square"})
+
+		res, err := assessment.AssessIID([]byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err)
+		require.Len(t, res.Estimators, 1)
+		assert.Equal(t, "Chi-Square Tests", res.Estimators[0].Name)
+	})
+
+	t.Run("isolated subset", func(t *testing.T) {
+		assessment := NewAssessment()
+		assessment.SetVerbose(0)
+		assessment.SetIsolation(IsolationSubprocess)
+		assessment.SetEstimators([]string{"lag"})
+
+		res, err := assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err)
+		require.Len(t, res.Estimators, 1)
+		assert.Equal(t, "Lag Prediction Test", res.Estimators[0].Name)
+		assert.Equal(t, 6.9, res.MinEntropy)
+	})
+}
+
+func TestCheckIIDStub(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	passed, tests, err := assessment.CheckIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.True(t, passed)
+	require.Len(t, tests, 3)
+	assert.Equal(t, "Chi-Square Tests", tests[0].Name)
+	assert.Equal(t, "Length of Longest Repeated Substring Test", tests[1].Name)
+	assert.Equal(t, "Permutation Tests", tests[2].Name)
+
+	passed, tests, err = assessment.CheckIID([]byte{0xBB, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.False(t, passed)
+	assert.False(t, tests[0].Passed)
+	assert.True(t, tests[1].Passed)
+	assert.False(t, tests[2].Passed)
+
+	_, _, err = assessment.CheckIID([]byte{0xFF, 2, 3, 4}, 8)
+	assert.Error(t, err)
+}
+
+func TestLongestRepeatedSubstringStub(t *testing.T) {
+	result, err := LongestRepeatedSubstring([]byte("abcXabc"), 8)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Length)
+
+	_, err = LongestRepeatedSubstring([]byte{0xFF, 2, 3, 4}, 8)
+	assert.ErrorIs(t, err, ErrInvalidData)
+}
+
+func TestLibraryInfoStub(t *testing.T) {
+	info := LibraryInfo()
+	assert.False(t, info.CGO)
+	assert.Equal(t, "stub", info.ToolVersion)
+	assert.Equal(t, "stub", info.WrapperVersion)
+	assert.Equal(t, []string{
+		"mcv", "collision", "markov", "compression", "t-tuple",
+		"lrs", "multi-mcw", "lag", "multi-mmc", "lz78y",
+		"chi-square", "permutation",
+	}, info.Estimators)
+	assert.Equal(t, BackendStub, info.Backend)
+	assert.Equal(t, ValidEstimatorNames(IID), info.IIDEstimators)
+	assert.Equal(t, ValidEstimatorNames(NonIID), info.NonIIDEstimators)
+}
+
+func TestResultBackendStub(t *testing.T) {
+	res, err := NewAssessment().AssessIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.Equal(t, BackendStub, res.Backend)
+	assert.False(t, res.Partial)
+}
+
+func TestResultTimingsStub(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	res, err := assessment.AssessIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.Len(t, res.Timings, 1)
+	assert.Contains(t, res.Timings, "IID")
+
+	res, err = assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.Len(t, res.Timings, 1)
+	assert.Contains(t, res.Timings, "Non-IID")
+}
+
+func TestSupportedEstimators_MatchReportedNames(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	for _, testType := range []TestType{IID, NonIID} {
+		var res *Result
+		var err error
+		if testType == IID {
+			res, err = assessment.AssessIID([]byte{1, 2, 3, 4}, 8)
+		} else {
+			res, err = assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+		}
+		require.NoError(t, err)
+
+		reported := make([]string, len(res.Estimators))
+		for i, e := range res.Estimators {
+			reported[i] = e.Name
+		}
+		assert.Equal(t, SupportedEstimators(testType), reported, testType.String())
+	}
+}
+
+func TestAssess_Histogram(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	res, err := assessment.AssessNonIID([]byte{1, 1, 2, 255}, 8)
+	require.NoError(t, err)
+	assert.Nil(t, res.Histogram)
+
+	assessment.SetHistogram(true)
+	res, err = assessment.AssessNonIID([]byte{1, 1, 2, 255}, 8)
+	require.NoError(t, err)
+	require.Len(t, res.Histogram, HistogramSize)
+	assert.Equal(t, uint64(2), res.Histogram[1])
+	assert.Equal(t, uint64(1), res.Histogram[2])
+	assert.Equal(t, uint64(1), res.Histogram[255])
+	assert.Equal(t, uint64(0), res.Histogram[0])
+
+	// Symbols are masked to the word size before counting.
+	res, err = assessment.AssessIID([]byte{0x12, 0x02, 0xF2, 0x05}, 4)
+	require.NoError(t, err)
+	require.Len(t, res.Histogram, HistogramSize)
+	assert.Equal(t, uint64(3), res.Histogram[2])
+	assert.Equal(t, uint64(1), res.Histogram[5])
+	for _, v := range res.Histogram[16:] {
+		assert.Zero(t, v)
+	}
+}
+
+func TestAssess_RawJSONStub(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	res, err := assessment.AssessNonIID([]byte{1, 1, 2, 255}, 8)
+	require.NoError(t, err)
+	assert.Nil(t, res.RawJSON)
+
+	assessment.SetRawJSON(true)
+	for _, assess := range []func([]byte, int) (*Result, error){assessment.AssessIID, assessment.AssessNonIID} {
+		res, err = assess([]byte{1, 1, 2, 255}, 8)
+		require.NoError(t, err)
+		require.NotEmpty(t, res.RawJSON)
+		assert.True(t, json.Valid(res.RawJSON))
+
+		var doc struct {
+			IID       bool
+			TestCases []map[string]any `json:"testCases"`
+		}
+		require.NoError(t, json.Unmarshal(res.RawJSON, &doc))
+		assert.Equal(t, res.TestType == IID, doc.IID)
+		require.NotEmpty(t, doc.TestCases)
+		assert.Equal(t, 8.0, doc.TestCases[len(doc.TestCases)-1]["dataWordSize"])
+	}
+}
+
+func TestAssess_WarnWriter(t *testing.T) {
+	var warnings bytes.Buffer
+	assessment := NewAssessment()
+	assessment.SetWarnWriter(&warnings)
+	assessment.SetEstimators([]string{"mcv"})
+
+	_, err := assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.Contains(t, warnings.String(), "Warning: data contains less than 1000000 samples")
+	assert.Contains(t, warnings.String(), "Warning: running estimator subset (mcv)")
+
+	warnings.Reset()
+	assessment.SetVerbose(0)
+	_, err = assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.Empty(t, warnings.String())
+}
+
+func TestAssess_PermutationOptionsStub(t *testing.T) {
+	var warnings bytes.Buffer
+	assessment := NewAssessment()
+	assessment.SetWarnWriter(&warnings)
+	assessment.SetEstimators([]string{"permutation"})
+
+	res, err := assessment.AssessIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	require.Len(t, res.Estimators, 1)
+	assert.Equal(t, map[string]float64{"rounds": PermutationRounds}, res.Estimators[0].Details)
+	assert.NotContains(t, warnings.String(), "permutation rounds")
+
+	assessment.SetPermutationRounds(100)
+	assessment.SetPermutationSeed(9)
+	_, tests, err := assessment.CheckIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"rounds": 100, "seed": 9}, tests[2].Details)
+	assert.Contains(t, warnings.String(), "Warning: running 100 permutation rounds instead of 10000; this is not a conforming SP 800-90B assessment")
+
+	// Runs without the permutation tests are unaffected.
+	warnings.Reset()
+	assessment.SetEstimators([]string{"mcv"})
+	_, err = assessment.AssessIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.NotContains(t, warnings.String(), "permutation rounds")
+}
+
+// TestAssess_WithConfigConcurrent derives differently configured assessments
+// from one shared instance on many goroutines; run it with -race.
+func TestAssess_WithConfigConcurrent(t *testing.T) {
+	base := NewAssessment()
+	const workers = 64
+
+	warnings := make([]bytes.Buffer, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cfg := base.Config()
+			cfg.Verbose = i % 4
+			cfg.WarnWriter = &warnings[i]
+			assessment := base.WithConfig(cfg)
+
+			res, err := assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+			assert.NoError(t, err)
+			assert.Equal(t, 6.5, res.MinEntropy)
+			assert.Equal(t, i%4, assessment.GetVerbose())
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range warnings {
+		assert.Equal(t, i%4 > 0, warnings[i].Len() > 0, "worker %d", i)
+	}
+	assert.Equal(t, 1, base.GetVerbose())
+}
+
+func TestAssess_ShannonEntropyInStubBuild(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	uniform := make([]byte, 256*40)
+	for i := range uniform {
+		uniform[i] = byte(i)
+	}
+	res, err := assessment.AssessNonIID(uniform, 8)
+	require.NoError(t, err)
+	assert.InDelta(t, 8.0, res.ShannonEntropy, 1e-12)
+
+	res, err = assessment.AssessIID(bytes.Repeat([]byte{3}, 1000), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, res.ShannonEntropy)
+}
+
+func TestAssess_MalformedCErrorMessage(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	data := []byte{0xFA, 1, 2, 3}
+	_, iidErr := assessment.AssessIID(data, 8)
+	_, nonIIDErr := assessment.AssessNonIID(data, 8)
+	for _, err := range []error{iidErr, nonIIDErr} {
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrCFunction))
+
+		var entropyErr *EntropyError
+		require.True(t, errors.As(err, &entropyErr))
+		assert.True(t, strings.HasPrefix(entropyErr.Msg, "code=-1, message=injected fault red  injected fault red "), entropyErr.Msg)
+		assert.NotContains(t, err.Error(), "\n")
+		assert.NotContains(t, err.Error(), "\x1b")
+		assert.LessOrEqual(t, len(entropyErr.Msg), len("code=-1, message=")+maxCErrorMessage)
+	}
+}
+
+func TestAssess_CExceptions(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	tests := []struct {
+		sentinel byte
+		want     error
+		msg      string
+	}{
+		{0xFB, ErrMemoryAllocation, "code=-3, message=Out of memory: std::bad_alloc"},
+		{0xFC, ErrCException, "code=-4, message=Logic error: injected logic_error"},
+		{0xFD, ErrCException, "code=-5, message=Runtime error: injected runtime_error"},
+		{0xFE, ErrCException, "code=-6, message=Unknown exception occurred"},
+	}
+	for _, tt := range tests {
+		data := []byte{tt.sentinel, 1, 2, 3}
+		_, iidErr := assessment.AssessIID(data, 8)
+		_, nonIIDErr := assessment.AssessNonIID(data, 8)
+		for _, err := range []error{iidErr, nonIIDErr} {
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Contains(t, err.Error(), tt.msg)
+		}
+	}
+}
+
+func TestAssess_ProgressStub(t *testing.T) {
+	data := make([]byte, 100)
+
+	var events []progressEvent
+	_, err := NewAssessment().WithProgress(recordProgress(&events)).AssessNonIID(data, 8)
+	require.NoError(t, err)
+	require.Len(t, events, 11)
+	assert.Equal(t, progressEvent{ProgressNonIID, 0}, events[0])
+	assert.Equal(t, progressEvent{"Most Common Value", 0}, events[1])
+	assert.Equal(t, progressEvent{ProgressDone, 100}, events[10])
+	for i := 2; i < len(events); i++ {
+		assert.Greater(t, events[i].percent, events[i-1].percent)
+	}
+
+	events = nil
+	subset := NewAssessment()
+	subset.SetEstimators([]string{"mcv", "chi-square"})
+	_, err = subset.WithProgress(recordProgress(&events)).AssessIID(data, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []progressEvent{
+		{ProgressIID, 0}, {"Most Common Value", 0}, {"Chi-Square Tests", 50}, {ProgressDone, 100},
+	}, events)
+
+	// A failed calculation does not report completion.
+	events = nil
+	_, err = NewAssessment().WithProgress(recordProgress(&events)).AssessNonIID([]byte{0xFF, 1, 2}, 8)
+	require.Error(t, err)
+	assert.Equal(t, []progressEvent{{ProgressNonIID, 0}}, events)
+}
+
+// TestAssess_ProgressConfigStub checks that a progress function set through
+// AssessmentConfig receives the stages of an assessment in order.
+func TestAssess_ProgressConfigStub(t *testing.T) {
+	var events []progressEvent
+	cfg := NewAssessment().Config()
+	cfg.Progress = recordProgress(&events)
+	assessment := NewAssessment().WithConfig(cfg)
+
+	_, err := assessment.AssessReader(bytes.NewReader(make([]byte, 100)), 8, IID)
+	require.NoError(t, err)
+	var stages []string
+	for i, e := range events {
+		if i > 0 {
+			assert.GreaterOrEqual(t, e.percent, events[i-1].percent, e.phase)
+		}
+		if e.phase == ProgressReading || e.phase == ProgressIID || e.phase == ProgressNonIID || e.phase == ProgressDone {
+			stages = append(stages, e.phase)
+		}
+	}
+	assert.Equal(t, []string{ProgressReading, ProgressIID, ProgressDone}, stages)
+
+	// A mixed assessment reports the stage of each test type in turn.
+	events = nil
+	_, err = assessment.AssessIID(make([]byte, 100), 8)
+	require.NoError(t, err)
+	_, err = assessment.AssessNonIID(make([]byte, 100), 8)
+	require.NoError(t, err)
+	stages = nil
+	for _, e := range events {
+		if e.phase == ProgressIID || e.phase == ProgressNonIID || e.phase == ProgressDone {
+			stages = append(stages, e.phase)
+		}
+	}
+	assert.Equal(t, []string{ProgressIID, ProgressDone, ProgressNonIID, ProgressDone}, stages)
+}
+
+func TestAssess_NormalizeSymbolsStub(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetHistogram(true)
+	data := []byte{0x00, 0xFF, 0xFF, 0x00, 0xFF}
+
+	res, err := assessment.AssessNonIID(data, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 8, res.DataWordSize)
+
+	normalized := assessment.WithConfig(AssessmentConfig{Normalize: true, Histogram: true})
+	res, err = normalized.AssessNonIID(data, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.DataWordSize)
+	assert.Equal(t, uint64(2), res.Histogram[0])
+	assert.Equal(t, uint64(3), res.Histogram[1])
+	assert.Equal(t, []byte{0x00, 0xFF, 0xFF, 0x00, 0xFF}, data)
+
+	passed, _, err := normalized.CheckIID(data, 0)
+	require.NoError(t, err)
+	assert.True(t, passed)
+}
+
+func TestAssess_AlphabetSizeStub(t *testing.T) {
+	var warnings bytes.Buffer
+	assessment := NewAssessment()
+	assessment.SetWarnWriter(&warnings)
+
+	full := make([]byte, 256*4)
+	for i := range full {
+		full[i] = byte(i)
+	}
+	res, err := assessment.AssessNonIID(full, 8)
+	require.NoError(t, err)
+	assert.Equal(t, 256, res.AlphabetSize)
+	assert.NotContains(t, warnings.String(), "distinct symbols")
+
+	res, err = assessment.AssessIID(bytes.Repeat([]byte{0x00, 0xFF}, 100), 8)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.AlphabetSize)
+	assert.Contains(t, warnings.String(), "Warning: data has only 2 distinct symbols, which fit in 1 bits per symbol instead of 8")
+
+	// Symbols are counted masked to the word size.
+	res, err = assessment.AssessNonIID([]byte{0x10, 0x20, 0x31, 0x41}, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.AlphabetSize)
+}
+
+func TestAssess_MaxSamplesStub(t *testing.T) {
+	var warnings bytes.Buffer
+	assessment := NewAssessment()
+	assessment.SetWarnWriter(&warnings)
+	assessment.SetHistogram(true)
+	assessment.SetMaxSamples(4)
+
+	// The samples beyond the limit, 0xAA among them, are not assessed, so
+	// the word size is detected on the first four.
+	data := []byte{1, 0, 1, 1, 0xAA, 0xAA}
+	res, err := assessment.AssessNonIID(data, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.DataWordSize)
+	assert.Equal(t, 6.5, res.MinEntropy)
+	assert.Equal(t, uint64(1), res.Histogram[0])
+	assert.Equal(t, uint64(3), res.Histogram[1])
+	assert.Zero(t, res.Histogram[0xAA])
+	assert.Contains(t, warnings.String(), "Warning: assessing only the first 4 of 6 samples; this is not a conforming SP 800-90B assessment")
+
+	// Data within the limit is assessed whole and without the warning.
+	warnings.Reset()
+	_, err = assessment.AssessIID(data[:3], 8)
+	require.NoError(t, err)
+	assert.NotContains(t, warnings.String(), "assessing only")
+
+	// The limit comes with AssessmentConfig too, and applies to CheckIID.
+	cfg := NewAssessment().Config()
+	cfg.MaxSamples = 2
+	cfg.WarnWriter = &warnings
+	passed, _, err := NewAssessment().WithConfig(cfg).CheckIID(data, 8)
+	require.NoError(t, err)
+	assert.True(t, passed)
+	assert.Contains(t, warnings.String(), "Warning: assessing only the first 2 of 6 samples")
+}
+
+func TestAssess_StrideStub(t *testing.T) {
+	var warnings bytes.Buffer
+	assessment := NewAssessment()
+	assessment.SetWarnWriter(&warnings)
+	assessment.SetHistogram(true)
+	assessment.SetStride(2)
+
+	// Only the samples at even indexes are assessed, so the 0xAA at the odd
+	// ones do not widen the detected word size.
+	data := []byte{1, 0xAA, 0, 0xAA, 1, 0xAA, 1}
+	res, err := assessment.AssessNonIID(data, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.DataWordSize)
+	assert.Equal(t, uint64(1), res.Histogram[0])
+	assert.Equal(t, uint64(3), res.Histogram[1])
+	assert.Zero(t, res.Histogram[0xAA])
+	assert.Contains(t, warnings.String(), "Warning: assessing one in every 2 samples, 4 of 7; this is not a conforming SP 800-90B assessment")
+
+	// The stride applies after the sample limit.
+	warnings.Reset()
+	assessment.SetMaxSamples(4)
+	res, err = assessment.AssessNonIID(data, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), res.Histogram[0]+res.Histogram[1])
+	assert.Contains(t, warnings.String(), "2 of 4")
+
+	// A stride of one assesses every sample without the warning.
+	warnings.Reset()
+	assessment.SetMaxSamples(0)
+	assessment.SetStride(1)
+	res, err = assessment.AssessIID(data, 8)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), res.Histogram[0xAA])
+	assert.NotContains(t, warnings.String(), "one in every")
+
+	// The stride comes with AssessmentConfig too; a negative one fails.
+	cfg := NewAssessment().Config()
+	cfg.Stride = -1
+	_, _, err = NewAssessment().WithConfig(cfg).CheckIID(data, 8)
+	assert.True(t, errors.Is(err, ErrInvalidStride))
+	cfg.Stride = 3
+	cfg.WarnWriter = &warnings
+	passed, _, err := NewAssessment().WithConfig(cfg).CheckIID(data, 8)
+	require.NoError(t, err)
+	assert.True(t, passed)
+	assert.Contains(t, warnings.String(), "3 of 7")
+}
@@ -3,6 +3,7 @@ package entropy
 import (
 	"bytes"
 	"errors"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -92,3 +93,57 @@ func TestAssessNonIID_EmptyData(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "data is empty")
 }
+
+func TestAssess_InvalidHSubmitter(t *testing.T) {
+	tests := []struct {
+		name string
+		h    float64
+		bits int
+	}{
+		{"negative", -0.5, 8},
+		{"above bits", 1.5, 1},
+		{"infinite", math.Inf(1), 0},
+		{"nan", math.NaN(), 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assessment := NewAssessment()
+			assessment.SetHSubmitter(tt.h)
+
+			_, err := assessment.AssessIID([]byte{1, 2, 3}, tt.bits)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrInvalidHSubmitter))
+
+			_, err = assessment.AssessNonIID([]byte{1, 2, 3}, tt.bits)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrInvalidHSubmitter))
+		})
+	}
+}
+
+func TestAssess_InvalidBitOrder(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetBitOrder(BitOrder(7))
+
+	_, err := assessment.AssessIID([]byte{1, 2, 3}, 8)
+	assert.True(t, errors.Is(err, ErrInvalidBitOrder))
+
+	_, err = assessment.AssessNonIID([]byte{1, 2, 3}, 8)
+	assert.True(t, errors.Is(err, ErrInvalidBitOrder))
+
+	_, _, err = assessment.CheckIID([]byte{1, 2, 3}, 8)
+	assert.True(t, errors.Is(err, ErrInvalidBitOrder))
+}
+
+func TestCheckIID_ValidationErrors(t *testing.T) {
+	assessment := NewAssessment()
+
+	_, _, err := assessment.CheckIID([]byte{1, 2, 3}, 9)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidBitsPerSymbol))
+
+	_, _, err = assessment.CheckIID(nil, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+}
@@ -12,7 +12,22 @@ var (
 	ErrInvalidBitsPerSymbol = errors.New("bits_per_symbol must be between 1 and 8")
 	ErrInsufficientData     = errors.New("insufficient data for entropy assessment")
 	ErrCFunction            = errors.New("c library function error")
+	ErrCException           = errors.New("c library raised an exception")
 	ErrMemoryAllocation     = errors.New("memory allocation failed")
+	ErrAssessmentCrashed    = errors.New("assessment process terminated abnormally")
+	ErrAssessmentTimeout    = errors.New("assessment exceeded its time limit")
+	ErrInvalidHSubmitter    = errors.New("H_submitter must be between 0 and bits_per_symbol")
+	ErrInvalidEstimator     = errors.New("unknown estimator name")
+	ErrSelfTestFailed       = errors.New("known-answer self-test failed")
+	ErrInvalidSection       = errors.New("section is outside the input data")
+	ErrInvalidBitOrder      = errors.New("bit order must be MSBFirst or LSBFirst")
+	ErrEstimatorUnavailable = errors.New("estimator not available in this build")
+	ErrInvalidTolerance     = errors.New("tolerance must be a non-negative number")
+	ErrResourceLimit        = errors.New("assessment exceeds the memory budget")
+	ErrInvalidConditioning  = errors.New("invalid conditioning component parameters")
+	ErrInvalidRestart       = errors.New("invalid restart test input")
+	ErrInvalidStride        = errors.New("stride must be at least 1")
+	ErrInvalidThreads       = errors.New("invalid process thread limit")
 )
 
 // EntropyError provides structured error context for entropy assessment failures.
@@ -44,8 +59,27 @@ func newError(op string, err error, msg string) error {
 	}
 }
 
-// wrapCError wraps a C library error code and message into an EntropyError
-// with ErrCFunction as the underlying sentinel.
+// Error codes of the C wrapper, mirroring the WRAPPER_ERROR_* macros in
+// wrapper.h.
+const (
+	cErrorInvalidInput = -1 // rejected argument or unusable data
+	cErrorException    = -2 // any other std::exception
+	cErrorOutOfMemory  = -3 // std::bad_alloc or a failed allocation
+	cErrorLogic        = -4 // std::logic_error
+	cErrorRuntime      = -5 // std::runtime_error
+	cErrorUnknown      = -6 // exception not derived from std::exception
+)
+
+// wrapCError wraps a C library error code and message into an EntropyError.
+// Out-of-memory codes map to ErrMemoryAllocation, caught C++ exceptions to
+// ErrCException and every other code to ErrCFunction.
 func wrapCError(op string, code int, message string) error {
-	return newError(op, ErrCFunction, fmt.Sprintf("code=%d, message=%s", code, message))
+	sentinel := ErrCFunction
+	switch code {
+	case cErrorOutOfMemory:
+		sentinel = ErrMemoryAllocation
+	case cErrorException, cErrorLogic, cErrorRuntime, cErrorUnknown:
+		sentinel = ErrCException
+	}
+	return newError(op, sentinel, fmt.Sprintf("code=%d, message=%s", code, message))
 }
@@ -2,6 +2,7 @@ package entropy
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -74,6 +75,26 @@ func TestWrapCError(t *testing.T) {
 	assert.Contains(t, entropyErr.Msg, "memory allocation failed")
 }
 
+func TestWrapCError_Sentinels(t *testing.T) {
+	tests := []struct {
+		code int
+		want error
+	}{
+		{cErrorInvalidInput, ErrCFunction},
+		{cErrorException, ErrCException},
+		{cErrorOutOfMemory, ErrMemoryAllocation},
+		{cErrorLogic, ErrCException},
+		{cErrorRuntime, ErrCException},
+		{cErrorUnknown, ErrCException},
+		{-99, ErrCFunction},
+	}
+	for _, tt := range tests {
+		err := wrapCError("calculate_non_iid_entropy", tt.code, "message")
+		assert.ErrorIs(t, err, tt.want, "code %d", tt.code)
+		assert.Contains(t, err.Error(), fmt.Sprintf("code=%d, message=message", tt.code))
+	}
+}
+
 func TestPredefinedErrors(t *testing.T) {
 	// Test that predefined errors exist and have correct messages
 	assert.NotNil(t, ErrInvalidData)
@@ -90,4 +111,31 @@ func TestPredefinedErrors(t *testing.T) {
 
 	assert.NotNil(t, ErrMemoryAllocation)
 	assert.Equal(t, "memory allocation failed", ErrMemoryAllocation.Error())
+
+	assert.NotNil(t, ErrAssessmentCrashed)
+	assert.Equal(t, "assessment process terminated abnormally", ErrAssessmentCrashed.Error())
+
+	assert.NotNil(t, ErrAssessmentTimeout)
+	assert.Equal(t, "assessment exceeded its time limit", ErrAssessmentTimeout.Error())
+
+	assert.NotNil(t, ErrInvalidHSubmitter)
+	assert.Equal(t, "H_submitter must be between 0 and bits_per_symbol", ErrInvalidHSubmitter.Error())
+
+	assert.NotNil(t, ErrInvalidEstimator)
+	assert.Equal(t, "unknown estimator name", ErrInvalidEstimator.Error())
+
+	assert.NotNil(t, ErrSelfTestFailed)
+	assert.Equal(t, "known-answer self-test failed", ErrSelfTestFailed.Error())
+
+	assert.NotNil(t, ErrInvalidSection)
+	assert.Equal(t, "section is outside the input data", ErrInvalidSection.Error())
+
+	assert.NotNil(t, ErrInvalidBitOrder)
+	assert.Equal(t, "bit order must be MSBFirst or LSBFirst", ErrInvalidBitOrder.Error())
+
+	assert.NotNil(t, ErrInvalidStride)
+	assert.Equal(t, "stride must be at least 1", ErrInvalidStride.Error())
+
+	assert.NotNil(t, ErrInvalidThreads)
+	assert.Equal(t, "invalid process thread limit", ErrInvalidThreads.Error())
 }
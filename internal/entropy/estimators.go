@@ -0,0 +1,188 @@
+package entropy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Estimator selection bits passed to the C wrapper. They must match the
+// ESTIMATOR_* definitions in wrapper.h.
+const (
+	estimatorMCV         uint32 = 1 << 0
+	estimatorCollision   uint32 = 1 << 1
+	estimatorMarkov      uint32 = 1 << 2
+	estimatorCompression uint32 = 1 << 3
+	estimatorTTuple      uint32 = 1 << 4
+	estimatorLRS         uint32 = 1 << 5
+	estimatorMultiMCW    uint32 = 1 << 6
+	estimatorLag         uint32 = 1 << 7
+	estimatorMultiMMC    uint32 = 1 << 8
+	estimatorLZ78Y       uint32 = 1 << 9
+	estimatorChiSquare   uint32 = 1 << 10
+	estimatorPermutation uint32 = 1 << 11
+)
+
+// EstimatorID is a stable identifier of an estimator or statistical test.
+// Unlike EstimatorResult.Name it does not depend on the display label of the
+// backend, so clients should match on it. The values are part of the gRPC API
+// and must match the EstimatorId enum in nist_sp800_90b.proto.
+type EstimatorID int
+
+// Estimator identifiers. EstimatorUnknown is reported for estimators this
+// version does not know, such as those added by a newer NIST library.
+const (
+	EstimatorUnknown EstimatorID = iota
+	EstimatorMCV
+	EstimatorCollision
+	EstimatorMarkov
+	EstimatorCompression
+	EstimatorTTuple
+	EstimatorLRS
+	EstimatorMultiMCW
+	EstimatorLag
+	EstimatorMultiMMC
+	EstimatorLZ78Y
+	EstimatorChiSquare
+	EstimatorPermutation
+)
+
+// String returns the canonical name of id, the name accepted by
+// SetEstimators, or "unknown".
+func (id EstimatorID) String() string {
+	for _, known := range [][]estimatorName{nonIIDEstimatorNames, iidEstimatorNames} {
+		for _, e := range known {
+			if e.id == id {
+				return e.name
+			}
+		}
+	}
+	return "unknown"
+}
+
+// EstimatorIDFromName maps a canonical estimator name or a label reported in
+// EstimatorResult.Name to its identifier. Canonical names are matched as in
+// SetEstimators; anything else yields EstimatorUnknown.
+func EstimatorIDFromName(name string) EstimatorID {
+	canonical := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-")
+	for _, known := range [][]estimatorName{nonIIDEstimatorNames, iidEstimatorNames} {
+		for _, e := range known {
+			if e.label == name || e.name == canonical {
+				return e.id
+			}
+		}
+	}
+	return EstimatorUnknown
+}
+
+// estimatorName associates a selectable estimator name with its identifier,
+// its mask bit and the label reported in EstimatorResult.Name. Labels must
+// match the add_estimator and add_test_result calls in wrapper.cpp.
+type estimatorName struct {
+	name  string
+	label string
+	id    EstimatorID
+	bit   uint32
+}
+
+// iidEstimatorNames lists the IID tests in execution order.
+var iidEstimatorNames = []estimatorName{
+	{"mcv", "Most Common Value", EstimatorMCV, estimatorMCV},
+	{"chi-square", "Chi-Square Tests", EstimatorChiSquare, estimatorChiSquare},
+	{"lrs", "Length of Longest Repeated Substring Test", EstimatorLRS, estimatorLRS},
+	{"permutation", "Permutation Tests", EstimatorPermutation, estimatorPermutation},
+}
+
+// nonIIDEstimatorNames lists the Non-IID estimators of SP 800-90B Section 6.3
+// in execution order.
+var nonIIDEstimatorNames = []estimatorName{
+	{"mcv", "Most Common Value", EstimatorMCV, estimatorMCV},
+	{"collision", "Collision Test", EstimatorCollision, estimatorCollision},
+	{"markov", "Markov Test", EstimatorMarkov, estimatorMarkov},
+	{"compression", "Compression Test", EstimatorCompression, estimatorCompression},
+	{"t-tuple", "t-Tuple Test", EstimatorTTuple, estimatorTTuple},
+	{"lrs", "LRS Test", EstimatorLRS, estimatorLRS},
+	{"multi-mcw", "Multi Most Common in Window Test", EstimatorMultiMCW, estimatorMultiMCW},
+	{"lag", "Lag Prediction Test", EstimatorLag, estimatorLag},
+	{"multi-mmc", "Multi Markov Model with Counting Test", EstimatorMultiMMC, estimatorMultiMMC},
+	{"lz78y", "LZ78Y Test", EstimatorLZ78Y, estimatorLZ78Y},
+}
+
+func estimatorNamesFor(testType TestType) []estimatorName {
+	if testType == IID {
+		return iidEstimatorNames
+	}
+	return nonIIDEstimatorNames
+}
+
+// ValidEstimatorNames returns the names accepted by SetEstimators for testType.
+func ValidEstimatorNames(testType TestType) []string {
+	known := estimatorNamesFor(testType)
+	names := make([]string, len(known))
+	for i, e := range known {
+		names[i] = e.name
+	}
+	return names
+}
+
+// SupportedEstimators returns the estimator names that a full assessment of
+// testType reports in EstimatorResult.Name, in execution order.
+func SupportedEstimators(testType TestType) []string {
+	known := estimatorNamesFor(testType)
+	labels := make([]string, len(known))
+	for i, e := range known {
+		labels[i] = e.label
+	}
+	return labels
+}
+
+// ValidateEstimators reports an ErrInvalidEstimator error if any name is not
+// a known estimator for testType. Names are matched case-insensitively and
+// underscores may be used in place of hyphens.
+func ValidateEstimators(testType TestType, names []string) error {
+	_, _, err := estimatorMask("ValidateEstimators", testType, names)
+	return err
+}
+
+// CanonicalEstimators returns the canonical, de-duplicated names of the
+// selection in execution order, as Result.EstimatorSelection reports them,
+// or nil for an empty selection. Unknown names fail as in
+// ValidateEstimators.
+func CanonicalEstimators(testType TestType, names []string) ([]string, error) {
+	_, selection, err := estimatorMask("CanonicalEstimators", testType, names)
+	return selection, err
+}
+
+// estimatorMask converts a selection into the wrapper bit mask and the
+// canonical, de-duplicated names in execution order. An empty selection
+// yields a zero mask, meaning all estimators.
+func estimatorMask(op string, testType TestType, names []string) (uint32, []string, error) {
+	if len(names) == 0 {
+		return 0, nil, nil
+	}
+
+	known := estimatorNamesFor(testType)
+	var mask uint32
+	for _, raw := range names {
+		name := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(raw)), "_", "-")
+		found := false
+		for _, e := range known {
+			if e.name == name {
+				mask |= e.bit
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, nil, newError(op, ErrInvalidEstimator, fmt.Sprintf("unknown %s estimator %q (valid: %s)",
+				testType, raw, strings.Join(ValidEstimatorNames(testType), ", ")))
+		}
+	}
+
+	selection := make([]string, 0, len(known))
+	for _, e := range known {
+		if mask&e.bit != 0 {
+			selection = append(selection, e.name)
+		}
+	}
+	return mask, selection, nil
+}
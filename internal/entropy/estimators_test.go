@@ -0,0 +1,91 @@
+package entropy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidEstimatorNames(t *testing.T) {
+	assert.Equal(t, []string{"mcv", "chi-square", "lrs", "permutation"}, ValidEstimatorNames(IID))
+	assert.Equal(t, []string{
+		"mcv", "collision", "markov", "compression", "t-tuple",
+		"lrs", "multi-mcw", "lag", "multi-mmc", "lz78y",
+	}, ValidEstimatorNames(NonIID))
+}
+
+func TestValidateEstimators(t *testing.T) {
+	assert.NoError(t, ValidateEstimators(NonIID, nil))
+	assert.NoError(t, ValidateEstimators(NonIID, []string{"MCV", " markov ", "multi_mmc"}))
+	assert.NoError(t, ValidateEstimators(IID, []string{"chi-square"}))
+
+	err := ValidateEstimators(IID, []string{"mcv", "markov"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidEstimator))
+	assert.Contains(t, err.Error(), `"markov"`)
+	assert.Contains(t, err.Error(), "valid: mcv, chi-square, lrs, permutation")
+}
+
+func TestCanonicalEstimators(t *testing.T) {
+	selection, err := CanonicalEstimators(NonIID, []string{"markov", " MCV ", "multi_mmc", "mcv"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mcv", "markov", "multi-mmc"}, selection)
+
+	selection, err = CanonicalEstimators(IID, nil)
+	require.NoError(t, err)
+	assert.Nil(t, selection)
+
+	_, err = CanonicalEstimators(IID, []string{"markov"})
+	assert.True(t, errors.Is(err, ErrInvalidEstimator))
+}
+
+func TestEstimatorID(t *testing.T) {
+	assert.Equal(t, EstimatorMCV, EstimatorIDFromName("Most Common Value"))
+	assert.Equal(t, EstimatorMultiMMC, EstimatorIDFromName("multi_mmc"))
+	assert.Equal(t, EstimatorLRS, EstimatorIDFromName("LRS Test"))
+	assert.Equal(t, EstimatorLRS, EstimatorIDFromName("Length of Longest Repeated Substring Test"))
+	assert.Equal(t, EstimatorUnknown, EstimatorIDFromName("Future Estimator Test"))
+	assert.Equal(t, EstimatorUnknown, EstimatorIDFromName(""))
+
+	assert.Equal(t, "lz78y", EstimatorLZ78Y.String())
+	assert.Equal(t, "chi-square", EstimatorChiSquare.String())
+	assert.Equal(t, "unknown", EstimatorUnknown.String())
+	assert.Equal(t, "unknown", EstimatorID(99).String())
+
+	// Every reported label maps to an identifier that round-trips through its
+	// canonical name.
+	for _, testType := range []TestType{IID, NonIID} {
+		for _, e := range estimatorNamesFor(testType) {
+			id := EstimatorIDFromName(e.label)
+			assert.NotEqual(t, EstimatorUnknown, id, e.label)
+			assert.Equal(t, e.name, id.String())
+		}
+	}
+}
+
+func TestEstimatorMask(t *testing.T) {
+	mask, selection, err := estimatorMask("test", NonIID, []string{"markov", "mcv", "MCV"})
+	require.NoError(t, err)
+	assert.Equal(t, estimatorMCV|estimatorMarkov, mask)
+	assert.Equal(t, []string{"mcv", "markov"}, selection)
+
+	mask, selection, err = estimatorMask("test", NonIID, nil)
+	require.NoError(t, err)
+	assert.Zero(t, mask)
+	assert.Nil(t, selection)
+}
+
+func TestAssess_InvalidEstimator(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetEstimators([]string{"bogus"})
+
+	_, err := assessment.AssessIID([]byte{1, 2, 3}, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidEstimator))
+
+	_, err = assessment.AssessNonIID([]byte{1, 2, 3}, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidEstimator))
+}
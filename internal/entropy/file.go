@@ -0,0 +1,199 @@
+package entropy
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// gzipHeader is the gzip magic number followed by the deflate method byte,
+// the only compression method gzip defines.
+var gzipHeader = []byte{0x1f, 0x8b, 0x08}
+
+// zstdHeader is the magic number of a zstd frame.
+var zstdHeader = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// MaxDecompressedSize is the largest number of bytes a compressed file or an
+// archive member may decompress to. Reading past it fails with
+// ErrResourceLimit, so that a small file cannot exhaust the memory.
+const MaxDecompressedSize = 4 << 30
+
+// maxDecompressedSize is MaxDecompressedSize; tests lower it.
+var maxDecompressedSize int64 = MaxDecompressedSize
+
+// decompressedFile reads the decompressed contents of a compressed file.
+type decompressedFile struct {
+	io.Reader
+	close func() error
+}
+
+// Close releases the decompressor and closes the underlying file.
+func (d decompressedFile) Close() error {
+	return d.close()
+}
+
+// sizeGuard fails with ErrResourceLimit once more than limit bytes have been
+// read from r.
+type sizeGuard struct {
+	r     io.Reader
+	read  int64
+	limit int64
+	op    string
+	name  string
+}
+
+// guardSize returns r bounded to maxDecompressedSize bytes.
+func guardSize(op, name string, r io.Reader) io.Reader {
+	return &sizeGuard{r: r, limit: maxDecompressedSize, op: op, name: name}
+}
+
+// Read implements io.Reader.
+func (g *sizeGuard) Read(b []byte) (int, error) {
+	n, err := g.r.Read(b)
+	g.read += int64(n)
+	if g.read > g.limit {
+		return n, newError(g.op, ErrResourceLimit, fmt.Sprintf("%s decompresses to more than %d bytes", g.name, g.limit))
+	}
+	return n, err
+}
+
+// OpenFile opens a sample file for reading. Files with a .gz or .zst
+// extension, or whose contents start with a gzip or zstd header, are
+// decompressed transparently, up to MaxDecompressedSize bytes; any other
+// file is read as is.
+func OpenFile(filename string) (io.ReadCloser, error) {
+	return openFile("OpenFile", filename, nil, true)
+}
+
+// openFile is OpenFile with the operation reported in errors. A non-nil
+// progress is called as the file is read, as for ReadFileProgress. Without
+// guard the decompressed size is not bounded, for archives whose members
+// are bounded one by one instead.
+func openFile(op, filename string, progress func(read, size int64), guard bool) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, newError(op, err, fmt.Sprintf("failed to open file: %s", filename))
+	}
+
+	var src io.Reader = file
+	if progress != nil {
+		var size int64
+		if info, err := file.Stat(); err == nil {
+			size = info.Size()
+		}
+		src = &progressReader{r: src, size: size, progress: progress}
+	}
+	br := bufio.NewReader(src)
+	header, _ := br.Peek(len(zstdHeader))
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	var out decompressedFile
+	switch {
+	case ext == ".gz" || bytes.HasPrefix(header, gzipHeader):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			file.Close()
+			return nil, newError(op, ErrInvalidData, fmt.Sprintf("%s is not a valid gzip file: %v", filename, err))
+		}
+		out = decompressedFile{Reader: zr, close: func() error {
+			err := zr.Close()
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+			return err
+		}}
+	case ext == ".zst" || bytes.HasPrefix(header, zstdHeader):
+		if !bytes.HasPrefix(header, zstdHeader) {
+			file.Close()
+			return nil, newError(op, ErrInvalidData, fmt.Sprintf("%s is not a valid zstd file", filename))
+		}
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			file.Close()
+			return nil, newError(op, ErrInvalidData, fmt.Sprintf("%s is not a valid zstd file: %v", filename, err))
+		}
+		out = decompressedFile{Reader: zr, close: func() error {
+			zr.Close()
+			return file.Close()
+		}}
+	default:
+		return struct {
+			io.Reader
+			io.Closer
+		}{br, file}, nil
+	}
+	if guard {
+		out.Reader = guardSize(op, filename, out.Reader)
+	}
+	return out, nil
+}
+
+// ReadFile reads all samples from a file, decompressing gzip and zstd files
+// as OpenFile does.
+func ReadFile(filename string) ([]byte, error) {
+	return readFile("ReadFile", filename, 0, nil)
+}
+
+// ReadFileProgress is ReadFile that calls progress after every read from the
+// file with the bytes read so far and the size of the file, or 0 when it is
+// unknown. Both count the file as stored, so a compressed file reports its
+// compressed bytes.
+func ReadFileProgress(filename string, progress func(read, size int64)) ([]byte, error) {
+	return readFile("ReadFileProgress", filename, 0, progress)
+}
+
+// ReadFileLimit is ReadFileProgress that stops decompressing a compressed
+// file after limit bytes when limit is above 0, for callers that only use
+// the start of the samples. Plain files are read whole. A nil progress is
+// not called.
+func ReadFileLimit(filename string, limit int64, progress func(read, size int64)) ([]byte, error) {
+	return readFile("ReadFileLimit", filename, limit, progress)
+}
+
+// readFile implements ReadFile, ReadFileProgress and ReadFileLimit.
+func readFile(op, filename string, limit int64, progress func(read, size int64)) ([]byte, error) {
+	r, err := openFile(op, filename, progress, true)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var src io.Reader = r
+	if _, compressed := r.(decompressedFile); compressed && limit > 0 {
+		src = io.LimitReader(r, limit)
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		if errors.Is(err, ErrResourceLimit) {
+			return nil, err
+		}
+		return nil, newError(op, err, fmt.Sprintf("failed to read %s", filename))
+	}
+	return data, nil
+}
+
+// progressReader reports the bytes read from r to progress.
+type progressReader struct {
+	r        io.Reader
+	read     int64
+	size     int64
+	progress func(read, size int64)
+}
+
+// Read implements io.Reader.
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.read += int64(n)
+		p.progress(p.read, p.size)
+	}
+	return n, err
+}
@@ -0,0 +1,195 @@
+package entropy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeGzip writes data gzip-compressed to path.
+func writeGzip(t *testing.T, path string, data []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
+
+func TestReadFile_Gzip(t *testing.T) {
+	dir := t.TempDir()
+	data := randomSamples(4096, 8, 5)
+
+	gz := filepath.Join(dir, "data.bin.gz")
+	writeGzip(t, gz, data)
+	got, err := ReadFile(gz)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	// The gzip header is recognised without the extension.
+	sniffed := filepath.Join(dir, "data.bin")
+	writeGzip(t, sniffed, data)
+	got, err = ReadFile(sniffed)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+}
+
+// writeZstd writes data zstd-compressed to path.
+func writeZstd(t *testing.T, path string, data []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
+
+func TestReadFile_Zstd(t *testing.T) {
+	dir := t.TempDir()
+	data := randomSamples(4096, 8, 5)
+
+	zst := filepath.Join(dir, "data.bin.zst")
+	writeZstd(t, zst, data)
+	got, err := ReadFile(zst)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	// The zstd header is recognised without the extension.
+	sniffed := filepath.Join(dir, "data.bin")
+	writeZstd(t, sniffed, data)
+	got, err = ReadFile(sniffed)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	// A .zst file must hold zstd data.
+	bad := filepath.Join(dir, "bad.zst")
+	require.NoError(t, os.WriteFile(bad, []byte{1, 2, 3, 4}, 0o644))
+	_, err = ReadFile(bad)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+}
+
+func TestReadFile_DecompressedSizeLimit(t *testing.T) {
+	defer func(limit int64) { maxDecompressedSize = limit }(maxDecompressedSize)
+	maxDecompressedSize = 1000
+
+	dir := t.TempDir()
+	gz := filepath.Join(dir, "data.bin.gz")
+	writeGzip(t, gz, make([]byte, 1001))
+	_, err := ReadFile(gz)
+	assert.True(t, errors.Is(err, ErrResourceLimit), "%v", err)
+
+	writeGzip(t, gz, make([]byte, 1000))
+	got, err := ReadFile(gz)
+	require.NoError(t, err)
+	assert.Len(t, got, 1000)
+
+	// Plain files are not bounded.
+	plain := filepath.Join(dir, "data.bin")
+	require.NoError(t, os.WriteFile(plain, make([]byte, 2000), 0o644))
+	got, err = ReadFile(plain)
+	require.NoError(t, err)
+	assert.Len(t, got, 2000)
+}
+
+func TestReadFileLimit(t *testing.T) {
+	dir := t.TempDir()
+	data := randomSamples(4096, 8, 8)
+	plain := filepath.Join(dir, "data.bin")
+	require.NoError(t, os.WriteFile(plain, data, 0o644))
+	gz := filepath.Join(dir, "data.bin.gz")
+	writeGzip(t, gz, data)
+	zst := filepath.Join(dir, "data.bin.zst")
+	writeZstd(t, zst, data)
+
+	// Decompression stops at the limit; plain files are read whole.
+	for _, path := range []string{gz, zst} {
+		got, err := ReadFileLimit(path, 100, nil)
+		require.NoError(t, err)
+		assert.Equal(t, data[:100], got, path)
+	}
+	got, err := ReadFileLimit(plain, 100, nil)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	got, err = ReadFileLimit(gz, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+}
+
+func TestReadFile_PlainUnaffected(t *testing.T) {
+	dir := t.TempDir()
+	for _, data := range [][]byte{
+		randomSamples(4096, 8, 6),
+		{0x1f, 0x8b}, // shorter than a gzip header
+		{0x1f, 0x8b, 0x07, 0x00},
+		{0x28, 0xb5, 0x2f}, // shorter than a zstd header
+		{},
+	} {
+		path := filepath.Join(dir, "data.bin")
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		got, err := ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, len(data), len(got))
+		assert.True(t, bytes.Equal(data, got))
+	}
+}
+
+func TestReadFileProgress(t *testing.T) {
+	dir := t.TempDir()
+	data := randomSamples(100000, 8, 7)
+
+	for _, name := range []string{"data.bin", "data.bin.gz"} {
+		path := filepath.Join(dir, name)
+		if filepath.Ext(name) == ".gz" {
+			writeGzip(t, path, data)
+		} else {
+			require.NoError(t, os.WriteFile(path, data, 0o644))
+		}
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+
+		var calls int
+		var last int64
+		got, err := ReadFileProgress(path, func(read, size int64) {
+			calls++
+			assert.Greater(t, read, last)
+			assert.Equal(t, info.Size(), size)
+			last = read
+		})
+		require.NoError(t, err)
+		assert.Equal(t, data, got)
+		assert.Positive(t, calls)
+		assert.Equal(t, info.Size(), last, name)
+	}
+}
+
+func TestReadFile_Errors(t *testing.T) {
+	_, err := ReadFile("/nonexistent/file.bin")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to open file")
+
+	// A .gz file must hold gzip data.
+	path := filepath.Join(t.TempDir(), "data.gz")
+	require.NoError(t, os.WriteFile(path, []byte{1, 2, 3, 4}, 0o644))
+	_, err = ReadFile(path)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+
+	// A truncated stream fails while reading.
+	writeGzip(t, path, randomSamples(4096, 8, 7))
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, raw[:len(raw)-8], 0o644))
+	_, err = ReadFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read")
+}
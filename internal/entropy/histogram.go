@@ -0,0 +1,121 @@
+package entropy
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// SymbolHistogram counts the occurrences of each symbol value in data, which
+// holds one symbol per byte. The result has 2^bitsPerSymbol entries indexed
+// by value. Unlike Result.Histogram, which counts values masked to the word
+// size, a symbol that does not fit in bitsPerSymbol bits is an ErrInvalidData
+// error.
+func SymbolHistogram(data []byte, bitsPerSymbol int) ([]uint64, error) {
+	if bitsPerSymbol < 1 || bitsPerSymbol > 8 {
+		return nil, newError("SymbolHistogram", ErrInvalidBitsPerSymbol, fmt.Sprintf("got %d", bitsPerSymbol))
+	}
+
+	counts := make([]uint64, 1<<bitsPerSymbol)
+	for i, symbol := range data {
+		if int(symbol) >= len(counts) {
+			return nil, newError("SymbolHistogram", ErrInvalidData,
+				fmt.Sprintf("symbol 0x%02x at index %d does not fit in %d bits per symbol", symbol, i, bitsPerSymbol))
+		}
+		counts[symbol]++
+	}
+	return counts, nil
+}
+
+// shannonEntropy returns the Shannon entropy in bits per symbol of data with
+// each sample masked to wordSize bits, as the assessment counts symbols. It
+// is at most wordSize and 0 for a constant stream.
+func shannonEntropy(data []byte, wordSize int) float64 {
+	if len(data) == 0 || wordSize < 1 || wordSize > 8 {
+		return 0
+	}
+	counts := countSymbols(data, wordSize)
+
+	n := float64(len(data))
+	h := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// AlphabetSize returns the number of distinct byte values in data, 0 when
+// data is empty. An alphabet much smaller than 2^bitsPerSymbol suggests that
+// the word size is larger than the source needs; see NormalizeSymbols.
+func AlphabetSize(data []byte) int {
+	return distinctSymbols(countSymbols(data, 8))
+}
+
+// countSymbols counts the samples of data masked to wordSize bits.
+func countSymbols(data []byte, wordSize int) [HistogramSize]uint64 {
+	var counts [HistogramSize]uint64
+	mask := byte(1<<uint(wordSize) - 1)
+	for _, b := range data {
+		counts[b&mask]++
+	}
+	return counts
+}
+
+// distinctSymbols returns the number of non-zero counts.
+func distinctSymbols(counts [HistogramSize]uint64) int {
+	k := 0
+	for _, c := range counts {
+		if c > 0 {
+			k++
+		}
+	}
+	return k
+}
+
+// alphabetBits returns the smallest word size, at least 1, that holds an
+// alphabet of k symbols.
+func alphabetBits(k int) int {
+	return max(1, bits.Len(uint(k-1)))
+}
+
+// NormalizeSymbols maps the distinct values in data onto 0 through k-1 in
+// ascending order and returns the translated copy together with the alphabet
+// size k. Sources that emit a sparse set of byte values, such as 0x00 and
+// 0xFF, then assess at the smallest word size that holds their alphabet.
+// The input is left unchanged.
+func NormalizeSymbols(data []byte) (normalized []byte, alphabetSize int) {
+	var present [HistogramSize]bool
+	for _, b := range data {
+		present[b] = true
+	}
+	var translate [HistogramSize]byte
+	for v, ok := range present {
+		if ok {
+			translate[v] = byte(alphabetSize)
+			alphabetSize++
+		}
+	}
+
+	normalized = make([]byte, len(data))
+	for i, b := range data {
+		normalized[i] = translate[b]
+	}
+	return normalized, alphabetSize
+}
+
+// normalizeSamples applies NormalizeSymbols when the assessment is configured to.
+// An explicit word size too small for the alphabet is an ErrInvalidData
+// error, since masking would merge symbols.
+func (a *Assessment) normalizeSamples(op string, data []byte, bitsPerSymbol int) ([]byte, error) {
+	if !a.normalize {
+		return data, nil
+	}
+	normalized, k := NormalizeSymbols(data)
+	if bitsPerSymbol > 0 && k > 1<<bitsPerSymbol {
+		return nil, newError(op, ErrInvalidData, fmt.Sprintf("%d distinct symbols do not fit in %d bits per symbol", k, bitsPerSymbol))
+	}
+	return normalized, nil
+}
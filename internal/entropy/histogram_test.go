@@ -0,0 +1,133 @@
+package entropy
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// histogramTotal adds up the counts of a histogram.
+func histogramTotal(counts []uint64) uint64 {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	return total
+}
+
+func TestSymbolHistogram_Uniform(t *testing.T) {
+	data := make([]byte, 256*40)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	counts, err := SymbolHistogram(data, 8)
+	require.NoError(t, err)
+	require.Len(t, counts, 256)
+	for value, c := range counts {
+		assert.Equal(t, uint64(40), c, "value %d", value)
+	}
+	assert.Equal(t, uint64(len(data)), histogramTotal(counts))
+}
+
+func TestSymbolHistogram_Skewed(t *testing.T) {
+	data := make([]byte, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		switch {
+		case i%10 < 7:
+			data = append(data, 0x3)
+		case i%10 < 9:
+			data = append(data, 0xA)
+		default:
+			data = append(data, byte(i/10%16))
+		}
+	}
+	counts, err := SymbolHistogram(data, 4)
+	require.NoError(t, err)
+	require.Len(t, counts, 16)
+	assert.Equal(t, uint64(len(data)), histogramTotal(counts))
+	// Every tenth sample cycles through i/10 = 0..99 modulo 16, which hits
+	// the values 0 to 3 seven times and the others six times.
+	assert.Equal(t, uint64(700+7), counts[0x3])
+	assert.Equal(t, uint64(200+6), counts[0xA])
+	assert.Equal(t, uint64(7), counts[0x0])
+	assert.Equal(t, uint64(6), counts[0xF])
+}
+
+func TestSymbolHistogram_Invalid(t *testing.T) {
+	_, err := SymbolHistogram([]byte{0, 1, 2, 3, 4}, 2)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+	assert.Contains(t, err.Error(), "symbol 0x04 at index 4 does not fit in 2 bits per symbol")
+
+	for _, bits := range []int{0, 9} {
+		_, err = SymbolHistogram([]byte{0}, bits)
+		assert.True(t, errors.Is(err, ErrInvalidBitsPerSymbol), "bits=%d", bits)
+	}
+
+	counts, err := SymbolHistogram(nil, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{0, 0}, counts)
+}
+
+func TestShannonEntropy(t *testing.T) {
+	uniform := make([]byte, 256*40)
+	for i := range uniform {
+		uniform[i] = byte(i)
+	}
+	assert.InDelta(t, 8.0, shannonEntropy(uniform, 8), 1e-12)
+	assert.InDelta(t, 4.0, shannonEntropy(uniform, 4), 1e-12, "masked to 4 bits the values stay uniform")
+	assert.Equal(t, 0.0, shannonEntropy(bytes.Repeat([]byte{0x5A}, 1000), 8))
+
+	// p = (3/4, 1/4): H = -(3/4)log2(3/4) - (1/4)log2(1/4) = 0.811278...
+	assert.InDelta(t, 0.8112781244591328, shannonEntropy([]byte{0, 0, 0, 1}, 1), 1e-12)
+
+	assert.Equal(t, 0.0, shannonEntropy(nil, 8))
+	assert.Equal(t, 0.0, shannonEntropy(uniform, 0))
+}
+
+func TestNormalizeSymbols_TwoValues(t *testing.T) {
+	data := []byte{0x00, 0xFF, 0xFF, 0x00}
+	normalized, k := NormalizeSymbols(data)
+	assert.Equal(t, 2, k)
+	assert.Equal(t, []byte{0, 1, 1, 0}, normalized)
+	assert.Equal(t, []byte{0x00, 0xFF, 0xFF, 0x00}, data, "input is not modified")
+}
+
+func TestNormalizeSymbols_KeepsValueOrder(t *testing.T) {
+	normalized, k := NormalizeSymbols([]byte{0x80, 0x10, 0x42, 0x10, 0xF0})
+	assert.Equal(t, 4, k)
+	assert.Equal(t, []byte{2, 0, 1, 0, 3}, normalized)
+
+	normalized, k = NormalizeSymbols(nil)
+	assert.Equal(t, 0, k)
+	assert.Empty(t, normalized)
+}
+
+func TestAssess_NormalizeRejectsNarrowWordSize(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetNormalizeSymbols(true)
+
+	_, err := assessment.AssessNonIID([]byte{0x01, 0x02, 0x03}, 1)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+	assert.Contains(t, err.Error(), "3 distinct symbols do not fit in 1 bits per symbol")
+}
+
+func TestAlphabetSize(t *testing.T) {
+	assert.Equal(t, 0, AlphabetSize(nil))
+	assert.Equal(t, 1, AlphabetSize(bytes.Repeat([]byte{0x5A}, 1000)))
+
+	full := make([]byte, 256*4)
+	for i := range full {
+		full[i] = byte(i)
+	}
+	assert.Equal(t, 256, AlphabetSize(full))
+}
+
+func TestAlphabetBits(t *testing.T) {
+	for k, want := range map[int]int{1: 1, 2: 1, 3: 2, 4: 2, 5: 3, 16: 4, 17: 5, 256: 8} {
+		assert.Equal(t, want, alphabetBits(k), "k=%d", k)
+	}
+}
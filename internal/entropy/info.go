@@ -0,0 +1,64 @@
+package entropy
+
+// Backends reported in Result.Backend and Capabilities.Backend. The backend is
+// selected at build time: the "nocgo" build tag, or building with CGO
+// disabled, replaces the NIST C++ library with the pure-Go estimators.
+const (
+	BackendNIST = "nist-cpp" // NIST SP 800-90B C++ reference implementation
+	BackendGo   = "go"       // Pure-Go estimators, a non-conforming subset
+	BackendStub = "stub"     // Test stub returning fixed results
+)
+
+// Capabilities describes the entropy library linked into the binary.
+type Capabilities struct {
+	ToolVersion      string   `json:"tool_version"`       // NIST SP 800-90B reference tool version
+	WrapperVersion   string   `json:"wrapper_version"`    // C wrapper API version
+	CGO              bool     `json:"cgo"`                // False for teststub and pure-Go (nocgo) builds
+	Backend          string   `json:"backend"`            // BackendNIST, BackendGo or BackendStub
+	Estimators       []string `json:"estimators"`         // Estimator names accepted by SetEstimators
+	IIDEstimators    []string `json:"iid_estimators"`     // IID estimators and tests this build runs
+	NonIIDEstimators []string `json:"non_iid_estimators"` // Non-IID estimators this build runs
+}
+
+// LibraryInfo reports the versions of the linked NIST code and whether this is
+// a real CGO build. A stub build returns fixed, meaningless entropy values, so
+// clients should refuse to trust results when CGO is false. In a pure-Go build
+// the active estimator lists omit the estimators without a Go implementation.
+func LibraryInfo() Capabilities {
+	tool, wrapper := libraryVersions()
+
+	names := ValidEstimatorNames(NonIID)
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+	}
+	for _, name := range ValidEstimatorNames(IID) {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+
+	return Capabilities{
+		ToolVersion:      tool,
+		WrapperVersion:   wrapper,
+		CGO:              cgoEnabled,
+		Backend:          backend,
+		Estimators:       names,
+		IIDEstimators:    ActiveEstimators(IID),
+		NonIIDEstimators: ActiveEstimators(NonIID),
+	}
+}
+
+// ActiveEstimators returns the names of the estimators of testType that this
+// build runs, in execution order. It equals ValidEstimatorNames except in
+// pure-Go builds, which only run the estimators implemented in Go.
+func ActiveEstimators(testType TestType) []string {
+	var names []string
+	for _, e := range estimatorNamesFor(testType) {
+		if backend == BackendGo && e.bit&nativeEstimatorsFor(testType) == 0 {
+			continue
+		}
+		names = append(names, e.name)
+	}
+	return names
+}
@@ -0,0 +1,314 @@
+package entropy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// ChildFlag is the hidden command-line argument that turns a binary into an
+// isolated assessment worker. Any binary that enables IsolationSubprocess must
+// check IsChildInvocation at the top of main and hand control to RunChild.
+const ChildFlag = "-entropy-isolated-child"
+
+// childResultFD is the descriptor on which the child writes its response.
+// Stdout cannot be used because the NIST code prints its progress there.
+const childResultFD = 3
+
+// childWaitDelay bounds how long the parent waits for pipe I/O to drain after
+// the child has been killed on context cancellation.
+const childWaitDelay = 2 * time.Second
+
+// inIsolatedChild is set by RunChild so that the test stub can simulate a
+// hard crash only when it cannot take down the caller.
+var inIsolatedChild bool
+
+// childRequest is the header line sent to the child on stdin. It is followed
+// by exactly Length bytes of raw sample data.
+type childRequest struct {
+	TestType      TestType `json:"test_type"`
+	BitsPerSymbol int      `json:"bits_per_symbol"`
+	Verbose       int      `json:"verbose"`
+	EstimatorMask uint32   `json:"estimator_mask,omitempty"`
+	BitOrder      BitOrder `json:"bit_order,omitempty"`
+	PermRounds    int      `json:"permutation_rounds,omitempty"`
+	PermSeed      *uint64  `json:"permutation_seed,omitempty"`
+	Threads       int      `json:"threads,omitempty"`
+	Length        int      `json:"length"`
+}
+
+// childResponse is the JSON document written by the child on childResultFD.
+type childResponse struct {
+	Result *wireResult `json:"result,omitempty"`
+	Error  *childError `json:"error,omitempty"`
+}
+
+// childError carries an EntropyError across the process boundary. Kind names
+// the sentinel so that errors.Is keeps working in the parent.
+type childError struct {
+	Op   string `json:"op"`
+	Kind string `json:"kind"`
+	Msg  string `json:"msg"`
+}
+
+// childErrorKinds maps wire names to the sentinels that may cross the pipe.
+var childErrorKinds = map[string]error{
+	"invalid_data":      ErrInvalidData,
+	"invalid_bits":      ErrInvalidBitsPerSymbol,
+	"insufficient_data": ErrInsufficientData,
+	"c_function":        ErrCFunction,
+	"c_exception":       ErrCException,
+	"memory_allocation": ErrMemoryAllocation,
+	"unavailable":       ErrEstimatorUnavailable,
+}
+
+// wireResult is the JSON form of a Result. The aggregate entropy values are
+// shadowed with wireFloat because the estimators may legitimately report
+// +Inf, which encoding/json refuses to marshal.
+type wireResult struct {
+	*Result
+	MinEntropy wireFloat `json:"MinEntropy"`
+	HOriginal  wireFloat `json:"HOriginal"`
+	HBitstring wireFloat `json:"HBitstring"`
+	HAssessed  wireFloat `json:"HAssessed"`
+}
+
+// wireFloat is a float64 that encodes non-finite values as JSON strings.
+type wireFloat float64
+
+// MarshalJSON implements json.Marshaler.
+func (f wireFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return json.Marshal(strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (f *wireFloat) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*f = wireFloat(v)
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*f = wireFloat(v)
+	return nil
+}
+
+func toWireResult(r *Result) *wireResult {
+	return &wireResult{
+		Result:     r,
+		MinEntropy: wireFloat(r.MinEntropy),
+		HOriginal:  wireFloat(r.HOriginal),
+		HBitstring: wireFloat(r.HBitstring),
+		HAssessed:  wireFloat(r.HAssessed),
+	}
+}
+
+func (w *wireResult) toResult() *Result {
+	r := &Result{}
+	if w.Result != nil {
+		*r = *w.Result
+	}
+	r.MinEntropy = float64(w.MinEntropy)
+	r.HOriginal = float64(w.HOriginal)
+	r.HBitstring = float64(w.HBitstring)
+	r.HAssessed = float64(w.HAssessed)
+	return r
+}
+
+func toChildError(err error) *childError {
+	ce := &childError{Op: "child", Msg: err.Error()}
+	var entropyErr *EntropyError
+	if errors.As(err, &entropyErr) {
+		ce.Op = entropyErr.Op
+		ce.Msg = entropyErr.Msg
+	}
+	for kind, sentinel := range childErrorKinds {
+		if errors.Is(err, sentinel) {
+			ce.Kind = kind
+			break
+		}
+	}
+	return ce
+}
+
+func (ce *childError) toError() error {
+	sentinel, ok := childErrorKinds[ce.Kind]
+	if !ok {
+		sentinel = ErrCFunction
+	}
+	return newError(ce.Op, sentinel, ce.Msg)
+}
+
+// IsChildInvocation reports whether args (typically os.Args[1:]) request the
+// isolated worker mode.
+func IsChildInvocation(args []string) bool {
+	return len(args) > 0 && args[0] == ChildFlag
+}
+
+// RunChild serves a single isolated assessment: it reads the request from
+// stdin, runs the in-process calculation, and writes the JSON response to
+// the result descriptor inherited from the parent. It returns the process
+// exit code.
+func RunChild() int {
+	inIsolatedChild = true
+
+	out := os.NewFile(childResultFD, "entropy-result")
+	if out == nil {
+		fmt.Fprintln(os.Stderr, "entropy child: result descriptor not available")
+		return 2
+	}
+	defer out.Close()
+
+	if err := json.NewEncoder(out).Encode(serveChild(os.Stdin)); err != nil {
+		fmt.Fprintf(os.Stderr, "entropy child: failed to write result: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// serveChild decodes a childRequest and its payload from r and runs the
+// assessment in the current process.
+func serveChild(r io.Reader) childResponse {
+	br := bufio.NewReader(r)
+
+	header, err := br.ReadBytes('\n')
+	if err != nil {
+		return childResponse{Error: toChildError(newError("RunChild", ErrInvalidData, "failed to read request header"))}
+	}
+
+	var req childRequest
+	if err := json.Unmarshal(header, &req); err != nil {
+		return childResponse{Error: toChildError(newError("RunChild", ErrInvalidData, "malformed request header"))}
+	}
+	if req.Length < 0 {
+		return childResponse{Error: toChildError(newError("RunChild", ErrInvalidData, "negative payload length"))}
+	}
+
+	data := make([]byte, req.Length)
+	if _, err := io.ReadFull(br, data); err != nil {
+		return childResponse{Error: toChildError(newError("RunChild", ErrInvalidData, "truncated payload"))}
+	}
+
+	perm := permutationOptions{rounds: req.PermRounds, threads: req.Threads}
+	if req.PermSeed != nil {
+		perm.seed, perm.hasSeed = *req.PermSeed, true
+	}
+	res, err := calculateInProcess(req.TestType, data, req.BitsPerSymbol, req.Verbose, req.EstimatorMask, req.BitOrder, perm, nil)
+	if err != nil {
+		return childResponse{Error: toChildError(err)}
+	}
+	return childResponse{Result: toWireResult(res)}
+}
+
+// runIsolated re-executes the current binary with ChildFlag, streams the
+// request to it, and decodes the Result from the result pipe. A child that
+// dies without producing a response yields ErrAssessmentCrashed; cancelling
+// ctx kills the child and returns the context cause, which is
+// ErrAssessmentTimeout when the assessment's own time limit elapsed.
+func runIsolated(ctx context.Context, testType TestType, data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, perm permutationOptions) (*Result, error) {
+	const op = "runIsolated"
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, newError(op, err, "assessment cancelled before start")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, newError(op, ErrAssessmentCrashed, fmt.Sprintf("cannot locate executable: %v", err))
+	}
+
+	resultR, resultW, err := os.Pipe()
+	if err != nil {
+		return nil, newError(op, ErrAssessmentCrashed, fmt.Sprintf("failed to create result pipe: %v", err))
+	}
+	defer resultR.Close()
+
+	cmd := exec.CommandContext(ctx, exe, ChildFlag)
+	// The NIST code writes progress to stdout; keep it out of the result pipe.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{resultW}
+	cmd.WaitDelay = childWaitDelay
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		resultW.Close()
+		return nil, newError(op, ErrAssessmentCrashed, fmt.Sprintf("failed to create stdin pipe: %v", err))
+	}
+
+	if err := cmd.Start(); err != nil {
+		resultW.Close()
+		return nil, newError(op, ErrAssessmentCrashed, fmt.Sprintf("failed to start child process: %v", err))
+	}
+	// Only the child may hold the write end, so a dead child yields EOF.
+	resultW.Close()
+
+	go func() {
+		defer stdin.Close()
+		req := childRequest{
+			TestType:      testType,
+			BitsPerSymbol: bitsPerSymbol,
+			Verbose:       verbose,
+			EstimatorMask: mask,
+			BitOrder:      order,
+			PermRounds:    perm.rounds,
+			Threads:       perm.threads,
+			Length:        len(data),
+		}
+		if perm.hasSeed {
+			req.PermSeed = &perm.seed
+		}
+		header, err := json.Marshal(req)
+		if err != nil {
+			return
+		}
+		if _, err := stdin.Write(append(header, '\n')); err != nil {
+			return
+		}
+		_, _ = stdin.Write(data) // a write error means the child died; Wait reports it
+	}()
+
+	var resp childResponse
+	decodeErr := json.NewDecoder(resultR).Decode(&resp)
+	waitErr := cmd.Wait()
+
+	if ctxErr := context.Cause(ctx); ctxErr != nil {
+		return nil, newError(op, ctxErr, "assessment cancelled, child process killed")
+	}
+	if waitErr != nil {
+		return nil, newError(op, ErrAssessmentCrashed, fmt.Sprintf("child process exited: %v", waitErr))
+	}
+	if decodeErr != nil {
+		return nil, newError(op, ErrAssessmentCrashed, fmt.Sprintf("child process returned no result: %v", decodeErr))
+	}
+	if resp.Error != nil {
+		return nil, resp.Error.toError()
+	}
+	if resp.Result == nil {
+		return nil, newError(op, ErrAssessmentCrashed, "child process returned an empty response")
+	}
+
+	return resp.Result.toResult(), nil
+}
@@ -0,0 +1,128 @@
+//go:build teststub
+
+package entropy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIsolatedAssessment() *Assessment {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetIsolation(IsolationSubprocess)
+	return assessment
+}
+
+func TestIsolation_SuccessMatchesInProcess(t *testing.T) {
+	data := []byte{1, 2, 3, 4}
+
+	isolated, err := newIsolatedAssessment().AssessNonIID(data, 8)
+	require.NoError(t, err)
+
+	inProcess := NewAssessment()
+	inProcess.SetVerbose(0)
+	want, err := inProcess.AssessNonIID(data, 8)
+	require.NoError(t, err)
+
+	// The timings differ between runs; the child must still report them.
+	assert.Contains(t, isolated.Timings, "Non-IID")
+	want.Timings, isolated.Timings = nil, nil
+	assert.Equal(t, want, isolated)
+}
+
+func TestIsolation_Histogram(t *testing.T) {
+	assessment := newIsolatedAssessment()
+	assessment.SetHistogram(true)
+
+	res, err := assessment.AssessIID([]byte{7, 7, 9}, 8)
+	require.NoError(t, err)
+	require.Len(t, res.Histogram, HistogramSize)
+	assert.Equal(t, uint64(2), res.Histogram[7])
+	assert.Equal(t, uint64(1), res.Histogram[9])
+}
+
+func TestIsolation_RawJSON(t *testing.T) {
+	assessment := newIsolatedAssessment()
+	assessment.SetRawJSON(true)
+
+	res, err := assessment.AssessNonIID([]byte{7, 7, 9}, 8)
+	require.NoError(t, err)
+	require.NotEmpty(t, res.RawJSON)
+	assert.True(t, json.Valid(res.RawJSON))
+}
+
+func TestIsolation_BitOrder(t *testing.T) {
+	assessment := newIsolatedAssessment()
+	assessment.SetBitOrder(LSBFirst)
+
+	res, err := assessment.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.InDelta(t, 6.1-stubLSBFirstShift, res.HBitstring, 1e-9)
+}
+
+func TestIsolation_PermutationOptions(t *testing.T) {
+	assessment := newIsolatedAssessment()
+	assessment.SetPermutationRounds(250)
+	assessment.SetPermutationSeed(3)
+
+	_, tests, err := assessment.CheckIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	require.Len(t, tests, 3)
+	assert.Equal(t, map[string]float64{"rounds": 250, "seed": 3}, tests[2].Details)
+}
+
+func TestIsolation_PropagatesAssessmentError(t *testing.T) {
+	_, err := newIsolatedAssessment().AssessIID([]byte{0xFF, 1, 2}, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+	assert.Contains(t, err.Error(), "stub failure")
+}
+
+func TestIsolation_PropagatesCException(t *testing.T) {
+	_, err := newIsolatedAssessment().AssessNonIID([]byte{0xFC, 1, 2}, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrCException))
+	assert.Contains(t, err.Error(), "Logic error: injected logic_error")
+}
+
+func TestIsolation_InfinityResult(t *testing.T) {
+	res, err := newIsolatedAssessment().AssessIID([]byte{0xEE, 1, 2}, 8)
+	require.NoError(t, err)
+	assert.True(t, res.MinEntropy > 8)
+}
+
+func TestIsolation_ChildCrash(t *testing.T) {
+	_, err := newIsolatedAssessment().AssessNonIID([]byte{0xDD, 1, 2}, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrAssessmentCrashed))
+	assert.True(t, strings.Contains(err.Error(), "exit status 134"))
+}
+
+func TestInProcess_SimulatedCrashDoesNotExit(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	_, err := assessment.AssessIID([]byte{0xDD, 1, 2}, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrAssessmentCrashed))
+}
+
+func TestIsolation_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := newIsolatedAssessment().AssessIIDContext(ctx, []byte{1, 2, 3}, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = NewAssessment().AssessNonIIDContext(ctx, []byte{1, 2, 3}, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
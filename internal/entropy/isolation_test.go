@@ -0,0 +1,116 @@
+package entropy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain lets the test binary double as the isolated child so that
+// subprocess isolation can be exercised end to end.
+func TestMain(m *testing.M) {
+	if IsChildInvocation(os.Args[1:]) {
+		os.Exit(RunChild())
+	}
+	os.Exit(m.Run())
+}
+
+func TestParseIsolationMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    IsolationMode
+		wantErr bool
+	}{
+		{"", IsolationInProcess, false},
+		{"inprocess", IsolationInProcess, false},
+		{" SubProcess ", IsolationSubprocess, false},
+		{"thread", IsolationInProcess, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseIsolationMode(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIsolationMode_String(t *testing.T) {
+	assert.Equal(t, "inprocess", IsolationInProcess.String())
+	assert.Equal(t, "subprocess", IsolationSubprocess.String())
+	assert.Equal(t, "unknown", IsolationMode(42).String())
+}
+
+func TestAssessment_SetIsolation(t *testing.T) {
+	assessment := NewAssessment()
+	assert.Equal(t, IsolationInProcess, assessment.GetIsolation())
+
+	assessment.SetIsolation(IsolationSubprocess)
+	assert.Equal(t, IsolationSubprocess, assessment.GetIsolation())
+}
+
+func TestIsChildInvocation(t *testing.T) {
+	assert.True(t, IsChildInvocation([]string{ChildFlag}))
+	assert.False(t, IsChildInvocation([]string{"-iid"}))
+	assert.False(t, IsChildInvocation(nil))
+}
+
+func TestWireResult_RoundTripInfinity(t *testing.T) {
+	in := &Result{
+		MinEntropy:   math.Inf(1),
+		HOriginal:    7.25,
+		HBitstring:   math.Inf(1),
+		HAssessed:    math.Inf(1),
+		DataWordSize: 8,
+		TestType:     NonIID,
+		Estimators: []EstimatorResult{
+			{Name: "Most Common Value", EntropyEstimate: 7.25, Passed: true, IsEntropyValid: true},
+		},
+	}
+
+	raw, err := json.Marshal(childResponse{Result: toWireResult(in)})
+	require.NoError(t, err)
+
+	var resp childResponse
+	require.NoError(t, json.Unmarshal(raw, &resp))
+	require.NotNil(t, resp.Result)
+	assert.Equal(t, in, resp.Result.toResult())
+}
+
+func TestChildError_PreservesSentinel(t *testing.T) {
+	ce := toChildError(newError("calculateIIDEntropy", ErrInvalidData, "bad input"))
+	assert.Equal(t, "invalid_data", ce.Kind)
+
+	err := ce.toError()
+	assert.True(t, errors.Is(err, ErrInvalidData))
+	assert.Contains(t, err.Error(), "bad input")
+
+	unknown := (&childError{Op: "x", Kind: "bogus", Msg: "m"}).toError()
+	assert.True(t, errors.Is(unknown, ErrCFunction))
+}
+
+func TestServeChild_MalformedRequests(t *testing.T) {
+	resp := serveChild(strings.NewReader("not json\n"))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, "invalid_data", resp.Error.Kind)
+
+	resp = serveChild(strings.NewReader(""))
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Msg, "header")
+
+	resp = serveChild(bytes.NewReader([]byte(`{"length":10}` + "\n" + "abc")))
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Msg, "truncated")
+}
@@ -0,0 +1,38 @@
+package entropy
+
+import "fmt"
+
+// LRSResult is the outcome of LongestRepeatedSubstring.
+type LRSResult struct {
+	// Length is the number of symbols in the longest substring that occurs
+	// at least twice, possibly overlapping; 0 when no symbol repeats.
+	Length int `json:"length"`
+	// Estimate is the Longest Repeated Substring estimate of SP 800-90B
+	// Section 6.3.6 in bits per symbol, or -1 when it cannot be computed
+	// because no substring is both repeated and longer than the t-Tuple
+	// cutoff allows.
+	Estimate float64 `json:"estimate"`
+	// BitsPerSymbol is the word size the samples were masked to, detected
+	// from the data when 0 was requested.
+	BitsPerSymbol int `json:"bits_per_symbol"`
+}
+
+// LongestRepeatedSubstring computes the length of the longest repeated
+// substring of the samples and the LRS estimate of SP 800-90B Section 6.3.6
+// on them, without running the rest of an assessment. Symbols are masked to
+// bitsPerSymbol bits as in a full assessment; 0 detects the word size from
+// the data. The NIST library computes it where it is linked; pure-Go and stub
+// builds use the suffix-array implementation of the native estimators.
+//
+// Only the literal symbols are examined; a Non-IID assessment of data with
+// more than one bit per symbol also applies the estimate to the bitstring.
+func LongestRepeatedSubstring(data []byte, bitsPerSymbol int) (LRSResult, error) {
+	const op = "LongestRepeatedSubstring"
+	if bitsPerSymbol < 0 || bitsPerSymbol > 8 {
+		return LRSResult{}, newError(op, ErrInvalidBitsPerSymbol, fmt.Sprintf("got %d", bitsPerSymbol))
+	}
+	if len(data) < 2 {
+		return LRSResult{}, newError(op, ErrInsufficientData, fmt.Sprintf("need at least 2 samples, got %d", len(data)))
+	}
+	return calculateLRS(data, bitsPerSymbol)
+}
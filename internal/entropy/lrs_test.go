@@ -0,0 +1,61 @@
+package entropy
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLongestRepeatedSubstring_KnownRepeats(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "run separated by a marker", data: []byte("abcXabc"), want: 3},
+		{name: "repeated phrase", data: []byte("the cat sat on the mat, the cat sat"), want: 11},
+		{name: "overlapping alternation", data: bytes.Repeat([]byte{0, 1}, 50), want: 98},
+		{name: "no repeats", data: []byte("abcdefgh"), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LongestRepeatedSubstring(tt.data, 8)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Length)
+			assert.Equal(t, 8, got.BitsPerSymbol)
+
+			_, want := countingEstimates(tt.data)
+			assert.InDelta(t, want, got.Estimate, 1e-12)
+		})
+	}
+}
+
+func TestLongestRepeatedSubstring_NoEstimateWithoutRepeats(t *testing.T) {
+	got, err := LongestRepeatedSubstring([]byte("abcdefgh"), 8)
+	require.NoError(t, err)
+	assert.Equal(t, -1.0, got.Estimate)
+}
+
+func TestLongestRepeatedSubstring_MasksAndDetectsWordSize(t *testing.T) {
+	// Masked to 4 bits, 0x10 0x21 and 0x30 0x41 become 0 1 0 1.
+	got, err := LongestRepeatedSubstring([]byte{0x10, 0x21, 0x30, 0x41}, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Length)
+	assert.Equal(t, 4, got.BitsPerSymbol)
+
+	got, err = LongestRepeatedSubstring([]byte("abcXabc"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Length)
+	assert.Equal(t, 7, got.BitsPerSymbol)
+}
+
+func TestLongestRepeatedSubstring_RejectsInvalidInput(t *testing.T) {
+	_, err := LongestRepeatedSubstring([]byte{1}, 8)
+	assert.True(t, errors.Is(err, ErrInsufficientData))
+
+	_, err = LongestRepeatedSubstring([]byte{1, 2}, 9)
+	assert.True(t, errors.Is(err, ErrInvalidBitsPerSymbol))
+}
@@ -0,0 +1,150 @@
+package entropy
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+)
+
+// MemoryModel predicts the peak memory of an assessment in the NIST library
+// from the number of samples n, the bits per symbol w, and the test type:
+//
+//	common  = Base + n × (PerSample + PerBit × b)
+//	IID     = common + n × (IIDPerSample + IIDPerWorkerSample × workers)
+//	Non-IID = common + n × (NonIIDPerSample + NonIIDPerBit × b)
+//
+// where b is w for multi-bit data and 0 for binary data, whose bitstring
+// shares the symbol buffer. The coefficients are bytes and may be tuned to a
+// particular build; DefaultMemoryModel documents where each one comes from.
+type MemoryModel struct {
+	Base      uint64  // Fixed overhead of an assessment
+	PerSample float64 // Symbol buffers of every assessment
+	PerBit    float64 // Bitstring expansion of multi-bit data
+
+	IIDPerSample       float64 // Suffix arrays of the LRS test
+	IIDPerWorkerSample float64 // Per-thread buffers of the permutation tests
+	Workers            int     // Permutation test threads; zero means runtime.NumCPU
+
+	NonIIDPerSample float64 // Suffix arrays over the symbols
+	NonIIDPerBit    float64 // Suffix arrays over the bitstring
+}
+
+// DefaultMemoryModel returns the coefficients derived from the allocations
+// of the NIST library:
+//
+//   - Base, 32 MiB: result structures, estimator tables and the LZ78Y and
+//     MultiMMC dictionaries, whose size does not depend on the input.
+//   - PerSample, 2: the symbols and rawsymbols buffers of one byte each.
+//   - PerBit, 1: the bitstring, one byte per bit.
+//   - IIDPerSample and NonIIDPerSample, 12: the suffix, LCP and rank arrays
+//     of the LRS and t-tuple estimators, 32-bit indices each.
+//   - IIDPerWorkerSample, 14: each permutation thread copies both symbol
+//     buffers (2), builds an int alternating sequence (4) and a decimal text
+//     and its bzip2 output for the compression statistic (8).
+//   - NonIIDPerBit, 12: the same suffix arrays over the bitstring.
+func DefaultMemoryModel() MemoryModel {
+	return MemoryModel{
+		Base:      32 << 20,
+		PerSample: 2,
+		PerBit:    1,
+
+		IIDPerSample:       12,
+		IIDPerWorkerSample: 14,
+
+		NonIIDPerSample: 12,
+		NonIIDPerBit:    12,
+	}
+}
+
+// EstimateMemory predicts the peak memory in bytes of assessing samples
+// samples of bitsPerSymbol bits with DefaultMemoryModel. A bitsPerSymbol of
+// 0 assumes the worst case of 8 bits.
+func EstimateMemory(samples, bitsPerSymbol int, testType TestType) uint64 {
+	return DefaultMemoryModel().Estimate(samples, bitsPerSymbol, testType)
+}
+
+// Estimate predicts the peak memory in bytes of assessing samples samples of
+// bitsPerSymbol bits. A bitsPerSymbol of 0 assumes the worst case of 8 bits.
+// The result saturates at math.MaxUint64.
+func (m MemoryModel) Estimate(samples, bitsPerSymbol int, testType TestType) uint64 {
+	if samples <= 0 {
+		return m.Base
+	}
+	if bitsPerSymbol <= 0 || bitsPerSymbol > 8 {
+		bitsPerSymbol = 8
+	}
+	bits := 0.0
+	if bitsPerSymbol > 1 {
+		bits = float64(bitsPerSymbol)
+	}
+
+	perSample := m.PerSample + m.PerBit*bits
+	switch testType {
+	case IID:
+		workers := m.Workers
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		perSample += m.IIDPerSample + m.IIDPerWorkerSample*float64(workers)
+	case NonIID:
+		perSample += m.NonIIDPerSample + m.NonIIDPerBit*bits
+	}
+
+	estimate := float64(m.Base) + float64(samples)*perSample
+	if estimate >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return uint64(math.Ceil(estimate))
+}
+
+// SetMemoryBudget rejects assessments whose estimated peak memory exceeds
+// bytes with ErrResourceLimit before they start. Zero disables the check.
+func (a *Assessment) SetMemoryBudget(bytes uint64) {
+	a.memoryBudget = bytes
+}
+
+// GetMemoryBudget returns the memory budget; zero means none.
+func (a *Assessment) GetMemoryBudget() uint64 {
+	return a.memoryBudget
+}
+
+// SetMemoryModel replaces the model used to check the memory budget. A nil
+// model restores DefaultMemoryModel.
+func (a *Assessment) SetMemoryModel(m *MemoryModel) {
+	if m == nil {
+		a.memoryModel = nil
+		return
+	}
+	model := *m
+	a.memoryModel = &model
+}
+
+// GetMemoryModel returns the model used to check the memory budget.
+func (a *Assessment) GetMemoryModel() MemoryModel {
+	if a.memoryModel == nil {
+		return DefaultMemoryModel()
+	}
+	return *a.memoryModel
+}
+
+// checkMemory rejects data whose estimated peak memory exceeds the budget.
+// Auto-detection is resolved first so that narrow data is not charged for
+// eight bits per symbol, and a thread cap limits the permutation workers.
+func (a *Assessment) checkMemory(op string, testType TestType, data []byte, bitsPerSymbol int) error {
+	if a.memoryBudget == 0 {
+		return nil
+	}
+	if bitsPerSymbol == 0 {
+		bitsPerSymbol = detectWordSize(data)
+	}
+	model := a.GetMemoryModel()
+	if threads := a.permutation().threads; threads > 0 && (model.Workers <= 0 || model.Workers > threads) {
+		model.Workers = threads
+	}
+	estimate := model.Estimate(len(data), bitsPerSymbol, testType)
+	if estimate > a.memoryBudget {
+		return newError(op, ErrResourceLimit,
+			fmt.Sprintf("estimated peak memory of %d bytes exceeds the budget of %d bytes", estimate, a.memoryBudget))
+	}
+	return nil
+}
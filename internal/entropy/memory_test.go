@@ -0,0 +1,127 @@
+package entropy
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultMemoryModel_Coefficients(t *testing.T) {
+	assert.Equal(t, MemoryModel{
+		Base:      32 << 20,
+		PerSample: 2,
+		PerBit:    1,
+
+		IIDPerSample:       12,
+		IIDPerWorkerSample: 14,
+
+		NonIIDPerSample: 12,
+		NonIIDPerBit:    12,
+	}, DefaultMemoryModel())
+}
+
+func TestMemoryModel_Estimate(t *testing.T) {
+	model := DefaultMemoryModel()
+	model.Workers = 4
+	const base = 32 << 20
+	const n = 1000000
+
+	tests := []struct {
+		name     string
+		bits     int
+		testType TestType
+		want     uint64
+	}{
+		// 2 + 12 + 14×4 bytes per sample; binary data has no bitstring.
+		{"iid binary", 1, IID, base + n*70},
+		// 2 + 1×8 + 12 + 14×4 bytes per sample.
+		{"iid bytes", 8, IID, base + n*78},
+		// 2 + 12 bytes per sample.
+		{"non-iid binary", 1, NonIID, base + n*14},
+		// 2 + 1×4 + 12 + 12×4 bytes per sample.
+		{"non-iid nibbles", 4, NonIID, base + n*66},
+		// 2 + 1×8 + 12 + 12×8 bytes per sample.
+		{"non-iid bytes", 8, NonIID, base + n*118},
+		// Auto-detection assumes the worst case.
+		{"non-iid auto", 0, NonIID, base + n*118},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, model.Estimate(n, tt.bits, tt.testType))
+		})
+	}
+}
+
+func TestMemoryModel_EstimateEdgeCases(t *testing.T) {
+	model := DefaultMemoryModel()
+	assert.Equal(t, model.Base, model.Estimate(0, 8, NonIID))
+	assert.Equal(t, model.Base, model.Estimate(-1, 8, NonIID))
+
+	model.Workers = 0
+	assert.Equal(t, EstimateMemory(1000, 8, IID), model.Estimate(1000, 8, IID))
+
+	huge := MemoryModel{PerSample: math.MaxFloat64}
+	assert.Equal(t, uint64(math.MaxUint64), huge.Estimate(math.MaxInt, 8, NonIID))
+}
+
+func TestAssessment_MemoryBudget(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i % 16)
+	}
+
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetMemoryBudget(1 << 20)
+	assert.Equal(t, uint64(1<<20), assessment.GetMemoryBudget())
+
+	_, err := assessment.AssessNonIID(data, 4)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrResourceLimit))
+	assert.Contains(t, err.Error(), "exceeds the budget of 1048576 bytes")
+
+	_, err = assessment.AssessIID(data, 0)
+	assert.True(t, errors.Is(err, ErrResourceLimit))
+
+	_, _, err = assessment.CheckIID(data, 4)
+	assert.True(t, errors.Is(err, ErrResourceLimit))
+}
+
+func TestAssessment_CheckMemory(t *testing.T) {
+	assessment := NewAssessment()
+	assert.NoError(t, assessment.checkMemory("test", NonIID, make([]byte, 1<<20), 8), "no budget")
+
+	model := MemoryModel{PerSample: 1, PerBit: 1}
+	assessment.SetMemoryModel(&model)
+	model.PerSample = 100 // the assessment keeps its own copy
+	assert.Equal(t, MemoryModel{PerSample: 1, PerBit: 1}, assessment.GetMemoryModel())
+
+	// Auto-detected 4-bit data needs 1 + 4 bytes per sample.
+	data := []byte{0x0F, 0x01, 0x02, 0x03}
+	assessment.SetMemoryBudget(20)
+	assert.NoError(t, assessment.checkMemory("test", NonIID, data, 0))
+	assessment.SetMemoryBudget(19)
+	assert.ErrorIs(t, assessment.checkMemory("test", NonIID, data, 0), ErrResourceLimit)
+
+	assessment.SetMemoryModel(nil)
+	assert.Equal(t, DefaultMemoryModel(), assessment.GetMemoryModel())
+}
+
+func TestAssessment_CheckMemoryThreads(t *testing.T) {
+	// Binary IID data needs 1 byte per sample and 1 per permutation worker.
+	assessment := NewAssessment()
+	assessment.SetMemoryModel(&MemoryModel{PerSample: 1, IIDPerWorkerSample: 1, Workers: 8})
+	data := make([]byte, 100)
+	assessment.SetMemoryBudget(300)
+	assert.ErrorIs(t, assessment.checkMemory("test", IID, data, 1), ErrResourceLimit)
+
+	assessment.SetThreads(2)
+	assert.Equal(t, 2, assessment.GetThreads())
+	assert.NoError(t, assessment.checkMemory("test", IID, data, 1), "two workers need 300 bytes")
+
+	assessment.SetThreads(-1)
+	assert.Equal(t, 0, assessment.GetThreads())
+}
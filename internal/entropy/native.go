@@ -0,0 +1,360 @@
+package entropy
+
+import (
+	"fmt"
+	"math"
+)
+
+// zAlpha is the 99.5th percentile of the standard normal distribution used
+// for the upper confidence bounds of SP 800-90B; it matches ZALPHA in the NIST
+// reference implementation.
+const zAlpha = 2.5758293035489008
+
+// nativeEstimatorsFor returns the mask of estimators of testType implemented
+// in pure Go. The IID LRS bit selects the pass/fail test of Section 5.2.5, not
+// the LRS estimate, and has no pure-Go implementation.
+func nativeEstimatorsFor(testType TestType) uint32 {
+	if testType == IID {
+		return estimatorMCV | estimatorChiSquare | estimatorPermutation
+	}
+	return estimatorMCV | estimatorCollision | estimatorTTuple | estimatorLRS |
+		estimatorMultiMCW | estimatorLag | estimatorMultiMMC | estimatorLZ78Y
+}
+
+// nativePredictors lists the prediction estimates of Sections 6.3.7 to 6.3.10
+// in the order the wrapper runs them.
+var nativePredictors = []struct {
+	bit  uint32
+	id   EstimatorID
+	name string
+	run  func(s []byte, k int, verbose int, label string) prediction
+}{
+	{estimatorMultiMCW, EstimatorMultiMCW, "Multi Most Common in Window Test", multiMCW},
+	{estimatorLag, EstimatorLag, "Lag Prediction Test", lag},
+	{estimatorMultiMMC, EstimatorMultiMMC, "Multi Markov Model with Counting Test", multiMMC},
+	{estimatorLZ78Y, EstimatorLZ78Y, "LZ78Y Test", lz78y},
+}
+
+// nativeData is the Go counterpart of the wrapper's prepared data_t: the
+// symbols masked to the word size and mapped down to a dense alphabet, their
+// binary expansion, and the input bytes as given.
+type nativeData struct {
+	wordSize  int
+	alphSize  int
+	symbols   []byte
+	raw       []byte
+	bitstring []byte
+	histogram []uint64
+}
+
+// prepareNativeData mirrors prepare_data in wrapper.cpp: it detects the word
+// size when bitsPerSymbol is 0, masks and counts the symbols, maps them down
+// to 0..alphSize-1 and expands the unmapped values into a bitstring in the
+// given order.
+func prepareNativeData(data []byte, bitsPerSymbol int, order BitOrder) *nativeData {
+	wordSize := bitsPerSymbol
+	if wordSize == 0 {
+		wordSize = detectWordSize(data)
+	}
+
+	symbolMask := byte(1<<uint(wordSize) - 1)
+	d := &nativeData{
+		wordSize:  wordSize,
+		symbols:   make([]byte, len(data)),
+		raw:       data,
+		bitstring: make([]byte, 0, len(data)*wordSize),
+		histogram: make([]uint64, HistogramSize),
+	}
+	for i, b := range data {
+		raw := b & symbolMask
+		d.symbols[i] = raw
+		d.histogram[raw]++
+		for j := 0; j < wordSize; j++ {
+			shift := wordSize - 1 - j
+			if order == LSBFirst {
+				shift = j
+			}
+			d.bitstring = append(d.bitstring, (raw>>uint(shift))&1)
+		}
+	}
+
+	var mapDown [HistogramSize]byte
+	for value, count := range d.histogram {
+		if count > 0 {
+			mapDown[value] = byte(d.alphSize)
+			d.alphSize++
+		}
+	}
+	for i, s := range d.symbols {
+		d.symbols[i] = mapDown[s]
+	}
+	return d
+}
+
+// detectWordSize returns the position of the highest bit set in any sample,
+// the word size the reference tool assumes when none is given. Data without
+// any bit set yields 1.
+func detectWordSize(data []byte) int {
+	var datamask byte
+	for _, b := range data {
+		datamask |= b
+	}
+	for w := 8; w > 1; w-- {
+		if datamask&(1<<uint(w-1)) != 0 {
+			return w
+		}
+	}
+	return 1
+}
+
+// mostCommonValue implements the Most Common Value estimate of SP 800-90B
+// Section 6.3.1: the min-entropy implied by the upper 99% confidence bound on
+// the probability of the most frequent symbol.
+func mostCommonValue(symbols []byte, verbose int, label string) float64 {
+	var counts [HistogramSize]int
+	mode := 0
+	for _, s := range symbols {
+		counts[s]++
+		if counts[s] > mode {
+			mode = counts[s]
+		}
+	}
+
+	n := float64(len(symbols))
+	pmax := float64(mode) / n
+	ubound := math.Min(1, pmax+zAlpha*math.Sqrt(pmax*(1-pmax)/(n-1)))
+	entEst := -math.Log2(ubound)
+	if verbose == 2 {
+		fmt.Printf("%s MCV Estimate: mode = %d, p-hat = %.17g, p_u = %.17g\n", label, mode, pmax, ubound)
+	}
+	return entEst
+}
+
+// collision implements the Collision estimate of SP 800-90B Section 6.3.2 on
+// binary data. It measures the mean number of samples until a repeated value
+// and derives p from the lower confidence bound on that mean. Because
+// F(q) = q(2q^2 + 2q + 1) for binary data, the equation of step 7 reduces to
+// X' = -2p^2 + 2p + 2, whose root in [0.5, 1] is computed directly, as in the
+// reference implementation, instead of by binary search.
+func collision(bits []byte, verbose int, label string) float64 {
+	v, i := 0, 0
+	sumSquares := 0.0
+	for i < len(bits)-1 {
+		t := 2
+		if bits[i] != bits[i+1] {
+			if i >= len(bits)-2 {
+				break
+			}
+			t = 3
+		}
+		v++
+		sumSquares += float64(t * t)
+		i += t
+	}
+
+	mean := float64(i) / float64(v)
+	sigma := math.Sqrt((sumSquares - float64(i)*mean) / float64(v-1))
+	bound := math.Max(2, mean-zAlpha*sigma/math.Sqrt(float64(v)))
+
+	p, entEst := 0.5, 1.0
+	if bound < 2.5 {
+		p = 0.5 + math.Sqrt(1.25-0.5*bound)
+		entEst = -math.Log2(p)
+	}
+	if verbose == 2 {
+		fmt.Printf("%s Collision Estimate: X-bar = %.17g, sigma-hat = %.17g, p = %.17g\n", label, mean, sigma, p)
+	}
+	return entEst
+}
+
+// calculateNative runs the estimators selected by mask that have a pure-Go
+// implementation and combines them into H_original, H_bitstring and
+// H_assessed as the wrapper does. The result is marked Partial when the
+// selection includes estimators that are only available through the NIST
+// library; a selection without any such estimator is rejected. perm only
+// applies to the IID permutation tests. progress receives the phases as the
+// wrapper reports them.
+func calculateNative(op string, testType TestType, data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, perm permutationOptions, progress ProgressFunc) (*Result, error) {
+	if len(data) == 0 {
+		return nil, newError(op, ErrInvalidData, "data is empty")
+	}
+
+	requested := mask
+	if requested == 0 {
+		for _, e := range estimatorNamesFor(testType) {
+			requested |= e.bit
+		}
+	}
+	native := nativeEstimatorsFor(testType)
+	selected := requested & native
+	if selected == 0 {
+		return nil, newError(op, ErrEstimatorUnavailable, "none of the selected estimators has a pure-Go implementation")
+	}
+
+	d := prepareNativeData(data, bitsPerSymbol, order)
+	if d.alphSize <= 1 {
+		return nil, newError(op, ErrInvalidData, "symbol alphabet consists of 1 symbol, no entropy awarded")
+	}
+
+	tracker := newProgressTracker(progress, testType, selected)
+	hOriginal := float64(d.wordSize)
+	hBitstring := 1.0
+	var estimators []EstimatorResult
+
+	if selected&estimatorMCV != 0 {
+		tracker.start("Most Common Value")
+		if d.alphSize > 2 {
+			hBitstring = math.Min(hBitstring, mostCommonValue(d.bitstring, verbose, "Bitstring"))
+		}
+		mcv := mostCommonValue(d.symbols, verbose, "Literal")
+		hOriginal = math.Min(hOriginal, mcv)
+		estimators = append(estimators, EstimatorResult{
+			Name:            "Most Common Value",
+			ID:              EstimatorMCV,
+			EntropyEstimate: mcv,
+			Passed:          true,
+			IsEntropyValid:  true,
+		})
+	}
+
+	if selected&estimatorChiSquare != 0 {
+		tracker.start("Chi-Square Tests")
+		res := chiSquareTests(d.symbols, d.alphSize, verbose)
+		estimators = append(estimators, EstimatorResult{
+			Name:            "Chi-Square Tests",
+			ID:              EstimatorChiSquare,
+			EntropyEstimate: -1,
+			Passed:          res.Passed,
+			Details:         res.details(),
+		})
+	}
+
+	if selected&estimatorPermutation != 0 {
+		tracker.start("Permutation Tests")
+		res := permutationTests(newPermutationInput(d), perm, verbose)
+		estimators = append(estimators, EstimatorResult{
+			Name:            "Permutation Tests",
+			ID:              EstimatorPermutation,
+			EntropyEstimate: -1,
+			Passed:          res.Passed,
+			Details:         res.details(),
+		})
+	}
+
+	// The Collision estimate is defined for binary data only: it runs on the
+	// bitstring, or on the symbols themselves for a two-symbol alphabet.
+	if selected&estimatorCollision != 0 {
+		tracker.start("Collision Test")
+		var estimate float64
+		if d.alphSize > 2 {
+			estimate = collision(d.bitstring, verbose, "Bitstring")
+			hBitstring = math.Min(hBitstring, estimate)
+		} else {
+			estimate = collision(d.symbols, verbose, "Literal")
+			hOriginal = math.Min(hOriginal, estimate)
+		}
+		estimators = append(estimators, EstimatorResult{
+			Name:            "Collision Test",
+			ID:              EstimatorCollision,
+			EntropyEstimate: estimate,
+			Passed:          true,
+			IsEntropyValid:  true,
+		})
+	}
+
+	// The t-Tuple and LRS estimates share one suffix-array pass per string.
+	// An estimate that cannot be computed is reported as invalid and left
+	// out of the entropy fields.
+	if selected&(estimatorTTuple|estimatorLRS) != 0 {
+		tracker.start(tTupleLRSPhase)
+		tTuple, lrs := -1.0, -1.0
+		if d.alphSize > 2 {
+			bitTTuple, bitLRS := suffixEstimates(d.bitstring, verbose, "Bitstring")
+			if selected&estimatorTTuple != 0 && bitTTuple >= 0 {
+				hBitstring = math.Min(hBitstring, bitTTuple)
+				tTuple = bitTTuple
+			}
+			if selected&estimatorLRS != 0 && bitLRS >= 0 {
+				hBitstring = math.Min(hBitstring, bitLRS)
+				lrs = bitLRS
+			}
+		}
+		symTTuple, symLRS := suffixEstimates(d.symbols, verbose, "Literal")
+		if selected&estimatorTTuple != 0 && symTTuple >= 0 {
+			hOriginal = math.Min(hOriginal, symTTuple)
+			tTuple = symTTuple
+		}
+		if selected&estimatorLRS != 0 && symLRS >= 0 {
+			hOriginal = math.Min(hOriginal, symLRS)
+			lrs = symLRS
+		}
+		if selected&estimatorTTuple != 0 {
+			estimators = append(estimators, EstimatorResult{
+				Name:            "t-Tuple Test",
+				ID:              EstimatorTTuple,
+				EntropyEstimate: tTuple,
+				Passed:          tTuple >= 0,
+				IsEntropyValid:  tTuple >= 0,
+			})
+		}
+		if selected&estimatorLRS != 0 {
+			estimators = append(estimators, EstimatorResult{
+				Name:            "LRS Test",
+				ID:              EstimatorLRS,
+				EntropyEstimate: lrs,
+				Passed:          lrs >= 0,
+				IsEntropyValid:  lrs >= 0,
+			})
+		}
+	}
+
+	// Each prediction estimate runs on the bitstring and on the symbols; the
+	// reported bounds are those of the last run, as in the wrapper.
+	for _, p := range nativePredictors {
+		if selected&p.bit == 0 {
+			continue
+		}
+		tracker.start(p.name)
+		res := unavailablePrediction
+		if d.alphSize > 2 {
+			if bit := p.run(d.bitstring, 2, verbose, "Bitstring"); bit.Entropy >= 0 {
+				hBitstring = math.Min(hBitstring, bit.Entropy)
+				res = bit
+			}
+		}
+		if sym := p.run(d.symbols, d.alphSize, verbose, "Literal"); sym.Entropy >= 0 {
+			hOriginal = math.Min(hOriginal, sym.Entropy)
+			res = sym
+		}
+		estimator := EstimatorResult{
+			Name:            p.name,
+			ID:              p.id,
+			EntropyEstimate: res.Entropy,
+			Passed:          res.Entropy >= 0,
+			IsEntropyValid:  res.Entropy >= 0,
+		}
+		if res.Entropy >= 0 {
+			estimator.Details = res.details()
+		}
+		estimators = append(estimators, estimator)
+	}
+
+	hAssessed := float64(d.wordSize)
+	if d.alphSize > 2 {
+		hAssessed = math.Min(hAssessed, hBitstring*float64(d.wordSize))
+	}
+	hAssessed = math.Min(hAssessed, hOriginal)
+	tracker.done()
+
+	return &Result{
+		MinEntropy:   hAssessed,
+		HOriginal:    hOriginal,
+		HBitstring:   hBitstring,
+		HAssessed:    hAssessed,
+		DataWordSize: d.wordSize,
+		TestType:     testType,
+		Partial:      requested&^native != 0,
+		Histogram:    d.histogram,
+		Estimators:   estimators,
+	}, nil
+}
@@ -0,0 +1,424 @@
+package entropy
+
+// The compression statistic of the permutation tests (Section 5.1.11) is the
+// length of the data compressed with bzip2 at block size 5. Only that length
+// matters, so this file reproduces the encoder of libbzip2 1.0.x far enough to
+// count the bits it would write, without producing the stream or its CRCs.
+
+const (
+	// bzBlockSize is the block size, in units of 100,000 bytes, the
+	// reference passes to BZ2_bzBuffToBuffCompress.
+	bzBlockSize = 5
+	// bzBlockMax is nblockMAX: the encoder starts a new block once a block
+	// holds this many bytes, leaving room for one more run-length pair.
+	bzBlockMax = 100000*bzBlockSize - 19
+	// bzGroupSize is the number of MTF values coded with one Huffman table.
+	bzGroupSize = 50
+	// bzIters is the number of table refinement passes.
+	bzIters = 4
+	// bzMaxCodeLen is the longest Huffman code the encoder generates.
+	bzMaxCodeLen = 17
+	// bzLesserCost and bzGreaterCost seed the initial coding tables.
+	bzLesserCost  = 0
+	bzGreaterCost = 15
+)
+
+// Fixed sizes of the stream, in bits: the "BZh5" header, the block header
+// (magic, CRC, randomised flag and origin pointer) and the end-of-stream
+// marker with the combined CRC.
+const (
+	bzStreamHeaderBits  = 32
+	bzBlockHeaderBits   = 48 + 32 + 1 + 24
+	bzStreamTrailerBits = 48 + 32
+)
+
+// bzip2CompressedLen returns the size in bytes of the bzip2 stream that
+// BZ2_bzBuffToBuffCompress produces for data at block size 5.
+func bzip2CompressedLen(data []byte) int {
+	bits := bzStreamHeaderBits
+	var rle bzRunLength
+	block := make([]byte, 0, bzBlockMax+5)
+	for _, b := range data {
+		if len(block) >= bzBlockMax {
+			bits += bzBlockBits(block, &rle.inUse)
+			block = block[:0]
+			rle.inUse = [256]bool{}
+		}
+		block = rle.add(block, b)
+	}
+	block = rle.flush(block)
+	if len(block) > 0 {
+		bits += bzBlockBits(block, &rle.inUse)
+	}
+	bits += bzStreamTrailerBits
+	return (bits + 7) / 8
+}
+
+// bzRunLength is the initial run-length encoder of bzip2: runs of 4 to 255
+// equal bytes are stored as four bytes followed by the excess count. A run in
+// progress carries over into the next block.
+type bzRunLength struct {
+	ch    int // byte of the pending run, or 256 when there is none
+	n     int // length of the pending run
+	inUse [256]bool
+}
+
+// add appends b to the pending run, emitting the previous run into block
+// when b ends it, and returns the extended block.
+func (r *bzRunLength) add(block []byte, b byte) []byte {
+	if r.n == 0 {
+		r.ch = 256
+	}
+	if int(b) != r.ch || r.n == 255 {
+		block = r.flush(block)
+		r.ch, r.n = int(b), 1
+		return block
+	}
+	r.n++
+	return block
+}
+
+// flush emits the pending run into block, as add_pair_to_block does.
+func (r *bzRunLength) flush(block []byte) []byte {
+	if r.n == 0 {
+		return block
+	}
+	ch := byte(r.ch)
+	r.inUse[ch] = true
+	switch {
+	case r.n <= 3:
+		for i := 0; i < r.n; i++ {
+			block = append(block, ch)
+		}
+	default:
+		extra := byte(r.n - 4)
+		r.inUse[extra] = true
+		block = append(block, ch, ch, ch, ch, extra)
+	}
+	r.n = 0
+	return block
+}
+
+// bzBlockBits returns the number of bits bzip2 writes for one block: its
+// header, symbol map, selectors, coding tables and Huffman-coded MTF values.
+func bzBlockBits(block []byte, inUse *[256]bool) int {
+	mtfv, freq, nInUse := bzMTFValues(block, inUse)
+	bits := bzBlockHeaderBits
+
+	bits += 16
+	for i := 0; i < 16; i++ {
+		for j := 0; j < 16; j++ {
+			if inUse[i*16+j] {
+				bits += 16
+				break
+			}
+		}
+	}
+
+	return bits + bzHuffmanBits(mtfv, freq, nInUse+2)
+}
+
+// bzMTFValues applies the Burrows-Wheeler transform to block and encodes the
+// last column with move-to-front and the RUNA/RUNB zero-run code, as
+// generateMTFValues does. It returns the values, their frequencies and the
+// number of distinct bytes in the block.
+func bzMTFValues(block []byte, inUse *[256]bool) (mtfv []uint16, freq []int32, nInUse int) {
+	var unseqToSeq [256]byte
+	for i, used := range inUse {
+		if used {
+			unseqToSeq[i] = byte(nInUse)
+			nInUse++
+		}
+	}
+	eob := nInUse + 1
+	freq = make([]int32, eob+1)
+	mtfv = make([]uint16, 0, len(block)+1)
+
+	emitZeros := func(zPend int) {
+		zPend--
+		for {
+			v := uint16(zPend & 1) // RUNB for odd, RUNA for even
+			mtfv = append(mtfv, v)
+			freq[v]++
+			if zPend < 2 {
+				break
+			}
+			zPend = (zPend - 2) / 2
+		}
+	}
+
+	yy := make([]byte, nInUse)
+	for i := range yy {
+		yy[i] = byte(i)
+	}
+	n := len(block)
+	zPend := 0
+	for _, p := range bwtRotations(block) {
+		j := int(p) - 1
+		if j < 0 {
+			j += n
+		}
+		ll := unseqToSeq[block[j]]
+		if yy[0] == ll {
+			zPend++
+			continue
+		}
+		if zPend > 0 {
+			emitZeros(zPend)
+			zPend = 0
+		}
+		k := 1
+		for yy[k] != ll {
+			k++
+		}
+		copy(yy[1:k+1], yy[:k])
+		yy[0] = ll
+		mtfv = append(mtfv, uint16(k+1))
+		freq[k+1]++
+	}
+	if zPend > 0 {
+		emitZeros(zPend)
+	}
+	mtfv = append(mtfv, uint16(eob))
+	freq[eob]++
+	return mtfv, freq, nInUse
+}
+
+// bwtRotations returns the start offsets of the cyclic rotations of block in
+// sorted order. The rotations starting in block are the first len(block)
+// bytes of the suffixes of the doubled block, which only tie when the
+// rotations are equal; equal rotations yield the same transform whatever
+// their order.
+func bwtRotations(block []byte) []int32 {
+	n := len(block)
+	doubled := make([]byte, 2*n)
+	copy(doubled, block)
+	copy(doubled[n:], block)
+
+	rot := make([]int32, 0, n)
+	for _, s := range suffixArray(doubled) {
+		if int(s) < n {
+			rot = append(rot, s)
+		}
+	}
+	return rot
+}
+
+// bzHuffmanBits returns the bits sendMTFValues writes for the selectors, the
+// coding tables and the coded MTF values of one block.
+func bzHuffmanBits(mtfv []uint16, freq []int32, alphaSize int) int {
+	nMTF := len(mtfv)
+	var nGroups int
+	switch {
+	case nMTF < 200:
+		nGroups = 2
+	case nMTF < 600:
+		nGroups = 3
+	case nMTF < 1200:
+		nGroups = 4
+	case nMTF < 2400:
+		nGroups = 5
+	default:
+		nGroups = 6
+	}
+
+	lens := make([][]byte, nGroups)
+	for t := range lens {
+		lens[t] = make([]byte, alphaSize)
+		for v := range lens[t] {
+			lens[t][v] = bzGreaterCost
+		}
+	}
+
+	// Initial tables: split the alphabet into nGroups ranges of roughly
+	// equal frequency, each cheap in its own table.
+	remF, gs := nMTF, 0
+	for nPart := nGroups; nPart > 0; nPart-- {
+		tFreq := remF / nPart
+		ge := gs - 1
+		aFreq := 0
+		for aFreq < tFreq && ge < alphaSize-1 {
+			ge++
+			aFreq += int(freq[ge])
+		}
+		if ge > gs && nPart != nGroups && nPart != 1 && (nGroups-nPart)%2 == 1 {
+			aFreq -= int(freq[ge])
+			ge--
+		}
+		for v := 0; v < alphaSize; v++ {
+			if v >= gs && v <= ge {
+				lens[nPart-1][v] = bzLesserCost
+			} else {
+				lens[nPart-1][v] = bzGreaterCost
+			}
+		}
+		gs = ge + 1
+		remF -= aFreq
+	}
+
+	// Refine: assign every group of 50 values to its cheapest table and
+	// rebuild the tables from the values assigned to them.
+	nSelectors := (nMTF + bzGroupSize - 1) / bzGroupSize
+	selectors := make([]int, nSelectors)
+	rfreq := make([][]int32, nGroups)
+	for t := range rfreq {
+		rfreq[t] = make([]int32, alphaSize)
+	}
+	for iter := 0; iter < bzIters; iter++ {
+		for t := range rfreq {
+			clear(rfreq[t])
+		}
+		for s := range selectors {
+			group := mtfv[s*bzGroupSize : min((s+1)*bzGroupSize, nMTF)]
+			bt, bc := -1, 999999999
+			for t := 0; t < nGroups; t++ {
+				cost := 0
+				for _, v := range group {
+					cost += int(lens[t][v])
+				}
+				if cost < bc {
+					bc, bt = cost, t
+				}
+			}
+			selectors[s] = bt
+			for _, v := range group {
+				rfreq[bt][v]++
+			}
+		}
+		for t := range lens {
+			bzMakeCodeLengths(lens[t], rfreq[t], bzMaxCodeLen)
+		}
+	}
+
+	bits := 3 + 15
+
+	// Selectors are sent move-to-front coded in unary.
+	var pos [6]int
+	for i := range pos {
+		pos[i] = i
+	}
+	for _, sel := range selectors {
+		j := 0
+		for pos[j] != sel {
+			j++
+		}
+		copy(pos[1:j+1], pos[:j])
+		pos[0] = sel
+		bits += j + 1
+	}
+
+	// Code lengths are sent as deltas from the previous length.
+	for _, l := range lens {
+		bits += 5
+		curr := int(l[0])
+		for _, v := range l {
+			d := int(v) - curr
+			if d < 0 {
+				d = -d
+			}
+			bits += 2*d + 1
+			curr = int(v)
+		}
+	}
+
+	for s, sel := range selectors {
+		for _, v := range mtfv[s*bzGroupSize : min((s+1)*bzGroupSize, nMTF)] {
+			bits += int(lens[sel][v])
+		}
+	}
+	return bits
+}
+
+// bzMakeCodeLengths is BZ2_hbMakeCodeLengths: it fills lens with Huffman code
+// lengths for freq, halving the frequencies and starting over while a code
+// exceeds maxLen. Node weights carry the subtree depth in their low byte, so
+// ties favour shallower subtrees exactly as in the reference.
+func bzMakeCodeLengths(lens []byte, freq []int32, maxLen int) {
+	alphaSize := len(freq)
+	heap := make([]int32, alphaSize+2)
+	weight := make([]int32, alphaSize*2)
+	parent := make([]int32, alphaSize*2)
+
+	for i, f := range freq {
+		weight[i+1] = max(f, 1) << 8
+	}
+
+	upHeap := func(z int) {
+		tmp := heap[z]
+		for weight[tmp] < weight[heap[z>>1]] {
+			heap[z] = heap[z>>1]
+			z >>= 1
+		}
+		heap[z] = tmp
+	}
+	downHeap := func(z, nHeap int) {
+		tmp := heap[z]
+		for {
+			y := z << 1
+			if y > nHeap {
+				break
+			}
+			if y < nHeap && weight[heap[y+1]] < weight[heap[y]] {
+				y++
+			}
+			if weight[tmp] < weight[heap[y]] {
+				break
+			}
+			heap[z] = heap[y]
+			z = y
+		}
+		heap[z] = tmp
+	}
+
+	for {
+		nNodes := alphaSize
+		nHeap := 0
+		heap[0], weight[0], parent[0] = 0, 0, -2
+
+		for i := 1; i <= alphaSize; i++ {
+			parent[i] = -1
+			nHeap++
+			heap[nHeap] = int32(i)
+			upHeap(nHeap)
+		}
+
+		for nHeap > 1 {
+			n1 := heap[1]
+			heap[1] = heap[nHeap]
+			nHeap--
+			downHeap(1, nHeap)
+			n2 := heap[1]
+			heap[1] = heap[nHeap]
+			nHeap--
+			downHeap(1, nHeap)
+
+			nNodes++
+			parent[n1], parent[n2] = int32(nNodes), int32(nNodes)
+			w1, w2 := weight[n1], weight[n2]
+			weight[nNodes] = (w1&^0xff + w2&^0xff) | (1 + max(w1&0xff, w2&0xff))
+			parent[nNodes] = -1
+			nHeap++
+			heap[nHeap] = int32(nNodes)
+			upHeap(nHeap)
+		}
+
+		tooLong := false
+		for i := 1; i <= alphaSize; i++ {
+			depth := 0
+			for k := int32(i); parent[k] >= 0; k = parent[k] {
+				depth++
+			}
+			lens[i-1] = byte(depth)
+			if depth > maxLen {
+				tooLong = true
+			}
+		}
+		if !tooLong {
+			return
+		}
+
+		for i := 1; i <= alphaSize; i++ {
+			weight[i] = (1 + (weight[i]>>8)/2) << 8
+		}
+	}
+}
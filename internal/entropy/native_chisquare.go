@@ -0,0 +1,465 @@
+package entropy
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"slices"
+)
+
+// chiSquareAlpha is the significance level of both chi-square tests.
+const chiSquareAlpha = 0.001
+
+// chiSquareMinExpected is the smallest expected count of a bin (Section
+// 5.2.1, step 2).
+const chiSquareMinExpected = 5.0
+
+// chiSquareTest is the outcome of one chi-square test.
+type chiSquareTest struct {
+	Score  float64 // T, the chi-square statistic
+	DF     int     // degrees of freedom
+	PValue float64
+}
+
+// chiSquareResult is the outcome of the chi-square tests of Section 5.2.
+type chiSquareResult struct {
+	Independence  chiSquareTest
+	GoodnessOfFit chiSquareTest
+	Passed        bool
+}
+
+// details returns the statistics, degrees of freedom and p-values in the
+// layout of EstimatorResult.Details.
+func (r *chiSquareResult) details() map[string]float64 {
+	return map[string]float64{
+		"independence_score":      r.Independence.Score,
+		"independence_df":         float64(r.Independence.DF),
+		"independence_p_value":    r.Independence.PValue,
+		"goodness_of_fit_score":   r.GoodnessOfFit.Score,
+		"goodness_of_fit_df":      float64(r.GoodnessOfFit.DF),
+		"goodness_of_fit_p_value": r.GoodnessOfFit.PValue,
+	}
+}
+
+// chiSquareTests implements the chi-square tests of SP 800-90B Section 5.2
+// on the mapped symbols: the test for independence (5.2.1, or 5.2.3 for
+// binary data) and the goodness-of-fit test (5.2.2, or 5.2.4). The data fail
+// when either p-value is below 0.001.
+func chiSquareTests(symbols []byte, alphSize int, verbose int) chiSquareResult {
+	var res chiSquareResult
+	if alphSize == 2 {
+		res.Independence = binaryChiSquareIndependence(symbols)
+		res.GoodnessOfFit = binaryGoodnessOfFit(symbols)
+	} else {
+		res.Independence = chiSquareIndependence(symbols, alphSize)
+		res.GoodnessOfFit = goodnessOfFit(symbols, alphSize)
+	}
+	res.Independence.PValue = chiSquarePValue(res.Independence.Score, res.Independence.DF)
+	res.GoodnessOfFit.PValue = chiSquarePValue(res.GoodnessOfFit.Score, res.GoodnessOfFit.DF)
+	res.Passed = !(res.Independence.PValue < chiSquareAlpha) && !(res.GoodnessOfFit.PValue < chiSquareAlpha)
+
+	for _, t := range []struct {
+		name string
+		test chiSquareTest
+	}{{"independence", res.Independence}, {"goodness of fit", res.GoodnessOfFit}} {
+		if verbose == 2 {
+			fmt.Printf("Chi square %s\n", t.name)
+			fmt.Printf("\tscore = %f\n", t.test.Score)
+			fmt.Printf("\tdegrees of freedom = %d\n", t.test.DF)
+			fmt.Printf("\tp-value = %f\n\n", t.test.PValue)
+		} else if verbose >= 3 {
+			fmt.Printf("Chi square %s: T = %.17g\n", t.name, t.test.Score)
+			fmt.Printf("Chi square %s: df = %d\n", t.name, t.test.DF)
+			fmt.Printf("Chi square %s: P-value = %.17g\n", t.name, t.test.PValue)
+		}
+	}
+	return res
+}
+
+// symbolProportions returns the proportion of each symbol, accumulated in
+// steps of 1/n as calc_proportions does in the reference.
+func symbolProportions(symbols []byte, alphSize int) []float64 {
+	p := make([]float64, alphSize)
+	step := 1.0 / float64(len(symbols))
+	for _, s := range symbols {
+		p[s] += step
+	}
+	return p
+}
+
+// binaryChiSquareIndependence implements Section 5.2.3: the data are split
+// into m-bit tuples, with m the largest value up to 11 for which the least
+// likely tuple is still expected at least five times. Data for which m would
+// be below 2 are not tested.
+func binaryChiSquareIndependence(data []byte) chiSquareTest {
+	n := len(data)
+	ones := 0.0
+	for _, b := range data {
+		ones += float64(b)
+	}
+	p1 := ones / float64(n)
+	p0 := 1 - p1
+
+	minP := math.Min(p0, p1)
+	m := 11
+	for m > 1 && !(roundedPow(minP, m)*float64(n/m) >= chiSquareMinExpected) {
+		m--
+	}
+	if m < 2 {
+		return chiSquareTest{}
+	}
+
+	pow1 := make([]float64, m+1)
+	pow0 := make([]float64, m+1)
+	for w := range pow1 {
+		pow1[w] = roundedPow(p1, w)
+		pow0[w] = roundedPow(p0, w)
+	}
+
+	occ := make([]int, 1<<uint(m))
+	blocks := n / m
+	for i := 0; i < blocks; i++ {
+		tuple := 0
+		for _, b := range data[i*m : (i+1)*m] {
+			tuple = tuple<<1 | int(b)
+		}
+		occ[tuple]++
+	}
+
+	t := 0.0
+	for tuple, o := range occ {
+		w := bits.OnesCount(uint(tuple))
+		e := pow1[w] * pow0[m-w] * float64(blocks)
+		d := float64(o) - e
+		t += d * d / e
+	}
+	return chiSquareTest{Score: t, DF: 1<<uint(m) - 2}
+}
+
+// roundedPow returns x^n rounded once to the nearest float64, as the C
+// library's pow does; math.Pow multiplies in float64 and can be off in the
+// last bits.
+func roundedPow(x float64, n int) float64 {
+	exact := big.NewFloat(1).SetPrec(uint(53 * (n + 1)))
+	bx := big.NewFloat(x)
+	for i := 0; i < n; i++ {
+		exact.Mul(exact, bx)
+	}
+	f, _ := exact.Float64()
+	return f
+}
+
+// binaryGoodnessOfFit implements Section 5.2.4: the counts of zeros and
+// ones in ten equal parts of the data are compared with the proportions of
+// the whole.
+func binaryGoodnessOfFit(data []byte) chiSquareTest {
+	n := len(data)
+	sub := n / 10
+	ones := 0
+	for _, b := range data {
+		ones += int(b)
+	}
+	p := float64(ones) / float64(n)
+	e0 := (1 - p) * float64(sub)
+	e1 := p * float64(sub)
+
+	t := 0.0
+	for i := 0; i < 10; i++ {
+		o1 := 0
+		for _, b := range data[i*sub : (i+1)*sub] {
+			o1 += int(b)
+		}
+		d0 := float64(sub-o1) - e0
+		d1 := float64(o1) - e1
+		t += d0*d0/e0 + d1*d1/e1
+	}
+	return chiSquareTest{Score: t, DF: 9}
+}
+
+// chiSquareCell is a pair of symbols, or a single symbol, with its expected
+// count and the bin it is allocated to.
+type chiSquareCell struct {
+	tuple    int
+	expected float64
+	bin      int
+}
+
+// allocateBins sorts the cells by expected count and merges consecutive
+// cells into bins expected at least five times, folding a short last bin
+// into the one before it (Section 5.2.1, step 2). It returns the expected
+// count of each bin and leaves the cells ordered by tuple, so that a tuple
+// indexes its cell.
+func allocateBins(cells []chiSquareCell) []float64 {
+	slices.SortFunc(cells, func(a, b chiSquareCell) int {
+		if a.expected != b.expected {
+			if a.expected < b.expected {
+				return -1
+			}
+			return 1
+		}
+		return a.tuple - b.tuple
+	})
+
+	var bins []float64
+	current, expected := 0, 0.0
+	for i := range cells {
+		if expected >= chiSquareMinExpected {
+			bins = append(bins, expected)
+			current++
+			expected = 0
+		}
+		cells[i].bin = current
+		expected += cells[i].expected
+	}
+	if current != 0 && expected < chiSquareMinExpected {
+		for i := len(cells) - 1; cells[i].bin == current; i-- {
+			cells[i].bin = current - 1
+		}
+		bins[current-1] += expected
+	} else {
+		bins = append(bins, expected)
+	}
+
+	slices.SortFunc(cells, func(a, b chiSquareCell) int { return a.tuple - b.tuple })
+	return bins
+}
+
+// chiSquareStatistic returns the sum of (o-e)^2/e over the bins.
+func chiSquareStatistic(expected []float64, observed []int) float64 {
+	t := 0.0
+	for i, e := range expected {
+		d := float64(observed[i]) - e
+		t += d * d / e
+	}
+	return t
+}
+
+// chiSquareIndependence implements Section 5.2.1: the pairs of consecutive
+// non-overlapping symbols are binned by the count expected if the symbols
+// were independent.
+func chiSquareIndependence(symbols []byte, alphSize int) chiSquareTest {
+	p := symbolProportions(symbols, alphSize)
+	pairs := math.Floor(float64(len(symbols)) * 0.5)
+	cells := make([]chiSquareCell, alphSize*alphSize)
+	for i := range p {
+		for j := range p {
+			tuple := i*alphSize + j
+			cells[tuple] = chiSquareCell{tuple: tuple, expected: p[i] * p[j] * pairs}
+		}
+	}
+	bins := allocateBins(cells)
+
+	observed := make([]int, len(bins))
+	for j := 0; j < len(symbols)-1; j += 2 {
+		observed[cells[int(symbols[j])*alphSize+int(symbols[j+1])].bin]++
+	}
+	return chiSquareTest{Score: chiSquareStatistic(bins, observed), DF: len(bins) - alphSize}
+}
+
+// goodnessOfFit implements Section 5.2.2: the symbol counts of ten equal
+// parts of the data are binned by the count expected from the proportions of
+// the whole.
+func goodnessOfFit(symbols []byte, alphSize int) chiSquareTest {
+	p := symbolProportions(symbols, alphSize)
+	cells := make([]chiSquareCell, alphSize)
+	for j := range cells {
+		cells[j] = chiSquareCell{tuple: j, expected: p[j] * math.Floor(float64(len(symbols))/10)}
+	}
+	bins := allocateBins(cells)
+
+	block := len(symbols) / 10
+	observed := make([]int, len(bins))
+	t := 0.0
+	for j := 0; j < 10; j++ {
+		clear(observed)
+		for _, s := range symbols[j*block : (j+1)*block] {
+			observed[cells[s].bin]++
+		}
+		t += chiSquareStatistic(bins, observed)
+	}
+	return chiSquareTest{Score: t, DF: 9 * (len(bins) - 1)}
+}
+
+// chiSquarePValue returns the probability that a chi-square distributed
+// variable with df degrees of freedom is at least x, Q(df/2, x/2). A test
+// without degrees of freedom yields 1.
+func chiSquarePValue(x float64, df int) float64 {
+	return igamc(float64(df)/2, x/2)
+}
+
+// Constants of the Cephes Math Library, from which the reference takes its
+// incomplete gamma functions.
+const (
+	cephesMachEp = 1.11022302462515654042e-16  // 2^-53
+	cephesMaxLog = 7.09782712893383996732224e2 // log(MAXNUM)
+	cephesBig    = 4.503599627370496e15
+	cephesBigInv = 2.22044604925031308085e-16
+	dblEpsilon   = 0x1p-52
+)
+
+// Coefficients of the Cephes log gamma function: Stirling's formula (A) and
+// the rational approximation between 2 and 3 (B over C).
+var (
+	cephesLgamA = []float64{
+		8.11614167470508450300e-4,
+		-5.95061904284301438324e-4,
+		7.93650340457716943945e-4,
+		-2.77777777730099687205e-3,
+		8.33333333333331927722e-2,
+	}
+	cephesLgamB = []float64{
+		-1.37825152569120859100e3,
+		-3.88016315134637840924e4,
+		-3.31612992738871184744e5,
+		-1.16237097492762307383e6,
+		-1.72173700820839662146e6,
+		-8.53555664245765465627e5,
+	}
+	cephesLgamC = []float64{
+		-3.51815701436523470549e2,
+		-1.70642106651881159223e4,
+		-2.20528590553854454839e5,
+		-1.13933444367982507207e6,
+		-2.53252307177582951285e6,
+		-2.01889141433532773231e6,
+	}
+)
+
+// polevl evaluates the polynomial with the given coefficients, highest
+// degree first.
+func polevl(x float64, coef []float64) float64 {
+	ans := coef[0]
+	for _, c := range coef[1:] {
+		ans = ans*x + c
+	}
+	return ans
+}
+
+// p1evl is polevl for a polynomial whose leading coefficient of 1 is
+// omitted.
+func p1evl(x float64, coef []float64) float64 {
+	ans := x + coef[0]
+	for _, c := range coef[1:] {
+		ans = ans*x + c
+	}
+	return ans
+}
+
+// lgam ports cephes_lgam for the positive arguments the chi-square tests
+// need; math.Lgamma differs in the last bits, which can move a p-value
+// across the threshold.
+func lgam(x float64) float64 {
+	if x < 13 {
+		z, p, u := 1.0, 0.0, x
+		for u >= 3 {
+			p--
+			u = x + p
+			z *= u
+		}
+		for u < 2 {
+			z /= u
+			p++
+			u = x + p
+		}
+		if relEpsilonEqual(u, 2, dblEpsilon, dblEpsilon, 4) {
+			return math.Log(z)
+		}
+		p -= 2
+		x += p
+		p = x * polevl(x, cephesLgamB) / p1evl(x, cephesLgamC)
+		return math.Log(z) + p
+	}
+
+	q := (x-0.5)*math.Log(x) - x + math.Log(math.Sqrt(2*math.Pi))
+	if x > 1e8 {
+		return q
+	}
+	p := 1 / (x * x)
+	if x >= 1000 {
+		q += ((7.9365079365079365079365e-4*p-2.7777777777777777777778e-3)*p + 0.0833333333333333333333) / x
+	} else {
+		q += polevl(p, cephesLgamA) / x
+	}
+	return q
+}
+
+// igam ports cephes_igam, the regularized lower incomplete gamma function
+// P(a, x), by its power series.
+func igam(a, x float64) float64 {
+	if x <= 0 || a <= 0 {
+		return 0
+	}
+	if x > 1 && x > a {
+		return 1 - igamc(a, x)
+	}
+
+	ax := a*math.Log(x) - x - lgam(a)
+	if ax < -cephesMaxLog {
+		return 0
+	}
+	ax = math.Exp(ax)
+
+	r, c, ans := a, 1.0, 1.0
+	for {
+		r++
+		c *= x / r
+		ans += c
+		if !(c/ans > cephesMachEp) {
+			break
+		}
+	}
+	return ans * ax / a
+}
+
+// igamc ports cephes_igamc, the regularized upper incomplete gamma function
+// Q(a, x), by its continued fraction.
+func igamc(a, x float64) float64 {
+	if x <= 0 || a <= 0 {
+		return 1
+	}
+	if x < 1 || x < a {
+		return 1 - igam(a, x)
+	}
+
+	ax := a*math.Log(x) - x - lgam(a)
+	if ax < -cephesMaxLog {
+		return 0
+	}
+	ax = math.Exp(ax)
+
+	y := 1 - a
+	z := x + y + 1
+	c := 0.0
+	pkm2, qkm2 := 1.0, x
+	pkm1, qkm1 := x+1, z*x
+	ans := pkm1 / qkm1
+	for {
+		c++
+		y++
+		z += 2
+		yc := y * c
+		pk := pkm1*z - pkm2*yc
+		qk := qkm1*z - qkm2*yc
+
+		t := 1.0
+		if !relEpsilonEqual(qk, 0, dblEpsilon, dblEpsilon, 4) {
+			r := pk / qk
+			t = math.Abs((ans - r) / r)
+			ans = r
+		}
+
+		pkm2, pkm1 = pkm1, pk
+		qkm2, qkm1 = qkm1, qk
+		if math.Abs(pk) > cephesBig {
+			pkm2 *= cephesBigInv
+			pkm1 *= cephesBigInv
+			qkm2 *= cephesBigInv
+			qkm1 *= cephesBigInv
+		}
+		if !(t > cephesMachEp) {
+			break
+		}
+	}
+	return ans * ax
+}
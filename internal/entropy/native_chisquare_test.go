@@ -0,0 +1,97 @@
+package entropy
+
+import (
+	"math"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChiSquareTests_MatchReference(t *testing.T) {
+	// Values printed by chi_square_tests of the NIST reference sources at
+	// verbosity 3. The statistics agree exactly; the p-values go through log
+	// and exp and agree to the last few bits.
+	cases := []struct {
+		file   string
+		bits   int
+		indep  chiSquareTest
+		fit    chiSquareTest
+		passed bool
+	}{
+		{"biased1.bin", 1,
+			chiSquareTest{67.101949035304727, 62, 0.30653818669120131},
+			chiSquareTest{10.82593674836504, 9, 0.28782910175956256}, true},
+		{"skewed8.bin", 8,
+			chiSquareTest{384.06166896125507, 147, 4.288435085669727e-23},
+			chiSquareTest{532.34855176509507, 558, 0.7763466427735698}, false},
+		{"sparse4.bin", 4,
+			chiSquareTest{48.71490850490764, 20, 0.00033738399396129588},
+			chiSquareTest{22.539927974920019, 36, 0.96091846793350755}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := selfTestFS.ReadFile(path.Join(selfTestDir, tc.file))
+			require.NoError(t, err)
+			d := prepareNativeData(data, tc.bits, MSBFirst)
+
+			res := chiSquareTests(d.symbols, d.alphSize, 0)
+			for _, got := range []struct{ want, got chiSquareTest }{{tc.indep, res.Independence}, {tc.fit, res.GoodnessOfFit}} {
+				assert.Equal(t, got.want.Score, got.got.Score)
+				assert.Equal(t, got.want.DF, got.got.DF)
+				assert.InEpsilon(t, got.want.PValue, got.got.PValue, 1e-12)
+			}
+			assert.Equal(t, tc.passed, res.Passed)
+		})
+	}
+}
+
+func TestAllocateBins(t *testing.T) {
+	// Sorted by expectation: 1 (tuple 2), 2 (tuple 0), 3 (tuple 3), 4 (tuple 4),
+	// 4 (tuple 1). The last bin of 4 falls short and joins the one before.
+	cells := []chiSquareCell{{0, 2, -1}, {1, 4, -1}, {2, 1, -1}, {3, 3, -1}, {4, 4, -1}}
+	bins := allocateBins(cells)
+	assert.Equal(t, []float64{6, 8}, bins)
+	for i, want := range []int{0, 1, 0, 0, 1} {
+		assert.Equal(t, i, cells[i].tuple)
+		assert.Equal(t, want, cells[i].bin, "tuple %d", i)
+	}
+
+	// A single bin keeps whatever it holds.
+	cells = []chiSquareCell{{0, 1, -1}, {1, 2, -1}}
+	assert.Equal(t, []float64{3}, allocateBins(cells))
+}
+
+func TestBinaryChiSquareIndependence_TooSkewed(t *testing.T) {
+	// With a single one in 100 bits even 2-bit tuples are too rare to test.
+	bits := make([]byte, 100)
+	bits[40] = 1
+	res := chiSquareTests(bits, 2, 0)
+	assert.Equal(t, chiSquareTest{PValue: 1}, res.Independence)
+}
+
+func TestChiSquarePValue(t *testing.T) {
+	// With two degrees of freedom the survival function is exp(-x/2).
+	for _, x := range []float64{0.5, 3, 20, 90} {
+		assert.InEpsilon(t, math.Exp(-x/2), chiSquarePValue(x, 2), 1e-13, "x=%g", x)
+	}
+	assert.InDelta(t, 0.001, chiSquarePValue(27.877164871256568, 9), 1e-15)
+	assert.Equal(t, 1.0, chiSquarePValue(0, 9))
+	assert.Equal(t, 1.0, chiSquarePValue(12, 0))
+}
+
+func TestCalculateNative_ChiSquareDetails(t *testing.T) {
+	result, err := calculateNative("test", IID, randomSamples(5000, 4, 3), 4, 0, estimatorChiSquare, MSBFirst, permutationOptions{}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Estimators, 1)
+
+	est := result.Estimators[0]
+	assert.Equal(t, "Chi-Square Tests", est.Name)
+	assert.Equal(t, -1.0, est.EntropyEstimate)
+	assert.False(t, est.IsEntropyValid)
+	assert.True(t, est.Passed)
+	require.Len(t, est.Details, 6)
+	assert.Greater(t, est.Details["independence_p_value"], chiSquareAlpha)
+	assert.Equal(t, 9*(16-1.0), est.Details["goodness_of_fit_df"])
+}
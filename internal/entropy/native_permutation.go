@@ -0,0 +1,493 @@
+package entropy
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// permutationStatCount is the number of test statistics of the permutation
+// tests: the eleven tests of Section 5.1.1 to 5.1.11, with periodicity and
+// covariance each computed at five lags.
+const permutationStatCount = 19
+
+// permutationMinCount is the count above which both C0+C1 and C1+C2 must
+// rise for a statistic to pass (Section 5.1, step 5).
+const permutationMinCount = 5
+
+// permutationLags are the lags of the periodicity and covariance tests.
+var permutationLags = [5]int{1, 2, 8, 16, 32}
+
+// permutationStatNames are the names the reference tool prints for each
+// statistic, in the order of the tests.
+var permutationStatNames = [permutationStatCount]string{
+	"excursion", "numDirectionalRuns", "lenDirectionalRuns", "numIncreasesDecreases",
+	"numRunsMedian", "lenRunsMedian", "avgCollision", "maxCollision",
+	"periodicity(1)", "periodicity(2)", "periodicity(8)", "periodicity(16)", "periodicity(32)",
+	"covariance(1)", "covariance(2)", "covariance(8)", "covariance(16)", "covariance(32)",
+	"compression",
+}
+
+// permutationDetailKeys prefix the counters of each statistic in the
+// estimator details.
+var permutationDetailKeys = [permutationStatCount]string{
+	"excursion", "num_directional_runs", "len_directional_runs", "num_increases_decreases",
+	"num_runs_median", "len_runs_median", "avg_collision", "max_collision",
+	"periodicity_1", "periodicity_2", "periodicity_8", "periodicity_16", "periodicity_32",
+	"covariance_1", "covariance_2", "covariance_8", "covariance_16", "covariance_32",
+	"compression",
+}
+
+// permutationOptions configures the permutation tests. The zero value runs
+// the PermutationRounds rounds of SP 800-90B with a random seed on one thread
+// per CPU. Only threads applies to the NIST library.
+type permutationOptions struct {
+	rounds  int
+	seed    uint64
+	hasSeed bool
+	threads int
+}
+
+// permutationStats holds one value per statistic.
+type permutationStats [permutationStatCount]float64
+
+// permutationSet marks a subset of the statistics.
+type permutationSet [permutationStatCount]bool
+
+// permutationResult is the outcome of the permutation tests: the counters
+// C0, C1 and C2 of each statistic (shuffled value greater than, equal to and
+// less than the original one) and the number of rounds that were run.
+type permutationResult struct {
+	Counts [permutationStatCount][3]int
+	Rounds int
+	Passed bool
+}
+
+// details returns the counters keyed by statistic, together with the rounds
+// run.
+func (r *permutationResult) details() map[string]float64 {
+	details := make(map[string]float64, 3*permutationStatCount+1)
+	for j, key := range permutationDetailKeys {
+		details[key+"_c0"] = float64(r.Counts[j][0])
+		details[key+"_c1"] = float64(r.Counts[j][1])
+		details[key+"_c2"] = float64(r.Counts[j][2])
+	}
+	details["rounds"] = float64(r.Rounds)
+	return details
+}
+
+// permutationInput is the data the statistics are computed from, as prepared
+// by calc_stats in the reference: the mapped symbols, the unmasked input
+// bytes, the mean of the latter and the median of the former.
+type permutationInput struct {
+	alphSize int
+	symbols  []byte
+	raw      []byte
+	rawMean  float64
+	median   float64
+}
+
+// newPermutationInput computes the mean and median the statistics use. The
+// median of binary data is fixed at 0.5 (Sections 5.1.5 and 5.1.6).
+func newPermutationInput(d *nativeData) *permutationInput {
+	in := &permutationInput{alphSize: d.alphSize, symbols: d.symbols, raw: d.raw, median: 0.5}
+
+	sum := 0
+	for _, b := range d.raw {
+		sum += int(b)
+	}
+	n := len(d.symbols)
+	in.rawMean = float64(sum) / float64(n)
+
+	if d.alphSize != 2 {
+		sorted := slices.Clone(d.symbols)
+		slices.Sort(sorted)
+		if n%2 == 1 {
+			in.median = float64(sorted[n/2])
+		} else {
+			in.median = float64(int(sorted[n/2])+int(sorted[n/2-1])) / 2
+		}
+	}
+	return in
+}
+
+// permutationTests implements the permutation testing of SP 800-90B Section
+// 5.1: the statistics of the data are compared with those of up to
+// opts.rounds shuffled copies, and a statistic passes once the original value
+// is neither among the five largest nor among the five smallest.
+//
+// Rounds run in parallel on GOMAXPROCS workers, or opts.threads if fewer. Round i shuffles a fresh copy
+// of the data with a generator seeded from the seed and i, and the rounds are
+// tallied in order, so the counters only depend on the seed. As in the
+// reference, a statistic that has passed is no longer computed and the
+// testing stops once all have passed.
+func permutationTests(in *permutationInput, opts permutationOptions, verbose int) permutationResult {
+	rounds := opts.rounds
+	if rounds <= 0 {
+		rounds = PermutationRounds
+	}
+	seed := opts.seed
+	if !opts.hasSeed {
+		seed = rand.Uint64()
+	}
+
+	var all permutationSet
+	for j := range all {
+		all[j] = true
+	}
+	var t permutationStats
+	in.compute(in.symbols, in.raw, &all, &t, nil)
+	if verbose == 2 {
+		fmt.Println("Initial test results")
+		for j, name := range permutationStatNames {
+			fmt.Printf("%23s: %g\n", name, t[j])
+		}
+	}
+
+	var (
+		mu        sync.Mutex
+		res       permutationResult
+		active    = all
+		undecided = permutationStatCount
+		next      int
+		pending   = make(map[int]permutationStats)
+	)
+
+	// tally folds the shuffled statistics of the rounds completed in order
+	// into the counters. Callers hold mu.
+	tally := func() {
+		for {
+			tp, ok := pending[res.Rounds]
+			if !ok || undecided == 0 {
+				return
+			}
+			delete(pending, res.Rounds)
+			res.Rounds++
+			for j := range tp {
+				if !active[j] {
+					continue
+				}
+				c := &res.Counts[j]
+				switch {
+				case tp[j] > t[j]:
+					c[0]++
+				case tp[j] == t[j]:
+					c[1]++
+				default:
+					c[2]++
+				}
+				if c[0]+c[1] > permutationMinCount && c[1]+c[2] > permutationMinCount {
+					active[j] = false
+					undecided--
+				}
+			}
+		}
+	}
+
+	worker := func() {
+		var sc permutationScratch
+		symbols := make([]byte, len(in.symbols))
+		raw := make([]byte, len(in.raw))
+		for {
+			mu.Lock()
+			if undecided == 0 || next >= rounds {
+				mu.Unlock()
+				return
+			}
+			i := next
+			next++
+			// The statistics still open now include every one that is
+			// open when round i is tallied.
+			want := active
+			mu.Unlock()
+
+			copy(symbols, in.symbols)
+			copy(raw, in.raw)
+			r := rand.New(rand.NewPCG(seed, uint64(i)))
+			r.Shuffle(len(symbols), func(a, b int) {
+				symbols[a], symbols[b] = symbols[b], symbols[a]
+				raw[a], raw[b] = raw[b], raw[a]
+			})
+			var tp permutationStats
+			in.compute(symbols, raw, &want, &tp, &sc)
+
+			mu.Lock()
+			pending[i] = tp
+			tally()
+			mu.Unlock()
+		}
+	}
+
+	workers := min(runtime.GOMAXPROCS(0), rounds)
+	if opts.threads > 0 {
+		workers = min(workers, opts.threads)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker()
+		}()
+	}
+	wg.Wait()
+
+	res.Passed = true
+	for _, c := range res.Counts {
+		if c[0]+c[1] <= permutationMinCount || c[1]+c[2] <= permutationMinCount {
+			res.Passed = false
+		}
+	}
+
+	if verbose > 1 {
+		fmt.Printf("\n\n%25s%9s%9s%9s\n", "statistic", "C[i][0]", "C[i][1]", "C[i][2]")
+		fmt.Println("----------------------------------------------------")
+		for j, c := range res.Counts {
+			name := fmt.Sprintf("%25s", permutationStatNames[j])
+			if c[0]+c[1] <= permutationMinCount || c[1]+c[2] <= permutationMinCount {
+				name = fmt.Sprintf("%24s*", permutationStatNames[j])
+			}
+			fmt.Printf("%s%8d%8d%8d\n", name, c[0], c[1], c[2])
+		}
+		fmt.Printf("(* denotes failed test)\n\n")
+	}
+	return res
+}
+
+// permutationScratch holds the buffers one worker reuses across rounds.
+type permutationScratch struct {
+	counts []byte
+	values []byte
+	text   []byte
+	seen   []bool
+}
+
+// compute stores in out the statistics of symbols and raw, a permutation of
+// the input, that want includes. sc may be nil.
+func (in *permutationInput) compute(symbols, raw []byte, want *permutationSet, out *permutationStats, sc *permutationScratch) {
+	if sc == nil {
+		sc = &permutationScratch{}
+	}
+	need := func(from, to int) bool {
+		for j := from; j < to; j++ {
+			if want[j] {
+				return true
+			}
+		}
+		return false
+	}
+	binary := in.alphSize == 2
+
+	if want[0] {
+		out[0] = excursion(raw, in.rawMean)
+	}
+
+	// Binary data is condensed to the number of ones in each 8-bit block
+	// (Conversion I) for the directional, periodicity and covariance tests.
+	condensed := symbols
+	if binary && (need(1, 4) || need(8, 18)) {
+		sc.counts = conversion1(sc.counts[:0], symbols)
+		condensed = sc.counts
+	}
+
+	if need(1, 4) {
+		runs, longest, increases := directionalRuns(condensed)
+		out[1], out[2], out[3] = float64(runs), float64(longest), float64(increases)
+	}
+	if need(4, 6) {
+		runs, longest := medianRuns(symbols, in.median)
+		out[4], out[5] = float64(runs), float64(longest)
+	}
+	if need(6, 8) {
+		var avg float64
+		var longest int
+		if binary {
+			sc.values = conversion2(sc.values[:0], symbols)
+			avg, longest = collisions(sc.values, 256, &sc.seen)
+		} else {
+			avg, longest = collisions(symbols, in.alphSize, &sc.seen)
+		}
+		out[6], out[7] = avg, float64(longest)
+	}
+	for k, p := range permutationLags {
+		if want[8+k] {
+			out[8+k] = float64(periodicity(condensed, p))
+		}
+	}
+	covData := raw
+	if binary {
+		covData = condensed
+	}
+	for k, p := range permutationLags {
+		if want[13+k] {
+			out[13+k] = float64(covariance(covData, p))
+		}
+	}
+	if want[18] {
+		sc.text = sc.text[:0]
+		for i, b := range raw {
+			if i > 0 {
+				sc.text = append(sc.text, ' ')
+			}
+			sc.text = strconv.AppendUint(sc.text, uint64(b), 10)
+		}
+		out[18] = float64(bzip2CompressedLen(sc.text))
+	}
+}
+
+// conversion1 appends to dst the number of ones in each 8-bit block of the
+// binary data, the last block possibly being shorter (Conversion I).
+func conversion1(dst, bits []byte) []byte {
+	for i := 0; i < len(bits); i += 8 {
+		var ones byte
+		for _, b := range bits[i:min(i+8, len(bits))] {
+			ones += b
+		}
+		dst = append(dst, ones)
+	}
+	return dst
+}
+
+// conversion2 appends to dst the value of each 8-bit block of the binary
+// data, most significant bit first, the last block padded with zeros
+// (Conversion II).
+func conversion2(dst, bits []byte) []byte {
+	for i := 0; i < len(bits); i += 8 {
+		var v byte
+		for k, b := range bits[i:min(i+8, len(bits))] {
+			v |= b << uint(7-k)
+		}
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+// excursion is the statistic of Section 5.1.1: the largest distance between
+// a running sum and the same number of samples at the mean.
+func excursion(data []byte, mean float64) float64 {
+	largest, sum := 0.0, 0.0
+	for i, b := range data {
+		sum += float64(b)
+		// The explicit conversion keeps the product rounded, as in the
+		// reference, instead of fusing it with the subtraction.
+		largest = math.Max(largest, math.Abs(sum-float64(float64(i+1)*mean)))
+	}
+	return largest
+}
+
+// directionalRuns returns the statistics of Sections 5.1.2 to 5.1.4 on the
+// sequence of steps between consecutive values, a step being down when a
+// value exceeds its successor and up otherwise: the number of runs of equal
+// steps, the longest such run, and the larger of the number of up and down
+// steps.
+func directionalRuns(data []byte) (runs, longest, increases int) {
+	if len(data) < 2 {
+		return 0, 1, 0
+	}
+	up := 0
+	runs, longest, run := 1, 0, 1
+	prev := data[0] <= data[1]
+	for i := 0; i+1 < len(data); i++ {
+		step := data[i] <= data[i+1]
+		if step {
+			up++
+		}
+		if i == 0 {
+			continue
+		}
+		if step == prev {
+			run++
+		} else {
+			runs++
+			longest = max(longest, run)
+			run = 1
+		}
+		prev = step
+	}
+	longest = max(longest, run)
+	return runs, longest, max(up, len(data)-1-up)
+}
+
+// medianRuns returns the statistics of Sections 5.1.5 and 5.1.6: the number
+// of runs of values on the same side of the median, counting the median
+// itself as above, and the longest such run.
+func medianRuns(data []byte, median float64) (runs, longest int) {
+	if len(data) == 0 {
+		return 0, 1
+	}
+	runs, run := 1, 1
+	prev := float64(data[0]) >= median
+	for _, b := range data[1:] {
+		above := float64(b) >= median
+		if above == prev {
+			run++
+		} else {
+			runs++
+			longest = max(longest, run)
+			run = 1
+		}
+		prev = above
+	}
+	return runs, max(longest, run)
+}
+
+// collisions returns the statistics of Sections 5.1.7 and 5.1.8: the mean
+// and the largest number of values read until one repeats, the data being
+// split into consecutive stretches that each end with their first repeated
+// value. A trailing stretch without a repeat is not counted; without any
+// repeat the mean is NaN, as in the reference. seen is reused across calls.
+func collisions(data []byte, k int, seen *[]bool) (avg float64, longest int) {
+	if cap(*seen) < k {
+		*seen = make([]bool, k)
+	}
+	s := (*seen)[:k]
+
+	count, sum := 0, 0
+	for start := 0; start < len(data); {
+		clear(s)
+		end := start
+		for end < len(data) && !s[data[end]] {
+			s[data[end]] = true
+			end++
+		}
+		if end == len(data) {
+			break
+		}
+		length := end - start + 1
+		count++
+		sum += length
+		longest = max(longest, length)
+		start = end + 1
+	}
+
+	if count == 0 {
+		return math.NaN(), longest
+	}
+	return float64(sum) / float64(count), longest
+}
+
+// periodicity is the statistic of Section 5.1.9: the number of values equal
+// to the value p positions later.
+func periodicity(data []byte, p int) int {
+	count := 0
+	for i := 0; i+p < len(data); i++ {
+		if data[i] == data[i+p] {
+			count++
+		}
+	}
+	return count
+}
+
+// covariance is the statistic of Section 5.1.10: the sum of the products of
+// each value with the value p positions later.
+func covariance(data []byte, p int) uint64 {
+	var sum uint64
+	for i := 0; i+p < len(data); i++ {
+		sum += uint64(data[i]) * uint64(data[i+p])
+	}
+	return sum
+}
@@ -0,0 +1,185 @@
+package entropy
+
+import (
+	"math"
+	"math/rand"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermutationStatistics_MatchReference(t *testing.T) {
+	// Values produced by run_tests on the unshuffled data with the NIST
+	// reference sources, linked against libbz2.
+	cases := []struct {
+		file string
+		bits int
+		want permutationStats
+	}{
+		{"biased1.bin", 1, permutationStats{
+			48.6357421875, 677, 7, 600, 4047, 15, 16.451612903225808, 41,
+			188, 205, 230, 219, 231, 10417, 10473, 10355, 10261, 10174, 1300,
+		}},
+		{"skewed8.bin", 8, permutationStats{
+			5886.7705078125, 2762, 6, 2081, 2031, 11, 15.058823529411764, 41,
+			46, 46, 35, 35, 42, 34122924, 34598459, 33923179, 33141900, 32858519, 4374,
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := selfTestFS.ReadFile(path.Join(selfTestDir, tc.file))
+			require.NoError(t, err)
+			in := newPermutationInput(prepareNativeData(data, tc.bits, MSBFirst))
+
+			var all permutationSet
+			for j := range all {
+				all[j] = true
+			}
+			var got permutationStats
+			in.compute(in.symbols, in.raw, &all, &got, nil)
+			for j, name := range permutationStatNames {
+				assert.Equal(t, tc.want[j], got[j], name)
+			}
+		})
+	}
+}
+
+func TestBzip2CompressedLen(t *testing.T) {
+	// Lengths of the output of BZ2_bzBuffToBuffCompress at block size 5.
+	pattern := func(n int, f func(i int) byte) []byte {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = f(i)
+		}
+		return b
+	}
+	assert.Equal(t, 14, bzip2CompressedLen(nil))
+	assert.Equal(t, 42, bzip2CompressedLen([]byte("0 1 0 1")))
+	// Three blocks.
+	assert.Equal(t, 2751, bzip2CompressedLen(pattern(1200000, func(i int) byte { return byte(i * i % 251) })))
+	// Runs longer than the 255 bytes one run-length pair holds.
+	assert.Equal(t, 141, bzip2CompressedLen(pattern(600000, func(i int) byte { return byte(i/300%7 + '0') })))
+}
+
+func TestCollisions(t *testing.T) {
+	var seen []bool
+	// Stretches 0 1 0 | 2 2 | 1 0 1, and a trailing 2 without a repeat.
+	avg, longest := collisions([]byte{0, 1, 0, 2, 2, 1, 0, 1, 2}, 3, &seen)
+	assert.InDelta(t, 8.0/3, avg, 1e-15)
+	assert.Equal(t, 3, longest)
+
+	avg, longest = collisions([]byte{0, 1, 2}, 3, &seen)
+	assert.True(t, math.IsNaN(avg))
+	assert.Equal(t, 0, longest)
+}
+
+func TestDirectionalRuns(t *testing.T) {
+	// Steps: up up down up up up down.
+	runs, longest, increases := directionalRuns([]byte{1, 2, 2, 0, 3, 4, 5, 1})
+	assert.Equal(t, 4, runs)
+	assert.Equal(t, 3, longest)
+	assert.Equal(t, 5, increases)
+
+	runs, longest, increases = directionalRuns([]byte{7})
+	assert.Equal(t, []int{0, 1, 0}, []int{runs, longest, increases})
+}
+
+// randomSamples returns n pseudo-random samples of the given width.
+func randomSamples(n, bits int, seed int64) []byte {
+	r := rand.New(rand.NewSource(seed))
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(r.Intn(1 << uint(bits)))
+	}
+	return data
+}
+
+func TestPermutationTests_SeedIsReproducible(t *testing.T) {
+	in := newPermutationInput(prepareNativeData(randomSamples(5000, 4, 1), 4, MSBFirst))
+	opts := permutationOptions{rounds: 200, seed: 42, hasSeed: true}
+
+	prev := runtime.GOMAXPROCS(1)
+	serial := permutationTests(in, opts, 0)
+	runtime.GOMAXPROCS(4)
+	parallel := permutationTests(in, opts, 0)
+	runtime.GOMAXPROCS(prev)
+
+	assert.Equal(t, serial, parallel)
+	assert.Greater(t, serial.Rounds, 0)
+	assert.LessOrEqual(t, serial.Rounds, 200)
+
+	opts.seed = 43
+	assert.NotEqual(t, serial.Counts, permutationTests(in, opts, 0).Counts)
+}
+
+func TestPermutationTests_Outcome(t *testing.T) {
+	opts := permutationOptions{rounds: 1000, seed: 1, hasSeed: true}
+
+	// Independent samples pass and stop as soon as every statistic has. With
+	// fewer rounds than PermutationRounds independent samples fail far more
+	// often, so the data and the seed are fixed.
+	res := permutationTests(newPermutationInput(prepareNativeData(randomSamples(5000, 8, 3), 8, MSBFirst)), opts, 0)
+	assert.True(t, res.Passed)
+	assert.Less(t, res.Rounds, 1000)
+	for j, c := range res.Counts {
+		assert.Greater(t, c[0]+c[1], permutationMinCount, permutationStatNames[j])
+		assert.Greater(t, c[1]+c[2], permutationMinCount, permutationStatNames[j])
+	}
+
+	// A ramp has fewer directional runs than any shuffle of it.
+	ramp := make([]byte, 5000)
+	for i := range ramp {
+		ramp[i] = byte(i * 256 / len(ramp))
+	}
+	opts.rounds = 50
+	res = permutationTests(newPermutationInput(prepareNativeData(ramp, 8, MSBFirst)), opts, 0)
+	assert.False(t, res.Passed)
+	assert.Equal(t, 50, res.Rounds)
+	assert.Equal(t, [3]int{50, 0, 0}, res.Counts[1])
+}
+
+func TestPermutationTests_ShortInput(t *testing.T) {
+	// Fewer samples than the largest lag must not break any statistic.
+	for _, bits := range []int{1, 2} {
+		in := newPermutationInput(prepareNativeData([]byte{0, 1, 0, 1, 1}, bits, MSBFirst))
+		res := permutationTests(in, permutationOptions{rounds: 20, seed: 1, hasSeed: true}, 0)
+		assert.False(t, res.Passed)
+		assert.Equal(t, 20, res.Rounds)
+	}
+}
+
+func TestCalculateNative_PermutationDetails(t *testing.T) {
+	perm := permutationOptions{rounds: 100, seed: 7, hasSeed: true}
+	result, err := calculateNative("test", IID, randomSamples(2000, 8, 3), 8, 0, estimatorMCV|estimatorPermutation, MSBFirst, perm, nil)
+	require.NoError(t, err)
+	assert.False(t, result.Partial)
+	require.Len(t, result.Estimators, 2)
+
+	est := result.Estimators[1]
+	assert.Equal(t, "Permutation Tests", est.Name)
+	assert.Equal(t, -1.0, est.EntropyEstimate)
+	assert.False(t, est.IsEntropyValid)
+	assert.True(t, est.Passed)
+	require.Len(t, est.Details, 3*permutationStatCount+1)
+	assert.LessOrEqual(t, est.Details["rounds"], 100.0)
+	assert.Greater(t, est.Details["compression_c0"]+est.Details["compression_c1"], 5.0)
+
+	// The permutation tests do not contribute an entropy estimate.
+	assert.Equal(t, result.Estimators[0].EntropyEstimate, result.HOriginal)
+}
+
+func BenchmarkPermutationRound(b *testing.B) {
+	in := newPermutationInput(prepareNativeData(randomSamples(1000000, 8, 1), 8, MSBFirst))
+	var all permutationSet
+	for j := range all {
+		all[j] = true
+	}
+	var sc permutationScratch
+	var out permutationStats
+	for i := 0; i < b.N; i++ {
+		in.compute(in.symbols, in.raw, &all, &out, &sc)
+	}
+}
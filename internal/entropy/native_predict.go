@@ -0,0 +1,627 @@
+package entropy
+
+import (
+	"fmt"
+	"math"
+)
+
+// Parameters of the prediction estimates of Sections 6.3.7 to 6.3.10, named
+// after their counterparts in the reference implementation.
+const (
+	lagDepth       = 128    // D in the Lag prediction estimate
+	mmcDepth       = 16     // D in the MultiMMC prediction estimate
+	mmcMaxEntries  = 100000 // maximum number of prefixes per MultiMMC depth
+	lz78yPrefixLen = 16     // B in the LZ78Y prediction estimate
+	lz78yMaxDict   = 65536  // maximum number of LZ78Y dictionary entries
+	predictIterMax = 1076   // ITERMAX, the bound on the P_local bisection
+)
+
+// mcwWindows are the window sizes of the MultiMCW prediction estimate.
+var mcwWindows = [...]int{63, 255, 1023, 4095}
+
+// prediction is the outcome of a prediction estimate: the global and local
+// predictability bounds and the min-entropy derived from the larger of them.
+// An estimate that cannot be computed has an Entropy of -1.
+type prediction struct {
+	Correct     int     // C, the number of correct predictions
+	Predictions int     // N, the number of predictions made
+	LongestRun  int     // the longest run of correct predictions
+	PGlobal     float64 // P'_global, the upper bound on the global predictability
+	PLocal      float64 // P_local, the local predictability implied by the longest run
+	Entropy     float64
+}
+
+// unavailablePrediction is returned for inputs too short to predict on.
+var unavailablePrediction = prediction{PGlobal: -1, PLocal: -1, Entropy: -1}
+
+// details returns the bounds in the layout of EstimatorResult.Details.
+func (p prediction) details() map[string]float64 {
+	return map[string]float64{
+		"correct":     float64(p.Correct),
+		"predictions": float64(p.Predictions),
+		"longest_run": float64(p.LongestRun),
+		"p_global":    p.PGlobal,
+		"p_local":     p.PLocal,
+	}
+}
+
+// dblMin is DBL_MIN, the smallest normal float64.
+const dblMin = 0x1p-1022
+
+// relEpsilonEqual mirrors the reference's comparison used to stop the P_local
+// bisection: a and b are equal when their difference is below maxAbs in the
+// subnormal range, relatively within maxRel, or at most maxULP units in the
+// last place apart.
+func relEpsilonEqual(a, b, maxAbs, maxRel float64, maxULP uint64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return false
+	}
+	if a == b {
+		return true
+	}
+	if math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return false
+	}
+
+	absA, absB := math.Abs(a), math.Abs(b)
+	if absA > absB {
+		a, b = b, a
+		absA, absB = absB, absA
+	}
+	diff := math.Abs(b - a)
+	if absA < dblMin || diff < dblMin || math.IsInf(diff, 0) || absB*maxRel < dblMin {
+		return diff <= maxAbs
+	}
+	if diff <= absB*maxRel {
+		return true
+	}
+	if math.Signbit(a) != math.Signbit(b) {
+		return false
+	}
+	return math.Float64bits(absB)-math.Float64bits(absA) <= maxULP
+}
+
+// inClosedInterval reports whether x lies between a and b inclusive, in
+// either order.
+func inClosedInterval(x, a, b float64) bool {
+	if a > b {
+		a, b = b, a
+	}
+	return x >= a && x <= b
+}
+
+// predictionEstimateFunction evaluates the logarithm of the probability that
+// the longest run of correct predictions among n is shorter than r when each
+// prediction succeeds with probability p (Section 6.3.7, step 8).
+func predictionEstimateFunction(p float64, r, n int) float64 {
+	q := 1 - p
+	x, xLast := 1.0, 0.0
+	for i := 0; i <= 65 && x-xLast > 0x1p-52*x; i++ {
+		xLast = x
+		x = 1 + q*math.Pow(p, float64(r))*math.Pow(x, float64(r+1))
+	}
+	return math.Log(1-p*x) - math.Log((float64(r)+1-float64(r)*x)*q) - float64(n+1)*math.Log(x)
+}
+
+// calcPLocal finds by bisection the p in [ldomain, 1] for which a longest run
+// of maxRunLen correct predictions out of n has probability 0.99, following
+// calc_p_local of the reference implementation including its guards.
+func calcPLocal(maxRunLen, n int, ldomain float64) float64 {
+	logAlpha := math.Log(0.99)
+	hdomain := 1.0
+	lbound, hbound := ldomain, hdomain
+	lvalue, hvalue := math.Inf(1), math.Inf(-1)
+
+	p := (lbound + hbound) / 2
+	pVal := predictionEstimateFunction(p, maxRunLen+1, n)
+	for j := 0; j < predictIterMax; j++ {
+		if relEpsilonEqual(pVal, logAlpha, dblMin, 0x1p-52, 4) {
+			break
+		}
+		if logAlpha < pVal {
+			lbound, lvalue = p, pVal
+		} else {
+			hbound, hvalue = p, pVal
+		}
+
+		if lbound >= hbound {
+			p = math.Min(math.Max(lbound, hbound), hdomain)
+			break
+		}
+		if !inClosedInterval(lbound, ldomain, hdomain) || !inClosedInterval(hbound, ldomain, hdomain) {
+			p = hdomain
+			break
+		}
+		if !inClosedInterval(logAlpha, lvalue, hvalue) {
+			p = hdomain
+			break
+		}
+
+		lastP := p
+		p = (lbound + hbound) / 2
+		if !(p > math.Min(lbound, hbound) && p < math.Max(lbound, hbound)) || lastP == p {
+			p = hbound
+			break
+		}
+
+		pVal = predictionEstimateFunction(p, maxRunLen+1, n)
+		if !inClosedInterval(pVal, lvalue, hvalue) {
+			p = hbound
+			break
+		}
+	}
+	return p
+}
+
+// predictionEstimate derives the min-entropy of a prediction estimate from
+// the number of correct predictions c out of n and the longest run of correct
+// predictions, for an alphabet of k symbols (Section 6.3.7, steps 7 to 9).
+// The reference implementation skips P_local when it cannot exceed P'_global;
+// it is computed here either way so that both bounds can be reported, clamped
+// to 1/k when the run length is consistent with a random guess.
+func predictionEstimate(c, n, maxRunLen, k int, name string, verbose int, label string) prediction {
+	res := prediction{Correct: c, Predictions: n, LongestRun: maxRunLen}
+
+	lower := 1 / float64(k)
+	pGlobal := float64(c) / float64(n)
+	if pGlobal > 0 {
+		res.PGlobal = math.Min(1, pGlobal+zAlpha*math.Sqrt(pGlobal*(1-pGlobal)/float64(n-1)))
+	} else {
+		res.PGlobal = 1 - math.Pow(0.01, 1/float64(n))
+	}
+
+	logAlpha := math.Log(0.99)
+	curMax := math.Max(lower, res.PGlobal)
+	switch {
+	case curMax < 1 && predictionEstimateFunction(curMax, maxRunLen+1, n) > logAlpha:
+		res.PLocal = calcPLocal(maxRunLen, n, curMax)
+		curMax = math.Max(curMax, res.PLocal)
+	case predictionEstimateFunction(lower, maxRunLen+1, n) > logAlpha:
+		res.PLocal = calcPLocal(maxRunLen, n, lower)
+	default:
+		res.PLocal = lower
+	}
+	res.Entropy = -math.Log2(curMax)
+
+	if verbose == 2 {
+		fmt.Printf("%s %s Prediction Estimate: N = %d, Pglobal' = %.17g (C = %d) Plocal = %.17g (r = %d)\n",
+			label, name, n, res.PGlobal, c, res.PLocal, maxRunLen+1)
+	}
+	return res
+}
+
+// runCounter tracks the correct predictions of an estimator and their longest
+// run.
+type runCounter struct {
+	correct, run, longest int
+}
+
+// record notes the outcome of one prediction.
+func (r *runCounter) record(hit bool) {
+	if !hit {
+		r.run = 0
+		return
+	}
+	r.correct++
+	r.run++
+	r.longest = max(r.longest, r.run)
+}
+
+// multiMCW implements the MultiMCW prediction estimate of Section 6.3.7: four
+// subpredictors guess the most common symbol of the last 63, 255, 1023 and
+// 4095 samples, and the one with the most correct guesses so far makes the
+// prediction. Ties between equally frequent symbols go to the most recent
+// one, as in the reference implementation.
+func multiMCW(s []byte, k int, verbose int, label string) prediction {
+	const numWins = len(mcwWindows)
+	longest := mcwWindows[numWins-1]
+	if len(s) < longest+1 {
+		if verbose > 1 {
+			fmt.Printf("%s MultiMCW Estimate: need more than %d samples.\n", label, longest+1)
+		}
+		return unavailablePrediction
+	}
+
+	var (
+		winCnts, winPoses [numWins][]int
+		maxCnts           [numWins]int
+		frequent          [numWins]byte
+		scoreboard        [numWins]int
+	)
+	for j := range winCnts {
+		winCnts[j] = make([]int, k)
+		winPoses[j] = make([]int, k)
+	}
+	for i := 0; i < longest; i++ {
+		for j, w := range mcwWindows {
+			if i < w {
+				winCnts[j][s[i]]++
+				if maxCnts[j] <= winCnts[j][s[i]] {
+					maxCnts[j] = winCnts[j][s[i]]
+					frequent[j] = s[i]
+				}
+				winPoses[j][s[i]] = i
+			}
+		}
+	}
+
+	var runs runCounter
+	winner := 0
+	for i := mcwWindows[0]; i < len(s); i++ {
+		runs.record(frequent[winner] == s[i])
+		for j, w := range mcwWindows {
+			if i >= w && frequent[j] == s[i] {
+				scoreboard[j]++
+				if scoreboard[j] >= scoreboard[winner] {
+					winner = j
+				}
+			}
+		}
+
+		for j, w := range mcwWindows {
+			if i < w {
+				continue
+			}
+			leaving := s[i-w]
+			winCnts[j][leaving]--
+			winCnts[j][s[i]]++
+			winPoses[j][s[i]] = i
+			if leaving != frequent[j] {
+				if maxCnts[j] <= winCnts[j][s[i]] {
+					maxCnts[j] = winCnts[j][s[i]]
+					frequent[j] = s[i]
+				}
+				continue
+			}
+			maxCnts[j]--
+			maxPos := i - w
+			for sym := 0; sym < k; sym++ {
+				if maxCnts[j] < winCnts[j][sym] || (maxCnts[j] == winCnts[j][sym] && maxPos <= winPoses[j][sym]) {
+					maxCnts[j] = winCnts[j][sym]
+					frequent[j] = byte(sym)
+					maxPos = winPoses[j][sym]
+				}
+			}
+		}
+	}
+	return predictionEstimate(runs.correct, len(s)-mcwWindows[0], runs.longest, k, "MultiMCW", verbose, label)
+}
+
+// lag implements the Lag prediction estimate of Section 6.3.8: 128
+// subpredictors guess that the next sample repeats the one d samples back, and
+// the one with the most correct guesses so far makes the prediction. Lags are
+// scored from the shortest, so ties go to the longest lag as in the reference
+// implementation, which visits the most recent occurrences first.
+func lag(s []byte, k int, verbose int, label string) prediction {
+	if len(s) < 3 {
+		if verbose > 1 {
+			fmt.Printf("%s Lag Estimate: need at least 3 samples.\n", label)
+		}
+		return unavailablePrediction
+	}
+
+	var (
+		scoreboard [lagDepth]int
+		runs       runCounter
+	)
+	winner, highScore := 0, 0
+	for i := 1; i < len(s); i++ {
+		cur := s[i]
+		runs.record(cur == s[i-winner-1])
+		for d := 1; d <= lagDepth && d <= i; d++ {
+			if s[i-d] != cur {
+				continue
+			}
+			scoreboard[d-1]++
+			if scoreboard[d-1] >= highScore {
+				winner, highScore = d-1, scoreboard[d-1]
+			}
+		}
+	}
+	return predictionEstimate(runs.correct, len(s)-1, runs.longest, k, "Lag", verbose, label)
+}
+
+// postfixCounts mirrors the reference's PostfixDictionary: how often each
+// symbol followed a given prefix, and the current prediction, the most
+// frequent of them with ties going to the larger symbol.
+type postfixCounts struct {
+	symbols    []byte
+	counts     []int
+	best       int
+	prediction byte
+}
+
+// increment counts y after the prefix. A symbol not seen before is added only
+// when makeNew is set; the return value reports whether it was.
+func (p *postfixCounts) increment(y byte, makeNew bool) bool {
+	var count int
+	created := false
+	idx := -1
+	for i, sym := range p.symbols {
+		if sym == y {
+			idx = i
+			break
+		}
+	}
+	switch {
+	case idx >= 0:
+		p.counts[idx]++
+		count = p.counts[idx]
+	case makeNew:
+		p.symbols = append(p.symbols, y)
+		p.counts = append(p.counts, 1)
+		count, created = 1, true
+	default:
+		return false
+	}
+	if count > p.best || (count == p.best && y > p.prediction) {
+		p.prediction, p.best = y, count
+	}
+	return created
+}
+
+// prefixKey holds a prefix of up to 16 symbols, zero padded, as the
+// fixed-size arrays keying the reference's dictionaries.
+type prefixKey [16]byte
+
+// makePrefixKey returns the key of prefix.
+func makePrefixKey(prefix []byte) prefixKey {
+	var key prefixKey
+	copy(key[:], prefix)
+	return key
+}
+
+// binaryPrefixCounts returns, for each prefix length d from 1 to depth, a
+// table holding two counters for every d-bit prefix: how often a 0 and a 1
+// followed it.
+func binaryPrefixCounts(depth int) [][]int {
+	dict := make([][]int, depth)
+	for d := range dict {
+		dict[d] = make([]int, 1<<(d+2))
+	}
+	return dict
+}
+
+// binaryEntry returns the counters of the d-bit prefix held in the low bits
+// of pattern.
+func binaryEntry(dict [][]int, d int, pattern uint32) []int {
+	off := (pattern & (1<<uint(d) - 1)) << 1
+	return dict[d-1][off : off+2]
+}
+
+// multiMMC implements the MultiMMC prediction estimate of Section 6.3.9:
+// sixteen Markov models of order 1 to 16 predict the symbol that most often
+// followed the current context, and the one with the most correct guesses so
+// far makes the prediction. Predictions and model updates are interleaved as
+// in the reference implementation.
+func multiMMC(s []byte, k int, verbose int, label string) prediction {
+	if len(s) < 3 {
+		if verbose > 1 {
+			fmt.Printf("%s MultiMMC Estimate: need at least 3 samples.\n", label)
+		}
+		return unavailablePrediction
+	}
+	if k == 2 {
+		return binaryMultiMMC(s, verbose, label)
+	}
+
+	n := len(s) - 2
+	var (
+		models     [mmcDepth]map[prefixKey]*postfixCounts
+		entries    [mmcDepth]int
+		scoreboard [mmcDepth]int
+		runs       runCounter
+	)
+	for d := range models {
+		models[d] = make(map[prefixKey]*postfixCounts)
+		if d < n {
+			counts := &postfixCounts{}
+			counts.increment(s[d+1], true)
+			models[d][makePrefixKey(s[:d+1])] = counts
+			entries[d] = 1
+		}
+	}
+
+	winner := 0
+	for i := 2; i < len(s); i++ {
+		found := false
+		curWinner := winner
+		var counts *postfixCounts
+		for d := 0; d < mmcDepth && d <= i-2; d++ {
+			key := makePrefixKey(s[i-d-1 : i])
+			if d == 0 || found {
+				counts, found = models[d][key]
+			}
+			if !found {
+				if entries[d] < mmcMaxEntries {
+					c := models[d][key]
+					if c == nil {
+						c = &postfixCounts{}
+						models[d][key] = c
+					}
+					c.increment(s[i], true)
+					entries[d]++
+				}
+				continue
+			}
+
+			if counts.prediction == s[i] {
+				scoreboard[d]++
+				if scoreboard[d] >= scoreboard[winner] {
+					winner = d
+				}
+				if d == curWinner {
+					runs.record(true)
+				}
+			} else if d == curWinner {
+				runs.record(false)
+			}
+			if counts.increment(s[i], entries[d] < mmcMaxEntries) {
+				entries[d]++
+			}
+		}
+	}
+	return predictionEstimate(runs.correct, n, runs.longest, k, "MultiMMC", verbose, label)
+}
+
+// binaryMultiMMC is the MultiMMC estimate for binary data, with the models
+// held in flat counter tables instead of maps.
+func binaryMultiMMC(s []byte, verbose int, label string) prediction {
+	dict := binaryPrefixCounts(mmcDepth)
+	var (
+		dictElems  [mmcDepth]int
+		scoreboard [mmcDepth]int
+		runs       runCounter
+		pattern    uint32
+	)
+	for d := 0; d < mmcDepth && d+1 < len(s); d++ {
+		pattern = pattern<<1 | uint32(s[d]&1)
+		binaryEntry(dict, d+1, pattern)[s[d+1]&1] = 1
+		dictElems[d] = 1
+	}
+
+	winner := 0
+	for i := 2; i < len(s); i++ {
+		found := false
+		curWinner := winner
+		pattern = 0
+		for d := 0; d < mmcDepth && d <= i-2; d++ {
+			pattern |= uint32(s[i-d-1]&1) << uint(d)
+			entry := binaryEntry(dict, d+1, pattern)
+			var predicted byte
+			if d == 0 || found {
+				count := entry[1]
+				predicted = 1
+				if entry[0] > entry[1] {
+					count, predicted = entry[0], 0
+				}
+				found = count != 0
+			}
+			if !found {
+				if dictElems[d] < mmcMaxEntries {
+					entry[s[i]&1] = 1
+					dictElems[d]++
+				}
+				continue
+			}
+
+			if predicted == s[i] {
+				scoreboard[d]++
+				if scoreboard[d] >= scoreboard[winner] {
+					winner = d
+				}
+				if d == curWinner {
+					runs.record(true)
+				}
+			} else if d == curWinner {
+				runs.record(false)
+			}
+			if entry[s[i]&1] != 0 {
+				entry[s[i]&1]++
+			} else if dictElems[d] < mmcMaxEntries {
+				entry[s[i]&1] = 1
+				dictElems[d]++
+			}
+		}
+	}
+	return predictionEstimate(runs.correct, len(s)-2, runs.longest, 2, "MultiMMC", verbose, label)
+}
+
+// lz78y implements the LZ78Y prediction estimate of Section 6.3.10: a
+// dictionary of up to 65536 strings of length 1 to 16 records which symbol
+// followed each of them, and the longest matching suffix with the highest
+// count makes the prediction.
+func lz78y(s []byte, k int, verbose int, label string) prediction {
+	minLen := lz78yPrefixLen + 2
+	if k == 2 {
+		minLen++
+	}
+	if len(s) < minLen {
+		if verbose > 1 {
+			fmt.Printf("%s LZ78Y Estimate: need at least %d samples.\n", label, minLen)
+		}
+		return unavailablePrediction
+	}
+	if k == 2 {
+		return binaryLZ78Y(s, verbose, label)
+	}
+
+	var (
+		dict     [lz78yPrefixLen]map[prefixKey]*postfixCounts
+		dictSize int
+		runs     runCounter
+	)
+	for j := range dict {
+		dict[j] = make(map[prefixKey]*postfixCounts)
+	}
+	for j := 1; j <= lz78yPrefixLen; j++ {
+		counts := &postfixCounts{}
+		counts.increment(s[lz78yPrefixLen], true)
+		dict[j-1][makePrefixKey(s[lz78yPrefixLen-j:lz78yPrefixLen])] = counts
+		dictSize++
+	}
+
+	for i := lz78yPrefixLen + 1; i < len(s); i++ {
+		havePrediction := false
+		var predicted byte
+		maxCount := 0
+		for j := lz78yPrefixLen; j > 0; j-- {
+			key := makePrefixKey(s[i-j : i])
+			if counts, ok := dict[j-1][key]; ok {
+				if counts.best > maxCount {
+					maxCount, predicted, havePrediction = counts.best, counts.prediction, true
+				}
+				counts.increment(s[i], true)
+			} else if dictSize < lz78yMaxDict {
+				counts := &postfixCounts{}
+				counts.increment(s[i], true)
+				dict[j-1][key] = counts
+				dictSize++
+			}
+		}
+		runs.record(havePrediction && predicted == s[i])
+	}
+	return predictionEstimate(runs.correct, len(s)-lz78yPrefixLen-1, runs.longest, k, "LZ78Y", verbose, label)
+}
+
+// binaryLZ78Y is the LZ78Y estimate for binary data, with the dictionary
+// held in flat counter tables instead of maps.
+func binaryLZ78Y(s []byte, verbose int, label string) prediction {
+	dict := binaryPrefixCounts(lz78yPrefixLen)
+	dictElems := 0
+	var pattern uint32
+	for j := 0; j < lz78yPrefixLen; j++ {
+		pattern |= uint32(s[lz78yPrefixLen-j-1]&1) << uint(j)
+		binaryEntry(dict, j+1, pattern)[s[lz78yPrefixLen]&1] = 1
+		dictElems++
+	}
+
+	var runs runCounter
+	for i := lz78yPrefixLen + 1; i < len(s); i++ {
+		havePrediction := false
+		var predicted byte
+		maxCount := 0
+		pattern = 0
+		for _, b := range s[i-lz78yPrefixLen : i] {
+			pattern = pattern<<1 | uint32(b&1)
+		}
+		for j := lz78yPrefixLen; j > 0; j-- {
+			entry := binaryEntry(dict, j, pattern)
+			count, guess := entry[1], byte(1)
+			if entry[0] > entry[1] {
+				count, guess = entry[0], 0
+			}
+			if count != 0 {
+				if count > maxCount {
+					maxCount, predicted, havePrediction = count, guess, true
+				}
+				entry[s[i]&1]++
+			} else if dictElems < lz78yMaxDict {
+				entry[s[i]&1] = 1
+				dictElems++
+			}
+		}
+		runs.record(havePrediction && predicted == s[i])
+	}
+	return predictionEstimate(runs.correct, len(s)-lz78yPrefixLen-1, runs.longest, 2, "LZ78Y", verbose, label)
+}
@@ -0,0 +1,192 @@
+package entropy
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const predictorMask = estimatorMultiMCW | estimatorLag | estimatorMultiMMC | estimatorLZ78Y
+
+// predictorReference holds the MultiMCW, Lag, MultiMMC and LZ78Y estimates of
+// a string in that order.
+type predictorReference [4]float64
+
+// longRunData returns 6000 random 2-bit samples with a run of 400 equal
+// samples in the middle, for which P_local exceeds P'_global.
+func longRunData() []byte {
+	r := rand.New(rand.NewSource(7))
+	data := make([]byte, 6000)
+	for i := range data {
+		data[i] = byte(r.Intn(4))
+	}
+	for i := 2000; i < 2400; i++ {
+		data[i] = 3
+	}
+	return data
+}
+
+func TestCalculateNative_PredictionEstimatesMatchReference(t *testing.T) {
+	// Values produced by multi_mcw_test, lag_test, multi_mmc_test and
+	// LZ78Y_test from the NIST reference sources; the bitstring is only
+	// assessed for alphabets of more than two symbols.
+	selfTest := func(name string) []byte {
+		data, err := selfTestFS.ReadFile(path.Join(selfTestDir, name))
+		require.NoError(t, err)
+		return data
+	}
+	cases := []struct {
+		name      string
+		data      []byte
+		bits      int
+		literal   predictorReference
+		bitstring *predictorReference
+	}{
+		{"biased1", selfTest("biased1.bin"), 1,
+			predictorReference{0.71767519920663891, 0.93657951499636993, 0.7086773076093752, 0.70550514715219426}, nil},
+		{"sparse4", selfTest("sparse4.bin"), 4,
+			predictorReference{1.0711384679929463, 1.7007185772552824, 1.0826412462803312, 1.0841976192806078},
+			&predictorReference{0.51030071720195702, 0.35993881811492096, 0.29738797213394397, 0.36000577410893159}},
+		{"skewed8", selfTest("skewed8.bin"), 8,
+			predictorReference{5.2937714702226355, 6.3507393763946896, 6.2051775599622552, 6.2034033896919212},
+			&predictorReference{0.78484913856585248, 0.8188454788179127, 0.77738893248211227, 0.77658314898978564}},
+		{"long-run", longRunData(), 2,
+			predictorReference{0.035152032000296818, 0.034307010519245319, 0.03634552660462112, 0.036334274432725903},
+			&predictorReference{0.017010566133361518, 0.017973389763809494, 0.017117136758632197, 0.017018756147819423}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := calculateNative("test", NonIID, tc.data, tc.bits, 0, predictorMask, MSBFirst, permutationOptions{}, nil)
+			require.NoError(t, err)
+			require.Len(t, result.Estimators, len(nativePredictors))
+
+			hOriginal := float64(tc.bits)
+			for i, p := range nativePredictors {
+				est := result.Estimators[i]
+				assert.Equal(t, p.name, est.Name)
+				assert.InDelta(t, tc.literal[i], est.EntropyEstimate, nativeTolerance, p.name)
+				assert.True(t, est.IsEntropyValid, p.name)
+				hOriginal = math.Min(hOriginal, tc.literal[i])
+			}
+			assert.InDelta(t, hOriginal, result.HOriginal, nativeTolerance)
+
+			wantBitstring := 1.0
+			if tc.bitstring != nil {
+				for _, v := range tc.bitstring {
+					wantBitstring = math.Min(wantBitstring, v)
+				}
+			}
+			assert.InDelta(t, wantBitstring, result.HBitstring, nativeTolerance)
+		})
+	}
+}
+
+func TestCalculateNative_PredictionDetails(t *testing.T) {
+	result, err := calculateNative("test", NonIID, longRunData(), 2, 0, estimatorLag, MSBFirst, permutationOptions{}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Estimators, 1)
+
+	details := result.Estimators[0].Details
+	require.NotNil(t, details)
+	assert.Equal(t, 5999.0, details["predictions"])
+	assert.GreaterOrEqual(t, details["longest_run"], 390.0)
+	// The long run dominates: the entropy derives from P_local, which
+	// exceeds P'_global.
+	assert.Greater(t, details["p_local"], details["p_global"])
+	assert.InDelta(t, -math.Log2(details["p_local"]), result.Estimators[0].EntropyEstimate, nativeTolerance)
+}
+
+func TestPredictionEstimate_Bounds(t *testing.T) {
+	// Without a correct prediction P'_global is 1 - 0.01^(1/N), and P_local is
+	// clamped to the chance of a random guess.
+	res := predictionEstimate(0, 1000, 0, 4, "Test", 0, "Literal")
+	assert.InDelta(t, 1-math.Pow(0.01, 1.0/1000), res.PGlobal, 1e-15)
+	assert.Equal(t, 0.25, res.PLocal)
+	assert.Equal(t, 2.0, res.Entropy)
+
+	// A somewhat long run leaves P_local below P'_global, so the estimate
+	// derives from P'_global; it is still reported.
+	res = predictionEstimate(5000, 10000, 18, 2, "Test", 0, "Literal")
+	assert.Greater(t, res.PLocal, 0.5)
+	assert.Less(t, res.PLocal, res.PGlobal)
+	assert.InDelta(t, -math.Log2(res.PGlobal), res.Entropy, 1e-15)
+
+	// Every prediction correct: both bounds reach 1 and no entropy is left.
+	res = predictionEstimate(1000, 1000, 1000, 2, "Test", 0, "Literal")
+	assert.Equal(t, 1.0, res.PGlobal)
+	assert.Greater(t, res.PLocal, 0.99)
+	assert.Equal(t, 0.0, res.Entropy)
+}
+
+func TestPredictors_ShortInput(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func([]byte, int, int, string) prediction
+		min  map[int]int // alphabet size to the minimum sample count
+	}{
+		{"MultiMCW", multiMCW, map[int]int{2: 4096, 3: 4096}},
+		{"Lag", lag, map[int]int{2: 3, 3: 3}},
+		{"MultiMMC", multiMMC, map[int]int{2: 3, 3: 3}},
+		{"LZ78Y", lz78y, map[int]int{2: 19, 3: 18}},
+	}
+	for _, tc := range cases {
+		for k, n := range tc.min {
+			data := make([]byte, n)
+			for i := range data {
+				data[i] = byte(i % k)
+			}
+			assert.Equal(t, -1.0, tc.run(data[:n-1], k, 0, "Literal").Entropy, "%s k=%d", tc.name, k)
+			assert.GreaterOrEqual(t, tc.run(data, k, 0, "Literal").Entropy, 0.0, "%s k=%d", tc.name, k)
+		}
+	}
+}
+
+func TestCalculateNative_PredictionEstimateUnavailable(t *testing.T) {
+	// MultiMCW needs 4096 samples: with fewer it is reported as invalid and
+	// does not lower the entropy fields.
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i % 3)
+	}
+	result, err := calculateNative("test", NonIID, data, 2, 0, estimatorMultiMCW, MSBFirst, permutationOptions{}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Estimators, 1)
+	assert.False(t, result.Estimators[0].IsEntropyValid)
+	assert.False(t, result.Estimators[0].Passed)
+	assert.Nil(t, result.Estimators[0].Details)
+	assert.Equal(t, 2.0, result.HOriginal)
+}
+
+// benchmarkPredictor runs an estimator on 100,000 random 8-bit samples and on
+// the 800,000-bit binary expansion of the same data.
+func benchmarkPredictor(b *testing.B, run func([]byte, int, int, string) prediction) {
+	data := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(data)
+	d := prepareNativeData(data, 8, MSBFirst)
+
+	for _, in := range []struct {
+		name    string
+		symbols []byte
+		k       int
+	}{
+		{"literal", d.symbols, d.alphSize},
+		{"bitstring", d.bitstring, 2},
+	} {
+		b.Run(fmt.Sprintf("%s-%d", in.name, len(in.symbols)), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				run(in.symbols, in.k, 0, "Benchmark")
+			}
+		})
+	}
+}
+
+func BenchmarkMultiMCW(b *testing.B) { benchmarkPredictor(b, multiMCW) }
+func BenchmarkLag(b *testing.B)      { benchmarkPredictor(b, lag) }
+func BenchmarkMultiMMC(b *testing.B) { benchmarkPredictor(b, multiMMC) }
+func BenchmarkLZ78Y(b *testing.B)    { benchmarkPredictor(b, lz78y) }
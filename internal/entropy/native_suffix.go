@@ -0,0 +1,208 @@
+package entropy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"index/suffixarray"
+	"math"
+)
+
+// tTupleCutoff is the minimum number of occurrences of the most common
+// t-tuple for t to be considered by the t-Tuple estimate (Section 6.3.5).
+const tTupleCutoff = 35
+
+// suffixArray returns the start offsets of the suffixes of text in
+// lexicographic order. The array is built by index/suffixarray, which does
+// not expose it directly; it is decoded from the index's serialized form, a
+// varint length header and the text followed by blocks of uvarint offsets,
+// each prefixed with its byte length.
+func suffixArray(text []byte) []int32 {
+	var buf bytes.Buffer
+	if err := suffixarray.New(text).Write(&buf); err != nil {
+		panic(fmt.Sprintf("suffix array serialization: %v", err))
+	}
+
+	raw := buf.Bytes()
+	n, _ := binary.Varint(raw)
+	raw = raw[binary.MaxVarintLen64+int(n):]
+
+	sa := make([]int32, 0, n)
+	for len(raw) > 0 {
+		size, _ := binary.Varint(raw)
+		block := raw[binary.MaxVarintLen64:size]
+		for len(block) > 0 {
+			offset, k := binary.Uvarint(block)
+			sa = append(sa, int32(offset))
+			block = block[k:]
+		}
+		raw = raw[size:]
+	}
+	return sa
+}
+
+// lcpArray returns the longest-common-prefix array of text in the layout the
+// reference implementation uses after shifting out its empty suffix: L[0] and
+// L[n] are 0 and L[i] is the length of the common prefix of the (i-1)-th and
+// i-th smallest suffixes. It is computed from the suffix array with the
+// linear-time algorithm of Kasai et al.
+func lcpArray(text []byte) []int32 {
+	n := len(text)
+	sa := suffixArray(text)
+
+	rank := make([]int32, n)
+	for i, s := range sa {
+		rank[s] = int32(i)
+	}
+
+	lcp := make([]int32, n+1)
+	h := 0
+	for i := 0; i < n; i++ {
+		if k := int(rank[i]); k > 0 {
+			j := int(sa[k-1])
+			for i+h < n && j+h < n && text[i+h] == text[j+h] {
+				h++
+			}
+			lcp[k] = int32(h)
+		}
+		if h > 0 {
+			h--
+		}
+	}
+	return lcp
+}
+
+// suffixEstimates implements the t-Tuple (Section 6.3.5) and Longest
+// Repeated Substring (Section 6.3.6) estimates, which both derive from tuple
+// counts read off the LCP array: the occurrences of a t-tuple are a run of
+// suffixes whose adjacent common prefixes are all at least t long. The
+// reference implementation walks the array with Kaufer's algorithm, whose
+// running time turns quadratic on highly repetitive input; here a single
+// monotonic-stack pass finds, for every entry, the widest run in which it is
+// the minimum, which yields the same counts in linear time. An estimate that
+// cannot be computed is returned as -1.
+func suffixEstimates(text []byte, verbose int, label string) (tTuple, lrs float64) {
+	if len(text) < 2 || len(text) > math.MaxInt32 {
+		return -1, -1
+	}
+	return lcpEstimates(lcpArray(text), verbose, label)
+}
+
+// longestRepeat returns the length of the longest repeated substring of the
+// text whose LCP array is L, the largest adjacent common prefix.
+func longestRepeat(L []int32) int32 {
+	v := int32(0)
+	for _, l := range L {
+		v = max(v, l)
+	}
+	return v
+}
+
+// lcpEstimates computes the estimates of suffixEstimates from the LCP array
+// of a text of len(L)-1 symbols.
+func lcpEstimates(L []int32, verbose int, label string) (tTuple, lrs float64) {
+	tTuple, lrs = -1, -1
+	n := len(L) - 1
+
+	// v is the length of the longest repeated substring.
+	v := longestRepeat(L)
+	if v == 0 {
+		return tTuple, lrs
+	}
+
+	// widest[k] is the largest number of adjacent suffixes whose common prefix
+	// is exactly k long, and pairs[k] the number of suffix pairs whose longest
+	// common prefix is exactly k. Each entry L[x] is the rightmost minimum of the
+	// runs starting after the previous smaller entry and ending before the
+	// next entry that is not larger.
+	widest := make([]int32, v+1)
+	pairs := make([]uint64, v+1)
+	stack := []int32{0}
+	for i := int32(1); i <= int32(n); i++ {
+		for len(stack) > 1 && L[stack[len(stack)-1]] >= L[i] {
+			x := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			p := stack[len(stack)-1]
+			widest[L[x]] = max(widest[L[x]], i-p)
+			pairs[L[x]] += uint64(x-p) * uint64(i-x)
+		}
+		stack = append(stack, i)
+	}
+
+	// Q[t] is the count of the most common t-tuple and S[t] the number of
+	// pairs of equal t-tuples.
+	Q := make([]int32, v+2)
+	S := make([]uint64, v+2)
+	for t := v; t >= 1; t-- {
+		Q[t] = max(Q[t+1], widest[t])
+		S[t] = S[t+1] + pairs[t]
+	}
+
+	// u is the smallest tuple length whose most common tuple occurs fewer
+	// than 35 times.
+	u := int32(1)
+	for u <= v && Q[u] >= tTupleCutoff {
+		u++
+	}
+
+	pMax := -1.0
+	for i := int32(1); i < u; i++ {
+		p := math.Pow(float64(Q[i])/float64(n-int(i)+1), 1/float64(i))
+		pMax = math.Max(pMax, p)
+	}
+	if pMax > 0 {
+		pu := math.Min(1, pMax+zAlpha*math.Sqrt(pMax*(1-pMax)/float64(n-1)))
+		tTuple = -math.Log2(pu)
+		if verbose == 2 {
+			fmt.Printf("%s t-Tuple Estimate: t = %d, p-hat_max = %.17g, p_u = %.17g\n", label, u-1, pMax, pu)
+		}
+	} else if verbose > 1 {
+		fmt.Printf("%s t-Tuple Estimate: No strings are repeated 35 times. t-Tuple estimate failed.\n", label)
+	}
+
+	if v < u {
+		if verbose > 1 {
+			fmt.Printf("%s LRS Estimate: v<u. Can't Run LRS Test.\n", label)
+		}
+		return tTuple, lrs
+	}
+
+	pMax = 0
+	for i := int(u); i <= int(v); i++ {
+		choices := uint64(n-i) * uint64(n-i+1) / 2
+		p := math.Pow(float64(S[i])/float64(choices), 1/float64(i))
+		pMax = math.Max(pMax, p)
+	}
+	pu := math.Min(1, pMax+zAlpha*math.Sqrt(pMax*(1-pMax)/float64(n-1)))
+	lrs = -math.Log2(pu)
+	if verbose == 2 {
+		fmt.Printf("%s LRS Estimate: u = %d, v = %d, p-hat = %.17g, p_u = %.17g\n", label, u, v, pMax, pu)
+	}
+	return tTuple, lrs
+}
+
+// nativeLRS implements LongestRepeatedSubstring in pure Go on the symbols
+// masked to the word size. The estimate is the literal LRS estimate of
+// Section 6.3.6; mapping the symbols down to a dense alphabet, as the full
+// assessment does, changes neither the repeats nor their counts.
+func nativeLRS(op string, data []byte, bitsPerSymbol int) (LRSResult, error) {
+	if len(data) > math.MaxInt32 {
+		return LRSResult{}, newError(op, ErrInvalidData, fmt.Sprintf("at most %d samples are supported, got %d", math.MaxInt32, len(data)))
+	}
+	if bitsPerSymbol == 0 {
+		bitsPerSymbol = detectWordSize(data)
+	}
+	mask := byte(1<<uint(bitsPerSymbol) - 1)
+	symbols := make([]byte, len(data))
+	for i, b := range data {
+		symbols[i] = b & mask
+	}
+
+	L := lcpArray(symbols)
+	_, lrs := lcpEstimates(L, 0, "Literal")
+	return LRSResult{
+		Length:        int(longestRepeat(L)),
+		Estimate:      lrs,
+		BitsPerSymbol: bitsPerSymbol,
+	}, nil
+}
@@ -0,0 +1,250 @@
+package entropy
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEstimates computes the t-Tuple and LRS estimates directly from the
+// definitions in SP 800-90B Sections 6.3.5 and 6.3.6 by counting every tuple.
+func countingEstimates(text []byte) (tTuple, lrs float64) {
+	n := len(text)
+	var q []int
+	var s []uint64
+	for t := 1; t < n; t++ {
+		counts := make(map[string]int)
+		for i := 0; i+t <= n; i++ {
+			counts[string(text[i:i+t])]++
+		}
+		most, pairs := 0, uint64(0)
+		for _, c := range counts {
+			most = max(most, c)
+			pairs += uint64(c) * uint64(c-1) / 2
+		}
+		if most < 2 {
+			break
+		}
+		q = append(q, most)
+		s = append(s, pairs)
+	}
+
+	bound := func(p float64) float64 {
+		return -math.Log2(math.Min(1, p+zAlpha*math.Sqrt(p*(1-p)/float64(n-1))))
+	}
+
+	tTuple, lrs = -1, -1
+	u := 1
+	pMax := 0.0
+	for ; u <= len(q) && q[u-1] >= tTupleCutoff; u++ {
+		pMax = math.Max(pMax, math.Pow(float64(q[u-1])/float64(n-u+1), 1/float64(u)))
+	}
+	if u > 1 {
+		tTuple = bound(pMax)
+	}
+	if u <= len(q) {
+		pMax = 0
+		for t := u; t <= len(q); t++ {
+			choices := float64(n-t) * float64(n-t+1) / 2
+			pMax = math.Max(pMax, math.Pow(float64(s[t-1])/choices, 1/float64(t)))
+		}
+		lrs = bound(pMax)
+	}
+	return tTuple, lrs
+}
+
+// repetitiveInputs returns highly repetitive texts of length n over a small
+// alphabet: a short period, the Thue-Morse sequence, the Fibonacci word and a
+// constant run with two interruptions.
+func repetitiveInputs(n int) map[string][]byte {
+	periodic := make([]byte, n)
+	thueMorse := make([]byte, n)
+	fibonacci := []byte{0, 1}
+	nearConstant := bytes.Repeat([]byte{2}, n)
+	for i := 0; i < n; i++ {
+		periodic[i] = byte(i % 3)
+		thueMorse[i] = byte(bitCount(i) % 2)
+	}
+	for len(fibonacci) < n {
+		next := make([]byte, 0, 2*len(fibonacci))
+		for _, b := range fibonacci {
+			next = append(next, 0)
+			if b == 0 {
+				next = append(next, 1)
+			}
+		}
+		fibonacci = next
+	}
+	nearConstant[3] = 0
+	nearConstant[n/2] = 1
+	return map[string][]byte{
+		"periodic":      periodic,
+		"thue-morse":    thueMorse,
+		"fibonacci":     fibonacci[:n],
+		"near-constant": nearConstant,
+	}
+}
+
+func bitCount(i int) int {
+	c := 0
+	for ; i > 0; i &= i - 1 {
+		c++
+	}
+	return c
+}
+
+func TestSuffixArray_SortsSuffixes(t *testing.T) {
+	text := []byte("mississippi")
+	want := make([]int32, len(text))
+	for i := range want {
+		want[i] = int32(i)
+	}
+	sort.Slice(want, func(a, b int) bool {
+		return bytes.Compare(text[want[a]:], text[want[b]:]) < 0
+	})
+	assert.Equal(t, want, suffixArray(text))
+
+	// issi/ississippi share 4, ssi/ssissippi share 3, and so on.
+	assert.Equal(t, []int32{0, 1, 1, 4, 0, 0, 1, 0, 2, 1, 3, 0}, lcpArray(text))
+}
+
+func TestSuffixEstimates_MatchesTupleCounting(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	inputs := repetitiveInputs(1500)
+	for _, alphabet := range []int{2, 4} {
+		random := make([]byte, 1500)
+		for i := range random {
+			random[i] = byte(r.Intn(alphabet))
+		}
+		inputs[fmt.Sprintf("random-%d", alphabet)] = random
+	}
+
+	for name, text := range inputs {
+		wantTTuple, wantLRS := countingEstimates(text)
+		gotTTuple, gotLRS := suffixEstimates(text, 0, "Literal")
+		assert.InDelta(t, wantTTuple, gotTTuple, 1e-12, name)
+		assert.InDelta(t, wantLRS, gotLRS, 1e-12, name)
+	}
+}
+
+func TestSuffixEstimates_WithoutRepeats(t *testing.T) {
+	tTuple, lrs := suffixEstimates([]byte{0, 1, 2, 3}, 0, "Literal")
+	assert.Equal(t, -1.0, tTuple)
+	assert.Equal(t, -1.0, lrs)
+
+	tTuple, lrs = suffixEstimates([]byte{1}, 0, "Literal")
+	assert.Equal(t, -1.0, tTuple)
+	assert.Equal(t, -1.0, lrs)
+
+	// Repeats occurring fewer than 35 times leave only the LRS estimate.
+	tTuple, lrs = suffixEstimates([]byte{0, 1, 0, 1, 2}, 0, "Literal")
+	assert.Equal(t, -1.0, tTuple)
+	assert.GreaterOrEqual(t, lrs, 0.0)
+}
+
+func TestCalculateNative_TupleEstimatesMatchReference(t *testing.T) {
+	// Values produced by SAalgs from the NIST reference sources on the
+	// self-test sample files; the bitstring is only assessed for alphabets
+	// of more than two symbols.
+	cases := []struct {
+		file                 string
+		bits                 int
+		tTuple, lrs          float64
+		bitTTuple, bitLRS    float64
+		hOriginal, hAssessed float64
+	}{
+		{"biased1.bin", 1, 0.70097161669762498, 0.85983234139451015, -1, -1, 0.70097161669762498, 0.70097161669762498},
+		{"sparse4.bin", 4, 1.0599115535957093, 1.6811561725236588, 0.29684864687495338, 0.47548995815330569, 1.0599115535957093, 1.0599115535957093},
+		{"skewed8.bin", 8, 5.0162227605831333, 6.2558242815416909, 0.65184227432678288, 0.86659067402097489, 5.0162227605831333, 5.0162227605831333},
+	}
+
+	for _, tc := range cases {
+		data, err := selfTestFS.ReadFile(path.Join(selfTestDir, tc.file))
+		require.NoError(t, err)
+
+		result, err := calculateNative("test", NonIID, data, tc.bits, 0, estimatorTTuple|estimatorLRS, MSBFirst, permutationOptions{}, nil)
+		require.NoError(t, err, tc.file)
+		require.Len(t, result.Estimators, 2, tc.file)
+		assert.Equal(t, "t-Tuple Test", result.Estimators[0].Name)
+		assert.InDelta(t, tc.tTuple, result.Estimators[0].EntropyEstimate, nativeTolerance, tc.file)
+		assert.Equal(t, "LRS Test", result.Estimators[1].Name)
+		assert.InDelta(t, tc.lrs, result.Estimators[1].EntropyEstimate, nativeTolerance, tc.file)
+
+		wantBitstring := 1.0
+		if tc.bitTTuple >= 0 {
+			wantBitstring = math.Min(tc.bitTTuple, tc.bitLRS)
+		}
+		assert.InDelta(t, wantBitstring, result.HBitstring, nativeTolerance, tc.file)
+		assert.InDelta(t, tc.hOriginal, result.HOriginal, nativeTolerance, tc.file)
+		assert.InDelta(t, tc.hAssessed, result.HAssessed, nativeTolerance, tc.file)
+	}
+}
+
+func TestCalculateNative_TupleEstimateUnavailable(t *testing.T) {
+	// No tuple repeats 35 times: the t-Tuple estimate is reported as invalid
+	// and does not lower the entropy fields.
+	result, err := calculateNative("test", NonIID, []byte{0, 1, 0, 1, 2}, 2, 0, estimatorTTuple, MSBFirst, permutationOptions{}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Estimators, 1)
+	assert.False(t, result.Estimators[0].IsEntropyValid)
+	assert.False(t, result.Estimators[0].Passed)
+	assert.Equal(t, 2.0, result.HOriginal)
+}
+
+// TestSuffixEstimates_MillionSamples runs the estimates on 1,000,000 8-bit
+// samples of random data and on highly repetitive strings as long as their
+// 8,000,000-bit bitstring. BenchmarkSuffixEstimates measures the time they
+// take.
+func TestSuffixEstimates_MillionSamples(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping 1,000,000-sample assessment in short mode")
+	}
+
+	const n = 1000000
+	random := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(random)
+	result, err := calculateNative("test", NonIID, random, 8, 0, estimatorTTuple|estimatorLRS, MSBFirst, permutationOptions{}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Estimators, 2)
+
+	for name, text := range repetitiveInputs(8 * n) {
+		_, lrs := suffixEstimates(text, 0, "Bitstring")
+		assert.GreaterOrEqual(t, lrs, 0.0, name)
+	}
+}
+
+// BenchmarkSuffixEstimates measures the inputs of
+// TestSuffixEstimates_MillionSamples, whose target is 10 seconds each.
+func BenchmarkSuffixEstimates(b *testing.B) {
+	const n = 1000000
+	random := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(random)
+	b.Run("random", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := calculateNative("test", NonIID, random, 8, 0, estimatorTTuple|estimatorLRS, MSBFirst, permutationOptions{}, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	inputs := repetitiveInputs(8 * n)
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				suffixEstimates(inputs[name], 0, "Bitstring")
+			}
+		})
+	}
+}
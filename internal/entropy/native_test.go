@@ -0,0 +1,185 @@
+package entropy
+
+import (
+	"errors"
+	"math"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nativeTolerance is the largest accepted difference between the pure-Go
+// estimates and the reference tool's values recorded in the self-test vectors.
+const nativeTolerance = 1e-6
+
+func TestCalculateNative_MatchesSelfTestVectors(t *testing.T) {
+	vectors, err := loadSelfTestVectors()
+	require.NoError(t, err)
+
+	for _, v := range vectors {
+		mask, _, err := estimatorMask("test", v.testType, v.Estimators)
+		require.NoError(t, err)
+
+		result, err := calculateNative("test", v.testType, v.data, v.BitsPerSymbol, 0, mask, MSBFirst, permutationOptions{}, nil)
+		require.NoError(t, err, v.Name)
+
+		assert.InDelta(t, v.Expected.MinEntropy, result.MinEntropy, nativeTolerance, v.Name)
+		assert.InDelta(t, v.Expected.HOriginal, result.HOriginal, nativeTolerance, v.Name)
+		assert.InDelta(t, v.Expected.HBitstring, result.HBitstring, nativeTolerance, v.Name)
+		for name, want := range v.Expected.Estimators {
+			est, ok := findEstimator(result.Estimators, name)
+			require.True(t, ok, "%s: %s missing", v.Name, name)
+			assert.InDelta(t, want, est.EntropyEstimate, nativeTolerance, v.Name)
+		}
+		assert.False(t, result.Partial, v.Name)
+		assert.Equal(t, v.testType, result.TestType)
+	}
+}
+
+func TestCalculateNative_FullSelectionIsPartial(t *testing.T) {
+	data := []byte{0, 1, 2, 3, 0, 1, 2, 0, 1, 0}
+
+	want := map[TestType][]string{
+		IID: {"Most Common Value", "Chi-Square Tests", "Permutation Tests"},
+		NonIID: {
+			"Most Common Value", "Collision Test", "t-Tuple Test", "LRS Test",
+			"Multi Most Common in Window Test", "Lag Prediction Test",
+			"Multi Markov Model with Counting Test", "LZ78Y Test",
+		},
+	}
+	for testType, names := range want {
+		result, err := calculateNative("test", testType, data, 2, 0, 0, MSBFirst, permutationOptions{}, nil)
+		require.NoError(t, err)
+		assert.True(t, result.Partial, testType.String())
+		require.Len(t, result.Estimators, len(names), testType.String())
+		for i, name := range names {
+			assert.Equal(t, name, result.Estimators[i].Name)
+			assert.Equal(t, EstimatorIDFromName(name), result.Estimators[i].ID, name)
+		}
+	}
+}
+
+func TestCalculateNative_ReportsProgress(t *testing.T) {
+	data := []byte{0, 1, 2, 3, 0, 1, 2, 0, 1, 0}
+
+	var events []progressEvent
+	_, err := calculateNative("test", NonIID, data, 2, 0, 0, MSBFirst, permutationOptions{}, recordProgress(&events))
+	require.NoError(t, err)
+
+	// Only the estimators that run natively are reported.
+	want := []string{
+		"Most Common Value", "Collision Test", "t-Tuple and LRS Tests",
+		"Multi Most Common in Window Test", "Lag Prediction Test",
+		"Multi Markov Model with Counting Test", "LZ78Y Test", ProgressDone,
+	}
+	require.Len(t, events, len(want))
+	for i, event := range events {
+		assert.Equal(t, want[i], event.phase)
+		assert.InDelta(t, 100*float64(i)/7, event.percent, 1e-9, event.phase)
+	}
+}
+
+func TestCalculateNative_NoNativeEstimatorSelected(t *testing.T) {
+	_, err := calculateNative("test", IID, []byte{0, 1, 0, 1}, 1, 0, estimatorLRS, MSBFirst, permutationOptions{}, nil)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrEstimatorUnavailable))
+}
+
+func TestCalculateNative_SingleSymbol(t *testing.T) {
+	_, err := calculateNative("test", NonIID, []byte{5, 5, 5, 5}, 8, 0, estimatorMCV, MSBFirst, permutationOptions{}, nil)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+
+	_, err = calculateNative("test", NonIID, nil, 8, 0, estimatorMCV, MSBFirst, permutationOptions{}, nil)
+	assert.True(t, errors.Is(err, ErrInvalidData))
+}
+
+func TestPrepareNativeData(t *testing.T) {
+	d := prepareNativeData([]byte{0x01, 0x12, 0x16, 0x01}, 0, MSBFirst)
+	assert.Equal(t, 5, d.wordSize)
+	assert.Equal(t, 3, d.alphSize)
+	assert.Equal(t, []byte{0, 1, 2, 0}, d.symbols)
+	assert.Equal(t, uint64(2), d.histogram[0x01])
+	assert.Equal(t, []byte{0, 0, 0, 0, 1}, d.bitstring[:5])
+
+	d = prepareNativeData([]byte{0x01, 0xF2}, 4, LSBFirst)
+	assert.Equal(t, 4, d.wordSize)
+	assert.Equal(t, []byte{1, 0, 0, 0, 0, 1, 0, 0}, d.bitstring)
+	assert.Equal(t, uint64(1), d.histogram[0x02])
+
+	assert.Equal(t, 1, prepareNativeData([]byte{0, 0}, 0, MSBFirst).wordSize)
+}
+
+// collisionExpectation is the right-hand side of SP 800-90B Section 6.3.2
+// step 7 for binary data, with F(q) = q(2q^2 + 2q + 1).
+func collisionExpectation(p float64) float64 {
+	q := 1 - p
+	f := q * (2*q*q + 2*q + 1)
+	return p/(q*q)*(1+0.5*(1/p-1/q))*f - p/q*0.5*(1/p-1/q)
+}
+
+func TestCollision_WorkedExample(t *testing.T) {
+	// Collision times 3, 3, 3, 3, 3, 2, 3: X-bar = 20/7, sigma-hat = 0.37796,
+	// X-bar' = 2.48915 and p = 0.57366.
+	bits := []byte{1, 0, 0, 0, 1, 1, 1, 0, 0, 1, 0, 1, 0, 1, 0, 1, 1, 1, 0, 0}
+	assert.InDelta(t, 0.80189300149840637, collision(bits, 0, "Literal"), nativeTolerance)
+
+	// Only pairs of equal bits: X-bar' is clamped to 2 and p to 1.
+	assert.Equal(t, 0.0, collision([]byte{0, 0, 1, 1, 0, 0, 1, 1}, 0, "Literal"))
+
+	// A mean collision time far above 2.5 has no root; p falls back to 0.5.
+	alternating := make([]byte, 3000)
+	for i := range alternating {
+		alternating[i] = byte(i % 2)
+	}
+	assert.Equal(t, 1.0, collision(alternating, 0, "Literal"))
+}
+
+func TestCollision_SolvesStepSeven(t *testing.T) {
+	for _, bound := range []float64{2.01, 2.1, 2.25, 2.4, 2.49} {
+		lo, hi := 0.5, 1.0-1e-12
+		for i := 0; i < 200; i++ {
+			mid := (lo + hi) / 2
+			if collisionExpectation(mid) > bound {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		assert.InDelta(t, 0.5+math.Sqrt(1.25-0.5*bound), lo, 1e-9, "X-bar' = %g", bound)
+	}
+}
+
+func TestCalculateNative_CollisionMatchesReference(t *testing.T) {
+	// Values produced by collision_test from the NIST reference sources on
+	// the self-test sample files.
+	cases := []struct {
+		file string
+		bits int
+		want float64
+	}{
+		{"biased1.bin", 1, 0.68143519652109907},
+		{"sparse4.bin", 4, 0.12439097096329124},
+		{"skewed8.bin", 8, 0.52775722725331986},
+	}
+
+	for _, tc := range cases {
+		data, err := selfTestFS.ReadFile(path.Join(selfTestDir, tc.file))
+		require.NoError(t, err)
+
+		result, err := calculateNative("test", NonIID, data, tc.bits, 0, estimatorCollision, MSBFirst, permutationOptions{}, nil)
+		require.NoError(t, err, tc.file)
+		est, ok := findEstimator(result.Estimators, "Collision Test")
+		require.True(t, ok, tc.file)
+		assert.InDelta(t, tc.want, est.EntropyEstimate, nativeTolerance, tc.file)
+
+		if tc.bits == 1 {
+			assert.InDelta(t, tc.want, result.HOriginal, nativeTolerance, tc.file)
+			assert.Equal(t, 1.0, result.HBitstring, tc.file)
+		} else {
+			assert.InDelta(t, tc.want, result.HBitstring, nativeTolerance, tc.file)
+		}
+	}
+}
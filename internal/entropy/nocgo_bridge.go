@@ -0,0 +1,43 @@
+//go:build (nocgo || !cgo) && !teststub
+
+// This file replaces the CGO bridge in builds without the NIST C++ library,
+// selected with the "nocgo" build tag or automatically when CGO is disabled.
+// Only the estimators implemented in pure Go run; results that omit requested
+// estimators are marked Partial.
+
+package entropy
+
+// cgoEnabled reports that the NIST library is not linked.
+const cgoEnabled = false
+
+// backend reports that the pure-Go estimators run.
+const backend = BackendGo
+
+// nativeVersion is reported for both library versions in pure-Go builds.
+const nativeVersion = "pure-go"
+
+func libraryVersions() (tool, wrapper string) {
+	return nativeVersion, nativeVersion
+}
+
+// calculateIIDEntropy runs the pure-Go subset of the IID assessment. The
+// LRS test is not available.
+func calculateIIDEntropy(data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, perm permutationOptions, progress ProgressFunc) (*Result, error) {
+	return calculateNative("calculateIIDEntropy", IID, data, bitsPerSymbol, verbose, mask, order, perm, progress)
+}
+
+// calculateNonIIDEntropy runs the pure-Go subset of the Non-IID estimators.
+func calculateNonIIDEntropy(data []byte, bitsPerSymbol int, verbose int, mask uint32, order BitOrder, progress ProgressFunc) (*Result, error) {
+	return calculateNative("calculateNonIIDEntropy", NonIID, data, bitsPerSymbol, verbose, mask, order, permutationOptions{}, progress)
+}
+
+// releaseRuntime has nothing to release: the pure-Go estimators hold no
+// process-wide resources.
+func releaseRuntime() error {
+	return nil
+}
+
+// calculateLRS computes the longest repeated substring in pure Go.
+func calculateLRS(data []byte, bitsPerSymbol int) (LRSResult, error) {
+	return nativeLRS("calculateLRS", data, bitsPerSymbol)
+}
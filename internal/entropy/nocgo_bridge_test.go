@@ -0,0 +1,88 @@
+//go:build (nocgo || !cgo) && !teststub
+
+package entropy
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The embedded vectors only use the Most Common Value estimator, so the
+// pure-Go build must pass the known-answer self-test.
+func TestSelfTest_PassesInPureGoBuild(t *testing.T) {
+	assert.NoError(t, SelfTest())
+}
+
+func TestLibraryInfo_PureGo(t *testing.T) {
+	info := LibraryInfo()
+	assert.False(t, info.CGO)
+	assert.Equal(t, nativeVersion, info.ToolVersion)
+	assert.Equal(t, nativeVersion, info.WrapperVersion)
+	assert.Equal(t, BackendGo, info.Backend)
+	assert.Equal(t, []string{"mcv", "chi-square", "permutation"}, info.IIDEstimators)
+	assert.Equal(t, []string{
+		"mcv", "collision", "t-tuple", "lrs", "multi-mcw", "lag", "multi-mmc", "lz78y",
+	}, info.NonIIDEstimators)
+}
+
+func TestActiveEstimators_PureGoOnlyNative(t *testing.T) {
+	for _, testType := range []TestType{IID, NonIID} {
+		for _, name := range ActiveEstimators(testType) {
+			mask, _, err := estimatorMask("test", testType, []string{name})
+			require.NoError(t, err)
+			assert.NotZero(t, mask&nativeEstimatorsFor(testType), "%s %s", testType, name)
+		}
+	}
+}
+
+func TestAssessNonIID_PureGoIsPartial(t *testing.T) {
+	result, err := NewAssessment().AssessNonIID([]byte{0, 1, 2, 3, 0, 1, 2, 0, 1, 0}, 2)
+	require.NoError(t, err)
+	assert.True(t, result.Partial)
+	assert.Equal(t, BackendGo, result.Backend)
+	require.Len(t, result.Estimators, 8)
+	assert.Equal(t, "Most Common Value", result.Estimators[0].Name)
+	assert.Equal(t, "Collision Test", result.Estimators[1].Name)
+	assert.Equal(t, "t-Tuple Test", result.Estimators[2].Name)
+	assert.Equal(t, "LRS Test", result.Estimators[3].Name)
+	assert.Equal(t, "Multi Most Common in Window Test", result.Estimators[4].Name)
+	assert.Equal(t, "Lag Prediction Test", result.Estimators[5].Name)
+	assert.Equal(t, "Multi Markov Model with Counting Test", result.Estimators[6].Name)
+	assert.Equal(t, "LZ78Y Test", result.Estimators[7].Name)
+}
+
+func TestCheckIID_PureGoRunsIIDTests(t *testing.T) {
+	var warnings bytes.Buffer
+	assessment := NewAssessment()
+	assessment.SetWarnWriter(&warnings)
+	assessment.SetPermutationRounds(300)
+	assessment.SetPermutationSeed(1)
+
+	passed, tests, err := assessment.CheckIID(randomSamples(5000, 4, 3), 4)
+	require.NoError(t, err)
+	require.Len(t, tests, 2)
+	assert.Equal(t, "Chi-Square Tests", tests[0].Name)
+	assert.Equal(t, "Permutation Tests", tests[1].Name)
+	assert.True(t, tests[0].Passed)
+	assert.True(t, tests[1].Passed)
+	assert.True(t, passed)
+	assert.Contains(t, warnings.String(), "only the IID tests with a pure-Go implementation ran")
+	assert.Contains(t, warnings.String(), "running 300 permutation rounds instead of 10000")
+}
+
+func TestAssessIID_UnavailableInPureGoBuild(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetEstimators([]string{"lrs"})
+	_, err := assessment.AssessIID([]byte{0, 1, 0, 1}, 1)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrEstimatorUnavailable))
+}
+
+func TestCrossValidate_UnavailableInPureGoBuild(t *testing.T) {
+	_, err := NewAssessment().CrossValidate(randomSamples(1000, 4, 1), 4, NonIID, DefaultCrossValidationTolerance)
+	assert.True(t, errors.Is(err, ErrEstimatorUnavailable))
+}
@@ -0,0 +1,111 @@
+package entropy
+
+// ProgressFunc receives the progress of a running assessment at estimator
+// boundaries: the name of the phase that starts and the percentage of the
+// selected phases already completed. The estimator phases of a calculation
+// follow a ProgressIID or ProgressNonIID stage at 0 percent, and the last
+// call of a successful calculation reports ProgressDone at 100 percent.
+type ProgressFunc func(phase string, percent float64)
+
+// ProgressDone is the phase of the final progress report. It matches
+// PROGRESS_PHASE_DONE in wrapper.h.
+const ProgressDone = "Done"
+
+// Stages reported around the estimator phases. AssessReader and AssessFile
+// report ProgressReading before they read the input, and every calculation
+// reports the stage of its test type before it starts.
+const (
+	ProgressReading = "Reading data"
+	ProgressIID     = "IID"
+	ProgressNonIID  = "Non-IID"
+)
+
+// tTupleLRSPhase is the phase of the suffix-array pass that the Non-IID
+// t-Tuple and LRS estimates share.
+const tTupleLRSPhase = "t-Tuple and LRS Tests"
+
+// WithProgress returns a copy of the assessment that reports its progress
+// to fn; a nil fn disables reporting. fn runs on the goroutine of the
+// calculation, between estimators, and must return quickly. Reports stop
+// once an abandoned in-process calculation has returned to its caller.
+// Assessments in subprocess isolation report the stages and ProgressDone
+// only. AssessmentConfig.Progress sets the same function.
+func (a *Assessment) WithProgress(fn ProgressFunc) *Assessment {
+	c := a.Clone()
+	c.progress = fn
+	return c
+}
+
+// reportStage reports a stage of the assessment, if a progress function is
+// set.
+func (a *Assessment) reportStage(stage string, percent float64) {
+	if a.progress != nil {
+		a.progress(stage, percent)
+	}
+}
+
+// progressStage returns the stage reported when a calculation of testType
+// starts.
+func progressStage(testType TestType) string {
+	if testType == IID {
+		return ProgressIID
+	}
+	return ProgressNonIID
+}
+
+// progressPhases returns the phases that a calculation of testType runs for
+// mask, in execution order, as the wrapper reports them.
+func progressPhases(testType TestType, mask uint32) []string {
+	var phases []string
+	for _, e := range estimatorNamesFor(testType) {
+		if mask != 0 && mask&e.bit == 0 {
+			continue
+		}
+		if testType == NonIID && (e.bit == estimatorTTuple || e.bit == estimatorLRS) {
+			if len(phases) == 0 || phases[len(phases)-1] != tTupleLRSPhase {
+				phases = append(phases, tTupleLRSPhase)
+			}
+			continue
+		}
+		phases = append(phases, e.label)
+	}
+	return phases
+}
+
+// progressTracker reports the phases of one calculation to a ProgressFunc.
+// A nil tracker reports nothing.
+type progressTracker struct {
+	fn        ProgressFunc
+	total     int
+	completed int
+}
+
+// newProgressTracker returns a tracker for the phases of testType selected
+// by mask, or nil when fn is nil.
+func newProgressTracker(fn ProgressFunc, testType TestType, mask uint32) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{fn: fn, total: len(progressPhases(testType, mask))}
+}
+
+// start reports that phase starts, which completes the previous one.
+func (p *progressTracker) start(phase string) {
+	if p == nil {
+		return
+	}
+	percent := 0.0
+	if p.total > 0 {
+		percent = 100 * float64(p.completed) / float64(p.total)
+	}
+	p.fn(phase, percent)
+	p.completed++
+}
+
+// done reports the end of the calculation.
+func (p *progressTracker) done() {
+	if p == nil {
+		return
+	}
+	p.fn(ProgressDone, 100)
+}
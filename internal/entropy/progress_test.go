@@ -0,0 +1,74 @@
+package entropy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// progressEvent records one call of a ProgressFunc.
+type progressEvent struct {
+	phase   string
+	percent float64
+}
+
+// recordProgress returns a ProgressFunc that appends its calls to events.
+func recordProgress(events *[]progressEvent) ProgressFunc {
+	return func(phase string, percent float64) {
+		*events = append(*events, progressEvent{phase, percent})
+	}
+}
+
+func TestProgressPhases(t *testing.T) {
+	assert.Equal(t, []string{
+		"Most Common Value", "Chi-Square Tests",
+		"Length of Longest Repeated Substring Test", "Permutation Tests",
+	}, progressPhases(IID, 0))
+
+	assert.Equal(t, []string{
+		"Most Common Value", "Collision Test", "Markov Test", "Compression Test",
+		"t-Tuple and LRS Tests", "Multi Most Common in Window Test",
+		"Lag Prediction Test", "Multi Markov Model with Counting Test", "LZ78Y Test",
+	}, progressPhases(NonIID, 0))
+
+	// The t-Tuple and LRS estimates share one phase, whichever is selected.
+	assert.Equal(t, []string{"t-Tuple and LRS Tests"}, progressPhases(NonIID, estimatorLRS))
+	assert.Equal(t, []string{"Most Common Value", "t-Tuple and LRS Tests"},
+		progressPhases(NonIID, estimatorMCV|estimatorTTuple|estimatorLRS))
+	assert.Equal(t, []string{"Length of Longest Repeated Substring Test"}, progressPhases(IID, estimatorLRS))
+}
+
+func TestProgressTracker(t *testing.T) {
+	var events []progressEvent
+	tracker := newProgressTracker(recordProgress(&events), IID, 0)
+	for _, phase := range []string{"a", "b", "c", "d"} {
+		tracker.start(phase)
+	}
+	tracker.done()
+
+	assert.Equal(t, []progressEvent{
+		{"a", 0}, {"b", 25}, {"c", 50}, {"d", 75}, {ProgressDone, 100},
+	}, events)
+}
+
+func TestProgressTracker_Nil(t *testing.T) {
+	tracker := newProgressTracker(nil, NonIID, 0)
+	require.Nil(t, tracker)
+	assert.NotPanics(t, func() {
+		tracker.start("a")
+		tracker.done()
+	})
+}
+
+func TestAssessment_WithProgress(t *testing.T) {
+	fn := func(string, float64) {}
+	a := NewAssessment()
+	b := a.WithProgress(fn)
+
+	assert.Nil(t, a.progress)
+	assert.NotNil(t, b.progress)
+	assert.NotNil(t, a.WithConfig(b.Config()).progress, "Config carries the progress function")
+	assert.Nil(t, b.WithConfig(a.Config()).progress)
+	assert.Nil(t, b.WithProgress(nil).progress)
+}
@@ -0,0 +1,32 @@
+package entropy
+
+import "fmt"
+
+// MostCommonValueEstimate computes the Most Common Value estimate of SP 800-90B
+// Section 6.3.1 in pure Go, without the NIST library: the min-entropy per
+// sample implied by the upper 99% confidence bound on the probability of the
+// most frequent symbol. Symbols are masked to bitsPerSymbol bits as in a full
+// assessment; 0 detects the word size from the data.
+//
+// The estimate is an upper bound on the result of a full assessment, which
+// takes the minimum over all estimators and the bitstring. It is meant for
+// quick triage and is not a conforming SP 800-90B assessment.
+func MostCommonValueEstimate(data []byte, bitsPerSymbol int) (float64, error) {
+	const op = "MostCommonValueEstimate"
+	if bitsPerSymbol < 0 || bitsPerSymbol > 8 {
+		return 0, newError(op, ErrInvalidBitsPerSymbol, fmt.Sprintf("got %d", bitsPerSymbol))
+	}
+	if len(data) < 2 {
+		return 0, newError(op, ErrInsufficientData, fmt.Sprintf("need at least 2 samples, got %d", len(data)))
+	}
+
+	if bitsPerSymbol == 0 {
+		bitsPerSymbol = detectWordSize(data)
+	}
+	mask := byte(1<<uint(bitsPerSymbol) - 1)
+	symbols := make([]byte, len(data))
+	for i, b := range data {
+		symbols[i] = b & mask
+	}
+	return mostCommonValue(symbols, 0, ""), nil
+}
@@ -0,0 +1,92 @@
+package entropy
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The expected values below follow from H = -log2(min(1, p + z*sqrt(p(1-p)/(n-1))))
+// with z = 2.5758293035489008.
+func TestMostCommonValueEstimate_KnownDistributions(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		bits int
+		want float64
+	}{
+		{
+			// p = 0.5, n = 100: p_u = 0.5 + z*sqrt(0.25/99) = 0.629440...
+			name: "balanced bits",
+			data: bytes.Repeat([]byte{0, 1}, 50),
+			bits: 1,
+			want: 0.6678585610843556,
+		},
+		{
+			// p = 0.7, n = 1000: p_u = 0.7 + z*sqrt(0.21/999) = 0.737346...
+			name: "skewed 2-bit symbols",
+			data: bytes.Repeat([]byte{3, 3, 3, 3, 3, 3, 3, 0, 1, 2}, 100),
+			bits: 2,
+			want: 0.4395863497838597,
+		},
+		{
+			// p = 1/256, n = 10240: p_u = 0.00390625 + z*sqrt((255/65536)/10239)
+			name: "uniform bytes",
+			data: uniformBytes(10240),
+			bits: 8,
+			want: 7.507892513100597,
+		},
+		{
+			// A constant source has p = 1 and no entropy.
+			name: "constant",
+			data: bytes.Repeat([]byte{0x2A}, 1000),
+			bits: 8,
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MostCommonValueEstimate(tt.data, tt.bits)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-12)
+		})
+	}
+}
+
+func TestMostCommonValueEstimate_MasksAndDetectsWordSize(t *testing.T) {
+	data := bytes.Repeat([]byte{0, 1}, 50)
+	want, err := MostCommonValueEstimate(data, 1)
+	require.NoError(t, err)
+
+	// The high bits are masked off as in a full assessment.
+	got, err := MostCommonValueEstimate(bytes.Repeat([]byte{0xF0, 0xF1}, 50), 1)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = MostCommonValueEstimate(data, 0)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestMostCommonValueEstimate_Invalid(t *testing.T) {
+	for _, bits := range []int{-1, 9} {
+		_, err := MostCommonValueEstimate([]byte{0, 1}, bits)
+		assert.True(t, errors.Is(err, ErrInvalidBitsPerSymbol), "bits=%d", bits)
+	}
+	for _, data := range [][]byte{nil, {7}} {
+		_, err := MostCommonValueEstimate(data, 8)
+		assert.True(t, errors.Is(err, ErrInsufficientData), "len=%d", len(data))
+	}
+}
+
+// uniformBytes returns n samples cycling through all byte values.
+func uniformBytes(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
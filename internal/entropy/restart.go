@@ -0,0 +1,246 @@
+package entropy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// RestartLayout is the order in which a restart matrix stores its samples.
+type RestartLayout int
+
+const (
+	// RowMajor stores the restarts one after the other: row i holds the
+	// samples of restart i. This is the row dataset of SP 800-90B Section
+	// 3.1.4.1 and the input of the NIST ea_restart tool. It is the default.
+	RowMajor RestartLayout = iota
+	// ColumnMajor stores the column dataset: sample j of every restart,
+	// then sample j+1.
+	ColumnMajor
+)
+
+// String returns the configuration name of the RestartLayout.
+func (l RestartLayout) String() string {
+	switch l {
+	case RowMajor:
+		return "row"
+	case ColumnMajor:
+		return "column"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseRestartLayout converts a configuration string ("row" or "column")
+// into a RestartLayout. An empty string selects RowMajor.
+func ParseRestartLayout(layout string) (RestartLayout, error) {
+	switch strings.ToLower(strings.TrimSpace(layout)) {
+	case "", "row", "row-major":
+		return RowMajor, nil
+	case "column", "col", "column-major":
+		return ColumnMajor, nil
+	default:
+		return RowMajor, fmt.Errorf("invalid restart layout: %s (use row or column)", layout)
+	}
+}
+
+// RestartMatrix describes restart data: Rows restarts of the noise source
+// with Cols samples each, one sample per byte. SP 800-90B asks for 1000
+// restarts of 1000 samples.
+type RestartMatrix struct {
+	Rows   int
+	Cols   int
+	Layout RestartLayout
+}
+
+// Size returns the number of samples, and bytes, of the matrix.
+func (m RestartMatrix) Size() int64 {
+	return int64(m.Rows) * int64(m.Cols)
+}
+
+// RestartResult is the outcome of the restart tests of SP 800-90B Section
+// 3.1.4.
+type RestartResult struct {
+	TestType      TestType
+	Rows          int
+	Cols          int
+	BitsPerSymbol int     // Word size used, detected when 0 was requested
+	HI            float64 // Initial entropy estimate H_I the data is tested against
+
+	// Sanity check (Section 3.1.4.3): the largest count of one symbol in any
+	// row or column must not exceed the cutoff at significance Alpha.
+	Alpha          float64
+	MaxRowCount    int // X_r, largest symbol count within a row
+	MaxColumnCount int // X_c, largest symbol count within a column
+	RowCutoff      int // Largest passing X_r
+	ColumnCutoff   int // Largest passing X_c
+	SanityPassed   bool
+
+	// Validation test (Section 3.1.4.2): the entropy of the row and column
+	// datasets must be at least H_I/2. The estimates are only computed when
+	// the sanity check passed.
+	HRow             float64 // H_r, literal entropy estimate of the row dataset
+	HColumn          float64 // H_c, literal entropy estimate of the column dataset
+	ValidationPassed bool
+
+	// MinEntropy is min(H_r, H_c, H_I), the validated entropy estimate, when
+	// both tests passed; 0 otherwise.
+	MinEntropy float64
+	Passed     bool
+}
+
+// AssessRestart runs the restart tests of SP 800-90B Section 3.1.4 on data
+// laid out as m, against the initial entropy estimate hI in bits per sample.
+// The sanity check compares the most common symbol of every row and column
+// with a cutoff; when it passes, the row and column datasets are assessed
+// with testType and their literal estimates H_r and H_c must reach hI/2.
+// A bitsPerSymbol of 0 detects the word size from the data.
+//
+// The NIST ea_restart tool finds the sanity cutoff by simulating the
+// worst-case distribution with most common probability 2^-hI. AssessRestart
+// instead bounds the same tail probability with the binomial distribution of
+// each of its ceil(2^hI) most likely symbols, which gives a cutoff at least
+// as large as the simulated one without the random rounds.
+//
+// Data whose length is not Rows*Cols, dimensions below 2 and an hI outside
+// (0, bitsPerSymbol] return an ErrInvalidRestart error. The settings of a
+// apply to both assessments.
+func (a *Assessment) AssessRestart(data []byte, m RestartMatrix, bitsPerSymbol int, hI float64, testType TestType) (*RestartResult, error) {
+	return a.AssessRestartContext(context.Background(), data, m, bitsPerSymbol, hI, testType)
+}
+
+// AssessRestartContext is like AssessRestart but honours cancellation of ctx
+// while the row and column datasets are assessed.
+func (a *Assessment) AssessRestartContext(ctx context.Context, data []byte, m RestartMatrix, bitsPerSymbol int, hI float64, testType TestType) (*RestartResult, error) {
+	const op = "AssessRestart"
+	if testType != IID && testType != NonIID {
+		return nil, newError(op, ErrInvalidData, "invalid test type")
+	}
+	if bitsPerSymbol < 0 || bitsPerSymbol > 8 {
+		return nil, newError(op, ErrInvalidBitsPerSymbol, fmt.Sprintf("got %d", bitsPerSymbol))
+	}
+	if m.Rows < 2 || m.Cols < 2 {
+		return nil, newError(op, ErrInvalidRestart, fmt.Sprintf("need at least 2 rows and 2 columns, got %dx%d", m.Rows, m.Cols))
+	}
+	if m.Layout != RowMajor && m.Layout != ColumnMajor {
+		return nil, newError(op, ErrInvalidRestart, fmt.Sprintf("unknown layout %d", int(m.Layout)))
+	}
+	if int64(len(data)) != m.Size() {
+		return nil, newError(op, ErrInvalidRestart,
+			fmt.Sprintf("a %dx%d matrix needs %d bytes, got %d", m.Rows, m.Cols, m.Size(), len(data)))
+	}
+
+	wordSize := bitsPerSymbol
+	if wordSize == 0 {
+		wordSize = detectWordSize(data)
+	}
+	if math.IsNaN(hI) || hI <= 0 || hI > float64(wordSize) {
+		return nil, newError(op, ErrInvalidRestart, fmt.Sprintf("H_I must be in (0, %d], got %g", wordSize, hI))
+	}
+
+	rows, cols := restartDatasets(data, m)
+	res := &RestartResult{
+		TestType:      testType,
+		Rows:          m.Rows,
+		Cols:          m.Cols,
+		BitsPerSymbol: wordSize,
+		HI:            hI,
+		Alpha:         1 - math.Pow(0.99, 1/float64(m.Rows+m.Cols)),
+	}
+
+	symbolMask := byte(1<<uint(wordSize) - 1)
+	res.MaxRowCount = maxSymbolCount(rows, m.Cols, symbolMask)
+	res.MaxColumnCount = maxSymbolCount(cols, m.Rows, symbolMask)
+	res.RowCutoff = restartCutoff(m.Cols, wordSize, hI, res.Alpha)
+	res.ColumnCutoff = restartCutoff(m.Rows, wordSize, hI, res.Alpha)
+	res.SanityPassed = res.MaxRowCount <= res.RowCutoff && res.MaxColumnCount <= res.ColumnCutoff
+	if !res.SanityPassed {
+		return res, nil
+	}
+
+	assess := a.AssessNonIIDContext
+	if testType == IID {
+		assess = a.AssessIIDContext
+	}
+	rowResult, err := assess(ctx, rows, bitsPerSymbol)
+	if err != nil {
+		return nil, err
+	}
+	colResult, err := assess(ctx, cols, bitsPerSymbol)
+	if err != nil {
+		return nil, err
+	}
+	res.HRow = rowResult.HOriginal
+	res.HColumn = colResult.HOriginal
+	res.ValidationPassed = min(res.HRow, res.HColumn) >= hI/2
+	res.Passed = res.ValidationPassed
+	if res.Passed {
+		res.MinEntropy = min(res.HRow, res.HColumn, hI)
+	}
+	return res, nil
+}
+
+// restartDatasets returns the row and column datasets of data laid out as
+// m. The dataset that matches the layout shares the memory of data.
+func restartDatasets(data []byte, m RestartMatrix) (rows, cols []byte) {
+	transposed := make([]byte, len(data))
+	if m.Layout == ColumnMajor {
+		for j := 0; j < m.Cols; j++ {
+			for i := 0; i < m.Rows; i++ {
+				transposed[i*m.Cols+j] = data[j*m.Rows+i]
+			}
+		}
+		return transposed, data
+	}
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			transposed[j*m.Rows+i] = data[i*m.Cols+j]
+		}
+	}
+	return data, transposed
+}
+
+// maxSymbolCount returns the largest number of occurrences of one symbol,
+// masked with symbolMask, within any consecutive run of width samples.
+func maxSymbolCount(dataset []byte, width int, symbolMask byte) int {
+	largest := 0
+	for start := 0; start < len(dataset); start += width {
+		var counts [HistogramSize]int
+		for _, s := range dataset[start : start+width] {
+			s &= symbolMask
+			counts[s]++
+			if counts[s] > largest {
+				largest = counts[s]
+			}
+		}
+	}
+	return largest
+}
+
+// restartCutoff returns the largest count of the most common symbol among n
+// samples that passes the sanity check at significance alpha, for a source
+// whose most likely symbol has probability p = 2^-hI. The worst-case
+// distribution has ceil(1/p) symbols of probability at most p, so by the
+// union bound the cutoff is the smallest u with
+// ceil(1/p) * P(Binomial(n, p) > u) <= alpha.
+func restartCutoff(n, wordSize int, hI, alpha float64) int {
+	p := math.Exp2(-hI)
+	k := min(math.Ceil(1/p), math.Exp2(float64(wordSize)))
+	target := alpha / k
+
+	lnP, lnQ := math.Log(p), math.Log1p(-p)
+	lnNFact, _ := math.Lgamma(float64(n) + 1)
+	tail := 0.0
+	for u := n - 1; u >= 0; u-- {
+		// Add P(X = u+1) to P(X > u).
+		x := float64(u + 1)
+		lnX, _ := math.Lgamma(x + 1)
+		lnRest, _ := math.Lgamma(float64(n) - x + 1)
+		tail += math.Exp(lnNFact - lnX - lnRest + x*lnP + (float64(n)-x)*lnQ)
+		if tail > target {
+			return u + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+//go:build teststub
+
+package entropy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssessRestart_Passes(t *testing.T) {
+	// No value repeats within a row or a column.
+	data := make([]byte, 20*20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	for _, tc := range []struct {
+		testType TestType
+		h        float64
+	}{
+		{NonIID, 6.6},
+		{IID, 7.6},
+	} {
+		res, err := NewAssessment().AssessRestart(data, RestartMatrix{Rows: 20, Cols: 20}, 0, 7, tc.testType)
+		require.NoError(t, err)
+		assert.Equal(t, 8, res.BitsPerSymbol)
+		assert.Equal(t, 1, res.MaxRowCount)
+		assert.Equal(t, 1, res.MaxColumnCount)
+		assert.True(t, res.SanityPassed)
+		assert.Equal(t, tc.h, res.HRow)
+		assert.Equal(t, tc.h, res.HColumn)
+		assert.True(t, res.ValidationPassed)
+		assert.True(t, res.Passed)
+		assert.Equal(t, min(tc.h, 7), res.MinEntropy)
+	}
+}
@@ -0,0 +1,149 @@
+package entropy
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRestartLayout(t *testing.T) {
+	for input, want := range map[string]RestartLayout{
+		"": RowMajor, "row": RowMajor, "Row-Major": RowMajor,
+		"column": ColumnMajor, "col": ColumnMajor, " column-major ": ColumnMajor,
+	} {
+		got, err := ParseRestartLayout(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+	_, err := ParseRestartLayout("diagonal")
+	assert.ErrorContains(t, err, "invalid restart layout: diagonal (use row or column)")
+
+	assert.Equal(t, "row", RowMajor.String())
+	assert.Equal(t, "column", ColumnMajor.String())
+	assert.Equal(t, "unknown", RestartLayout(7).String())
+}
+
+func TestRestartDatasets(t *testing.T) {
+	// Two restarts of three samples.
+	rowMajor := []byte{1, 2, 3, 4, 5, 6}
+	columnMajor := []byte{1, 4, 2, 5, 3, 6}
+
+	rows, cols := restartDatasets(rowMajor, RestartMatrix{Rows: 2, Cols: 3})
+	assert.Equal(t, rowMajor, rows)
+	assert.Equal(t, columnMajor, cols)
+
+	rows, cols = restartDatasets(columnMajor, RestartMatrix{Rows: 2, Cols: 3, Layout: ColumnMajor})
+	assert.Equal(t, rowMajor, rows)
+	assert.Equal(t, columnMajor, cols)
+}
+
+func TestMaxSymbolCount(t *testing.T) {
+	dataset := []byte{1, 1, 2, 3, 3, 3, 0x11, 0x21, 4}
+	assert.Equal(t, 3, maxSymbolCount(dataset, 3, 0xFF))
+	// Masked to 4 bits, the row holds 1, 1, 1 and 4.
+	assert.Equal(t, 3, maxSymbolCount([]byte{0x11, 0x21, 0x01, 4}, 4, 0x0F))
+	assert.Equal(t, 1, maxSymbolCount([]byte{0x11, 0x21, 0x01, 4}, 4, 0xFF))
+}
+
+// restartCutoffBruteForce evaluates the binomial tail term by term with
+// exact binomial coefficients, for small n.
+func restartCutoffBruteForce(n, wordSize int, hI, alpha float64) int {
+	p := math.Exp2(-hI)
+	k := math.Min(math.Ceil(1/p), math.Exp2(float64(wordSize)))
+	for u := 0; u <= n; u++ {
+		tail := 0.0
+		for x := u + 1; x <= n; x++ {
+			coef := 1.0
+			for i := 0; i < x; i++ {
+				coef = coef * float64(n-i) / float64(i+1)
+			}
+			tail += coef * math.Pow(p, float64(x)) * math.Pow(1-p, float64(n-x))
+		}
+		if k*tail <= alpha {
+			return u
+		}
+	}
+	return n
+}
+
+func TestRestartCutoff(t *testing.T) {
+	alpha := 1 - math.Pow(0.99, 1.0/2000)
+	for _, tc := range []struct {
+		n        int
+		wordSize int
+		hI       float64
+	}{
+		{40, 8, 7}, {40, 1, 0.9}, {60, 4, 3.3}, {30, 8, 1}, {50, 2, 2},
+	} {
+		assert.Equal(t, restartCutoffBruteForce(tc.n, tc.wordSize, tc.hI, alpha),
+			restartCutoff(tc.n, tc.wordSize, tc.hI, alpha), "%+v", tc)
+	}
+
+	// A lower claim tolerates a more frequent most common symbol.
+	assert.Greater(t, restartCutoff(1000, 8, 2, alpha), restartCutoff(1000, 8, 6, alpha))
+	// Full entropy for one bit per sample: well above the mean of 500.
+	cutoff := restartCutoff(1000, 1, 1, alpha)
+	assert.Greater(t, cutoff, 550)
+	assert.Less(t, cutoff, 600)
+}
+
+func TestAssessRestart_SanityFailure(t *testing.T) {
+	// Every restart repeats a single value.
+	data := make([]byte, 20*20)
+	for i := range data {
+		data[i] = byte(i / 20)
+	}
+	res, err := NewAssessment().AssessRestart(data, RestartMatrix{Rows: 20, Cols: 20}, 8, 7, NonIID)
+	require.NoError(t, err)
+	assert.Equal(t, 20, res.MaxRowCount)
+	assert.Equal(t, 1, res.MaxColumnCount)
+	assert.Less(t, res.RowCutoff, 20)
+	assert.False(t, res.SanityPassed)
+	assert.False(t, res.Passed)
+	assert.Zero(t, res.HRow)
+	assert.Zero(t, res.MinEntropy)
+	assert.InDelta(t, 1-math.Pow(0.99, 1.0/40), res.Alpha, 1e-15)
+
+	// The same matrix read column by column fails on the columns.
+	res, err = NewAssessment().AssessRestart(data, RestartMatrix{Rows: 20, Cols: 20, Layout: ColumnMajor}, 8, 7, NonIID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.MaxRowCount)
+	assert.Equal(t, 20, res.MaxColumnCount)
+	assert.False(t, res.SanityPassed)
+}
+
+func TestAssessRestart_RejectsInvalidInput(t *testing.T) {
+	a := NewAssessment()
+	data := make([]byte, 12)
+	data[0] = 0x0F
+	m := RestartMatrix{Rows: 3, Cols: 4}
+
+	for _, tc := range []struct {
+		name     string
+		data     []byte
+		m        RestartMatrix
+		bits     int
+		hI       float64
+		testType TestType
+		want     error
+		msg      string
+	}{
+		{"size", data[:10], m, 4, 2, NonIID, ErrInvalidRestart, "a 3x4 matrix needs 12 bytes, got 10"},
+		{"dimensions", data, RestartMatrix{Rows: 1, Cols: 12}, 4, 2, NonIID, ErrInvalidRestart, "need at least 2 rows and 2 columns, got 1x12"},
+		{"layout", data, RestartMatrix{Rows: 3, Cols: 4, Layout: 5}, 4, 2, NonIID, ErrInvalidRestart, "unknown layout 5"},
+		{"h_i above word size", data, m, 0, 4.5, NonIID, ErrInvalidRestart, "H_I must be in (0, 4], got 4.5"},
+		{"h_i zero", data, m, 4, 0, NonIID, ErrInvalidRestart, "H_I must be in (0, 4], got 0"},
+		{"bits", data, m, 9, 2, NonIID, ErrInvalidBitsPerSymbol, "got 9"},
+		{"test type", data, m, 4, 2, TestType(9), ErrInvalidData, "invalid test type"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := a.AssessRestart(tc.data, tc.m, tc.bits, tc.hI, tc.testType)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, tc.want), err.Error())
+			assert.Contains(t, err.Error(), tc.msg)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package entropy
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Process-wide state of the computation runtime shared by every Assessment.
+var (
+	// maxThreads is the cap set by SetMaxThreads; zero means none.
+	maxThreads atomic.Int64
+	// runtimeStarted is set by the first in-process computation.
+	runtimeStarted atomic.Bool
+	// runtimeLock is held for reading by every in-process computation and
+	// for writing by Shutdown, which waits for them to complete.
+	runtimeLock sync.RWMutex
+)
+
+// SetMaxThreads caps the threads any assessment of this process may use, the
+// equivalent of OMP_NUM_THREADS for the NIST library: the permutation tests
+// of every Assessment run on at most n threads, whatever SetThreads asks for,
+// and so do the workers of the pure-Go implementation. Zero removes the cap.
+//
+// SetMaxThreads must be called before the first assessment, because the
+// OpenMP runtime sizes its thread pool when it first runs; afterwards it
+// fails with ErrInvalidThreads. A call racing with the first assessment waits
+// for its computation to return and then fails as well. A negative n fails the
+// same way.
+func SetMaxThreads(n int) error {
+	const op = "SetMaxThreads"
+	if n < 0 {
+		return newError(op, ErrInvalidThreads, fmt.Sprintf("n must not be negative, got %d", n))
+	}
+	runtimeLock.Lock()
+	defer runtimeLock.Unlock()
+	if runtimeStarted.Load() {
+		return newError(op, ErrInvalidThreads, "must be called before the first assessment")
+	}
+	maxThreads.Store(int64(n))
+	return nil
+}
+
+// MaxThreads returns the cap set by SetMaxThreads; zero means none.
+func MaxThreads() int {
+	return int(maxThreads.Load())
+}
+
+// capThreads applies the cap of SetMaxThreads to a thread count, where zero
+// or less means one thread per CPU.
+func capThreads(threads int) int {
+	limit := int(maxThreads.Load())
+	if limit > 0 && (threads <= 0 || threads > limit) {
+		return limit
+	}
+	return threads
+}
+
+// enterRuntime marks the start of an in-process computation. The caller must
+// call the returned function once the computation has returned.
+func enterRuntime() func() {
+	runtimeLock.RLock()
+	runtimeStarted.Store(true)
+	return runtimeLock.RUnlock
+}
+
+// Shutdown releases the process-wide resources of the computation runtime,
+// such as the idle thread pool of OpenMP, for embedders that stop assessing
+// long before the process exits. It first waits for the in-process
+// computations still running to complete, including those abandoned after a
+// timeout or cancellation (see AbandonedAssessments).
+//
+// Shutdown is idempotent: calling it again releases nothing and returns nil.
+// A later assessment acquires the resources again and may be followed by
+// another Shutdown. Builds without the NIST library hold no such resources.
+func Shutdown() error {
+	runtimeLock.Lock()
+	defer runtimeLock.Unlock()
+	if err := releaseRuntime(); err != nil {
+		return newError("Shutdown", ErrCFunction, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package entropy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetRuntime clears the process-wide thread cap and the record of the first
+// assessment for the duration of a test.
+func resetRuntime(t *testing.T) {
+	t.Helper()
+	limit, started := maxThreads.Load(), runtimeStarted.Load()
+	maxThreads.Store(0)
+	runtimeStarted.Store(false)
+	t.Cleanup(func() {
+		maxThreads.Store(limit)
+		runtimeStarted.Store(started)
+	})
+}
+
+func TestSetMaxThreads(t *testing.T) {
+	resetRuntime(t)
+
+	err := SetMaxThreads(-1)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidThreads))
+	assert.Equal(t, 0, MaxThreads())
+
+	require.NoError(t, SetMaxThreads(2))
+	assert.Equal(t, 2, MaxThreads())
+	assert.Equal(t, 2, capThreads(0))
+	assert.Equal(t, 2, capThreads(8))
+	assert.Equal(t, 1, capThreads(1))
+
+	a := NewAssessment()
+	a.SetThreads(4)
+	assert.Equal(t, 2, a.permutation().threads)
+	assert.Equal(t, 4, a.GetThreads())
+
+	require.NoError(t, SetMaxThreads(0))
+	assert.Equal(t, 4, a.permutation().threads)
+}
+
+func TestSetMaxThreads_AfterFirstAssessment(t *testing.T) {
+	resetRuntime(t)
+
+	a := NewAssessment()
+	a.SetVerbose(0)
+	_, err := a.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+
+	err = SetMaxThreads(2)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidThreads))
+	assert.Contains(t, err.Error(), "before the first assessment")
+	assert.Equal(t, 0, MaxThreads())
+}
+
+func TestSetMaxThreads_DuringFirstComputation(t *testing.T) {
+	resetRuntime(t)
+
+	exit := enterRuntime()
+	errc := make(chan error, 1)
+	go func() { errc <- SetMaxThreads(2) }()
+
+	select {
+	case err := <-errc:
+		t.Fatalf("SetMaxThreads returned %v while a computation was running", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	exit()
+
+	err := <-errc
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidThreads))
+	assert.Equal(t, 0, MaxThreads())
+}
+
+func TestShutdown_Idempotent(t *testing.T) {
+	require.NoError(t, Shutdown())
+	require.NoError(t, Shutdown())
+
+	// Assessments still run after a shutdown, which may then be repeated.
+	a := NewAssessment()
+	a.SetVerbose(0)
+	_, err := a.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	require.NoError(t, Shutdown())
+}
@@ -0,0 +1,103 @@
+package entropy
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Section identifies the byte range of an input that was assessed.
+type Section struct {
+	Offset int64 // Index of the first assessed byte
+	Length int64 // Number of assessed bytes
+}
+
+// AssessSection assesses the window of r that starts at offset and spans
+// length bytes; a length of 0 extends the window to the end of r. A window
+// that is negative, starts at or beyond the end of r, or extends past it
+// returns an ErrInvalidSection error. Result.Section records the window.
+func (a *Assessment) AssessSection(r io.ReaderAt, offset, length int64, bitsPerSymbol int, testType TestType) (*Result, error) {
+	if err := checkSection("AssessSection", offset, length); err != nil {
+		return nil, err
+	}
+
+	size := length
+	if size == 0 {
+		size = math.MaxInt64 - offset
+	}
+	data, err := io.ReadAll(io.NewSectionReader(r, offset, size))
+	if err != nil {
+		return nil, newError("AssessSection", err, "failed to read section")
+	}
+	if len(data) == 0 {
+		return nil, newError("AssessSection", ErrInvalidSection, fmt.Sprintf("offset %d is at or beyond the end of the data", offset))
+	}
+	if length > 0 && int64(len(data)) < length {
+		return nil, newError("AssessSection", ErrInvalidSection,
+			fmt.Sprintf("offset %d + length %d exceeds the data size of %d bytes", offset, length, offset+int64(len(data))))
+	}
+
+	var result *Result
+	switch testType {
+	case IID:
+		result, err = a.AssessIID(data, bitsPerSymbol)
+	case NonIID:
+		result, err = a.AssessNonIID(data, bitsPerSymbol)
+	default:
+		return nil, newError("AssessSection", ErrInvalidData, "invalid test type")
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Section = &Section{Offset: offset, Length: int64(len(data))}
+	return result, nil
+}
+
+// SliceSection returns the window of data that starts at offset and spans
+// length bytes, or the rest of data when length is 0. It applies the same
+// bounds checks as AssessSection.
+func SliceSection(data []byte, offset, length int64) ([]byte, error) {
+	if err := checkSection("SliceSection", offset, length); err != nil {
+		return nil, err
+	}
+	size := int64(len(data))
+	if offset >= size {
+		return nil, newError("SliceSection", ErrInvalidSection,
+			fmt.Sprintf("offset %d is at or beyond the end of the data (%d bytes)", offset, size))
+	}
+	if length == 0 {
+		return data[offset:], nil
+	}
+	if length > size-offset {
+		return nil, newError("SliceSection", ErrInvalidSection,
+			fmt.Sprintf("offset %d + length %d exceeds the data size of %d bytes", offset, length, size))
+	}
+	return data[offset : offset+length], nil
+}
+
+// StrideSamples returns every k-th sample of data, those at index 0, k, 2k
+// and so on, in a new slice. A k of 1 returns data itself; a k below 1
+// returns an ErrInvalidStride error.
+func StrideSamples(data []byte, k int) ([]byte, error) {
+	if k < 1 {
+		return nil, newError("StrideSamples", ErrInvalidStride, fmt.Sprintf("got %d", k))
+	}
+	if k == 1 {
+		return data, nil
+	}
+	out := make([]byte, 0, (len(data)+k-1)/k)
+	for i := 0; i < len(data); i += k {
+		out = append(out, data[i])
+	}
+	return out, nil
+}
+
+func checkSection(op string, offset, length int64) error {
+	if offset < 0 {
+		return newError(op, ErrInvalidSection, fmt.Sprintf("offset must not be negative, got %d", offset))
+	}
+	if length < 0 {
+		return newError(op, ErrInvalidSection, fmt.Sprintf("length must not be negative, got %d", length))
+	}
+	return nil
+}
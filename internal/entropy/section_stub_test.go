@@ -0,0 +1,41 @@
+//go:build teststub
+
+package entropy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssessSection_RecordsWindow(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetHistogram(true)
+
+	// The header byte 0xFF would make the stub fail if it were assessed.
+	r := bytes.NewReader([]byte{0xFF, 1, 1, 2, 9, 9})
+
+	res, err := assessment.AssessSection(r, 1, 3, 8, NonIID)
+	require.NoError(t, err)
+	assert.Equal(t, &Section{Offset: 1, Length: 3}, res.Section)
+	assert.Equal(t, uint64(2), res.Histogram[1])
+	assert.Equal(t, uint64(1), res.Histogram[2])
+	assert.Zero(t, res.Histogram[9])
+
+	res, err = assessment.AssessSection(r, 1, 0, 8, IID)
+	require.NoError(t, err)
+	assert.Equal(t, &Section{Offset: 1, Length: 5}, res.Section)
+	assert.Equal(t, IID, res.TestType)
+}
+
+func TestAssess_NoSectionForWholeInput(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+
+	res, err := assessment.AssessNonIID([]byte{1, 2, 3}, 8)
+	require.NoError(t, err)
+	assert.Nil(t, res.Section)
+}
@@ -0,0 +1,85 @@
+package entropy
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSliceSection(t *testing.T) {
+	data := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+
+	got, err := SliceSection(data, 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{2, 3, 4}, got)
+
+	got, err = SliceSection(data, 5, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{5, 6, 7}, got)
+
+	got, err = SliceSection(data, 0, 8)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	for _, tc := range []struct {
+		name           string
+		offset, length int64
+	}{
+		{"negative offset", -1, 0},
+		{"negative length", 0, -1},
+		{"offset at end", 8, 0},
+		{"offset beyond end", 9, 1},
+		{"length past end", 6, 3},
+	} {
+		_, err := SliceSection(data, tc.offset, tc.length)
+		assert.True(t, errors.Is(err, ErrInvalidSection), tc.name)
+	}
+}
+
+func TestStrideSamples(t *testing.T) {
+	data := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8}
+
+	got, err := StrideSamples(data, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 2, 4, 6, 8}, got)
+
+	got, err = StrideSamples(data, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 4, 8}, got)
+
+	got, err = StrideSamples(data, 20)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0}, got)
+
+	got, err = StrideSamples(data, 1)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	for _, k := range []int{0, -2} {
+		_, err := StrideSamples(data, k)
+		assert.True(t, errors.Is(err, ErrInvalidStride), k)
+	}
+}
+
+func TestAssessSection_OutOfRange(t *testing.T) {
+	assessment := NewAssessment()
+	r := bytes.NewReader([]byte{1, 2, 3, 4})
+
+	for _, tc := range []struct {
+		name           string
+		offset, length int64
+	}{
+		{"negative offset", -1, 0},
+		{"negative length", 0, -2},
+		{"offset at end", 4, 0},
+		{"offset beyond end", 10, 2},
+		{"length past end", 1, 4},
+	} {
+		res, err := assessment.AssessSection(r, tc.offset, tc.length, 8, NonIID)
+		assert.Nil(t, res, tc.name)
+		assert.True(t, errors.Is(err, ErrInvalidSection), tc.name)
+	}
+}
@@ -0,0 +1,147 @@
+package entropy
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+)
+
+// selfTestTolerance is the largest accepted absolute difference, in bits per
+// sample, between a computed value and its known answer.
+const selfTestTolerance = 1e-9
+
+//go:embed testdata/selftest
+var selfTestFS embed.FS
+
+// selfTestDir is the embedded directory holding vectors.json and the sample
+// files it references.
+const selfTestDir = "testdata/selftest"
+
+// selfTestVector is one known-answer case: a sample file, how to assess it,
+// and the values the reference implementation reports for it.
+type selfTestVector struct {
+	Name          string   `json:"name"`
+	File          string   `json:"file"`
+	TestType      string   `json:"test_type"`
+	BitsPerSymbol int      `json:"bits_per_symbol"`
+	Estimators    []string `json:"estimators"`
+	Expected      struct {
+		MinEntropy float64            `json:"min_entropy"`
+		HOriginal  float64            `json:"h_original"`
+		HBitstring float64            `json:"h_bitstring"`
+		Estimators map[string]float64 `json:"estimators"`
+	} `json:"expected"`
+
+	testType TestType
+	data     []byte
+}
+
+// SelfTest assesses the embedded known-answer vectors and compares every
+// reported value against its reference within a fixed tolerance. A failure
+// indicates a broken build or modified NIST sources; the returned
+// ErrSelfTestFailed error lists each vector and value that diverged.
+func SelfTest() error {
+	vectors, err := loadSelfTestVectors()
+	if err != nil {
+		return newError("SelfTest", ErrSelfTestFailed, err.Error())
+	}
+	return runSelfTest(vectors)
+}
+
+// loadSelfTestVectors parses the embedded manifest and reads each sample file.
+func loadSelfTestVectors() ([]selfTestVector, error) {
+	manifest, err := selfTestFS.ReadFile(path.Join(selfTestDir, "vectors.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vectors: %w", err)
+	}
+
+	var vectors []selfTestVector
+	if err := json.Unmarshal(manifest, &vectors); err != nil {
+		return nil, fmt.Errorf("failed to parse vectors: %w", err)
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no vectors defined")
+	}
+
+	for i := range vectors {
+		v := &vectors[i]
+		switch v.TestType {
+		case IID.String():
+			v.testType = IID
+		case NonIID.String():
+			v.testType = NonIID
+		default:
+			return nil, fmt.Errorf("vector %s: unknown test type %q", v.Name, v.TestType)
+		}
+		v.data, err = selfTestFS.ReadFile(path.Join(selfTestDir, v.File))
+		if err != nil {
+			return nil, fmt.Errorf("vector %s: %w", v.Name, err)
+		}
+	}
+	return vectors, nil
+}
+
+// runSelfTest assesses each vector and collects every divergence.
+func runSelfTest(vectors []selfTestVector) error {
+	var failures []string
+	for _, v := range vectors {
+		label := fmt.Sprintf("%s (%s)", v.Name, v.TestType)
+
+		a := NewAssessment()
+		a.SetVerbose(0)
+		a.SetEstimators(v.Estimators)
+
+		var result *Result
+		var err error
+		if v.testType == IID {
+			result, err = a.AssessIID(v.data, v.BitsPerSymbol)
+		} else {
+			result, err = a.AssessNonIID(v.data, v.BitsPerSymbol)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", label, err))
+			continue
+		}
+
+		check := func(name string, got, want float64) {
+			if math.IsNaN(got) || math.Abs(got-want) > selfTestTolerance {
+				failures = append(failures, fmt.Sprintf("%s: %s = %.12g, expected %.12g", label, name, got, want))
+			}
+		}
+		check("min_entropy", result.MinEntropy, v.Expected.MinEntropy)
+		check("h_original", result.HOriginal, v.Expected.HOriginal)
+		check("h_bitstring", result.HBitstring, v.Expected.HBitstring)
+
+		names := make([]string, 0, len(v.Expected.Estimators))
+		for name := range v.Expected.Estimators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			est, ok := findEstimator(result.Estimators, name)
+			if !ok || !est.IsEntropyValid {
+				failures = append(failures, fmt.Sprintf("%s: %s estimate missing", label, name))
+				continue
+			}
+			check(name, est.EntropyEstimate, v.Expected.Estimators[name])
+		}
+	}
+
+	if len(failures) > 0 {
+		return newError("SelfTest", ErrSelfTestFailed, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func findEstimator(results []EstimatorResult, name string) (EstimatorResult, bool) {
+	for _, r := range results {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return EstimatorResult{}, false
+}
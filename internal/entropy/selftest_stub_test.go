@@ -0,0 +1,56 @@
+//go:build teststub
+
+package entropy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The stub returns fixed values, so the embedded vectors must not pass.
+func TestSelfTest_FailsAgainstStub(t *testing.T) {
+	err := SelfTest()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrSelfTestFailed))
+	assert.Contains(t, err.Error(), "skewed-8bit (IID): min_entropy")
+	assert.Contains(t, err.Error(), "Most Common Value")
+}
+
+func stubVector(name string, data []byte) selfTestVector {
+	v := selfTestVector{
+		Name:          name,
+		TestType:      "Non-IID",
+		BitsPerSymbol: 8,
+		Estimators:    []string{"mcv"},
+		testType:      NonIID,
+		data:          data,
+	}
+	v.Expected.MinEntropy = 6.8
+	v.Expected.HOriginal = 6.8
+	v.Expected.HBitstring = 6.1
+	v.Expected.Estimators = map[string]float64{"Most Common Value": 6.8}
+	return v
+}
+
+func TestRunSelfTest_PassesWithinTolerance(t *testing.T) {
+	assert.NoError(t, runSelfTest([]selfTestVector{stubVector("stub", []byte{1, 2, 3, 4})}))
+}
+
+func TestRunSelfTest_ReportsEachDivergence(t *testing.T) {
+	diverged := stubVector("diverged", []byte{1, 2, 3, 4})
+	diverged.Expected.HBitstring = 6.2
+	diverged.Expected.Estimators["Collision Test"] = 6.9
+
+	failing := stubVector("failing", []byte{0xFF, 2, 3, 4})
+
+	err := runSelfTest([]selfTestVector{diverged, failing})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrSelfTestFailed))
+	assert.Contains(t, err.Error(), "diverged (Non-IID): h_bitstring = 6.1, expected 6.2")
+	assert.Contains(t, err.Error(), "diverged (Non-IID): Collision Test estimate missing")
+	assert.Contains(t, err.Error(), "failing (Non-IID): calculateNonIIDEntropy")
+	assert.NotContains(t, err.Error(), "min_entropy")
+}
@@ -0,0 +1,68 @@
+package entropy
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mcvEstimate is an independent implementation of SP 800-90B Section 6.3.1
+// used to confirm that the embedded expectations match their sample files.
+func mcvEstimate(symbols []byte) float64 {
+	counts := make(map[byte]int)
+	mode := 0
+	for _, s := range symbols {
+		counts[s]++
+		if counts[s] > mode {
+			mode = counts[s]
+		}
+	}
+	n := float64(len(symbols))
+	pmax := float64(mode) / n
+	ubound := math.Min(1, pmax+2.5758293035489008*math.Sqrt(pmax*(1-pmax)/(n-1)))
+	return -math.Log2(ubound)
+}
+
+func TestLoadSelfTestVectors(t *testing.T) {
+	vectors, err := loadSelfTestVectors()
+	require.NoError(t, err)
+	require.NotEmpty(t, vectors)
+
+	for _, v := range vectors {
+		assert.NotEmpty(t, v.data, v.Name)
+		assert.NoError(t, ValidateEstimators(v.testType, v.Estimators), v.Name)
+		assert.Equal(t, v.TestType, v.testType.String())
+	}
+}
+
+func TestSelfTestVectors_MatchMostCommonValue(t *testing.T) {
+	vectors, err := loadSelfTestVectors()
+	require.NoError(t, err)
+
+	for _, v := range vectors {
+		mask := byte(1<<uint(v.BitsPerSymbol) - 1)
+		symbols := make([]byte, len(v.data))
+		distinct := make(map[byte]bool)
+		var bits []byte
+		for i, b := range v.data {
+			symbols[i] = b & mask
+			distinct[symbols[i]] = true
+			for j := v.BitsPerSymbol - 1; j >= 0; j-- {
+				bits = append(bits, (symbols[i]>>uint(j))&1)
+			}
+		}
+
+		literal := mcvEstimate(symbols)
+		hBitstring := 1.0
+		if len(distinct) > 2 {
+			hBitstring = math.Min(1, mcvEstimate(bits))
+		}
+
+		assert.InDelta(t, literal, v.Expected.Estimators["Most Common Value"], selfTestTolerance, v.Name)
+		assert.InDelta(t, literal, v.Expected.HOriginal, selfTestTolerance, v.Name)
+		assert.InDelta(t, hBitstring, v.Expected.HBitstring, selfTestTolerance, v.Name)
+		assert.InDelta(t, math.Min(literal, hBitstring*float64(v.BitsPerSymbol)), v.Expected.MinEntropy, selfTestTolerance, v.Name)
+	}
+}
@@ -0,0 +1,71 @@
+//go:build teststub
+
+package entropy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hangingData makes the stub block for stubHangDuration.
+var hangingData = []byte{0xCC, 1, 2, 3}
+
+func TestTimeout_InProcessReturnsAndCountsAbandonedWork(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetTimeout(50 * time.Millisecond)
+
+	before := AbandonedAssessments()
+
+	start := time.Now()
+	_, err := assessment.AssessNonIID(hangingData, 8)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.True(t, errors.Is(err, ErrAssessmentTimeout))
+	assert.Contains(t, err.Error(), "still consuming CPU")
+	assert.Equal(t, before+1, AbandonedAssessments())
+}
+
+func TestTimeout_SubprocessKillsChild(t *testing.T) {
+	assessment := newIsolatedAssessment()
+	assessment.SetTimeout(200 * time.Millisecond)
+
+	before := AbandonedAssessments()
+
+	start := time.Now()
+	_, err := assessment.AssessIID(hangingData, 8)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.True(t, errors.Is(err, ErrAssessmentTimeout))
+	assert.Contains(t, err.Error(), "child process killed")
+	assert.Equal(t, before, AbandonedAssessments())
+}
+
+func TestTimeout_FastAssessmentUnaffected(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetTimeout(time.Minute)
+
+	res, err := assessment.AssessIID([]byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.Equal(t, 7.5, res.MinEntropy)
+}
+
+func TestTimeout_CallerDeadlineIsNotAssessmentTimeout(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(0)
+	assessment.SetTimeout(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := assessment.AssessIIDContext(ctx, hangingData, 8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, errors.Is(err, ErrAssessmentTimeout))
+}
@@ -4,6 +4,15 @@
 // C++ reference implementation via a CGO bridge.
 package entropy
 
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
 // TestType represents the type of entropy test performed.
 type TestType int
 
@@ -30,12 +39,22 @@ func (t TestType) String() string {
 // estimator or statistical test. When IsEntropyValid is false, the
 // EntropyEstimate field is set to -1.0 and should be disregarded.
 type EstimatorResult struct {
-	Name            string  // Estimator name (e.g., "Most Common Value")
-	EntropyEstimate float64 // Entropy estimate in bits per sample, or -1.0 if not applicable
-	Passed          bool    // Whether the test passed
-	IsEntropyValid  bool    // Indicates whether EntropyEstimate holds a meaningful value
+	Name            string      // Estimator name (e.g., "Most Common Value")
+	ID              EstimatorID // Stable identifier, EstimatorUnknown for names this version does not know
+	EntropyEstimate float64     // Entropy estimate in bits per sample, or -1.0 if not applicable
+	Passed          bool        // Whether the test passed
+	IsEntropyValid  bool        // Indicates whether EntropyEstimate holds a meaningful value
+
+	// Details holds estimator-specific intermediate values, such as the
+	// global and local predictability bounds of the prediction estimates.
+	// It is nil when the estimator reports none.
+	Details map[string]float64
 }
 
+// HistogramSize is the number of entries in Result.Histogram, one per
+// possible 8-bit symbol value.
+const HistogramSize = 256
+
 // Result contains the aggregate entropy assessment output. HOriginal is the
 // per-sample entropy estimated from the original symbol alphabet, HBitstring
 // is derived from the binary expansion, and HAssessed is the conservative
@@ -48,15 +67,161 @@ type Result struct {
 	DataWordSize int      // Bits per symbol used in the assessment
 	TestType     TestType // IID or NonIID
 
+	// Submitter claim (SP 800-90B Section 3.1.3). HFinal equals HAssessed
+	// when no claim was supplied.
+	HSubmitter       float64 // Entropy claimed by the submitter, if supplied
+	HasHSubmitter    bool    // Whether HSubmitter was supplied
+	HFinal           float64 // min(H_original, bits * H_bitstring, H_submitter)
+	SubmitterBinding bool    // Whether H_submitter was the binding constraint
+
+	// ShannonEntropy is the plain Shannon entropy of the symbol frequencies
+	// in bits per symbol, computed in Go for every backend. It is not an
+	// SP 800-90B estimate; the gap to MinEntropy indicates how far the
+	// distribution is from uniform.
+	ShannonEntropy float64
+
+	// AlphabetSize is the number of distinct symbol values in the data,
+	// masked to DataWordSize. A warning is printed when it fits in fewer
+	// bits than DataWordSize.
+	AlphabetSize int
+
+	// EstimatorSelection lists the estimators that were run when a subset was
+	// requested; it is nil for a full, conforming assessment.
+	EstimatorSelection []string
+
+	// Partial is set when the build could only run part of the requested
+	// estimators, as in a pure-Go (nocgo) build. The result is then not a
+	// conforming assessment.
+	Partial bool
+
+	// Backend names the implementation that produced the result: BackendNIST,
+	// BackendGo or BackendStub.
+	Backend string
+
+	// Section is the window of the input that was assessed when the
+	// assessment ran through AssessSection; nil otherwise.
+	Section *Section
+
+	// Histogram holds the occurrences of each symbol value, masked to
+	// DataWordSize, indexed by value. It has HistogramSize entries and is only
+	// set when requested via SetHistogram.
+	Histogram []uint64
+
+	// RawJSON is the document the NIST tool writes with -o for the run, as
+	// produced by the C wrapper. It is only set when requested via
+	// SetRawJSON and when the backend produces one.
+	RawJSON json.RawMessage `json:",omitempty"`
+
+	// Timings holds the wall time of the NIST library call of each phase
+	// that ran, keyed by TestType.String(): "IID" or "Non-IID". It leaves
+	// out the validation and preparation of the data in Go.
+	Timings map[string]time.Duration `json:",omitempty"`
+
 	Estimators []EstimatorResult // Individual estimator results
 }
 
+// IsolationMode selects where the NIST C++ reference code is executed.
+type IsolationMode int
+
+const (
+	// IsolationInProcess runs the C++ code inside the calling process.
+	IsolationInProcess IsolationMode = iota
+	// IsolationSubprocess runs each assessment in a re-executed child process
+	// so that an abort or segfault in the C++ code cannot take down the caller.
+	IsolationSubprocess
+)
+
+// String returns the configuration name of the IsolationMode.
+func (m IsolationMode) String() string {
+	switch m {
+	case IsolationInProcess:
+		return "inprocess"
+	case IsolationSubprocess:
+		return "subprocess"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseIsolationMode converts a configuration string ("inprocess" or
+// "subprocess") into an IsolationMode. An empty string selects in-process.
+func ParseIsolationMode(mode string) (IsolationMode, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "inprocess", "in-process":
+		return IsolationInProcess, nil
+	case "subprocess":
+		return IsolationSubprocess, nil
+	default:
+		return IsolationInProcess, fmt.Errorf("invalid isolation mode: %s (use inprocess or subprocess)", mode)
+	}
+}
+
+// BitOrder selects how each symbol is expanded into bits for the bitstring
+// estimates that yield HBitstring.
+type BitOrder int
+
+const (
+	// MSBFirst emits the most significant bit of each symbol first, matching
+	// the NIST reference tool. It is the default.
+	MSBFirst BitOrder = iota
+	// LSBFirst emits the least significant bit of each symbol first.
+	LSBFirst
+)
+
+// String returns the configuration name of the BitOrder.
+func (o BitOrder) String() string {
+	switch o {
+	case MSBFirst:
+		return "msb"
+	case LSBFirst:
+		return "lsb"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseBitOrder converts a configuration string ("msb" or "lsb") into a
+// BitOrder. An empty string selects MSBFirst.
+func ParseBitOrder(order string) (BitOrder, error) {
+	switch strings.ToLower(strings.TrimSpace(order)) {
+	case "", "msb", "msb-first":
+		return MSBFirst, nil
+	case "lsb", "lsb-first":
+		return LSBFirst, nil
+	default:
+		return MSBFirst, fmt.Errorf("invalid bit order: %s (use msb or lsb)", order)
+	}
+}
+
 // Assessment holds configuration for entropy estimation and serves as the
 // primary entry point for running IID and Non-IID assessments.
 type Assessment struct {
-	verbose int
+	verbose       int
+	isolation     IsolationMode
+	timeout       time.Duration
+	hSubmitter    float64
+	hasHSubmitter bool
+	estimators    []string
+	histogram     bool
+	rawJSON       bool
+	normalize     bool
+	bitOrder      BitOrder
+	permRounds    int
+	permSeed      uint64
+	hasPermSeed   bool
+	threads       int
+	warnWriter    io.Writer
+	memoryBudget  uint64
+	memoryModel   *MemoryModel
+	maxSamples    int
+	stride        int
+	progress      ProgressFunc
 }
 
+// PermutationRounds is the number of shuffles of the permutation tests of
+// SP 800-90B Section 5.1.
+const PermutationRounds = 10000
+
 // NewAssessment creates a new Assessment instance with default configuration.
 func NewAssessment() *Assessment {
 	return &Assessment{
@@ -80,3 +245,345 @@ func (a *Assessment) SetVerbose(level int) {
 func (a *Assessment) GetVerbose() int {
 	return a.verbose
 }
+
+// SetIsolation selects whether assessments run in-process or in an isolated
+// child process. See IsolationSubprocess for the requirements on the binary.
+func (a *Assessment) SetIsolation(mode IsolationMode) {
+	a.isolation = mode
+}
+
+// GetIsolation returns the configured isolation mode.
+func (a *Assessment) GetIsolation() IsolationMode {
+	return a.isolation
+}
+
+// SetTimeout bounds the wall-clock time of a single assessment. A value of
+// zero or less disables the limit. In subprocess isolation mode the child is
+// killed when the limit elapses; in-process the call returns
+// ErrAssessmentTimeout but the C++ computation cannot be interrupted and keeps
+// running in the background until it finishes.
+func (a *Assessment) SetTimeout(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	a.timeout = d
+}
+
+// GetTimeout returns the configured per-assessment timeout.
+func (a *Assessment) GetTimeout() time.Duration {
+	return a.timeout
+}
+
+// SetHSubmitter records the entropy claimed by the submitter so that HFinal
+// takes it into account. The claim is validated against bits per symbol when
+// an assessment runs.
+func (a *Assessment) SetHSubmitter(h float64) {
+	a.hSubmitter = h
+	a.hasHSubmitter = true
+}
+
+// ClearHSubmitter removes a previously set submitter claim.
+func (a *Assessment) ClearHSubmitter() {
+	a.hSubmitter = 0
+	a.hasHSubmitter = false
+}
+
+// GetHSubmitter returns the submitter claim and whether one is set.
+func (a *Assessment) GetHSubmitter() (float64, bool) {
+	return a.hSubmitter, a.hasHSubmitter
+}
+
+// SetEstimators restricts assessments to the named estimators (see
+// ValidEstimatorNames). Names are validated when an assessment runs. An empty
+// selection runs all estimators. Results computed from a subset are not a
+// conforming SP 800-90B assessment.
+func (a *Assessment) SetEstimators(names []string) {
+	if len(names) == 0 {
+		a.estimators = nil
+		return
+	}
+	a.estimators = append([]string(nil), names...)
+}
+
+// GetEstimators returns a copy of the estimator selection, or nil if all
+// estimators run.
+func (a *Assessment) GetEstimators() []string {
+	if a.estimators == nil {
+		return nil
+	}
+	return append([]string(nil), a.estimators...)
+}
+
+// SetHistogram controls whether results include the symbol histogram. The
+// counts are gathered during the library's existing pass over the data.
+func (a *Assessment) SetHistogram(enabled bool) {
+	a.histogram = enabled
+}
+
+// GetHistogram reports whether results include the symbol histogram.
+func (a *Assessment) GetHistogram() bool {
+	return a.histogram
+}
+
+// SetRawJSON controls whether results include the NIST tool JSON document
+// of the run. Only the NIST backend produces one.
+func (a *Assessment) SetRawJSON(enabled bool) {
+	a.rawJSON = enabled
+}
+
+// GetRawJSON reports whether results include the NIST tool JSON document.
+func (a *Assessment) GetRawJSON() bool {
+	return a.rawJSON
+}
+
+// SetNormalizeSymbols makes every assessment translate the samples with
+// NormalizeSymbols first, so that the estimators see the alphabet 0 through
+// k-1 rather than the raw byte values. Result.Histogram and the word size
+// then refer to the translated symbols.
+func (a *Assessment) SetNormalizeSymbols(enabled bool) {
+	a.normalize = enabled
+}
+
+// GetNormalizeSymbols reports whether samples are normalized before an
+// assessment.
+func (a *Assessment) GetNormalizeSymbols() bool {
+	return a.normalize
+}
+
+// SetBitOrder selects how symbols are expanded into the bitstring. Only
+// HBitstring and the estimates computed from the bitstring depend on it;
+// results are conforming only with the default MSBFirst. The value is
+// validated when an assessment runs.
+func (a *Assessment) SetBitOrder(order BitOrder) {
+	a.bitOrder = order
+}
+
+// GetBitOrder returns the configured bit order.
+func (a *Assessment) GetBitOrder() BitOrder {
+	return a.bitOrder
+}
+
+// SetPermutationRounds sets how many shuffles the pure-Go permutation tests
+// run at most. A value of zero or less restores PermutationRounds; any other
+// count, meant for quick smoke tests, makes IID results non-conforming. The
+// NIST library always runs PermutationRounds rounds.
+func (a *Assessment) SetPermutationRounds(n int) {
+	if n < 0 {
+		n = 0
+	}
+	a.permRounds = n
+}
+
+// GetPermutationRounds returns the number of permutation rounds that run.
+func (a *Assessment) GetPermutationRounds() int {
+	if a.permRounds == 0 {
+		return PermutationRounds
+	}
+	return a.permRounds
+}
+
+// SetPermutationSeed makes the pure-Go permutation tests reproducible: each
+// round shuffles with a generator derived from seed and the round number, so
+// the same seed yields the same counters whatever the number of workers.
+// Without a seed, every assessment draws a random one. The NIST library
+// ignores the seed.
+func (a *Assessment) SetPermutationSeed(seed uint64) {
+	a.permSeed = seed
+	a.hasPermSeed = true
+}
+
+// ClearPermutationSeed restores random seeding of the permutation tests.
+func (a *Assessment) ClearPermutationSeed() {
+	a.permSeed = 0
+	a.hasPermSeed = false
+}
+
+// GetPermutationSeed returns the permutation seed and whether one is set.
+func (a *Assessment) GetPermutationSeed() (uint64, bool) {
+	return a.permSeed, a.hasPermSeed
+}
+
+// SetThreads caps the threads of the IID permutation tests, the only part of
+// an assessment that runs in parallel: the OpenMP team of the NIST library or
+// the workers of the pure-Go implementation. Zero or less restores the
+// default of one thread per CPU. Capping avoids oversubscribing the machine
+// when several assessments run at once.
+func (a *Assessment) SetThreads(n int) {
+	if n < 0 {
+		n = 0
+	}
+	a.threads = n
+}
+
+// GetThreads returns the thread cap of the permutation tests; zero means
+// one thread per CPU.
+func (a *Assessment) GetThreads() int {
+	return a.threads
+}
+
+// SetMaxSamples truncates the data of every assessment to its first n
+// samples, for a quick dry run before the full assessment. The word size is
+// then detected on the truncated data, and a warning notes that the result
+// is not a conforming SP 800-90B assessment. Zero or less assesses all
+// samples.
+func (a *Assessment) SetMaxSamples(n int) {
+	if n < 0 {
+		n = 0
+	}
+	a.maxSamples = n
+}
+
+// GetMaxSamples returns the sample limit of an assessment; zero means no
+// limit.
+func (a *Assessment) GetMaxSamples() int {
+	return a.maxSamples
+}
+
+// SetStride down-samples the data of every assessment to every k-th sample,
+// those at index 0, k, 2k and so on, after the SetMaxSamples limit. It helps
+// tell whether a suspected periodic artifact lowers the estimate. A warning
+// notes that the result is not a conforming SP 800-90B assessment. Zero or
+// one assesses all samples; a negative k fails the assessment with
+// ErrInvalidStride.
+func (a *Assessment) SetStride(k int) {
+	a.stride = k
+}
+
+// GetStride returns the stride of an assessment; zero or one means every
+// sample is assessed.
+func (a *Assessment) GetStride() int {
+	return a.stride
+}
+
+// permutation returns the permutation test settings passed to the bridge,
+// with the thread cap of SetMaxThreads applied.
+func (a *Assessment) permutation() permutationOptions {
+	return permutationOptions{rounds: a.permRounds, seed: a.permSeed, hasSeed: a.hasPermSeed, threads: capThreads(a.threads)}
+}
+
+// SetWarnWriter redirects the warnings printed at verbosity 1 and above, such
+// as the small-sample and estimator-subset notices. A nil writer restores the
+// default, os.Stderr.
+func (a *Assessment) SetWarnWriter(w io.Writer) {
+	a.warnWriter = w
+}
+
+// GetWarnWriter returns the writer that receives warnings.
+func (a *Assessment) GetWarnWriter() io.Writer {
+	if a.warnWriter == nil {
+		return os.Stderr
+	}
+	return a.warnWriter
+}
+
+// warnf writes a warning unless the assessment is quiet.
+func (a *Assessment) warnf(format string, args ...any) {
+	if a.verbose > 0 {
+		fmt.Fprintf(a.GetWarnWriter(), "Warning: "+format+"\n", args...)
+	}
+}
+
+// AssessmentConfig is a snapshot of the settings of an Assessment. It holds
+// no reference into the Assessment it came from, so a shared base
+// configuration can be copied and adjusted per request and applied with
+// WithConfig without any synchronisation on the Assessment itself.
+type AssessmentConfig struct {
+	Verbose    int           // Clamped to [0, 3] when applied
+	Isolation  IsolationMode // In-process or subprocess execution
+	Timeout    time.Duration // Per-assessment limit; zero disables it
+	HSubmitter *float64      // Submitter claim; nil when none is set
+	Estimators []string      // Estimator subset; empty runs all
+	Histogram  bool          // Include the symbol histogram in results
+	RawJSON    bool          // Include the NIST tool JSON document in results
+	Normalize  bool          // Translate samples with NormalizeSymbols first
+	BitOrder   BitOrder      // Bitstring expansion; the zero value is MSBFirst
+	WarnWriter io.Writer     // Destination of warnings; nil means os.Stderr
+
+	PermutationRounds int     // Permutation test rounds; zero means PermutationRounds
+	PermutationSeed   *uint64 // Permutation test seed; nil draws a random one
+	Threads           int     // Permutation test threads; zero means one per CPU
+
+	MemoryBudget uint64       // Peak memory limit in bytes; zero disables it
+	MemoryModel  *MemoryModel // Memory estimate; nil means DefaultMemoryModel
+
+	Progress   ProgressFunc // Progress reports, see WithProgress; nil for none
+	MaxSamples int          // Truncates the data to this many samples; zero assesses all
+	Stride     int          // Keeps every Stride-th sample; zero or one assesses all
+}
+
+// Config returns a snapshot of the current settings. Modifying the returned
+// value does not affect the assessment.
+func (a *Assessment) Config() AssessmentConfig {
+	cfg := AssessmentConfig{
+		Verbose:    a.verbose,
+		Isolation:  a.isolation,
+		Timeout:    a.timeout,
+		Estimators: a.GetEstimators(),
+		Histogram:  a.histogram,
+		RawJSON:    a.rawJSON,
+		Normalize:  a.normalize,
+		BitOrder:   a.bitOrder,
+		WarnWriter: a.warnWriter,
+
+		PermutationRounds: a.permRounds,
+		Threads:           a.threads,
+		MemoryBudget:      a.memoryBudget,
+		Progress:          a.progress,
+		MaxSamples:        a.maxSamples,
+		Stride:            a.stride,
+	}
+	if a.hasHSubmitter {
+		h := a.hSubmitter
+		cfg.HSubmitter = &h
+	}
+	if a.hasPermSeed {
+		seed := a.permSeed
+		cfg.PermutationSeed = &seed
+	}
+	if a.memoryModel != nil {
+		model := *a.memoryModel
+		cfg.MemoryModel = &model
+	}
+	return cfg
+}
+
+// WithConfig returns a shallow copy of the assessment with every setting
+// taken from cfg, leaving the receiver untouched. The values are validated
+// when an assessment runs, as with the individual setters.
+func (a *Assessment) WithConfig(cfg AssessmentConfig) *Assessment {
+	c := a.Clone()
+	c.SetVerbose(cfg.Verbose)
+	c.SetIsolation(cfg.Isolation)
+	c.SetTimeout(cfg.Timeout)
+	if cfg.HSubmitter != nil {
+		c.SetHSubmitter(*cfg.HSubmitter)
+	} else {
+		c.ClearHSubmitter()
+	}
+	c.SetEstimators(cfg.Estimators)
+	c.SetHistogram(cfg.Histogram)
+	c.SetRawJSON(cfg.RawJSON)
+	c.SetNormalizeSymbols(cfg.Normalize)
+	c.SetBitOrder(cfg.BitOrder)
+	c.SetWarnWriter(cfg.WarnWriter)
+	c.SetPermutationRounds(cfg.PermutationRounds)
+	if cfg.PermutationSeed != nil {
+		c.SetPermutationSeed(*cfg.PermutationSeed)
+	} else {
+		c.ClearPermutationSeed()
+	}
+	c.SetThreads(cfg.Threads)
+	c.SetMemoryBudget(cfg.MemoryBudget)
+	c.SetMemoryModel(cfg.MemoryModel)
+	c.progress = cfg.Progress
+	c.SetMaxSamples(cfg.MaxSamples)
+	c.SetStride(cfg.Stride)
+	return c
+}
+
+// Clone returns an independent copy of the assessment settings, allowing
+// per-call options to be applied without mutating a shared instance.
+func (a *Assessment) Clone() *Assessment {
+	c := *a
+	return &c
+}
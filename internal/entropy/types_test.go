@@ -1,9 +1,13 @@
 package entropy
 
 import (
+	"bytes"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTestType_String(t *testing.T) {
@@ -51,6 +55,203 @@ func TestAssessment_SetVerbose(t *testing.T) {
 	assert.Equal(t, 3, assessment.GetVerbose())
 }
 
+func TestAssessment_SetTimeout(t *testing.T) {
+	assessment := NewAssessment()
+	assert.Zero(t, assessment.GetTimeout())
+
+	assessment.SetTimeout(30 * time.Second)
+	assert.Equal(t, 30*time.Second, assessment.GetTimeout())
+
+	// Negative values disable the limit
+	assessment.SetTimeout(-time.Second)
+	assert.Zero(t, assessment.GetTimeout())
+}
+
+func TestAssessment_HSubmitter(t *testing.T) {
+	assessment := NewAssessment()
+
+	_, ok := assessment.GetHSubmitter()
+	assert.False(t, ok)
+
+	assessment.SetHSubmitter(5.5)
+	h, ok := assessment.GetHSubmitter()
+	assert.True(t, ok)
+	assert.Equal(t, 5.5, h)
+
+	assessment.ClearHSubmitter()
+	_, ok = assessment.GetHSubmitter()
+	assert.False(t, ok)
+}
+
+func TestAssessment_SetEstimators(t *testing.T) {
+	assessment := NewAssessment()
+	assert.Nil(t, assessment.GetEstimators())
+
+	names := []string{"mcv", "markov"}
+	assessment.SetEstimators(names)
+	names[0] = "lag"
+	assert.Equal(t, []string{"mcv", "markov"}, assessment.GetEstimators())
+
+	assessment.SetEstimators(nil)
+	assert.Nil(t, assessment.GetEstimators())
+}
+
+func TestAssessment_SetHistogram(t *testing.T) {
+	assessment := NewAssessment()
+	assert.False(t, assessment.GetHistogram())
+
+	assessment.SetHistogram(true)
+	assert.True(t, assessment.GetHistogram())
+}
+
+func TestAssessment_SetRawJSON(t *testing.T) {
+	assessment := NewAssessment()
+	assert.False(t, assessment.GetRawJSON())
+
+	assessment.SetRawJSON(true)
+	assert.True(t, assessment.GetRawJSON())
+}
+
+func TestAssessment_SetBitOrder(t *testing.T) {
+	assessment := NewAssessment()
+	assert.Equal(t, MSBFirst, assessment.GetBitOrder())
+
+	assessment.SetBitOrder(LSBFirst)
+	assert.Equal(t, LSBFirst, assessment.GetBitOrder())
+}
+
+func TestAssessment_PermutationSettings(t *testing.T) {
+	assessment := NewAssessment()
+	assert.Equal(t, PermutationRounds, assessment.GetPermutationRounds())
+	_, ok := assessment.GetPermutationSeed()
+	assert.False(t, ok)
+
+	assessment.SetPermutationRounds(100)
+	assessment.SetPermutationSeed(42)
+	assert.Equal(t, 100, assessment.GetPermutationRounds())
+	seed, ok := assessment.GetPermutationSeed()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(42), seed)
+	assert.Equal(t, permutationOptions{rounds: 100, seed: 42, hasSeed: true}, assessment.permutation())
+
+	assessment.SetPermutationRounds(-5)
+	assessment.ClearPermutationSeed()
+	assert.Equal(t, PermutationRounds, assessment.GetPermutationRounds())
+	assert.Equal(t, permutationOptions{}, assessment.permutation())
+}
+
+func TestParseBitOrder(t *testing.T) {
+	for input, want := range map[string]BitOrder{"": MSBFirst, "msb": MSBFirst, "MSB-first": MSBFirst, "lsb": LSBFirst, " lsb-first ": LSBFirst} {
+		got, err := ParseBitOrder(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := ParseBitOrder("little")
+	assert.Error(t, err)
+
+	assert.Equal(t, "msb", MSBFirst.String())
+	assert.Equal(t, "lsb", LSBFirst.String())
+	assert.Equal(t, "unknown", BitOrder(7).String())
+}
+
+func TestAssessment_Clone(t *testing.T) {
+	assessment := NewAssessment()
+	assessment.SetVerbose(2)
+
+	clone := assessment.Clone()
+	clone.SetHSubmitter(3)
+	clone.SetVerbose(0)
+
+	assert.Equal(t, 2, assessment.GetVerbose())
+	_, ok := assessment.GetHSubmitter()
+	assert.False(t, ok)
+	assert.Equal(t, 0, clone.GetVerbose())
+}
+
+func TestAssessment_ConfigRoundTrip(t *testing.T) {
+	var warnings bytes.Buffer
+	assessment := NewAssessment()
+	assessment.SetVerbose(2)
+	assessment.SetIsolation(IsolationSubprocess)
+	assessment.SetTimeout(time.Minute)
+	assessment.SetHSubmitter(3)
+	assessment.SetEstimators([]string{"mcv"})
+	assessment.SetHistogram(true)
+	assessment.SetRawJSON(true)
+	assessment.SetNormalizeSymbols(true)
+	assessment.SetBitOrder(LSBFirst)
+	assessment.SetWarnWriter(&warnings)
+	assessment.SetPermutationRounds(500)
+	assessment.SetPermutationSeed(7)
+	assessment.SetThreads(2)
+	assessment.SetMemoryBudget(1 << 30)
+	assessment.SetMemoryModel(&MemoryModel{Base: 1})
+	assessment.SetMaxSamples(1000)
+	assessment.SetStride(3)
+
+	cfg := assessment.Config()
+	require.NotNil(t, cfg.HSubmitter)
+	assert.Equal(t, 3.0, *cfg.HSubmitter)
+	require.NotNil(t, cfg.PermutationSeed)
+	assert.Equal(t, uint64(7), *cfg.PermutationSeed)
+	assert.Equal(t, AssessmentConfig{
+		Verbose:    2,
+		Isolation:  IsolationSubprocess,
+		Timeout:    time.Minute,
+		HSubmitter: cfg.HSubmitter,
+		Estimators: []string{"mcv"},
+		Histogram:  true,
+		RawJSON:    true,
+		Normalize:  true,
+		BitOrder:   LSBFirst,
+		WarnWriter: &warnings,
+
+		PermutationRounds: 500,
+		PermutationSeed:   cfg.PermutationSeed,
+		Threads:           2,
+
+		MemoryBudget: 1 << 30,
+		MemoryModel:  &MemoryModel{Base: 1},
+
+		MaxSamples: 1000,
+		Stride:     3,
+	}, cfg)
+	assert.Equal(t, cfg, NewAssessment().WithConfig(cfg).Config())
+
+	// The snapshot shares no state with the assessment.
+	cfg.Estimators[0] = "collision"
+	*cfg.HSubmitter = 1
+	*cfg.PermutationSeed = 1
+	cfg.MemoryModel.Base = 2
+	assert.Equal(t, []string{"mcv"}, assessment.GetEstimators())
+	h, _ := assessment.GetHSubmitter()
+	assert.Equal(t, 3.0, h)
+	seed, _ := assessment.GetPermutationSeed()
+	assert.Equal(t, uint64(7), seed)
+	assert.Equal(t, uint64(1), assessment.GetMemoryModel().Base)
+}
+
+func TestAssessment_WithConfig(t *testing.T) {
+	base := NewAssessment()
+	base.SetHSubmitter(2)
+
+	derived := base.WithConfig(AssessmentConfig{Verbose: 9, Timeout: -time.Second, Estimators: []string{}, MaxSamples: -1})
+	assert.Equal(t, 3, derived.GetVerbose())
+	assert.Equal(t, time.Duration(0), derived.GetTimeout())
+	assert.Zero(t, derived.GetMaxSamples())
+	assert.Nil(t, derived.GetEstimators())
+	_, ok := derived.GetHSubmitter()
+	assert.False(t, ok)
+	assert.Equal(t, os.Stderr, derived.GetWarnWriter())
+
+	// The receiver keeps its settings.
+	assert.Equal(t, 1, base.GetVerbose())
+	h, ok := base.GetHSubmitter()
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, h)
+}
+
 func TestResult(t *testing.T) {
 	result := &Result{
 		MinEntropy:   7.5,
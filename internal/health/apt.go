@@ -0,0 +1,115 @@
+package health
+
+import (
+	"fmt"
+	"math"
+)
+
+// Window sizes of the Adaptive Proportion Test recommended by SP 800-90B
+// Section 4.4.2.
+const (
+	BinaryWindow    = 512  // for sources producing one bit per sample
+	NonBinaryWindow = 1024 // for all other sources
+)
+
+// AdaptiveProportionTest implements the Adaptive Proportion Test of SP 800-90B
+// Section 4.4.2. It splits the samples into windows of W samples and fails
+// when the first sample of a window occurs C or more times within it, where
+// C = 1 + CRITBINOM(W, 2^-H, 1-alpha) is the cutoff for a source with H bits
+// of min-entropy per sample and a false positive probability of alpha.
+type AdaptiveProportionTest struct {
+	window   int
+	cutoff   int
+	first    byte // A, the first sample of the current window
+	seen     int  // samples of the current window, 0 before the window starts
+	count    int  // B, the occurrences of A in the current window
+	samples  uint64
+	failures uint64
+}
+
+// NewAdaptiveProportionTest returns a test with the given window size for a
+// source with h bits of min-entropy per sample and the false positive
+// probability alpha. Use BinaryWindow or NonBinaryWindow unless the source
+// requires another size.
+func NewAdaptiveProportionTest(h, alpha float64, window int) (*AdaptiveProportionTest, error) {
+	if err := validateParams(h, alpha); err != nil {
+		return nil, err
+	}
+	if window < 2 {
+		return nil, fmt.Errorf("%w, got %d", ErrInvalidWindow, window)
+	}
+	return &AdaptiveProportionTest{
+		window: window,
+		cutoff: 1 + critBinom(window, math.Exp2(-h), alpha),
+	}, nil
+}
+
+// critBinom returns the smallest k for which the binomial distribution with n
+// trials and success probability p has P(X <= k) >= 1-alpha. The upper tail
+// is summed from n downwards so that it stays accurate for tiny alpha.
+func critBinom(n int, p, alpha float64) int {
+	lgN, _ := math.Lgamma(float64(n + 1))
+	logP, logQ := math.Log(p), math.Log1p(-p)
+	tail := 0.0 // P(X > k)
+	for k := n; k > 0; k-- {
+		lgK, _ := math.Lgamma(float64(k + 1))
+		lgNK, _ := math.Lgamma(float64(n - k + 1))
+		pmf := math.Exp(lgN - lgK - lgNK + float64(k)*logP + float64(n-k)*logQ)
+		if tail+pmf > alpha {
+			return k
+		}
+		tail += pmf
+	}
+	return 0
+}
+
+// Feed processes the next sample. It returns a *FailureError for every
+// sample that brings the count of the window's first sample to C or more.
+func (t *AdaptiveProportionTest) Feed(b byte) error {
+	t.samples++
+	if t.seen == 0 {
+		t.first = b
+		t.count = 1
+	} else if b == t.first {
+		t.count++
+	}
+	t.seen++
+	failed := b == t.first && t.count >= t.cutoff
+	if failed && t.count == t.cutoff {
+		t.failures++
+	}
+	count := t.count
+	if t.seen == t.window {
+		t.seen = 0
+	}
+	if !failed {
+		return nil
+	}
+	return &FailureError{Test: "adaptive proportion test", Sample: b, Index: t.samples - 1, Count: count, Cutoff: t.cutoff}
+}
+
+// Reset starts a new window with the next sample. The counters are kept.
+func (t *AdaptiveProportionTest) Reset() {
+	t.seen = 0
+}
+
+// Window returns W, the number of samples in a window.
+func (t *AdaptiveProportionTest) Window() int {
+	return t.window
+}
+
+// Cutoff returns C, the count within one window at which the test fails.
+func (t *AdaptiveProportionTest) Cutoff() int {
+	return t.cutoff
+}
+
+// Samples returns the number of samples fed since the test was created.
+func (t *AdaptiveProportionTest) Samples() uint64 {
+	return t.samples
+}
+
+// Failures returns the number of windows that reached the cutoff since the
+// test was created.
+func (t *AdaptiveProportionTest) Failures() uint64 {
+	return t.failures
+}
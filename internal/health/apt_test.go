@@ -0,0 +1,95 @@
+package health
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAdaptiveProportionTest_Cutoff(t *testing.T) {
+	// Cutoffs 1 + CRITBINOM(W, 2^-H, 1-alpha) for the standard windows, taken
+	// from an exact rational evaluation of the binomial distribution.
+	cases := []struct {
+		h, alpha float64
+		window   int
+		want     int
+	}{
+		{0.2, math.Exp2(-20), BinaryWindow, 480},
+		{0.5, math.Exp2(-20), BinaryWindow, 410},
+		{1, math.Exp2(-20), BinaryWindow, 311},
+		{1, math.Exp2(-40), BinaryWindow, 336},
+		{0.5, math.Exp2(-20), NonBinaryWindow, 793},
+		{1, math.Exp2(-20), NonBinaryWindow, 589},
+		{2, math.Exp2(-20), NonBinaryWindow, 325},
+		{4, math.Exp2(-20), NonBinaryWindow, 105},
+		{7.3, math.Exp2(-30), NonBinaryWindow, 28},
+		{8, math.Exp2(-20), NonBinaryWindow, 18},
+		{8, math.Exp2(-40), NonBinaryWindow, 26},
+	}
+	for _, tc := range cases {
+		apt, err := NewAdaptiveProportionTest(tc.h, tc.alpha, tc.window)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, apt.Cutoff(), "H=%g alpha=%g W=%d", tc.h, tc.alpha, tc.window)
+		assert.Equal(t, tc.window, apt.Window())
+	}
+}
+
+func TestNewAdaptiveProportionTest_InvalidParams(t *testing.T) {
+	_, err := NewAdaptiveProportionTest(0, math.Exp2(-20), NonBinaryWindow)
+	assert.True(t, errors.Is(err, ErrInvalidEntropy))
+	_, err = NewAdaptiveProportionTest(1, 2, NonBinaryWindow)
+	assert.True(t, errors.Is(err, ErrInvalidAlpha))
+	for _, window := range []int{-1, 0, 1} {
+		_, err = NewAdaptiveProportionTest(1, math.Exp2(-20), window)
+		assert.True(t, errors.Is(err, ErrInvalidWindow), "window=%d", window)
+	}
+}
+
+func TestCritBinom(t *testing.T) {
+	// Two fair coin flips: P(X <= 1) = 0.75 and P(X <= 2) = 1.
+	assert.Equal(t, 1, critBinom(2, 0.5, 0.25))
+	assert.Equal(t, 2, critBinom(2, 0.5, 0.2))
+	assert.Equal(t, 0, critBinom(2, 0.5, 0.8))
+}
+
+func TestAdaptiveProportionTest_Window(t *testing.T) {
+	apt, err := NewAdaptiveProportionTest(8, math.Exp2(-20), 64)
+	require.NoError(t, err)
+	cutoff := apt.Cutoff()
+	require.Less(t, cutoff, 64)
+
+	// Only the first sample of a window is counted: cutoff-1 copies of it
+	// pass, even when another value is more frequent.
+	window := make([]byte, 0, 64)
+	for i := 0; i < cutoff-1; i++ {
+		window = append(window, 3)
+	}
+	for len(window) < 64 {
+		window = append(window, 4)
+	}
+	for i, b := range window {
+		require.NoError(t, apt.Feed(b), "sample %d", i)
+	}
+
+	// The next window starts with 4 and fails on its cutoff-th 4.
+	for i := 0; i < cutoff-1; i++ {
+		require.NoError(t, apt.Feed(4))
+	}
+	err = apt.Feed(4)
+	var failure *FailureError
+	require.True(t, errors.As(err, &failure))
+	assert.Equal(t, FailureError{Test: "adaptive proportion test", Sample: 4, Index: uint64(64 + cutoff - 1), Count: cutoff, Cutoff: cutoff}, *failure)
+
+	// Other values pass, the window's sample keeps failing but counts once.
+	assert.NoError(t, apt.Feed(5))
+	assert.Error(t, apt.Feed(4))
+	assert.Equal(t, uint64(1), apt.Failures())
+
+	// A new window starts from scratch.
+	apt.Reset()
+	assert.NoError(t, apt.Feed(4))
+	assert.Equal(t, uint64(64+cutoff+3), apt.Samples())
+}
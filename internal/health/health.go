@@ -0,0 +1,90 @@
+// Package health implements the continuous health tests of NIST SP 800-90B
+// Section 4.4, which an entropy source runs on every sample it produces to
+// detect failures of the noise source while it operates.
+package health
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Sentinel errors for health test failures and invalid parameters. Use
+// errors.Is to match against these.
+var (
+	ErrHealthTestFailed = errors.New("health test failed")
+	ErrInvalidEntropy   = errors.New("min-entropy per sample must be in (0, 8]")
+	ErrInvalidAlpha     = errors.New("false positive probability alpha must be in (0, 1)")
+	ErrInvalidWindow    = errors.New("window size must be at least 2")
+	ErrInvalidBits      = errors.New("bits per sample must be in [1, 8]")
+)
+
+// FailureError reports a sample that made a health test fail. It unwraps to
+// ErrHealthTestFailed.
+type FailureError struct {
+	Test   string // Name of the failing test
+	Sample byte   // The sample value that was repeated too often
+	Index  uint64 // Zero-based position of the sample in the stream
+	Count  int    // Occurrences observed when the test failed
+	Cutoff int    // The cutoff value C of the test
+}
+
+func (e *FailureError) Error() string {
+	return fmt.Sprintf("%s: sample 0x%02x at index %d seen %d times, cutoff %d: %v", e.Test, e.Sample, e.Index, e.Count, e.Cutoff, ErrHealthTestFailed)
+}
+
+func (e *FailureError) Unwrap() error {
+	return ErrHealthTestFailed
+}
+
+// Test is a continuous health test fed one sample at a time.
+type Test interface {
+	// Feed processes the next sample and returns a *FailureError when the
+	// test fails on it.
+	Feed(b byte) error
+	// Reset discards the samples seen so far, as after a restart of the
+	// noise source.
+	Reset()
+}
+
+// validateParams checks the min-entropy per sample and the false positive
+// probability the cutoff of a test is derived from.
+func validateParams(h, alpha float64) error {
+	if !(h > 0 && h <= 8) {
+		return fmt.Errorf("%w, got %g", ErrInvalidEntropy, h)
+	}
+	if !(alpha > 0 && alpha < 1) {
+		return fmt.Errorf("%w, got %g", ErrInvalidAlpha, alpha)
+	}
+	return nil
+}
+
+// Reader passes the samples read from an underlying reader through health
+// tests. A failure is returned together with the samples before the failing
+// one, and every later Read returns it again.
+type Reader struct {
+	r     io.Reader
+	tests []Test
+	err   error
+}
+
+// NewReader returns a Reader that feeds every sample read from r to tests.
+func NewReader(r io.Reader, tests ...Test) *Reader {
+	return &Reader{r: r, tests: tests}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.r.Read(p)
+	for i, b := range p[:n] {
+		for _, t := range r.tests {
+			if ferr := t.Feed(b); ferr != nil {
+				r.err = ferr
+				return i, ferr
+			}
+		}
+	}
+	return n, err
+}
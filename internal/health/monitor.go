@@ -0,0 +1,110 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultAlpha is the false positive probability used when Config.Alpha is
+// zero, the upper end of the range SP 800-90B recommends.
+const DefaultAlpha = 0x1p-20
+
+// Config describes the noise source a Monitor checks.
+type Config struct {
+	H      float64 // Claimed min-entropy per sample, at most Bits
+	Alpha  float64 // False positive probability, 0 for DefaultAlpha
+	Bits   int     // Bits per sample; 1 selects BinaryWindow
+	Window int     // Adaptive Proportion Test window, 0 for the standard size
+}
+
+// Monitor runs the Repetition Count Test and the Adaptive Proportion Test
+// side by side over one stream of samples.
+type Monitor struct {
+	rct *RepetitionCountTest
+	apt *AdaptiveProportionTest
+}
+
+// NewMonitor returns a Monitor for the source described by cfg.
+func NewMonitor(cfg Config) (*Monitor, error) {
+	if cfg.Bits < 1 || cfg.Bits > 8 {
+		return nil, fmt.Errorf("%w, got %d", ErrInvalidBits, cfg.Bits)
+	}
+	if cfg.H > float64(cfg.Bits) {
+		return nil, fmt.Errorf("%w, got %g for %d-bit samples", ErrInvalidEntropy, cfg.H, cfg.Bits)
+	}
+	alpha := cfg.Alpha
+	if alpha == 0 {
+		alpha = DefaultAlpha
+	}
+	window := cfg.Window
+	if window == 0 {
+		window = NonBinaryWindow
+		if cfg.Bits == 1 {
+			window = BinaryWindow
+		}
+	}
+
+	rct, err := NewRepetitionCountTest(cfg.H, alpha)
+	if err != nil {
+		return nil, err
+	}
+	apt, err := NewAdaptiveProportionTest(cfg.H, alpha, window)
+	if err != nil {
+		return nil, err
+	}
+	return &Monitor{rct: rct, apt: apt}, nil
+}
+
+// Feed passes the next sample to both tests and returns the first
+// *FailureError. Both tests see every sample, so their state stays in step.
+func (m *Monitor) Feed(b byte) error {
+	rctErr := m.rct.Feed(b)
+	aptErr := m.apt.Feed(b)
+	if rctErr != nil {
+		return rctErr
+	}
+	return aptErr
+}
+
+// Reset restarts both tests, as after a restart of the noise source.
+func (m *Monitor) Reset() {
+	m.rct.Reset()
+	m.apt.Reset()
+}
+
+// Run feeds every sample read from r until the end of the stream and returns
+// the first failure, whose Index locates the sample in the stream. Reading
+// stops at the failure.
+func (m *Monitor) Run(r io.Reader) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			if ferr := m.Feed(b); ferr != nil {
+				return ferr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// RepetitionCount returns the Repetition Count Test run by the monitor.
+func (m *Monitor) RepetitionCount() *RepetitionCountTest {
+	return m.rct
+}
+
+// AdaptiveProportion returns the Adaptive Proportion Test run by the monitor.
+func (m *Monitor) AdaptiveProportion() *AdaptiveProportionTest {
+	return m.apt
+}
+
+// Samples returns the number of samples fed since the monitor was created.
+func (m *Monitor) Samples() uint64 {
+	return m.rct.Samples()
+}
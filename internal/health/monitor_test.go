@@ -0,0 +1,83 @@
+package health
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMonitor_Defaults(t *testing.T) {
+	m, err := NewMonitor(Config{H: 1, Bits: 1})
+	require.NoError(t, err)
+	assert.Equal(t, BinaryWindow, m.AdaptiveProportion().Window())
+	assert.Equal(t, 311, m.AdaptiveProportion().Cutoff())
+	assert.Equal(t, 21, m.RepetitionCount().Cutoff())
+
+	m, err = NewMonitor(Config{H: 8, Bits: 8, Alpha: math.Exp2(-40)})
+	require.NoError(t, err)
+	assert.Equal(t, NonBinaryWindow, m.AdaptiveProportion().Window())
+	assert.Equal(t, 26, m.AdaptiveProportion().Cutoff())
+
+	m, err = NewMonitor(Config{H: 4, Bits: 8, Window: 256})
+	require.NoError(t, err)
+	assert.Equal(t, 256, m.AdaptiveProportion().Window())
+}
+
+func TestNewMonitor_InvalidConfig(t *testing.T) {
+	_, err := NewMonitor(Config{H: 1, Bits: 0})
+	assert.True(t, errors.Is(err, ErrInvalidBits))
+	_, err = NewMonitor(Config{H: 1.5, Bits: 1})
+	assert.True(t, errors.Is(err, ErrInvalidEntropy))
+	_, err = NewMonitor(Config{H: 0, Bits: 8})
+	assert.True(t, errors.Is(err, ErrInvalidEntropy))
+	_, err = NewMonitor(Config{H: 4, Bits: 8, Window: 1})
+	assert.True(t, errors.Is(err, ErrInvalidWindow))
+}
+
+func TestMonitor_Run(t *testing.T) {
+	samples := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(samples)
+
+	m, err := NewMonitor(Config{H: 7, Bits: 8})
+	require.NoError(t, err)
+	require.NoError(t, m.Run(iotest.HalfReader(bytes.NewReader(samples))))
+	assert.Equal(t, uint64(len(samples)), m.Samples())
+
+	// A stuck source trips the Repetition Count Test first.
+	stuck := append(append([]byte{}, samples[:5000]...), make([]byte, 50)...)
+	m, err = NewMonitor(Config{H: 7, Bits: 8})
+	require.NoError(t, err)
+	err = m.Run(bytes.NewReader(stuck))
+	var failure *FailureError
+	require.True(t, errors.As(err, &failure))
+	assert.Equal(t, "repetition count test", failure.Test)
+	assert.Equal(t, uint64(5000+m.RepetitionCount().Cutoff()-1), failure.Index)
+
+	// A biased source without long runs trips only the Adaptive Proportion
+	// Test.
+	biased := make([]byte, 4096)
+	for i := range biased {
+		biased[i] = byte(i % 2 * (i % 7))
+	}
+	m, err = NewMonitor(Config{H: 7, Bits: 8})
+	require.NoError(t, err)
+	err = m.Run(bytes.NewReader(biased))
+	require.True(t, errors.As(err, &failure))
+	assert.Equal(t, "adaptive proportion test", failure.Test)
+	assert.Equal(t, byte(0), failure.Sample)
+	assert.Less(t, failure.Index, uint64(NonBinaryWindow))
+	assert.Equal(t, uint64(0), m.RepetitionCount().Failures())
+}
+
+func TestMonitor_RunReadError(t *testing.T) {
+	m, err := NewMonitor(Config{H: 4, Bits: 8})
+	require.NoError(t, err)
+	readErr := errors.New("device unplugged")
+	assert.Equal(t, readErr, m.Run(iotest.ErrReader(readErr)))
+}
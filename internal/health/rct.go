@@ -0,0 +1,65 @@
+package health
+
+import "math"
+
+// RepetitionCountTest implements the Repetition Count Test of SP 800-90B
+// Section 4.4.1. It fails when one sample value repeats C or more times in a
+// row, where C = 1 + ceil(-log2(alpha) / H) is the cutoff for a source with H
+// bits of min-entropy per sample and a false positive probability of alpha.
+type RepetitionCountTest struct {
+	cutoff   int
+	last     byte
+	count    int // B, the length of the current run
+	samples  uint64
+	failures uint64
+}
+
+// NewRepetitionCountTest returns a test for a source with h bits of
+// min-entropy per sample and the false positive probability alpha, for which
+// SP 800-90B recommends a value between 2^-40 and 2^-20.
+func NewRepetitionCountTest(h, alpha float64) (*RepetitionCountTest, error) {
+	if err := validateParams(h, alpha); err != nil {
+		return nil, err
+	}
+	return &RepetitionCountTest{cutoff: 1 + int(math.Ceil(-math.Log2(alpha)/h))}, nil
+}
+
+// Feed processes the next sample. It returns a *FailureError for every
+// sample that extends a run to C or more.
+func (t *RepetitionCountTest) Feed(b byte) error {
+	t.samples++
+	if t.count > 0 && b == t.last {
+		t.count++
+	} else {
+		t.last = b
+		t.count = 1
+	}
+	if t.count < t.cutoff {
+		return nil
+	}
+	if t.count == t.cutoff {
+		t.failures++
+	}
+	return &FailureError{Test: "repetition count test", Sample: b, Index: t.samples - 1, Count: t.count, Cutoff: t.cutoff}
+}
+
+// Reset starts a new run with the next sample. The counters are kept.
+func (t *RepetitionCountTest) Reset() {
+	t.count = 0
+}
+
+// Cutoff returns C, the run length at which the test fails.
+func (t *RepetitionCountTest) Cutoff() int {
+	return t.cutoff
+}
+
+// Samples returns the number of samples fed since the test was created.
+func (t *RepetitionCountTest) Samples() uint64 {
+	return t.samples
+}
+
+// Failures returns the number of runs that reached the cutoff since the test
+// was created.
+func (t *RepetitionCountTest) Failures() uint64 {
+	return t.failures
+}
@@ -0,0 +1,111 @@
+package health
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRepetitionCountTest_Cutoff(t *testing.T) {
+	cases := []struct {
+		h, alpha float64
+		want     int
+	}{
+		{1, math.Exp2(-20), 21},
+		{0.5, math.Exp2(-20), 41},
+		{8, math.Exp2(-20), 4},
+		{7.3, math.Exp2(-30), 6},
+		{2, math.Exp2(-40), 21},
+		{0.1, math.Exp2(-20), 201},
+	}
+	for _, tc := range cases {
+		rct, err := NewRepetitionCountTest(tc.h, tc.alpha)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, rct.Cutoff(), "H=%g alpha=%g", tc.h, tc.alpha)
+	}
+}
+
+func TestNewRepetitionCountTest_InvalidParams(t *testing.T) {
+	for _, h := range []float64{0, -1, 8.5, math.NaN()} {
+		_, err := NewRepetitionCountTest(h, math.Exp2(-20))
+		assert.True(t, errors.Is(err, ErrInvalidEntropy), "H=%g", h)
+	}
+	for _, alpha := range []float64{0, 1, -0.5, math.NaN()} {
+		_, err := NewRepetitionCountTest(1, alpha)
+		assert.True(t, errors.Is(err, ErrInvalidAlpha), "alpha=%g", alpha)
+	}
+}
+
+func TestRepetitionCountTest_StuckAt(t *testing.T) {
+	rct, err := NewRepetitionCountTest(8, math.Exp2(-20))
+	require.NoError(t, err)
+	require.Equal(t, 4, rct.Cutoff())
+
+	// Three repetitions of a value pass, the fourth fails.
+	for i, b := range []byte{7, 1, 1, 1, 2, 1} {
+		require.NoError(t, rct.Feed(b), "sample %d", i)
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, rct.Feed(0x5a))
+	}
+	err = rct.Feed(0x5a)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrHealthTestFailed))
+
+	var failure *FailureError
+	require.True(t, errors.As(err, &failure))
+	assert.Equal(t, FailureError{Test: "repetition count test", Sample: 0x5a, Index: 9, Count: 4, Cutoff: 4}, *failure)
+	assert.Equal(t, uint64(1), rct.Failures())
+
+	// The run keeps failing, but counts as one failure.
+	assert.Error(t, rct.Feed(0x5a))
+	assert.Equal(t, uint64(1), rct.Failures())
+	assert.NoError(t, rct.Feed(0))
+	assert.Equal(t, uint64(12), rct.Samples())
+}
+
+func TestRepetitionCountTest_Reset(t *testing.T) {
+	rct, err := NewRepetitionCountTest(8, math.Exp2(-20))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, rct.Feed(9))
+	}
+	rct.Reset()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, rct.Feed(9))
+	}
+	assert.Error(t, rct.Feed(9))
+
+	rct.Reset()
+	assert.NoError(t, rct.Feed(9))
+	assert.Equal(t, uint64(1), rct.Failures())
+}
+
+func TestReader(t *testing.T) {
+	rct, err := NewRepetitionCountTest(1, math.Exp2(-20))
+	require.NoError(t, err)
+
+	// A healthy stream passes through unchanged.
+	healthy := bytes.Repeat([]byte{0, 1, 1, 0}, 1000)
+	got, err := io.ReadAll(NewReader(bytes.NewReader(healthy), rct))
+	require.NoError(t, err)
+	assert.Equal(t, healthy, got)
+
+	// A stuck source is cut off before the sample that reaches the cutoff.
+	rct.Reset()
+	stuck := append([]byte{1, 0}, make([]byte, 100)...)
+	r := NewReader(bytes.NewReader(stuck), rct)
+	got, err = io.ReadAll(r)
+	assert.True(t, errors.Is(err, ErrHealthTestFailed))
+	assert.Equal(t, stuck[:1+rct.Cutoff()-1], got, "the leading 1 and C-1 zeros")
+
+	n, err := r.Read(make([]byte, 8))
+	assert.Equal(t, 0, n)
+	assert.True(t, errors.Is(err, ErrHealthTestFailed))
+}
@@ -3,39 +3,33 @@
 package metrics
 
 import (
+	"strconv"
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 var (
-	// RequestsTotal counts the total number of entropy assessment requests,
-	// partitioned by test type (IID, Non-IID, or mixed).
-	RequestsTotal = promauto.NewCounterVec(
+	// GRPCRequestsTotal counts handled gRPC requests, partitioned by full
+	// method name and status code.
+	GRPCRequestsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "entropy_requests_total",
-			Help: "Total number of entropy assessment requests",
+			Name: "grpc_requests_total",
+			Help: "Total number of gRPC requests by method and status code",
 		},
-		[]string{"test_type"}, // iid or non_iid
+		[]string{"method", "code"},
 	)
 
-	// DurationSeconds measures the duration of entropy assessments.
-	DurationSeconds = promauto.NewHistogramVec(
+	// GRPCRequestDurationSeconds measures the handling time of gRPC requests,
+	// partitioned by full method name.
+	GRPCRequestDurationSeconds = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "entropy_duration_seconds",
-			Help:    "Duration of entropy assessment in seconds",
+			Name:    "grpc_request_duration_seconds",
+			Help:    "Duration of gRPC requests in seconds",
 			Buckets: prometheus.ExponentialBuckets(0.01, 2, 10), // 10ms to ~10s
 		},
-		[]string{"test_type"}, // iid or non_iid
-	)
-
-	// ErrorsTotal counts the total number of assessment errors, partitioned
-	// by test type and error classification.
-	ErrorsTotal = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "entropy_errors_total",
-			Help: "Total number of entropy assessment errors",
-		},
-		[]string{"test_type", "error_type"}, // test_type and error classification
+		[]string{"method"},
 	)
 
 	// DataSizeBytes tracks the size of data being assessed.
@@ -57,21 +51,57 @@ var (
 		},
 		[]string{"test_type"},
 	)
+
+	// EstimatorEntropyValue tracks the distribution of valid entropy
+	// estimates per estimator. Callers keep the estimator label bounded to
+	// the canonical estimator names.
+	EstimatorEntropyValue = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "entropy_estimator_entropy_value",
+			Help:    "Entropy estimates calculated by individual estimators",
+			Buckets: prometheus.LinearBuckets(0, 0.5, 17), // 0 to 8 in 0.5 increments
+		},
+		[]string{"test_type", "estimator"},
+	)
+
+	// AbandonedAssessmentsTotal counts in-process assessments that timed out
+	// while the underlying C++ computation kept running in the background.
+	AbandonedAssessmentsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "entropy_abandoned_assessments_total",
+			Help: "Total number of timed-out in-process assessments left running in the background",
+		},
+		[]string{"test_type"},
+	)
 )
 
-// RecordRequest increments the request counter for the given test type.
-func RecordRequest(testType string) {
-	RequestsTotal.WithLabelValues(testType).Inc()
-}
+// buildInfoOnce guards the registration of the entropy_build_info gauge,
+// whose labels are fixed when it is created.
+var buildInfoOnce sync.Once
 
-// RecordDuration records the duration of an entropy assessment in seconds.
-func RecordDuration(testType string, duration float64) {
-	DurationSeconds.WithLabelValues(testType).Observe(duration)
+// RegisterBuildInfo registers the entropy_build_info gauge, which is always 1
+// and carries the build of the running binary as constant labels. Only the
+// first call has an effect.
+func RegisterBuildInfo(version, commit, buildDate string, cgo bool) {
+	buildInfoOnce.Do(func() {
+		promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "entropy_build_info",
+			Help: "Build information of the running binary, always 1",
+			ConstLabels: prometheus.Labels{
+				"version":    version,
+				"commit":     commit,
+				"build_date": buildDate,
+				"cgo":        strconv.FormatBool(cgo),
+			},
+		}).Set(1)
+	})
 }
 
-// RecordError increments the error counter for the given test type and error classification.
-func RecordError(testType, errorType string) {
-	ErrorsTotal.WithLabelValues(testType, errorType).Inc()
+// RecordGRPCRequest counts a handled gRPC request and records its duration
+// in seconds.
+func RecordGRPCRequest(method, code string, duration float64) {
+	GRPCRequestsTotal.WithLabelValues(method, code).Inc()
+	GRPCRequestDurationSeconds.WithLabelValues(method).Observe(duration)
 }
 
 // RecordDataSize records the size of the data being assessed in bytes.
@@ -83,3 +113,13 @@ func RecordDataSize(testType string, sizeBytes int) {
 func RecordMinEntropy(testType string, value float64) {
 	MinEntropyValue.WithLabelValues(testType).Observe(value)
 }
+
+// RecordEstimatorEntropy records the entropy estimate of a single estimator.
+func RecordEstimatorEntropy(testType, estimator string, value float64) {
+	EstimatorEntropyValue.WithLabelValues(testType, estimator).Observe(value)
+}
+
+// RecordAbandonedAssessment increments the abandoned-work counter for the given test type.
+func RecordAbandonedAssessment(testType string) {
+	AbandonedAssessmentsTotal.WithLabelValues(testType).Inc()
+}
@@ -3,51 +3,24 @@ package metrics
 import (
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestRecordRequest(t *testing.T) {
-	// Reset the counter
-	RequestsTotal.Reset()
+func TestRecordGRPCRequest(t *testing.T) {
+	GRPCRequestsTotal.Reset()
+	GRPCRequestDurationSeconds.Reset()
 
-	RecordRequest("iid")
-	RecordRequest("iid")
-	RecordRequest("non_iid")
+	RecordGRPCRequest("/svc/Method", "OK", 0.5)
+	RecordGRPCRequest("/svc/Method", "OK", 1.5)
+	RecordGRPCRequest("/svc/Method", "InvalidArgument", 0.1)
 
-	// Verify counter values
-	iidCount := testutil.ToFloat64(RequestsTotal.WithLabelValues("iid"))
-	assert.Equal(t, 2.0, iidCount)
-
-	nonIIDCount := testutil.ToFloat64(RequestsTotal.WithLabelValues("non_iid"))
-	assert.Equal(t, 1.0, nonIIDCount)
-}
-
-func TestRecordDuration(t *testing.T) {
-	DurationSeconds.Reset()
-
-	RecordDuration("iid", 0.5)
-	RecordDuration("iid", 1.5)
-	RecordDuration("non_iid", 2.0)
-
-	// Just verify the function doesn't panic - histogram metrics are hard to test
-	// The fact that we got here means the function works
-	assert.True(t, true)
-}
-
-func TestRecordError(t *testing.T) {
-	ErrorsTotal.Reset()
-
-	RecordError("iid", "validation")
-	RecordError("iid", "validation")
-	RecordError("non_iid", "memory")
-
-	// Verify counter values
-	iidValidationCount := testutil.ToFloat64(ErrorsTotal.WithLabelValues("iid", "validation"))
-	assert.Equal(t, 2.0, iidValidationCount)
-
-	nonIIDMemoryCount := testutil.ToFloat64(ErrorsTotal.WithLabelValues("non_iid", "memory"))
-	assert.Equal(t, 1.0, nonIIDMemoryCount)
+	assert.Equal(t, 2.0, testutil.ToFloat64(GRPCRequestsTotal.WithLabelValues("/svc/Method", "OK")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(GRPCRequestsTotal.WithLabelValues("/svc/Method", "InvalidArgument")))
+	assert.Equal(t, 1, testutil.CollectAndCount(GRPCRequestDurationSeconds))
 }
 
 func TestRecordDataSize(t *testing.T) {
@@ -72,11 +45,60 @@ func TestRecordMinEntropy(t *testing.T) {
 	assert.True(t, true)
 }
 
+func TestRecordEstimatorEntropy(t *testing.T) {
+	EstimatorEntropyValue.Reset()
+
+	RecordEstimatorEntropy("IID", "Most Common Value", 7.6)
+	RecordEstimatorEntropy("Non-IID", "Markov Test", 6.7)
+	RecordEstimatorEntropy("Non-IID", "Markov Test", 6.9)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(EstimatorEntropyValue))
+}
+
+func TestRecordAbandonedAssessment(t *testing.T) {
+	AbandonedAssessmentsTotal.Reset()
+
+	RecordAbandonedAssessment("IID")
+	RecordAbandonedAssessment("IID")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(AbandonedAssessmentsTotal.WithLabelValues("IID")))
+}
+
+func TestRegisterBuildInfo(t *testing.T) {
+	RegisterBuildInfo("1.2.3", "abc1234", "2026-01-02T03:04:05Z", true)
+	RegisterBuildInfo("9.9.9", "other", "never", false) // ignored
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	var family *dto.MetricFamily
+	for _, f := range families {
+		if f.GetName() == "entropy_build_info" {
+			family = f
+		}
+	}
+	require.NotNil(t, family, "entropy_build_info not registered")
+	require.Len(t, family.GetMetric(), 1)
+
+	metric := family.GetMetric()[0]
+	labels := make(map[string]string)
+	for _, pair := range metric.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	assert.Equal(t, map[string]string{
+		"version":    "1.2.3",
+		"commit":     "abc1234",
+		"build_date": "2026-01-02T03:04:05Z",
+		"cgo":        "true",
+	}, labels)
+	assert.Equal(t, 1.0, metric.GetGauge().GetValue())
+}
+
 func TestMetricsInitialization(t *testing.T) {
 	// Verify that all metrics are properly initialized
-	assert.NotNil(t, RequestsTotal)
-	assert.NotNil(t, DurationSeconds)
-	assert.NotNil(t, ErrorsTotal)
+	assert.NotNil(t, GRPCRequestsTotal)
+	assert.NotNil(t, GRPCRequestDurationSeconds)
 	assert.NotNil(t, DataSizeBytes)
 	assert.NotNil(t, MinEntropyValue)
+	assert.NotNil(t, EstimatorEntropyValue)
+	assert.NotNil(t, AbandonedAssessmentsTotal)
 }
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/metrics"
+)
+
+// UnaryMetricsInterceptor returns a gRPC unary interceptor that counts every
+// request and records its duration, labelled with the full method name and
+// the status code returned by the handler.
+func UnaryMetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		metrics.RecordGRPCRequest(info.FullMethod, status.Code(err).String(), time.Since(start).Seconds())
+		return resp, err
+	}
+}
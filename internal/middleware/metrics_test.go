@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/metrics"
+)
+
+func durationSampleCount(t *testing.T, method string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	observer := metrics.GRPCRequestDurationSeconds.WithLabelValues(method)
+	require.NoError(t, observer.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestUnaryMetricsInterceptorRecordsSuccessfulCall(t *testing.T) {
+	const method = "/test.Service/Success"
+	metrics.GRPCRequestsTotal.Reset()
+	metrics.GRPCRequestDurationSeconds.Reset()
+
+	interceptor := UnaryMetricsInterceptor()
+	resp, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	assert.Equal(t, uint64(1), durationSampleCount(t, method))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GRPCRequestsTotal.WithLabelValues(method, "OK")))
+}
+
+func TestUnaryMetricsInterceptorRecordsErrorCode(t *testing.T) {
+	const method = "/test.Service/Failure"
+	metrics.GRPCRequestsTotal.Reset()
+	metrics.GRPCRequestDurationSeconds.Reset()
+
+	interceptor := UnaryMetricsInterceptor()
+	_, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.InvalidArgument, "bad request")
+		})
+	require.Error(t, err)
+
+	_, err = interceptor(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, errors.New("plain error")
+		})
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GRPCRequestsTotal.WithLabelValues(method, "InvalidArgument")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GRPCRequestsTotal.WithLabelValues(method, "Unknown")))
+	assert.Equal(t, uint64(2), durationSampleCount(t, method))
+}
@@ -14,9 +14,18 @@ type contextKey string
 
 const requestIDKey contextKey = "request_id"
 
-// UnaryRequestIDInterceptor returns a gRPC unary interceptor that generates a
-// UUID v4 request ID, stores it in the context, and sends it back to the client
-// via the "x-request-id" response header.
+// requestIDHeader is the metadata key carrying the request ID in both
+// directions.
+const requestIDHeader = "x-request-id"
+
+// maxRequestIDLength bounds the length of a client-supplied request ID.
+const maxRequestIDLength = 128
+
+// UnaryRequestIDInterceptor returns a gRPC unary interceptor that assigns each
+// request an ID, stores it in the context, and sends it back to the client via
+// the "x-request-id" response header. A well-formed "x-request-id" sent by the
+// client is reused so that the request can be correlated across services;
+// otherwise a UUID v4 is generated.
 func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
@@ -24,11 +33,14 @@ func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		requestID := uuid.New().String()
+		requestID, ok := incomingRequestID(ctx)
+		if !ok {
+			requestID = uuid.New().String()
+		}
 
 		ctx = context.WithValue(ctx, requestIDKey, requestID)
 
-		md := metadata.Pairs("x-request-id", requestID)
+		md := metadata.Pairs(requestIDHeader, requestID)
 		_ = grpc.SetHeader(ctx, md) // best effort; do not fail the request
 
 		return handler(ctx, req)
@@ -43,3 +55,27 @@ func GetRequestID(ctx context.Context) string {
 	}
 	return ""
 }
+
+// incomingRequestID returns the first "x-request-id" value of the incoming
+// metadata if it is well-formed: at most maxRequestIDLength bytes of visible
+// ASCII, so that it cannot break up or forge log lines.
+func incomingRequestID(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get(requestIDHeader)
+	if len(values) == 0 {
+		return "", false
+	}
+	id := values[0]
+	if id == "" || len(id) > maxRequestIDLength {
+		return "", false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return "", false
+		}
+	}
+	return id, true
+}
@@ -2,10 +2,14 @@ package middleware
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestUnaryRequestIDInterceptorSetsHeaderAndContext(t *testing.T) {
@@ -25,6 +29,63 @@ func TestUnaryRequestIDInterceptorSetsHeaderAndContext(t *testing.T) {
 	assert.NotEmpty(t, requestID)
 }
 
+// runInterceptor passes ctx through the interceptor and returns the request ID
+// the handler saw.
+func runInterceptor(t *testing.T, ctx context.Context) string {
+	t.Helper()
+	var got string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = GetRequestID(ctx)
+		return "ok", nil
+	}
+	_, err := UnaryRequestIDInterceptor()(ctx, "req", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+	require.NoError(t, err)
+	return got
+}
+
+// headerStream records the headers set through grpc.SetHeader.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "/test.Service/Method" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(metadata.MD) error { return nil }
+
+func TestUnaryRequestIDInterceptorReusesIncomingID(t *testing.T) {
+	stream := &headerStream{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "trace-42:abc"))
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+	assert.Equal(t, "trace-42:abc", runInterceptor(t, ctx))
+	assert.Equal(t, []string{"trace-42:abc"}, stream.header.Get("x-request-id"))
+}
+
+func TestUnaryRequestIDInterceptorGeneratesWhenMissing(t *testing.T) {
+	for name, ctx := range map[string]context.Context{
+		"no metadata": context.Background(),
+		"no header":   metadata.NewIncomingContext(context.Background(), metadata.Pairs("other", "x")),
+		"empty":       metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "")),
+		"too long":    metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", strings.Repeat("a", 129))),
+		"newline":     metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "id\nforged")),
+		"space":       metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "a b")),
+	} {
+		id := runInterceptor(t, ctx)
+		_, err := uuid.Parse(id)
+		assert.NoError(t, err, name)
+	}
+
+	long := strings.Repeat("a", 128)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", long))
+	assert.Equal(t, long, runInterceptor(t, ctx))
+}
+
 func TestGetRequestIDMissing(t *testing.T) {
 	assert.Equal(t, "", GetRequestID(context.Background()))
 }
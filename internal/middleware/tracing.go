@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// TracerName is the instrumentation scope of the spans started by
+// UnaryTracingInterceptor.
+const TracerName = "github.com/AmmannChristian/nist-800-90b/internal/middleware"
+
+// assessmentRequest is implemented by the assessment request messages; it
+// lets the interceptor name the test type without importing pkg/pb.
+type assessmentRequest interface {
+	GetIidMode() bool
+	GetNonIidMode() bool
+	GetIidCheckOnly() bool
+}
+
+// UnaryTracingInterceptor returns a gRPC unary interceptor that wraps each
+// call in a server span of the global OpenTelemetry tracer provider, which is
+// a no-op until one is installed with otel.SetTracerProvider. The span is
+// named after the full method and carries the request ID; assessment requests
+// add their test type and, when the samples are sent inline, their count. A
+// failed call records the error and its gRPC status code on the span.
+//
+// It must run after UnaryRequestIDInterceptor so that the request ID is set.
+func UnaryTracingInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, span := otel.GetTracerProvider().Tracer(TracerName).Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(requestAttributes(ctx, req)...),
+		)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			st := status.Convert(err)
+			span.SetAttributes(attribute.String("grpc.status_code", st.Code().String()))
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, st.Message())
+		}
+		return resp, err
+	}
+}
+
+// requestAttributes returns the span attributes describing req.
+func requestAttributes(ctx context.Context, req interface{}) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("request_id", GetRequestID(ctx))}
+	if r, ok := req.(assessmentRequest); ok {
+		attrs = append(attrs, attribute.String("test_type", testTypeOf(r)))
+	}
+	if r, ok := req.(interface{ GetData() []byte }); ok {
+		attrs = append(attrs, attribute.Int("sample_count", len(r.GetData())))
+	}
+	return attrs
+}
+
+// testTypeOf names the assessment r asks for.
+func testTypeOf(r assessmentRequest) string {
+	switch {
+	case r.GetIidCheckOnly():
+		return "IID check"
+	case r.GetIidMode() && r.GetNonIidMode():
+		return "IID and Non-IID"
+	case r.GetIidMode():
+		return "IID"
+	case r.GetNonIidMode():
+		return "Non-IID"
+	default:
+		return "none"
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in memory
+// for the duration of the test.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = tp.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// traceCall runs req through the request ID and tracing interceptors.
+func traceCall(ctx context.Context, req interface{}, handler grpc.UnaryHandler) error {
+	info := &grpc.UnaryServerInfo{FullMethod: "/nist.sp800_90b.v1.Sp80090bAssessmentService/AssessEntropy"}
+	tracing := UnaryTracingInterceptor()
+	_, err := UnaryRequestIDInterceptor()(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return tracing(ctx, req, info, handler)
+	})
+	return err
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestUnaryTracingInterceptorRecordsSpan(t *testing.T) {
+	exporter := recordSpans(t)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "trace-1"))
+	req := &pb.Sp80090BAssessmentRequest{Data: make([]byte, 1000), BitsPerSymbol: 8, NonIidMode: true}
+	var handlerSpan trace.SpanContext
+	err := traceCall(ctx, req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return "ok", nil
+	})
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "/nist.sp800_90b.v1.Sp80090bAssessmentService/AssessEntropy", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, otelcodes.Unset, span.Status.Code)
+	assert.Equal(t, span.SpanContext.SpanID(), handlerSpan.SpanID(), "the handler runs inside the span")
+
+	attrs := spanAttributes(span)
+	assert.Equal(t, "trace-1", attrs["request_id"].AsString())
+	assert.Equal(t, "Non-IID", attrs["test_type"].AsString())
+	assert.Equal(t, int64(1000), attrs["sample_count"].AsInt64())
+}
+
+func TestUnaryTracingInterceptorRecordsError(t *testing.T) {
+	exporter := recordSpans(t)
+
+	req := &pb.Sp80090BFileAssessmentRequest{Path: "a.bin", IidMode: true, NonIidMode: true}
+	err := traceCall(context.Background(), req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.InvalidArgument, "bad request")
+	})
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, otelcodes.Error, span.Status.Code)
+	assert.Equal(t, "bad request", span.Status.Description)
+	require.Len(t, span.Events, 1)
+	assert.Equal(t, "exception", span.Events[0].Name)
+
+	attrs := spanAttributes(span)
+	assert.NotEmpty(t, attrs["request_id"].AsString())
+	assert.Equal(t, "IID and Non-IID", attrs["test_type"].AsString())
+	assert.Equal(t, "InvalidArgument", attrs["grpc.status_code"].AsString())
+	_, ok := attrs["sample_count"]
+	assert.False(t, ok, "file requests carry no inline samples")
+}
+
+func TestTestTypeOf(t *testing.T) {
+	assert.Equal(t, "IID", testTypeOf(&pb.Sp80090BAssessmentRequest{IidMode: true}))
+	assert.Equal(t, "IID check", testTypeOf(&pb.Sp80090BAssessmentRequest{IidCheckOnly: true}))
+	assert.Equal(t, "none", testTypeOf(&pb.Sp80090BAssessmentRequest{}))
+}
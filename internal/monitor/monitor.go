@@ -0,0 +1,229 @@
+// Package monitor watches a live noise source. It runs the continuous health
+// tests of SP 800-90B Section 4.4 on every sample and periodically assesses a
+// window of consecutive samples, reporting both as events on a channel.
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/health"
+)
+
+// DefaultWindow is the number of samples per assessment window when
+// Config.Window is zero, the minimum SP 800-90B recommends for an assessment.
+const DefaultWindow = entropy.MinRecommendedSamples
+
+// maxPending is the number of windows that may be under or awaiting
+// assessment at the same time.
+const maxPending = 2
+
+// ErrInvalidConfig is returned by Run for a Config it cannot use.
+var ErrInvalidConfig = errors.New("invalid monitor configuration")
+
+// EventType distinguishes the events delivered by a Monitor.
+type EventType int
+
+const (
+	// HealthFailure reports a sample that made a health test fail.
+	HealthFailure EventType = iota + 1
+	// AssessmentResult reports the assessment of a window.
+	AssessmentResult
+)
+
+func (t EventType) String() string {
+	switch t {
+	case HealthFailure:
+		return "health failure"
+	case AssessmentResult:
+		return "assessment result"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+// Event is delivered on the channel returned by Monitor.Events.
+type Event struct {
+	Type    EventType
+	Window  uint64               // Zero-based index of the window the event belongs to
+	Failure *health.FailureError // HealthFailure: the failing sample
+	Result  *entropy.Result      // AssessmentResult: the result, nil when Err is set
+	Err     error                // AssessmentResult: the assessment error
+}
+
+// AssessFunc assesses one window of samples.
+type AssessFunc func(ctx context.Context, data []byte, bitsPerSymbol int) (*entropy.Result, error)
+
+// Config describes the source and how often it is assessed.
+type Config struct {
+	TestType     entropy.TestType // Assessment type of the default AssessFunc
+	Bits         int              // Bits per sample, 1 to 8
+	H            float64          // Claimed min-entropy per sample for the health tests
+	Alpha        float64          // Health test false positive probability, 0 for health.DefaultAlpha
+	HealthWindow int              // Adaptive Proportion Test window, 0 for the standard size
+	Window       int              // Samples per assessment window, 0 for DefaultWindow
+	Every        int              // Assess every Nth window starting with the first, 0 for every window
+	Assess       AssessFunc       // Nil for a quiet entropy.Assessment of TestType
+}
+
+// Monitor runs health tests and windowed assessments over a stream.
+type Monitor struct {
+	events  chan Event
+	windows atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// New returns a Monitor whose events are delivered on Events.
+func New() *Monitor {
+	return &Monitor{events: make(chan Event, 16)}
+}
+
+// Events returns the channel on which events are delivered. It is closed when
+// Run returns.
+func (m *Monitor) Events() <-chan Event {
+	return m.events
+}
+
+// Windows returns the number of complete windows read so far.
+func (m *Monitor) Windows() uint64 {
+	return m.windows.Load()
+}
+
+// Dropped returns the number of windows that were due for assessment but
+// skipped because the previous assessments had not finished.
+func (m *Monitor) Dropped() uint64 {
+	return m.dropped.Load()
+}
+
+// window is a complete window handed to the assessment worker.
+type window struct {
+	index uint64
+	data  []byte
+}
+
+// Run reads samples from r until the end of the stream, a read error or the
+// cancellation of ctx, which takes effect once a pending Read returns. Every
+// sample is fed to the health tests; each failing run or window is reported
+// once. Samples are collected into windows of cfg.Window samples, and every
+// cfg.Every-th window is assessed in the background. At most two windows are
+// under or awaiting assessment; windows due beyond that are dropped whole and
+// counted by Dropped, so a slow assessment never stalls the source or sees
+// partial data. A trailing partial window is never assessed.
+// Run waits for pending assessments, closes the Events channel and returns
+// the read error or ctx.Err(). It may only be called once.
+func (m *Monitor) Run(ctx context.Context, r io.Reader, cfg Config) error {
+	defer close(m.events)
+
+	size, every, assess, err := cfg.resolve()
+	if err != nil {
+		return err
+	}
+	tests, err := health.NewMonitor(health.Config{H: cfg.H, Alpha: cfg.Alpha, Bits: cfg.Bits, Window: cfg.HealthWindow})
+	if err != nil {
+		return err
+	}
+
+	queue := make(chan window, maxPending)
+	var pending atomic.Int32
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for w := range queue {
+			res, err := assess(ctx, w.data, cfg.Bits)
+			m.send(ctx, Event{Type: AssessmentResult, Window: w.index, Result: res, Err: err})
+			pending.Add(-1)
+		}
+	}()
+	defer func() {
+		close(queue)
+		wg.Wait()
+	}()
+
+	buf := make([]byte, 32*1024)
+	current := make([]byte, 0, size)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, readErr := r.Read(buf)
+		for _, b := range buf[:n] {
+			if err := tests.Feed(b); err != nil {
+				var failure *health.FailureError
+				if errors.As(err, &failure) && failure.Count == failure.Cutoff {
+					m.send(ctx, Event{Type: HealthFailure, Window: failure.Index / uint64(size), Failure: failure})
+				}
+			}
+			current = append(current, b)
+			if len(current) < size {
+				continue
+			}
+
+			index := m.windows.Add(1) - 1
+			if index%uint64(every) != 0 {
+				current = current[:0]
+				continue
+			}
+			if pending.Load() < maxPending {
+				pending.Add(1)
+				queue <- window{index: index, data: current}
+				current = make([]byte, 0, size)
+			} else {
+				m.dropped.Add(1)
+				current = current[:0]
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// send delivers ev unless ctx is cancelled first.
+func (m *Monitor) send(ctx context.Context, ev Event) {
+	select {
+	case m.events <- ev:
+	case <-ctx.Done():
+	}
+}
+
+// resolve validates cfg and fills in its defaults.
+func (cfg Config) resolve() (size, every int, assess AssessFunc, err error) {
+	size, every, assess = cfg.Window, cfg.Every, cfg.Assess
+	if size == 0 {
+		size = DefaultWindow
+	}
+	if every == 0 {
+		every = 1
+	}
+	if size < 0 || every < 0 {
+		return 0, 0, nil, fmt.Errorf("%w: window and every must not be negative, got %d and %d", ErrInvalidConfig, cfg.Window, cfg.Every)
+	}
+	if assess == nil {
+		if cfg.TestType != entropy.IID && cfg.TestType != entropy.NonIID {
+			return 0, 0, nil, fmt.Errorf("%w: unknown test type %d", ErrInvalidConfig, int(cfg.TestType))
+		}
+		assess = defaultAssess(cfg.TestType)
+	}
+	return size, every, assess, nil
+}
+
+// defaultAssess runs a quiet assessment of testType with default settings.
+func defaultAssess(testType entropy.TestType) AssessFunc {
+	return func(ctx context.Context, data []byte, bitsPerSymbol int) (*entropy.Result, error) {
+		assessment := entropy.NewAssessment()
+		assessment.SetVerbose(0)
+		if testType == entropy.IID {
+			return assessment.AssessIIDContext(ctx, data, bitsPerSymbol)
+		}
+		return assessment.AssessNonIIDContext(ctx, data, bitsPerSymbol)
+	}
+}
@@ -0,0 +1,196 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/health"
+)
+
+// samples returns n samples that pass the health tests for H = 4.
+func samples(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7 % 251)
+	}
+	return data
+}
+
+// collect runs m over r and returns its events and the error of Run.
+func collect(t *testing.T, m *Monitor, r io.Reader, cfg Config) ([]Event, error) {
+	t.Helper()
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err = m.Run(context.Background(), r, cfg)
+	}()
+	var events []Event
+	for ev := range m.Events() {
+		events = append(events, ev)
+	}
+	<-done
+	return events, err
+}
+
+// sizeAssess reports the length of each window as its min-entropy.
+func sizeAssess(_ context.Context, data []byte, _ int) (*entropy.Result, error) {
+	return &entropy.Result{MinEntropy: float64(len(data))}, nil
+}
+
+// pacedReader returns one chunk per Read and, after a chunk that completes
+// an assessed window, waits until that window has been assessed.
+type pacedReader struct {
+	chunks   [][]byte
+	every    int
+	read     int
+	assessed <-chan struct{}
+}
+
+func (p *pacedReader) Read(b []byte) (int, error) {
+	if p.read == len(p.chunks) {
+		return 0, io.EOF
+	}
+	if p.read > 0 && (p.read-1)%p.every == 0 {
+		<-p.assessed
+	}
+	n := copy(b, p.chunks[p.read])
+	p.read++
+	return n, nil
+}
+
+func TestRun_AssessesEveryNthWindow(t *testing.T) {
+	data := samples(10500)
+	var chunks [][]byte
+	for start := 0; start < len(data); start += 1000 {
+		chunks = append(chunks, data[start:min(start+1000, len(data))])
+	}
+	assessed := make(chan struct{}, len(chunks))
+	assess := func(ctx context.Context, data []byte, bits int) (*entropy.Result, error) {
+		defer func() { assessed <- struct{}{} }()
+		return sizeAssess(ctx, data, bits)
+	}
+
+	m := New()
+	events, err := collect(t, m, &pacedReader{chunks: chunks, every: 3, assessed: assessed}, Config{
+		Bits: 8, H: 4, Window: 1000, Every: 3, Assess: assess,
+	})
+	require.NoError(t, err)
+
+	var windows []uint64
+	for _, ev := range events {
+		require.Equal(t, AssessmentResult, ev.Type)
+		require.NoError(t, ev.Err)
+		assert.Equal(t, 1000.0, ev.Result.MinEntropy)
+		windows = append(windows, ev.Window)
+	}
+	assert.Equal(t, []uint64{0, 3, 6, 9}, windows)
+	assert.Equal(t, uint64(10), m.Windows())
+	assert.Equal(t, uint64(0), m.Dropped())
+}
+
+func TestRun_ReportsHealthFailureOnce(t *testing.T) {
+	data := samples(3000)
+	for i := 1500; i < 1600; i++ {
+		data[i] = 0x42
+	}
+	m := New()
+	events, err := collect(t, m, bytes.NewReader(data), Config{Bits: 8, H: 4, Window: 1000, Every: 100, Assess: sizeAssess})
+	require.NoError(t, err)
+
+	// The assessment of window 0 and the failure are reported concurrently.
+	require.Len(t, events, 2)
+	var failures []Event
+	for _, ev := range events {
+		if ev.Type == HealthFailure {
+			failures = append(failures, ev)
+		}
+	}
+	require.Len(t, failures, 1)
+	assert.Equal(t, uint64(1), failures[0].Window)
+	assert.Equal(t, "repetition count test", failures[0].Failure.Test)
+	assert.Equal(t, uint64(1500+6-1), failures[0].Failure.Index, "cutoff 1+ceil(20/4) = 6")
+}
+
+// gatedReader returns the head at once and waits for gate before the tail.
+type gatedReader struct {
+	head, tail io.Reader
+	gate       <-chan struct{}
+	once       sync.Once
+}
+
+func (g *gatedReader) Read(p []byte) (int, error) {
+	n, err := g.head.Read(p)
+	if n > 0 || !errors.Is(err, io.EOF) {
+		return n, err
+	}
+	g.once.Do(func() { <-g.gate })
+	return g.tail.Read(p)
+}
+
+func TestRun_DropsWholeWindowsUnderBackpressure(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	blockingAssess := func(_ context.Context, data []byte, _ int) (*entropy.Result, error) {
+		once.Do(func() {
+			close(started)
+			<-release
+		})
+		return sizeAssess(nil, data, 8)
+	}
+
+	// Window 0 blocks the worker, window 1 waits in the queue and windows 2
+	// to 4 are dropped.
+	data := samples(5000)
+	r := &gatedReader{head: bytes.NewReader(data[:1000]), tail: bytes.NewReader(data[1000:]), gate: started}
+	m := New()
+	done := make(chan error)
+	go func() {
+		done <- m.Run(context.Background(), r, Config{Bits: 8, H: 4, Window: 1000, Assess: blockingAssess})
+	}()
+
+	require.Eventually(t, func() bool { return m.Windows() == 5 }, 5*time.Second, time.Millisecond)
+	close(release)
+
+	var windows []uint64
+	for ev := range m.Events() {
+		assert.Equal(t, 1000.0, ev.Result.MinEntropy, "windows are never partial")
+		windows = append(windows, ev.Window)
+	}
+	require.NoError(t, <-done)
+	assert.Equal(t, []uint64{0, 1}, windows)
+	assert.Equal(t, uint64(3), m.Dropped())
+}
+
+func TestRun_Errors(t *testing.T) {
+	readErr := errors.New("device unplugged")
+	_, err := collect(t, New(), iotest.ErrReader(readErr), Config{Bits: 8, H: 4, Assess: sizeAssess})
+	assert.Equal(t, readErr, err)
+
+	_, err = collect(t, New(), bytes.NewReader(nil), Config{Bits: 8, H: 4, Window: -1})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+	_, err = collect(t, New(), bytes.NewReader(nil), Config{Bits: 8, H: 4, TestType: entropy.TestType(7)})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+	_, err = collect(t, New(), bytes.NewReader(nil), Config{Bits: 1, H: 4})
+	assert.True(t, errors.Is(err, health.ErrInvalidEntropy))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m := New()
+	assert.Equal(t, context.Canceled, m.Run(ctx, bytes.NewReader(samples(10)), Config{Bits: 8, H: 4, Assess: sizeAssess}))
+	_, open := <-m.Events()
+	assert.False(t, open)
+}
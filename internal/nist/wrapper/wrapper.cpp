@@ -12,9 +12,13 @@
 
 #include "wrapper.h"
 
-#include <cstring> // memcpy, strcpy
+#include <cstring> // memcpy, memset, strcpy
 #include <cstdlib> // malloc, free
+#include <climits> // LONG_MAX
 #include <exception>
+#include <new>       // std::bad_alloc
+#include <stdexcept> // std::logic_error, std::runtime_error
+#include <string>
 
 #include "../cpp/shared/utils.h"
 #include "../cpp/shared/most_common.h"
@@ -55,6 +59,38 @@ private:
     bool released_;
 };
 
+/**
+ * @brief Reports the phases of an assessment through the caller's
+ *        ProgressCallback, counting those already completed. A NULL
+ *        callback disables reporting.
+ */
+class ProgressReporter {
+public:
+    ProgressReporter(ProgressCallback callback, uintptr_t user_data, int total)
+        : callback_(callback), user_data_(user_data), total_(total), completed_(0) {}
+
+    // Reports that phase starts, which completes the previous one.
+    void start(const char* phase) {
+        if (callback_) {
+            callback_(phase, total_ > 0 ? 100.0 * completed_ / total_ : 0.0, user_data_);
+        }
+        completed_++;
+    }
+
+    // Reports the end of the assessment.
+    void done() {
+        if (callback_) {
+            callback_(PROGRESS_PHASE_DONE, 100.0, user_data_);
+        }
+    }
+
+private:
+    ProgressCallback callback_;
+    uintptr_t user_data_;
+    int total_;
+    int completed_;
+};
+
 extern "C" {
 
 // Allocates and zero-initializes an EntropyResult on the heap.
@@ -66,13 +102,24 @@ static EntropyResult* create_result() {
         result->h_bitstring = 0.0;
         result->h_assessed = 0.0;
         result->data_word_size = 0;
-        result->error_code = 0;
+        result->error_code = WRAPPER_OK;
         result->error_message[0] = '\0';
         result->estimator_count = 0;
+        memset(result->histogram, 0, sizeof(result->histogram));
+        result->raw_json = NULL;
     }
     return result;
 }
 
+// Returns a malloc'd, NUL-terminated copy of json, or NULL if out of memory.
+static char* copy_json(const std::string& json) {
+    char* copy = (char*)malloc(json.size() + 1);
+    if (copy) {
+        memcpy(copy, json.c_str(), json.size() + 1);
+    }
+    return copy;
+}
+
 // Appends an estimator entry with a valid entropy value to the result array.
 static void add_estimator(EntropyResult* result, const char* name, double entropy, bool passed) {
     if (result->estimator_count >= MAX_ESTIMATORS) return;
@@ -95,6 +142,34 @@ static void add_test_result(EntropyResult* result, const char* name, bool passed
     est->is_entropy_valid = false;
 }
 
+// Reports whether the estimator identified by bit is enabled in mask.
+static bool selected(uint32_t mask, uint32_t bit) {
+    return mask == ESTIMATOR_ALL || (mask & bit) != 0;
+}
+
+// Estimator bits of the progress phases of each test type, in execution
+// order. The t-Tuple and LRS estimates share one phase, as they share one
+// suffix-array pass.
+static const uint32_t iid_phases[] = {
+    ESTIMATOR_MCV, ESTIMATOR_CHI_SQUARE, ESTIMATOR_LRS, ESTIMATOR_PERMUTATION,
+};
+static const uint32_t non_iid_phases[] = {
+    ESTIMATOR_MCV, ESTIMATOR_COLLISION, ESTIMATOR_MARKOV, ESTIMATOR_COMPRESSION,
+    ESTIMATOR_T_TUPLE | ESTIMATOR_LRS, ESTIMATOR_MULTI_MCW, ESTIMATOR_LAG,
+    ESTIMATOR_MULTI_MMC, ESTIMATOR_LZ78Y,
+};
+
+// Counts the phases of phases[0..n) that mask selects.
+static int count_phases(uint32_t mask, const uint32_t* phases, size_t n) {
+    int count = 0;
+    for (size_t i = 0; i < n; i++) {
+        if (selected(mask, phases[i])) {
+            count++;
+        }
+    }
+    return count;
+}
+
 // Records an error code and message in the result structure.
 static void set_error(EntropyResult* result, int code, const char* message) {
     result->error_code = code;
@@ -102,17 +177,91 @@ static void set_error(EntropyResult* result, int code, const char* message) {
     result->error_message[sizeof(result->error_message) - 1] = '\0';
 }
 
+// Records the exception being handled in the result structure, with the
+// error code of its type. Must be called from within a catch block.
+static void set_exception_error(EntropyResult* result) {
+    try {
+        throw;
+    } catch (const std::bad_alloc& e) {
+        set_error(result, WRAPPER_ERROR_OUT_OF_MEMORY, (std::string("Out of memory: ") + e.what()).c_str());
+    } catch (const std::logic_error& e) {
+        set_error(result, WRAPPER_ERROR_LOGIC, (std::string("Logic error: ") + e.what()).c_str());
+    } catch (const std::runtime_error& e) {
+        set_error(result, WRAPPER_ERROR_RUNTIME, (std::string("Runtime error: ") + e.what()).c_str());
+    } catch (const std::exception& e) {
+        set_error(result, WRAPPER_ERROR_EXCEPTION, (std::string("Exception: ") + e.what()).c_str());
+    } catch (...) {
+        set_error(result, WRAPPER_ERROR_UNKNOWN, "Unknown exception occurred");
+    }
+}
+
+#ifdef WRAPPER_FAULT_INJECTION
+// First sample bytes that make a fault-injection build fail on purpose:
+// 0xFA reports an error through a malformed message buffer, 0xFB to 0xFE
+// throw a std::bad_alloc, std::logic_error, std::runtime_error and a
+// non-standard exception.
+#define FAULT_INJECTION_SENTINEL 0xFA
+#define FAULT_INJECTION_BAD_ALLOC 0xFB
+#define FAULT_INJECTION_LOGIC_ERROR 0xFC
+#define FAULT_INJECTION_RUNTIME_ERROR 0xFD
+#define FAULT_INJECTION_UNKNOWN 0xFE
+
+// Fills the error message buffer with line breaks and ANSI escapes and
+// leaves it without a terminating NUL, standing in for a wrapper bug. Only
+// compiled with -DWRAPPER_FAULT_INJECTION, for the bridge's bounds tests.
+static void inject_malformed_error(EntropyResult* result) {
+    static const char pattern[] = "injected fault\n\x1b[31mred\x1b[0m\r\t";
+    result->error_code = WRAPPER_ERROR_INVALID_INPUT;
+    for (size_t i = 0; i < sizeof(result->error_message); i++) {
+        result->error_message[i] = pattern[i % (sizeof(pattern) - 1)];
+    }
+}
+
+// Injects the fault selected by the first sample byte. Returns true when
+// the result already holds an error; exceptions propagate to the caller's
+// handler as they would from the NIST code.
+static bool inject_fault(EntropyResult* result, uint8_t sentinel) {
+    switch (sentinel) {
+    case FAULT_INJECTION_SENTINEL:
+        inject_malformed_error(result);
+        return true;
+    case FAULT_INJECTION_BAD_ALLOC:
+        throw std::bad_alloc();
+    case FAULT_INJECTION_LOGIC_ERROR:
+        throw std::out_of_range("injected logic_error");
+    case FAULT_INJECTION_RUNTIME_ERROR:
+        throw std::runtime_error("injected runtime_error");
+    case FAULT_INJECTION_UNKNOWN:
+        throw 42;
+    default:
+        return false;
+    }
+}
+#endif
+
 /**
  * @brief Initializes a NIST data_t structure from raw sample bytes.
  *
  * Handles word-size auto-detection when bits_per_symbol is 0, builds the
- * symbol alphabet mapping, and constructs the bitstring representation
- * required by several Non-IID estimators.
+ * symbol alphabet mapping and the symbol histogram, and constructs the
+ * bitstring representation required by several Non-IID estimators, expanding
+ * each symbol in the given bit_order.
+ *
+ * The caller's buffer is read exactly once, by the memcpy of length bytes
+ * below, and never retained: every estimator works on the copies in dp. The
+ * Go bridge relies on this to keep the buffer pinned only for the call.
  *
- * @return true on success; false if memory allocation fails (error is
- *         recorded in result).
+ * @return true on success; false if length is out of range or memory
+ *         allocation fails (error is recorded in result).
  */
-static bool prepare_data(data_t* dp, const uint8_t* data, size_t length, int bits_per_symbol, EntropyResult* result) {
+static bool prepare_data(data_t* dp, const uint8_t* data, size_t length, int bits_per_symbol, int bit_order, EntropyResult* result) {
+    // The bitstring holds up to 8 entries per sample and is indexed with a
+    // long, as is the sample array.
+    if (length > (size_t)(LONG_MAX / 8)) {
+        set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid input: length exceeds LONG_MAX / 8 samples");
+        return false;
+    }
+
     dp->word_size = bits_per_symbol;
     dp->len = (long)length;
     dp->symbols = NULL;
@@ -127,17 +276,18 @@ static bool prepare_data(data_t* dp, const uint8_t* data, size_t length, int bit
     dp->rawsymbols = (uint8_t*)malloc(sizeof(uint8_t) * dp->len);
 
     if (!dp->symbols || !dp->rawsymbols) {
-        set_error(result, -1, "Failed to allocate memory for symbols");
+        set_error(result, WRAPPER_ERROR_OUT_OF_MEMORY, "Failed to allocate memory for symbols");
         if (dp->symbols) free(dp->symbols);
         if (dp->rawsymbols) free(dp->rawsymbols);
         return false;
     }
 
-    // Copy data
-    memcpy(dp->symbols, data, dp->len);
-    memcpy(dp->rawsymbols, data, dp->len);
+    // Copy data: the only access to the caller's buffer.
+    memcpy(dp->symbols, data, length);
+    memcpy(dp->rawsymbols, dp->symbols, length);
 
-    // Auto-detect word size if needed
+    // Auto-detect word size if needed: the position of the highest bit set
+    // in any sample, as in read_file_subset of the reference tool.
     if (dp->word_size == 0) {
         uint8_t datamask = 0;
         for (long i = 0; i < dp->len; i++) {
@@ -146,14 +296,21 @@ static bool prepare_data(data_t* dp, const uint8_t* data, size_t length, int bit
 
         uint8_t curbit = 0x80;
         int detected_size = 8;
-        for (int i = 8; i > 0 && (datamask & curbit) == 0; i--) {
+        while (detected_size > 1 && (datamask & curbit) == 0) {
             curbit >>= 1;
-            detected_size = i - 1;
+            detected_size--;
         }
-        dp->word_size = (detected_size == -1) ? 1 : detected_size + 1;
+        dp->word_size = detected_size;
     }
 
-    // Validate symbol width (max 8 bits = 256 symbols)
+    // Validate symbol width (max 8 bits = 256 symbols). Masked symbols then
+    // stay within the 256-entry histogram and mapping table.
+    if (dp->word_size < 1 || dp->word_size > 8) {
+        set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid word size: must be 1-8");
+        free(dp->symbols);
+        free(dp->rawsymbols);
+        return false;
+    }
     int max_symbols = 1 << dp->word_size;
     int mask = max_symbols - 1;
 
@@ -166,6 +323,7 @@ static bool prepare_data(data_t* dp, const uint8_t* data, size_t length, int bit
 
     for (long i = 0; i < dp->len; i++) {
         dp->symbols[i] &= mask;
+        result->histogram[dp->symbols[i]]++;
         if (dp->symbols[i] > dp->maxsymbol) {
             dp->maxsymbol = dp->symbols[i];
         }
@@ -189,7 +347,7 @@ static bool prepare_data(data_t* dp, const uint8_t* data, size_t length, int bit
     } else {
         dp->bsymbols = (uint8_t*)malloc(dp->blen);
         if (!dp->bsymbols) {
-            set_error(result, -1, "Failed to allocate memory for bitstring");
+            set_error(result, WRAPPER_ERROR_OUT_OF_MEMORY, "Failed to allocate memory for bitstring");
             free(dp->symbols);
             free(dp->rawsymbols);
             return false;
@@ -198,8 +356,8 @@ static bool prepare_data(data_t* dp, const uint8_t* data, size_t length, int bit
         for (long i = 0; i < dp->len; i++) {
             uint8_t raw = dp->rawsymbols[i] & mask;
             for (int j = 0; j < dp->word_size; j++) {
-                dp->bsymbols[i * dp->word_size + j] =
-                    (raw >> (dp->word_size - 1 - j)) & 0x1;
+                int shift = (bit_order == BIT_ORDER_LSB_FIRST) ? j : dp->word_size - 1 - j;
+                dp->bsymbols[i * dp->word_size + j] = (raw >> shift) & 0x1;
             }
         }
     }
@@ -219,7 +377,11 @@ EntropyResult* calculate_iid_entropy(
     size_t length,
     int bits_per_symbol,
     bool is_binary,
-    int verbose
+    int verbose,
+    uint32_t estimator_mask,
+    int bit_order,
+    ProgressCallback progress_callback,
+    uintptr_t progress_data
 ) {
     EntropyResult* result = create_result();
     if (!result) {
@@ -229,54 +391,86 @@ EntropyResult* calculate_iid_entropy(
     try {
         // Validate input
         if (!data || length == 0) {
-            set_error(result, -1, "Invalid input: data is NULL or empty");
+            set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid input: data is NULL or empty");
+            return result;
+        }
+
+#ifdef WRAPPER_FAULT_INJECTION
+        if (inject_fault(result, data[0])) {
             return result;
         }
+#endif
 
         if (bits_per_symbol < 0 || bits_per_symbol > 8) {
-            set_error(result, -1, "Invalid bits_per_symbol: must be 0-8");
+            set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid bits_per_symbol: must be 0-8");
+            return result;
+        }
+
+        if (bit_order != BIT_ORDER_MSB_FIRST && bit_order != BIT_ORDER_LSB_FIRST) {
+            set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid bit_order: must be BIT_ORDER_MSB_FIRST or BIT_ORDER_LSB_FIRST");
             return result;
         }
 
         // Prepare data structure
         data_t dp;
-        if (!prepare_data(&dp, data, length, bits_per_symbol, result)) {
+        if (!prepare_data(&dp, data, length, bits_per_symbol, bit_order, result)) {
             return result;
         }
         DataGuard guard(&dp);  // RAII: ensures free_data() on any exit path
 
         // Check alphabet size
         if (dp.alph_size <= 1) {
-            set_error(result, -1, "Symbol alphabet consists of 1 symbol. No entropy awarded.");
+            set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Symbol alphabet consists of 1 symbol. No entropy awarded.");
             return result;
         }
 
+        ProgressReporter progress(progress_callback, progress_data,
+                                  count_phases(estimator_mask, iid_phases, sizeof(iid_phases) / sizeof(iid_phases[0])));
+
         // Calculate entropy estimates
         double H_original = dp.word_size;
         double H_bitstring = 1.0;
+        IidTestCase tc;
+        tc.binary = (dp.alph_size == 2);
 
         // Most Common Value estimate
-        H_original = most_common(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
-        add_estimator(result, "Most Common Value", H_original, true);
+        if (selected(estimator_mask, ESTIMATOR_MCV)) {
+            progress.start("Most Common Value");
+            H_original = most_common(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
+            add_estimator(result, "Most Common Value", H_original, true);
 
-        if (dp.alph_size > 2) {
-            H_bitstring = most_common(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
+            if (dp.alph_size > 2) {
+                H_bitstring = most_common(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
+            }
         }
 
         // Chi-square tests
-        bool chi_square_pass = chi_square_tests(dp.symbols, dp.len, dp.alph_size, verbose);
-        add_test_result(result, "Chi-Square Tests", chi_square_pass);
+        if (selected(estimator_mask, ESTIMATOR_CHI_SQUARE)) {
+            progress.start("Chi-Square Tests");
+            bool chi_square_pass = chi_square_tests(dp.symbols, dp.len, dp.alph_size, verbose);
+            tc.passed_chi_square_tests = chi_square_pass;
+            add_test_result(result, "Chi-Square Tests", chi_square_pass);
+        }
 
         // LRS test
-        bool lrs_pass = len_LRS_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
-        add_test_result(result, "Length of Longest Repeated Substring Test", lrs_pass);
+        if (selected(estimator_mask, ESTIMATOR_LRS)) {
+            progress.start("Length of Longest Repeated Substring Test");
+            bool lrs_pass = len_LRS_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
+            tc.passed_longest_repeated_substring_test = lrs_pass;
+            add_test_result(result, "Length of Longest Repeated Substring Test", lrs_pass);
+        }
 
         // Permutation tests
-        double rawmean, median;
-        calc_stats(&dp, rawmean, median);
-        IidTestCase tc;
-        bool perm_pass = permutation_tests(&dp, rawmean, median, verbose, tc);
-        add_test_result(result, "Permutation Tests", perm_pass);
+        if (selected(estimator_mask, ESTIMATOR_PERMUTATION)) {
+            progress.start("Permutation Tests");
+            double rawmean, median;
+            calc_stats(&dp, rawmean, median);
+            tc.mean = rawmean;
+            tc.median = median;
+            bool perm_pass = permutation_tests(&dp, rawmean, median, verbose, tc);
+            tc.passed_iid_permutation_tests = perm_pass;
+            add_test_result(result, "Permutation Tests", perm_pass);
+        }
 
         // Calculate assessed entropy
         double h_assessed = dp.word_size;
@@ -285,20 +479,29 @@ EntropyResult* calculate_iid_entropy(
         }
         h_assessed = std::min(h_assessed, H_original);
 
+        IidTestRun run;
+        tc.h_original = H_original;
+        if (dp.alph_size > 2) {
+            tc.h_bitstring = H_bitstring;
+        }
+        tc.h_assessed = h_assessed;
+        tc.data_word_size = dp.word_size;
+        run.testCases.push_back(tc);
+        result->raw_json = copy_json(run.GetAsJson());
+
         // Set results
         result->h_original = H_original;
         result->h_bitstring = H_bitstring;
         result->h_assessed = h_assessed;
         result->min_entropy = h_assessed;
         result->data_word_size = dp.word_size;
-        result->error_code = 0;
+        result->error_code = WRAPPER_OK;
+        progress.done();
 
         // guard destructor calls free_data(&dp) automatically
 
-    } catch (const std::exception& e) {
-        set_error(result, -2, e.what());
     } catch (...) {
-        set_error(result, -2, "Unknown exception occurred");
+        set_exception_error(result);
     }
 
     return result;
@@ -309,7 +512,11 @@ EntropyResult* calculate_non_iid_entropy(
     size_t length,
     int bits_per_symbol,
     bool is_binary,
-    int verbose
+    int verbose,
+    uint32_t estimator_mask,
+    int bit_order,
+    ProgressCallback progress_callback,
+    uintptr_t progress_data
 ) {
     EntropyResult* result = create_result();
     if (!result) {
@@ -319,25 +526,36 @@ EntropyResult* calculate_non_iid_entropy(
     try {
         // Validate input
         if (!data || length == 0) {
-            set_error(result, -1, "Invalid input: data is NULL or empty");
+            set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid input: data is NULL or empty");
             return result;
         }
 
+#ifdef WRAPPER_FAULT_INJECTION
+        if (inject_fault(result, data[0])) {
+            return result;
+        }
+#endif
+
         if (bits_per_symbol < 0 || bits_per_symbol > 8) {
-            set_error(result, -1, "Invalid bits_per_symbol: must be 0-8");
+            set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid bits_per_symbol: must be 0-8");
+            return result;
+        }
+
+        if (bit_order != BIT_ORDER_MSB_FIRST && bit_order != BIT_ORDER_LSB_FIRST) {
+            set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid bit_order: must be BIT_ORDER_MSB_FIRST or BIT_ORDER_LSB_FIRST");
             return result;
         }
 
         // Prepare data structure
         data_t dp;
-        if (!prepare_data(&dp, data, length, bits_per_symbol, result)) {
+        if (!prepare_data(&dp, data, length, bits_per_symbol, bit_order, result)) {
             return result;
         }
         DataGuard guard(&dp);  // RAII: ensures free_data() on any exit path
 
         // Check alphabet size
         if (dp.alph_size <= 1) {
-            set_error(result, -1, "Symbol alphabet consists of 1 symbol. No entropy awarded.");
+            set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Symbol alphabet consists of 1 symbol. No entropy awarded.");
             return result;
         }
 
@@ -346,172 +564,262 @@ EntropyResult* calculate_non_iid_entropy(
         double H_bitstring = 1.0;
         double ret_min_entropy;
 
-        // Section 6.3.1 - Most Common Value
         // Note: is_binary parameter represents initial_entropy mode (not whether data is binary)
         bool initial_entropy = is_binary;
-        double mcv_entropy = -1.0;
+        NonIidTestRun run;
 
-        if ((dp.alph_size > 2) || !initial_entropy) {
-            ret_min_entropy = most_common(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
-            H_bitstring = std::min(ret_min_entropy, H_bitstring);
-            mcv_entropy = ret_min_entropy;
-        }
-        if (initial_entropy) {
-            ret_min_entropy = most_common(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
-            H_original = std::min(ret_min_entropy, H_original);
-            mcv_entropy = ret_min_entropy;
+        ProgressReporter progress(progress_callback, progress_data,
+                                  count_phases(estimator_mask, non_iid_phases, sizeof(non_iid_phases) / sizeof(non_iid_phases[0])));
+
+        // Section 6.3.1 - Most Common Value
+        if (selected(estimator_mask, ESTIMATOR_MCV)) {
+            progress.start("Most Common Value");
+            NonIidTestCase tc;
+            double mcv_entropy = -1.0;
+
+            if ((dp.alph_size > 2) || !initial_entropy) {
+                ret_min_entropy = most_common(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
+                tc.h_bitstring = ret_min_entropy;
+                H_bitstring = std::min(ret_min_entropy, H_bitstring);
+                mcv_entropy = ret_min_entropy;
+            }
+            if (initial_entropy) {
+                ret_min_entropy = most_common(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
+                tc.h_original = ret_min_entropy;
+                H_original = std::min(ret_min_entropy, H_original);
+                mcv_entropy = ret_min_entropy;
+            }
+            tc.testCaseNumber = "Most Common Value";
+            run.testCases.push_back(tc);
+            add_estimator(result, "Most Common Value", mcv_entropy, true);
         }
-        add_estimator(result, "Most Common Value", mcv_entropy, true);
 
         // Section 6.3.2 - Collision Test (bit strings only)
-        double collision_entropy = -1.0;
-        if ((dp.alph_size > 2) || !initial_entropy) {
-            ret_min_entropy = collision_test(dp.bsymbols, dp.blen, verbose, "Bitstring");
-            H_bitstring = std::min(ret_min_entropy, H_bitstring);
-            collision_entropy = ret_min_entropy;
-        }
-        if (initial_entropy && (dp.alph_size == 2)) {
-            ret_min_entropy = collision_test(dp.symbols, dp.len, verbose, "Literal");
-            H_original = std::min(ret_min_entropy, H_original);
-            collision_entropy = ret_min_entropy;
+        if (selected(estimator_mask, ESTIMATOR_COLLISION)) {
+            progress.start("Collision Test");
+            NonIidTestCase tc;
+            double collision_entropy = -1.0;
+            if ((dp.alph_size > 2) || !initial_entropy) {
+                ret_min_entropy = collision_test(dp.bsymbols, dp.blen, verbose, "Bitstring");
+                tc.h_bitstring = ret_min_entropy;
+                H_bitstring = std::min(ret_min_entropy, H_bitstring);
+                collision_entropy = ret_min_entropy;
+            }
+            if (initial_entropy && (dp.alph_size == 2)) {
+                ret_min_entropy = collision_test(dp.symbols, dp.len, verbose, "Literal");
+                tc.h_original = ret_min_entropy;
+                H_original = std::min(ret_min_entropy, H_original);
+                collision_entropy = ret_min_entropy;
+            }
+            tc.testCaseNumber = "Collision Test (for bit strings only)";
+            run.testCases.push_back(tc);
+            add_estimator(result, "Collision Test", collision_entropy, true);
         }
-        add_estimator(result, "Collision Test", collision_entropy, true);
 
         // Section 6.3.3 - Markov Test (bit strings only)
-        double markov_entropy = -1.0;
-        if ((dp.alph_size > 2) || !initial_entropy) {
-            ret_min_entropy = markov_test(dp.bsymbols, dp.blen, verbose, "Bitstring");
-            H_bitstring = std::min(ret_min_entropy, H_bitstring);
-            markov_entropy = ret_min_entropy;
-        }
-        if (initial_entropy && (dp.alph_size == 2)) {
-            ret_min_entropy = markov_test(dp.symbols, dp.len, verbose, "Literal");
-            H_original = std::min(ret_min_entropy, H_original);
-            markov_entropy = ret_min_entropy;
+        if (selected(estimator_mask, ESTIMATOR_MARKOV)) {
+            progress.start("Markov Test");
+            NonIidTestCase tc;
+            double markov_entropy = -1.0;
+            if ((dp.alph_size > 2) || !initial_entropy) {
+                ret_min_entropy = markov_test(dp.bsymbols, dp.blen, verbose, "Bitstring");
+                tc.h_bitstring = ret_min_entropy;
+                H_bitstring = std::min(ret_min_entropy, H_bitstring);
+                markov_entropy = ret_min_entropy;
+            }
+            if (initial_entropy && (dp.alph_size == 2)) {
+                ret_min_entropy = markov_test(dp.symbols, dp.len, verbose, "Literal");
+                tc.h_original = ret_min_entropy;
+                H_original = std::min(ret_min_entropy, H_original);
+                markov_entropy = ret_min_entropy;
+            }
+            tc.testCaseNumber = "Markov Test (for bit strings only)";
+            run.testCases.push_back(tc);
+            add_estimator(result, "Markov Test", markov_entropy, true);
         }
-        add_estimator(result, "Markov Test", markov_entropy, true);
 
         // Section 6.3.4 - Compression Test (bit strings only)
-        double compression_entropy = -1.0;
-        if ((dp.alph_size > 2) || !initial_entropy) {
-            ret_min_entropy = compression_test(dp.bsymbols, dp.blen, verbose, "Bitstring");
-            if (ret_min_entropy >= 0) {
-                H_bitstring = std::min(ret_min_entropy, H_bitstring);
-                compression_entropy = ret_min_entropy;
+        if (selected(estimator_mask, ESTIMATOR_COMPRESSION)) {
+            progress.start("Compression Test");
+            NonIidTestCase tc;
+            double compression_entropy = -1.0;
+            if ((dp.alph_size > 2) || !initial_entropy) {
+                ret_min_entropy = compression_test(dp.bsymbols, dp.blen, verbose, "Bitstring");
+                tc.h_bitstring = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_bitstring = std::min(ret_min_entropy, H_bitstring);
+                    compression_entropy = ret_min_entropy;
+                }
             }
-        }
-        if (initial_entropy && (dp.alph_size == 2)) {
-            ret_min_entropy = compression_test(dp.symbols, dp.len, verbose, "Literal");
-            if (ret_min_entropy >= 0) {
-                H_original = std::min(ret_min_entropy, H_original);
-                compression_entropy = ret_min_entropy;
+            if (initial_entropy && (dp.alph_size == 2)) {
+                ret_min_entropy = compression_test(dp.symbols, dp.len, verbose, "Literal");
+                tc.h_original = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_original = std::min(ret_min_entropy, H_original);
+                    compression_entropy = ret_min_entropy;
+                }
             }
+            tc.testCaseNumber = "Compression Test (for bit strings only)";
+            run.testCases.push_back(tc);
+            add_estimator(result, "Compression Test", compression_entropy, compression_entropy >= 0);
         }
-        add_estimator(result, "Compression Test", compression_entropy, compression_entropy >= 0);
 
         // Section 6.3.5 - t-Tuple Test
         // Section 6.3.6 - LRS Test
+        // Both are computed by one suffix-array pass; only the selected
+        // estimates contribute to the result.
+        bool run_t_tuple = selected(estimator_mask, ESTIMATOR_T_TUPLE);
+        bool run_lrs = selected(estimator_mask, ESTIMATOR_LRS);
         double bin_t_tuple_res = -1.0, bin_lrs_res = -1.0;
         double t_tuple_res = -1.0, lrs_res = -1.0;
         double t_tuple_entropy = -1.0, lrs_entropy = -1.0;
+        if (run_t_tuple || run_lrs) {
+            progress.start("t-Tuple and LRS Tests");
+        }
 
-        if ((dp.alph_size > 2) || !initial_entropy) {
+        if ((run_t_tuple || run_lrs) && ((dp.alph_size > 2) || !initial_entropy)) {
             SAalgs(dp.bsymbols, dp.blen, 2, bin_t_tuple_res, bin_lrs_res, verbose, "Bitstring");
-            if (bin_t_tuple_res >= 0.0) {
+            if (run_t_tuple && bin_t_tuple_res >= 0.0) {
                 H_bitstring = std::min(bin_t_tuple_res, H_bitstring);
                 t_tuple_entropy = bin_t_tuple_res;
             }
-            if (bin_lrs_res >= 0.0) {
+            if (run_lrs && bin_lrs_res >= 0.0) {
                 H_bitstring = std::min(bin_lrs_res, H_bitstring);
                 lrs_entropy = bin_lrs_res;
             }
         }
 
-        if (initial_entropy) {
+        if ((run_t_tuple || run_lrs) && initial_entropy) {
             SAalgs(dp.symbols, dp.len, dp.alph_size, t_tuple_res, lrs_res, verbose, "Literal");
-            if (t_tuple_res >= 0.0) {
+            if (run_t_tuple && t_tuple_res >= 0.0) {
                 H_original = std::min(t_tuple_res, H_original);
                 t_tuple_entropy = t_tuple_res;
             }
-            if (lrs_res >= 0.0) {
+            if (run_lrs && lrs_res >= 0.0) {
                 H_original = std::min(lrs_res, H_original);
                 lrs_entropy = lrs_res;
             }
         }
-        add_estimator(result, "t-Tuple Test", t_tuple_entropy, t_tuple_entropy >= 0);
-        add_estimator(result, "LRS Test", lrs_entropy, lrs_entropy >= 0);
+        if (run_t_tuple) {
+            NonIidTestCase tc;
+            tc.bin_t_tuple_res = bin_t_tuple_res;
+            tc.t_tuple_res = t_tuple_res;
+            tc.testCaseNumber = "T-Tuple Test";
+            run.testCases.push_back(tc);
+            add_estimator(result, "t-Tuple Test", t_tuple_entropy, t_tuple_entropy >= 0);
+        }
+        if (run_lrs) {
+            NonIidTestCase tc;
+            tc.bin_lrs_res = bin_lrs_res;
+            tc.lrs_res = lrs_res;
+            tc.testCaseNumber = "LRS Test";
+            run.testCases.push_back(tc);
+            add_estimator(result, "LRS Test", lrs_entropy, lrs_entropy >= 0);
+        }
 
         // Section 6.3.7 - MultiMCW Test
-        double mcw_entropy = -1.0;
-        if ((dp.alph_size > 2) || !initial_entropy) {
-            ret_min_entropy = multi_mcw_test(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
-            if (ret_min_entropy >= 0) {
-                H_bitstring = std::min(ret_min_entropy, H_bitstring);
-                mcw_entropy = ret_min_entropy;
+        if (selected(estimator_mask, ESTIMATOR_MULTI_MCW)) {
+            progress.start("Multi Most Common in Window Test");
+            NonIidTestCase tc;
+            double mcw_entropy = -1.0;
+            if ((dp.alph_size > 2) || !initial_entropy) {
+                ret_min_entropy = multi_mcw_test(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
+                tc.h_bitstring = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_bitstring = std::min(ret_min_entropy, H_bitstring);
+                    mcw_entropy = ret_min_entropy;
+                }
             }
-        }
-        if (initial_entropy) {
-            ret_min_entropy = multi_mcw_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
-            if (ret_min_entropy >= 0) {
-                H_original = std::min(ret_min_entropy, H_original);
-                mcw_entropy = ret_min_entropy;
+            if (initial_entropy) {
+                ret_min_entropy = multi_mcw_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
+                tc.h_original = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_original = std::min(ret_min_entropy, H_original);
+                    mcw_entropy = ret_min_entropy;
+                }
             }
+            tc.testCaseNumber = "Multi Most Common in Window Test";
+            run.testCases.push_back(tc);
+            add_estimator(result, "Multi Most Common in Window Test", mcw_entropy, mcw_entropy >= 0);
         }
-        add_estimator(result, "Multi Most Common in Window Test", mcw_entropy, mcw_entropy >= 0);
 
         // Section 6.3.8 - Lag Prediction Test
-        double lag_entropy = -1.0;
-        if ((dp.alph_size > 2) || !initial_entropy) {
-            ret_min_entropy = lag_test(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
-            if (ret_min_entropy >= 0) {
-                H_bitstring = std::min(ret_min_entropy, H_bitstring);
-                lag_entropy = ret_min_entropy;
+        if (selected(estimator_mask, ESTIMATOR_LAG)) {
+            progress.start("Lag Prediction Test");
+            NonIidTestCase tc;
+            double lag_entropy = -1.0;
+            if ((dp.alph_size > 2) || !initial_entropy) {
+                ret_min_entropy = lag_test(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
+                tc.h_bitstring = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_bitstring = std::min(ret_min_entropy, H_bitstring);
+                    lag_entropy = ret_min_entropy;
+                }
             }
-        }
-        if (initial_entropy) {
-            ret_min_entropy = lag_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
-            if (ret_min_entropy >= 0) {
-                H_original = std::min(ret_min_entropy, H_original);
-                lag_entropy = ret_min_entropy;
+            if (initial_entropy) {
+                ret_min_entropy = lag_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
+                tc.h_original = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_original = std::min(ret_min_entropy, H_original);
+                    lag_entropy = ret_min_entropy;
+                }
             }
+            tc.testCaseNumber = "Lag Prediction Test";
+            run.testCases.push_back(tc);
+            add_estimator(result, "Lag Prediction Test", lag_entropy, lag_entropy >= 0);
         }
-        add_estimator(result, "Lag Prediction Test", lag_entropy, lag_entropy >= 0);
 
         // Section 6.3.9 - MultiMMC Test
-        double mmc_entropy = -1.0;
-        if ((dp.alph_size > 2) || !initial_entropy) {
-            ret_min_entropy = multi_mmc_test(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
-            if (ret_min_entropy >= 0) {
-                H_bitstring = std::min(ret_min_entropy, H_bitstring);
-                mmc_entropy = ret_min_entropy;
+        if (selected(estimator_mask, ESTIMATOR_MULTI_MMC)) {
+            progress.start("Multi Markov Model with Counting Test");
+            NonIidTestCase tc;
+            double mmc_entropy = -1.0;
+            if ((dp.alph_size > 2) || !initial_entropy) {
+                ret_min_entropy = multi_mmc_test(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
+                tc.h_bitstring = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_bitstring = std::min(ret_min_entropy, H_bitstring);
+                    mmc_entropy = ret_min_entropy;
+                }
             }
-        }
-        if (initial_entropy) {
-            ret_min_entropy = multi_mmc_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
-            if (ret_min_entropy >= 0) {
-                H_original = std::min(ret_min_entropy, H_original);
-                mmc_entropy = ret_min_entropy;
+            if (initial_entropy) {
+                ret_min_entropy = multi_mmc_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
+                tc.h_original = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_original = std::min(ret_min_entropy, H_original);
+                    mmc_entropy = ret_min_entropy;
+                }
             }
+            tc.testCaseNumber = "Multi Markov Model with Counting Test (MultiMMC)";
+            run.testCases.push_back(tc);
+            add_estimator(result, "Multi Markov Model with Counting Test", mmc_entropy, mmc_entropy >= 0);
         }
-        add_estimator(result, "Multi Markov Model with Counting Test", mmc_entropy, mmc_entropy >= 0);
 
         // Section 6.3.10 - LZ78Y Test
-        double lz78y_entropy = -1.0;
-        if ((dp.alph_size > 2) || !initial_entropy) {
-            ret_min_entropy = LZ78Y_test(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
-            if (ret_min_entropy >= 0) {
-                H_bitstring = std::min(ret_min_entropy, H_bitstring);
-                lz78y_entropy = ret_min_entropy;
+        if (selected(estimator_mask, ESTIMATOR_LZ78Y)) {
+            progress.start("LZ78Y Test");
+            NonIidTestCase tc;
+            double lz78y_entropy = -1.0;
+            if ((dp.alph_size > 2) || !initial_entropy) {
+                ret_min_entropy = LZ78Y_test(dp.bsymbols, dp.blen, 2, verbose, "Bitstring");
+                tc.h_bitstring = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_bitstring = std::min(ret_min_entropy, H_bitstring);
+                    lz78y_entropy = ret_min_entropy;
+                }
             }
-        }
-        if (initial_entropy) {
-            ret_min_entropy = LZ78Y_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
-            if (ret_min_entropy >= 0) {
-                H_original = std::min(ret_min_entropy, H_original);
-                lz78y_entropy = ret_min_entropy;
+            if (initial_entropy) {
+                ret_min_entropy = LZ78Y_test(dp.symbols, dp.len, dp.alph_size, verbose, "Literal");
+                tc.h_original = ret_min_entropy;
+                if (ret_min_entropy >= 0) {
+                    H_original = std::min(ret_min_entropy, H_original);
+                    lz78y_entropy = ret_min_entropy;
+                }
             }
+            tc.testCaseNumber = "LZ78Y Test";
+            run.testCases.push_back(tc);
+            add_estimator(result, "LZ78Y Test", lz78y_entropy, lz78y_entropy >= 0);
         }
-        add_estimator(result, "LZ78Y Test", lz78y_entropy, lz78y_entropy >= 0);
 
         // Calculate assessed entropy
         // Following NIST SP800-90B Section 3.1.3 (non_iid_main.cpp lines 491-496)
@@ -523,20 +831,32 @@ EntropyResult* calculate_non_iid_entropy(
             h_assessed = std::min(h_assessed, H_original);
         }
 
+        NonIidTestCase overall;
+        if ((dp.alph_size > 2) || !initial_entropy) {
+            overall.h_bitstring = H_bitstring;
+        }
+        if (initial_entropy) {
+            overall.h_original = H_original;
+        }
+        overall.data_word_size = dp.word_size;
+        overall.testCaseNumber = "Overall";
+        overall.h_assessed = h_assessed;
+        run.testCases.push_back(overall);
+        result->raw_json = copy_json(run.GetAsJson());
+
         // Set results
         result->h_original = H_original;
         result->h_bitstring = H_bitstring;
         result->h_assessed = h_assessed;
         result->min_entropy = h_assessed;
         result->data_word_size = dp.word_size;
-        result->error_code = 0;
+        result->error_code = WRAPPER_OK;
+        progress.done();
 
         // guard destructor calls free_data(&dp) automatically
 
-    } catch (const std::exception& e) {
-        set_error(result, -2, e.what());
     } catch (...) {
-        set_error(result, -2, "Unknown exception occurred");
+        set_exception_error(result);
     }
 
     return result;
@@ -544,8 +864,94 @@ EntropyResult* calculate_non_iid_entropy(
 
 void free_entropy_result(EntropyResult* result) {
     if (result) {
+        free(result->raw_json);
         free(result);
     }
 }
 
+// Fills lrs for calculate_lrs. Errors are recorded in result, so that the
+// data preparation and error helpers can be shared with the assessments.
+static void compute_lrs(LRSResult* lrs, EntropyResult* result, const uint8_t* data, size_t length, int bits_per_symbol, int verbose) {
+    if (!data || length < 2) {
+        set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid input: need at least 2 samples");
+        return;
+    }
+
+#ifdef WRAPPER_FAULT_INJECTION
+    if (inject_fault(result, data[0])) {
+        return;
+    }
+#endif
+
+    // len_LRS32 indexes its suffix array with 32-bit integers.
+    if (length >= (size_t)SAINDEX_MAX) {
+        set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid input: length must be less than INT32_MAX samples");
+        return;
+    }
+
+    if (bits_per_symbol < 0 || bits_per_symbol > 8) {
+        set_error(result, WRAPPER_ERROR_INVALID_INPUT, "Invalid bits_per_symbol: must be 0-8");
+        return;
+    }
+
+    data_t dp;
+    if (!prepare_data(&dp, data, length, bits_per_symbol, BIT_ORDER_MSB_FIRST, result)) {
+        return;
+    }
+    DataGuard guard(&dp);  // RAII: ensures free_data() on any exit path
+
+    double t_tuple_res = -1.0, lrs_res = -1.0;
+    SAalgs(dp.symbols, dp.len, dp.alph_size, t_tuple_res, lrs_res, verbose, "Literal");
+    lrs->length = len_LRS32(dp.symbols, (int)dp.len);
+    lrs->entropy_estimate = lrs_res;
+    lrs->data_word_size = dp.word_size;
+}
+
+LRSResult* calculate_lrs(
+    const uint8_t* data,
+    size_t length,
+    int bits_per_symbol,
+    int verbose
+) {
+    LRSResult* lrs = (LRSResult*)malloc(sizeof(LRSResult));
+    if (!lrs) {
+        return NULL;
+    }
+    EntropyResult* result = create_result();
+    if (!result) {
+        free(lrs);
+        return NULL;
+    }
+    lrs->length = 0;
+    lrs->entropy_estimate = -1.0;
+    lrs->data_word_size = 0;
+
+    try {
+        compute_lrs(lrs, result, data, length, bits_per_symbol, verbose);
+    } catch (...) {
+        set_exception_error(result);
+    }
+
+    lrs->error_code = result->error_code;
+    memcpy(lrs->error_message, result->error_message, sizeof(lrs->error_message));
+    free_entropy_result(result);
+    return lrs;
+}
+
+void free_lrs_result(LRSResult* result) {
+    if (result) {
+        free(result);
+    }
+}
+
+// Returns the VERSION string of the NIST reference tool sources (utils.h).
+const char* nist_tool_version(void) {
+    return VERSION;
+}
+
+// Returns the wrapper API version this library was compiled with.
+const char* wrapper_version(void) {
+    return WRAPPER_VERSION;
+}
+
 } // extern "C"
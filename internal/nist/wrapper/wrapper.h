@@ -21,6 +21,55 @@ extern "C" {
 // Maximum number of estimators per assessment
 #define MAX_ESTIMATORS 16
 
+// Version of the wrapper API, reported by wrapper_version().
+#define WRAPPER_VERSION "2.2.0"
+
+// Estimator selection bits for the estimator_mask argument. A mask of
+// ESTIMATOR_ALL runs every estimator applicable to the test type; any other
+// value restricts the run to the selected estimators and does not constitute
+// a conforming SP 800-90B assessment.
+#define ESTIMATOR_ALL          0u
+#define ESTIMATOR_MCV          (1u << 0)  // Most Common Value (IID and Non-IID)
+#define ESTIMATOR_COLLISION    (1u << 1)  // Collision Test (Non-IID)
+#define ESTIMATOR_MARKOV       (1u << 2)  // Markov Test (Non-IID)
+#define ESTIMATOR_COMPRESSION  (1u << 3)  // Compression Test (Non-IID)
+#define ESTIMATOR_T_TUPLE      (1u << 4)  // t-Tuple Test (Non-IID)
+#define ESTIMATOR_LRS          (1u << 5)  // LRS estimate (Non-IID) / LRS test (IID)
+#define ESTIMATOR_MULTI_MCW    (1u << 6)  // Multi Most Common in Window Test (Non-IID)
+#define ESTIMATOR_LAG          (1u << 7)  // Lag Prediction Test (Non-IID)
+#define ESTIMATOR_MULTI_MMC    (1u << 8)  // Multi Markov Model with Counting Test (Non-IID)
+#define ESTIMATOR_LZ78Y        (1u << 9)  // LZ78Y Test (Non-IID)
+#define ESTIMATOR_CHI_SQUARE   (1u << 10) // Chi-Square Tests (IID)
+#define ESTIMATOR_PERMUTATION  (1u << 11) // Permutation Tests (IID)
+
+// Bit orders for the bit_order argument, selecting how each symbol is expanded
+// into data_word_size bits for the bitstring estimates. BIT_ORDER_MSB_FIRST
+// matches the NIST reference tool.
+#define BIT_ORDER_MSB_FIRST    0
+#define BIT_ORDER_LSB_FIRST    1
+
+// Values of EntropyResult.error_code. Every C++ exception is caught at the
+// API boundary and reported with the code of its type instead of aborting
+// the calling process.
+#define WRAPPER_OK                      0
+#define WRAPPER_ERROR_INVALID_INPUT    -1 // Rejected argument or unusable data
+#define WRAPPER_ERROR_EXCEPTION        -2 // Any other std::exception
+#define WRAPPER_ERROR_OUT_OF_MEMORY    -3 // std::bad_alloc or a failed allocation
+#define WRAPPER_ERROR_LOGIC            -4 // std::logic_error (out_of_range, ...)
+#define WRAPPER_ERROR_RUNTIME          -5 // std::runtime_error (overflow_error, ...)
+#define WRAPPER_ERROR_UNKNOWN          -6 // An exception not derived from std::exception
+
+// Final phase name reported through a ProgressCallback.
+#define PROGRESS_PHASE_DONE "Done"
+
+// ProgressCallback receives progress reports at estimator boundaries: the
+// name of the phase about to run and the percentage (0-100) of the selected
+// phases already completed. The last report is PROGRESS_PHASE_DONE at 100.
+// It is called on the thread that called calculate_*, never from a parallel
+// region, and user_data is passed through unchanged. phase is only valid
+// for the duration of the call.
+typedef void (*ProgressCallback)(const char* phase, double percent, uintptr_t user_data);
+
 // EstimatorResult holds the output of a single entropy estimator or statistical test.
 typedef struct {
     char name[64];           // Estimator name (e.g., "Most Common Value")
@@ -36,22 +85,45 @@ typedef struct {
     double h_bitstring;      // Entropy from bitstring
     double h_assessed;       // Assessed entropy value
     int data_word_size;      // Bits per symbol
-    int error_code;          // 0 = success, negative = error
+    int error_code;          // WRAPPER_OK or a WRAPPER_ERROR_* code
     char error_message[512]; // Error description
 
     // Individual estimator results
     EstimatorResult estimators[MAX_ESTIMATORS];
     int estimator_count;     // Number of valid entries in estimators array
+
+    // Occurrences of each symbol value after masking to data_word_size
+    uint64_t histogram[256];
+
+    // JSON document of the run in the layout of the NIST tool's -o output,
+    // NUL-terminated and owned by the result; NULL if it could not be built.
+    char* raw_json;
 } EntropyResult;
 
+// LRSResult holds the output of calculate_lrs.
+typedef struct {
+    long length;             // Longest repeated substring length in symbols
+    double entropy_estimate; // LRS estimate (-1.0 if it cannot be computed)
+    int data_word_size;      // Bits per symbol
+    int error_code;          // WRAPPER_OK or a WRAPPER_ERROR_* code
+    char error_message[512]; // Error description
+} LRSResult;
+
 /**
  * Calculate IID (Independent and Identically Distributed) entropy estimate.
  *
- * @param data Pointer to raw sample bytes.
- * @param length Number of bytes in data.
+ * @param data Pointer to raw sample bytes. Only the first length bytes are
+ *             read, once, before any estimator runs; the pointer is not
+ *             retained after the call returns.
+ * @param length Number of bytes in data, at most LONG_MAX / 8 so that the
+ *               bitstring length fits in a long.
  * @param bits_per_symbol Number of bits per symbol (1-8), 0 for auto-detect.
  * @param is_binary If true, run in initial-entropy mode (unconditioned source).
  * @param verbose Verbosity level (0=quiet, 1=normal, 2=verbose, 3=very verbose).
+ * @param estimator_mask ESTIMATOR_* bits to run, or ESTIMATOR_ALL.
+ * @param bit_order BIT_ORDER_MSB_FIRST or BIT_ORDER_LSB_FIRST.
+ * @param progress Progress callback, or NULL for none.
+ * @param progress_data Value passed to every progress call.
  * @return Pointer to EntropyResult (caller must free with free_entropy_result).
  */
 EntropyResult* calculate_iid_entropy(
@@ -59,18 +131,29 @@ EntropyResult* calculate_iid_entropy(
     size_t length,
     int bits_per_symbol,
     bool is_binary,
-    int verbose
+    int verbose,
+    uint32_t estimator_mask,
+    int bit_order,
+    ProgressCallback progress,
+    uintptr_t progress_data
 );
 
 /**
  * Calculate Non-IID entropy estimate using all ten SP 800-90B Section 6.3
  * estimators.
  *
- * @param data Pointer to raw sample bytes.
- * @param length Number of bytes in data.
+ * @param data Pointer to raw sample bytes. Only the first length bytes are
+ *             read, once, before any estimator runs; the pointer is not
+ *             retained after the call returns.
+ * @param length Number of bytes in data, at most LONG_MAX / 8 so that the
+ *               bitstring length fits in a long.
  * @param bits_per_symbol Number of bits per symbol (1-8), 0 for auto-detect.
  * @param is_binary If true, run in initial-entropy mode (unconditioned source).
  * @param verbose Verbosity level (0=quiet, 1=normal, 2=verbose, 3=very verbose).
+ * @param estimator_mask ESTIMATOR_* bits to run, or ESTIMATOR_ALL.
+ * @param bit_order BIT_ORDER_MSB_FIRST or BIT_ORDER_LSB_FIRST.
+ * @param progress Progress callback, or NULL for none.
+ * @param progress_data Value passed to every progress call.
  * @return Pointer to EntropyResult (caller must free with free_entropy_result).
  */
 EntropyResult* calculate_non_iid_entropy(
@@ -78,7 +161,11 @@ EntropyResult* calculate_non_iid_entropy(
     size_t length,
     int bits_per_symbol,
     bool is_binary,
-    int verbose
+    int verbose,
+    uint32_t estimator_mask,
+    int bit_order,
+    ProgressCallback progress,
+    uintptr_t progress_data
 );
 
 /**
@@ -88,6 +175,46 @@ EntropyResult* calculate_non_iid_entropy(
  */
 void free_entropy_result(EntropyResult* result);
 
+/**
+ * Compute the longest repeated substring of the symbols and the SP 800-90B
+ * Section 6.3.6 LRS estimate on them, without running an assessment. Only
+ * the literal symbols are examined, not their bitstring.
+ *
+ * @param data Pointer to raw sample bytes. Only the first length bytes are
+ *             read, once; the pointer is not retained after the call returns.
+ * @param length Number of bytes in data, at least 2 and less than INT32_MAX.
+ * @param bits_per_symbol Number of bits per symbol (1-8), 0 for auto-detect.
+ * @param verbose Verbosity level (0=quiet, 1=normal, 2=verbose, 3=very verbose).
+ * @return Pointer to LRSResult (caller must free with free_lrs_result).
+ */
+LRSResult* calculate_lrs(
+    const uint8_t* data,
+    size_t length,
+    int bits_per_symbol,
+    int verbose
+);
+
+/**
+ * Free an LRSResult structure allocated by calculate_lrs.
+ *
+ * @param result Pointer to LRSResult to free (NULL-safe).
+ */
+void free_lrs_result(LRSResult* result);
+
+/**
+ * Return the version of the linked NIST SP 800-90B reference tool.
+ *
+ * @return Static, NUL-terminated string; must not be freed.
+ */
+const char* nist_tool_version(void);
+
+/**
+ * Return the version of the compiled wrapper (WRAPPER_VERSION).
+ *
+ * @return Static, NUL-terminated string; must not be freed.
+ */
+const char* wrapper_version(void);
+
 #ifdef __cplusplus
 }
 #endif
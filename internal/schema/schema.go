@@ -0,0 +1,23 @@
+// Package schema holds the JSON Schema of the assessment results that
+// ea_tool writes with -output. The gRPC server serves it over HTTP so that
+// consumers can validate result files without a copy of this repository.
+package schema
+
+import _ "embed"
+
+// Version is the schema_version of the result documents. Bump it whenever a
+// field is added, removed or changes its type or meaning, and update
+// assess_output.schema.json to match.
+const Version = "16"
+
+// ContentType is the media type of the schema document.
+const ContentType = "application/schema+json"
+
+//go:embed assess_output.schema.json
+var assessOutput []byte
+
+// AssessOutput returns the JSON Schema describing one ea_tool result
+// document. The returned slice is a copy and may be modified.
+func AssessOutput() []byte {
+	return append([]byte(nil), assessOutput...)
+}
@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compile compiles the assessment output schema.
+func compile(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(AssessOutput()))
+	require.NoError(t, err)
+	c := jsonschema.NewCompiler()
+	require.NoError(t, c.AddResource("assess_output.schema.json", doc))
+	sch, err := c.Compile("assess_output.schema.json")
+	require.NoError(t, err)
+	return sch
+}
+
+// validate validates the JSON document doc against sch.
+func validate(t *testing.T, sch *jsonschema.Schema, doc string) error {
+	t.Helper()
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(doc)))
+	require.NoError(t, err)
+	return sch.Validate(v)
+}
+
+func TestAssessOutput_MatchesVersion(t *testing.T) {
+	var doc struct {
+		Properties struct {
+			SchemaVersion struct {
+				Const string `json:"const"`
+			} `json:"schema_version"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(AssessOutput(), &doc))
+	assert.Equal(t, Version, doc.Properties.SchemaVersion.Const)
+}
+
+func TestAssessOutput_Validates(t *testing.T) {
+	sch := compile(t)
+
+	minimal := `{"schema_version": "16", "version": "1.0.0", "filename": "data.bin", "test_type": "Non-IID",
+		"bits_per_symbol": 8, "data_size": 1000, "min_entropy": 6.5, "h_assessed": 6.5, "h_final": 6.5,
+		"submitter_binding": false, "duration_ms": 12, "error_code": 0}`
+	assert.NoError(t, validate(t, sch, minimal))
+
+	for name, doc := range map[string]string{
+		"old schema":    `{"schema_version": "0", "version": "1.0.0", "filename": "data.bin", "test_type": "IID", "bits_per_symbol": 8, "data_size": 1, "min_entropy": 1, "h_assessed": 1, "h_final": 1, "submitter_binding": false, "duration_ms": 12, "error_code": 0}`,
+		"missing field": `{"schema_version": "16", "version": "1.0.0", "filename": "data.bin", "test_type": "IID", "bits_per_symbol": 8, "data_size": 1, "min_entropy": 1, "h_assessed": 1, "h_final": 1, "duration_ms": 12, "error_code": 0}`,
+		"unknown field": `{"schema_version": "16", "version": "1.0.0", "filename": "data.bin", "test_type": "IID", "bits_per_symbol": 8, "data_size": 1, "min_entropy": 1, "h_assessed": 1, "h_final": 1, "submitter_binding": false, "duration_ms": 12, "error_code": 0, "extra": 1}`,
+		"bad bits":      `{"schema_version": "16", "version": "1.0.0", "filename": "data.bin", "test_type": "IID", "bits_per_symbol": 9, "data_size": 1, "min_entropy": 1, "h_assessed": 1, "h_final": 1, "submitter_binding": false, "duration_ms": 12, "error_code": 0}`,
+	} {
+		assert.Error(t, validate(t, sch, doc), name)
+	}
+}
+
+func TestAssessOutput_ReturnsCopy(t *testing.T) {
+	doc := AssessOutput()
+	doc[0] = 'x'
+	assert.NotEqual(t, doc, AssessOutput())
+}
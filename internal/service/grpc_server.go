@@ -2,10 +2,17 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -24,6 +31,14 @@ const Version = "2.0.0"
 type GRPCServer struct {
 	pb.UnimplementedSp80090BAssessmentServiceServer
 	svc *EntropyService
+
+	// Base directory for AssessEntropyFile, as configured and with symlinks
+	// resolved. Empty disables the RPC.
+	fileBaseDir     string
+	fileBaseDirReal string
+
+	// Largest data accepted in bytes; zero for no limit.
+	maxUploadSize int64
 }
 
 // NewGRPCServer creates a new GRPCServer instance.
@@ -33,48 +48,102 @@ func NewGRPCServer(svc *EntropyService) *GRPCServer {
 	}
 }
 
+// UnaryInFlightInterceptor returns a gRPC unary interceptor that registers
+// every call of the assessment service with svc for the duration of the
+// handler, so that EntropyService.Drain can wait for it during shutdown.
+// Calls of other services, such as health checks, are not tracked.
+func UnaryInFlightInterceptor(svc *EntropyService) grpc.UnaryServerInterceptor {
+	prefix := "/" + pb.Sp80090BAssessmentService_ServiceDesc.ServiceName + "/"
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, prefix) {
+			return handler(ctx, req)
+		}
+		done := svc.BeginAssessment()
+		defer done()
+		return handler(ctx, req)
+	}
+}
+
+// progressKey is the context key of WithProgress.
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx that makes AssessEntropy and
+// AssessEntropyFile report the progress of their calculations to fn, with
+// the semantics of entropy.Assessment.WithProgress. A request in both modes
+// reports the phases of the IID pass and then those of the Non-IID pass.
+func WithProgress(ctx context.Context, fn entropy.ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressFrom returns the progress function set with WithProgress, or nil.
+func progressFrom(ctx context.Context) entropy.ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(entropy.ProgressFunc)
+	return fn
+}
+
+// SetFileBaseDir restricts AssessEntropyFile to files below dir. An empty dir
+// disables the RPC.
+func (s *GRPCServer) SetFileBaseDir(dir string) {
+	if dir == "" {
+		s.fileBaseDir, s.fileBaseDirReal = "", ""
+		return
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	s.fileBaseDir = filepath.Clean(dir)
+	s.fileBaseDirReal = s.fileBaseDir
+	if resolved, err := filepath.EvalSymlinks(s.fileBaseDir); err == nil {
+		s.fileBaseDirReal = resolved
+	}
+}
+
+// SetMaxUploadSize rejects requests whose data exceeds n bytes, as
+// MAX_UPLOAD_SIZE does for uploads. Zero or less removes the limit.
+func (s *GRPCServer) SetMaxUploadSize(n int64) {
+	s.maxUploadSize = max(n, 0)
+}
+
 // AssessEntropy handles gRPC requests for NIST SP 800-90B entropy assessment.
 // It supports IID mode, Non-IID mode, or both simultaneously. The overall
 // min-entropy is the minimum across all enabled modes. If either mode produces
 // an infinity result (no valid estimators), min-entropy falls back to zero.
+// The request is first validated with the checks ValidateRequest reports.
 func (s *GRPCServer) AssessEntropy(ctx context.Context, req *pb.Sp80090BAssessmentRequest) (*pb.Sp80090BAssessmentResponse, error) {
+	return s.assessEntropy(ctx, req, s.maxUploadSize)
+}
+
+// assessEntropy implements AssessEntropy, rejecting data above maxSize bytes
+// unless maxSize is zero. Files read by AssessEntropyFile are not uploads and
+// are not limited.
+func (s *GRPCServer) assessEntropy(ctx context.Context, req *pb.Sp80090BAssessmentRequest, maxSize int64) (*pb.Sp80090BAssessmentResponse, error) {
 	requestID := middleware.GetRequestID(ctx)
 
-	if req == nil {
-		log.Error().
-			Str("request_id", requestID).
-			Msg("AssessEntropy request validation failed: request cannot be nil")
-		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
-	}
+	// The C library clamps to [0, 3] as well; clamping here keeps the
+	// logged value and the conversion to int meaningful.
+	verbose := int(min(req.GetVerbosity(), 3))
 
 	log.Info().
 		Str("request_id", requestID).
-		Int("sample_count", len(req.Data)).
-		Uint32("bits_per_symbol", req.BitsPerSymbol).
-		Bool("iid_mode", req.IidMode).
-		Bool("non_iid_mode", req.NonIidMode).
+		Int("sample_count", len(req.GetData())).
+		Uint32("bits_per_symbol", req.GetBitsPerSymbol()).
+		Bool("iid_mode", req.GetIidMode()).
+		Bool("non_iid_mode", req.GetNonIidMode()).
+		Int("verbosity", verbose).
 		Msg("AssessEntropy request received")
 
-	if len(req.Data) == 0 {
+	v := s.requestChecks(req, uint64(len(req.GetData())), maxSize)
+	if c := v.failed(); c != nil {
 		log.Error().
 			Str("request_id", requestID).
-			Msg("AssessEntropy request validation failed: data cannot be empty")
-		return nil, status.Error(codes.InvalidArgument, "data cannot be empty")
+			Str("check", c.name).
+			Msg("AssessEntropy request validation failed: " + c.err.Error())
+		return nil, status.Error(codes.InvalidArgument, c.err.Error())
 	}
+	data := v.data
 
-	if req.BitsPerSymbol > 8 {
-		log.Error().
-			Str("request_id", requestID).
-			Uint32("bits_per_symbol", req.BitsPerSymbol).
-			Msg("AssessEntropy request validation failed: bits_per_symbol out of range")
-		return nil, status.Errorf(codes.InvalidArgument, "bits_per_symbol must be between 0 and 8, got %d", req.BitsPerSymbol)
-	}
-
-	if !req.IidMode && !req.NonIidMode {
-		log.Error().
-			Str("request_id", requestID).
-			Msg("AssessEntropy request validation failed: no assessment mode selected")
-		return nil, status.Error(codes.InvalidArgument, "either iid_mode or non_iid_mode must be enabled")
+	if req.IidCheckOnly {
+		return s.checkIID(ctx, requestID, req, data)
 	}
 
 	testType := "mixed"
@@ -84,77 +153,492 @@ func (s *GRPCServer) AssessEntropy(ctx context.Context, req *pb.Sp80090BAssessme
 		testType = "Non-IID"
 	}
 	startTime := time.Now()
-	metrics.RecordRequest(testType)
-	metrics.RecordDataSize(testType, len(req.Data))
 
+	// Both passes of a mixed-mode request read the same slice; the service
+	// does not copy the samples.
 	bits := int(req.BitsPerSymbol)
+	opts := AssessOptions{
+		HSubmitter: req.HSubmitter,
+		Estimators: req.Estimators,
+		Histogram:  req.IncludeHistogram,
+		RawJSON:    req.IncludeRawJson,
+		BitOrder:   v.bitOrder,
+		Verbose:    &verbose,
+		Progress:   progressFrom(ctx),
+	}
+	hFinal := math.Inf(1)
+	submitterBinding := req.HSubmitter != nil
 	var iidResults []*pb.Sp80090BEstimatorResult
 	var nonIIDResults []*pb.Sp80090BEstimatorResult
-	minEntropy := math.Inf(1)
+	var histogram []uint64
+	var iidRaw, nonIIDRaw string
+	var backend string
+	var shannon float64
+	var alphabet uint32
+	partial := false
+	iidMin, nonIIDMin := math.Inf(1), math.Inf(1)
+	var iidH, nonIIDH [2]float64 // H_original and H_bitstring of each mode
 	var usedBits uint32
+	timings := make(map[string]float64)
 
 	// IID path
 	if req.IidMode {
-		res, err := s.svc.AssessIID(req.Data, bits)
+		res, err := s.svc.AssessIID(ctx, data, bits, opts)
 		if err != nil {
-			metrics.RecordError("IID", "IID assessment failed")
-			metrics.RecordDuration(testType, time.Since(startTime).Seconds())
-			return nil, status.Errorf(codes.InvalidArgument, "IID assessment failed: %v", err)
+			s.recordAbandonedWork("IID", err)
+			logAssessmentFailure(requestID, "IID", err)
+			return nil, status.Errorf(assessmentErrorCode(err), "IID assessment failed: %v", err)
 		}
-		minEntropy = math.Min(minEntropy, res.MinEntropy)
+		logEstimatorResults(requestID, "IID", res.Estimators)
+		recordEstimatorEntropy("IID", res.Estimators)
+		iidMin = res.MinEntropy
+		iidH = [2]float64{res.HOriginal, res.HBitstring}
+		hFinal = math.Min(hFinal, res.HFinal)
+		submitterBinding = submitterBinding && res.SubmitterBinding
 		usedBits = uint32(res.DataWordSize)
 		iidResults = convertEstimatorsToProto(res.Estimators)
+		histogram = res.Histogram
+		iidRaw = string(res.RawJSON)
+		backend = res.Backend
+		shannon = res.ShannonEntropy
+		alphabet = uint32(res.AlphabetSize)
+		partial = partial || res.Partial
+		addTimings(timings, res.Timings)
 	}
 
 	// Non-IID path
 	if req.NonIidMode {
-		res, err := s.svc.AssessNonIID(req.Data, bits)
+		res, err := s.svc.AssessNonIID(ctx, data, bits, opts)
 		if err != nil {
-			metrics.RecordError("Non-IID", "Non-IID assessment failed")
-			metrics.RecordDuration(testType, time.Since(startTime).Seconds())
-			return nil, status.Errorf(codes.InvalidArgument, "Non-IID assessment failed: %v", err)
+			s.recordAbandonedWork("Non-IID", err)
+			logAssessmentFailure(requestID, "Non-IID", err)
+			return nil, status.Errorf(assessmentErrorCode(err), "Non-IID assessment failed: %v", err)
 		}
-		minEntropy = math.Min(minEntropy, res.MinEntropy)
+		logEstimatorResults(requestID, "Non-IID", res.Estimators)
+		recordEstimatorEntropy("Non-IID", res.Estimators)
+		nonIIDMin = res.MinEntropy
+		nonIIDH = [2]float64{res.HOriginal, res.HBitstring}
+		hFinal = math.Min(hFinal, res.HFinal)
+		submitterBinding = submitterBinding && res.SubmitterBinding
 		usedBits = uint32(res.DataWordSize)
 		nonIIDResults = convertEstimatorsToProto(res.Estimators)
+		histogram = res.Histogram
+		nonIIDRaw = string(res.RawJSON)
+		backend = res.Backend
+		shannon = res.ShannonEntropy
+		alphabet = uint32(res.AlphabetSize)
+		partial = partial || res.Partial
+		addTimings(timings, res.Timings)
 	}
 
+	// A detected word size is reported as such; the request's value is only
+	// echoed when the assessment did not report one.
+	autoDetected := req.BitsPerSymbol == 0 && usedBits != 0
 	if usedBits == 0 {
 		usedBits = req.BitsPerSymbol
 	}
 
+	minEntropy := math.Min(iidMin, nonIIDMin)
+	source := minEntropySource(iidMin, nonIIDMin)
+	var h [2]float64
+	switch source {
+	case pb.MinEntropySource_MIN_ENTROPY_SOURCE_IID:
+		h = iidH
+	case pb.MinEntropySource_MIN_ENTROPY_SOURCE_NON_IID:
+		h = nonIIDH
+	}
 	if !math.IsInf(minEntropy, 1) {
 		metrics.RecordMinEntropy(testType, minEntropy)
 	} else {
 		minEntropy = 0
 	}
-	metrics.RecordDuration(testType, time.Since(startTime).Seconds())
+	if math.IsInf(hFinal, 1) {
+		hFinal = minEntropy
+	}
+
+	summary := "NIST SP 800-90B entropy assessment completed"
+	if len(req.Estimators) > 0 {
+		summary += " with an estimator subset (non-conforming)"
+	}
+	if partial {
+		summary += "; only the pure-Go estimators ran (non-conforming)"
+	}
 
 	response := &pb.Sp80090BAssessmentResponse{
-		MinEntropy:        minEntropy,
-		IidResults:        iidResults,
-		NonIidResults:     nonIIDResults,
-		Passed:            true,
-		AssessmentSummary: "NIST SP 800-90B entropy assessment completed",
-		SampleCount:       uint64(len(req.Data)),
-		BitsPerSymbol:     usedBits,
+		MinEntropy:                minEntropy,
+		IidResults:                iidResults,
+		NonIidResults:             nonIIDResults,
+		Passed:                    true,
+		AssessmentSummary:         summary,
+		SampleCount:               uint64(len(data)),
+		BitsPerSymbol:             usedBits,
+		BitsPerSymbolAutoDetected: autoDetected,
+		HFinal:                    hFinal,
+		SubmitterBinding:          submitterBinding,
+		Histogram:                 histogram,
+		Backend:                   backend,
+		Partial:                   partial,
+		ShannonEntropy:            shannon,
+		MinEntropySource:          source,
+		AlphabetSize:              alphabet,
+		IidRawJson:                iidRaw,
+		NonIidRawJson:             nonIIDRaw,
+		HOriginal:                 h[0],
+		HBitstring:                h[1],
+		TimingsMs:                 timings,
 	}
 
 	log.Info().
 		Str("request_id", requestID).
+		Str("test_type", testType).
+		Int("sample_count", len(data)).
+		Uint32("bits_per_symbol", response.BitsPerSymbol).
 		Int64("execution_time_ms", time.Since(startTime).Milliseconds()).
 		Float64("min_entropy", response.MinEntropy).
+		Stringer("min_entropy_source", response.MinEntropySource).
+		Float64("h_final", response.HFinal).
 		Int("iid_results_count", len(response.IidResults)).
 		Int("non_iid_results_count", len(response.NonIidResults)).
+		Bool("passed", response.Passed).
 		Msg("AssessEntropy completed successfully")
 
 	return response, nil
 }
 
+// AssessEntropyFile reads a file from the server's filesystem and assesses it
+// exactly like AssessEntropy. The path must resolve, after following
+// symlinks, to a regular file below the configured base directory.
+func (s *GRPCServer) AssessEntropyFile(ctx context.Context, req *pb.Sp80090BFileAssessmentRequest) (*pb.Sp80090BAssessmentResponse, error) {
+	requestID := middleware.GetRequestID(ctx)
+
+	if req == nil {
+		log.Error().
+			Str("request_id", requestID).
+			Msg("AssessEntropyFile request validation failed: request cannot be nil")
+		return nil, status.Error(codes.InvalidArgument, "request cannot be nil")
+	}
+
+	path, err := s.resolveFilePath(req.Path)
+	if err != nil {
+		log.Error().
+			Str("request_id", requestID).
+			Str("path", req.Path).
+			Err(err).
+			Msg("AssessEntropyFile request rejected")
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, fs.ErrPermission) {
+			code = codes.PermissionDenied
+		}
+		return nil, status.Errorf(code, "failed to read %s: %v", req.Path, err)
+	}
+
+	log.Info().
+		Str("request_id", requestID).
+		Str("path", path).
+		Msg("AssessEntropyFile read server-side file")
+
+	return s.assessEntropy(ctx, &pb.Sp80090BAssessmentRequest{
+		Data:             data,
+		BitsPerSymbol:    req.BitsPerSymbol,
+		IidMode:          req.IidMode,
+		NonIidMode:       req.NonIidMode,
+		Verbosity:        req.Verbosity,
+		HSubmitter:       req.HSubmitter,
+		Estimators:       req.Estimators,
+		IidCheckOnly:     req.IidCheckOnly,
+		IncludeHistogram: req.IncludeHistogram,
+		IncludeRawJson:   req.IncludeRawJson,
+		Offset:           req.Offset,
+		Length:           req.Length,
+		BitOrder:         req.BitOrder,
+	}, 0)
+}
+
+// resolveFilePath maps a requested path to a regular file inside the base
+// directory. Relative paths are taken relative to the base directory. Paths
+// that leave it, lexically or through a symlink, are PermissionDenied.
+func (s *GRPCServer) resolveFilePath(path string) (string, error) {
+	if s.fileBaseDir == "" {
+		return "", status.Error(codes.FailedPrecondition, "file assessment is disabled; set ASSESS_FILE_BASE_DIR")
+	}
+	if path == "" {
+		return "", status.Error(codes.InvalidArgument, "path cannot be empty")
+	}
+
+	target := filepath.Clean(path)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(s.fileBaseDir, target)
+	}
+	if !isWithin(s.fileBaseDir, target) && !isWithin(s.fileBaseDirReal, target) {
+		return "", status.Errorf(codes.PermissionDenied, "path %s is outside the permitted base directory", path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", status.Errorf(codes.NotFound, "file %s not found", path)
+		}
+		return "", status.Errorf(codes.PermissionDenied, "cannot resolve %s: %v", path, err)
+	}
+	if !isWithin(s.fileBaseDirReal, resolved) {
+		return "", status.Errorf(codes.PermissionDenied, "path %s is outside the permitted base directory", path)
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", status.Errorf(codes.NotFound, "file %s not found", path)
+	}
+	if !info.Mode().IsRegular() {
+		return "", status.Errorf(codes.InvalidArgument, "path %s is not a regular file", path)
+	}
+	return resolved, nil
+}
+
+// isWithin reports whether path is base or lies below it. Both must be clean
+// absolute paths.
+func isWithin(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// GetCapabilities reports the service version and the linked entropy library,
+// allowing clients to detect stub builds that return fixed results.
+func (s *GRPCServer) GetCapabilities(ctx context.Context, req *pb.Sp80090BCapabilitiesRequest) (*pb.Sp80090BCapabilitiesResponse, error) {
+	info := entropy.LibraryInfo()
+	return &pb.Sp80090BCapabilitiesResponse{
+		ServiceVersion:   Version,
+		ToolVersion:      info.ToolVersion,
+		WrapperVersion:   info.WrapperVersion,
+		Cgo:              info.CGO,
+		Estimators:       info.Estimators,
+		Backend:          info.Backend,
+		IidEstimators:    info.IIDEstimators,
+		NonIidEstimators: info.NonIIDEstimators,
+	}, nil
+}
+
+// GetSupportedEstimators lists, per test type, the estimator names that appear
+// in assessment results.
+func (s *GRPCServer) GetSupportedEstimators(ctx context.Context, req *pb.Sp80090BSupportedEstimatorsRequest) (*pb.Sp80090BSupportedEstimatorsResponse, error) {
+	return &pb.Sp80090BSupportedEstimatorsResponse{
+		Iid:    entropy.SupportedEstimators(entropy.IID),
+		NonIid: entropy.SupportedEstimators(entropy.NonIID),
+	}, nil
+}
+
+// checkIID answers an iid_check_only request on data, the requested window of
+// req.Data. Passed reflects the outcome of the IID statistical tests; no
+// entropy estimate is produced.
+func (s *GRPCServer) checkIID(ctx context.Context, requestID string, req *pb.Sp80090BAssessmentRequest, data []byte) (*pb.Sp80090BAssessmentResponse, error) {
+	const testType = "IID"
+	startTime := time.Now()
+
+	passed, tests, err := s.svc.CheckIID(ctx, data, int(req.BitsPerSymbol))
+	if err != nil {
+		s.recordAbandonedWork(testType, err)
+		logAssessmentFailure(requestID, testType, err)
+		return nil, status.Errorf(assessmentErrorCode(err), "IID check failed: %v", err)
+	}
+	logEstimatorResults(requestID, testType, tests)
+
+	summary := "IID check passed: data is consistent with the IID assumption"
+	if !passed {
+		summary = "IID check failed: data does not satisfy the IID assumption"
+	}
+
+	log.Info().
+		Str("request_id", requestID).
+		Str("test_type", testType).
+		Int("sample_count", len(data)).
+		Uint32("bits_per_symbol", req.BitsPerSymbol).
+		Int64("execution_time_ms", time.Since(startTime).Milliseconds()).
+		Bool("iid_check_passed", passed).
+		Msg("AssessEntropy IID check completed")
+
+	return &pb.Sp80090BAssessmentResponse{
+		IidResults:        convertEstimatorsToProto(tests),
+		Passed:            passed,
+		AssessmentSummary: summary,
+		SampleCount:       uint64(len(data)),
+		BitsPerSymbol:     req.BitsPerSymbol,
+	}, nil
+}
+
+// validateEstimatorSelection checks the requested estimator names against
+// every enabled mode before any computation starts.
+func validateEstimatorSelection(req *pb.Sp80090BAssessmentRequest) error {
+	if len(req.Estimators) == 0 {
+		return nil
+	}
+	if req.IidMode {
+		if err := entropy.ValidateEstimators(entropy.IID, req.Estimators); err != nil {
+			return err
+		}
+	}
+	if req.NonIidMode {
+		if err := entropy.ValidateEstimators(entropy.NonIID, req.Estimators); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bitOrderFromProto maps the requested bit order onto the entropy package,
+// rejecting values this server does not know.
+func bitOrderFromProto(order pb.BitOrder) (entropy.BitOrder, error) {
+	switch order {
+	case pb.BitOrder_BIT_ORDER_MSB_FIRST:
+		return entropy.MSBFirst, nil
+	case pb.BitOrder_BIT_ORDER_LSB_FIRST:
+		return entropy.LSBFirst, nil
+	default:
+		return entropy.MSBFirst, fmt.Errorf("unknown bit_order %d", int32(order))
+	}
+}
+
+// estimatorIDs maps the entropy package identifiers onto the proto enum.
+var estimatorIDs = map[entropy.EstimatorID]pb.EstimatorId{
+	entropy.EstimatorMCV:         pb.EstimatorId_ESTIMATOR_ID_MCV,
+	entropy.EstimatorCollision:   pb.EstimatorId_ESTIMATOR_ID_COLLISION,
+	entropy.EstimatorMarkov:      pb.EstimatorId_ESTIMATOR_ID_MARKOV,
+	entropy.EstimatorCompression: pb.EstimatorId_ESTIMATOR_ID_COMPRESSION,
+	entropy.EstimatorTTuple:      pb.EstimatorId_ESTIMATOR_ID_T_TUPLE,
+	entropy.EstimatorLRS:         pb.EstimatorId_ESTIMATOR_ID_LRS,
+	entropy.EstimatorMultiMCW:    pb.EstimatorId_ESTIMATOR_ID_MULTI_MCW,
+	entropy.EstimatorLag:         pb.EstimatorId_ESTIMATOR_ID_LAG,
+	entropy.EstimatorMultiMMC:    pb.EstimatorId_ESTIMATOR_ID_MULTI_MMC,
+	entropy.EstimatorLZ78Y:       pb.EstimatorId_ESTIMATOR_ID_LZ78Y,
+	entropy.EstimatorChiSquare:   pb.EstimatorId_ESTIMATOR_ID_CHI_SQUARE,
+	entropy.EstimatorPermutation: pb.EstimatorId_ESTIMATOR_ID_PERMUTATION,
+}
+
+// estimatorIDToProto maps an estimator identifier onto the proto enum.
+// Identifiers without a proto value are reported as ESTIMATOR_ID_UNKNOWN.
+func estimatorIDToProto(id entropy.EstimatorID) pb.EstimatorId {
+	return estimatorIDs[id]
+}
+
+// recordAbandonedWork counts a timed-out in-process assessment whose C++
+// computation keeps running after the request has been answered.
+func (s *GRPCServer) recordAbandonedWork(testType string, err error) {
+	if errors.Is(err, entropy.ErrAssessmentTimeout) && s.svc.Isolation() == entropy.IsolationInProcess {
+		metrics.RecordAbandonedAssessment(testType)
+	}
+}
+
+// knownEstimatorLabels holds the estimator names that may appear as metric
+// labels. Anything else is recorded as "other" so that unexpected names
+// cannot grow the label set.
+var knownEstimatorLabels = func() map[string]bool {
+	known := make(map[string]bool)
+	for _, testType := range []entropy.TestType{entropy.IID, entropy.NonIID} {
+		for _, name := range entropy.SupportedEstimators(testType) {
+			known[name] = true
+		}
+	}
+	return known
+}()
+
+// recordEstimatorEntropy observes the estimate of every estimator that
+// produced a valid entropy value.
+func recordEstimatorEntropy(testType string, estimators []entropy.EstimatorResult) {
+	for _, est := range estimators {
+		if !est.IsEntropyValid {
+			continue
+		}
+		label := est.Name
+		if !knownEstimatorLabels[label] {
+			label = "other"
+		}
+		metrics.RecordEstimatorEntropy(testType, label, est.EntropyEstimate)
+	}
+}
+
+// logAssessmentFailure logs a failed assessment together with its
+// classification, the gRPC code the error is reported with.
+func logAssessmentFailure(requestID, testType string, err error) {
+	log.Error().
+		Err(err).
+		Str("request_id", requestID).
+		Str("test_type", testType).
+		Str("error_code", assessmentErrorCode(err).String()).
+		Msg("AssessEntropy assessment failed")
+}
+
+// logEstimatorResults logs the per-estimator breakdown of an assessment at
+// debug level.
+func logEstimatorResults(requestID, testType string, estimators []entropy.EstimatorResult) {
+	for _, est := range estimators {
+		log.Debug().
+			Str("request_id", requestID).
+			Str("test_type", testType).
+			Str("estimator", est.Name).
+			Float64("entropy_estimate", est.EntropyEstimate).
+			Bool("entropy_valid", est.IsEntropyValid).
+			Bool("passed", est.Passed).
+			Msg("AssessEntropy estimator result")
+	}
+}
+
+// assessmentErrorCode maps an assessment failure to a gRPC status code. Input
+// problems remain InvalidArgument and datasets below the minimum sample count
+// FailedPrecondition, while a crashed isolated child or a C++ exception is
+// reported as Internal, a dataset over the memory budget or an allocation
+// failure as ResourceExhausted, and timeouts and context errors keep their
+// deadline semantics.
+func assessmentErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, entropy.ErrAssessmentCrashed), errors.Is(err, entropy.ErrCException):
+		return codes.Internal
+	case errors.Is(err, entropy.ErrAssessmentTimeout), errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, entropy.ErrInsufficientData):
+		return codes.FailedPrecondition
+	case errors.Is(err, entropy.ErrResourceLimit), errors.Is(err, entropy.ErrMemoryAllocation):
+		return codes.ResourceExhausted
+	default:
+		return codes.InvalidArgument
+	}
+}
+
+// addTimings adds the phase timings of one result to timings, in
+// milliseconds.
+func addTimings(timings map[string]float64, phases map[string]time.Duration) {
+	for phase, d := range phases {
+		timings[phase] = float64(d) / float64(time.Millisecond)
+	}
+}
+
+// minEntropySource names the mode whose estimate is min_entropy, given the
+// minimum of each mode and +Inf for a mode that did not run or produced no
+// estimate. A tie goes to Non-IID, the conservative result for data that
+// may fail the IID tests.
+func minEntropySource(iid, nonIID float64) pb.MinEntropySource {
+	switch {
+	case math.IsInf(iid, 1) && math.IsInf(nonIID, 1):
+		return pb.MinEntropySource_MIN_ENTROPY_SOURCE_NONE
+	case nonIID <= iid:
+		return pb.MinEntropySource_MIN_ENTROPY_SOURCE_NON_IID
+	default:
+		return pb.MinEntropySource_MIN_ENTROPY_SOURCE_IID
+	}
+}
+
 // convertEstimatorsToProto maps internal EstimatorResult values to their
-// protobuf representation. Entropy estimators include the estimate in the
-// details map; statistical tests (where the estimate is not valid) are
-// described as such in the description field.
+// protobuf representation. The details map carries the estimator's own
+// details, plus the estimate for entropy estimators; statistical tests
+// (where the estimate is not valid) are described as such in the
+// description field.
 func convertEstimatorsToProto(estimators []entropy.EstimatorResult) []*pb.Sp80090BEstimatorResult {
 	if len(estimators) == 0 {
 		return nil
@@ -162,7 +646,10 @@ func convertEstimatorsToProto(estimators []entropy.EstimatorResult) []*pb.Sp8009
 
 	results := make([]*pb.Sp80090BEstimatorResult, len(estimators))
 	for i, est := range estimators {
-		details := make(map[string]float64)
+		details := make(map[string]float64, len(est.Details)+1)
+		for key, value := range est.Details {
+			details[key] = value
+		}
 		if est.IsEntropyValid {
 			details["entropy_estimate"] = est.EntropyEstimate
 		}
@@ -176,6 +663,7 @@ func convertEstimatorsToProto(estimators []entropy.EstimatorResult) []*pb.Sp8009
 
 		results[i] = &pb.Sp80090BEstimatorResult{
 			Name:            est.Name,
+			Id:              estimatorIDToProto(est.ID),
 			EntropyEstimate: est.EntropyEstimate,
 			Passed:          est.Passed,
 			Details:         details,
@@ -3,14 +3,29 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/metrics"
+	"github.com/AmmannChristian/nist-800-90b/internal/middleware"
 	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
 )
 
@@ -33,6 +48,8 @@ func TestAssessEntropySuccessModes(t *testing.T) {
 	assert.Greater(t, resp.MinEntropy, 0.0)
 	// Verify first estimator is Most Common Value
 	assert.Equal(t, "Most Common Value", resp.IidResults[0].Name)
+	assert.Equal(t, pb.EstimatorId_ESTIMATOR_ID_MCV, resp.IidResults[0].Id)
+	assert.Equal(t, pb.EstimatorId_ESTIMATOR_ID_LRS, resp.IidResults[2].Id)
 
 	// Non-IID, returns 10 individual estimator results
 	resp, err = server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
@@ -46,6 +63,9 @@ func TestAssessEntropySuccessModes(t *testing.T) {
 	assert.Len(t, resp.NonIidResults, 10) // All 10 Non-IID estimators
 	// Verify first estimator is Most Common Value
 	assert.Equal(t, "Most Common Value", resp.NonIidResults[0].Name)
+	for _, est := range resp.NonIidResults {
+		assert.NotEqual(t, pb.EstimatorId_ESTIMATOR_ID_UNKNOWN, est.Id, est.Name)
+	}
 
 	// Mixed mode
 	resp, err = server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
@@ -58,9 +78,66 @@ func TestAssessEntropySuccessModes(t *testing.T) {
 	assert.Len(t, resp.IidResults, 4)
 	assert.Len(t, resp.NonIidResults, 10)
 	assert.True(t, resp.Passed)
+	assert.Equal(t, "stub", resp.Backend)
+	assert.False(t, resp.Partial)
+	assert.InDelta(t, 2.0, resp.ShannonEntropy, 1e-12)
+	assert.Equal(t, uint32(4), resp.AlphabetSize)
 }
 
-func TestAssessEntropyUsedBitsFallback(t *testing.T) {
+func TestAssessEntropyReportsProgressFromContext(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	var phases []string
+	ctx := WithProgress(context.Background(), func(phase string, percent float64) {
+		phases = append(phases, phase)
+	})
+	_, err := server.AssessEntropy(ctx, &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4},
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, phases)
+	assert.Equal(t, entropy.ProgressDone, phases[len(phases)-1])
+}
+
+func TestAssessEntropyMinEntropySource(t *testing.T) {
+	server := NewGRPCServer(NewService())
+	data := []byte{1, 2, 3, 4}
+
+	// The stub reports 7.5 for IID and 6.5 for Non-IID; h_original and
+	// h_bitstring follow the mode of min_entropy.
+	for _, tc := range []struct {
+		name        string
+		iid, nonIID bool
+		min         float64
+		want        pb.MinEntropySource
+		hOriginal   float64
+		hBitstring  float64
+	}{
+		{"IID only", true, false, 7.5, pb.MinEntropySource_MIN_ENTROPY_SOURCE_IID, 7.6, 7.1},
+		{"Non-IID only", false, true, 6.5, pb.MinEntropySource_MIN_ENTROPY_SOURCE_NON_IID, 6.6, 6.1},
+		{"mixed", true, true, 6.5, pb.MinEntropySource_MIN_ENTROPY_SOURCE_NON_IID, 6.6, 6.1},
+	} {
+		resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+			Data: data, BitsPerSymbol: 8, IidMode: tc.iid, NonIidMode: tc.nonIID,
+		})
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.min, resp.MinEntropy, tc.name)
+		assert.Equal(t, tc.want, resp.MinEntropySource, tc.name)
+		assert.Equal(t, tc.hOriginal, resp.HOriginal, tc.name)
+		assert.Equal(t, tc.hBitstring, resp.HBitstring, tc.name)
+	}
+
+	resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data: data, BitsPerSymbol: 8, IidMode: true, IidCheckOnly: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, pb.MinEntropySource_MIN_ENTROPY_SOURCE_NONE, resp.MinEntropySource)
+	assert.Zero(t, resp.HOriginal)
+}
+
+func TestAssessEntropyReportsDetectedBits(t *testing.T) {
 	server := NewGRPCServer(NewService())
 	data := []byte{1, 2, 3, 4}
 
@@ -72,7 +149,44 @@ func TestAssessEntropyUsedBitsFallback(t *testing.T) {
 	})
 	require.NoError(t, err)
 	assert.Len(t, resp.IidResults, 4) // 4 IID estimators
+	assert.Equal(t, uint32(3), resp.BitsPerSymbol)
+	assert.True(t, resp.BitsPerSymbolAutoDetected)
+
+	resp, err = server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          data,
+		BitsPerSymbol: 0,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), resp.BitsPerSymbol)
+	assert.True(t, resp.BitsPerSymbolAutoDetected)
+}
+
+func TestAssessEntropyExplicitBitsNotAutoDetected(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(8), resp.BitsPerSymbol)
+	assert.False(t, resp.BitsPerSymbolAutoDetected)
+}
+
+func TestAssessEntropyUsedBitsFallback(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	// The 0xEE stub reports no word size, so the request's value is echoed.
+	resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{0xEE, 1, 2},
+		BitsPerSymbol: 0,
+		IidMode:       true,
+	})
+	require.NoError(t, err)
 	assert.Equal(t, uint32(0), resp.BitsPerSymbol)
+	assert.False(t, resp.BitsPerSymbolAutoDetected)
 }
 
 func TestAssessEntropyIIDError(t *testing.T) {
@@ -105,6 +219,51 @@ func TestAssessEntropyNonIIDError(t *testing.T) {
 	assert.Contains(t, st.Message(), "Non-IID assessment failed")
 }
 
+func TestAssessEntropySymbolOutOfRange(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	_, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 0x1F, 3},
+		BitsPerSymbol: 4,
+		NonIidMode:    true,
+	})
+	require.Error(t, err)
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Contains(t, st.Message(), "symbol value 31 exceeds the maximum 15 for 4 bits per symbol in 1 of 4 samples")
+}
+
+func TestAssessEntropyMalformedCErrorMessage(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	_, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{0xFA, 1, 2},
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+	})
+	require.Error(t, err)
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Contains(t, st.Message(), "message=injected fault red ")
+	assert.NotContains(t, st.Message(), "\n")
+	assert.NotContains(t, st.Message(), "\x1b")
+}
+
+func TestAssessEntropyCException(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	for sentinel, code := range map[byte]codes.Code{0xFB: codes.ResourceExhausted, 0xFD: codes.Internal} {
+		_, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+			Data:          []byte{sentinel, 1, 2},
+			BitsPerSymbol: 8,
+			NonIidMode:    true,
+		})
+		require.Error(t, err)
+		st, _ := status.FromError(err)
+		assert.Equal(t, code, st.Code())
+	}
+}
+
 func TestAssessEntropyInfinityFallback(t *testing.T) {
 	server := NewGRPCServer(NewService())
 
@@ -118,3 +277,521 @@ func TestAssessEntropyInfinityFallback(t *testing.T) {
 	assert.Equal(t, float64(0), resp.MinEntropy)
 	assert.Equal(t, uint32(8), resp.BitsPerSymbol)
 }
+
+func TestAssessEntropySimulatedCrashIsInternal(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	_, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{0xDD, 1, 2},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+	})
+	require.Error(t, err)
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.Internal, st.Code())
+}
+
+func TestAssessEntropyMinSamplesGuard(t *testing.T) {
+	svc := NewService()
+	svc.SetMinSamples(entropy.MinRecommendedSamples)
+	server := NewGRPCServer(svc)
+
+	for _, req := range []*pb.Sp80090BAssessmentRequest{
+		{Data: []byte{1, 2, 3, 4}, BitsPerSymbol: 8, IidMode: true},
+		{Data: []byte{1, 2, 3, 4}, BitsPerSymbol: 8, NonIidMode: true},
+	} {
+		_, err := server.AssessEntropy(context.Background(), req)
+		require.Error(t, err)
+		st, _ := status.FromError(err)
+		assert.Equal(t, codes.FailedPrecondition, st.Code())
+		assert.Contains(t, st.Message(), "at least 1000000 samples required, got 4")
+	}
+}
+
+func TestAssessEntropyMinSamplesGuardDisabled(t *testing.T) {
+	svc := NewService()
+	svc.SetMinSamples(entropy.MinRecommendedSamples)
+	svc.SetMinSamples(0)
+	server := NewGRPCServer(svc)
+
+	resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4},
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, resp.MinEntropy)
+}
+
+func TestAssessEntropyMemoryBudget(t *testing.T) {
+	svc := NewService()
+	svc.SetMinSamples(0)
+	svc.SetMaxMemory(entropy.EstimateMemory(4, 8, entropy.NonIID))
+	server := NewGRPCServer(svc)
+
+	resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4},
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, resp.MinEntropy)
+
+	_, err = server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4, 5},
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+	})
+	require.Error(t, err)
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Contains(t, st.Message(), "exceeds the budget")
+}
+
+func TestAssessEntropyTimeoutRecordsAbandonedWork(t *testing.T) {
+	metrics.AbandonedAssessmentsTotal.Reset()
+
+	svc := NewService()
+	svc.SetTimeout(50 * time.Millisecond)
+	server := NewGRPCServer(svc)
+
+	_, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{0xCC, 1, 2},
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+	})
+	require.Error(t, err)
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.DeadlineExceeded, st.Code())
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.AbandonedAssessmentsTotal.WithLabelValues("Non-IID")))
+}
+
+func TestAssessEntropyRecordsEstimatorEntropy(t *testing.T) {
+	metrics.EstimatorEntropyValue.Reset()
+	server := NewGRPCServer(NewService())
+
+	_, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+	})
+	require.NoError(t, err)
+
+	// Only the Most Common Value estimate is a valid entropy value in the
+	// stubbed IID results; the pass/fail tests are not observed.
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.EstimatorEntropyValue))
+	var m dto.Metric
+	observer := metrics.EstimatorEntropyValue.WithLabelValues("IID", "Most Common Value")
+	require.NoError(t, observer.(prometheus.Metric).Write(&m))
+	assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	assert.Equal(t, 7.6, m.GetHistogram().GetSampleSum())
+}
+
+func TestAssessEntropyRecordsDataSizePerRun(t *testing.T) {
+	metrics.DataSizeBytes.Reset()
+	server := NewGRPCServer(NewService())
+
+	_, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+
+	// A mixed-mode request runs both assessments and observes the data
+	// once for each.
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.DataSizeBytes))
+	for _, testType := range []string{"IID", "Non-IID"} {
+		count, sum := dataSizeObservations(t, testType)
+		assert.Equal(t, uint64(1), count, testType)
+		assert.Equal(t, 4.0, sum, testType)
+	}
+}
+
+func TestAssessEntropyHSubmitter(t *testing.T) {
+	server := NewGRPCServer(NewService())
+	data := []byte{1, 2, 3, 4}
+
+	// Without a claim h_final follows min_entropy
+	resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          data,
+		BitsPerSymbol: 8,
+		IidMode:       true,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, resp.MinEntropy, resp.HFinal)
+	assert.False(t, resp.SubmitterBinding)
+
+	// A low claim becomes the binding constraint
+	resp, err = server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          data,
+		BitsPerSymbol: 8,
+		IidMode:       true,
+		NonIidMode:    true,
+		HSubmitter:    proto.Float64(3),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, resp.MinEntropy)
+	assert.Equal(t, 3.0, resp.HFinal)
+	assert.True(t, resp.SubmitterBinding)
+
+	// A claim between the IID and Non-IID estimates is not binding overall
+	resp, err = server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          data,
+		BitsPerSymbol: 8,
+		IidMode:       true,
+		NonIidMode:    true,
+		HSubmitter:    proto.Float64(7),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, resp.HFinal)
+	assert.False(t, resp.SubmitterBinding)
+}
+
+func TestAssessEntropyEstimatorSubset(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4},
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+		Estimators:    []string{"mcv", "markov"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6.7, resp.MinEntropy)
+	require.Len(t, resp.NonIidResults, 2)
+	assert.Equal(t, "Most Common Value", resp.NonIidResults[0].Name)
+	assert.Equal(t, "Markov Test", resp.NonIidResults[1].Name)
+	assert.Contains(t, resp.AssessmentSummary, "non-conforming")
+}
+
+func TestAssessEntropyIIDCheckOnly(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4},
+		BitsPerSymbol: 8,
+		IidCheckOnly:  true,
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Passed)
+	assert.Zero(t, resp.MinEntropy)
+	require.Len(t, resp.IidResults, 3)
+	assert.Equal(t, "Chi-Square Tests", resp.IidResults[0].Name)
+	assert.Contains(t, resp.AssessmentSummary, "IID check passed")
+
+	resp, err = server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{0xBB, 2, 3, 4},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+		IidCheckOnly:  true,
+	})
+	require.NoError(t, err)
+	assert.False(t, resp.Passed)
+	assert.Contains(t, resp.AssessmentSummary, "IID check failed")
+
+	_, err = server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{0xFF, 2, 3, 4},
+		BitsPerSymbol: 8,
+		IidCheckOnly:  true,
+	})
+	require.Error(t, err)
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+}
+
+func TestGetCapabilitiesReportsStub(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	resp, err := server.GetCapabilities(context.Background(), &pb.Sp80090BCapabilitiesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, Version, resp.ServiceVersion)
+	assert.Equal(t, "stub", resp.ToolVersion)
+	assert.False(t, resp.Cgo)
+	assert.Contains(t, resp.Estimators, "markov")
+	assert.Equal(t, "stub", resp.Backend)
+	assert.Equal(t, entropy.ValidEstimatorNames(entropy.IID), resp.IidEstimators)
+	assert.Equal(t, entropy.ValidEstimatorNames(entropy.NonIID), resp.NonIidEstimators)
+}
+
+func TestGetSupportedEstimators(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	resp, err := server.GetSupportedEstimators(context.Background(), &pb.Sp80090BSupportedEstimatorsRequest{})
+	require.NoError(t, err)
+	assert.Contains(t, resp.NonIid, "Collision Test")
+	assert.Contains(t, resp.NonIid, "Markov Test")
+	assert.Contains(t, resp.NonIid, "Compression Test")
+	assert.Len(t, resp.NonIid, 10)
+	assert.Equal(t, []string{
+		"Most Common Value", "Chi-Square Tests",
+		"Length of Longest Repeated Substring Test", "Permutation Tests",
+	}, resp.Iid)
+}
+
+func TestAssessEntropy_Histogram(t *testing.T) {
+	server := NewGRPCServer(NewService())
+	req := &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{3, 3, 3, 4},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+		NonIidMode:    true,
+	}
+
+	resp, err := server.AssessEntropy(context.Background(), req)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Histogram)
+
+	req.IncludeHistogram = true
+	resp, err = server.AssessEntropy(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, resp.Histogram, 256)
+	assert.Equal(t, uint64(3), resp.Histogram[3])
+	assert.Equal(t, uint64(1), resp.Histogram[4])
+}
+
+func TestAssessEntropy_RawJSON(t *testing.T) {
+	server := NewGRPCServer(NewService())
+	req := &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{3, 3, 3, 4},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+		NonIidMode:    true,
+	}
+
+	resp, err := server.AssessEntropy(context.Background(), req)
+	require.NoError(t, err)
+	assert.Empty(t, resp.IidRawJson)
+	assert.Empty(t, resp.NonIidRawJson)
+
+	req.IncludeRawJson = true
+	resp, err = server.AssessEntropy(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, json.Valid([]byte(resp.IidRawJson)))
+	assert.True(t, json.Valid([]byte(resp.NonIidRawJson)))
+	assert.Contains(t, resp.IidRawJson, `"IID" : true`)
+	assert.Contains(t, resp.NonIidRawJson, `"IID" : false`)
+}
+
+func TestAssessEntropy_Timings(t *testing.T) {
+	server := NewGRPCServer(NewService())
+	req := &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+		NonIidMode:    true,
+	}
+
+	resp, err := server.AssessEntropy(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, resp.TimingsMs, 2)
+	assert.Contains(t, resp.TimingsMs, "IID")
+	assert.Contains(t, resp.TimingsMs, "Non-IID")
+	for phase, ms := range resp.TimingsMs {
+		assert.GreaterOrEqual(t, ms, 0.0, phase)
+	}
+
+	req.IidMode = false
+	resp, err = server.AssessEntropy(context.Background(), req)
+	require.NoError(t, err)
+	assert.Len(t, resp.TimingsMs, 1)
+	assert.Contains(t, resp.TimingsMs, "Non-IID")
+}
+
+func TestAssessEntropy_Window(t *testing.T) {
+	server := NewGRPCServer(NewService())
+	req := &pb.Sp80090BAssessmentRequest{
+		Data:             []byte{0xFF, 3, 3, 4, 0xFF},
+		BitsPerSymbol:    8,
+		NonIidMode:       true,
+		IncludeHistogram: true,
+		Offset:           1,
+		Length:           3,
+	}
+
+	resp, err := server.AssessEntropy(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), resp.SampleCount)
+	assert.Equal(t, uint64(2), resp.Histogram[3])
+	assert.Zero(t, resp.Histogram[0xFF])
+
+	req.Length = 0
+	resp, err = server.AssessEntropy(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), resp.SampleCount)
+	assert.Equal(t, uint64(1), resp.Histogram[0xFF])
+}
+
+func TestAssessEntropyFile_PermittedFile(t *testing.T) {
+	baseDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(baseDir, "captures"), 0o755))
+	path := filepath.Join(baseDir, "captures", "run1.bin")
+	require.NoError(t, os.WriteFile(path, []byte{1, 2, 3, 4, 5}, 0o600))
+
+	server := NewGRPCServer(NewService())
+	server.SetFileBaseDir(baseDir)
+
+	for _, requested := range []string{"captures/run1.bin", path} {
+		resp, err := server.AssessEntropyFile(context.Background(), &pb.Sp80090BFileAssessmentRequest{
+			Path:          requested,
+			BitsPerSymbol: 8,
+			NonIidMode:    true,
+		})
+		require.NoError(t, err, requested)
+		assert.Equal(t, uint64(5), resp.SampleCount)
+		assert.InDelta(t, 6.5, resp.MinEntropy, 1e-9)
+		assert.Len(t, resp.NonIidResults, 10)
+	}
+}
+
+func TestAssessEntropyFile_Window(t *testing.T) {
+	baseDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "run.bin"), []byte{1, 2, 3, 4, 5}, 0o600))
+
+	server := NewGRPCServer(NewService())
+	server.SetFileBaseDir(baseDir)
+
+	resp, err := server.AssessEntropyFile(context.Background(), &pb.Sp80090BFileAssessmentRequest{
+		Path:          "run.bin",
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+		Offset:        2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), resp.SampleCount)
+
+	_, err = server.AssessEntropyFile(context.Background(), &pb.Sp80090BFileAssessmentRequest{
+		Path:          "run.bin",
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+		Offset:        5,
+	})
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+}
+
+func TestAssessEntropyFile_NotLimitedByUploadSize(t *testing.T) {
+	baseDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "run.bin"), []byte{1, 2, 3, 4, 5}, 0o600))
+
+	server := NewGRPCServer(NewService())
+	server.SetFileBaseDir(baseDir)
+	server.SetMaxUploadSize(4)
+
+	resp, err := server.AssessEntropyFile(context.Background(), &pb.Sp80090BFileAssessmentRequest{
+		Path:          "run.bin",
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), resp.SampleCount)
+}
+
+// captureLog redirects the global logger to a buffer at debug level for the
+// duration of the test and returns a function decoding the entries written
+// so far.
+func captureLog(t *testing.T) func() []map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	origLogger, origLevel := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&buf)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	t.Cleanup(func() {
+		log.Logger = origLogger
+		zerolog.SetGlobalLevel(origLevel)
+	})
+
+	return func() []map[string]interface{} {
+		var entries []map[string]interface{}
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal(line, &entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+// findLogEntries returns the entries with the given message.
+func findLogEntries(entries []map[string]interface{}, message string) []map[string]interface{} {
+	var found []map[string]interface{}
+	for _, e := range entries {
+		if e["message"] == message {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func TestAssessEntropyLogsOutcome(t *testing.T) {
+	entries := captureLog(t)
+	server := NewGRPCServer(NewService())
+	req := &pb.Sp80090BAssessmentRequest{Data: []byte{1, 2, 3, 4}, BitsPerSymbol: 8, NonIidMode: true}
+
+	var requestID string
+	_, err := middleware.UnaryRequestIDInterceptor()(context.Background(), req, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			requestID = middleware.GetRequestID(ctx)
+			return server.AssessEntropy(ctx, req.(*pb.Sp80090BAssessmentRequest))
+		})
+	require.NoError(t, err)
+	require.NotEmpty(t, requestID)
+
+	completed := findLogEntries(entries(), "AssessEntropy completed successfully")
+	require.Len(t, completed, 1)
+	e := completed[0]
+	assert.Equal(t, "info", e["level"])
+	assert.Equal(t, requestID, e["request_id"])
+	assert.Equal(t, "Non-IID", e["test_type"])
+	assert.Equal(t, 4.0, e["sample_count"])
+	assert.Equal(t, 8.0, e["bits_per_symbol"])
+	assert.Equal(t, 6.5, e["min_entropy"])
+	assert.Equal(t, true, e["passed"])
+
+	breakdown := findLogEntries(entries(), "AssessEntropy estimator result")
+	require.Len(t, breakdown, 10)
+	assert.Equal(t, "debug", breakdown[0]["level"])
+	assert.Equal(t, requestID, breakdown[0]["request_id"])
+	assert.Equal(t, "Most Common Value", breakdown[0]["estimator"])
+	assert.Equal(t, 6.8, breakdown[0]["entropy_estimate"])
+}
+
+func TestAssessEntropyVerbosity(t *testing.T) {
+	entries := captureLog(t)
+	server := NewGRPCServer(NewService())
+
+	for _, verbosity := range []uint32{2, 99} {
+		resp, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+			Data: []byte{1, 2, 3, 4}, BitsPerSymbol: 8, NonIidMode: true, Verbosity: verbosity,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 6.5, resp.MinEntropy)
+	}
+
+	received := findLogEntries(entries(), "AssessEntropy request received")
+	require.Len(t, received, 2)
+	assert.Equal(t, 2.0, received[0]["verbosity"])
+	assert.Equal(t, 3.0, received[1]["verbosity"], "out-of-range verbosity is clamped")
+}
+
+func TestAssessEntropyLogsFailureClassification(t *testing.T) {
+	entries := captureLog(t)
+	server := NewGRPCServer(NewService())
+
+	_, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{0xDD, 1, 2},
+		BitsPerSymbol: 8,
+		IidMode:       true,
+	})
+	require.Error(t, err)
+
+	failed := findLogEntries(entries(), "AssessEntropy assessment failed")
+	require.Len(t, failed, 1)
+	assert.Equal(t, "error", failed[0]["level"])
+	assert.Equal(t, "IID", failed[0]["test_type"])
+	assert.Equal(t, codes.Internal.String(), failed[0]["error_code"])
+	assert.Contains(t, failed[0]["error"], "stub simulated abort")
+	assert.Empty(t, findLogEntries(entries(), "AssessEntropy estimator result"))
+}
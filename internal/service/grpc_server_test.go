@@ -2,12 +2,24 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/metrics"
 	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
 )
 
@@ -44,6 +56,88 @@ func TestAssessEntropyValidation(t *testing.T) {
 			},
 			code: codes.InvalidArgument,
 		},
+		{
+			name: "negative h_submitter",
+			req: &pb.Sp80090BAssessmentRequest{
+				Data:          []byte{1, 2, 3},
+				IidMode:       true,
+				BitsPerSymbol: 8,
+				HSubmitter:    proto.Float64(-1),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "h_submitter above bits",
+			req: &pb.Sp80090BAssessmentRequest{
+				Data:          []byte{1, 2, 3},
+				IidMode:       true,
+				BitsPerSymbol: 1,
+				HSubmitter:    proto.Float64(1.5),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "unknown estimator",
+			req: &pb.Sp80090BAssessmentRequest{
+				Data:          []byte{1, 2, 3},
+				NonIidMode:    true,
+				BitsPerSymbol: 8,
+				Estimators:    []string{"mcv", "bogus"},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "non-iid estimator in iid mode",
+			req: &pb.Sp80090BAssessmentRequest{
+				Data:          []byte{1, 2, 3},
+				IidMode:       true,
+				NonIidMode:    true,
+				BitsPerSymbol: 8,
+				Estimators:    []string{"markov"},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "iid_check_only with non_iid_mode",
+			req: &pb.Sp80090BAssessmentRequest{
+				Data:          []byte{1, 2, 3},
+				NonIidMode:    true,
+				IidCheckOnly:  true,
+				BitsPerSymbol: 8,
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "offset beyond data",
+			req: &pb.Sp80090BAssessmentRequest{
+				Data:          []byte{1, 2, 3},
+				IidMode:       true,
+				BitsPerSymbol: 8,
+				Offset:        3,
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "window past end of data",
+			req: &pb.Sp80090BAssessmentRequest{
+				Data:          []byte{1, 2, 3},
+				IidMode:       true,
+				BitsPerSymbol: 8,
+				Offset:        1,
+				Length:        3,
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "unknown bit_order",
+			req: &pb.Sp80090BAssessmentRequest{
+				Data:          []byte{1, 2, 3},
+				NonIidMode:    true,
+				BitsPerSymbol: 8,
+				BitOrder:      pb.BitOrder(7),
+			},
+			code: codes.InvalidArgument,
+		},
 		{
 			name: "no mode selected",
 			req: &pb.Sp80090BAssessmentRequest{
@@ -65,8 +159,420 @@ func TestAssessEntropyValidation(t *testing.T) {
 	}
 }
 
+func TestAssessEntropyRejectsDataOverUploadLimit(t *testing.T) {
+	server := NewGRPCServer(NewService())
+	server.SetMaxUploadSize(4)
+
+	_, err := server.AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data: []byte{1, 2, 3, 4, 5}, NonIidMode: true, BitsPerSymbol: 8,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "data of 5 bytes exceeds the upload limit of 4 bytes")
+}
+
+// checkOutcomes maps the name of every check in resp to "passed", "skipped"
+// or "failed".
+func checkOutcomes(resp *pb.Sp80090BValidateResponse) map[string]string {
+	outcomes := make(map[string]string)
+	for _, c := range resp.Checks {
+		switch {
+		case c.Skipped:
+			outcomes[c.Name] = "skipped"
+		case c.Passed:
+			outcomes[c.Name] = "passed"
+		default:
+			outcomes[c.Name] = "failed"
+		}
+	}
+	return outcomes
+}
+
+func TestValidateRequest(t *testing.T) {
+	svc := NewService()
+	svc.SetMinSamples(0)
+	server := NewGRPCServer(svc)
+	server.SetMaxUploadSize(1024)
+
+	resp, err := server.ValidateRequest(context.Background(), &pb.Sp80090BValidateRequest{
+		Request: &pb.Sp80090BAssessmentRequest{Data: []byte{1, 2, 3}, NonIidMode: true, BitsPerSymbol: 2, Offset: 1},
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Valid)
+	assert.Equal(t, uint64(1024), resp.MaxUploadSize)
+	assert.Equal(t, uint64(0), resp.MinSamples)
+	var names []string
+	for _, c := range resp.Checks {
+		names = append(names, c.Name)
+		assert.True(t, c.Passed, c.Name)
+		assert.Empty(t, c.Message, c.Name)
+	}
+	assert.Equal(t, append(append([]string(nil), requestCheckNames...), "sample_count", "symbol_range"), names)
+
+	// iid_check_only does not use the mode, bit order and estimators.
+	resp, err = server.ValidateRequest(context.Background(), &pb.Sp80090BValidateRequest{
+		Request: &pb.Sp80090BAssessmentRequest{Data: []byte{1, 2, 3}, IidCheckOnly: true, BitOrder: pb.BitOrder(7)},
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Valid)
+	outcomes := checkOutcomes(resp)
+	assert.Equal(t, "skipped", outcomes["mode"])
+	assert.Equal(t, "skipped", outcomes["bit_order"])
+	assert.Equal(t, "skipped", outcomes["estimators"])
+}
+
+func TestValidateRequest_DataSize(t *testing.T) {
+	svc := NewService()
+	svc.SetMinSamples(1000)
+	server := NewGRPCServer(svc)
+	server.SetMaxUploadSize(4096)
+
+	// The parameters are checked before the data is sent.
+	resp, err := server.ValidateRequest(context.Background(), &pb.Sp80090BValidateRequest{
+		Request:  &pb.Sp80090BAssessmentRequest{NonIidMode: true, BitsPerSymbol: 4},
+		DataSize: 2000,
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Valid)
+	assert.Equal(t, uint64(1000), resp.MinSamples)
+	outcomes := checkOutcomes(resp)
+	assert.Equal(t, "passed", outcomes["size"])
+	assert.Equal(t, "passed", outcomes["sample_count"])
+	assert.Equal(t, "skipped", outcomes["symbol_range"])
+
+	for name, tc := range map[string]struct {
+		size   uint64
+		req    *pb.Sp80090BAssessmentRequest
+		failed string
+	}{
+		"too large":  {5000, &pb.Sp80090BAssessmentRequest{IidMode: true}, "size"},
+		"too small":  {999, &pb.Sp80090BAssessmentRequest{IidMode: true}, "sample_count"},
+		"no mode":    {2000, &pb.Sp80090BAssessmentRequest{}, "mode"},
+		"empty":      {0, &pb.Sp80090BAssessmentRequest{IidMode: true}, "data"},
+		"bad bits":   {2000, &pb.Sp80090BAssessmentRequest{IidMode: true, BitsPerSymbol: nineBits()}, "bits_per_symbol"},
+		"bad window": {2000, &pb.Sp80090BAssessmentRequest{IidMode: true, Offset: 10}, ""},
+	} {
+		resp, err := server.ValidateRequest(context.Background(), &pb.Sp80090BValidateRequest{Request: tc.req, DataSize: tc.size})
+		require.NoError(t, err, name)
+		if tc.failed == "" {
+			// A window cannot be checked without the data.
+			assert.True(t, resp.Valid, name)
+			assert.Equal(t, "skipped", checkOutcomes(resp)["window"], name)
+			assert.Equal(t, "skipped", checkOutcomes(resp)["sample_count"], name)
+			continue
+		}
+		assert.False(t, resp.Valid, name)
+		assert.Equal(t, "failed", checkOutcomes(resp)[tc.failed], name)
+	}
+}
+
+// TestValidateRequest_FailingChecks covers every check that can fail, and
+// that AssessEntropy rejects the same requests with the same message.
+func TestValidateRequest_FailingChecks(t *testing.T) {
+	svc := NewService()
+	svc.SetMinSamples(3)
+	server := NewGRPCServer(svc)
+	server.SetMaxUploadSize(8)
+
+	valid := func() *pb.Sp80090BAssessmentRequest {
+		return &pb.Sp80090BAssessmentRequest{Data: []byte{1, 2, 3, 0}, NonIidMode: true, BitsPerSymbol: 2}
+	}
+	tests := []struct {
+		name    string
+		modify  func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest
+		check   string
+		message string
+		skipped []string
+	}{
+		{
+			name:    "nil request",
+			modify:  func(*pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest { return nil },
+			check:   "request",
+			message: "request cannot be nil",
+			skipped: []string{"data", "window", "mode", "sample_count", "symbol_range"},
+		},
+		{
+			name:    "empty data",
+			modify:  func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest { req.Data = nil; return req },
+			check:   "data",
+			message: "data cannot be empty",
+			skipped: []string{"window", "sample_count", "symbol_range"},
+		},
+		{
+			name: "over the upload limit",
+			modify: func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest {
+				req.Data = make([]byte, 9)
+				return req
+			},
+			check:   "size",
+			message: "data of 9 bytes exceeds the upload limit of 8 bytes",
+		},
+		{
+			name:    "window past the data",
+			modify:  func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest { req.Offset = 4; return req },
+			check:   "window",
+			message: "offset 4 is at or beyond the end of the data",
+			skipped: []string{"sample_count", "symbol_range"},
+		},
+		{
+			name: "bits out of range",
+			modify: func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest {
+				req.BitsPerSymbol = nineBits()
+				return req
+			},
+			check:   "bits_per_symbol",
+			message: "bits_per_symbol must be between 0 and 8, got 9",
+			skipped: []string{"symbol_range"},
+		},
+		{
+			name: "h_submitter above bits",
+			modify: func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest {
+				req.HSubmitter = proto.Float64(3)
+				return req
+			},
+			check:   "h_submitter",
+			message: "h_submitter must be between 0 and bits_per_symbol, got 3",
+		},
+		{
+			name: "iid_check_only with non_iid_mode",
+			modify: func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest {
+				req.IidCheckOnly = true
+				return req
+			},
+			check:   "iid_check_only",
+			message: "iid_check_only cannot be combined with non_iid_mode, h_submitter, or estimators",
+			skipped: []string{"mode", "bit_order", "estimators"},
+		},
+		{
+			name: "no mode selected",
+			modify: func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest {
+				req.NonIidMode = false
+				return req
+			},
+			check:   "mode",
+			message: "either iid_mode or non_iid_mode must be enabled",
+		},
+		{
+			name: "unknown bit_order",
+			modify: func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest {
+				req.BitOrder = pb.BitOrder(7)
+				return req
+			},
+			check:   "bit_order",
+			message: "unknown bit_order 7",
+		},
+		{
+			name: "unknown estimator",
+			modify: func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest {
+				req.Estimators = []string{"bogus"}
+				return req
+			},
+			check:   "estimators",
+			message: "bogus",
+		},
+		{
+			name:    "too few samples",
+			modify:  func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest { req.Length = 2; return req },
+			check:   "sample_count",
+			message: "at least 3 samples required, got 2",
+		},
+		{
+			name:    "symbol out of range",
+			modify:  func(req *pb.Sp80090BAssessmentRequest) *pb.Sp80090BAssessmentRequest { req.Data[1] = 7; return req },
+			check:   "symbol_range",
+			message: "symbol value 7 exceeds the maximum 3 for 2 bits per symbol",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.modify(valid())
+			resp, err := server.ValidateRequest(context.Background(), &pb.Sp80090BValidateRequest{Request: req})
+			require.NoError(t, err)
+			assert.False(t, resp.Valid)
+
+			var failed []*pb.Sp80090BValidationCheck
+			for _, c := range resp.Checks {
+				if !c.Passed && !c.Skipped {
+					failed = append(failed, c)
+				}
+			}
+			require.Len(t, failed, 1)
+			assert.Equal(t, tt.check, failed[0].Name)
+			assert.Contains(t, failed[0].Message, tt.message)
+			outcomes := checkOutcomes(resp)
+			for _, name := range tt.skipped {
+				assert.Equal(t, "skipped", outcomes[name], name)
+			}
+
+			_, err = server.AssessEntropy(context.Background(), req)
+			require.Error(t, err)
+			assert.NotEqual(t, codes.OK, status.Code(err))
+			assert.Contains(t, status.Convert(err).Message(), tt.message)
+		})
+	}
+}
+
 // nineBits returns a uint32 value exceeding the valid bits-per-symbol range,
 // used to avoid a compile-time constant overflow warning in test literals.
 func nineBits() uint32 {
 	return 9
 }
+
+func TestAssessmentErrorCode(t *testing.T) {
+	assert.Equal(t, codes.InvalidArgument, assessmentErrorCode(errors.New("bad input")))
+	assert.Equal(t, codes.Internal, assessmentErrorCode(fmt.Errorf("wrapped: %w", entropy.ErrAssessmentCrashed)))
+	assert.Equal(t, codes.DeadlineExceeded, assessmentErrorCode(context.DeadlineExceeded))
+	assert.Equal(t, codes.DeadlineExceeded, assessmentErrorCode(fmt.Errorf("wrapped: %w", entropy.ErrAssessmentTimeout)))
+	assert.Equal(t, codes.Canceled, assessmentErrorCode(context.Canceled))
+	assert.Equal(t, codes.ResourceExhausted, assessmentErrorCode(fmt.Errorf("wrapped: %w", entropy.ErrResourceLimit)))
+	assert.Equal(t, codes.ResourceExhausted, assessmentErrorCode(fmt.Errorf("wrapped: %w", entropy.ErrMemoryAllocation)))
+	assert.Equal(t, codes.Internal, assessmentErrorCode(fmt.Errorf("wrapped: %w", entropy.ErrCException)))
+}
+
+func TestUnaryInFlightInterceptor(t *testing.T) {
+	svc := NewService()
+	interceptor := UnaryInFlightInterceptor(svc)
+
+	var inFlight int
+	handler := func(ctx context.Context, req any) (any, error) {
+		inFlight = svc.InFlight()
+		return "ok", nil
+	}
+
+	resp, err := interceptor(context.Background(), nil,
+		&grpc.UnaryServerInfo{FullMethod: pb.Sp80090BAssessmentService_AssessEntropy_FullMethodName}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Equal(t, 1, inFlight)
+	assert.Equal(t, 0, svc.InFlight())
+
+	_, err = interceptor(context.Background(), nil,
+		&grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, 0, inFlight, "health checks are not tracked")
+}
+
+func TestConvertEstimatorsToProto_Details(t *testing.T) {
+	results := convertEstimatorsToProto([]entropy.EstimatorResult{
+		{Name: "Lag Prediction Test", ID: entropy.EstimatorLag, EntropyEstimate: 0.9, Passed: true, IsEntropyValid: true,
+			Details: map[string]float64{"p_global": 0.53, "p_local": 0.51}},
+		{Name: "Chi-Square Tests", ID: entropy.EstimatorChiSquare, EntropyEstimate: -1, Passed: true},
+	})
+	require.Len(t, results, 2)
+	assert.Equal(t, map[string]float64{"p_global": 0.53, "p_local": 0.51, "entropy_estimate": 0.9}, results[0].Details)
+	assert.Empty(t, results[1].Details)
+	assert.Equal(t, pb.EstimatorId_ESTIMATOR_ID_LAG, results[0].Id)
+	assert.Equal(t, pb.EstimatorId_ESTIMATOR_ID_CHI_SQUARE, results[1].Id)
+}
+
+func TestMinEntropySource(t *testing.T) {
+	inf := math.Inf(1)
+	assert.Equal(t, pb.MinEntropySource_MIN_ENTROPY_SOURCE_IID, minEntropySource(5.9, 6.2))
+	assert.Equal(t, pb.MinEntropySource_MIN_ENTROPY_SOURCE_NON_IID, minEntropySource(6.2, 5.9))
+	assert.Equal(t, pb.MinEntropySource_MIN_ENTROPY_SOURCE_NON_IID, minEntropySource(6.2, 6.2), "a tie goes to Non-IID")
+	assert.Equal(t, pb.MinEntropySource_MIN_ENTROPY_SOURCE_IID, minEntropySource(6.2, inf))
+	assert.Equal(t, pb.MinEntropySource_MIN_ENTROPY_SOURCE_NON_IID, minEntropySource(inf, 6.2))
+	assert.Equal(t, pb.MinEntropySource_MIN_ENTROPY_SOURCE_NONE, minEntropySource(inf, inf))
+}
+
+func TestEstimatorIDToProto(t *testing.T) {
+	assert.Equal(t, pb.EstimatorId_ESTIMATOR_ID_MCV, estimatorIDToProto(entropy.EstimatorMCV))
+	assert.Equal(t, pb.EstimatorId_ESTIMATOR_ID_PERMUTATION, estimatorIDToProto(entropy.EstimatorPermutation))
+	assert.Equal(t, pb.EstimatorId_ESTIMATOR_ID_UNKNOWN, estimatorIDToProto(entropy.EstimatorUnknown))
+	assert.Equal(t, pb.EstimatorId_ESTIMATOR_ID_UNKNOWN, estimatorIDToProto(entropy.EstimatorID(99)))
+}
+
+func TestRecordEstimatorEntropy_BoundedLabels(t *testing.T) {
+	metrics.EstimatorEntropyValue.Reset()
+
+	recordEstimatorEntropy("Non-IID", []entropy.EstimatorResult{
+		{Name: "Markov Test", EntropyEstimate: 6.7, IsEntropyValid: true},
+		{Name: "Unexpected Test 1", EntropyEstimate: 5, IsEntropyValid: true},
+		{Name: "Unexpected Test 2", EntropyEstimate: 4, IsEntropyValid: true},
+		{Name: "Collision Test", EntropyEstimate: -1, IsEntropyValid: false},
+	})
+
+	// Unknown names share one series and invalid estimates are skipped.
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.EstimatorEntropyValue))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.EstimatorEntropyValue.MustCurryWith(map[string]string{"estimator": "other"})))
+}
+
+func TestAssessEntropyFile_PathValidation(t *testing.T) {
+	baseDir := t.TempDir()
+	outsideDir := t.TempDir()
+	outsideFile := filepath.Join(outsideDir, "secret.bin")
+	require.NoError(t, os.WriteFile(outsideFile, []byte{1, 2, 3, 4}, 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(baseDir, "sub"), 0o755))
+	require.NoError(t, os.Symlink(outsideFile, filepath.Join(baseDir, "escape.bin")))
+
+	server := NewGRPCServer(NewService())
+	server.SetFileBaseDir(baseDir)
+
+	tests := []struct {
+		name string
+		path string
+		code codes.Code
+	}{
+		{name: "empty path", path: "", code: codes.InvalidArgument},
+		{name: "relative traversal", path: "../" + filepath.Base(outsideDir) + "/secret.bin", code: codes.PermissionDenied},
+		{name: "traversal through subdirectory", path: "sub/../../secret.bin", code: codes.PermissionDenied},
+		{name: "absolute path outside base", path: outsideFile, code: codes.PermissionDenied},
+		{name: "symlink leaving base", path: "escape.bin", code: codes.PermissionDenied},
+		{name: "missing file", path: "missing.bin", code: codes.NotFound},
+		{name: "missing absolute file", path: filepath.Join(baseDir, "missing.bin"), code: codes.NotFound},
+		{name: "directory", path: "sub", code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.AssessEntropyFile(context.Background(), &pb.Sp80090BFileAssessmentRequest{
+				Path:          tt.path,
+				BitsPerSymbol: 8,
+				NonIidMode:    true,
+			})
+			require.Error(t, err)
+			st, _ := status.FromError(err)
+			assert.Equal(t, tt.code, st.Code())
+		})
+	}
+}
+
+func TestAssessEntropyFile_Disabled(t *testing.T) {
+	server := NewGRPCServer(NewService())
+
+	_, err := server.AssessEntropyFile(context.Background(), &pb.Sp80090BFileAssessmentRequest{Path: "data.bin", NonIidMode: true})
+	require.Error(t, err)
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+
+	_, err = server.AssessEntropyFile(context.Background(), nil)
+	st, _ = status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+}
+
+// BenchmarkAssessEntropy50MB measures the allocations of a mixed-mode
+// request over 50 MB of samples with the linked backend, beyond the payload
+// itself, which both passes read in place. make bench-grpc runs it against
+// the NIST library with -benchmem and -memprofile for pprof.
+func BenchmarkAssessEntropy50MB(b *testing.B) {
+	origLevel := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	b.Cleanup(func() { zerolog.SetGlobalLevel(origLevel) })
+
+	data := make([]byte, 50<<20)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	server := NewGRPCServer(NewService())
+	req := &pb.Sp80090BAssessmentRequest{Data: data, BitsPerSymbol: 8, IidMode: true, NonIidMode: true}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := server.AssessEntropy(context.Background(), req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
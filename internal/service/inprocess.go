@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+// inProcessBufferSize is the buffer of the in-memory connection of
+// NewInProcessServer; larger messages stream through it in chunks.
+const inProcessBufferSize = 1 << 20
+
+// Dialer connects to an in-process server. It fits grpc.WithContextDialer,
+// which ignores the target address:
+//
+//	conn, err := grpc.NewClient("passthrough:///bufnet",
+//		grpc.WithContextDialer(dialer),
+//		grpc.WithTransportCredentials(insecure.NewCredentials()))
+type Dialer func(ctx context.Context, addr string) (net.Conn, error)
+
+// NewInProcessServer serves the assessment service of svc on an in-memory
+// bufconn listener instead of a network port, for tests and for binaries
+// that embed the service. A nil svc uses NewService(). Every call is tracked
+// with UnaryInFlightInterceptor, so svc.Drain works as in the server; opts
+// add further options, such as interceptors, after it.
+//
+// The returned server is already serving and Dialer connects to it. Stop or
+// GracefulStop the server to close the listener.
+func NewInProcessServer(svc *EntropyService, opts ...grpc.ServerOption) (*grpc.Server, Dialer) {
+	if svc == nil {
+		svc = NewService()
+	}
+	opts = append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(UnaryInFlightInterceptor(svc))}, opts...)
+	server := grpc.NewServer(opts...)
+	pb.RegisterSp80090BAssessmentServiceServer(server, NewGRPCServer(svc))
+
+	ln := bufconn.Listen(inProcessBufferSize)
+	go func() { _ = server.Serve(ln) }()
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return ln.DialContext(ctx)
+	}
+	return server, dialer
+}
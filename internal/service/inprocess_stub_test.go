@@ -0,0 +1,72 @@
+//go:build teststub
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+func TestNewInProcessServer_AssessEntropy(t *testing.T) {
+	svc := NewService()
+	server, dialer := NewInProcessServer(svc)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := pb.NewSp80090BAssessmentServiceClient(conn).AssessEntropy(ctx, &pb.Sp80090BAssessmentRequest{
+		Data:          []byte{1, 2, 3, 4, 5},
+		BitsPerSymbol: 8,
+		NonIidMode:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), resp.SampleCount)
+	assert.InDelta(t, 6.5, resp.MinEntropy, 1e-9)
+	assert.Len(t, resp.NonIidResults, 10)
+	assert.Equal(t, 0, svc.InFlight())
+}
+
+func TestNewInProcessServer_TracksCallsInFlight(t *testing.T) {
+	svc := NewService()
+	release := make(chan struct{})
+	block := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		<-release
+		return handler(ctx, req)
+	}
+	server, dialer := NewInProcessServer(svc, grpc.ChainUnaryInterceptor(block))
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := pb.NewSp80090BAssessmentServiceClient(conn).AssessEntropy(context.Background(), &pb.Sp80090BAssessmentRequest{
+			Data:          []byte{1, 2, 3, 4},
+			BitsPerSymbol: 8,
+			NonIidMode:    true,
+		})
+		errCh <- err
+	}()
+	require.Eventually(t, func() bool { return svc.InFlight() == 1 }, 5*time.Second, 5*time.Millisecond)
+	close(release)
+	require.NoError(t, <-errCh)
+	assert.Equal(t, 0, svc.InFlight())
+}
@@ -4,32 +4,169 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/metrics"
 )
 
 // EntropyService provides the business-logic layer for entropy assessment,
-// wrapping the lower-level Assessment with input validation.
+// wrapping the lower-level Assessment with input validation. Its settings may
+// be changed while requests are served: every call derives its own Assessment
+// from a snapshot of them.
 type EntropyService struct {
-	assessment *entropy.Assessment
+	mu         sync.RWMutex
+	config     entropy.AssessmentConfig
+	minSamples int
+
+	// In-flight assessments, registered with BeginAssessment so that Drain
+	// can wait for them during shutdown.
+	inFlight      sync.WaitGroup
+	inFlightCount atomic.Int64
 }
 
 // NewService creates a new EntropyService with default assessment settings.
+// Builds linked against the NIST library reject datasets smaller than
+// entropy.MinRecommendedSamples, which the C++ code does not handle reliably.
 func NewService() *EntropyService {
-	return &EntropyService{
-		assessment: entropy.NewAssessment(),
+	s := &EntropyService{
+		config: entropy.NewAssessment().Config(),
+	}
+	if entropy.LibraryInfo().CGO {
+		s.minSamples = entropy.MinRecommendedSamples
 	}
+	return s
+}
+
+// SetMinSamples sets the smallest dataset accepted for assessment. Zero
+// disables the check, for libraries patched to cope with small inputs.
+func (s *EntropyService) SetMinSamples(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minSamples = n
+}
+
+// MinSamples returns the smallest dataset accepted for assessment; zero
+// means any.
+func (s *EntropyService) MinSamples() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.minSamples
 }
 
 // SetVerbose sets the verbosity level for entropy calculations.
 func (s *EntropyService) SetVerbose(level int) {
-	s.assessment.SetVerbose(level)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Verbose = level
+}
+
+// SetIsolation selects in-process or subprocess execution of the C++ code.
+func (s *EntropyService) SetIsolation(mode entropy.IsolationMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Isolation = mode
+}
+
+// Isolation returns the configured execution mode.
+func (s *EntropyService) Isolation() entropy.IsolationMode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Isolation
+}
+
+// SetTimeout bounds the duration of each individual assessment. Zero disables
+// the limit.
+func (s *EntropyService) SetTimeout(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Timeout = d
+}
+
+// SetMaxMemory rejects assessments whose estimated peak memory exceeds bytes
+// with entropy.ErrResourceLimit. Zero disables the check.
+func (s *EntropyService) SetMaxMemory(bytes uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.MemoryBudget = bytes
+}
+
+// BeginAssessment registers an in-flight assessment. The returned function
+// deregisters it and must be called exactly once when the assessment ends.
+func (s *EntropyService) BeginAssessment() (done func()) {
+	s.inFlight.Add(1)
+	s.inFlightCount.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.inFlightCount.Add(-1)
+			s.inFlight.Done()
+		})
+	}
+}
+
+// InFlight returns the number of registered assessments that have not ended.
+func (s *EntropyService) InFlight() int {
+	return int(s.inFlightCount.Load())
+}
+
+// Drain waits until every registered assessment has ended or ctx is done,
+// and returns how many were still running. It is meant for shutdown, once no
+// new assessment can begin; a waiter left behind by an expired ctx lives on
+// until the remaining assessments end.
+func (s *EntropyService) Drain(ctx context.Context) int {
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0
+	case <-ctx.Done():
+		return s.InFlight()
+	}
+}
+
+// Config returns a snapshot of the settings applied to new assessments.
+func (s *EntropyService) Config() entropy.AssessmentConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// AssessOptions carries per-request settings that must not leak into the
+// shared Assessment.
+type AssessOptions struct {
+	// HSubmitter is the submitter's entropy claim; nil means none was given.
+	HSubmitter *float64
+	// Estimators restricts the run to the named estimators; empty means all.
+	Estimators []string
+	// Histogram requests the symbol histogram in the result.
+	Histogram bool
+	// RawJSON requests the NIST tool JSON document in the result.
+	RawJSON bool
+	// BitOrder selects the bitstring expansion; the zero value is MSBFirst.
+	BitOrder entropy.BitOrder
+	// Verbose overrides the verbosity of the service when not nil. It is
+	// clamped to [0, 3] like Assessment.SetVerbose.
+	Verbose *int
+	// Progress receives the progress reports of the calculation, as set by
+	// Assessment.WithProgress; nil for none.
+	Progress entropy.ProgressFunc
 }
 
 // AssessIID validates inputs and performs an IID entropy assessment on the
-// provided data. A bitsPerSymbol of 0 enables auto-detection.
-func (s *EntropyService) AssessIID(data []byte, bitsPerSymbol int) (*entropy.Result, error) {
+// provided data. A bitsPerSymbol of 0 enables auto-detection. Cancelling ctx
+// or exceeding the configured timeout returns early. Valid data is observed
+// in entropy_data_size_bytes.
+func (s *EntropyService) AssessIID(ctx context.Context, data []byte, bitsPerSymbol int, opts AssessOptions) (*entropy.Result, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("data cannot be empty")
 	}
@@ -38,7 +175,21 @@ func (s *EntropyService) AssessIID(data []byte, bitsPerSymbol int) (*entropy.Res
 		return nil, fmt.Errorf("bits_per_symbol must be between 0 (auto-detect) and 8, got %d", bitsPerSymbol)
 	}
 
-	result, err := s.assessment.AssessIID(data, bitsPerSymbol)
+	if err := s.checkSampleCount(len(data)); err != nil {
+		return nil, err
+	}
+
+	assessment, err := s.assessmentFor(entropy.IID, bitsPerSymbol, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSymbolRange(data, bitsPerSymbol); err != nil {
+		return nil, err
+	}
+
+	metrics.RecordDataSize("IID", len(data))
+	result, err := assessment.AssessIIDContext(ctx, data, bitsPerSymbol)
 	if err != nil {
 		return nil, fmt.Errorf("IID assessment failed: %w", err)
 	}
@@ -47,8 +198,10 @@ func (s *EntropyService) AssessIID(data []byte, bitsPerSymbol int) (*entropy.Res
 }
 
 // AssessNonIID validates inputs and performs a Non-IID entropy assessment on
-// the provided data. A bitsPerSymbol of 0 enables auto-detection.
-func (s *EntropyService) AssessNonIID(data []byte, bitsPerSymbol int) (*entropy.Result, error) {
+// the provided data. A bitsPerSymbol of 0 enables auto-detection. Cancelling
+// ctx or exceeding the configured timeout returns early. Valid data is
+// observed in entropy_data_size_bytes.
+func (s *EntropyService) AssessNonIID(ctx context.Context, data []byte, bitsPerSymbol int, opts AssessOptions) (*entropy.Result, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("data cannot be empty")
 	}
@@ -57,10 +210,117 @@ func (s *EntropyService) AssessNonIID(data []byte, bitsPerSymbol int) (*entropy.
 		return nil, fmt.Errorf("bits_per_symbol must be between 0 (auto-detect) and 8, got %d", bitsPerSymbol)
 	}
 
-	result, err := s.assessment.AssessNonIID(data, bitsPerSymbol)
+	if err := s.checkSampleCount(len(data)); err != nil {
+		return nil, err
+	}
+
+	assessment, err := s.assessmentFor(entropy.NonIID, bitsPerSymbol, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSymbolRange(data, bitsPerSymbol); err != nil {
+		return nil, err
+	}
+
+	metrics.RecordDataSize("Non-IID", len(data))
+	result, err := assessment.AssessNonIIDContext(ctx, data, bitsPerSymbol)
 	if err != nil {
 		return nil, fmt.Errorf("Non-IID assessment failed: %w", err)
 	}
 
 	return result, nil
 }
+
+// CheckIID validates inputs and runs only the IID statistical tests, skipping
+// entropy estimation. It reports whether every test passed along with the
+// per-test results. Valid data is observed in entropy_data_size_bytes.
+func (s *EntropyService) CheckIID(ctx context.Context, data []byte, bitsPerSymbol int) (bool, []entropy.EstimatorResult, error) {
+	if len(data) == 0 {
+		return false, nil, fmt.Errorf("data cannot be empty")
+	}
+
+	if bitsPerSymbol < 0 || bitsPerSymbol > 8 {
+		return false, nil, fmt.Errorf("bits_per_symbol must be between 0 (auto-detect) and 8, got %d", bitsPerSymbol)
+	}
+
+	if err := s.checkSampleCount(len(data)); err != nil {
+		return false, nil, err
+	}
+
+	if err := checkSymbolRange(data, bitsPerSymbol); err != nil {
+		return false, nil, err
+	}
+
+	metrics.RecordDataSize("IID", len(data))
+	assessment := entropy.NewAssessment().WithConfig(s.Config())
+	passed, tests, err := assessment.CheckIIDContext(ctx, data, bitsPerSymbol)
+	if err != nil {
+		return false, nil, fmt.Errorf("IID check failed: %w", err)
+	}
+
+	return passed, tests, nil
+}
+
+// checkSymbolRange rejects samples that do not fit in bitsPerSymbol bits,
+// on which the C++ code behaves undefined, naming the largest such value and
+// the number of samples out of range. Full bytes and auto-detection accept
+// every value, so they are not scanned.
+func checkSymbolRange(data []byte, bitsPerSymbol int) error {
+	if bitsPerSymbol == 0 || bitsPerSymbol == 8 {
+		return nil
+	}
+	maxSymbol := byte(1<<bitsPerSymbol - 1)
+	var largest byte
+	violations := 0
+	for _, b := range data {
+		if b > maxSymbol {
+			violations++
+			largest = max(largest, b)
+		}
+	}
+	if violations > 0 {
+		return fmt.Errorf("symbol value %d exceeds the maximum %d for %d bits per symbol in %d of %d samples: %w",
+			largest, maxSymbol, bitsPerSymbol, violations, len(data), entropy.ErrInvalidData)
+	}
+	return nil
+}
+
+// checkSampleCount rejects n samples when they are below the configured
+// minimum sample count, before they reach the C++ code.
+func (s *EntropyService) checkSampleCount(n int) error {
+	minSamples := s.MinSamples()
+	if n < minSamples {
+		return fmt.Errorf("at least %d samples required, got %d: %w", minSamples, n, entropy.ErrInsufficientData)
+	}
+	return nil
+}
+
+// assessmentFor validates opts and returns an Assessment configured from a
+// snapshot of the service settings with the per-request options applied.
+func (s *EntropyService) assessmentFor(testType entropy.TestType, bitsPerSymbol int, opts AssessOptions) (*entropy.Assessment, error) {
+	cfg := s.Config()
+	cfg.Histogram = opts.Histogram
+	cfg.RawJSON = opts.RawJSON
+	cfg.BitOrder = opts.BitOrder
+	if opts.Verbose != nil {
+		cfg.Verbose = *opts.Verbose
+	}
+
+	if opts.HSubmitter != nil {
+		h := *opts.HSubmitter
+		if math.IsNaN(h) || math.IsInf(h, 0) || h < 0 || (bitsPerSymbol > 0 && h > float64(bitsPerSymbol)) {
+			return nil, fmt.Errorf("h_submitter must be between 0 and bits_per_symbol (%d), got %g", bitsPerSymbol, h)
+		}
+		cfg.HSubmitter = &h
+	}
+
+	if len(opts.Estimators) > 0 {
+		if err := entropy.ValidateEstimators(testType, opts.Estimators); err != nil {
+			return nil, err
+		}
+		cfg.Estimators = opts.Estimators
+	}
+
+	return entropy.NewAssessment().WithConfig(cfg).WithProgress(opts.Progress), nil
+}
@@ -3,23 +3,30 @@
 package service
 
 import (
+	"context"
+	"sync"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/metrics"
 )
 
 // Success paths rely on the teststub build tag to avoid CGO.
 func TestService_AssessIID_SuccessStub(t *testing.T) {
 	svc := NewService()
-	res, err := svc.AssessIID([]byte{1, 2, 3, 4}, 8)
+	res, err := svc.AssessIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{})
 	require.NoError(t, err)
 	assert.Equal(t, 7.5, res.MinEntropy)
 }
 
 func TestService_AssessNonIID_SuccessStub(t *testing.T) {
 	svc := NewService()
-	res, err := svc.AssessNonIID([]byte{1, 2, 3, 4}, 8)
+	res, err := svc.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{})
 	require.NoError(t, err)
 	assert.Equal(t, 6.5, res.MinEntropy)
 }
@@ -27,7 +34,7 @@ func TestService_AssessNonIID_SuccessStub(t *testing.T) {
 func TestService_AssessIID_AssessmentError(t *testing.T) {
 	svc := NewService()
 
-	_, err := svc.AssessIID([]byte{0xFF, 1, 2, 3}, 8)
+	_, err := svc.AssessIID(context.Background(), []byte{0xFF, 1, 2, 3}, 8, AssessOptions{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "IID assessment failed")
 }
@@ -35,7 +42,144 @@ func TestService_AssessIID_AssessmentError(t *testing.T) {
 func TestService_AssessNonIID_AssessmentError(t *testing.T) {
 	svc := NewService()
 
-	_, err := svc.AssessNonIID([]byte{0xFF, 1, 2, 3}, 8)
+	_, err := svc.AssessNonIID(context.Background(), []byte{0xFF, 1, 2, 3}, 8, AssessOptions{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "Non-IID assessment failed")
 }
+
+func TestService_HSubmitterDoesNotLeakIntoSharedAssessment(t *testing.T) {
+	svc := NewService()
+
+	claim := 2.0
+	res, err := svc.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{HSubmitter: &claim})
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, res.HFinal)
+	assert.True(t, res.SubmitterBinding)
+
+	res, err = svc.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, res.HFinal)
+	assert.False(t, res.HasHSubmitter)
+}
+
+func TestService_EstimatorsDoNotLeakIntoSharedAssessment(t *testing.T) {
+	svc := NewService()
+
+	res, err := svc.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{Estimators: []string{"markov"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"markov"}, res.EstimatorSelection)
+	assert.Len(t, res.Estimators, 1)
+
+	res, err = svc.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, res.EstimatorSelection)
+	assert.Len(t, res.Estimators, 10)
+}
+
+func TestService_BitOrderDoesNotLeakIntoSharedAssessment(t *testing.T) {
+	svc := NewService()
+
+	lsb, err := svc.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{BitOrder: entropy.LSBFirst})
+	require.NoError(t, err)
+
+	msb, err := svc.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 6.1, msb.HBitstring)
+	assert.Less(t, lsb.HBitstring, msb.HBitstring)
+}
+
+func TestService_MinSamplesGuard(t *testing.T) {
+	svc := NewService()
+	assert.Zero(t, svc.minSamples, "stub builds accept small datasets by default")
+
+	svc.SetMinSamples(8)
+	_, _, err := svc.CheckIID(context.Background(), []byte{1, 2, 3, 4}, 8)
+	require.ErrorIs(t, err, entropy.ErrInsufficientData)
+	assert.Contains(t, err.Error(), "at least 8 samples required, got 4")
+
+	_, err = svc.AssessIID(context.Background(), []byte{1, 2, 3, 4, 5, 6, 7, 8}, 8, AssessOptions{})
+	require.NoError(t, err)
+}
+
+func TestService_SymbolRange(t *testing.T) {
+	svc := NewService()
+	inRange := []byte{0, 5, 10, 15}
+	outOfRange := []byte{0, 16, 15, 200, 17}
+
+	_, err := svc.AssessIID(context.Background(), inRange, 4, AssessOptions{})
+	require.NoError(t, err)
+	_, err = svc.AssessNonIID(context.Background(), inRange, 4, AssessOptions{})
+	require.NoError(t, err)
+
+	_, err = svc.AssessIID(context.Background(), outOfRange, 4, AssessOptions{})
+	require.ErrorIs(t, err, entropy.ErrInvalidData)
+	assert.Contains(t, err.Error(), "symbol value 200 exceeds the maximum 15 for 4 bits per symbol in 3 of 5 samples")
+	_, err = svc.AssessNonIID(context.Background(), outOfRange, 4, AssessOptions{})
+	require.ErrorIs(t, err, entropy.ErrInvalidData)
+	_, _, err = svc.CheckIID(context.Background(), outOfRange, 4)
+	require.ErrorIs(t, err, entropy.ErrInvalidData)
+
+	// Full bytes and auto-detection accept every value.
+	_, err = svc.AssessNonIID(context.Background(), outOfRange, 8, AssessOptions{})
+	require.NoError(t, err)
+	_, err = svc.AssessNonIID(context.Background(), outOfRange, 0, AssessOptions{})
+	require.NoError(t, err)
+}
+
+// dataSizeObservations returns the number and sum of the observations of
+// entropy_data_size_bytes for testType.
+func dataSizeObservations(t *testing.T, testType string) (uint64, float64) {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, metrics.DataSizeBytes.WithLabelValues(testType).(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestService_RecordsDataSize(t *testing.T) {
+	metrics.DataSizeBytes.Reset()
+	svc := NewService()
+
+	_, err := svc.AssessIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{})
+	require.NoError(t, err)
+	_, err = svc.AssessNonIID(context.Background(), []byte{1, 2, 3, 4, 5}, 8, AssessOptions{})
+	require.NoError(t, err)
+	_, _, err = svc.CheckIID(context.Background(), []byte{1, 2, 3}, 8)
+	require.NoError(t, err)
+
+	count, sum := dataSizeObservations(t, "IID")
+	assert.Equal(t, uint64(2), count)
+	assert.Equal(t, 7.0, sum)
+	count, sum = dataSizeObservations(t, "Non-IID")
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 5.0, sum)
+
+	// Data rejected before the assessment is not observed.
+	svc.SetMinSamples(10)
+	_, err = svc.AssessIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{})
+	require.Error(t, err)
+	count, _ = dataSizeObservations(t, "IID")
+	assert.Equal(t, uint64(2), count)
+}
+
+// TestService_ConcurrentReconfiguration changes the verbosity while
+// assessments run; run it with -race.
+func TestService_ConcurrentReconfiguration(t *testing.T) {
+	svc := NewService()
+	svc.SetVerbose(0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(2)
+		go func(level int) {
+			defer wg.Done()
+			svc.SetVerbose(level % 2 * 2)
+		}(i)
+		go func() {
+			defer wg.Done()
+			res, err := svc.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8, AssessOptions{Histogram: true})
+			assert.NoError(t, err)
+			assert.Equal(t, 6.5, res.MinEntropy)
+		}()
+	}
+	wg.Wait()
+}
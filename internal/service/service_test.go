@@ -1,10 +1,15 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
 )
 
 // NOTE: Success tests with actual CGO calls are skipped because the NIST C++ library
@@ -15,34 +20,133 @@ func TestNewService(t *testing.T) {
 	svc := NewService()
 
 	assert.NotNil(t, svc)
-	assert.NotNil(t, svc.assessment)
+	assert.Equal(t, entropy.NewAssessment().Config(), svc.Config())
 }
 
 func TestService_SetVerbose(t *testing.T) {
 	svc := NewService()
 
 	svc.SetVerbose(2)
-	assert.Equal(t, 2, svc.assessment.GetVerbose())
+	assert.Equal(t, 2, svc.Config().Verbose)
 
 	svc.SetVerbose(0)
-	assert.Equal(t, 0, svc.assessment.GetVerbose())
+	assert.Equal(t, 0, svc.Config().Verbose)
+}
+
+func TestService_AssessOptionsVerbose(t *testing.T) {
+	svc := NewService()
+	svc.SetVerbose(2)
+
+	a, err := svc.assessmentFor(entropy.NonIID, 8, AssessOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, a.GetVerbose(), "nil keeps the service verbosity")
+
+	for _, tc := range []struct{ in, want int }{{0, 0}, {3, 3}, {99, 3}, {-1, 0}} {
+		verbose := tc.in
+		a, err := svc.assessmentFor(entropy.NonIID, 8, AssessOptions{Verbose: &verbose})
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, a.GetVerbose(), "verbose %d", tc.in)
+	}
+	assert.Equal(t, 2, svc.Config().Verbose, "the service setting is unchanged")
+}
+
+func TestService_SetIsolationAndTimeout(t *testing.T) {
+	svc := NewService()
+	assert.Equal(t, entropy.IsolationInProcess, svc.Isolation())
+
+	svc.SetIsolation(entropy.IsolationSubprocess)
+	assert.Equal(t, entropy.IsolationSubprocess, svc.Isolation())
+
+	svc.SetTimeout(time.Minute)
+	assert.Equal(t, time.Minute, svc.Config().Timeout)
+}
+
+func TestService_SetMaxMemory(t *testing.T) {
+	svc := NewService()
+	assert.Zero(t, svc.Config().MemoryBudget)
+
+	svc.SetMaxMemory(1 << 30)
+	assert.Equal(t, uint64(1<<30), svc.Config().MemoryBudget)
+}
+
+func TestService_DrainWaitsForAssessments(t *testing.T) {
+	svc := NewService()
+	assert.Equal(t, 0, svc.Drain(context.Background()), "nothing in flight")
+
+	done := svc.BeginAssessment()
+	assert.Equal(t, 1, svc.InFlight())
+	time.AfterFunc(50*time.Millisecond, done)
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Equal(t, 0, svc.Drain(ctx))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, 0, svc.InFlight())
+
+	done() // a second call is ignored
+	assert.Equal(t, 0, svc.InFlight())
+}
+
+func TestService_DrainDeadline(t *testing.T) {
+	svc := NewService()
+	done := svc.BeginAssessment()
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, 1, svc.Drain(ctx))
 }
 
 func TestService_AssessIID_ValidationErrors(t *testing.T) {
 	svc := NewService()
 
 	// Empty data
-	_, err := svc.AssessIID([]byte{}, 8)
+	_, err := svc.AssessIID(context.Background(), []byte{}, 8, AssessOptions{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "cannot be empty")
 
 	// Invalid bits_per_symbol - too low
-	_, err = svc.AssessIID([]byte{1, 2, 3}, -1)
+	_, err = svc.AssessIID(context.Background(), []byte{1, 2, 3}, -1, AssessOptions{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "bits_per_symbol")
 
 	// Invalid bits_per_symbol - too high
-	_, err = svc.AssessIID([]byte{1, 2, 3}, 9)
+	_, err = svc.AssessIID(context.Background(), []byte{1, 2, 3}, 9, AssessOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bits_per_symbol")
+}
+
+func TestService_AssessHSubmitterValidation(t *testing.T) {
+	svc := NewService()
+
+	negative := -0.1
+	_, err := svc.AssessIID(context.Background(), []byte{1, 2, 3}, 8, AssessOptions{HSubmitter: &negative})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "h_submitter")
+
+	tooHigh := 2.0
+	_, err = svc.AssessNonIID(context.Background(), []byte{1, 2, 3}, 1, AssessOptions{HSubmitter: &tooHigh})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "h_submitter")
+}
+
+func TestService_AssessEstimatorValidation(t *testing.T) {
+	svc := NewService()
+
+	_, err := svc.AssessIID(context.Background(), []byte{1, 2, 3}, 8, AssessOptions{Estimators: []string{"markov"}})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, entropy.ErrInvalidEstimator))
+}
+
+func TestService_CheckIID_ValidationErrors(t *testing.T) {
+	svc := NewService()
+
+	_, _, err := svc.CheckIID(context.Background(), nil, 8)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "data cannot be empty")
+
+	_, _, err = svc.CheckIID(context.Background(), []byte{1, 2, 3}, 9)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "bits_per_symbol")
 }
@@ -51,17 +155,17 @@ func TestService_AssessNonIID_ValidationErrors(t *testing.T) {
 	svc := NewService()
 
 	// Empty data
-	_, err := svc.AssessNonIID([]byte{}, 8)
+	_, err := svc.AssessNonIID(context.Background(), []byte{}, 8, AssessOptions{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "cannot be empty")
 
 	// Invalid bits_per_symbol - too low
-	_, err = svc.AssessNonIID([]byte{1, 2, 3}, -1)
+	_, err = svc.AssessNonIID(context.Background(), []byte{1, 2, 3}, -1, AssessOptions{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "bits_per_symbol")
 
 	// Invalid bits_per_symbol - too high
-	_, err = svc.AssessNonIID([]byte{1, 2, 3}, 9)
+	_, err = svc.AssessNonIID(context.Background(), []byte{1, 2, 3}, 9, AssessOptions{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "bits_per_symbol")
 }
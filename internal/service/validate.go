@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/entropy"
+	"github.com/AmmannChristian/nist-800-90b/internal/middleware"
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+// requestCheckNames are the input checks of an assessment request, in the
+// order AssessEntropy applies them before anything is assessed.
+var requestCheckNames = []string{
+	"request", "data", "size", "window", "bits_per_symbol", "h_submitter",
+	"iid_check_only", "mode", "bit_order", "estimators",
+}
+
+// inputCheck is the outcome of one input check. err is nil when the check
+// passed; for a skipped check it says why the check did not run.
+type inputCheck struct {
+	name    string
+	err     error
+	skipped bool
+}
+
+// requestValidation holds the input checks of an assessment request and
+// what they derived from it.
+type requestValidation struct {
+	checks   []inputCheck
+	data     []byte           // the window of the data to assess; nil when not sent or invalid
+	samples  int64            // number of samples in the window; -1 when unknown
+	bitOrder entropy.BitOrder // the requested bit order
+}
+
+// pass records the outcome of the check name and reports whether it passed.
+func (v *requestValidation) pass(name string, err error) bool {
+	v.checks = append(v.checks, inputCheck{name: name, err: err})
+	return err == nil
+}
+
+// skip records that the check name did not run because of reason.
+func (v *requestValidation) skip(name, reason string) {
+	v.checks = append(v.checks, inputCheck{name: name, err: errors.New(reason), skipped: true})
+}
+
+// requires skips the check name and returns false unless every check in
+// deps ran and passed.
+func (v *requestValidation) requires(name string, deps ...string) bool {
+	for _, dep := range deps {
+		for _, c := range v.checks {
+			if c.name == dep && c.err != nil {
+				v.skip(name, fmt.Sprintf("requires the %s check to pass", dep))
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// failed returns the first check that failed, or nil when none did.
+func (v *requestValidation) failed() *inputCheck {
+	for i := range v.checks {
+		if c := &v.checks[i]; c.err != nil && !c.skipped {
+			return c
+		}
+	}
+	return nil
+}
+
+// requestChecks runs the input checks AssessEntropy applies to req before
+// it assesses anything, in the order of requestCheckNames. size is the
+// number of bytes of data, which is len(req.Data) unless the data has not
+// been sent yet; the window is then not checked. maxSize is the upload limit,
+// zero for none. Every check runs, so that
+// ValidateRequest can report them all, except those that depend on a check
+// that did not pass. AssessEntropy rejects the request with the first
+// failure.
+func (s *GRPCServer) requestChecks(req *pb.Sp80090BAssessmentRequest, size uint64, maxSize int64) *requestValidation {
+	v := &requestValidation{samples: -1}
+	if req == nil {
+		v.pass("request", errors.New("request cannot be nil"))
+		for _, name := range requestCheckNames[1:] {
+			v.requires(name, "request")
+		}
+		return v
+	}
+	v.pass("request", nil)
+
+	var err error
+	if size == 0 {
+		err = errors.New("data cannot be empty")
+	}
+	v.pass("data", err)
+
+	if maxSize > 0 {
+		err = nil
+		if size > uint64(maxSize) {
+			err = fmt.Errorf("data of %d bytes exceeds the upload limit of %d bytes", size, maxSize)
+		}
+		v.pass("size", err)
+	} else {
+		v.skip("size", "no upload limit is configured")
+	}
+
+	windowed := req.Offset > 0 || req.Length > 0
+	switch {
+	case !v.requires("window", "data"):
+	case len(req.Data) == 0 && windowed:
+		v.skip("window", "requires the data")
+	case len(req.Data) == 0:
+		v.pass("window", nil)
+		v.samples = int64(min(size, math.MaxInt64))
+	case windowed:
+		window, err := entropy.SliceSection(req.Data, int64(req.Offset), int64(req.Length))
+		if v.pass("window", err) {
+			v.data, v.samples = window, int64(len(window))
+		}
+	default:
+		v.pass("window", nil)
+		v.data, v.samples = req.Data, int64(len(req.Data))
+	}
+
+	err = nil
+	if req.BitsPerSymbol > 8 {
+		err = fmt.Errorf("bits_per_symbol must be between 0 and 8, got %d", req.BitsPerSymbol)
+	}
+	v.pass("bits_per_symbol", err)
+
+	err = nil
+	if req.HSubmitter != nil {
+		h := req.GetHSubmitter()
+		if math.IsNaN(h) || math.IsInf(h, 0) || h < 0 || (req.BitsPerSymbol > 0 && h > float64(req.BitsPerSymbol)) {
+			err = fmt.Errorf("h_submitter must be between 0 and bits_per_symbol, got %g", h)
+		}
+	}
+	v.pass("h_submitter", err)
+
+	err = nil
+	if req.IidCheckOnly && (req.NonIidMode || req.HSubmitter != nil || len(req.Estimators) > 0) {
+		err = errors.New("iid_check_only cannot be combined with non_iid_mode, h_submitter, or estimators")
+	}
+	v.pass("iid_check_only", err)
+
+	if req.IidCheckOnly {
+		for _, name := range []string{"mode", "bit_order", "estimators"} {
+			v.skip(name, "not used with iid_check_only")
+		}
+		return v
+	}
+
+	err = nil
+	if !req.IidMode && !req.NonIidMode {
+		err = errors.New("either iid_mode or non_iid_mode must be enabled")
+	}
+	v.pass("mode", err)
+
+	v.bitOrder, err = bitOrderFromProto(req.BitOrder)
+	v.pass("bit_order", err)
+
+	v.pass("estimators", validateEstimatorSelection(req))
+	return v
+}
+
+// dataChecks adds the checks EntropyService applies to the samples before
+// they reach the C++ code: the minimum sample count and, for fewer than 8
+// bits per symbol, the range of every symbol.
+func (s *GRPCServer) dataChecks(req *pb.Sp80090BAssessmentRequest, v *requestValidation) {
+	if v.requires("sample_count", "request", "window") {
+		v.pass("sample_count", s.svc.checkSampleCount(int(min(v.samples, math.MaxInt))))
+	}
+
+	switch {
+	case !v.requires("symbol_range", "request", "window", "bits_per_symbol"):
+	case v.data == nil:
+		v.skip("symbol_range", "requires the data")
+	default:
+		v.pass("symbol_range", checkSymbolRange(v.data, int(req.BitsPerSymbol)))
+	}
+}
+
+// ValidateRequest runs every input check of AssessEntropy on the request
+// without assessing it, the checks of the request itself followed by the
+// sample count and symbol range checks of the service, and reports the
+// outcome of each. When the request carries no data, data_size stands in
+// for it and the checks that need the data are skipped. The request is
+// valid when no check failed; the call itself only fails for a malformed
+// message.
+func (s *GRPCServer) ValidateRequest(ctx context.Context, req *pb.Sp80090BValidateRequest) (*pb.Sp80090BValidateResponse, error) {
+	requestID := middleware.GetRequestID(ctx)
+
+	assessReq := req.GetRequest()
+	size := uint64(len(assessReq.GetData()))
+	if size == 0 {
+		size = req.GetDataSize()
+	}
+	v := s.requestChecks(assessReq, size, s.maxUploadSize)
+	s.dataChecks(assessReq, v)
+
+	response := &pb.Sp80090BValidateResponse{
+		Valid:         v.failed() == nil,
+		Checks:        make([]*pb.Sp80090BValidationCheck, len(v.checks)),
+		MaxUploadSize: uint64(s.maxUploadSize),
+		MinSamples:    uint64(s.svc.MinSamples()),
+	}
+	for i, c := range v.checks {
+		check := &pb.Sp80090BValidationCheck{Name: c.name, Passed: c.err == nil, Skipped: c.skipped}
+		if c.err != nil {
+			check.Message = c.err.Error()
+		}
+		response.Checks[i] = check
+	}
+
+	event := log.Info().
+		Str("request_id", requestID).
+		Uint64("data_size", size).
+		Bool("valid", response.Valid)
+	if c := v.failed(); c != nil {
+		event = event.Str("failed_check", c.name)
+	}
+	event.Msg("ValidateRequest completed")
+
+	return response, nil
+}
@@ -0,0 +1,191 @@
+// Package client is a Go client for the NIST SP 800-90B assessment service.
+// It dials the gRPC API, attaches credentials to every call and returns the
+// results as plain Go values instead of protobuf messages.
+//
+//	c, err := client.New("entropy.example.com:9090",
+//		client.WithTLS(&tls.Config{}),
+//		client.WithToken(token),
+//		client.WithTimeout(time.Minute),
+//		client.WithRetry(client.RetryPolicy{MaxAttempts: 4}))
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//	res, err := c.AssessNonIID(ctx, samples, 8)
+//
+// Errors returned by the service are gRPC status errors; status.Code tells
+// an invalid request (codes.InvalidArgument) from a failed assessment.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+// Client calls the assessment service over one gRPC connection. It is safe
+// for concurrent use.
+type Client struct {
+	conn    *grpc.ClientConn
+	rpc     pb.Sp80090BAssessmentServiceClient
+	timeout time.Duration
+	retry   *RetryPolicy
+}
+
+// options collects the settings of New.
+type options struct {
+	tls      *tls.Config
+	token    string
+	timeout  time.Duration
+	retry    *RetryPolicy
+	dialOpts []grpc.DialOption
+}
+
+// Option configures a Client.
+type Option func(*options)
+
+// WithTLS connects with TLS using cfg. Without it the connection is
+// plaintext.
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) { o.tls = cfg }
+}
+
+// WithToken sends token as a bearer token in the authorization metadata of
+// every call, for servers with AUTH_ENABLED. The token is sent on plaintext
+// connections as well, so combine it with WithTLS outside of tests.
+func WithToken(token string) Option {
+	return func(o *options) { o.token = token }
+}
+
+// WithTimeout bounds every call to d, all attempts of WithRetry included.
+// A shorter deadline on the context of
+// a call still applies. Zero, the default, leaves calls unbounded.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+// WithDialOptions passes further options to grpc.NewClient, such as a
+// custom dialer or larger message size limits.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) { o.dialOpts = append(o.dialOpts, opts...) }
+}
+
+// New creates a Client for the server at addr. The connection is
+// established lazily by the first call; New does not contact the server.
+func New(addr string, opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.timeout < 0 {
+		return nil, errors.New("client: timeout must not be negative")
+	}
+	if o.retry != nil {
+		if err := o.retry.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	creds := insecure.NewCredentials()
+	if o.tls != nil {
+		creds = credentials.NewTLS(o.tls)
+	}
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if o.token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(bearerToken(o.token)))
+	}
+	dialOpts = append(dialOpts, o.dialOpts...)
+
+	conn, err := grpc.NewClient(addr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		conn:    conn,
+		rpc:     pb.NewSp80090BAssessmentServiceClient(conn),
+		timeout: o.timeout,
+		retry:   o.retry,
+	}, nil
+}
+
+// Close closes the connection. Calls in progress fail.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// AssessIID runs the IID tests and estimators on data, one sample per byte
+// with bitsPerSymbol significant bits. A bitsPerSymbol of 0 lets the server
+// detect the word size.
+func (c *Client) AssessIID(ctx context.Context, data []byte, bitsPerSymbol int) (*Result, error) {
+	return c.assess(ctx, data, bitsPerSymbol, true)
+}
+
+// AssessNonIID runs the Non-IID estimators on data, one sample per byte with
+// bitsPerSymbol significant bits. A bitsPerSymbol of 0 lets the server
+// detect the word size.
+func (c *Client) AssessNonIID(ctx context.Context, data []byte, bitsPerSymbol int) (*Result, error) {
+	return c.assess(ctx, data, bitsPerSymbol, false)
+}
+
+// Assess sends req as it is and returns the response of the server, for
+// the request fields and results that AssessIID and AssessNonIID do not
+// cover. The call is bounded by WithTimeout and retried as configured by
+// WithRetry; the server validates the request.
+func (c *Client) Assess(ctx context.Context, req *pb.Sp80090BAssessmentRequest) (*pb.Sp80090BAssessmentResponse, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var resp *pb.Sp80090BAssessmentResponse
+	err := c.retry.retry(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = c.rpc.AssessEntropy(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// assess sends one AssessEntropy request for either mode.
+func (c *Client) assess(ctx context.Context, data []byte, bitsPerSymbol int, iid bool) (*Result, error) {
+	if bitsPerSymbol < 0 || bitsPerSymbol > 8 {
+		return nil, errors.New("client: bitsPerSymbol must be between 0 and 8")
+	}
+	resp, err := c.Assess(ctx, &pb.Sp80090BAssessmentRequest{
+		Data:          data,
+		BitsPerSymbol: uint32(bitsPerSymbol),
+		IidMode:       iid,
+		NonIidMode:    !iid,
+	})
+	if err != nil {
+		return nil, err
+	}
+	estimators := resp.GetNonIidResults()
+	if iid {
+		estimators = resp.GetIidResults()
+	}
+	return newResult(resp, estimators), nil
+}
+
+// bearerToken attaches a fixed OAuth2 bearer token to every call.
+type bearerToken string
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials. It
+// reports false so that the token also works over plaintext connections,
+// such as a local port-forward.
+func (bearerToken) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,60 @@
+//go:build teststub
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+func TestClient_AssessIID(t *testing.T) {
+	c := startServer(t, nil)
+
+	res, err := c.AssessIID(context.Background(), []byte{1, 2, 3, 4}, 8)
+	require.NoError(t, err)
+	assert.Equal(t, 7.5, res.MinEntropy)
+	assert.Equal(t, uint64(4), res.SampleCount)
+	assert.Equal(t, 8, res.BitsPerSymbol)
+	assert.False(t, res.BitsPerSymbolAutoDetected)
+	assert.Equal(t, "stub", res.Backend)
+	require.Len(t, res.Estimators, 4)
+	assert.Equal(t, "Most Common Value", res.Estimators[0].Name)
+}
+
+func TestClient_AssessNonIID(t *testing.T) {
+	c := startServer(t, nil)
+
+	res, err := c.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, res.MinEntropy)
+	assert.True(t, res.Passed)
+	assert.True(t, res.BitsPerSymbolAutoDetected)
+	assert.Equal(t, 3, res.BitsPerSymbol)
+	assert.Equal(t, 4, res.AlphabetSize)
+	require.Len(t, res.Estimators, 10)
+	for _, est := range res.Estimators {
+		assert.NotEmpty(t, est.Name)
+		assert.Greater(t, est.Estimate, 0.0, est.Name)
+	}
+}
+
+func TestClient_Assess(t *testing.T) {
+	c := startServer(t, nil)
+
+	resp, err := c.Assess(context.Background(), &pb.Sp80090BAssessmentRequest{
+		Data:             []byte{1, 2, 3, 4},
+		BitsPerSymbol:    8,
+		NonIidMode:       true,
+		IncludeHistogram: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, resp.GetMinEntropy())
+	assert.Equal(t, 6.6, resp.GetHOriginal())
+	assert.Equal(t, 6.1, resp.GetHBitstring())
+	assert.Len(t, resp.GetHistogram(), 256)
+}
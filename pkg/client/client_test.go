@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/AmmannChristian/nist-800-90b/internal/service"
+)
+
+// startServer serves the assessment service in-process and returns a Client
+// connected to it. Both are closed on cleanup.
+func startServer(t *testing.T, serverOpts []grpc.ServerOption, opts ...Option) *Client {
+	t.Helper()
+
+	svc := service.NewService()
+	svc.SetMinSamples(0)
+	server, dialer := service.NewInProcessServer(svc, serverOpts...)
+	t.Cleanup(server.Stop)
+
+	opts = append(opts, WithDialOptions(grpc.WithContextDialer(dialer)))
+	c, err := New("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClient_ReturnsServiceErrors(t *testing.T) {
+	c := startServer(t, nil)
+
+	_, err := c.AssessNonIID(context.Background(), nil, 8)
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "data cannot be empty")
+}
+
+func TestClient_RejectsInvalidArguments(t *testing.T) {
+	c := startServer(t, nil)
+
+	for _, bits := range []int{-1, 9} {
+		_, err := c.AssessIID(context.Background(), []byte{1, 2, 3}, bits)
+		assert.ErrorContains(t, err, "bitsPerSymbol must be between 0 and 8")
+	}
+
+	_, err := New("localhost:0", WithTimeout(-time.Second))
+	assert.ErrorContains(t, err, "timeout must not be negative")
+}
+
+func TestClient_SendsToken(t *testing.T) {
+	var authorization []string
+	capture := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		authorization = md.Get("authorization")
+		return handler(ctx, req)
+	}
+	c := startServer(t, []grpc.ServerOption{grpc.UnaryInterceptor(capture)}, WithToken("secret"))
+
+	_, _ = c.AssessNonIID(context.Background(), nil, 8)
+	assert.Equal(t, []string{"Bearer secret"}, authorization)
+}
+
+func TestClient_Timeout(t *testing.T) {
+	slow := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	c := startServer(t, []grpc.ServerOption{grpc.UnaryInterceptor(slow)}, WithTimeout(50*time.Millisecond))
+
+	_, err := c.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8)
+	require.Error(t, err)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
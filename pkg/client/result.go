@@ -0,0 +1,77 @@
+package client
+
+import (
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+// Result is the outcome of one assessment.
+type Result struct {
+	// MinEntropy is the min-entropy estimate in bits per sample.
+	MinEntropy float64
+	// Passed reports whether the data passed the assessment.
+	Passed bool
+	// Summary is the human-readable summary of the server.
+	Summary string
+	// SampleCount is the number of samples assessed.
+	SampleCount uint64
+	// BitsPerSymbol is the word size used, detected by the server when the
+	// request passed 0; BitsPerSymbolAutoDetected tells which.
+	BitsPerSymbol             int
+	BitsPerSymbolAutoDetected bool
+	// ShannonEntropy of the symbol frequencies in bits per symbol, a
+	// baseline rather than an SP 800-90B estimate.
+	ShannonEntropy float64
+	// AlphabetSize is the number of distinct symbol values in the data.
+	AlphabetSize int
+	// Backend names the implementation that produced the result: "nist-cpp",
+	// "go" or "stub".
+	Backend string
+	// Partial reports that the backend ran only part of the estimators, so
+	// the result is not a conforming SP 800-90B assessment.
+	Partial bool
+	// Estimators holds the result of each test or estimator in the order
+	// the server ran them.
+	Estimators []Estimator
+}
+
+// Estimator is the result of one test or estimator.
+type Estimator struct {
+	// Name of the estimator, e.g. "Most Common Value".
+	Name string
+	// Estimate is the entropy estimate in bits per sample.
+	Estimate float64
+	// Passed reports whether the test or estimator passed.
+	Passed bool
+	// Description is the human-readable description of the result.
+	Description string
+	// Details holds further metrics of the estimator, keyed by name.
+	Details map[string]float64
+}
+
+// newResult converts a response into a Result with the estimators of the
+// mode that was requested.
+func newResult(resp *pb.Sp80090BAssessmentResponse, estimators []*pb.Sp80090BEstimatorResult) *Result {
+	res := &Result{
+		MinEntropy:                resp.GetMinEntropy(),
+		Passed:                    resp.GetPassed(),
+		Summary:                   resp.GetAssessmentSummary(),
+		SampleCount:               resp.GetSampleCount(),
+		BitsPerSymbol:             int(resp.GetBitsPerSymbol()),
+		BitsPerSymbolAutoDetected: resp.GetBitsPerSymbolAutoDetected(),
+		ShannonEntropy:            resp.GetShannonEntropy(),
+		AlphabetSize:              int(resp.GetAlphabetSize()),
+		Backend:                   resp.GetBackend(),
+		Partial:                   resp.GetPartial(),
+		Estimators:                make([]Estimator, 0, len(estimators)),
+	}
+	for _, est := range estimators {
+		res.Estimators = append(res.Estimators, Estimator{
+			Name:        est.GetName(),
+			Estimate:    est.GetEntropyEstimate(),
+			Passed:      est.GetPassed(),
+			Description: est.GetDescription(),
+			Details:     est.GetDetails(),
+		})
+	}
+	return res
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults of RetryPolicy.
+const (
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+)
+
+// DefaultRetryCodes are retried when RetryPolicy.Codes is empty: the
+// server's concurrency limit (ResourceExhausted) and a server that is
+// restarting or unreachable (Unavailable).
+var DefaultRetryCodes = []codes.Code{codes.ResourceExhausted, codes.Unavailable}
+
+// RetryPolicy configures WithRetry.
+type RetryPolicy struct {
+	// MaxAttempts caps the number of attempts of a call, the first one
+	// included. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the second attempt; every further
+	// wait doubles, up to MaxBackoff. Zero selects DefaultInitialBackoff
+	// and DefaultMaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Codes are the status codes worth another attempt, DefaultRetryCodes
+	// when empty. InvalidArgument and FailedPrecondition cannot be retried,
+	// as the same request fails again.
+	Codes []codes.Code
+}
+
+// WithRetry retries the assessment calls, which are idempotent, when they
+// fail with one of the codes of p, waiting with exponential backoff
+// between the attempts. The context of the call, including the deadline of
+// WithTimeout, bounds all attempts together: a call whose deadline would
+// pass during the wait returns the last error.
+func WithRetry(p RetryPolicy) Option {
+	return func(o *options) { o.retry = &p }
+}
+
+// validate checks p and fills in its defaults.
+func (p *RetryPolicy) validate() error {
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("client: retry backoff must not be negative")
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = DefaultInitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = max(DefaultMaxBackoff, p.InitialBackoff)
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		return fmt.Errorf("client: retry MaxBackoff %s is below InitialBackoff %s", p.MaxBackoff, p.InitialBackoff)
+	}
+	if len(p.Codes) == 0 {
+		p.Codes = DefaultRetryCodes
+	}
+	for _, code := range p.Codes {
+		if code == codes.OK || code == codes.InvalidArgument || code == codes.FailedPrecondition {
+			return fmt.Errorf("client: status code %s cannot be retried", code)
+		}
+	}
+	return nil
+}
+
+// backoff returns the wait after the given failed attempt, counted from 1.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, p.MaxBackoff)
+}
+
+// retry runs call until it succeeds, fails with a code that is not retried,
+// runs out of attempts or ctx ends, and returns the last error. A nil p
+// runs call once.
+func (p *RetryPolicy) retry(ctx context.Context, call func(context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := call(ctx)
+		if err == nil || p == nil || attempt >= p.MaxAttempts || !slices.Contains(p.Codes, status.Code(err)) {
+			return err
+		}
+
+		wait := p.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/AmmannChristian/nist-800-90b/pkg/pb"
+)
+
+// flakyServer fails the first failures calls with code and answers the
+// following ones.
+type flakyServer struct {
+	pb.UnimplementedSp80090BAssessmentServiceServer
+	failures int32
+	code     codes.Code
+	calls    atomic.Int32
+}
+
+func (s *flakyServer) AssessEntropy(ctx context.Context, req *pb.Sp80090BAssessmentRequest) (*pb.Sp80090BAssessmentResponse, error) {
+	if s.calls.Add(1) <= s.failures {
+		return nil, status.Error(s.code, "try again")
+	}
+	return &pb.Sp80090BAssessmentResponse{
+		MinEntropy:    6.5,
+		Passed:        true,
+		SampleCount:   uint64(len(req.GetData())),
+		NonIidResults: []*pb.Sp80090BEstimatorResult{{Name: "Most Common Value", EntropyEstimate: 6.5, Passed: true}},
+	}, nil
+}
+
+// startFlakyServer serves srv in-process and returns a Client connected to
+// it. Both are closed on cleanup.
+func startFlakyServer(t *testing.T, srv *flakyServer, opts ...Option) *Client {
+	t.Helper()
+
+	server := grpc.NewServer()
+	pb.RegisterSp80090BAssessmentServiceServer(server, srv)
+	ln := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(server.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return ln.DialContext(ctx)
+	}
+	opts = append(opts, WithDialOptions(grpc.WithContextDialer(dialer)))
+	c, err := New("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClient_RetriesTransientErrors(t *testing.T) {
+	for _, code := range []codes.Code{codes.Unavailable, codes.ResourceExhausted} {
+		srv := &flakyServer{failures: 2, code: code}
+		c := startFlakyServer(t, srv, WithRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+
+		res, err := c.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8)
+		require.NoError(t, err, code)
+		assert.Equal(t, 6.5, res.MinEntropy, code)
+		assert.Equal(t, uint64(4), res.SampleCount, code)
+		assert.Equal(t, int32(3), srv.calls.Load(), code)
+	}
+}
+
+func TestClient_RetryGivesUp(t *testing.T) {
+	// The attempts run out.
+	srv := &flakyServer{failures: 5, code: codes.Unavailable}
+	c := startFlakyServer(t, srv, WithRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	_, err := c.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, int32(3), srv.calls.Load())
+
+	// Invalid requests are not retried.
+	srv = &flakyServer{failures: 5, code: codes.InvalidArgument}
+	c = startFlakyServer(t, srv, WithRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	_, err = c.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, int32(1), srv.calls.Load())
+
+	// Without WithRetry a call is sent once.
+	srv = &flakyServer{failures: 5, code: codes.Unavailable}
+	c = startFlakyServer(t, srv)
+	_, err = c.AssessNonIID(context.Background(), []byte{1, 2, 3, 4}, 8)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, int32(1), srv.calls.Load())
+}
+
+func TestClient_RetryHonoursDeadline(t *testing.T) {
+	srv := &flakyServer{failures: 5, code: codes.ResourceExhausted}
+	c := startFlakyServer(t, srv, WithRetry(RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := c.AssessNonIID(ctx, []byte{1, 2, 3, 4}, 8)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Less(t, time.Since(start), time.Second, "the backoff would pass the deadline")
+	assert.Equal(t, int32(1), srv.calls.Load())
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5}
+	require.NoError(t, p.validate())
+	assert.Equal(t, DefaultInitialBackoff, p.InitialBackoff)
+	assert.Equal(t, DefaultMaxBackoff, p.MaxBackoff)
+	assert.Equal(t, DefaultRetryCodes, p.Codes)
+
+	p = RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	require.NoError(t, p.validate())
+	var waits []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		waits = append(waits, p.backoff(attempt))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, waits)
+
+	for _, tc := range []struct {
+		policy RetryPolicy
+		want   string
+	}{
+		{RetryPolicy{InitialBackoff: -time.Second}, "client: retry backoff must not be negative"},
+		{RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Millisecond}, "client: retry MaxBackoff 1ms is below InitialBackoff 1s"},
+		{RetryPolicy{Codes: []codes.Code{codes.Unavailable, codes.InvalidArgument}}, "client: status code InvalidArgument cannot be retried"},
+		{RetryPolicy{Codes: []codes.Code{codes.FailedPrecondition}}, "client: status code FailedPrecondition cannot be retried"},
+	} {
+		_, err := New("localhost:0", WithRetry(tc.policy))
+		assert.EqualError(t, err, tc.want)
+	}
+}
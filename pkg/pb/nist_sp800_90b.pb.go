@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.10
+// 	protoc-gen-go v1.36.11
 // 	protoc        v3.21.12
 // source: nist_sp800_90b.proto
 
@@ -21,6 +21,192 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// BitOrder selects how each symbol is expanded into bits for the bitstring estimates.
+type BitOrder int32
+
+const (
+	// Most significant bit first, as in the NIST reference tool (default).
+	BitOrder_BIT_ORDER_MSB_FIRST BitOrder = 0
+	// Least significant bit first.
+	BitOrder_BIT_ORDER_LSB_FIRST BitOrder = 1
+)
+
+// Enum value maps for BitOrder.
+var (
+	BitOrder_name = map[int32]string{
+		0: "BIT_ORDER_MSB_FIRST",
+		1: "BIT_ORDER_LSB_FIRST",
+	}
+	BitOrder_value = map[string]int32{
+		"BIT_ORDER_MSB_FIRST": 0,
+		"BIT_ORDER_LSB_FIRST": 1,
+	}
+)
+
+func (x BitOrder) Enum() *BitOrder {
+	p := new(BitOrder)
+	*p = x
+	return p
+}
+
+func (x BitOrder) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BitOrder) Descriptor() protoreflect.EnumDescriptor {
+	return file_nist_sp800_90b_proto_enumTypes[0].Descriptor()
+}
+
+func (BitOrder) Type() protoreflect.EnumType {
+	return &file_nist_sp800_90b_proto_enumTypes[0]
+}
+
+func (x BitOrder) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BitOrder.Descriptor instead.
+func (BitOrder) EnumDescriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{0}
+}
+
+// EstimatorId identifies an estimator or statistical test independently of its
+// display name. Clients should match on it rather than on Sp80090bEstimatorResult.name.
+type EstimatorId int32
+
+const (
+	// Estimator not known to this service version.
+	EstimatorId_ESTIMATOR_ID_UNKNOWN     EstimatorId = 0
+	EstimatorId_ESTIMATOR_ID_MCV         EstimatorId = 1
+	EstimatorId_ESTIMATOR_ID_COLLISION   EstimatorId = 2
+	EstimatorId_ESTIMATOR_ID_MARKOV      EstimatorId = 3
+	EstimatorId_ESTIMATOR_ID_COMPRESSION EstimatorId = 4
+	EstimatorId_ESTIMATOR_ID_T_TUPLE     EstimatorId = 5
+	// The LRS estimate (Non-IID) or the LRS test (IID).
+	EstimatorId_ESTIMATOR_ID_LRS         EstimatorId = 6
+	EstimatorId_ESTIMATOR_ID_MULTI_MCW   EstimatorId = 7
+	EstimatorId_ESTIMATOR_ID_LAG         EstimatorId = 8
+	EstimatorId_ESTIMATOR_ID_MULTI_MMC   EstimatorId = 9
+	EstimatorId_ESTIMATOR_ID_LZ78Y       EstimatorId = 10
+	EstimatorId_ESTIMATOR_ID_CHI_SQUARE  EstimatorId = 11
+	EstimatorId_ESTIMATOR_ID_PERMUTATION EstimatorId = 12
+)
+
+// Enum value maps for EstimatorId.
+var (
+	EstimatorId_name = map[int32]string{
+		0:  "ESTIMATOR_ID_UNKNOWN",
+		1:  "ESTIMATOR_ID_MCV",
+		2:  "ESTIMATOR_ID_COLLISION",
+		3:  "ESTIMATOR_ID_MARKOV",
+		4:  "ESTIMATOR_ID_COMPRESSION",
+		5:  "ESTIMATOR_ID_T_TUPLE",
+		6:  "ESTIMATOR_ID_LRS",
+		7:  "ESTIMATOR_ID_MULTI_MCW",
+		8:  "ESTIMATOR_ID_LAG",
+		9:  "ESTIMATOR_ID_MULTI_MMC",
+		10: "ESTIMATOR_ID_LZ78Y",
+		11: "ESTIMATOR_ID_CHI_SQUARE",
+		12: "ESTIMATOR_ID_PERMUTATION",
+	}
+	EstimatorId_value = map[string]int32{
+		"ESTIMATOR_ID_UNKNOWN":     0,
+		"ESTIMATOR_ID_MCV":         1,
+		"ESTIMATOR_ID_COLLISION":   2,
+		"ESTIMATOR_ID_MARKOV":      3,
+		"ESTIMATOR_ID_COMPRESSION": 4,
+		"ESTIMATOR_ID_T_TUPLE":     5,
+		"ESTIMATOR_ID_LRS":         6,
+		"ESTIMATOR_ID_MULTI_MCW":   7,
+		"ESTIMATOR_ID_LAG":         8,
+		"ESTIMATOR_ID_MULTI_MMC":   9,
+		"ESTIMATOR_ID_LZ78Y":       10,
+		"ESTIMATOR_ID_CHI_SQUARE":  11,
+		"ESTIMATOR_ID_PERMUTATION": 12,
+	}
+)
+
+func (x EstimatorId) Enum() *EstimatorId {
+	p := new(EstimatorId)
+	*p = x
+	return p
+}
+
+func (x EstimatorId) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EstimatorId) Descriptor() protoreflect.EnumDescriptor {
+	return file_nist_sp800_90b_proto_enumTypes[1].Descriptor()
+}
+
+func (EstimatorId) Type() protoreflect.EnumType {
+	return &file_nist_sp800_90b_proto_enumTypes[1]
+}
+
+func (x EstimatorId) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EstimatorId.Descriptor instead.
+func (EstimatorId) EnumDescriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{1}
+}
+
+// MinEntropySource names the assessment mode that produced min_entropy.
+type MinEntropySource int32
+
+const (
+	// No entropy estimate was produced, as for iid_check_only requests.
+	MinEntropySource_MIN_ENTROPY_SOURCE_NONE MinEntropySource = 0
+	// The IID estimate is lower than the Non-IID estimate, or only IID ran.
+	MinEntropySource_MIN_ENTROPY_SOURCE_IID MinEntropySource = 1
+	// The Non-IID estimate is lower than or equal to the IID estimate, or only
+	// Non-IID ran.
+	MinEntropySource_MIN_ENTROPY_SOURCE_NON_IID MinEntropySource = 2
+)
+
+// Enum value maps for MinEntropySource.
+var (
+	MinEntropySource_name = map[int32]string{
+		0: "MIN_ENTROPY_SOURCE_NONE",
+		1: "MIN_ENTROPY_SOURCE_IID",
+		2: "MIN_ENTROPY_SOURCE_NON_IID",
+	}
+	MinEntropySource_value = map[string]int32{
+		"MIN_ENTROPY_SOURCE_NONE":    0,
+		"MIN_ENTROPY_SOURCE_IID":     1,
+		"MIN_ENTROPY_SOURCE_NON_IID": 2,
+	}
+)
+
+func (x MinEntropySource) Enum() *MinEntropySource {
+	p := new(MinEntropySource)
+	*p = x
+	return p
+}
+
+func (x MinEntropySource) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MinEntropySource) Descriptor() protoreflect.EnumDescriptor {
+	return file_nist_sp800_90b_proto_enumTypes[2].Descriptor()
+}
+
+func (MinEntropySource) Type() protoreflect.EnumType {
+	return &file_nist_sp800_90b_proto_enumTypes[2]
+}
+
+func (x MinEntropySource) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MinEntropySource.Descriptor instead.
+func (MinEntropySource) EnumDescriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{2}
+}
+
 // Sp80090bAssessmentRequest contains the entropy source data and assessment parameters.
 type Sp80090BAssessmentRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -32,10 +218,31 @@ type Sp80090BAssessmentRequest struct {
 	IidMode bool `protobuf:"varint,3,opt,name=iid_mode,json=iidMode,proto3" json:"iid_mode,omitempty"`
 	// If true, run Non-IID estimators.
 	NonIidMode bool `protobuf:"varint,4,opt,name=non_iid_mode,json=nonIidMode,proto3" json:"non_iid_mode,omitempty"`
-	// Verbosity level for output (0=quiet, 1=normal, 2=verbose, 3=debug).
-	Verbosity     uint32 `protobuf:"varint,5,opt,name=verbosity,proto3" json:"verbosity,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	// Verbosity of the NIST library for this request only (0=quiet, 1=normal,
+	// 2=verbose, 3=debug); larger values are clamped to 3.
+	Verbosity uint32 `protobuf:"varint,5,opt,name=verbosity,proto3" json:"verbosity,omitempty"`
+	// Entropy claimed by the submitter in bits per sample (0 to bits_per_symbol).
+	// When set, h_final is min(H_original, bits_per_symbol * H_bitstring, h_submitter).
+	HSubmitter *float64 `protobuf:"fixed64,6,opt,name=h_submitter,json=hSubmitter,proto3,oneof" json:"h_submitter,omitempty"`
+	// Restricts the run to the named estimators (e.g. "mcv", "markov"); empty runs all.
+	// A subset is not a conforming SP 800-90B assessment.
+	Estimators []string `protobuf:"bytes,7,rep,name=estimators,proto3" json:"estimators,omitempty"`
+	// If true, run only the IID statistical tests and skip entropy estimation.
+	// passed then reports whether the data satisfies the IID assumption.
+	IidCheckOnly bool `protobuf:"varint,8,opt,name=iid_check_only,json=iidCheckOnly,proto3" json:"iid_check_only,omitempty"`
+	// If true, return the symbol histogram in the response. Ignored for iid_check_only.
+	IncludeHistogram bool `protobuf:"varint,9,opt,name=include_histogram,json=includeHistogram,proto3" json:"include_histogram,omitempty"`
+	// Index of the first sample of data to assess; earlier samples are skipped.
+	Offset uint64 `protobuf:"varint,10,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Number of samples to assess from offset; 0 means to the end of data.
+	Length uint64 `protobuf:"varint,11,opt,name=length,proto3" json:"length,omitempty"`
+	// Bit order of the bitstring expansion. Ignored for iid_check_only.
+	BitOrder BitOrder `protobuf:"varint,12,opt,name=bit_order,json=bitOrder,proto3,enum=nist.sp800_90b.v1.BitOrder" json:"bit_order,omitempty"`
+	// If true, return the NIST tool JSON document of each run in the response.
+	// Ignored for iid_check_only.
+	IncludeRawJson bool `protobuf:"varint,13,opt,name=include_raw_json,json=includeRawJson,proto3" json:"include_raw_json,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *Sp80090BAssessmentRequest) Reset() {
@@ -103,41 +310,112 @@ func (x *Sp80090BAssessmentRequest) GetVerbosity() uint32 {
 	return 0
 }
 
-// Sp80090bAssessmentResponse contains the results of the NIST SP 800-90B entropy assessment.
-type Sp80090BAssessmentResponse struct {
+func (x *Sp80090BAssessmentRequest) GetHSubmitter() float64 {
+	if x != nil && x.HSubmitter != nil {
+		return *x.HSubmitter
+	}
+	return 0
+}
+
+func (x *Sp80090BAssessmentRequest) GetEstimators() []string {
+	if x != nil {
+		return x.Estimators
+	}
+	return nil
+}
+
+func (x *Sp80090BAssessmentRequest) GetIidCheckOnly() bool {
+	if x != nil {
+		return x.IidCheckOnly
+	}
+	return false
+}
+
+func (x *Sp80090BAssessmentRequest) GetIncludeHistogram() bool {
+	if x != nil {
+		return x.IncludeHistogram
+	}
+	return false
+}
+
+func (x *Sp80090BAssessmentRequest) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Sp80090BAssessmentRequest) GetLength() uint64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *Sp80090BAssessmentRequest) GetBitOrder() BitOrder {
+	if x != nil {
+		return x.BitOrder
+	}
+	return BitOrder_BIT_ORDER_MSB_FIRST
+}
+
+func (x *Sp80090BAssessmentRequest) GetIncludeRawJson() bool {
+	if x != nil {
+		return x.IncludeRawJson
+	}
+	return false
+}
+
+// Sp80090bFileAssessmentRequest is Sp80090bAssessmentRequest with a server-side
+// file in place of inline data.
+type Sp80090BFileAssessmentRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
-	// Minimum entropy estimate in bits per sample.
-	MinEntropy float64 `protobuf:"fixed64,1,opt,name=min_entropy,json=minEntropy,proto3" json:"min_entropy,omitempty"`
-	// Results from IID tests (if iid_mode was true).
-	IidResults []*Sp80090BEstimatorResult `protobuf:"bytes,2,rep,name=iid_results,json=iidResults,proto3" json:"iid_results,omitempty"`
-	// Results from Non-IID estimators (if non_iid_mode was true).
-	NonIidResults []*Sp80090BEstimatorResult `protobuf:"bytes,3,rep,name=non_iid_results,json=nonIidResults,proto3" json:"non_iid_results,omitempty"`
-	// Overall assessment result (true if data passes validation).
-	Passed bool `protobuf:"varint,4,opt,name=passed,proto3" json:"passed,omitempty"`
-	// Human-readable summary of the assessment.
-	AssessmentSummary string `protobuf:"bytes,5,opt,name=assessment_summary,json=assessmentSummary,proto3" json:"assessment_summary,omitempty"`
-	// Number of samples analyzed.
-	SampleCount uint64 `protobuf:"varint,6,opt,name=sample_count,json=sampleCount,proto3" json:"sample_count,omitempty"`
-	// Actual bits per symbol used in the assessment.
-	BitsPerSymbol uint32 `protobuf:"varint,7,opt,name=bits_per_symbol,json=bitsPerSymbol,proto3" json:"bits_per_symbol,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	// Path of the sample file, relative to the server's base directory or absolute
+	// within it.
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// Bits per symbol (0-8); 0 enables auto-detection.
+	BitsPerSymbol uint32 `protobuf:"varint,2,opt,name=bits_per_symbol,json=bitsPerSymbol,proto3" json:"bits_per_symbol,omitempty"`
+	// If true, run IID (Independent and Identically Distributed) tests.
+	IidMode bool `protobuf:"varint,3,opt,name=iid_mode,json=iidMode,proto3" json:"iid_mode,omitempty"`
+	// If true, run Non-IID estimators.
+	NonIidMode bool `protobuf:"varint,4,opt,name=non_iid_mode,json=nonIidMode,proto3" json:"non_iid_mode,omitempty"`
+	// Verbosity of the NIST library for this request only (0=quiet, 1=normal,
+	// 2=verbose, 3=debug); larger values are clamped to 3.
+	Verbosity uint32 `protobuf:"varint,5,opt,name=verbosity,proto3" json:"verbosity,omitempty"`
+	// Entropy claimed by the submitter in bits per sample (0 to bits_per_symbol).
+	HSubmitter *float64 `protobuf:"fixed64,6,opt,name=h_submitter,json=hSubmitter,proto3,oneof" json:"h_submitter,omitempty"`
+	// Restricts the run to the named estimators; empty runs all.
+	Estimators []string `protobuf:"bytes,7,rep,name=estimators,proto3" json:"estimators,omitempty"`
+	// If true, run only the IID statistical tests and skip entropy estimation.
+	IidCheckOnly bool `protobuf:"varint,8,opt,name=iid_check_only,json=iidCheckOnly,proto3" json:"iid_check_only,omitempty"`
+	// If true, return the symbol histogram in the response.
+	IncludeHistogram bool `protobuf:"varint,9,opt,name=include_histogram,json=includeHistogram,proto3" json:"include_histogram,omitempty"`
+	// Byte offset of the first sample to assess within the file.
+	Offset uint64 `protobuf:"varint,10,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Number of bytes to assess from offset; 0 means to the end of the file.
+	Length uint64 `protobuf:"varint,11,opt,name=length,proto3" json:"length,omitempty"`
+	// Bit order of the bitstring expansion.
+	BitOrder BitOrder `protobuf:"varint,12,opt,name=bit_order,json=bitOrder,proto3,enum=nist.sp800_90b.v1.BitOrder" json:"bit_order,omitempty"`
+	// If true, return the NIST tool JSON document of each run in the response.
+	IncludeRawJson bool `protobuf:"varint,13,opt,name=include_raw_json,json=includeRawJson,proto3" json:"include_raw_json,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *Sp80090BAssessmentResponse) Reset() {
-	*x = Sp80090BAssessmentResponse{}
+func (x *Sp80090BFileAssessmentRequest) Reset() {
+	*x = Sp80090BFileAssessmentRequest{}
 	mi := &file_nist_sp800_90b_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Sp80090BAssessmentResponse) String() string {
+func (x *Sp80090BFileAssessmentRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Sp80090BAssessmentResponse) ProtoMessage() {}
+func (*Sp80090BFileAssessmentRequest) ProtoMessage() {}
 
-func (x *Sp80090BAssessmentResponse) ProtoReflect() protoreflect.Message {
+func (x *Sp80090BFileAssessmentRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_nist_sp800_90b_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -149,91 +427,177 @@ func (x *Sp80090BAssessmentResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Sp80090BAssessmentResponse.ProtoReflect.Descriptor instead.
-func (*Sp80090BAssessmentResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use Sp80090BFileAssessmentRequest.ProtoReflect.Descriptor instead.
+func (*Sp80090BFileAssessmentRequest) Descriptor() ([]byte, []int) {
 	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *Sp80090BAssessmentResponse) GetMinEntropy() float64 {
+func (x *Sp80090BFileAssessmentRequest) GetPath() string {
 	if x != nil {
-		return x.MinEntropy
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Sp80090BFileAssessmentRequest) GetBitsPerSymbol() uint32 {
+	if x != nil {
+		return x.BitsPerSymbol
 	}
 	return 0
 }
 
-func (x *Sp80090BAssessmentResponse) GetIidResults() []*Sp80090BEstimatorResult {
+func (x *Sp80090BFileAssessmentRequest) GetIidMode() bool {
 	if x != nil {
-		return x.IidResults
+		return x.IidMode
 	}
-	return nil
+	return false
 }
 
-func (x *Sp80090BAssessmentResponse) GetNonIidResults() []*Sp80090BEstimatorResult {
+func (x *Sp80090BFileAssessmentRequest) GetNonIidMode() bool {
 	if x != nil {
-		return x.NonIidResults
+		return x.NonIidMode
+	}
+	return false
+}
+
+func (x *Sp80090BFileAssessmentRequest) GetVerbosity() uint32 {
+	if x != nil {
+		return x.Verbosity
+	}
+	return 0
+}
+
+func (x *Sp80090BFileAssessmentRequest) GetHSubmitter() float64 {
+	if x != nil && x.HSubmitter != nil {
+		return *x.HSubmitter
+	}
+	return 0
+}
+
+func (x *Sp80090BFileAssessmentRequest) GetEstimators() []string {
+	if x != nil {
+		return x.Estimators
 	}
 	return nil
 }
 
-func (x *Sp80090BAssessmentResponse) GetPassed() bool {
+func (x *Sp80090BFileAssessmentRequest) GetIidCheckOnly() bool {
 	if x != nil {
-		return x.Passed
+		return x.IidCheckOnly
 	}
 	return false
 }
 
-func (x *Sp80090BAssessmentResponse) GetAssessmentSummary() string {
+func (x *Sp80090BFileAssessmentRequest) GetIncludeHistogram() bool {
 	if x != nil {
-		return x.AssessmentSummary
+		return x.IncludeHistogram
 	}
-	return ""
+	return false
 }
 
-func (x *Sp80090BAssessmentResponse) GetSampleCount() uint64 {
+func (x *Sp80090BFileAssessmentRequest) GetOffset() uint64 {
 	if x != nil {
-		return x.SampleCount
+		return x.Offset
 	}
 	return 0
 }
 
-func (x *Sp80090BAssessmentResponse) GetBitsPerSymbol() uint32 {
+func (x *Sp80090BFileAssessmentRequest) GetLength() uint64 {
 	if x != nil {
-		return x.BitsPerSymbol
+		return x.Length
 	}
 	return 0
 }
 
-// Sp80090bEstimatorResult contains the result of a single NIST SP 800-90B estimator or test.
-type Sp80090BEstimatorResult struct {
+func (x *Sp80090BFileAssessmentRequest) GetBitOrder() BitOrder {
+	if x != nil {
+		return x.BitOrder
+	}
+	return BitOrder_BIT_ORDER_MSB_FIRST
+}
+
+func (x *Sp80090BFileAssessmentRequest) GetIncludeRawJson() bool {
+	if x != nil {
+		return x.IncludeRawJson
+	}
+	return false
+}
+
+// Sp80090bAssessmentResponse contains the results of the NIST SP 800-90B entropy assessment.
+type Sp80090BAssessmentResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
-	// Name of the estimator or test (e.g., "Most Common Value", "Collision Test").
-	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	// Entropy estimate in bits per sample.
-	EntropyEstimate float64 `protobuf:"fixed64,2,opt,name=entropy_estimate,json=entropyEstimate,proto3" json:"entropy_estimate,omitempty"`
-	// Whether this test/estimator passed.
-	Passed bool `protobuf:"varint,3,opt,name=passed,proto3" json:"passed,omitempty"`
-	// Additional test-specific metrics and details.
-	Details map[string]float64 `protobuf:"bytes,4,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
-	// Human-readable description of the result.
-	Description   string `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	// Minimum entropy estimate in bits per sample.
+	MinEntropy float64 `protobuf:"fixed64,1,opt,name=min_entropy,json=minEntropy,proto3" json:"min_entropy,omitempty"`
+	// Results from IID tests (if iid_mode was true).
+	IidResults []*Sp80090BEstimatorResult `protobuf:"bytes,2,rep,name=iid_results,json=iidResults,proto3" json:"iid_results,omitempty"`
+	// Results from Non-IID estimators (if non_iid_mode was true).
+	NonIidResults []*Sp80090BEstimatorResult `protobuf:"bytes,3,rep,name=non_iid_results,json=nonIidResults,proto3" json:"non_iid_results,omitempty"`
+	// Overall assessment result (true if data passes validation). For
+	// iid_check_only requests, whether every IID test passed.
+	Passed bool `protobuf:"varint,4,opt,name=passed,proto3" json:"passed,omitempty"`
+	// Human-readable summary of the assessment.
+	AssessmentSummary string `protobuf:"bytes,5,opt,name=assessment_summary,json=assessmentSummary,proto3" json:"assessment_summary,omitempty"`
+	// Number of samples analyzed.
+	SampleCount uint64 `protobuf:"varint,6,opt,name=sample_count,json=sampleCount,proto3" json:"sample_count,omitempty"`
+	// Actual bits per symbol used in the assessment. When the request asked for
+	// auto-detection (bits_per_symbol = 0), this is the detected word size.
+	BitsPerSymbol uint32 `protobuf:"varint,7,opt,name=bits_per_symbol,json=bitsPerSymbol,proto3" json:"bits_per_symbol,omitempty"`
+	// Final entropy estimate: min_entropy, further limited by h_submitter when supplied.
+	HFinal float64 `protobuf:"fixed64,8,opt,name=h_final,json=hFinal,proto3" json:"h_final,omitempty"`
+	// True if h_submitter was the binding constraint for h_final.
+	SubmitterBinding bool `protobuf:"varint,9,opt,name=submitter_binding,json=submitterBinding,proto3" json:"submitter_binding,omitempty"`
+	// Occurrences of each symbol value (masked to bits_per_symbol), indexed by value.
+	// 256 entries when include_histogram was set, otherwise empty.
+	Histogram []uint64 `protobuf:"varint,10,rep,packed,name=histogram,proto3" json:"histogram,omitempty"`
+	// True if bits_per_symbol was detected from the data because the request
+	// asked for auto-detection, rather than taken from the request.
+	BitsPerSymbolAutoDetected bool `protobuf:"varint,11,opt,name=bits_per_symbol_auto_detected,json=bitsPerSymbolAutoDetected,proto3" json:"bits_per_symbol_auto_detected,omitempty"`
+	// Implementation that produced the result: "nist-cpp" for the NIST reference
+	// tool, "go" for the pure-Go estimators, or "stub" in test builds.
+	Backend string `protobuf:"bytes,12,opt,name=backend,proto3" json:"backend,omitempty"`
+	// True if the backend could only run part of the requested estimators. Such a
+	// result is not a conforming SP 800-90B assessment.
+	Partial bool `protobuf:"varint,13,opt,name=partial,proto3" json:"partial,omitempty"`
+	// Shannon entropy of the symbol frequencies in bits per symbol, a baseline
+	// next to min_entropy rather than an SP 800-90B estimate.
+	ShannonEntropy float64 `protobuf:"fixed64,14,opt,name=shannon_entropy,json=shannonEntropy,proto3" json:"shannon_entropy,omitempty"`
+	// Mode whose estimate is min_entropy. In mixed mode a tie is reported as
+	// Non-IID, the conservative result for data that may fail the IID tests.
+	MinEntropySource MinEntropySource `protobuf:"varint,15,opt,name=min_entropy_source,json=minEntropySource,proto3,enum=nist.sp800_90b.v1.MinEntropySource" json:"min_entropy_source,omitempty"`
+	// Number of distinct symbol values in the data, masked to bits_per_symbol.
+	// An alphabet that fits in fewer bits suggests a smaller word size.
+	AlphabetSize uint32 `protobuf:"varint,16,opt,name=alphabet_size,json=alphabetSize,proto3" json:"alphabet_size,omitempty"`
+	// JSON documents the NIST tool writes with -o for the IID and Non-IID runs,
+	// when include_raw_json was set and the backend produces them; otherwise empty.
+	IidRawJson    string `protobuf:"bytes,17,opt,name=iid_raw_json,json=iidRawJson,proto3" json:"iid_raw_json,omitempty"`
+	NonIidRawJson string `protobuf:"bytes,18,opt,name=non_iid_raw_json,json=nonIidRawJson,proto3" json:"non_iid_raw_json,omitempty"`
+	// H_original and H_bitstring (SP 800-90B Section 3.1.3) of the mode named by
+	// min_entropy_source; min_entropy is the lower of H_original and
+	// bits_per_symbol * H_bitstring. h_bitstring is 0 for 1-bit samples, which
+	// have no separate bitstring estimate, and both are 0 without an estimate.
+	HOriginal  float64 `protobuf:"fixed64,19,opt,name=h_original,json=hOriginal,proto3" json:"h_original,omitempty"`
+	HBitstring float64 `protobuf:"fixed64,20,opt,name=h_bitstring,json=hBitstring,proto3" json:"h_bitstring,omitempty"`
+	// Wall time of the NIST library call of each mode that ran, in
+	// milliseconds, keyed by "IID" and "Non-IID".
+	TimingsMs     map[string]float64 `protobuf:"bytes,21,rep,name=timings_ms,json=timingsMs,proto3" json:"timings_ms,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Sp80090BEstimatorResult) Reset() {
-	*x = Sp80090BEstimatorResult{}
+func (x *Sp80090BAssessmentResponse) Reset() {
+	*x = Sp80090BAssessmentResponse{}
 	mi := &file_nist_sp800_90b_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Sp80090BEstimatorResult) String() string {
+func (x *Sp80090BAssessmentResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Sp80090BEstimatorResult) ProtoMessage() {}
+func (*Sp80090BAssessmentResponse) ProtoMessage() {}
 
-func (x *Sp80090BEstimatorResult) ProtoReflect() protoreflect.Message {
+func (x *Sp80090BAssessmentResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_nist_sp800_90b_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -245,58 +609,743 @@ func (x *Sp80090BEstimatorResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Sp80090BEstimatorResult.ProtoReflect.Descriptor instead.
-func (*Sp80090BEstimatorResult) Descriptor() ([]byte, []int) {
+// Deprecated: Use Sp80090BAssessmentResponse.ProtoReflect.Descriptor instead.
+func (*Sp80090BAssessmentResponse) Descriptor() ([]byte, []int) {
 	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *Sp80090BEstimatorResult) GetName() string {
+func (x *Sp80090BAssessmentResponse) GetMinEntropy() float64 {
 	if x != nil {
-		return x.Name
+		return x.MinEntropy
 	}
-	return ""
+	return 0
 }
 
-func (x *Sp80090BEstimatorResult) GetEntropyEstimate() float64 {
+func (x *Sp80090BAssessmentResponse) GetIidResults() []*Sp80090BEstimatorResult {
 	if x != nil {
-		return x.EntropyEstimate
+		return x.IidResults
 	}
-	return 0
+	return nil
 }
 
-func (x *Sp80090BEstimatorResult) GetPassed() bool {
+func (x *Sp80090BAssessmentResponse) GetNonIidResults() []*Sp80090BEstimatorResult {
+	if x != nil {
+		return x.NonIidResults
+	}
+	return nil
+}
+
+func (x *Sp80090BAssessmentResponse) GetPassed() bool {
 	if x != nil {
 		return x.Passed
 	}
 	return false
 }
 
-func (x *Sp80090BEstimatorResult) GetDetails() map[string]float64 {
+func (x *Sp80090BAssessmentResponse) GetAssessmentSummary() string {
 	if x != nil {
-		return x.Details
+		return x.AssessmentSummary
 	}
-	return nil
+	return ""
 }
 
-func (x *Sp80090BEstimatorResult) GetDescription() string {
+func (x *Sp80090BAssessmentResponse) GetSampleCount() uint64 {
 	if x != nil {
-		return x.Description
+		return x.SampleCount
 	}
-	return ""
+	return 0
+}
+
+func (x *Sp80090BAssessmentResponse) GetBitsPerSymbol() uint32 {
+	if x != nil {
+		return x.BitsPerSymbol
+	}
+	return 0
+}
+
+func (x *Sp80090BAssessmentResponse) GetHFinal() float64 {
+	if x != nil {
+		return x.HFinal
+	}
+	return 0
+}
+
+func (x *Sp80090BAssessmentResponse) GetSubmitterBinding() bool {
+	if x != nil {
+		return x.SubmitterBinding
+	}
+	return false
+}
+
+func (x *Sp80090BAssessmentResponse) GetHistogram() []uint64 {
+	if x != nil {
+		return x.Histogram
+	}
+	return nil
+}
+
+func (x *Sp80090BAssessmentResponse) GetBitsPerSymbolAutoDetected() bool {
+	if x != nil {
+		return x.BitsPerSymbolAutoDetected
+	}
+	return false
+}
+
+func (x *Sp80090BAssessmentResponse) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *Sp80090BAssessmentResponse) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *Sp80090BAssessmentResponse) GetShannonEntropy() float64 {
+	if x != nil {
+		return x.ShannonEntropy
+	}
+	return 0
+}
+
+func (x *Sp80090BAssessmentResponse) GetMinEntropySource() MinEntropySource {
+	if x != nil {
+		return x.MinEntropySource
+	}
+	return MinEntropySource_MIN_ENTROPY_SOURCE_NONE
+}
+
+func (x *Sp80090BAssessmentResponse) GetAlphabetSize() uint32 {
+	if x != nil {
+		return x.AlphabetSize
+	}
+	return 0
+}
+
+func (x *Sp80090BAssessmentResponse) GetIidRawJson() string {
+	if x != nil {
+		return x.IidRawJson
+	}
+	return ""
+}
+
+func (x *Sp80090BAssessmentResponse) GetNonIidRawJson() string {
+	if x != nil {
+		return x.NonIidRawJson
+	}
+	return ""
+}
+
+func (x *Sp80090BAssessmentResponse) GetHOriginal() float64 {
+	if x != nil {
+		return x.HOriginal
+	}
+	return 0
+}
+
+func (x *Sp80090BAssessmentResponse) GetHBitstring() float64 {
+	if x != nil {
+		return x.HBitstring
+	}
+	return 0
+}
+
+func (x *Sp80090BAssessmentResponse) GetTimingsMs() map[string]float64 {
+	if x != nil {
+		return x.TimingsMs
+	}
+	return nil
+}
+
+// Sp80090bEstimatorResult contains the result of a single NIST SP 800-90B estimator or test.
+type Sp80090BEstimatorResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the estimator or test (e.g., "Most Common Value", "Collision Test").
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Entropy estimate in bits per sample.
+	EntropyEstimate float64 `protobuf:"fixed64,2,opt,name=entropy_estimate,json=entropyEstimate,proto3" json:"entropy_estimate,omitempty"`
+	// Whether this test/estimator passed.
+	Passed bool `protobuf:"varint,3,opt,name=passed,proto3" json:"passed,omitempty"`
+	// Additional test-specific metrics and details.
+	Details map[string]float64 `protobuf:"bytes,4,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	// Human-readable description of the result.
+	Description string `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	// Stable identifier of the estimator or test.
+	Id            EstimatorId `protobuf:"varint,6,opt,name=id,proto3,enum=nist.sp800_90b.v1.EstimatorId" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sp80090BEstimatorResult) Reset() {
+	*x = Sp80090BEstimatorResult{}
+	mi := &file_nist_sp800_90b_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sp80090BEstimatorResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sp80090BEstimatorResult) ProtoMessage() {}
+
+func (x *Sp80090BEstimatorResult) ProtoReflect() protoreflect.Message {
+	mi := &file_nist_sp800_90b_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sp80090BEstimatorResult.ProtoReflect.Descriptor instead.
+func (*Sp80090BEstimatorResult) Descriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Sp80090BEstimatorResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Sp80090BEstimatorResult) GetEntropyEstimate() float64 {
+	if x != nil {
+		return x.EntropyEstimate
+	}
+	return 0
+}
+
+func (x *Sp80090BEstimatorResult) GetPassed() bool {
+	if x != nil {
+		return x.Passed
+	}
+	return false
+}
+
+func (x *Sp80090BEstimatorResult) GetDetails() map[string]float64 {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+func (x *Sp80090BEstimatorResult) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Sp80090BEstimatorResult) GetId() EstimatorId {
+	if x != nil {
+		return x.Id
+	}
+	return EstimatorId_ESTIMATOR_ID_UNKNOWN
+}
+
+// Sp80090bCapabilitiesRequest is the (empty) request for GetCapabilities.
+type Sp80090BCapabilitiesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sp80090BCapabilitiesRequest) Reset() {
+	*x = Sp80090BCapabilitiesRequest{}
+	mi := &file_nist_sp800_90b_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sp80090BCapabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sp80090BCapabilitiesRequest) ProtoMessage() {}
+
+func (x *Sp80090BCapabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nist_sp800_90b_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sp80090BCapabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*Sp80090BCapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{4}
+}
+
+// Sp80090bCapabilitiesResponse describes the assessment library behind the service.
+type Sp80090BCapabilitiesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Version of the gRPC service API.
+	ServiceVersion string `protobuf:"bytes,1,opt,name=service_version,json=serviceVersion,proto3" json:"service_version,omitempty"`
+	// Version of the linked NIST SP 800-90B reference tool ("stub" in test builds).
+	ToolVersion string `protobuf:"bytes,2,opt,name=tool_version,json=toolVersion,proto3" json:"tool_version,omitempty"`
+	// Version of the C wrapper around the reference tool.
+	WrapperVersion string `protobuf:"bytes,3,opt,name=wrapper_version,json=wrapperVersion,proto3" json:"wrapper_version,omitempty"`
+	// False when the service runs a stub build that returns fixed, fake results.
+	Cgo bool `protobuf:"varint,4,opt,name=cgo,proto3" json:"cgo,omitempty"`
+	// Estimator names accepted in Sp80090bAssessmentRequest.estimators.
+	Estimators []string `protobuf:"bytes,5,rep,name=estimators,proto3" json:"estimators,omitempty"`
+	// Implementation behind assessments: "nist-cpp", "go" (pure-Go estimators,
+	// a non-conforming subset) or "stub".
+	Backend string `protobuf:"bytes,6,opt,name=backend,proto3" json:"backend,omitempty"`
+	// Names of the IID estimators and tests this build runs.
+	IidEstimators []string `protobuf:"bytes,7,rep,name=iid_estimators,json=iidEstimators,proto3" json:"iid_estimators,omitempty"`
+	// Names of the Non-IID estimators this build runs.
+	NonIidEstimators []string `protobuf:"bytes,8,rep,name=non_iid_estimators,json=nonIidEstimators,proto3" json:"non_iid_estimators,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Sp80090BCapabilitiesResponse) Reset() {
+	*x = Sp80090BCapabilitiesResponse{}
+	mi := &file_nist_sp800_90b_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sp80090BCapabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sp80090BCapabilitiesResponse) ProtoMessage() {}
+
+func (x *Sp80090BCapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nist_sp800_90b_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sp80090BCapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*Sp80090BCapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Sp80090BCapabilitiesResponse) GetServiceVersion() string {
+	if x != nil {
+		return x.ServiceVersion
+	}
+	return ""
+}
+
+func (x *Sp80090BCapabilitiesResponse) GetToolVersion() string {
+	if x != nil {
+		return x.ToolVersion
+	}
+	return ""
+}
+
+func (x *Sp80090BCapabilitiesResponse) GetWrapperVersion() string {
+	if x != nil {
+		return x.WrapperVersion
+	}
+	return ""
+}
+
+func (x *Sp80090BCapabilitiesResponse) GetCgo() bool {
+	if x != nil {
+		return x.Cgo
+	}
+	return false
+}
+
+func (x *Sp80090BCapabilitiesResponse) GetEstimators() []string {
+	if x != nil {
+		return x.Estimators
+	}
+	return nil
+}
+
+func (x *Sp80090BCapabilitiesResponse) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *Sp80090BCapabilitiesResponse) GetIidEstimators() []string {
+	if x != nil {
+		return x.IidEstimators
+	}
+	return nil
+}
+
+func (x *Sp80090BCapabilitiesResponse) GetNonIidEstimators() []string {
+	if x != nil {
+		return x.NonIidEstimators
+	}
+	return nil
+}
+
+// Sp80090bSupportedEstimatorsRequest is the (empty) request for GetSupportedEstimators.
+type Sp80090BSupportedEstimatorsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sp80090BSupportedEstimatorsRequest) Reset() {
+	*x = Sp80090BSupportedEstimatorsRequest{}
+	mi := &file_nist_sp800_90b_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sp80090BSupportedEstimatorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sp80090BSupportedEstimatorsRequest) ProtoMessage() {}
+
+func (x *Sp80090BSupportedEstimatorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nist_sp800_90b_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sp80090BSupportedEstimatorsRequest.ProtoReflect.Descriptor instead.
+func (*Sp80090BSupportedEstimatorsRequest) Descriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{6}
+}
+
+// Sp80090bSupportedEstimatorsResponse lists, per test type, the names that appear in
+// Sp80090bEstimatorResult.name, in the order the estimators run.
+type Sp80090BSupportedEstimatorsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Estimators and statistical tests reported by IID assessments.
+	Iid []string `protobuf:"bytes,1,rep,name=iid,proto3" json:"iid,omitempty"`
+	// Estimators reported by Non-IID assessments.
+	NonIid        []string `protobuf:"bytes,2,rep,name=non_iid,json=nonIid,proto3" json:"non_iid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sp80090BSupportedEstimatorsResponse) Reset() {
+	*x = Sp80090BSupportedEstimatorsResponse{}
+	mi := &file_nist_sp800_90b_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sp80090BSupportedEstimatorsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sp80090BSupportedEstimatorsResponse) ProtoMessage() {}
+
+func (x *Sp80090BSupportedEstimatorsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nist_sp800_90b_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sp80090BSupportedEstimatorsResponse.ProtoReflect.Descriptor instead.
+func (*Sp80090BSupportedEstimatorsResponse) Descriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Sp80090BSupportedEstimatorsResponse) GetIid() []string {
+	if x != nil {
+		return x.Iid
+	}
+	return nil
+}
+
+func (x *Sp80090BSupportedEstimatorsResponse) GetNonIid() []string {
+	if x != nil {
+		return x.NonIid
+	}
+	return nil
+}
+
+// Sp80090bValidateRequest carries a request to check with ValidateRequest.
+type Sp80090BValidateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The request as it would be sent to AssessEntropy.
+	Request *Sp80090BAssessmentRequest `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	// Size in bytes of the data to be sent, checked in place of request.data
+	// when that is empty, to validate a request before uploading its data. The
+	// window, sample count and symbol range checks need the data and are
+	// skipped without it.
+	DataSize      uint64 `protobuf:"varint,2,opt,name=data_size,json=dataSize,proto3" json:"data_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sp80090BValidateRequest) Reset() {
+	*x = Sp80090BValidateRequest{}
+	mi := &file_nist_sp800_90b_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sp80090BValidateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sp80090BValidateRequest) ProtoMessage() {}
+
+func (x *Sp80090BValidateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nist_sp800_90b_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sp80090BValidateRequest.ProtoReflect.Descriptor instead.
+func (*Sp80090BValidateRequest) Descriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Sp80090BValidateRequest) GetRequest() *Sp80090BAssessmentRequest {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *Sp80090BValidateRequest) GetDataSize() uint64 {
+	if x != nil {
+		return x.DataSize
+	}
+	return 0
+}
+
+// Sp80090bValidationCheck is the outcome of one input check.
+type Sp80090BValidationCheck struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the check, in the order AssessEntropy applies them: "request",
+	// "data", "size", "window", "bits_per_symbol", "h_submitter",
+	// "iid_check_only", "mode", "bit_order", "estimators", "sample_count" and
+	// "symbol_range".
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// True when the request passed the check.
+	Passed bool `protobuf:"varint,2,opt,name=passed,proto3" json:"passed,omitempty"`
+	// True when the check did not run, because it does not apply to the
+	// request or depends on a check that failed or was skipped.
+	Skipped bool `protobuf:"varint,3,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	// Why the check failed or was skipped; empty when it passed.
+	Message       string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sp80090BValidationCheck) Reset() {
+	*x = Sp80090BValidationCheck{}
+	mi := &file_nist_sp800_90b_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sp80090BValidationCheck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sp80090BValidationCheck) ProtoMessage() {}
+
+func (x *Sp80090BValidationCheck) ProtoReflect() protoreflect.Message {
+	mi := &file_nist_sp800_90b_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sp80090BValidationCheck.ProtoReflect.Descriptor instead.
+func (*Sp80090BValidationCheck) Descriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Sp80090BValidationCheck) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Sp80090BValidationCheck) GetPassed() bool {
+	if x != nil {
+		return x.Passed
+	}
+	return false
+}
+
+func (x *Sp80090BValidationCheck) GetSkipped() bool {
+	if x != nil {
+		return x.Skipped
+	}
+	return false
+}
+
+func (x *Sp80090BValidationCheck) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Sp80090bValidateResponse reports the input checks of a request.
+type Sp80090BValidateResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// True when no check failed, so that AssessEntropy accepts the request,
+	// with the data when data_size was given.
+	Valid bool `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	// Every check, in the order AssessEntropy applies them.
+	Checks []*Sp80090BValidationCheck `protobuf:"bytes,2,rep,name=checks,proto3" json:"checks,omitempty"`
+	// Largest data the server accepts in bytes; 0 when unlimited.
+	MaxUploadSize uint64 `protobuf:"varint,3,opt,name=max_upload_size,json=maxUploadSize,proto3" json:"max_upload_size,omitempty"`
+	// Smallest number of samples the server assesses; 0 when any.
+	MinSamples    uint64 `protobuf:"varint,4,opt,name=min_samples,json=minSamples,proto3" json:"min_samples,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sp80090BValidateResponse) Reset() {
+	*x = Sp80090BValidateResponse{}
+	mi := &file_nist_sp800_90b_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sp80090BValidateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sp80090BValidateResponse) ProtoMessage() {}
+
+func (x *Sp80090BValidateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nist_sp800_90b_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sp80090BValidateResponse.ProtoReflect.Descriptor instead.
+func (*Sp80090BValidateResponse) Descriptor() ([]byte, []int) {
+	return file_nist_sp800_90b_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Sp80090BValidateResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *Sp80090BValidateResponse) GetChecks() []*Sp80090BValidationCheck {
+	if x != nil {
+		return x.Checks
+	}
+	return nil
+}
+
+func (x *Sp80090BValidateResponse) GetMaxUploadSize() uint64 {
+	if x != nil {
+		return x.MaxUploadSize
+	}
+	return 0
+}
+
+func (x *Sp80090BValidateResponse) GetMinSamples() uint64 {
+	if x != nil {
+		return x.MinSamples
+	}
+	return 0
 }
 
 var File_nist_sp800_90b_proto protoreflect.FileDescriptor
 
 const file_nist_sp800_90b_proto_rawDesc = "" +
 	"\n" +
-	"\x14nist_sp800_90b.proto\x12\x11nist.sp800_90b.v1\"\xb2\x01\n" +
+	"\x14nist_sp800_90b.proto\x12\x11nist.sp800_90b.v1\"\xef\x03\n" +
 	"\x19Sp80090bAssessmentRequest\x12\x12\n" +
 	"\x04data\x18\x01 \x01(\fR\x04data\x12&\n" +
 	"\x0fbits_per_symbol\x18\x02 \x01(\rR\rbitsPerSymbol\x12\x19\n" +
 	"\biid_mode\x18\x03 \x01(\bR\aiidMode\x12 \n" +
 	"\fnon_iid_mode\x18\x04 \x01(\bR\n" +
 	"nonIidMode\x12\x1c\n" +
-	"\tverbosity\x18\x05 \x01(\rR\tverbosity\"\xf0\x02\n" +
+	"\tverbosity\x18\x05 \x01(\rR\tverbosity\x12$\n" +
+	"\vh_submitter\x18\x06 \x01(\x01H\x00R\n" +
+	"hSubmitter\x88\x01\x01\x12\x1e\n" +
+	"\n" +
+	"estimators\x18\a \x03(\tR\n" +
+	"estimators\x12$\n" +
+	"\x0eiid_check_only\x18\b \x01(\bR\fiidCheckOnly\x12+\n" +
+	"\x11include_histogram\x18\t \x01(\bR\x10includeHistogram\x12\x16\n" +
+	"\x06offset\x18\n" +
+	" \x01(\x04R\x06offset\x12\x16\n" +
+	"\x06length\x18\v \x01(\x04R\x06length\x128\n" +
+	"\tbit_order\x18\f \x01(\x0e2\x1b.nist.sp800_90b.v1.BitOrderR\bbitOrder\x12(\n" +
+	"\x10include_raw_json\x18\r \x01(\bR\x0eincludeRawJsonB\x0e\n" +
+	"\f_h_submitter\"\xf3\x03\n" +
+	"\x1dSp80090bFileAssessmentRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12&\n" +
+	"\x0fbits_per_symbol\x18\x02 \x01(\rR\rbitsPerSymbol\x12\x19\n" +
+	"\biid_mode\x18\x03 \x01(\bR\aiidMode\x12 \n" +
+	"\fnon_iid_mode\x18\x04 \x01(\bR\n" +
+	"nonIidMode\x12\x1c\n" +
+	"\tverbosity\x18\x05 \x01(\rR\tverbosity\x12$\n" +
+	"\vh_submitter\x18\x06 \x01(\x01H\x00R\n" +
+	"hSubmitter\x88\x01\x01\x12\x1e\n" +
+	"\n" +
+	"estimators\x18\a \x03(\tR\n" +
+	"estimators\x12$\n" +
+	"\x0eiid_check_only\x18\b \x01(\bR\fiidCheckOnly\x12+\n" +
+	"\x11include_histogram\x18\t \x01(\bR\x10includeHistogram\x12\x16\n" +
+	"\x06offset\x18\n" +
+	" \x01(\x04R\x06offset\x12\x16\n" +
+	"\x06length\x18\v \x01(\x04R\x06length\x128\n" +
+	"\tbit_order\x18\f \x01(\x0e2\x1b.nist.sp800_90b.v1.BitOrderR\bbitOrder\x12(\n" +
+	"\x10include_raw_json\x18\r \x01(\bR\x0eincludeRawJsonB\x0e\n" +
+	"\f_h_submitter\"\x91\b\n" +
 	"\x1aSp80090bAssessmentResponse\x12\x1f\n" +
 	"\vmin_entropy\x18\x01 \x01(\x01R\n" +
 	"minEntropy\x12K\n" +
@@ -306,18 +1355,97 @@ const file_nist_sp800_90b_proto_rawDesc = "" +
 	"\x06passed\x18\x04 \x01(\bR\x06passed\x12-\n" +
 	"\x12assessment_summary\x18\x05 \x01(\tR\x11assessmentSummary\x12!\n" +
 	"\fsample_count\x18\x06 \x01(\x04R\vsampleCount\x12&\n" +
-	"\x0fbits_per_symbol\x18\a \x01(\rR\rbitsPerSymbol\"\xa1\x02\n" +
+	"\x0fbits_per_symbol\x18\a \x01(\rR\rbitsPerSymbol\x12\x17\n" +
+	"\ah_final\x18\b \x01(\x01R\x06hFinal\x12+\n" +
+	"\x11submitter_binding\x18\t \x01(\bR\x10submitterBinding\x12\x1c\n" +
+	"\thistogram\x18\n" +
+	" \x03(\x04R\thistogram\x12@\n" +
+	"\x1dbits_per_symbol_auto_detected\x18\v \x01(\bR\x19bitsPerSymbolAutoDetected\x12\x18\n" +
+	"\abackend\x18\f \x01(\tR\abackend\x12\x18\n" +
+	"\apartial\x18\r \x01(\bR\apartial\x12'\n" +
+	"\x0fshannon_entropy\x18\x0e \x01(\x01R\x0eshannonEntropy\x12Q\n" +
+	"\x12min_entropy_source\x18\x0f \x01(\x0e2#.nist.sp800_90b.v1.MinEntropySourceR\x10minEntropySource\x12#\n" +
+	"\ralphabet_size\x18\x10 \x01(\rR\falphabetSize\x12 \n" +
+	"\fiid_raw_json\x18\x11 \x01(\tR\n" +
+	"iidRawJson\x12'\n" +
+	"\x10non_iid_raw_json\x18\x12 \x01(\tR\rnonIidRawJson\x12\x1d\n" +
+	"\n" +
+	"h_original\x18\x13 \x01(\x01R\thOriginal\x12\x1f\n" +
+	"\vh_bitstring\x18\x14 \x01(\x01R\n" +
+	"hBitstring\x12[\n" +
+	"\n" +
+	"timings_ms\x18\x15 \x03(\v2<.nist.sp800_90b.v1.Sp80090bAssessmentResponse.TimingsMsEntryR\ttimingsMs\x1a<\n" +
+	"\x0eTimingsMsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"\xd1\x02\n" +
 	"\x17Sp80090bEstimatorResult\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12)\n" +
 	"\x10entropy_estimate\x18\x02 \x01(\x01R\x0fentropyEstimate\x12\x16\n" +
 	"\x06passed\x18\x03 \x01(\bR\x06passed\x12Q\n" +
 	"\adetails\x18\x04 \x03(\v27.nist.sp800_90b.v1.Sp80090bEstimatorResult.DetailsEntryR\adetails\x12 \n" +
-	"\vdescription\x18\x05 \x01(\tR\vdescription\x1a:\n" +
+	"\vdescription\x18\x05 \x01(\tR\vdescription\x12.\n" +
+	"\x02id\x18\x06 \x01(\x0e2\x1e.nist.sp800_90b.v1.EstimatorIdR\x02id\x1a:\n" +
 	"\fDetailsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x012\x89\x01\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"\x1d\n" +
+	"\x1bSp80090bCapabilitiesRequest\"\xb4\x02\n" +
+	"\x1cSp80090bCapabilitiesResponse\x12'\n" +
+	"\x0fservice_version\x18\x01 \x01(\tR\x0eserviceVersion\x12!\n" +
+	"\ftool_version\x18\x02 \x01(\tR\vtoolVersion\x12'\n" +
+	"\x0fwrapper_version\x18\x03 \x01(\tR\x0ewrapperVersion\x12\x10\n" +
+	"\x03cgo\x18\x04 \x01(\bR\x03cgo\x12\x1e\n" +
+	"\n" +
+	"estimators\x18\x05 \x03(\tR\n" +
+	"estimators\x12\x18\n" +
+	"\abackend\x18\x06 \x01(\tR\abackend\x12%\n" +
+	"\x0eiid_estimators\x18\a \x03(\tR\riidEstimators\x12,\n" +
+	"\x12non_iid_estimators\x18\b \x03(\tR\x10nonIidEstimators\"$\n" +
+	"\"Sp80090bSupportedEstimatorsRequest\"P\n" +
+	"#Sp80090bSupportedEstimatorsResponse\x12\x10\n" +
+	"\x03iid\x18\x01 \x03(\tR\x03iid\x12\x17\n" +
+	"\anon_iid\x18\x02 \x03(\tR\x06nonIid\"~\n" +
+	"\x17Sp80090bValidateRequest\x12F\n" +
+	"\arequest\x18\x01 \x01(\v2,.nist.sp800_90b.v1.Sp80090bAssessmentRequestR\arequest\x12\x1b\n" +
+	"\tdata_size\x18\x02 \x01(\x04R\bdataSize\"y\n" +
+	"\x17Sp80090bValidationCheck\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06passed\x18\x02 \x01(\bR\x06passed\x12\x18\n" +
+	"\askipped\x18\x03 \x01(\bR\askipped\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\"\xbd\x01\n" +
+	"\x18Sp80090bValidateResponse\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\bR\x05valid\x12B\n" +
+	"\x06checks\x18\x02 \x03(\v2*.nist.sp800_90b.v1.Sp80090bValidationCheckR\x06checks\x12&\n" +
+	"\x0fmax_upload_size\x18\x03 \x01(\x04R\rmaxUploadSize\x12\x1f\n" +
+	"\vmin_samples\x18\x04 \x01(\x04R\n" +
+	"minSamples*<\n" +
+	"\bBitOrder\x12\x17\n" +
+	"\x13BIT_ORDER_MSB_FIRST\x10\x00\x12\x17\n" +
+	"\x13BIT_ORDER_LSB_FIRST\x10\x01*\xe1\x02\n" +
+	"\vEstimatorId\x12\x18\n" +
+	"\x14ESTIMATOR_ID_UNKNOWN\x10\x00\x12\x14\n" +
+	"\x10ESTIMATOR_ID_MCV\x10\x01\x12\x1a\n" +
+	"\x16ESTIMATOR_ID_COLLISION\x10\x02\x12\x17\n" +
+	"\x13ESTIMATOR_ID_MARKOV\x10\x03\x12\x1c\n" +
+	"\x18ESTIMATOR_ID_COMPRESSION\x10\x04\x12\x18\n" +
+	"\x14ESTIMATOR_ID_T_TUPLE\x10\x05\x12\x14\n" +
+	"\x10ESTIMATOR_ID_LRS\x10\x06\x12\x1a\n" +
+	"\x16ESTIMATOR_ID_MULTI_MCW\x10\a\x12\x14\n" +
+	"\x10ESTIMATOR_ID_LAG\x10\b\x12\x1a\n" +
+	"\x16ESTIMATOR_ID_MULTI_MMC\x10\t\x12\x16\n" +
+	"\x12ESTIMATOR_ID_LZ78Y\x10\n" +
+	"\x12\x1b\n" +
+	"\x17ESTIMATOR_ID_CHI_SQUARE\x10\v\x12\x1c\n" +
+	"\x18ESTIMATOR_ID_PERMUTATION\x10\f*k\n" +
+	"\x10MinEntropySource\x12\x1b\n" +
+	"\x17MIN_ENTROPY_SOURCE_NONE\x10\x00\x12\x1a\n" +
+	"\x16MIN_ENTROPY_SOURCE_IID\x10\x01\x12\x1e\n" +
+	"\x1aMIN_ENTROPY_SOURCE_NON_IID\x10\x022\xe9\x04\n" +
 	"\x19Sp80090bAssessmentService\x12l\n" +
-	"\rAssessEntropy\x12,.nist.sp800_90b.v1.Sp80090bAssessmentRequest\x1a-.nist.sp800_90b.v1.Sp80090bAssessmentResponseB?Z=github.com/AmmannChristian/nist-800-90b/pkg/pb;nistsp80090bv1b\x06proto3"
+	"\rAssessEntropy\x12,.nist.sp800_90b.v1.Sp80090bAssessmentRequest\x1a-.nist.sp800_90b.v1.Sp80090bAssessmentResponse\x12t\n" +
+	"\x11AssessEntropyFile\x120.nist.sp800_90b.v1.Sp80090bFileAssessmentRequest\x1a-.nist.sp800_90b.v1.Sp80090bAssessmentResponse\x12r\n" +
+	"\x0fGetCapabilities\x12..nist.sp800_90b.v1.Sp80090bCapabilitiesRequest\x1a/.nist.sp800_90b.v1.Sp80090bCapabilitiesResponse\x12\x87\x01\n" +
+	"\x16GetSupportedEstimators\x125.nist.sp800_90b.v1.Sp80090bSupportedEstimatorsRequest\x1a6.nist.sp800_90b.v1.Sp80090bSupportedEstimatorsResponse\x12j\n" +
+	"\x0fValidateRequest\x12*.nist.sp800_90b.v1.Sp80090bValidateRequest\x1a+.nist.sp800_90b.v1.Sp80090bValidateResponseB?Z=github.com/AmmannChristian/nist-800-90b/pkg/pb;nistsp80090bv1b\x06proto3"
 
 var (
 	file_nist_sp800_90b_proto_rawDescOnce sync.Once
@@ -331,24 +1459,52 @@ func file_nist_sp800_90b_proto_rawDescGZIP() []byte {
 	return file_nist_sp800_90b_proto_rawDescData
 }
 
-var file_nist_sp800_90b_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_nist_sp800_90b_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_nist_sp800_90b_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_nist_sp800_90b_proto_goTypes = []any{
-	(*Sp80090BAssessmentRequest)(nil),  // 0: nist.sp800_90b.v1.Sp80090bAssessmentRequest
-	(*Sp80090BAssessmentResponse)(nil), // 1: nist.sp800_90b.v1.Sp80090bAssessmentResponse
-	(*Sp80090BEstimatorResult)(nil),    // 2: nist.sp800_90b.v1.Sp80090bEstimatorResult
-	nil,                                // 3: nist.sp800_90b.v1.Sp80090bEstimatorResult.DetailsEntry
+	(BitOrder)(0),                               // 0: nist.sp800_90b.v1.BitOrder
+	(EstimatorId)(0),                            // 1: nist.sp800_90b.v1.EstimatorId
+	(MinEntropySource)(0),                       // 2: nist.sp800_90b.v1.MinEntropySource
+	(*Sp80090BAssessmentRequest)(nil),           // 3: nist.sp800_90b.v1.Sp80090bAssessmentRequest
+	(*Sp80090BFileAssessmentRequest)(nil),       // 4: nist.sp800_90b.v1.Sp80090bFileAssessmentRequest
+	(*Sp80090BAssessmentResponse)(nil),          // 5: nist.sp800_90b.v1.Sp80090bAssessmentResponse
+	(*Sp80090BEstimatorResult)(nil),             // 6: nist.sp800_90b.v1.Sp80090bEstimatorResult
+	(*Sp80090BCapabilitiesRequest)(nil),         // 7: nist.sp800_90b.v1.Sp80090bCapabilitiesRequest
+	(*Sp80090BCapabilitiesResponse)(nil),        // 8: nist.sp800_90b.v1.Sp80090bCapabilitiesResponse
+	(*Sp80090BSupportedEstimatorsRequest)(nil),  // 9: nist.sp800_90b.v1.Sp80090bSupportedEstimatorsRequest
+	(*Sp80090BSupportedEstimatorsResponse)(nil), // 10: nist.sp800_90b.v1.Sp80090bSupportedEstimatorsResponse
+	(*Sp80090BValidateRequest)(nil),             // 11: nist.sp800_90b.v1.Sp80090bValidateRequest
+	(*Sp80090BValidationCheck)(nil),             // 12: nist.sp800_90b.v1.Sp80090bValidationCheck
+	(*Sp80090BValidateResponse)(nil),            // 13: nist.sp800_90b.v1.Sp80090bValidateResponse
+	nil,                                         // 14: nist.sp800_90b.v1.Sp80090bAssessmentResponse.TimingsMsEntry
+	nil,                                         // 15: nist.sp800_90b.v1.Sp80090bEstimatorResult.DetailsEntry
 }
 var file_nist_sp800_90b_proto_depIdxs = []int32{
-	2, // 0: nist.sp800_90b.v1.Sp80090bAssessmentResponse.iid_results:type_name -> nist.sp800_90b.v1.Sp80090bEstimatorResult
-	2, // 1: nist.sp800_90b.v1.Sp80090bAssessmentResponse.non_iid_results:type_name -> nist.sp800_90b.v1.Sp80090bEstimatorResult
-	3, // 2: nist.sp800_90b.v1.Sp80090bEstimatorResult.details:type_name -> nist.sp800_90b.v1.Sp80090bEstimatorResult.DetailsEntry
-	0, // 3: nist.sp800_90b.v1.Sp80090bAssessmentService.AssessEntropy:input_type -> nist.sp800_90b.v1.Sp80090bAssessmentRequest
-	1, // 4: nist.sp800_90b.v1.Sp80090bAssessmentService.AssessEntropy:output_type -> nist.sp800_90b.v1.Sp80090bAssessmentResponse
-	4, // [4:5] is the sub-list for method output_type
-	3, // [3:4] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	0,  // 0: nist.sp800_90b.v1.Sp80090bAssessmentRequest.bit_order:type_name -> nist.sp800_90b.v1.BitOrder
+	0,  // 1: nist.sp800_90b.v1.Sp80090bFileAssessmentRequest.bit_order:type_name -> nist.sp800_90b.v1.BitOrder
+	6,  // 2: nist.sp800_90b.v1.Sp80090bAssessmentResponse.iid_results:type_name -> nist.sp800_90b.v1.Sp80090bEstimatorResult
+	6,  // 3: nist.sp800_90b.v1.Sp80090bAssessmentResponse.non_iid_results:type_name -> nist.sp800_90b.v1.Sp80090bEstimatorResult
+	2,  // 4: nist.sp800_90b.v1.Sp80090bAssessmentResponse.min_entropy_source:type_name -> nist.sp800_90b.v1.MinEntropySource
+	14, // 5: nist.sp800_90b.v1.Sp80090bAssessmentResponse.timings_ms:type_name -> nist.sp800_90b.v1.Sp80090bAssessmentResponse.TimingsMsEntry
+	15, // 6: nist.sp800_90b.v1.Sp80090bEstimatorResult.details:type_name -> nist.sp800_90b.v1.Sp80090bEstimatorResult.DetailsEntry
+	1,  // 7: nist.sp800_90b.v1.Sp80090bEstimatorResult.id:type_name -> nist.sp800_90b.v1.EstimatorId
+	3,  // 8: nist.sp800_90b.v1.Sp80090bValidateRequest.request:type_name -> nist.sp800_90b.v1.Sp80090bAssessmentRequest
+	12, // 9: nist.sp800_90b.v1.Sp80090bValidateResponse.checks:type_name -> nist.sp800_90b.v1.Sp80090bValidationCheck
+	3,  // 10: nist.sp800_90b.v1.Sp80090bAssessmentService.AssessEntropy:input_type -> nist.sp800_90b.v1.Sp80090bAssessmentRequest
+	4,  // 11: nist.sp800_90b.v1.Sp80090bAssessmentService.AssessEntropyFile:input_type -> nist.sp800_90b.v1.Sp80090bFileAssessmentRequest
+	7,  // 12: nist.sp800_90b.v1.Sp80090bAssessmentService.GetCapabilities:input_type -> nist.sp800_90b.v1.Sp80090bCapabilitiesRequest
+	9,  // 13: nist.sp800_90b.v1.Sp80090bAssessmentService.GetSupportedEstimators:input_type -> nist.sp800_90b.v1.Sp80090bSupportedEstimatorsRequest
+	11, // 14: nist.sp800_90b.v1.Sp80090bAssessmentService.ValidateRequest:input_type -> nist.sp800_90b.v1.Sp80090bValidateRequest
+	5,  // 15: nist.sp800_90b.v1.Sp80090bAssessmentService.AssessEntropy:output_type -> nist.sp800_90b.v1.Sp80090bAssessmentResponse
+	5,  // 16: nist.sp800_90b.v1.Sp80090bAssessmentService.AssessEntropyFile:output_type -> nist.sp800_90b.v1.Sp80090bAssessmentResponse
+	8,  // 17: nist.sp800_90b.v1.Sp80090bAssessmentService.GetCapabilities:output_type -> nist.sp800_90b.v1.Sp80090bCapabilitiesResponse
+	10, // 18: nist.sp800_90b.v1.Sp80090bAssessmentService.GetSupportedEstimators:output_type -> nist.sp800_90b.v1.Sp80090bSupportedEstimatorsResponse
+	13, // 19: nist.sp800_90b.v1.Sp80090bAssessmentService.ValidateRequest:output_type -> nist.sp800_90b.v1.Sp80090bValidateResponse
+	15, // [15:20] is the sub-list for method output_type
+	10, // [10:15] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_nist_sp800_90b_proto_init() }
@@ -356,18 +1512,21 @@ func file_nist_sp800_90b_proto_init() {
 	if File_nist_sp800_90b_proto != nil {
 		return
 	}
+	file_nist_sp800_90b_proto_msgTypes[0].OneofWrappers = []any{}
+	file_nist_sp800_90b_proto_msgTypes[1].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_nist_sp800_90b_proto_rawDesc), len(file_nist_sp800_90b_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      3,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_nist_sp800_90b_proto_goTypes,
 		DependencyIndexes: file_nist_sp800_90b_proto_depIdxs,
+		EnumInfos:         file_nist_sp800_90b_proto_enumTypes,
 		MessageInfos:      file_nist_sp800_90b_proto_msgTypes,
 	}.Build()
 	File_nist_sp800_90b_proto = out.File
@@ -19,17 +19,31 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Sp80090BAssessmentService_AssessEntropy_FullMethodName = "/nist.sp800_90b.v1.Sp80090bAssessmentService/AssessEntropy"
+	Sp80090BAssessmentService_AssessEntropy_FullMethodName          = "/nist.sp800_90b.v1.Sp80090bAssessmentService/AssessEntropy"
+	Sp80090BAssessmentService_AssessEntropyFile_FullMethodName      = "/nist.sp800_90b.v1.Sp80090bAssessmentService/AssessEntropyFile"
+	Sp80090BAssessmentService_GetCapabilities_FullMethodName        = "/nist.sp800_90b.v1.Sp80090bAssessmentService/GetCapabilities"
+	Sp80090BAssessmentService_GetSupportedEstimators_FullMethodName = "/nist.sp800_90b.v1.Sp80090bAssessmentService/GetSupportedEstimators"
+	Sp80090BAssessmentService_ValidateRequest_FullMethodName        = "/nist.sp800_90b.v1.Sp80090bAssessmentService/ValidateRequest"
 )
 
 // Sp80090BAssessmentServiceClient is the client API for Sp80090BAssessmentService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 //
-// Service for NIST SP 800-90B entropy assessment
+// Sp80090bAssessmentService exposes NIST SP 800-90B entropy assessment
+// over gRPC. It supports IID and Non-IID test modes on raw sample data.
 type Sp80090BAssessmentServiceClient interface {
 	// AssessEntropy performs NIST SP 800-90B entropy assessment on the provided data samples.
 	AssessEntropy(ctx context.Context, in *Sp80090BAssessmentRequest, opts ...grpc.CallOption) (*Sp80090BAssessmentResponse, error)
+	// AssessEntropyFile assesses a file on the server, below ASSESS_FILE_BASE_DIR.
+	AssessEntropyFile(ctx context.Context, in *Sp80090BFileAssessmentRequest, opts ...grpc.CallOption) (*Sp80090BAssessmentResponse, error)
+	// GetCapabilities reports the linked NIST library version and available estimators.
+	GetCapabilities(ctx context.Context, in *Sp80090BCapabilitiesRequest, opts ...grpc.CallOption) (*Sp80090BCapabilitiesResponse, error)
+	// GetSupportedEstimators lists the estimator names reported in assessment results.
+	GetSupportedEstimators(ctx context.Context, in *Sp80090BSupportedEstimatorsRequest, opts ...grpc.CallOption) (*Sp80090BSupportedEstimatorsResponse, error)
+	// ValidateRequest runs the input checks of AssessEntropy on a request and
+	// reports the outcome of each, without assessing the data.
+	ValidateRequest(ctx context.Context, in *Sp80090BValidateRequest, opts ...grpc.CallOption) (*Sp80090BValidateResponse, error)
 }
 
 type sp80090BAssessmentServiceClient struct {
@@ -50,14 +64,64 @@ func (c *sp80090BAssessmentServiceClient) AssessEntropy(ctx context.Context, in
 	return out, nil
 }
 
+func (c *sp80090BAssessmentServiceClient) AssessEntropyFile(ctx context.Context, in *Sp80090BFileAssessmentRequest, opts ...grpc.CallOption) (*Sp80090BAssessmentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Sp80090BAssessmentResponse)
+	err := c.cc.Invoke(ctx, Sp80090BAssessmentService_AssessEntropyFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sp80090BAssessmentServiceClient) GetCapabilities(ctx context.Context, in *Sp80090BCapabilitiesRequest, opts ...grpc.CallOption) (*Sp80090BCapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Sp80090BCapabilitiesResponse)
+	err := c.cc.Invoke(ctx, Sp80090BAssessmentService_GetCapabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sp80090BAssessmentServiceClient) GetSupportedEstimators(ctx context.Context, in *Sp80090BSupportedEstimatorsRequest, opts ...grpc.CallOption) (*Sp80090BSupportedEstimatorsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Sp80090BSupportedEstimatorsResponse)
+	err := c.cc.Invoke(ctx, Sp80090BAssessmentService_GetSupportedEstimators_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sp80090BAssessmentServiceClient) ValidateRequest(ctx context.Context, in *Sp80090BValidateRequest, opts ...grpc.CallOption) (*Sp80090BValidateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Sp80090BValidateResponse)
+	err := c.cc.Invoke(ctx, Sp80090BAssessmentService_ValidateRequest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Sp80090BAssessmentServiceServer is the server API for Sp80090BAssessmentService service.
 // All implementations must embed UnimplementedSp80090BAssessmentServiceServer
 // for forward compatibility.
 //
-// Service for NIST SP 800-90B entropy assessment
+// Sp80090bAssessmentService exposes NIST SP 800-90B entropy assessment
+// over gRPC. It supports IID and Non-IID test modes on raw sample data.
 type Sp80090BAssessmentServiceServer interface {
 	// AssessEntropy performs NIST SP 800-90B entropy assessment on the provided data samples.
 	AssessEntropy(context.Context, *Sp80090BAssessmentRequest) (*Sp80090BAssessmentResponse, error)
+	// AssessEntropyFile assesses a file on the server, below ASSESS_FILE_BASE_DIR.
+	AssessEntropyFile(context.Context, *Sp80090BFileAssessmentRequest) (*Sp80090BAssessmentResponse, error)
+	// GetCapabilities reports the linked NIST library version and available estimators.
+	GetCapabilities(context.Context, *Sp80090BCapabilitiesRequest) (*Sp80090BCapabilitiesResponse, error)
+	// GetSupportedEstimators lists the estimator names reported in assessment results.
+	GetSupportedEstimators(context.Context, *Sp80090BSupportedEstimatorsRequest) (*Sp80090BSupportedEstimatorsResponse, error)
+	// ValidateRequest runs the input checks of AssessEntropy on a request and
+	// reports the outcome of each, without assessing the data.
+	ValidateRequest(context.Context, *Sp80090BValidateRequest) (*Sp80090BValidateResponse, error)
 	mustEmbedUnimplementedSp80090BAssessmentServiceServer()
 }
 
@@ -71,6 +135,18 @@ type UnimplementedSp80090BAssessmentServiceServer struct{}
 func (UnimplementedSp80090BAssessmentServiceServer) AssessEntropy(context.Context, *Sp80090BAssessmentRequest) (*Sp80090BAssessmentResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method AssessEntropy not implemented")
 }
+func (UnimplementedSp80090BAssessmentServiceServer) AssessEntropyFile(context.Context, *Sp80090BFileAssessmentRequest) (*Sp80090BAssessmentResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AssessEntropyFile not implemented")
+}
+func (UnimplementedSp80090BAssessmentServiceServer) GetCapabilities(context.Context, *Sp80090BCapabilitiesRequest) (*Sp80090BCapabilitiesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCapabilities not implemented")
+}
+func (UnimplementedSp80090BAssessmentServiceServer) GetSupportedEstimators(context.Context, *Sp80090BSupportedEstimatorsRequest) (*Sp80090BSupportedEstimatorsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetSupportedEstimators not implemented")
+}
+func (UnimplementedSp80090BAssessmentServiceServer) ValidateRequest(context.Context, *Sp80090BValidateRequest) (*Sp80090BValidateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ValidateRequest not implemented")
+}
 func (UnimplementedSp80090BAssessmentServiceServer) mustEmbedUnimplementedSp80090BAssessmentServiceServer() {
 }
 func (UnimplementedSp80090BAssessmentServiceServer) testEmbeddedByValue() {}
@@ -111,6 +187,78 @@ func _Sp80090BAssessmentService_AssessEntropy_Handler(srv interface{}, ctx conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Sp80090BAssessmentService_AssessEntropyFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Sp80090BFileAssessmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Sp80090BAssessmentServiceServer).AssessEntropyFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sp80090BAssessmentService_AssessEntropyFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Sp80090BAssessmentServiceServer).AssessEntropyFile(ctx, req.(*Sp80090BFileAssessmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sp80090BAssessmentService_GetCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Sp80090BCapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Sp80090BAssessmentServiceServer).GetCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sp80090BAssessmentService_GetCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Sp80090BAssessmentServiceServer).GetCapabilities(ctx, req.(*Sp80090BCapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sp80090BAssessmentService_GetSupportedEstimators_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Sp80090BSupportedEstimatorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Sp80090BAssessmentServiceServer).GetSupportedEstimators(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sp80090BAssessmentService_GetSupportedEstimators_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Sp80090BAssessmentServiceServer).GetSupportedEstimators(ctx, req.(*Sp80090BSupportedEstimatorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sp80090BAssessmentService_ValidateRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Sp80090BValidateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Sp80090BAssessmentServiceServer).ValidateRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sp80090BAssessmentService_ValidateRequest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Sp80090BAssessmentServiceServer).ValidateRequest(ctx, req.(*Sp80090BValidateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Sp80090BAssessmentService_ServiceDesc is the grpc.ServiceDesc for Sp80090BAssessmentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -122,6 +270,22 @@ var Sp80090BAssessmentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AssessEntropy",
 			Handler:    _Sp80090BAssessmentService_AssessEntropy_Handler,
 		},
+		{
+			MethodName: "AssessEntropyFile",
+			Handler:    _Sp80090BAssessmentService_AssessEntropyFile_Handler,
+		},
+		{
+			MethodName: "GetCapabilities",
+			Handler:    _Sp80090BAssessmentService_GetCapabilities_Handler,
+		},
+		{
+			MethodName: "GetSupportedEstimators",
+			Handler:    _Sp80090BAssessmentService_GetSupportedEstimators_Handler,
+		},
+		{
+			MethodName: "ValidateRequest",
+			Handler:    _Sp80090BAssessmentService_ValidateRequest_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "nist_sp800_90b.proto",